@@ -0,0 +1,189 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package analyze
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/facebook/time/calnex/api"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+var errNoUsedChannels = errors.New("no used channels")
+
+// Value is a single point of MTIE or TDEV curve, in seconds
+type Value struct {
+	Tau   float64 `json:"tau"`
+	Value float64 `json:"value"`
+	// 0 if mask doesn't cover the observation interval
+	Limit float64 `json:"limit"`
+	Pass  bool    `json:"pass"`
+}
+
+// Result is the analysis of a single channel measurement
+type Result struct {
+	Channel        string  `json:"channel"`
+	Mask           string  `json:"mask"`
+	Samples        int     `json:"samples"`
+	SampleInterval float64 `json:"sample_interval"`
+	MaxTE          float64 `json:"max_te"`
+	MaxTEPass      bool    `json:"max_te_pass"`
+	TIE            []Point `json:"-"`
+	MTIE           []Value `json:"mtie"`
+	TDEV           []Value `json:"tdev"`
+	Pass           bool    `json:"pass"`
+}
+
+// Failures returns human readable description of mask violations
+func (r *Result) Failures() []string {
+	failures := []string{}
+	if !r.MaxTEPass {
+		failures = append(failures, fmt.Sprintf("max|TE| %.3fns", r.MaxTE*1e9))
+	}
+	for _, v := range r.MTIE {
+		if !v.Pass {
+			failures = append(failures, fmt.Sprintf("MTIE(%gs) %.3fns > %.3fns", v.Tau, v.Value*1e9, v.Limit*1e9))
+		}
+	}
+	for _, v := range r.TDEV {
+		if !v.Pass {
+			failures = append(failures, fmt.Sprintf("TDEV(%gs) %.3fns > %.3fns", v.Tau, v.Value*1e9, v.Limit*1e9))
+		}
+	}
+	return failures
+}
+
+func check(segments []MaskSegment, tau, value float64) Value {
+	v := Value{Tau: tau, Value: value, Pass: true}
+	if l, ok := limit(segments, tau); ok {
+		v.Limit = l
+		v.Pass = value <= l
+	}
+	return v
+}
+
+// Compute calculates TIE, MTIE and TDEV of the measurement and compares them to the mask.
+// Points are expected to be taken at regular interval
+func Compute(points []Point, mask *Mask) (*Result, error) {
+	tau0, err := SampleInterval(points)
+	if err != nil {
+		return nil, err
+	}
+	r := &Result{
+		Mask:           mask.Name,
+		Samples:        len(points),
+		SampleInterval: tau0,
+		TIE:            TIE(points),
+	}
+	x := make([]float64, len(points))
+	for i, p := range points {
+		x[i] = p.Value
+		r.MaxTE = math.Max(r.MaxTE, math.Abs(p.Value))
+	}
+	r.MaxTEPass = mask.MaxTE == 0 || r.MaxTE <= mask.MaxTE
+	r.Pass = r.MaxTEPass
+	for _, n := range observationIntervals(len(x) - 1) {
+		v := check(mask.MTIE, float64(n)*tau0, MTIE(x, n))
+		r.Pass = r.Pass && v.Pass
+		r.MTIE = append(r.MTIE, v)
+	}
+	for _, n := range observationIntervals(len(x) / 3) {
+		v := check(mask.TDEV, float64(n)*tau0, TDEV(x, n))
+		r.Pass = r.Pass && v.Pass
+		r.TDEV = append(r.TDEV, v)
+	}
+	return r, nil
+}
+
+// PointsFromFile reads measurement data in the device CSV format
+func PointsFromFile(path string) ([]Point, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	csvReader := csv.NewReader(f)
+	csvReader.Comment = '#'
+	csvReader.FieldsPerRecord = -1
+	csvLines, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse csv from %s: %w", path, err)
+	}
+	return PointsFromCSV(csvLines)
+}
+
+func analyzeChannel(calnexAPI api.Device, channel api.Channel, mask *Mask) (*Result, error) {
+	csvLines, err := calnexAPI.FetchCsv(channel, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch data from channel %s: %w", channel, err)
+	}
+	points, err := PointsFromCSV(csvLines)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse data from channel %s: %w", channel, err)
+	}
+	r, err := Compute(points, mask)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze data from channel %s: %w", channel, err)
+	}
+	r.Channel = string(channel)
+	return r, nil
+}
+
+// Analyze downloads measurement data of specified channels from the Sentinel device and compares it to the mask
+func Analyze(source string, insecureTLS bool, channels []api.Channel, mask *Mask) ([]*Result, error) {
+	calnexAPI, err := api.Connect(source, insecureTLS, 2*time.Minute, api.DefaultRetry)
+	if err != nil {
+		return nil, err
+	}
+	return AnalyzeDevice(calnexAPI, source, channels, mask)
+}
+
+// AnalyzeDevice downloads measurement data of specified channels from the device concurrently and compares it to the mask
+func AnalyzeDevice(calnexAPI api.Device, source string, channels []api.Channel, mask *Mask) ([]*Result, error) {
+	var err error
+	if len(channels) == 0 {
+		channels, err = calnexAPI.FetchUsedChannels()
+		if err != nil || len(channels) == 0 {
+			return nil, errNoUsedChannels
+		}
+	}
+
+	results := make([]*Result, len(channels))
+	eg := new(errgroup.Group)
+	for i, channel := range channels {
+		i, channel := i, channel
+		eg.Go(func() error {
+			r, err := analyzeChannel(calnexAPI, channel, mask)
+			if err != nil {
+				return fmt.Errorf("%s: %w", source, err)
+			}
+			log.Debugf("%s: channel %s: %d samples, %gs apart", source, channel, r.Samples, r.SampleInterval)
+			results[i] = r
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package analyze
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/facebook/time/calnex/api"
+	"github.com/stretchr/testify/require"
+)
+
+// sine returns points 1 second apart, with time error oscillating around offset
+func sine(n int, offset, amplitude, period float64) []Point {
+	points := make([]Point, n)
+	for i := range points {
+		points[i] = Point{Time: 1607961193 + float64(i), Value: offset + amplitude*math.Sin(2*math.Pi*float64(i)/period)}
+	}
+	return points
+}
+
+func TestCompute(t *testing.T) {
+	r, err := Compute(sine(1000, 10e-9, 0.2e-9, 60), Masks["prtc-b"])
+	require.NoError(t, err)
+	require.Equal(t, "G.8272 PRTC-B", r.Mask)
+	require.Equal(t, 1000, r.Samples)
+	require.Equal(t, 1.0, r.SampleInterval)
+	require.Len(t, r.TIE, 1000)
+	require.InDelta(t, 10.2e-9, r.MaxTE, 1e-11)
+	require.True(t, r.MaxTEPass)
+	require.Equal(t, []float64{1, 2, 5, 10, 20, 50, 100, 200, 500}, taus(r.MTIE))
+	require.Equal(t, []float64{1, 2, 5, 10, 20, 50, 100, 200}, taus(r.TDEV))
+	// peak to peak of the sine
+	require.InDelta(t, 0.4e-9, r.MTIE[len(r.MTIE)-1].Value, 1e-11)
+	require.True(t, r.Pass)
+	require.Empty(t, r.Failures())
+
+	r, err = Compute(sine(1000, 50e-9, 25e-9, 60), Masks["prtc-b"])
+	require.NoError(t, err)
+	require.False(t, r.Pass)
+	require.False(t, r.MaxTEPass)
+	failures := r.Failures()
+	require.Equal(t, "max|TE| 75.000ns", failures[0])
+	require.Contains(t, failures, "MTIE(500s) 50.000ns > 40.000ns")
+
+	_, err = Compute(sine(1, 0, 0, 60), Masks["prtc-b"])
+	require.ErrorIs(t, err, errNotEnoughData)
+}
+
+func taus(values []Value) []float64 {
+	t := []float64{}
+	for _, v := range values {
+		t = append(t, v.Tau)
+	}
+	return t
+}
+
+func TestPointsFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.csv")
+	require.NoError(t, os.WriteFile(path, []byte("# channel A\n1607961193.773740,-000.000000250501\n1607961194.773740,-000.000000250504\n"), 0644))
+	points, err := PointsFromFile(path)
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	require.InDelta(t, -250.504e-9, points[1].Value, 1e-15)
+
+	_, err = PointsFromFile(filepath.Join(t.TempDir(), "missing.csv"))
+	require.Error(t, err)
+}
+
+func TestAnalyze(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		if strings.Contains(r.URL.Path, "getsettings") {
+			// FetchUsedChannels
+			fmt.Fprintln(w, "[measure]\nch0\\used=Yes\nch0\\installed=1\nch1\\used=No\nch1\\installed=0")
+		} else if r.URL.Query().Get("channel") == "A" {
+			for _, p := range sine(100, 0, 1e-9, 10) {
+				fmt.Fprintf(w, "%f,%.15f\n", p.Time, p.Value)
+			}
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	parsed, _ := url.Parse(ts.URL)
+	results, err := Analyze(parsed.Host, true, []api.Channel{}, Masks["prtc-a"])
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "A", results[0].Channel)
+	require.Equal(t, 100, results[0].Samples)
+	require.True(t, results[0].Pass)
+
+	_, err = Analyze(parsed.Host, true, []api.Channel{api.ChannelB}, Masks["prtc-a"])
+	require.ErrorContains(t, err, "failed to fetch data from channel B")
+
+	_, err = Analyze("localhost:1", true, []api.Channel{}, Masks["prtc-a"])
+	require.ErrorIs(t, err, errNoUsedChannels)
+}
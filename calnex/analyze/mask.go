@@ -0,0 +1,117 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package analyze
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// MaskSegment is a part of the mask where limit is Offset + Slope * tau, for From < tau <= To (in seconds)
+type MaskSegment struct {
+	From   float64
+	To     float64
+	Slope  float64
+	Offset float64
+}
+
+// Mask is a set of limits measurement must satisfy
+type Mask struct {
+	Name string
+	// limit of Max|TE| in seconds, 0 if not limited
+	MaxTE float64
+	MTIE  []MaskSegment
+	TDEV  []MaskSegment
+}
+
+// limit returns limit for the observation interval tau, false if mask doesn't cover it
+func limit(segments []MaskSegment, tau float64) (float64, bool) {
+	for _, s := range segments {
+		if tau > s.From && tau <= s.To {
+			return s.Offset + s.Slope*tau, true
+		}
+	}
+	return 0, false
+}
+
+// Masks are the supported masks by name
+var Masks = map[string]*Mask{
+	// ITU-T G.8272, PRTC class A
+	"prtc-a": {
+		Name:  "G.8272 PRTC-A",
+		MaxTE: 100e-9,
+		MTIE: []MaskSegment{
+			{From: 0.273, To: 275, Slope: 0.275e-9, Offset: 25e-9},
+			{From: 275, To: math.Inf(1), Offset: 100e-9},
+		},
+		TDEV: []MaskSegment{
+			{From: 0.1, To: 100, Offset: 3e-9},
+			{From: 100, To: 1000, Slope: 0.03e-9},
+			{From: 1000, To: 1e6, Offset: 30e-9},
+		},
+	},
+	// ITU-T G.8272, PRTC class B
+	"prtc-b": {
+		Name:  "G.8272 PRTC-B",
+		MaxTE: 40e-9,
+		MTIE: []MaskSegment{
+			{From: 0.273, To: 54.5, Slope: 0.275e-9, Offset: 25e-9},
+			{From: 54.5, To: math.Inf(1), Offset: 40e-9},
+		},
+		TDEV: []MaskSegment{
+			{From: 0.1, To: 100, Offset: 1e-9},
+			{From: 100, To: 500, Slope: 0.01e-9},
+			{From: 500, To: 1e5, Offset: 5e-9},
+		},
+	},
+	// ITU-T G.8272.1, ePRTC class A
+	"eprtc": {
+		Name:  "G.8272.1 ePRTC-A",
+		MaxTE: 30e-9,
+		MTIE: []MaskSegment{
+			{From: 1, To: 300, Offset: 4e-9},
+			{From: 300, To: 750, Slope: 4e-9 / 300},
+			{From: 750, To: math.Inf(1), Offset: 10e-9},
+		},
+		TDEV: []MaskSegment{
+			{From: 1, To: 30000, Offset: 1e-9},
+			{From: 30000, To: 300000, Slope: 1e-9 / 30000},
+			{From: 300000, To: 1e6, Offset: 10e-9},
+		},
+	},
+}
+
+// MaskNames returns names of all supported masks
+func MaskNames() string {
+	names := make([]string, 0, len(Masks))
+	for name := range Masks {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
+
+// MaskFromString returns mask by name
+func MaskFromString(name string) (*Mask, error) {
+	m, ok := Masks[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown mask %q, supported: %s", name, MaskNames())
+	}
+	return m, nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package analyze
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskFromString(t *testing.T) {
+	m, err := MaskFromString("PRTC-A")
+	require.NoError(t, err)
+	require.Equal(t, "G.8272 PRTC-A", m.Name)
+
+	_, err = MaskFromString("g.811")
+	require.EqualError(t, err, "unknown mask \"g.811\", supported: eprtc, prtc-a, prtc-b")
+}
+
+func TestMaskLimit(t *testing.T) {
+	m := Masks["prtc-a"]
+	_, ok := limit(m.MTIE, 0.1)
+	require.False(t, ok)
+	l, ok := limit(m.MTIE, 100)
+	require.True(t, ok)
+	require.InDelta(t, 52.5e-9, l, 1e-15)
+	l, ok = limit(m.MTIE, 1e5)
+	require.True(t, ok)
+	require.InDelta(t, 100e-9, l, 1e-15)
+	l, ok = limit(m.TDEV, 500)
+	require.True(t, ok)
+	require.InDelta(t, 15e-9, l, 1e-15)
+}
+
+func TestMasksContinuous(t *testing.T) {
+	for name, m := range Masks {
+		for _, segments := range [][]MaskSegment{m.MTIE, m.TDEV} {
+			for i := 1; i < len(segments); i++ {
+				prev, cur := segments[i-1], segments[i]
+				require.Equal(t, prev.To, cur.From, name)
+				// G.8272 masks have tiny steps
+				require.InDelta(t, prev.Offset+prev.Slope*prev.To, cur.Offset+cur.Slope*cur.From, 1e-9, name)
+			}
+			require.False(t, math.IsInf(segments[0].From, 0), name)
+		}
+	}
+}
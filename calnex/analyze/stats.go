@@ -0,0 +1,143 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package analyze
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+var errNotEnoughData = errors.New("not enough data")
+
+// Point is a single measurement, time is unix time in seconds and value is time error in seconds
+type Point struct {
+	Time  float64 `json:"time"`
+	Value float64 `json:"value"`
+}
+
+// PointsFromCSV parses measurement data as returned by the device
+func PointsFromCSV(csvLines [][]string) ([]Point, error) {
+	points := make([]Point, 0, len(csvLines))
+	for _, csvLine := range csvLines {
+		if len(csvLine) < 2 {
+			return nil, fmt.Errorf("malformed line %v", csvLine)
+		}
+		t, err := strconv.ParseFloat(csvLine[0], 64)
+		if err != nil {
+			return nil, err
+		}
+		v, err := strconv.ParseFloat(csvLine[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, Point{Time: t, Value: v})
+	}
+	return points, nil
+}
+
+// SampleInterval returns median interval between the points
+func SampleInterval(points []Point) (float64, error) {
+	if len(points) < 2 {
+		return 0, errNotEnoughData
+	}
+	intervals := make([]float64, 0, len(points)-1)
+	for i := 1; i < len(points); i++ {
+		intervals = append(intervals, points[i].Time-points[i-1].Time)
+	}
+	slices.Sort(intervals)
+	tau0 := intervals[len(intervals)/2]
+	if tau0 <= 0 {
+		return 0, fmt.Errorf("bad sample interval %v", tau0)
+	}
+	return tau0, nil
+}
+
+// TIE returns time interval error relative to the first point
+func TIE(points []Point) []Point {
+	tie := make([]Point, len(points))
+	for i, p := range points {
+		tie[i] = Point{Time: p.Time, Value: p.Value - points[0].Value}
+	}
+	return tie
+}
+
+// MTIE returns maximum time interval error over all windows n samples long.
+// Samples are expected to be taken at regular interval
+func MTIE(x []float64, n int) float64 {
+	if n < 1 || n >= len(x) {
+		return math.NaN()
+	}
+	// indexes of window max and min candidates, in order
+	var maxq, minq []int
+	mtie := 0.0
+	for i := range x {
+		for len(maxq) > 0 && x[maxq[len(maxq)-1]] <= x[i] {
+			maxq = maxq[:len(maxq)-1]
+		}
+		maxq = append(maxq, i)
+		for len(minq) > 0 && x[minq[len(minq)-1]] >= x[i] {
+			minq = minq[:len(minq)-1]
+		}
+		minq = append(minq, i)
+		// window covers n intervals, so n+1 samples
+		if maxq[0] < i-n {
+			maxq = maxq[1:]
+		}
+		if minq[0] < i-n {
+			minq = minq[1:]
+		}
+		if i >= n {
+			mtie = math.Max(mtie, x[maxq[0]]-x[minq[0]])
+		}
+	}
+	return mtie
+}
+
+// TDEV returns time deviation for observation interval of n samples.
+// Samples are expected to be taken at regular interval
+func TDEV(x []float64, n int) float64 {
+	if n < 1 || 3*n > len(x) {
+		return math.NaN()
+	}
+	sum := make([]float64, len(x)+1)
+	for i, v := range x {
+		sum[i+1] = sum[i] + v
+	}
+	windows := len(x) - 3*n + 1
+	total := 0.0
+	for j := 0; j < windows; j++ {
+		d := (sum[j+3*n] - sum[j+2*n]) - 2*(sum[j+2*n]-sum[j+n]) + (sum[j+n] - sum[j])
+		total += d * d
+	}
+	return math.Sqrt(total / (6 * float64(n) * float64(n) * float64(windows)))
+}
+
+// observationIntervals returns 1, 2, 5 decade sequence of sample counts up to max
+func observationIntervals(max int) []int {
+	ns := []int{}
+	for decade := 1; decade <= max; decade *= 10 {
+		for _, m := range []int{1, 2, 5} {
+			if n := decade * m; n <= max {
+				ns = append(ns, n)
+			}
+		}
+	}
+	return ns
+}
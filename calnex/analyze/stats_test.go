@@ -0,0 +1,81 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package analyze
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPointsFromCSV(t *testing.T) {
+	points, err := PointsFromCSV([][]string{
+		{"1607961193.000000", "-000.000000250501"},
+		{"1607961194.000000", "000.000000000001", "extra"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Point{{Time: 1607961193, Value: -250.501e-9}, {Time: 1607961194, Value: 1e-12}}, points)
+
+	_, err = PointsFromCSV([][]string{{"1607961193.000000"}})
+	require.Error(t, err)
+	_, err = PointsFromCSV([][]string{{"foo", "0"}})
+	require.Error(t, err)
+	_, err = PointsFromCSV([][]string{{"1607961193.000000", "bar"}})
+	require.Error(t, err)
+}
+
+func TestSampleInterval(t *testing.T) {
+	tau0, err := SampleInterval([]Point{{Time: 0}, {Time: 1}, {Time: 2}, {Time: 5}, {Time: 6}})
+	require.NoError(t, err)
+	require.Equal(t, 1.0, tau0)
+
+	_, err = SampleInterval([]Point{{Time: 0}})
+	require.ErrorIs(t, err, errNotEnoughData)
+	_, err = SampleInterval([]Point{{Time: 1}, {Time: 1}})
+	require.Error(t, err)
+}
+
+func TestTIE(t *testing.T) {
+	tie := TIE([]Point{{Time: 1, Value: 5}, {Time: 2, Value: 7}, {Time: 3, Value: 4}})
+	require.Equal(t, []Point{{Time: 1, Value: 0}, {Time: 2, Value: 2}, {Time: 3, Value: -1}}, tie)
+}
+
+func TestMTIE(t *testing.T) {
+	ramp := []float64{0, 1, 2, 3, 4, 5}
+	for n := 1; n < len(ramp); n++ {
+		require.Equal(t, float64(n), MTIE(ramp, n))
+	}
+	require.Equal(t, 5.0, MTIE([]float64{0, 1, 0, 1, 5, 0, 1}, 1))
+	require.Equal(t, 1.0, MTIE([]float64{0, 1, 0, 1, 0, 1}, 5))
+	require.True(t, math.IsNaN(MTIE(ramp, 0)))
+	require.True(t, math.IsNaN(MTIE(ramp, 6)))
+}
+
+func TestTDEV(t *testing.T) {
+	// second difference of linear drift is 0
+	require.Equal(t, 0.0, TDEV([]float64{0, 1, 2, 3, 4, 5, 6}, 2))
+	require.InDelta(t, math.Sqrt(2.0/3.0), TDEV([]float64{0, 1, 0, 1, 0, 1}, 1), 1e-12)
+	require.True(t, math.IsNaN(TDEV([]float64{0, 1, 0, 1, 0, 1}, 3)))
+	require.True(t, math.IsNaN(TDEV([]float64{0, 1, 0}, 0)))
+}
+
+func TestObservationIntervals(t *testing.T) {
+	require.Equal(t, []int{1, 2, 5, 10, 20}, observationIntervals(49))
+	require.Equal(t, []int{1}, observationIntervals(1))
+	require.Empty(t, observationIntervals(0))
+}
@@ -31,12 +31,16 @@ import (
 	"time"
 
 	"github.com/go-ini/ini"
+	log "github.com/sirupsen/logrus"
 )
 
 // API is struct for accessing calnex API
 type API struct {
 	Client *http.Client
-	source string
+	// Retry is a retry policy of every request to the device
+	Retry     Retry
+	source    string
+	endpoints Endpoints
 }
 
 // Status is a struct representing Calnex status JSON response
@@ -71,6 +75,15 @@ type ModuleStatus struct {
 	Type     string
 }
 
+// ModuleReady returns whether module or channel in the state can measure
+func ModuleReady(state string) bool {
+	switch state {
+	case "Measuring", "Ready", "ReadyForMeasurement":
+		return true
+	}
+	return false
+}
+
 // Result is a struct representing Calnex result JSON response
 type Result struct {
 	Result  bool
@@ -351,6 +364,14 @@ func (c Channel) CalnexAPI() string {
 	return fmt.Sprintf("ch%d", c.Calnex())
 }
 
+// Physical returns physical channel used for the measurement, virtual ports run on channel 1
+func (c Channel) Physical() Channel {
+	if MeasureChannelDatatypeMap[c] == TWOWAYTE {
+		return ChannelONE
+	}
+	return c
+}
+
 // Set Channel to Channels
 func (cs *Channels) Set(channel string) error {
 	c, err := ChannelFromString(channel)
@@ -424,6 +445,16 @@ func ProbeFromCalnex(calnex string) (*Probe, error) {
 	return &p, nil
 }
 
+// ProbeFromCalnexName returns Probe object from Calnex name used in settings
+func ProbeFromCalnexName(name string) (*Probe, error) {
+	for p, n := range probeToCalnexName {
+		if n == name {
+			return &p, nil
+		}
+	}
+	return nil, errBadProbe
+}
+
 // UnmarshalText probe from string version
 func (p *Probe) UnmarshalText(value []byte) error {
 	pr, err := ProbeFromString(string(value))
@@ -481,6 +512,8 @@ var (
 	ErrBadChannel = errors.New("channel is not recognized")
 	errBadProbe   = errors.New("probe protocol is not recognized")
 	errAPI        = errors.New("invalid response from API")
+	// ErrNoNewData is returned by FetchCsv when device has no data to return
+	ErrNoNewData = errors.New("no new data")
 )
 
 func parseResponse(response string) (string, error) {
@@ -501,32 +534,87 @@ func NewAPI(source string, insecureTLS bool, timeout time.Duration) *API {
 			},
 			Timeout: timeout,
 		},
-		source: source,
+		Retry:     DefaultRetry,
+		source:    source,
+		endpoints: defaultEndpoints,
 	}
 }
 
-// FetchCsv takes channel name (like 1, 2, c, d)
-// it returns list of CSV lines which is []string
-func (a *API) FetchCsv(channel Channel, allData bool) ([][]string, error) {
-	url := fmt.Sprintf(dataURL, a.source, channel, MeasureChannelDatatypeMap[channel], allData)
-	resp, err := a.Client.Get(url)
+// downloadFrom makes a single attempt to continue download of url after data.
+// Device may ignore the range and send everything again, then download starts over.
+// It returns as much data as it received, whether it's worth retrying on error
+// and the response if device rejected the request
+func (a *API) downloadFrom(url string, data []byte) ([]byte, bool, *http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return nil, err
+		return data, false, nil, err
+	}
+	if len(data) > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", len(data)))
+	}
+	resp, err := a.attempt(req)
+	if err != nil {
+		return data, retryable(req, nil, err), nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(http.StatusText(resp.StatusCode))
+	switch resp.StatusCode {
+	case http.StatusOK:
+		data = data[:0]
+	case http.StatusPartialContent:
+	default:
+		return data, resp.StatusCode >= http.StatusInternalServerError || busy(resp.StatusCode), resp, &StatusError{StatusCode: resp.StatusCode}
 	}
 
-	b, err := io.ReadAll(resp.Body)
+	buf := bytes.NewBuffer(data)
+	_, err = buf.ReadFrom(resp.Body)
+	return buf.Bytes(), err != nil, nil, err
+}
+
+// download fetches url, resuming partial transfers and retrying on transient errors according to the retry policy.
+// Long downloads may need many attempts, so only attempts which didn't make progress count
+func (a *API) download(url string) ([]byte, error) {
+	var data []byte
+	failures := 0
+	for {
+		received := len(data)
+		var retry bool
+		var resp *http.Response
+		var err error
+		data, retry, resp, err = a.downloadFrom(url, data)
+		if err == nil {
+			return data, nil
+		}
+		if len(data) > received {
+			failures = 0
+		} else {
+			failures++
+		}
+		if !retry || failures >= a.Retry.Attempts {
+			return nil, err
+		}
+		delay := a.Retry.delay(failures, resp)
+		log.Warningf("%s: download of %s failed after %d bytes, retrying in %s: %v", a.source, url, len(data), delay.Round(time.Millisecond), err)
+		time.Sleep(delay)
+	}
+}
+
+// FetchCsv takes channel name (like 1, 2, c, d)
+// it returns list of CSV lines which is []string
+func (a *API) FetchCsv(channel Channel, allData bool) ([][]string, error) {
+	url := fmt.Sprintf(a.endpoints.Data, a.source, channel, MeasureChannelDatatypeMap[channel], allData)
+	b, err := a.download(url)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check for empty response
+	// Check for empty response, device responds with JSON message instead of CSV.
+	// Successful result means there is no data, anything else is an error.
 	r := &Result{}
 	if err = json.Unmarshal(b, r); err == nil {
+		if r.Result {
+			return nil, fmt.Errorf("%w: %s", ErrNoNewData, r.Message)
+		}
 		return nil, errors.New(r.Message)
 	}
 
@@ -552,16 +640,16 @@ func (a *API) FetchChannelProbe(channel Channel) (*Probe, error) {
 	if MeasureChannelDatatypeMap[channel] == TE {
 		pth = path.Join(channel.CalnexAPI(), "signal_type")
 	}
-	url := fmt.Sprintf(measureURL, a.source, pth)
+	url := fmt.Sprintf(a.endpoints.Measure, a.source, pth)
 
-	resp, err := a.Client.Get(url)
+	resp, err := a.getURL(url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(http.StatusText(resp.StatusCode))
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	b, err := io.ReadAll(resp.Body)
@@ -583,15 +671,15 @@ func (a *API) FetchChannelTarget(channel Channel, probe Probe) (string, error) {
 	if MeasureChannelDatatypeMap[channel] == TE {
 		pth = path.Join(channel.CalnexAPI(), probe.ServerType())
 	}
-	url := fmt.Sprintf(measureURL, a.source, pth)
-	resp, err := a.Client.Get(url)
+	url := fmt.Sprintf(a.endpoints.Measure, a.source, pth)
+	resp, err := a.getURL(url)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", errors.New(http.StatusText(resp.StatusCode))
+		return "", &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	b, err := io.ReadAll(resp.Body)
@@ -625,15 +713,15 @@ func (a *API) FetchUsedChannels() ([]Channel, error) {
 
 // FetchSettings returns the calnex settings
 func (a *API) FetchSettings() (*ini.File, error) {
-	url := fmt.Sprintf(getSettingsURL, a.source)
-	resp, err := a.Client.Get(url)
+	url := fmt.Sprintf(a.endpoints.GetSettings, a.source)
+	resp, err := a.getURL(url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(http.StatusText(resp.StatusCode))
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	return ini.Load(resp.Body)
@@ -641,15 +729,15 @@ func (a *API) FetchSettings() (*ini.File, error) {
 
 // FetchStatus returns the calnex status
 func (a *API) FetchStatus() (*Status, error) {
-	url := fmt.Sprintf(getStatusURL, a.source)
-	resp, err := a.Client.Get(url)
+	url := fmt.Sprintf(a.endpoints.GetStatus, a.source)
+	resp, err := a.getURL(url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(http.StatusText(resp.StatusCode))
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	s := &Status{}
@@ -662,15 +750,15 @@ func (a *API) FetchStatus() (*Status, error) {
 
 // FetchInstrumentStatus returns the calnex instrument status
 func (a *API) FetchInstrumentStatus() (*InstrumentStatus, error) {
-	url := fmt.Sprintf(instrumentStatusURL, a.source)
-	resp, err := a.Client.Get(url)
+	url := fmt.Sprintf(a.endpoints.InstrumentStatus, a.source)
+	resp, err := a.getURL(url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(http.StatusText(resp.StatusCode))
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 	i := &InstrumentStatus{}
 	if err = json.NewDecoder(resp.Body).Decode(i); err != nil {
@@ -681,15 +769,15 @@ func (a *API) FetchInstrumentStatus() (*InstrumentStatus, error) {
 
 // FetchProblemReport saves a problem report
 func (a *API) FetchProblemReport(dir string) (string, error) {
-	url := fmt.Sprintf(getProblemReportURL, a.source)
-	resp, err := a.Client.Get(url)
+	url := fmt.Sprintf(a.endpoints.GetProblemReport, a.source)
+	resp, err := a.getURL(url)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", errors.New(http.StatusText(resp.StatusCode))
+		return "", &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	// calnex_problem_report_2021-12-07_10-42-26.tar
@@ -710,15 +798,15 @@ func (a *API) FetchProblemReport(dir string) (string, error) {
 
 // FetchVersion returns current Firmware Version
 func (a *API) FetchVersion() (*Version, error) {
-	url := fmt.Sprintf(versionURL, a.source)
-	resp, err := a.Client.Get(url)
+	url := fmt.Sprintf(a.endpoints.Version, a.source)
+	resp, err := a.getURL(url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(http.StatusText(resp.StatusCode))
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	v := &Version{}
@@ -737,7 +825,7 @@ func (a *API) PushVersion(path string) (*Result, error) {
 	}
 	defer fw.Close()
 
-	url := fmt.Sprintf(firmwareURL, a.source)
+	url := fmt.Sprintf(a.endpoints.Firmware, a.source)
 	buf := &bytes.Buffer{}
 	_, err = buf.ReadFrom(fw)
 
@@ -751,7 +839,7 @@ func (a *API) PushVersion(path string) (*Result, error) {
 
 // PushCert uploads a new Certificate to the device
 func (a *API) PushCert(cert []byte) (*Result, error) {
-	url := fmt.Sprintf(certificateURL, a.source)
+	url := fmt.Sprintf(a.endpoints.Certificate, a.source)
 	buf := bytes.NewBuffer(cert)
 
 	r, err := a.post(url, buf)
@@ -766,7 +854,7 @@ func (a *API) PushLicense(path string) (*Result, error) {
 	}
 	defer license.Close()
 
-	url := fmt.Sprintf(licenseURL, a.source)
+	url := fmt.Sprintf(a.endpoints.License, a.source)
 	buf := &bytes.Buffer{}
 	_, err = buf.ReadFrom(license)
 
@@ -784,7 +872,7 @@ func (a *API) PushSettings(f *ini.File) error {
 	if err != nil {
 		return err
 	}
-	url := fmt.Sprintf(setSettingsURL, a.source)
+	url := fmt.Sprintf(a.endpoints.SetSettings, a.source)
 
 	_, err = a.post(url, buf)
 	return err
@@ -793,7 +881,7 @@ func (a *API) PushSettings(f *ini.File) error {
 func (a *API) post(url string, content *bytes.Buffer) (*Result, error) {
 	// content must be a bytes.Buffer or anything which supports .Len()
 	// Otherwise Content-Length will not be set.
-	resp, err := a.Client.Post(url, "application/x-www-form-urlencoded", content)
+	resp, err := a.postURL(url, "application/x-www-form-urlencoded", content)
 	if err != nil {
 		return nil, err
 	}
@@ -805,7 +893,7 @@ func (a *API) post(url string, content *bytes.Buffer) (*Result, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return r, errors.New(http.StatusText(resp.StatusCode))
+		return r, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	if !r.Result {
@@ -817,14 +905,14 @@ func (a *API) post(url string, content *bytes.Buffer) (*Result, error) {
 
 func (a *API) get(path string) error {
 	url := fmt.Sprintf(path, a.source)
-	resp, err := a.Client.Get(url)
+	resp, err := a.getURL(url)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return errors.New(http.StatusText(resp.StatusCode))
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	r := &Result{}
@@ -841,12 +929,12 @@ func (a *API) get(path string) error {
 
 // StartMeasure starts measurement
 func (a *API) StartMeasure() error {
-	return a.get(startMeasure)
+	return a.get(a.endpoints.StartMeasure)
 }
 
 // StopMeasure stops measurement
 func (a *API) StopMeasure() error {
-	return a.get(stopMeasure)
+	return a.get(a.endpoints.StopMeasure)
 }
 
 // ClearDevice clears device data
@@ -858,7 +946,7 @@ func (a *API) ClearDevice() error {
 		// stop measurement if possible
 		_ = a.StopMeasure()
 	}
-	return a.get(clearDeviceURL)
+	return a.get(a.endpoints.ClearDevice)
 }
 
 // Reboot the device
@@ -870,20 +958,20 @@ func (a *API) Reboot() error {
 		// stop measurement if possible
 		_ = a.StopMeasure()
 	}
-	return a.get(rebootURL)
+	return a.get(a.endpoints.Reboot)
 }
 
 // GnssStatus returns current GNSS status
 func (a *API) GnssStatus() (*GNSS, error) {
-	url := fmt.Sprintf(gnssURL, a.source)
-	resp, err := a.Client.Get(url)
+	url := fmt.Sprintf(a.endpoints.GNSS, a.source)
+	resp, err := a.getURL(url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(http.StatusText(resp.StatusCode))
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	g := &GNSS{}
@@ -896,15 +984,15 @@ func (a *API) GnssStatus() (*GNSS, error) {
 
 // PowerSupplyStatus returns current PSU status
 func (a *API) PowerSupplyStatus() (*PowerSupplyStatus, error) {
-	url := fmt.Sprintf(powerSupplyURL, a.source)
-	resp, err := a.Client.Get(url)
+	url := fmt.Sprintf(a.endpoints.PowerSupply, a.source)
+	resp, err := a.getURL(url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(http.StatusText(resp.StatusCode))
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	p := &PowerSupplyStatus{}
@@ -917,15 +1005,15 @@ func (a *API) PowerSupplyStatus() (*PowerSupplyStatus, error) {
 
 // FetchUptime returns uptime of the device
 func (a *API) FetchUptime() (*Uptime, error) {
-	url := fmt.Sprintf(uptimeURL, a.source)
-	resp, err := a.Client.Get(url)
+	url := fmt.Sprintf(a.endpoints.Uptime, a.source)
+	resp, err := a.getURL(url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(http.StatusText(resp.StatusCode))
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	u := &Uptime{}
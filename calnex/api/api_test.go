@@ -75,6 +75,22 @@ func TestChannels(t *testing.T) {
 	require.Equal(t, "1, A", cs.String())
 }
 
+func TestChannelPhysical(t *testing.T) {
+	require.Equal(t, ChannelA, ChannelA.Physical())
+	require.Equal(t, ChannelONE, ChannelONE.Physical())
+	require.Equal(t, ChannelONE, ChannelVP1.Physical())
+	require.Equal(t, ChannelONE, ChannelVP32.Physical())
+}
+
+func TestModuleReady(t *testing.T) {
+	for _, state := range []string{"Measuring", "Ready", "ReadyForMeasurement"} {
+		require.True(t, ModuleReady(state), state)
+	}
+	for _, state := range []string{"", "Failed", "Initialising"} {
+		require.False(t, ModuleReady(state), state)
+	}
+}
+
 func TestProbe(t *testing.T) {
 	legitProbeNamesToProbe := map[string]Probe{
 		"ntp": ProbeNTP,
@@ -122,6 +138,25 @@ func TestProbeFromCalnex(t *testing.T) {
 	}
 }
 
+func TestProbeFromCalnexName(t *testing.T) {
+	legitProbeNamesToProbe := map[string]Probe{
+		"NTP":   ProbeNTP,
+		"PTP":   ProbePTP,
+		"1 PPS": ProbePPS,
+	}
+	for probeH, probe := range legitProbeNamesToProbe {
+		p, err := ProbeFromCalnexName(probeH)
+		require.NoError(t, err)
+		require.Equal(t, probe, *p)
+	}
+	wrongProbeNames := []string{"", "Disabled", "PPS", "ntp"}
+	for _, probe := range wrongProbeNames {
+		p, err := ProbeFromCalnexName(probe)
+		require.Nil(t, p)
+		require.ErrorIs(t, errBadProbe, err)
+	}
+}
+
 func TestCalnexName(t *testing.T) {
 	require.Equal(t, "NTP", ProbeNTP.CalnexName())
 	require.Equal(t, "PTP", ProbePTP.CalnexName())
@@ -175,10 +210,107 @@ func TestFetchCsvNoData(t *testing.T) {
 	calnexAPI := NewAPI(parsed.Host, true, time.Second)
 	calnexAPI.Client = ts.Client()
 	lines, err := calnexAPI.FetchCsv(ChannelVP22, true)
-	require.Error(t, err)
+	require.ErrorIs(t, err, ErrNoNewData)
+	require.Nil(t, lines)
+
+	sampleResp = "{\"message\": \"Measurement failed\", \"result\": false}"
+	lines, err = calnexAPI.FetchCsv(ChannelVP22, true)
+	require.EqualError(t, err, "Measurement failed")
+	require.NotErrorIs(t, err, ErrNoNewData)
 	require.Nil(t, lines)
 }
 
+func TestFetchCsvResume(t *testing.T) {
+	sampleResp := "1607961193.773740,-000.000000250501\n1607961194.773740,-000.000000250502\n"
+	var ranges []string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		switch len(ranges) {
+		case 1:
+			// connection breaks in the middle of the transfer
+			w.Header().Set("Content-Length", fmt.Sprint(len(sampleResp)))
+			fmt.Fprint(w, sampleResp[:10])
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusPartialContent)
+			fmt.Fprint(w, sampleResp[10:])
+		}
+	}))
+	defer ts.Close()
+
+	parsed, _ := url.Parse(ts.URL)
+	calnexAPI := NewAPI(parsed.Host, true, time.Second)
+	calnexAPI.Client = ts.Client()
+	calnexAPI.Retry.Delay = 0
+	lines, err := calnexAPI.FetchCsv(ChannelA, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"", "bytes=10-", "bytes=10-"}, ranges)
+	require.Equal(t, 2, len(lines))
+	require.Equal(t, "1607961194.773740,-000.000000250502", strings.Join(lines[1], ","))
+}
+
+func TestFetchCsvRestart(t *testing.T) {
+	sampleResp := "1607961193.773740,-000.000000250501\n"
+	requests := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		requests++
+		w.Header().Set("Content-Length", fmt.Sprint(len(sampleResp)))
+		if requests == 1 {
+			fmt.Fprint(w, sampleResp[:10])
+			return
+		}
+		// range is not supported
+		fmt.Fprint(w, sampleResp)
+	}))
+	defer ts.Close()
+
+	parsed, _ := url.Parse(ts.URL)
+	calnexAPI := NewAPI(parsed.Host, true, time.Second)
+	calnexAPI.Client = ts.Client()
+	calnexAPI.Retry.Delay = 0
+	lines, err := calnexAPI.FetchCsv(ChannelA, true)
+	require.NoError(t, err)
+	require.Equal(t, 2, requests)
+	require.Equal(t, 1, len(lines))
+	require.Equal(t, strings.TrimSpace(sampleResp), strings.Join(lines[0], ","))
+}
+
+func TestFetchCsvRetries(t *testing.T) {
+	status := http.StatusInternalServerError
+	requests := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		requests++
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	parsed, _ := url.Parse(ts.URL)
+	calnexAPI := NewAPI(parsed.Host, true, time.Second)
+	calnexAPI.Client = ts.Client()
+	calnexAPI.Retry.Delay = 0
+	_, err := calnexAPI.FetchCsv(ChannelA, true)
+	require.EqualError(t, err, http.StatusText(status))
+	require.Equal(t, calnexAPI.Retry.Attempts, requests)
+
+	// busy device is retried by the same policy, not once per layer
+	status = http.StatusServiceUnavailable
+	requests = 0
+	_, err = calnexAPI.FetchCsv(ChannelA, true)
+	require.EqualError(t, err, http.StatusText(status))
+	require.Equal(t, calnexAPI.Retry.Attempts, requests)
+
+	// client errors are not retried
+	status = http.StatusNotFound
+	requests = 0
+	_, err = calnexAPI.FetchCsv(ChannelA, true)
+	require.EqualError(t, err, http.StatusText(status))
+	require.Equal(t, 1, requests)
+}
+
 func TestFetchChannelProtocol_NTP(t *testing.T) {
 	sampleResp := "measure/ch9/ptp_synce/mode/probe_type=2"
 	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter,
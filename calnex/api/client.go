@@ -0,0 +1,204 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrDeviceBusy is matched by errors of requests rejected because device is busy
+	ErrDeviceBusy = errors.New("device is busy")
+	// ErrAuth is matched by errors of requests rejected because of missing or wrong credentials
+	ErrAuth = errors.New("authentication failed")
+)
+
+// StatusError is returned when device API responds with unexpected HTTP status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return http.StatusText(e.StatusCode)
+}
+
+// Is allows matching StatusError against ErrDeviceBusy and ErrAuth with errors.Is
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrDeviceBusy:
+		return busy(e.StatusCode)
+	case ErrAuth:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+func busy(code int) bool {
+	return code == http.StatusServiceUnavailable || code == http.StatusTooManyRequests
+}
+
+// Retry is a retry policy of device API requests
+type Retry struct {
+	// Attempts is the maximum number of attempts including the first one
+	Attempts int
+	// Delay is a delay before the first retry, doubled for every next one
+	Delay time.Duration
+	// MaxDelay caps the delay, including one requested by device with Retry-After
+	MaxDelay time.Duration
+	// Jitter is a fraction of the delay which is randomized, so clients don't retry in lockstep
+	Jitter float64
+	// AttemptTimeout limits every attempt. Zero means only client timeout applies
+	AttemptTimeout time.Duration
+}
+
+// DefaultRetry is the retry policy of API created by NewAPI
+var DefaultRetry = Retry{
+	Attempts: 4,
+	Delay:    time.Second,
+	MaxDelay: 30 * time.Second,
+	Jitter:   0.5,
+}
+
+// delay returns how long to wait after the failed attempt
+func (r Retry) delay(attempt int, resp *http.Response) time.Duration {
+	d := r.Delay
+	for i := 1; i < attempt && d < r.MaxDelay; i++ {
+		d *= 2
+	}
+	if resp != nil {
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			d = time.Duration(s) * time.Second
+		}
+	}
+	if d > r.MaxDelay {
+		d = r.MaxDelay
+	}
+	return d - time.Duration(rand.Float64()*r.Jitter*float64(d))
+}
+
+// cancelBody releases the attempt context when response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+func (a *API) attempt(req *http.Request) (*http.Response, error) {
+	if a.Retry.AttemptTimeout <= 0 {
+		return a.Client.Do(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), a.Retry.AttemptTimeout)
+	resp, err := a.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// retryable returns whether request is worth another attempt.
+// Requests which may have changed device state are only retried if device rejected them
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+	if resp != nil {
+		if busy(resp.StatusCode) {
+			return true
+		}
+		return idempotent && (resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusGatewayTimeout)
+	}
+	if !idempotent {
+		return false
+	}
+	// device which is down is not going to get up soon, refused connections are not retried
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout() ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// do sends the request, retrying it on flaky network or busy device according to the retry policy
+func (a *API) do(req *http.Request) (*http.Response, error) {
+	r := req
+	for attempt := 1; ; attempt++ {
+		resp, err := a.attempt(r)
+		if attempt >= a.Retry.Attempts || !retryable(req, resp, err) {
+			return resp, err
+		}
+		if req.Body != nil && req.GetBody == nil {
+			return resp, err
+		}
+
+		delay := a.Retry.delay(attempt, resp)
+		if resp != nil {
+			err = &StatusError{StatusCode: resp.StatusCode}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		log.Debugf("%s: %s %s failed: %v, retrying in %s", a.source, req.Method, req.URL.Path, err, delay.Round(time.Millisecond))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		r = req.Clone(req.Context())
+		if req.GetBody != nil {
+			if r.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// getURL sends GET request to the device
+func (a *API) getURL(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return a.do(req)
+}
+
+// postURL sends POST request to the device
+func (a *API) postURL(url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return a.do(req)
+}
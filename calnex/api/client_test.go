@@ -0,0 +1,171 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-ini/ini"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusError(t *testing.T) {
+	err := error(&StatusError{StatusCode: http.StatusServiceUnavailable})
+	require.EqualError(t, err, "Service Unavailable")
+	require.ErrorIs(t, err, ErrDeviceBusy)
+	require.NotErrorIs(t, err, ErrAuth)
+
+	err = fmt.Errorf("fetching status: %w", &StatusError{StatusCode: http.StatusForbidden})
+	require.ErrorIs(t, err, ErrAuth)
+	require.NotErrorIs(t, err, ErrDeviceBusy)
+
+	require.NotErrorIs(t, &StatusError{StatusCode: http.StatusNotFound}, ErrAuth)
+}
+
+func TestRetryDelay(t *testing.T) {
+	r := Retry{Delay: time.Second, MaxDelay: 5 * time.Second}
+	require.Equal(t, time.Second, r.delay(1, nil))
+	require.Equal(t, 2*time.Second, r.delay(2, nil))
+	require.Equal(t, 4*time.Second, r.delay(3, nil))
+	require.Equal(t, 5*time.Second, r.delay(4, nil))
+	require.Equal(t, 5*time.Second, r.delay(100, nil))
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"3"}}}
+	require.Equal(t, 3*time.Second, r.delay(1, resp))
+	resp.Header.Set("Retry-After", "600")
+	require.Equal(t, 5*time.Second, r.delay(1, resp))
+
+	r.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := r.delay(2, nil)
+		require.GreaterOrEqual(t, d, time.Second)
+		require.LessOrEqual(t, d, 2*time.Second)
+	}
+}
+
+func testRetryAPI(ts *httptest.Server) *API {
+	parsed, _ := url.Parse(ts.URL)
+	calnexAPI := NewAPI(parsed.Host, true, time.Second)
+	calnexAPI.Client = ts.Client()
+	calnexAPI.Retry = Retry{Attempts: 3, Delay: time.Millisecond, MaxDelay: time.Millisecond}
+	return calnexAPI
+}
+
+func TestRetryBusy(t *testing.T) {
+	requests := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "{\"MeasurementActive\": true}")
+	}))
+	defer ts.Close()
+	calnexAPI := testRetryAPI(ts)
+
+	s, err := calnexAPI.FetchStatus()
+	require.NoError(t, err)
+	require.True(t, s.MeasurementActive)
+	require.Equal(t, 3, requests)
+
+	// give up after all attempts
+	requests = -10
+	_, err = calnexAPI.FetchStatus()
+	require.ErrorIs(t, err, ErrDeviceBusy)
+	require.Equal(t, -7, requests)
+}
+
+func TestRetryAuth(t *testing.T) {
+	requests := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+	calnexAPI := testRetryAPI(ts)
+
+	_, err := calnexAPI.FetchStatus()
+	require.ErrorIs(t, err, ErrAuth)
+	require.Equal(t, 1, requests)
+}
+
+func TestRetryPost(t *testing.T) {
+	var bodies []string
+	status := http.StatusServiceUnavailable
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies = append(bodies, string(b))
+		if len(bodies) == 1 {
+			w.WriteHeader(status)
+		}
+		fmt.Fprintln(w, "{\"result\": true}")
+	}))
+	defer ts.Close()
+	calnexAPI := testRetryAPI(ts)
+
+	f := ini.Empty()
+	f.Section("measure").Key("ch0\\used").SetValue("Yes")
+	// rejected by busy device, sent again
+	require.NoError(t, calnexAPI.PushSettings(f))
+	require.Equal(t, []string{"[measure]\nch0\\used=Yes\n", "[measure]\nch0\\used=Yes\n"}, bodies)
+
+	// device may have applied it, not sent again
+	bodies = nil
+	status = http.StatusBadGateway
+	require.EqualError(t, calnexAPI.PushSettings(f), "Bad Gateway")
+	require.Len(t, bodies, 1)
+}
+
+func TestRetryAttemptTimeout(t *testing.T) {
+	requests := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		requests++
+		if requests == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		fmt.Fprintln(w, "{\"MeasurementActive\": true}")
+	}))
+	defer ts.Close()
+	calnexAPI := testRetryAPI(ts)
+	calnexAPI.Retry.AttemptTimeout = 50 * time.Millisecond
+
+	s, err := calnexAPI.FetchStatus()
+	require.NoError(t, err)
+	require.True(t, s.MeasurementActive)
+	require.Equal(t, 2, requests)
+}
+
+func TestRetryRefused(t *testing.T) {
+	calnexAPI := NewAPI("localhost:1", true, time.Second)
+	calnexAPI.Retry.Delay = time.Hour
+	start := time.Now()
+	_, err := calnexAPI.FetchStatus()
+	require.Error(t, err)
+	require.Less(t, time.Since(start), time.Minute)
+}
@@ -0,0 +1,163 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	version "github.com/hashicorp/go-version"
+	log "github.com/sirupsen/logrus"
+)
+
+// Endpoints are URL formats of the device API calls
+type Endpoints struct {
+	Measure          string
+	Data             string
+	StartMeasure     string
+	StopMeasure      string
+	GetSettings      string
+	SetSettings      string
+	GetStatus        string
+	GetProblemReport string
+	ClearDevice      string
+	Reboot           string
+	Version          string
+	Uptime           string
+	Firmware         string
+	Certificate      string
+	License          string
+	GNSS             string
+	InstrumentStatus string
+	PowerSupply      string
+}
+
+var defaultEndpoints = Endpoints{
+	Measure:          measureURL,
+	Data:             dataURL,
+	StartMeasure:     startMeasure,
+	StopMeasure:      stopMeasure,
+	GetSettings:      getSettingsURL,
+	SetSettings:      setSettingsURL,
+	GetStatus:        getStatusURL,
+	GetProblemReport: getProblemReportURL,
+	ClearDevice:      clearDeviceURL,
+	Reboot:           rebootURL,
+	Version:          versionURL,
+	Uptime:           uptimeURL,
+	Firmware:         firmwareURL,
+	Certificate:      certificateURL,
+	License:          licenseURL,
+	GNSS:             gnssURL,
+	InstrumentStatus: instrumentStatusURL,
+	PowerSupply:      powerSupplyURL,
+}
+
+// FirmwareSupport describes API of firmware versions starting with MinVersion
+type FirmwareSupport struct {
+	MinVersion *version.Version
+	Note       string
+	// Adapt changes endpoints of the previous firmware range, nil if API didn't change
+	Adapt func(e *Endpoints)
+}
+
+// SupportMatrix is a list of supported firmware ranges, oldest first.
+// When device firmware changes its API, add a new range adapting the endpoints
+var SupportMatrix = []FirmwareSupport{
+	{MinVersion: version.Must(version.NewVersion("13.0")), Note: "Sentinel firmware"},
+	{MinVersion: version.Must(version.NewVersion("21.0")), Note: "combined firmware (calnex_combined_fw_R*)"},
+}
+
+// ErrUnsupportedFirmware is returned when device firmware is older than the support matrix
+var ErrUnsupportedFirmware = errors.New("firmware is not supported")
+
+// firmwareRegex matches numeric part of firmware version like 2.13.1.0.5583D-20210924 or R21.0.0.9705
+var firmwareRegex = regexp.MustCompile(`^([Rr]?)([0-9]+(\.[0-9]+)*)`)
+
+// ParseFirmwareVersion returns software version of the device firmware.
+// Sentinel versions are prefixed with hardware revision (2.13.1.0.5583 -> 13.1.0.5583), combined firmware versions with R.
+func ParseFirmwareVersion(firmware string) (*version.Version, error) {
+	m := firmwareRegex.FindStringSubmatch(strings.TrimSpace(firmware))
+	if m == nil {
+		return nil, fmt.Errorf("unrecognized firmware version %q", firmware)
+	}
+	vs := m[2]
+	if m[1] == "" && strings.Count(vs, ".") >= 4 {
+		vs = strings.SplitN(vs, ".", 2)[1]
+	}
+	return version.NewVersion(vs)
+}
+
+// Support returns the firmware range of the version and endpoints to use with it
+func Support(v *version.Version) (*FirmwareSupport, Endpoints, error) {
+	e := defaultEndpoints
+	var support *FirmwareSupport
+	for i, s := range SupportMatrix {
+		if v.LessThan(s.MinVersion) {
+			break
+		}
+		if s.Adapt != nil {
+			s.Adapt(&e)
+		}
+		support = &SupportMatrix[i]
+	}
+	if support == nil {
+		return nil, e, fmt.Errorf("%w: %s is older than %s", ErrUnsupportedFirmware, v, SupportMatrix[0].MinVersion)
+	}
+	return support, e, nil
+}
+
+// DetectFirmware fetches firmware version from the device and adapts API calls to it
+func (a *API) DetectFirmware() (*version.Version, error) {
+	fw, err := a.FetchVersion()
+	if err != nil {
+		return nil, err
+	}
+	v, err := ParseFirmwareVersion(fw.Firmware)
+	if err != nil {
+		return nil, err
+	}
+	support, endpoints, err := Support(v)
+	if err != nil {
+		return v, err
+	}
+	latest := SupportMatrix[len(SupportMatrix)-1].MinVersion
+	if v.Segments()[0] > latest.Segments()[0] {
+		log.Warningf("%s: firmware %s is newer than the support matrix, assuming %s API", a.source, v, support.Note)
+	} else {
+		log.Debugf("%s: firmware %s, using %s API", a.source, v, support.Note)
+	}
+	a.endpoints = endpoints
+	return v, nil
+}
+
+// Connect returns API adapted to the device firmware, retrying requests according to retry.
+// It fails only if firmware is known to be unsupported, default API is used if version can't be detected
+func Connect(source string, insecureTLS bool, timeout time.Duration, retry Retry) (*API, error) {
+	a := NewAPI(source, insecureTLS, timeout)
+	a.Retry = retry
+	if _, err := a.DetectFirmware(); err != nil {
+		if errors.Is(err, ErrUnsupportedFirmware) {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		log.Warningf("%s: failed to detect firmware version, using default API: %v", source, err)
+	}
+	return a, nil
+}
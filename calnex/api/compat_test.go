@@ -0,0 +1,132 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFirmwareVersion(t *testing.T) {
+	for firmware, expected := range map[string]string{
+		"2.13.1.0.5583D-20210924": "13.1.0.5583",
+		"2.17.0":                  "2.17.0",
+		"R21.0.0.9705":            "21.0.0.9705",
+		"r21.1.0.9800-20250101":   "21.1.0.9800",
+	} {
+		v, err := ParseFirmwareVersion(firmware)
+		require.NoError(t, err, firmware)
+		require.Equal(t, expected, v.String(), firmware)
+	}
+	_, err := ParseFirmwareVersion("latest")
+	require.EqualError(t, err, "unrecognized firmware version \"latest\"")
+}
+
+func TestSupport(t *testing.T) {
+	s, e, err := Support(version.Must(version.NewVersion("13.1.0.5583")))
+	require.NoError(t, err)
+	require.Equal(t, "Sentinel firmware", s.Note)
+	require.Equal(t, defaultEndpoints, e)
+
+	s, _, err = Support(version.Must(version.NewVersion("25.0")))
+	require.NoError(t, err)
+	require.Equal(t, &SupportMatrix[len(SupportMatrix)-1], s)
+
+	_, _, err = Support(version.Must(version.NewVersion("12.9")))
+	require.ErrorIs(t, err, ErrUnsupportedFirmware)
+	require.EqualError(t, err, "firmware is not supported: 12.9.0 is older than 13.0.0")
+}
+
+// withStatusMoved adds firmware range which moved status endpoint
+func withStatusMoved(t *testing.T) {
+	orig := SupportMatrix
+	SupportMatrix = append([]FirmwareSupport{}, orig...)
+	SupportMatrix = append(SupportMatrix, FirmwareSupport{
+		MinVersion: version.Must(version.NewVersion("22.0")),
+		Note:       "test firmware",
+		Adapt: func(e *Endpoints) {
+			e.GetStatus = "https://%s/api/v2/status"
+		},
+	})
+	t.Cleanup(func() { SupportMatrix = orig })
+}
+
+func TestDetectFirmware(t *testing.T) {
+	withStatusMoved(t)
+	firmware := "R22.0.0.1"
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		switch r.URL.Path {
+		case "/api/version":
+			fmt.Fprintf(w, "{\"firmware\": \"%s\"}\n", firmware)
+		case "/api/v2/status":
+			fmt.Fprintln(w, "{\"MeasurementActive\": true}")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	parsed, _ := url.Parse(ts.URL)
+	calnexAPI := NewAPI(parsed.Host, true, time.Second)
+	calnexAPI.Client = ts.Client()
+	_, err := calnexAPI.FetchStatus()
+	require.EqualError(t, err, "Not Found")
+
+	v, err := calnexAPI.DetectFirmware()
+	require.NoError(t, err)
+	require.Equal(t, "22.0.0.1", v.String())
+	s, err := calnexAPI.FetchStatus()
+	require.NoError(t, err)
+	require.True(t, s.MeasurementActive)
+
+	firmware = "2.12.0.0.1000"
+	_, err = calnexAPI.DetectFirmware()
+	require.ErrorIs(t, err, ErrUnsupportedFirmware)
+}
+
+func TestConnect(t *testing.T) {
+	firmware := "2.12.0.0.1000"
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		if r.URL.Path == "/api/version" {
+			fmt.Fprintf(w, "{\"firmware\": \"%s\"}\n", firmware)
+		}
+	}))
+	defer ts.Close()
+	parsed, _ := url.Parse(ts.URL)
+
+	_, err := Connect(parsed.Host, true, time.Second, DefaultRetry)
+	require.ErrorIs(t, err, ErrUnsupportedFirmware)
+
+	// default API is used if version is unknown
+	firmware = "unknown"
+	a, err := Connect(parsed.Host, true, time.Second, DefaultRetry)
+	require.NoError(t, err)
+	require.Equal(t, defaultEndpoints, a.endpoints)
+
+	a, err = Connect("localhost:1", true, time.Second, DefaultRetry)
+	require.NoError(t, err)
+	require.Equal(t, defaultEndpoints, a.endpoints)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	version "github.com/hashicorp/go-version"
+)
+
+// Device is a timing analyzer which can be driven by measurement automation.
+// Sentinel specific features like settings and firmware upgrade are only available from API
+type Device interface {
+	DetectFirmware() (*version.Version, error)
+	FetchChannelProbe(channel Channel) (*Probe, error)
+	FetchChannelTarget(channel Channel, probe Probe) (string, error)
+	FetchCsv(channel Channel, allData bool) ([][]string, error)
+	FetchInstrumentStatus() (*InstrumentStatus, error)
+	FetchStatus() (*Status, error)
+	FetchUsedChannels() ([]Channel, error)
+	GnssStatus() (*GNSS, error)
+	StartMeasure() error
+	StopMeasure() error
+}
+
+// Family is a family of devices sharing the API
+type Family string
+
+// Supported device families
+const (
+	FamilySentinel Family = "sentinel"
+	FamilyParagon  Family = "paragon"
+)
+
+// ErrBadFamily is returned when device family is not recognized
+var ErrBadFamily = errors.New("device family is not recognized")
+
+// ErrExperimentalFamily is returned when experimental device family is used without opting in
+var ErrExperimentalFamily = errors.New("device family is experimental")
+
+// Experimental returns whether API of the family is not verified against vendor documentation
+func (f Family) Experimental() bool {
+	return f == FamilyParagon
+}
+
+// FamilyFromString returns Family object from String version
+func FamilyFromString(value string) (Family, error) {
+	f := Family(strings.ToLower(value))
+	switch f {
+	case FamilySentinel, FamilyParagon:
+		return f, nil
+	}
+	return "", ErrBadFamily
+}
+
+// ConnectDevice returns device of the family adapted to its firmware
+func ConnectDevice(source string, insecureTLS bool, timeout time.Duration, retry Retry, family Family) (Device, error) {
+	switch family {
+	case FamilySentinel:
+		a, err := Connect(source, insecureTLS, timeout, retry)
+		if err != nil {
+			return nil, err
+		}
+		return a, nil
+	case FamilyParagon:
+		return ConnectParagon(source, insecureTLS, timeout, retry)
+	}
+	return nil, ErrBadFamily
+}
@@ -0,0 +1,56 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFamilyFromString(t *testing.T) {
+	for value, family := range map[string]Family{
+		"sentinel": FamilySentinel,
+		"Paragon":  FamilyParagon,
+	} {
+		f, err := FamilyFromString(value)
+		require.NoError(t, err)
+		require.Equal(t, family, f)
+	}
+	_, err := FamilyFromString("sentry")
+	require.ErrorIs(t, err, ErrBadFamily)
+}
+
+func TestFamilyExperimental(t *testing.T) {
+	require.False(t, FamilySentinel.Experimental())
+	require.True(t, FamilyParagon.Experimental())
+}
+
+func TestConnectDevice(t *testing.T) {
+	p, _ := testParagon(t)
+	d, err := ConnectDevice(p.api.source, true, time.Second, DefaultRetry, FamilyParagon)
+	require.NoError(t, err)
+	require.IsType(t, &Paragon{}, d)
+
+	d, err = ConnectDevice("localhost:1", true, time.Second, DefaultRetry, FamilySentinel)
+	require.NoError(t, err)
+	require.IsType(t, &API{}, d)
+
+	_, err = ConnectDevice("localhost:1", true, time.Second, DefaultRetry, Family("sentry"))
+	require.ErrorIs(t, err, ErrBadFamily)
+}
@@ -0,0 +1,338 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	version "github.com/hashicorp/go-version"
+	log "github.com/sirupsen/logrus"
+)
+
+// ParagonEndpoints are URL formats of the Paragon-neo and Paragon-x remote API.
+// They are not verified against vendor API documentation, which is why FamilyParagon is experimental
+type ParagonEndpoints struct {
+	Info   string
+	Status string
+	Start  string
+	Stop   string
+	Ports  string
+	Data   string
+	GNSS   string
+}
+
+var defaultParagonEndpoints = ParagonEndpoints{
+	Info:   "https://%s/api/v1/instrument/info",
+	Status: "https://%s/api/v1/measurement/status",
+	Start:  "https://%s/api/v1/measurement/start",
+	Stop:   "https://%s/api/v1/measurement/stop",
+	Ports:  "https://%s/api/v1/measurement/ports",
+	Data:   "https://%s/api/v1/measurement/data?port=%s&from=%d",
+	GNSS:   "https://%s/api/v1/gnss/status",
+}
+
+// ParagonModels are instrument models supported by Paragon
+var ParagonModels = []string{"Paragon-neo", "Paragon-x"}
+
+// ParagonInfo is a struct representing Paragon instrument info JSON response
+type ParagonInfo struct {
+	Model    string
+	Serial   string
+	Firmware string
+}
+
+// ParagonStatus is a struct representing Paragon measurement status JSON response
+type ParagonStatus struct {
+	Active    bool
+	Ready     bool
+	Reference bool
+}
+
+// ParagonPort is a struct representing a single measurement port of Paragon
+type ParagonPort struct {
+	Port    Channel
+	Enabled bool
+	Probe   Probe
+	Target  string
+	State   string
+	Type    string
+}
+
+// ParagonGNSS is a struct representing Paragon GNSS status JSON response
+type ParagonGNSS struct {
+	Antenna    string
+	Locked     bool
+	Satellites int
+}
+
+// Paragon is a Device accessing Paragon-neo and Paragon-x instruments.
+// Paragon API returns samples since the requested index, so unread data is tracked by the client
+// and can be persisted with PersistReadIndex
+type Paragon struct {
+	api       *API
+	endpoints ParagonEndpoints
+
+	sync.Mutex
+	read          map[Channel]int
+	readIndexFile string
+}
+
+// NewParagon returns a pointer of Paragon struct with default values.
+// Requests are sent with the API client, so they are retried the same way
+func NewParagon(source string, insecureTLS bool, timeout time.Duration, retry Retry) *Paragon {
+	a := NewAPI(source, insecureTLS, timeout)
+	a.Retry = retry
+	return &Paragon{
+		api:       a,
+		endpoints: defaultParagonEndpoints,
+		read:      map[Channel]int{},
+	}
+}
+
+// ConnectParagon returns Paragon after checking the instrument model.
+// Like Connect, it only warns if the instrument can't be identified
+func ConnectParagon(source string, insecureTLS bool, timeout time.Duration, retry Retry) (*Paragon, error) {
+	p := NewParagon(source, insecureTLS, timeout, retry)
+	if _, err := p.DetectFirmware(); err != nil {
+		log.Warningf("%s: failed to detect firmware version: %v", source, err)
+	}
+	return p, nil
+}
+
+// PersistReadIndex restores read index of every channel from the file and keeps it updated,
+// so unread data survives restarts of the tool
+func (p *Paragon) PersistReadIndex(path string) error {
+	read := map[Channel]int{}
+	b, err := os.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(b, &read); err != nil {
+			return fmt.Errorf("parsing read index %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.read = read
+	p.readIndexFile = path
+	return nil
+}
+
+// saveReadIndex writes read index of every channel to the file, replacing it atomically.
+// Must be called with the lock held
+func (p *Paragon) saveReadIndex() error {
+	b, err := json.Marshal(p.read)
+	if err != nil {
+		return err
+	}
+	tmp := p.readIndexFile + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.readIndexFile)
+}
+
+func (p *Paragon) fetchJSON(url string, v any) error {
+	resp, err := p.api.getURL(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// FetchInfo returns the instrument info
+func (p *Paragon) FetchInfo() (*ParagonInfo, error) {
+	i := &ParagonInfo{}
+	if err := p.fetchJSON(fmt.Sprintf(p.endpoints.Info, p.api.source), i); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// DetectFirmware fetches firmware version of the instrument, warning if the model is not a Paragon
+func (p *Paragon) DetectFirmware() (*version.Version, error) {
+	i, err := p.FetchInfo()
+	if err != nil {
+		return nil, err
+	}
+	v, err := version.NewVersion(i.Firmware)
+	if err != nil {
+		return nil, fmt.Errorf("unrecognized firmware version %q", i.Firmware)
+	}
+	known := false
+	for _, m := range ParagonModels {
+		known = known || m == i.Model
+	}
+	if !known {
+		log.Warningf("%s: unknown model %q, assuming Paragon API", p.api.source, i.Model)
+	} else {
+		log.Debugf("%s: %s firmware %s", p.api.source, i.Model, v)
+	}
+	return v, nil
+}
+
+// FetchPorts returns the measurement ports
+func (p *Paragon) FetchPorts() ([]ParagonPort, error) {
+	var ports []ParagonPort
+	if err := p.fetchJSON(fmt.Sprintf(p.endpoints.Ports, p.api.source), &ports); err != nil {
+		return nil, err
+	}
+	return ports, nil
+}
+
+func (p *Paragon) port(channel Channel) (*ParagonPort, error) {
+	ports, err := p.FetchPorts()
+	if err != nil {
+		return nil, err
+	}
+	for _, port := range ports {
+		if port.Port == channel {
+			return &port, nil
+		}
+	}
+	return nil, ErrBadChannel
+}
+
+// FetchChannelProbe returns monitored protocol of the channel
+func (p *Paragon) FetchChannelProbe(channel Channel) (*Probe, error) {
+	port, err := p.port(channel)
+	if err != nil {
+		return nil, err
+	}
+	return &port.Probe, nil
+}
+
+// FetchChannelTarget returns the measure target of the server monitored on the channel
+func (p *Paragon) FetchChannelTarget(channel Channel, _ Probe) (string, error) {
+	port, err := p.port(channel)
+	if err != nil {
+		return "", err
+	}
+	return port.Target, nil
+}
+
+// FetchUsedChannels returns list of channels in use
+func (p *Paragon) FetchUsedChannels() ([]Channel, error) {
+	channels := []Channel{}
+	ports, err := p.FetchPorts()
+	if err != nil {
+		return channels, err
+	}
+	for _, port := range ports {
+		if port.Enabled {
+			channels = append(channels, port.Port)
+		}
+	}
+	return channels, nil
+}
+
+// FetchInstrumentStatus returns state of every measurement port
+func (p *Paragon) FetchInstrumentStatus() (*InstrumentStatus, error) {
+	ports, err := p.FetchPorts()
+	if err != nil {
+		return nil, err
+	}
+	is := &InstrumentStatus{Channels: map[Channel]ChannelStatus{}}
+	for _, port := range ports {
+		is.Channels[port.Port] = ChannelStatus{Progress: -1, Slot: string(port.Port), State: port.State, Type: port.Type}
+	}
+	return is, nil
+}
+
+// FetchStatus returns the measurement status
+func (p *Paragon) FetchStatus() (*Status, error) {
+	s := &ParagonStatus{}
+	if err := p.fetchJSON(fmt.Sprintf(p.endpoints.Status, p.api.source), s); err != nil {
+		return nil, err
+	}
+	return &Status{
+		MeasurementActive: s.Active,
+		MeasurementReady:  s.Ready,
+		ModulesReady:      s.Ready,
+		ReferenceReady:    s.Reference,
+	}, nil
+}
+
+// GnssStatus returns current GNSS status
+func (p *Paragon) GnssStatus() (*GNSS, error) {
+	g := &ParagonGNSS{}
+	if err := p.fetchJSON(fmt.Sprintf(p.endpoints.GNSS, p.api.source), g); err != nil {
+		return nil, err
+	}
+	return &GNSS{AntennaStatus: g.Antenna, Locked: g.Locked, LockedSatellites: g.Satellites}, nil
+}
+
+// FetchCsv returns samples of the channel as CSV lines of timestamp and time error.
+// Without allData only samples which were not returned before are fetched
+func (p *Paragon) FetchCsv(channel Channel, allData bool) ([][]string, error) {
+	p.Lock()
+	from := p.read[channel]
+	p.Unlock()
+	if allData {
+		from = 0
+	}
+
+	b, err := p.api.download(fmt.Sprintf(p.endpoints.Data, p.api.source, channel, from))
+	if err != nil {
+		return nil, err
+	}
+	csvReader := csv.NewReader(bytes.NewReader(b))
+	csvReader.Comment = '#'
+	res, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, ErrNoNewData
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	p.read[channel] = from + len(res)
+	if p.readIndexFile != "" {
+		// data is already read, losing the index only means it's returned again after restart
+		if err := p.saveReadIndex(); err != nil {
+			log.Warningf("%s: failed to save read index: %v", p.api.source, err)
+		}
+	}
+	return res, nil
+}
+
+// StartMeasure starts measurement
+func (p *Paragon) StartMeasure() error {
+	_, err := p.api.post(fmt.Sprintf(p.endpoints.Start, p.api.source), &bytes.Buffer{})
+	return err
+}
+
+// StopMeasure stops measurement
+func (p *Paragon) StopMeasure() error {
+	_, err := p.api.post(fmt.Sprintf(p.endpoints.Stop, p.api.source), &bytes.Buffer{})
+	return err
+}
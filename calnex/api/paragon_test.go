@@ -0,0 +1,192 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const paragonPorts = `[
+{"Port": "1", "Enabled": true, "Probe": "PTP", "Target": "fd00:3016:3109:face:0:1:0", "State": "Measuring", "Type": "Paragon-neo"},
+{"Port": "2", "Enabled": false, "Probe": "NTP", "Target": "", "State": "Ready", "Type": "Paragon-neo"},
+{"Port": "A", "Enabled": true, "Probe": "PPS", "Target": "1 PPS", "State": "Ready", "Type": "Paragon-neo"}
+]`
+
+var paragonSamples = []string{
+	"1607961193.773740,-000.000000250501",
+	"1607961194.773740,-000.000000250502",
+	"1607961195.773740,-000.000000250503",
+}
+
+type fakeParagon struct {
+	samples int
+	started int
+	stopped int
+}
+
+func (f *fakeParagon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api/v1/instrument/info":
+		fmt.Fprintln(w, "{\"Model\": \"Paragon-neo\", \"Serial\": \"1234\", \"Firmware\": \"3.2.1\"}")
+	case "/api/v1/measurement/status":
+		fmt.Fprintf(w, "{\"Active\": %t, \"Ready\": true, \"Reference\": true}\n", f.started > f.stopped)
+	case "/api/v1/measurement/ports":
+		fmt.Fprintln(w, paragonPorts)
+	case "/api/v1/gnss/status":
+		fmt.Fprintln(w, "{\"Antenna\": \"OK\", \"Locked\": true, \"Satellites\": 11}")
+	case "/api/v1/measurement/data":
+		from, _ := strconv.Atoi(r.URL.Query().Get("from"))
+		for _, s := range paragonSamples[from:f.samples] {
+			fmt.Fprintln(w, s)
+		}
+	case "/api/v1/measurement/start":
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		f.started++
+		fmt.Fprintln(w, "{\"result\": true}")
+	case "/api/v1/measurement/stop":
+		f.stopped++
+		fmt.Fprintln(w, "{\"result\": true}")
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func testParagon(t *testing.T) (*Paragon, *fakeParagon) {
+	f := &fakeParagon{samples: 2}
+	ts := httptest.NewTLSServer(f)
+	t.Cleanup(ts.Close)
+	parsed, _ := url.Parse(ts.URL)
+	p, err := ConnectParagon(parsed.Host, true, time.Second, DefaultRetry)
+	require.NoError(t, err)
+	return p, f
+}
+
+func TestParagonDetectFirmware(t *testing.T) {
+	p, _ := testParagon(t)
+	v, err := p.DetectFirmware()
+	require.NoError(t, err)
+	require.Equal(t, "3.2.1", v.String())
+
+	_, err = NewParagon("localhost:1", true, time.Second, DefaultRetry).DetectFirmware()
+	require.Error(t, err)
+}
+
+func TestParagonChannels(t *testing.T) {
+	p, _ := testParagon(t)
+
+	channels, err := p.FetchUsedChannels()
+	require.NoError(t, err)
+	require.Equal(t, []Channel{ChannelONE, ChannelA}, channels)
+
+	probe, err := p.FetchChannelProbe(ChannelONE)
+	require.NoError(t, err)
+	require.Equal(t, ProbePTP, *probe)
+	target, err := p.FetchChannelTarget(ChannelONE, *probe)
+	require.NoError(t, err)
+	require.Equal(t, "fd00:3016:3109:face:0:1:0", target)
+
+	_, err = p.FetchChannelProbe(ChannelB)
+	require.ErrorIs(t, err, ErrBadChannel)
+
+	is, err := p.FetchInstrumentStatus()
+	require.NoError(t, err)
+	require.Len(t, is.Channels, 3)
+	require.Equal(t, "Measuring", is.Channels[ChannelONE].State)
+}
+
+func TestParagonStatus(t *testing.T) {
+	p, f := testParagon(t)
+
+	g, err := p.GnssStatus()
+	require.NoError(t, err)
+	require.Equal(t, &GNSS{AntennaStatus: "OK", Locked: true, LockedSatellites: 11}, g)
+
+	s, err := p.FetchStatus()
+	require.NoError(t, err)
+	require.Equal(t, &Status{MeasurementReady: true, ModulesReady: true, ReferenceReady: true}, s)
+
+	require.NoError(t, p.StartMeasure())
+	s, err = p.FetchStatus()
+	require.NoError(t, err)
+	require.True(t, s.MeasurementActive)
+	require.NoError(t, p.StopMeasure())
+	require.Equal(t, 1, f.stopped)
+}
+
+func TestParagonFetchCsv(t *testing.T) {
+	p, f := testParagon(t)
+
+	lines, err := p.FetchCsv(ChannelA, false)
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+
+	// nothing new yet
+	_, err = p.FetchCsv(ChannelA, false)
+	require.ErrorIs(t, err, ErrNoNewData)
+
+	f.samples = 3
+	lines, err = p.FetchCsv(ChannelA, false)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"1607961195.773740", "-000.000000250503"}}, lines)
+
+	lines, err = p.FetchCsv(ChannelA, true)
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+
+	// unread data is tracked per channel
+	lines, err = p.FetchCsv(ChannelONE, false)
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+}
+
+func TestParagonPersistReadIndex(t *testing.T) {
+	p, f := testParagon(t)
+	path := filepath.Join(t.TempDir(), "read.json")
+	require.NoError(t, p.PersistReadIndex(path))
+
+	lines, err := p.FetchCsv(ChannelA, false)
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+
+	// restarted tool only gets new data
+	restarted := NewParagon(p.api.source, true, time.Second, DefaultRetry)
+	restarted.api.Client = p.api.Client
+	require.NoError(t, restarted.PersistReadIndex(path))
+	_, err = restarted.FetchCsv(ChannelA, false)
+	require.ErrorIs(t, err, ErrNoNewData)
+
+	f.samples = 3
+	lines, err = restarted.FetchCsv(ChannelA, false)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"1607961195.773740", "-000.000000250503"}}, lines)
+
+	require.NoError(t, os.WriteFile(path, []byte("{\"Z\": 1}"), 0644))
+	require.Error(t, restarted.PersistReadIndex(path))
+}
@@ -0,0 +1,110 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/facebook/time/calnex/analyze"
+	"github.com/facebook/time/calnex/api"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(analyzeCmd)
+	analyzeCmd.Flags().BoolVar(&insecureTLS, "insecureTLS", false, "Ignore TLS certificate errors")
+	analyzeCmd.Flags().BoolVar(&jsonOutput, "json", false, "print results as JSON")
+	analyzeCmd.Flags().Var(&channels, "channel", "Channel name. Ex: 1, 2, C ,D, VP1. Repeat for multiple. Skip for auto-detection")
+	analyzeCmd.Flags().StringVar(&family, "family", string(api.FamilySentinel), "Device family. One of: sentinel, paragon")
+	analyzeCmd.Flags().BoolVar(&experimental, "experimental", false, "Allow experimental device families: paragon")
+	analyzeCmd.Flags().StringVar(&target, "device", "", "device to download measurement data from")
+	analyzeCmd.Flags().StringVar(&source, "file", "", "analyze measurement data from CSV file instead of the device")
+	analyzeCmd.Flags().StringVar(&mask, "mask", "prtc-a", fmt.Sprintf("mask to compare measurements to. One of: %s", analyze.MaskNames()))
+	analyzeCmd.MarkFlagsOneRequired("device", "file")
+	analyzeCmd.MarkFlagsMutuallyExclusive("device", "file")
+}
+
+func analyzeMeasurements() ([]*analyze.Result, error) {
+	m, err := analyze.MaskFromString(mask)
+	if err != nil {
+		return nil, err
+	}
+	if source == "" {
+		var chs []api.Channel
+		for _, channel := range channels {
+			chs = append(chs, channel)
+		}
+		d, err := connectDevice(2 * time.Minute)
+		if err != nil {
+			return nil, err
+		}
+		return analyze.AnalyzeDevice(d, target, chs, m)
+	}
+
+	points, err := analyze.PointsFromFile(source)
+	if err != nil {
+		return nil, err
+	}
+	r, err := analyze.Compute(points, m)
+	if err != nil {
+		return nil, err
+	}
+	r.Channel = source
+	return []*analyze.Result{r}, nil
+}
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "compute TIE, MTIE and TDEV of measurement data and compare them to the mask",
+	Run: func(_ *cobra.Command, _ []string) {
+		results, err := analyzeMeasurements()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if jsonOutput {
+			j, err := json.Marshal(results)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(string(j))
+		}
+		pass := true
+		for _, r := range results {
+			for _, v := range r.MTIE {
+				log.Debugf("%s: MTIE(%gs) = %.3fns", r.Channel, v.Tau, v.Value*1e9)
+			}
+			for _, v := range r.TDEV {
+				log.Debugf("%s: TDEV(%gs) = %.3fns", r.Channel, v.Tau, v.Value*1e9)
+			}
+			if r.Pass {
+				log.Infof("%s: max|TE| %.3fns, %s mask pass", r.Channel, r.MaxTE*1e9, r.Mask)
+				continue
+			}
+			pass = false
+			for _, f := range r.Failures() {
+				log.Errorf("%s: %s mask fail: %s", r.Channel, r.Mask, f)
+			}
+		}
+		if !pass {
+			log.Fatal(errors.New("measurement doesn't satisfy the mask"))
+		}
+	},
+}
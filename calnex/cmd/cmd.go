@@ -17,6 +17,9 @@ limitations under the License.
 package cmd
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/facebook/time/calnex/api"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -29,17 +32,60 @@ var RootCmd = &cobra.Command{
 }
 
 var (
-	allData     bool
-	apply       bool
-	channels    api.Channels
-	dir         string
-	force       bool
-	insecureTLS bool
-	saveConfig  string
-	source      string
-	target      string
+	allChannels  bool
+	allData      bool
+	apply        bool
+	channels     api.Channels
+	dir          string
+	duration     time.Duration
+	experimental bool
+	family       string
+	force        bool
+	format       string
+	insecureTLS  bool
+	interval     time.Duration
+	jsonOutput   bool
+	listen       string
+	mask         string
+	output       string
+	readIndex    string
+	reset        bool
+	saveConfig   string
+	source       string
+	startAt      string
+	target       string
+
+	// retry is the retry policy of every device the command connects to
+	retry = api.DefaultRetry
 )
 
+func init() {
+	RootCmd.PersistentFlags().IntVar(&retry.Attempts, "retries", api.DefaultRetry.Attempts, "Maximum number of attempts of every request to the device")
+	RootCmd.PersistentFlags().DurationVar(&retry.Delay, "retryDelay", api.DefaultRetry.Delay, "Delay before the first retry, doubled for every next one")
+	RootCmd.PersistentFlags().DurationVar(&retry.AttemptTimeout, "requestTimeout", api.DefaultRetry.AttemptTimeout, "Timeout of a single request attempt. 0 for no limit other than the command timeout")
+}
+
+// connectDevice connects to the target device of the family
+func connectDevice(timeout time.Duration) (api.Device, error) {
+	f, err := api.FamilyFromString(family)
+	if err != nil {
+		return nil, err
+	}
+	if f.Experimental() && !experimental {
+		return nil, fmt.Errorf("%w: %s, use --experimental to enable it", api.ErrExperimentalFamily, f)
+	}
+	d, err := api.ConnectDevice(target, insecureTLS, timeout, retry, f)
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := d.(*api.Paragon); ok && readIndex != "" {
+		if err := p.PersistReadIndex(readIndex); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
 // Execute is the main entry point for CLI interface
 func Execute() {
 	log.SetLevel(log.DebugLevel)
@@ -0,0 +1,38 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/facebook/time/calnex/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryFlags(t *testing.T) {
+	defer func() { retry = api.DefaultRetry }()
+	flags := RootCmd.PersistentFlags()
+	require.NoError(t, flags.Parse([]string{"--retries", "7", "--retryDelay", "3s"}))
+
+	require.Equal(t, 7, retry.Attempts)
+	require.Equal(t, 3*time.Second, retry.Delay)
+	require.Equal(t, api.DefaultRetry.MaxDelay, retry.MaxDelay)
+	// default policy of the library stays intact
+	require.Equal(t, 4, api.DefaultRetry.Attempts)
+	require.Equal(t, time.Second, api.DefaultRetry.Delay)
+}
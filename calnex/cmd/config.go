@@ -68,11 +68,11 @@ var configCmd = &cobra.Command{
 		}
 
 		if saveConfig != "" {
-			if err := config.Save(target, insecureTLS, dc, saveConfig); err != nil {
+			if err := config.Save(target, insecureTLS, retry, dc, saveConfig); err != nil {
 				log.Fatal(err)
 			}
 		} else {
-			if err := config.Config(target, insecureTLS, dc, apply); err != nil {
+			if err := config.Config(target, insecureTLS, retry, dc, apply); err != nil {
 				log.Fatal(err)
 			}
 		}
@@ -0,0 +1,62 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/facebook/time/calnex/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(discoverCmd)
+	discoverCmd.Flags().BoolVar(&allChannels, "all", false, "add installed channels which are not in use")
+	discoverCmd.Flags().BoolVar(&insecureTLS, "insecureTLS", false, "Ignore TLS certificate errors")
+	discoverCmd.Flags().StringVar(&target, "device", "", "device to discover")
+	discoverCmd.Flags().StringVar(&output, "output", "", "Output file. Skip for stdout")
+	if err := discoverCmd.MarkFlagRequired("device"); err != nil {
+		log.Fatal(err)
+	}
+}
+
+var discoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "generate config of a calnex appliance from installed modules and channels",
+	Run: func(_ *cobra.Command, _ []string) {
+		cs, err := config.Discover(target, insecureTLS, retry, allChannels)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		b, err := json.MarshalIndent(cs, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if output == "" {
+			fmt.Println(string(b))
+			return
+		}
+		if err := os.WriteFile(output, append(b, '\n'), 0644); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
@@ -17,7 +17,10 @@ limitations under the License.
 package cmd
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"time"
 
 	"github.com/facebook/time/calnex/api"
 	"github.com/facebook/time/calnex/export"
@@ -30,22 +33,80 @@ func init() {
 	exportCmd.Flags().BoolVar(&allData, "allData", true, "Export entire data from device every run. Set false for unread only")
 	exportCmd.Flags().BoolVar(&insecureTLS, "insecureTLS", false, "Ignore TLS certificate errors")
 	exportCmd.Flags().Var(&channels, "channel", "Channel name. Ex: 1, 2, C ,D, VP1. Repeat for multiple. Skip for auto-detection")
+	exportCmd.Flags().StringVar(&family, "family", string(api.FamilySentinel), "Device family. One of: sentinel, paragon")
+	exportCmd.Flags().BoolVar(&experimental, "experimental", false, "Allow experimental device families: paragon")
+	exportCmd.Flags().StringVar(&readIndex, "readIndex", "", "Paragon only. File to persist read index of the channels in, so unread data survives restarts")
 	exportCmd.Flags().StringVar(&target, "device", "localhost", "Source of the data. Ex: calnex01.example.com")
+	exportCmd.Flags().StringVar(&format, "format", "json", "Output format. One of: json, csv.gz, parquet")
+	exportCmd.Flags().StringVar(&output, "output", "", "Output file. Skip for stdout")
 	if err := exportCmd.MarkFlagRequired("device"); err != nil {
 		log.Fatal(err)
 	}
 }
 
+// exportLogger is a logger which has to be closed to finish the output
+type exportLogger interface {
+	export.Logger
+	Close() error
+}
+
+type jsonExportLogger struct {
+	export.JSONLogger
+}
+
+func (jsonExportLogger) Close() error {
+	return nil
+}
+
+func newExportLogger(out io.Writer) (exportLogger, error) {
+	metadata := map[string]string{
+		"source":   target,
+		"exported": time.Now().UTC().Format(time.RFC3339),
+	}
+	switch format {
+	case "json":
+		return jsonExportLogger{export.JSONLogger{Out: out}}, nil
+	case "csv.gz":
+		return export.NewGzipCSVLogger(out, metadata), nil
+	case "parquet":
+		return export.NewParquetLogger(out, metadata), nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+func exportData() error {
+	var chs []api.Channel
+	for _, channel := range channels {
+		chs = append(chs, channel)
+	}
+	out := os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	l, err := newExportLogger(out)
+	if err != nil {
+		return err
+	}
+	d, err := connectDevice(2 * time.Minute)
+	if err != nil {
+		return err
+	}
+	if err := export.ExportDevice(d, target, allData, chs, l); err != nil {
+		return err
+	}
+	return l.Close()
+}
+
 var exportCmd = &cobra.Command{
 	Use:   "export",
 	Short: "export calnex measurement data",
 	Run: func(_ *cobra.Command, _ []string) {
-		var chs []api.Channel
-		for _, channel := range channels {
-			chs = append(chs, channel)
-		}
-		l := export.JSONLogger{Out: os.Stdout}
-		if err := export.Export(target, insecureTLS, allData, chs, l); err != nil {
+		if err := exportData(); err != nil {
 			log.Fatal(err)
 		}
 	},
@@ -46,7 +46,7 @@ var firmwareCmd = &cobra.Command{
 			log.Fatal(err)
 		}
 		up := firmware.CalnexUpgrader{}
-		if err := up.Firmware(target, insecureTLS, fw, apply, force); err != nil {
+		if err := up.Firmware(target, insecureTLS, retry, fw, apply, force); err != nil {
 			log.Fatal(err)
 		}
 	},
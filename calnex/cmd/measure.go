@@ -0,0 +1,87 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io"
+	"time"
+
+	"github.com/facebook/time/calnex/api"
+	"github.com/facebook/time/calnex/measure"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(measureCmd)
+	measureCmd.Flags().BoolVar(&insecureTLS, "insecureTLS", false, "Ignore TLS certificate errors")
+	measureCmd.Flags().BoolVar(&reset, "reset", false, "Stop measurement which is already running and start from scratch")
+	measureCmd.Flags().DurationVar(&duration, "duration", 12*time.Hour, "Measurement duration")
+	measureCmd.Flags().DurationVar(&interval, "interval", time.Minute, "How often measurement is checked to be running")
+	measureCmd.Flags().StringVar(&startAt, "at", "", "Start time in RFC3339 format. Ex: 2024-01-01T22:00:00Z. Skip to start now")
+	measureCmd.Flags().StringVar(&family, "family", string(api.FamilySentinel), "Device family. One of: sentinel, paragon")
+	measureCmd.Flags().BoolVar(&experimental, "experimental", false, "Allow experimental device families: paragon")
+	measureCmd.Flags().StringVar(&target, "device", "", "device to measure with. Ex: calnex01.example.com")
+	measureCmd.Flags().StringVar(&format, "format", "json", "Output format of the results. One of: json, csv.gz, parquet")
+	measureCmd.Flags().StringVar(&output, "output", "", "Output file of the results. Skip for stdout")
+	if err := measureCmd.MarkFlagRequired("device"); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runMeasurement() error {
+	s := measure.Schedule{
+		Duration:      duration,
+		Reset:         reset,
+		CheckInterval: interval,
+	}
+	if startAt != "" {
+		at, err := time.Parse(time.RFC3339, startAt)
+		if err != nil {
+			return err
+		}
+		s.At = at
+	}
+	// fail early rather than after the measurement
+	if _, err := newExportLogger(io.Discard); err != nil {
+		return err
+	}
+
+	d, err := connectDevice(time.Minute)
+	if err != nil {
+		return err
+	}
+	measured, err := measure.RunDevice(d, target, s)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("%s: downloading results", target)
+	allData = true
+	channels = measured
+	return exportData()
+}
+
+var measureCmd = &cobra.Command{
+	Use:   "measure",
+	Short: "run scheduled measurement and download the results",
+	Run: func(_ *cobra.Command, _ []string) {
+		if err := runMeasurement(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
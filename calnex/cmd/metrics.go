@@ -0,0 +1,62 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/facebook/time/calnex/api"
+	"github.com/facebook/time/calnex/metrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(metricsCmd)
+	metricsCmd.Flags().BoolVar(&insecureTLS, "insecureTLS", false, "Ignore TLS certificate errors")
+	metricsCmd.Flags().Var(&channels, "channel", "Channel name. Ex: 1, 2, C ,D, VP1. Repeat for multiple. Skip for auto-detection")
+	metricsCmd.Flags().StringVar(&family, "family", string(api.FamilySentinel), "Device family. One of: sentinel, paragon")
+	metricsCmd.Flags().BoolVar(&experimental, "experimental", false, "Allow experimental device families: paragon")
+	metricsCmd.Flags().StringVar(&readIndex, "readIndex", "", "Paragon only. File to persist read index of the channels in, so unread data survives restarts")
+	metricsCmd.Flags().StringVar(&target, "device", "", "device to poll measurements from")
+	metricsCmd.Flags().DurationVar(&interval, "interval", time.Minute, "how often to poll the device")
+	metricsCmd.Flags().StringVar(&listen, "listen", ":9856", "address to serve JSON (/) and Prometheus (/metrics) metrics on")
+	if err := metricsCmd.MarkFlagRequired("device"); err != nil {
+		log.Fatal(err)
+	}
+}
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "poll live measurements and export them as metrics",
+	Long:  "poll data measured since the previous poll and export it as metrics. Don't run it alongside 'export --allData=false', both consume unread data",
+	Run: func(_ *cobra.Command, _ []string) {
+		var chs []api.Channel
+		for _, channel := range channels {
+			chs = append(chs, channel)
+		}
+		d, err := connectDevice(time.Minute)
+		if err != nil {
+			log.Fatal(err)
+		}
+		p := metrics.NewDevicePoller(d, target, chs)
+		go p.Run(interval)
+		log.Infof("Starting http server on %s", listen)
+		log.Fatal(http.ListenAndServe(listen, p.Handler()))
+	},
+}
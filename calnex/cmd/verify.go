@@ -44,7 +44,7 @@ var verifyCmd = &cobra.Command{
 			&checks.PSU{Remediation: checks.PSURemediation{}},
 			&checks.Module{Remediation: checks.ModuleRemediation{}},
 		}}
-		if err := verify.Verify(target, insecureTLS, v, apply); err != nil {
+		if err := verify.Verify(target, insecureTLS, retry, v, apply); err != nil {
 			log.Fatal(err)
 		}
 	},
@@ -194,9 +194,12 @@ func (c *config) baseConfig(target string, measure *ini.Section, gnss *ini.Secti
 }
 
 // Config configures target Calnex with Network/Calnex configs if apply is specified
-func Config(target string, insecureTLS bool, cc *CalnexConfig, apply bool) error {
+func Config(target string, insecureTLS bool, retry api.Retry, cc *CalnexConfig, apply bool) error {
 	var c config
-	api := api.NewAPI(target, insecureTLS, 4*time.Minute)
+	api, err := api.Connect(target, insecureTLS, 4*time.Minute, retry)
+	if err != nil {
+		return err
+	}
 
 	f, err := prepare(&c, api, target, cc)
 	if err != nil {
@@ -244,9 +247,12 @@ func Config(target string, insecureTLS bool, cc *CalnexConfig, apply bool) error
 }
 
 // Save saves the Network/Calnex configs to file
-func Save(target string, insecureTLS bool, cc *CalnexConfig, saveConfig string) error {
+func Save(target string, insecureTLS bool, retry api.Retry, cc *CalnexConfig, saveConfig string) error {
 	var c config
-	calnexAPI := api.NewAPI(target, insecureTLS, time.Minute)
+	calnexAPI, err := api.Connect(target, insecureTLS, time.Minute, retry)
+	if err != nil {
+		return err
+	}
 
 	f, err := prepare(&c, calnexAPI, target, cc)
 	if err != nil {
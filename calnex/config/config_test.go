@@ -610,14 +610,14 @@ ch30\ptp_synce\ptp\domain=0
 		},
 	}
 
-	err := Config(parsed.Host, true, cc, true)
+	err := Config(parsed.Host, true, api.DefaultRetry, cc, true)
 	require.NoError(t, err)
 }
 
 func TestConfigFail(t *testing.T) {
 	cc := &CalnexConfig{Measure: map[api.Channel]MeasureConfig{}}
 
-	err := Config("localhost", true, cc, true)
+	err := Config("localhost", true, api.DefaultRetry, cc, true)
 	require.Error(t, err)
 }
 
@@ -827,7 +827,7 @@ antenna_delay=42 ns
 	defer os.Remove(f.Name())
 	defer f.Close()
 
-	err = Save(parsed.Host, true, cc, f.Name())
+	err = Save(parsed.Host, true, api.DefaultRetry, cc, f.Name())
 	require.NoError(t, err)
 
 	savedConfig, err := os.ReadFile(f.Name())
@@ -845,7 +845,7 @@ func TestSaveConfigFail(t *testing.T) {
 	defer os.Remove(f.Name())
 	defer f.Close()
 
-	err = Save("localhost", true, cc, f.Name())
+	err = Save("localhost", true, api.DefaultRetry, cc, f.Name())
 	require.Error(t, err)
 }
 
@@ -0,0 +1,135 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/facebook/time/calnex/api"
+	"github.com/go-ini/ini"
+	log "github.com/sirupsen/logrus"
+)
+
+// Discover generates config of the target Calnex based on installed modules and channels.
+// Channels in use keep their current probe and target.
+// With all, installed channels which are not in use are added with default probe and empty target to be filled in.
+func Discover(target string, insecureTLS bool, retry api.Retry, all bool) (Calnexes, error) {
+	calnexAPI, err := api.Connect(target, insecureTLS, time.Minute, retry)
+	if err != nil {
+		return nil, err
+	}
+
+	is, err := calnexAPI.FetchInstrumentStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := calnexAPI.FetchSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	cc := discover(target, is, f, all)
+	return Calnexes{target: cc}, nil
+}
+
+// discover generates config out of instrument status and calnex settings
+func discover(target string, is *api.InstrumentStatus, f *ini.File, all bool) *CalnexConfig {
+	for m, module := range is.Modules {
+		if !api.ModuleReady(module.State) {
+			log.Warningf("%s: module %s (%s) is %s", target, m, module.Type, module.State)
+		}
+	}
+
+	m := f.Section("measure")
+	cc := &CalnexConfig{Measure: map[api.Channel]MeasureConfig{}}
+	for ch, datatype := range api.MeasureChannelDatatypeMap {
+		if m.Key(fmt.Sprintf("%s\\installed", ch.CalnexAPI())).String() != "1" {
+			continue
+		}
+
+		if _, ok := is.Channels[ch.Physical()]; !ok {
+			log.Debugf("%s: no module for channel %s", target, ch)
+			continue
+		}
+
+		mc, err := channelConfig(m, ch, datatype)
+		if err != nil {
+			log.Warningf("%s: channel %s: %v", target, ch, err)
+		}
+		used := m.Key(fmt.Sprintf("%s\\used", ch.CalnexAPI())).String() == api.YES
+		if (used && err == nil) || all {
+			cc.Measure[ch] = mc
+		}
+	}
+
+	delay, err := parseAntennaDelay(f.Section("gnss").Key("antenna_delay").String())
+	if err != nil {
+		log.Warningf("%s: %v", target, err)
+	}
+	cc.AntennaDelayNS = delay
+
+	return cc
+}
+
+// channelConfig returns the current config of the channel, or default probe if it's not set
+func channelConfig(m *ini.Section, ch api.Channel, datatype string) (MeasureConfig, error) {
+	probe := api.ProbePPS
+	probeKey := fmt.Sprintf("%s\\signal_type", ch.CalnexAPI())
+	if datatype == api.TWOWAYTE {
+		probe = api.ProbePTP
+		probeKey = fmt.Sprintf("%s\\ptp_synce\\mode\\probe_type", ch.CalnexAPI())
+	}
+
+	mc := MeasureConfig{Probe: probe}
+	name := m.Key(probeKey).String()
+	p, err := api.ProbeFromCalnexName(name)
+	if err != nil {
+		return mc, fmt.Errorf("unsupported probe %q", name)
+	}
+	mc.Probe = *p
+
+	targetKey := fmt.Sprintf("%s\\%s", ch.CalnexAPI(), p.ServerType())
+	if datatype == api.TWOWAYTE {
+		targetKey = fmt.Sprintf("%s\\ptp_synce\\%s\\%s", ch.CalnexAPI(), p.CalnexAPI(), p.ServerType())
+	}
+	mc.Target = m.Key(targetKey).String()
+	return mc, nil
+}
+
+// parseAntennaDelay parses antenna delay written by baseConfig, like "42 ns" or "1.5 us"
+func parseAntennaDelay(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	v, unit, _ := strings.Cut(value, " ")
+	d, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unsupported antenna delay %q", value)
+	}
+	switch unit {
+	case "ns":
+		return int(d), nil
+	case "us":
+		return int(math.Round(d * 1000)), nil
+	}
+	return 0, fmt.Errorf("unsupported antenna delay %q", value)
+}
@@ -0,0 +1,156 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/facebook/time/calnex/api"
+	"github.com/go-ini/ini"
+	"github.com/stretchr/testify/require"
+)
+
+// installedSettings returns settings of the device with all measurement channels installed
+func installedSettings(t *testing.T) *ini.File {
+	f := ini.Empty()
+	m, err := f.NewSection("measure")
+	require.NoError(t, err)
+	for ch := range api.MeasureChannelDatatypeMap {
+		_, err = m.NewKey(fmt.Sprintf("%s\\installed", ch.CalnexAPI()), "1")
+		require.NoError(t, err)
+	}
+	return f
+}
+
+var testInstrumentStatus = &api.InstrumentStatus{
+	Channels: map[api.Channel]api.ChannelStatus{
+		api.ChannelONE: {Slot: "1", State: "Ready", Type: "10G Packet Module (V2)"},
+		api.ChannelTWO: {Slot: "1", State: "Ready", Type: "10G Packet Module (V2)"},
+		api.ChannelA:   {Slot: "A", State: "Ready", Type: "Clock Module"},
+		api.ChannelB:   {Slot: "A", State: "Ready", Type: "Clock Module"},
+	},
+	Modules: map[api.Channel]api.ModuleStatus{
+		api.ChannelONE: {Channels: []string{"1", "2"}, State: "Ready", Type: "Packet Module (V2)"},
+		api.ChannelA:   {Channels: []string{"A", "B"}, State: "Ready", Type: "Clock Module"},
+	},
+}
+
+func TestDiscover(t *testing.T) {
+	expected := &CalnexConfig{
+		AntennaDelayNS: 4200,
+		Measure: map[api.Channel]MeasureConfig{
+			api.ChannelA: {
+				Target: "1 PPS",
+				Probe:  api.ProbePPS,
+			},
+			api.ChannelVP1: {
+				Target: "fd00:3226:301b::3f",
+				Probe:  api.ProbeNTP,
+			},
+			api.ChannelVP22: {
+				Target: "fd00:3016:3109:face:0:1:0",
+				Probe:  api.ProbePTP,
+			},
+		},
+	}
+
+	// discover what was configured
+	f := installedSettings(t)
+	c := &config{}
+	c.baseConfig("calnex01", f.Section("measure"), f.Section("gnss"), expected.AntennaDelayNS)
+	c.measureConfig("calnex01", f.Section("measure"), expected.Measure)
+	require.Equal(t, expected, discover("calnex01", testInstrumentStatus, f, false))
+
+	all := discover("calnex01", testInstrumentStatus, f, true)
+	// no module for C-F channels, all virtual ports are on the packet module
+	require.Len(t, all.Measure, 2+32)
+	require.Equal(t, expected.Measure[api.ChannelVP22], all.Measure[api.ChannelVP22])
+	require.Equal(t, MeasureConfig{Probe: api.ProbePPS}, all.Measure[api.ChannelB])
+	require.Equal(t, MeasureConfig{Probe: api.ProbePTP}, all.Measure[api.ChannelVP2])
+}
+
+func TestDiscoverNotInstalled(t *testing.T) {
+	f := ini.Empty()
+	m, err := f.NewSection("measure")
+	require.NoError(t, err)
+	_, err = m.NewKey("ch0\\installed", "1")
+	require.NoError(t, err)
+	_, err = m.NewKey("ch1\\installed", "0")
+	require.NoError(t, err)
+
+	cc := discover("calnex01", testInstrumentStatus, f, true)
+	require.Equal(t, &CalnexConfig{Measure: map[api.Channel]MeasureConfig{api.ChannelA: {Probe: api.ProbePPS}}}, cc)
+}
+
+func TestParseAntennaDelay(t *testing.T) {
+	for value, expected := range map[string]int{
+		"":       0,
+		"42 ns":  42,
+		"1 us":   1000,
+		"4.2 us": 4200,
+	} {
+		d, err := parseAntennaDelay(value)
+		require.NoError(t, err, value)
+		require.Equal(t, expected, d, value)
+	}
+
+	for _, value := range []string{"42", "4.2 ms", "a ns"} {
+		_, err := parseAntennaDelay(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestDiscoverDevice(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		switch r.URL.Path {
+		case "/api/version":
+			fmt.Fprintln(w, "{\"firmware\": \"R21.0.0.9705\"}")
+		case "/api/instrument/status":
+			require.NoError(t, json.NewEncoder(w).Encode(testInstrumentStatus))
+		case "/api/getsettings":
+			fmt.Fprintln(w, "[gnss]\nantenna_delay=42 ns\n[measure]\nch0\\installed=1\nch0\\used=Yes\nch0\\signal_type=1 PPS\nch0\\server_ip=1 PPS\nch9\\installed=1\nch9\\used=Yes\nch9\\ptp_synce\\mode\\probe_type=NTP\nch9\\ptp_synce\\ntp\\server_ip_ipv6=fd00:3226:301b::3f\nch10\\installed=1\nch10\\used=No\nch10\\ptp_synce\\mode\\probe_type=Disabled")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	parsed, _ := url.Parse(ts.URL)
+
+	cs, err := Discover(parsed.Host, true, api.DefaultRetry, false)
+	require.NoError(t, err)
+	expected := Calnexes{
+		parsed.Host: {
+			AntennaDelayNS: 42,
+			Measure: map[api.Channel]MeasureConfig{
+				api.ChannelA:   {Target: "1 PPS", Probe: api.ProbePPS},
+				api.ChannelVP1: {Target: "fd00:3226:301b::3f", Probe: api.ProbeNTP},
+			},
+		},
+	}
+	require.Equal(t, expected, cs)
+}
+
+func TestDiscoverFail(t *testing.T) {
+	_, err := Discover("localhost:1", true, api.DefaultRetry, false)
+	require.Error(t, err)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+)
+
+// Column describes a column of exported data
+type Column struct {
+	Name        string
+	Description string
+}
+
+// Schema is the list of columns in CSV and Parquet exports
+var Schema = []Column{
+	{Name: "time", Description: "measurement time, UTC"},
+	{Name: "value", Description: "time error, seconds"},
+	{Name: "channel", Description: "device channel, like A or VP1"},
+	{Name: "target", Description: "measured server"},
+	{Name: "protocol", Description: "probe type, PTP, NTP or PPS"},
+	{Name: "source", Description: "device the data was downloaded from"},
+}
+
+// sortedKeys returns metadata keys in stable order
+func sortedKeys(metadata map[string]string) []string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// GzipCSVLogger writes entries as gzip compressed CSV.
+// Schema and metadata are written as comment lines before the header
+type GzipCSVLogger struct {
+	gz       *gzip.Writer
+	csv      *csv.Writer
+	metadata map[string]string
+	started  bool
+}
+
+// NewGzipCSVLogger returns a new GzipCSVLogger writing to out
+func NewGzipCSVLogger(out io.Writer, metadata map[string]string) *GzipCSVLogger {
+	gz := gzip.NewWriter(out)
+	return &GzipCSVLogger{gz: gz, csv: csv.NewWriter(gz), metadata: metadata}
+}
+
+func (g *GzipCSVLogger) writeHeader() {
+	for _, k := range sortedKeys(g.metadata) {
+		fmt.Fprintf(g.gz, "# %s: %s\n", k, g.metadata[k])
+	}
+	header := make([]string, 0, len(Schema))
+	for _, c := range Schema {
+		fmt.Fprintf(g.gz, "# column %s: %s\n", c.Name, c.Description)
+		header = append(header, c.Name)
+	}
+	_ = g.csv.Write(header)
+	g.started = true
+}
+
+// PrintEntry writes entry as CSV line, time is unix time in seconds
+func (g *GzipCSVLogger) PrintEntry(e *Entry) {
+	if !g.started {
+		g.writeHeader()
+	}
+	_ = g.csv.Write([]string{
+		strconv.FormatFloat(e.Timestamp, 'f', 6, 64),
+		strconv.FormatFloat(e.Float.Value, 'g', -1, 64),
+		e.Normal.Channel,
+		e.Normal.Target,
+		e.Normal.Protocol,
+		e.Normal.Source,
+	})
+}
+
+// Close flushes the data and finishes gzip stream
+func (g *GzipCSVLogger) Close() error {
+	if !g.started {
+		g.writeHeader()
+	}
+	g.csv.Flush()
+	if err := g.csv.Error(); err != nil {
+		return err
+	}
+	return g.gz.Close()
+}
@@ -0,0 +1,73 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testEntry(timestamp, value float64) *Entry {
+	return &Entry{
+		Int:       &IntData{Time: int(timestamp)},
+		Float:     &FloatData{Value: value},
+		Normal:    &NormalData{Channel: "VP1", Target: "::1", Protocol: "PTP", Source: "calnex01"},
+		Timestamp: timestamp,
+	}
+}
+
+func TestGzipCSVLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewGzipCSVLogger(buf, map[string]string{"source": "calnex01", "exported": "2020-12-14T15:53:13Z"})
+	l.PrintEntry(testEntry(1607961193.77374, -2.50501e-7))
+	l.PrintEntry(testEntry(1607961194.77374, 1e-9))
+	require.NoError(t, l.Close())
+
+	gz, err := gzip.NewReader(buf)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	expected := `# exported: 2020-12-14T15:53:13Z
+# source: calnex01
+# column time: measurement time, UTC
+# column value: time error, seconds
+# column channel: device channel, like A or VP1
+# column target: measured server
+# column protocol: probe type, PTP, NTP or PPS
+# column source: device the data was downloaded from
+time,value,channel,target,protocol,source
+1607961193.773740,-2.50501e-07,VP1,::1,PTP,calnex01
+1607961194.773740,1e-09,VP1,::1,PTP,calnex01
+`
+	require.Equal(t, expected, string(data))
+}
+
+func TestGzipCSVLoggerEmpty(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewGzipCSVLogger(buf, nil)
+	require.NoError(t, l.Close())
+
+	gz, err := gzip.NewReader(buf)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "time,value,channel,target,protocol,source\n")
+}
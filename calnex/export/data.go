@@ -26,6 +26,8 @@ type Entry struct {
 	Float  *FloatData  `json:"double"`
 	Int    *IntData    `json:"int"`
 	Normal *NormalData `json:"normal"`
+	// Timestamp is full precision time in seconds, scribe only gets whole seconds
+	Timestamp float64 `json:"-"`
 }
 
 // FloatData data with floats
@@ -55,6 +57,10 @@ func entryFromCSV(csvLine []string, channel, target, protocol, source string) (*
 	if err != nil {
 		return nil, err
 	}
+	fullTimestamp, err := strconv.ParseFloat(csvLine[0], 64)
+	if err != nil {
+		return nil, err
+	}
 
 	intdata := &IntData{Time: int(timestamp)}
 
@@ -66,5 +72,5 @@ func entryFromCSV(csvLine []string, channel, target, protocol, source string) (*
 
 	normaldata := &NormalData{Channel: channel, Target: target, Protocol: protocol, Source: source}
 
-	return &Entry{Float: floatdata, Int: intdata, Normal: normaldata}, nil
+	return &Entry{Float: floatdata, Int: intdata, Normal: normaldata, Timestamp: fullTimestamp}, nil
 }
@@ -32,9 +32,10 @@ func TestEntryFromCSV(t *testing.T) {
 	protocol := "ptp"
 
 	expectedEntry := &Entry{
-		Int:    &IntData{Time: int(1599158325)},
-		Float:  &FloatData{Value: float64(-000.000006966500)},
-		Normal: &NormalData{Channel: channel, Target: target, Protocol: protocol, Source: source},
+		Int:       &IntData{Time: int(1599158325)},
+		Float:     &FloatData{Value: float64(-000.000006966500)},
+		Normal:    &NormalData{Channel: channel, Target: target, Protocol: protocol, Source: source},
+		Timestamp: 1599158325.368869,
 	}
 	entry, err := entryFromCSV(csvLine, channel, target, protocol, source)
 	require.Nil(t, err)
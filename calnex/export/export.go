@@ -18,8 +18,10 @@ package export
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/facebook/time/calnex/api"
@@ -46,11 +48,46 @@ func isHardFailure(err error) bool {
 	return false
 }
 
-// Export data from the device about specified channels to the specified output
-func Export(source string, insecureTLS bool, allData bool, channels []api.Channel, l Logger) (err error) {
-	var success bool
-	calnexAPI := api.NewAPI(source, insecureTLS, 2*time.Minute)
+// channelEntries fetches measurement data of the channel and converts it to entries.
+// Entries generated before a malformed line are returned along with the error
+func channelEntries(calnexAPI api.Device, source string, allData bool, channel api.Channel) ([]*Entry, error) {
+	probe, err := calnexAPI.FetchChannelProbe(channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch protocol from channel %s: %w", channel, err)
+	}
+	target, err := calnexAPI.FetchChannelTarget(channel, *probe)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch target from channel %s: %w", channel, err)
+	}
+	csvLines, err := calnexAPI.FetchCsv(channel, allData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch data from channel %s: %w", channel, err)
+	}
+
+	entries := make([]*Entry, 0, len(csvLines))
+	for _, csvLine := range csvLines {
+		entry, err := entryFromCSV(csvLine, string(channel), target, string(*probe), source)
+		if err != nil {
+			return entries, fmt.Errorf("failed to generate scribe line for channel %s: %w", channel, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
 
+// Export data from the Sentinel device about specified channels to the specified output
+func Export(source string, insecureTLS bool, allData bool, channels []api.Channel, l Logger) error {
+	calnexAPI, err := api.Connect(source, insecureTLS, 2*time.Minute, api.DefaultRetry)
+	if err != nil {
+		return err
+	}
+	return ExportDevice(calnexAPI, source, allData, channels, l)
+}
+
+// ExportDevice exports data from the device about specified channels to the specified output.
+// All channels are fetched concurrently
+func ExportDevice(calnexAPI api.Device, source string, allData bool, channels []api.Channel, l Logger) (err error) {
+	var success bool
 	if len(channels) == 0 {
 		channels, err = calnexAPI.FetchUsedChannels()
 		if err != nil {
@@ -58,49 +95,30 @@ func Export(source string, insecureTLS bool, allData bool, channels []api.Channe
 		}
 	}
 
-	for _, channel := range channels {
-		printSuccess := true
+	entries := make([][]*Entry, len(channels))
+	errs := make([]error, len(channels))
+	var wg sync.WaitGroup
+	for i, channel := range channels {
+		wg.Add(1)
+		go func(i int, channel api.Channel) {
+			defer wg.Done()
+			entries[i], errs[i] = channelEntries(calnexAPI, source, allData, channel)
+		}(i, channel)
+	}
+	wg.Wait()
 
-		probe, err := calnexAPI.FetchChannelProbe(channel)
-		if err != nil {
-			log.Warnf("%s: failed to fetch protocol from channel %s: %v", source, channel, err)
-			success = success || false
-			if isHardFailure(err) {
-				return err
-			}
-			continue
-		}
-		target, err := calnexAPI.FetchChannelTarget(channel, *probe)
-		if err != nil {
-			log.Warnf("%s: failed to fetch target from channel %s: %v", source, channel, err)
-			success = success || false
-			if isHardFailure(err) {
-				return err
-			}
-			continue
+	for i := range channels {
+		for _, entry := range entries[i] {
+			l.PrintEntry(entry)
 		}
-		csvLines, err := calnexAPI.FetchCsv(channel, allData)
-		if err != nil {
-			log.Warnf("%s: failed to fetch data from channel %s: %v", source, channel, err)
-			success = success || false
-			if isHardFailure(err) {
-				return err
+		if errs[i] != nil {
+			log.Warnf("%s: %v", source, errs[i])
+			if isHardFailure(errs[i]) {
+				return errs[i]
 			}
 			continue
 		}
-
-		for _, csvLine := range csvLines {
-			entry, err := entryFromCSV(csvLine, string(channel), target, string(*probe), source)
-			if err != nil {
-				printSuccess = false
-				success = success || printSuccess
-				log.Warnf("%s failed to generate scribe line for channel %s: %v", source, channel, err)
-				break
-			}
-
-			l.PrintEntry(entry)
-		}
-		success = success || printSuccess
+		success = true
 	}
 
 	if !success {
@@ -88,6 +88,68 @@ func TestExport(t *testing.T) {
 	require.ElementsMatch(t, expected, w.data)
 }
 
+func TestExportMalformed(t *testing.T) {
+	w := &writer{}
+	l := JSONLogger{Out: w}
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		if strings.Contains(r.URL.Path, "signal_type") {
+			fmt.Fprintln(w, "measure/ch0/signal_type=1 PPS")
+		} else if strings.Contains(r.URL.Path, "server_ip") {
+			fmt.Fprintln(w, "ch0/server_ip=127.0.0.1")
+		} else if r.URL.Query().Get("channel") == "A" {
+			fmt.Fprintln(w, "1607961193.773740,-000.000000250501\n1607961194.773740,foo\n1607961195.773740,-000.000000250503")
+		} else {
+			fmt.Fprintln(w, "1607961196.773740,-000.000000250504")
+		}
+	}))
+	defer ts.Close()
+
+	parsed, _ := url.Parse(ts.URL)
+	// entries before malformed line are still exported
+	err := Export(parsed.Host, true, true, []api.Channel{api.ChannelA}, l)
+	require.ErrorIs(t, err, errNoTarget)
+	require.Len(t, w.data, 1)
+	require.Contains(t, w.data[0], "1607961193")
+
+	// channels are exported in order
+	w.data = nil
+	err = Export(parsed.Host, true, true, []api.Channel{api.ChannelA, api.ChannelB}, l)
+	require.NoError(t, err)
+	require.Len(t, w.data, 2)
+	require.Contains(t, w.data[1], "1607961196")
+}
+
+func TestExportParagon(t *testing.T) {
+	w := &writer{}
+	l := JSONLogger{Out: w}
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/instrument/info":
+			fmt.Fprintln(w, "{\"Model\": \"Paragon-x\", \"Firmware\": \"2.0\"}")
+		case "/api/v1/measurement/ports":
+			fmt.Fprintln(w, "[{\"Port\": \"1\", \"Enabled\": true, \"Probe\": \"PTP\", \"Target\": \"127.0.0.1\"}]")
+		case "/api/v1/measurement/data":
+			fmt.Fprintln(w, "1607961194.773740,-000.000000250504")
+		}
+	}))
+	defer ts.Close()
+
+	parsed, _ := url.Parse(ts.URL)
+	d, err := api.ConnectDevice(parsed.Host, true, time.Second, api.DefaultRetry, api.FamilyParagon)
+	require.NoError(t, err)
+
+	expected := []string{
+		fmt.Sprintf("{\"double\":{\"value\":-2.50504e-7},\"int\":{\"time\":1607961194},\"normal\":{\"channel\":\"1\",\"target\":\"127.0.0.1\",\"protocol\":\"PTP\",\"source\":\"%s\"}}\n", parsed.Host),
+	}
+	err = ExportDevice(d, parsed.Host, true, nil, l)
+	require.NoError(t, err)
+	require.Equal(t, expected, w.data)
+}
+
 func TestExportFail(t *testing.T) {
 	err := Export("localhost", true, true, []api.Channel{}, nil)
 	require.ErrorIs(t, errNoUsedChannels, err)
@@ -0,0 +1,338 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// Parquet format constants, see https://github.com/apache/parquet-format
+const (
+	parquetMagic = "PAR1"
+
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetRequired = 0
+
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+
+	parquetPlain     = 0
+	parquetRLE       = 3
+	parquetDataPage  = 0
+	parquetCodecGzip = 2
+
+	// entries in a row group, so the whole file doesn't have to be kept in memory
+	parquetRowGroupSize = 1 << 20
+)
+
+// thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes thrift compact protocol, which is used for Parquet metadata
+type thriftWriter struct {
+	bytes.Buffer
+	lastField []int16
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{lastField: []int16{0}}
+}
+
+func (w *thriftWriter) uvarint(v uint64) {
+	w.Write(binary.AppendUvarint(nil, v))
+}
+
+func (w *thriftWriter) varint(v int64) {
+	w.Write(binary.AppendVarint(nil, v))
+}
+
+func (w *thriftWriter) field(id int16, t byte) {
+	last := &w.lastField[len(w.lastField)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.WriteByte(byte(delta)<<4 | t)
+	} else {
+		w.WriteByte(t)
+		w.varint(int64(id))
+	}
+	*last = id
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.varint(int64(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.varint(v)
+}
+
+func (w *thriftWriter) rawString(s string) {
+	w.uvarint(uint64(len(s)))
+	w.WriteString(s)
+}
+
+func (w *thriftWriter) string(id int16, s string) {
+	w.field(id, thriftBinary)
+	w.rawString(s)
+}
+
+func (w *thriftWriter) list(id int16, elemType byte, size int) {
+	w.field(id, thriftList)
+	if size < 15 {
+		w.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	w.WriteByte(0xf0 | elemType)
+	w.uvarint(uint64(size))
+}
+
+// beginStruct starts struct value, either a field or a list element
+func (w *thriftWriter) beginStruct() {
+	w.lastField = append(w.lastField, 0)
+}
+
+func (w *thriftWriter) structField(id int16) {
+	w.field(id, thriftStruct)
+	w.beginStruct()
+}
+
+func (w *thriftWriter) endStruct() {
+	w.WriteByte(0)
+	w.lastField = w.lastField[:len(w.lastField)-1]
+}
+
+// parquetColumn is a column of the current row group
+type parquetColumn struct {
+	name          string
+	physicalType  int32
+	convertedType int32
+	values        bytes.Buffer
+}
+
+// parquetColumnChunk is metadata of a written column chunk
+type parquetColumnChunk struct {
+	column           *parquetColumn
+	offset           int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+type parquetRowGroup struct {
+	chunks []parquetColumnChunk
+	rows   int64
+}
+
+// ParquetLogger writes entries to Parquet file, one gzip compressed data page per column in a row group.
+// Entries are buffered, so Close must be called to write the file footer
+type ParquetLogger struct {
+	out       io.Writer
+	metadata  map[string]string
+	columns   []*parquetColumn
+	rows      int64
+	offset    int64
+	rowGroups []parquetRowGroup
+	err       error
+	// rowGroupSize is the number of entries in a row group
+	rowGroupSize int64
+}
+
+// NewParquetLogger returns a new ParquetLogger writing to out
+func NewParquetLogger(out io.Writer, metadata map[string]string) *ParquetLogger {
+	p := &ParquetLogger{out: out, metadata: metadata, rowGroupSize: parquetRowGroupSize}
+	for _, c := range Schema {
+		col := &parquetColumn{name: c.Name, physicalType: parquetByteArray, convertedType: parquetUTF8}
+		switch c.Name {
+		case "time":
+			col.physicalType, col.convertedType = parquetInt64, parquetTimestampMicros
+		case "value":
+			col.physicalType, col.convertedType = parquetDouble, -1
+		}
+		p.columns = append(p.columns, col)
+	}
+	p.write([]byte(parquetMagic))
+	return p
+}
+
+func (p *ParquetLogger) write(b []byte) {
+	if p.err != nil {
+		return
+	}
+	n, err := p.out.Write(b)
+	p.offset += int64(n)
+	p.err = err
+}
+
+// PrintEntry adds entry to the current row group
+func (p *ParquetLogger) PrintEntry(e *Entry) {
+	values := []any{
+		int64(math.Round(e.Timestamp * 1e6)),
+		e.Float.Value,
+		e.Normal.Channel,
+		e.Normal.Target,
+		e.Normal.Protocol,
+		e.Normal.Source,
+	}
+	for i, v := range values {
+		buf := &p.columns[i].values
+		switch v := v.(type) {
+		case int64:
+			_ = binary.Write(buf, binary.LittleEndian, v)
+		case float64:
+			_ = binary.Write(buf, binary.LittleEndian, math.Float64bits(v))
+		case string:
+			_ = binary.Write(buf, binary.LittleEndian, uint32(len(v)))
+			buf.WriteString(v)
+		}
+	}
+	p.rows++
+	if p.rows == p.rowGroupSize {
+		p.flush()
+	}
+}
+
+// flush writes buffered entries as a row group
+func (p *ParquetLogger) flush() {
+	if p.rows == 0 {
+		return
+	}
+	rg := parquetRowGroup{rows: p.rows}
+	for _, c := range p.columns {
+		compressed := &bytes.Buffer{}
+		gz := gzip.NewWriter(compressed)
+		_, _ = gz.Write(c.values.Bytes())
+		_ = gz.Close()
+
+		h := newThriftWriter()
+		h.i32(1, parquetDataPage)
+		h.i32(2, int32(c.values.Len()))
+		h.i32(3, int32(compressed.Len()))
+		h.structField(5)
+		h.i32(1, int32(p.rows))
+		h.i32(2, parquetPlain)
+		h.i32(3, parquetRLE)
+		h.i32(4, parquetRLE)
+		h.endStruct()
+		h.endStruct()
+
+		chunk := parquetColumnChunk{
+			column:           c,
+			offset:           p.offset,
+			uncompressedSize: int64(h.Len() + c.values.Len()),
+			compressedSize:   int64(h.Len() + compressed.Len()),
+		}
+		p.write(h.Bytes())
+		p.write(compressed.Bytes())
+		rg.chunks = append(rg.chunks, chunk)
+		c.values.Reset()
+	}
+	p.rowGroups = append(p.rowGroups, rg)
+	p.rows = 0
+}
+
+// footer returns encoded FileMetaData
+func (p *ParquetLogger) footer() []byte {
+	var total int64
+	for _, rg := range p.rowGroups {
+		total += rg.rows
+	}
+	w := newThriftWriter()
+	w.i32(1, 1)
+	w.list(2, thriftStruct, len(p.columns)+1)
+	w.beginStruct()
+	w.string(4, "schema")
+	w.i32(5, int32(len(p.columns)))
+	w.endStruct()
+	for _, c := range p.columns {
+		w.beginStruct()
+		w.i32(1, c.physicalType)
+		w.i32(3, parquetRequired)
+		w.string(4, c.name)
+		if c.convertedType >= 0 {
+			w.i32(6, c.convertedType)
+		}
+		w.endStruct()
+	}
+	w.i64(3, total)
+	w.list(4, thriftStruct, len(p.rowGroups))
+	for _, rg := range p.rowGroups {
+		w.beginStruct()
+		w.list(1, thriftStruct, len(rg.chunks))
+		var size int64
+		for _, chunk := range rg.chunks {
+			size += chunk.uncompressedSize
+			w.beginStruct()
+			w.i64(2, chunk.offset)
+			w.structField(3)
+			w.i32(1, chunk.column.physicalType)
+			w.list(2, thriftI32, 1)
+			w.varint(parquetPlain)
+			w.list(3, thriftBinary, 1)
+			w.rawString(chunk.column.name)
+			w.i32(4, parquetCodecGzip)
+			w.i64(5, rg.rows)
+			w.i64(6, chunk.uncompressedSize)
+			w.i64(7, chunk.compressedSize)
+			w.i64(9, chunk.offset)
+			w.endStruct()
+			w.endStruct()
+		}
+		w.i64(2, size)
+		w.i64(3, rg.rows)
+		w.endStruct()
+	}
+	keys := sortedKeys(p.metadata)
+	w.list(5, thriftStruct, len(keys)+len(Schema))
+	for _, k := range keys {
+		w.beginStruct()
+		w.string(1, k)
+		w.string(2, p.metadata[k])
+		w.endStruct()
+	}
+	for _, c := range Schema {
+		w.beginStruct()
+		w.string(1, "column."+c.Name)
+		w.string(2, c.Description)
+		w.endStruct()
+	}
+	w.string(6, "github.com/facebook/time/calnex")
+	w.endStruct()
+	return w.Bytes()
+}
+
+// Close writes remaining entries and the file footer
+func (p *ParquetLogger) Close() error {
+	p.flush()
+	footer := p.footer()
+	p.write(footer)
+	p.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	p.write([]byte(parquetMagic))
+	return p.err
+}
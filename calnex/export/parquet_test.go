@@ -0,0 +1,175 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/deprecated"
+	"github.com/parquet-go/parquet-go/format"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThriftWriter(t *testing.T) {
+	w := newThriftWriter()
+	w.i32(1, 1)
+	w.i64(3, -1)
+	w.string(4, "ab")
+	w.structField(5)
+	w.i32(1, 300)
+	w.endStruct()
+	w.list(6, thriftI32, 2)
+	w.varint(1)
+	w.varint(2)
+	w.list(7, thriftStruct, 15)
+	// field id delta is too large for short form
+	w.i32(30, 0)
+	w.endStruct()
+	require.Equal(t, []byte{
+		0x15, 0x02,
+		0x26, 0x01,
+		0x18, 0x02, 'a', 'b',
+		0x1c, 0x15, 0xd8, 0x04, 0x00,
+		0x19, 0x25, 0x02, 0x04,
+		0x19, 0xfc, 0x0f,
+		0x05, 0x3c, 0x00,
+		0x00,
+	}, w.Bytes())
+}
+
+func TestParquetLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := NewParquetLogger(buf, map[string]string{"source": "calnex01"})
+	p.PrintEntry(testEntry(1607961193.77374, -2.50501e-7))
+	p.PrintEntry(testEntry(1607961194.77374, 1e-9))
+	require.NoError(t, p.Close())
+
+	b := buf.Bytes()
+	require.Equal(t, parquetMagic, string(b[:4]))
+	require.Equal(t, parquetMagic, string(b[len(b)-4:]))
+	footerLen := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	footer := b[len(b)-8-footerLen : len(b)-8]
+	require.Contains(t, string(footer), "column.value")
+	require.Contains(t, string(footer), "calnex01")
+
+	require.Len(t, p.rowGroups, 1)
+	rg := p.rowGroups[0]
+	require.Equal(t, int64(2), rg.rows)
+	require.Len(t, rg.chunks, len(Schema))
+
+	// time column is the first chunk, right after the magic
+	chunk := rg.chunks[0]
+	require.Equal(t, int64(4), chunk.offset)
+	headerLen := chunk.uncompressedSize - 16
+	gz, err := gzip.NewReader(bytes.NewReader(b[chunk.offset+headerLen : chunk.offset+chunk.compressedSize]))
+	require.NoError(t, err)
+	values, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	require.Equal(t, int64(1607961193773740), int64(binary.LittleEndian.Uint64(values[:8])))
+	require.Equal(t, int64(1607961194773740), int64(binary.LittleEndian.Uint64(values[8:])))
+
+	// next chunk follows
+	require.Equal(t, chunk.offset+chunk.compressedSize, rg.chunks[1].offset)
+}
+
+// parquetRow is how an independent Parquet reader sees the entry
+type parquetRow struct {
+	Time     int64   `parquet:"time"`
+	Value    float64 `parquet:"value"`
+	Channel  string  `parquet:"channel"`
+	Target   string  `parquet:"target"`
+	Protocol string  `parquet:"protocol"`
+	Source   string  `parquet:"source"`
+}
+
+func TestParquetLoggerReader(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := NewParquetLogger(buf, map[string]string{"source": "calnex01"})
+	p.rowGroupSize = 2
+	var want []parquetRow
+	for i := 0; i < 5; i++ {
+		ts := 1607961193.77374 + float64(i)
+		p.PrintEntry(testEntry(ts, float64(i)*1e-9))
+		want = append(want, parquetRow{
+			Time:     1607961193773740 + int64(i)*1000000,
+			Value:    float64(i) * 1e-9,
+			Channel:  "VP1",
+			Target:   "::1",
+			Protocol: "PTP",
+			Source:   "calnex01",
+		})
+	}
+	require.NoError(t, p.Close())
+
+	r := bytes.NewReader(buf.Bytes())
+	f, err := parquet.OpenFile(r, r.Size())
+	require.NoError(t, err)
+
+	// footer
+	require.Equal(t, int64(5), f.NumRows())
+	require.Equal(t, "github.com/facebook/time/calnex", f.Metadata().CreatedBy)
+	v, ok := f.Lookup("source")
+	require.True(t, ok)
+	require.Equal(t, "calnex01", v)
+	v, ok = f.Lookup("column.value")
+	require.True(t, ok)
+	require.Equal(t, "time error, seconds", v)
+
+	// schema
+	fields := f.Schema().Fields()
+	require.Len(t, fields, len(Schema))
+	for i, c := range Schema {
+		require.Equal(t, c.Name, fields[i].Name())
+		require.True(t, fields[i].Required())
+	}
+	require.Equal(t, parquet.Int64, fields[0].Type().Kind())
+	require.Equal(t, deprecated.TimestampMicros, *f.Metadata().Schema[1].ConvertedType)
+	require.Equal(t, deprecated.UTF8, *f.Metadata().Schema[3].ConvertedType)
+	require.Equal(t, parquet.Double, fields[1].Type().Kind())
+	require.Equal(t, parquet.ByteArray, fields[2].Type().Kind())
+
+	// row groups
+	rgs := f.RowGroups()
+	require.Len(t, rgs, 3)
+	for i, n := range []int64{2, 2, 1} {
+		require.Equal(t, n, rgs[i].NumRows())
+		for _, chunk := range f.Metadata().RowGroups[i].Columns {
+			require.Equal(t, format.Gzip, chunk.MetaData.Codec)
+		}
+	}
+
+	got, err := parquet.Read[parquetRow](r, r.Size())
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(_ []byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
+
+func TestParquetLoggerError(t *testing.T) {
+	p := NewParquetLogger(failingWriter{}, nil)
+	p.PrintEntry(testEntry(1607961193.77374, -2.50501e-7))
+	require.ErrorIs(t, p.Close(), io.ErrShortWrite)
+}
@@ -18,7 +18,6 @@ package firmware
 
 import (
 	"fmt"
-	"strings"
 	"sync"
 	"time"
 
@@ -37,7 +36,7 @@ type FW interface {
 
 // CalnexUpgraderInterface represents an upgradeable firmware
 type CalnexUpgraderInterface interface {
-	Firmware(target string, insecureTLS bool, fw FW, apply bool, force bool) error
+	Firmware(target string, insecureTLS bool, retry calnexAPI.Retry, fw FW, apply bool, force bool) error
 	InProgress(target string, api *calnexAPI.API) (bool, error)
 	ShouldUpgrade(target string, api *calnexAPI.API, fw FW, force bool) (bool, error)
 }
@@ -51,9 +50,7 @@ func (up CalnexUpgrader) ShouldUpgrade(target string, api *calnexAPI.API, fw FW,
 	if err != nil {
 		return false, err
 	}
-	//remove major version 2.17.0 -> 17.0 as this is hadware revision related
-	//calnexVersion, err := version.NewVersion(strings.ToLower(cv.Firmware))
-	calnexVersion, err := version.NewVersion(strings.ToLower(strings.SplitN(cv.Firmware, ".", 2)[1]))
+	calnexVersion, err := calnexAPI.ParseFirmwareVersion(cv.Firmware)
 	if err != nil {
 		return false, err
 	}
@@ -84,8 +81,9 @@ func (up CalnexUpgrader) InProgress(target string, api *calnexAPI.API) (bool, er
 
 // Firmware checks target Calnex firmware version and upgrades if apply is specified
 // Returns err if there is a failure at any point in the process
-func (up CalnexUpgrader) Firmware(target string, insecureTLS bool, fw FW, apply bool, force bool) error {
+func (up CalnexUpgrader) Firmware(target string, insecureTLS bool, retry calnexAPI.Retry, fw FW, apply bool, force bool) error {
 	api := calnexAPI.NewAPI(target, insecureTLS, 4*time.Minute)
+	api.Retry = retry
 
 	shouldUpgrade, err := up.ShouldUpgrade(target, api, fw, force)
 	if err != nil {
@@ -130,7 +128,7 @@ func (up CalnexUpgrader) Firmware(target string, insecureTLS bool, fw FW, apply
 
 // ParallelFirmwareUpgrade upgrades the provided list of devices in parallel
 // Returns a slice of errors, which contains an error for each device that failed to upgrade
-func ParallelFirmwareUpgrade(devices []string, insecureTLS bool, fw FW, ufw CalnexUpgraderInterface, apply bool, force bool) []error {
+func ParallelFirmwareUpgrade(devices []string, insecureTLS bool, retry calnexAPI.Retry, fw FW, ufw CalnexUpgraderInterface, apply bool, force bool) []error {
 	var wg = sync.WaitGroup{}
 	errors := make([]error, 0, len(devices))
 	errorMutex := sync.Mutex{}
@@ -139,7 +137,7 @@ func ParallelFirmwareUpgrade(devices []string, insecureTLS bool, fw FW, ufw Caln
 		device := devices[i]
 		go func(device string) {
 			defer wg.Done()
-			err := ufw.Firmware(device, insecureTLS, fw, apply, force)
+			err := ufw.Firmware(device, insecureTLS, retry, fw, apply, force)
 			if err != nil {
 				errorMutex.Lock()
 				errors = append(errors, fmt.Errorf("%s: error during firmware upgrade: %w", device, err))
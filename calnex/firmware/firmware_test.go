@@ -71,7 +71,7 @@ func TestFirmware(t *testing.T) {
 	calnexAPI.Client = ts.Client()
 
 	up := CalnexUpgrader{}
-	err = up.Firmware(parsed.Host, true, fw, true, false)
+	err = up.Firmware(parsed.Host, true, api.DefaultRetry, fw, true, false)
 	require.NoError(t, err)
 }
 
@@ -114,7 +114,7 @@ func TestFirmwareForce(t *testing.T) {
 	calnexAPI.Client = ts.Client()
 
 	up := CalnexUpgrader{}
-	err = up.Firmware(parsed.Host, true, fw, true, true)
+	err = up.Firmware(parsed.Host, true, api.DefaultRetry, fw, true, true)
 	require.NoError(t, err)
 }
 
@@ -157,19 +157,43 @@ func TestFirmwareInProgress(t *testing.T) {
 	calnexAPI.Client = ts.Client()
 
 	up := CalnexUpgrader{}
-	err = up.Firmware(parsed.Host, true, fw, true, false)
+	err = up.Firmware(parsed.Host, true, api.DefaultRetry, fw, true, false)
 	require.NoError(t, err)
 }
 
+func TestShouldUpgrade(t *testing.T) {
+	fw, err := NewOSSFW("/tmp/calnex_combined_fw_R21.0.0.9705-20241111.tar")
+	require.NoError(t, err)
+
+	for firmware, want := range map[string]bool{
+		"2.13.1.0.5583D-20210924": true,
+		"R20.1.0.9000":            true,
+		"R21.0.0.9705":            false,
+		"R21.1.0.100":             false,
+	} {
+		ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			fmt.Fprintf(w, "{ \"firmware\": %q }", firmware)
+		}))
+		parsed, _ := url.Parse(ts.URL)
+		calnexAPI := api.NewAPI(parsed.Host, true, time.Second)
+		calnexAPI.Client = ts.Client()
+
+		got, err := CalnexUpgrader{}.ShouldUpgrade(parsed.Host, calnexAPI, fw, false)
+		ts.Close()
+		require.NoError(t, err)
+		require.Equal(t, want, got, firmware)
+	}
+}
+
 func TestParallelFirmwareUpgrade(t *testing.T) {
 	// Should call Firmware once per device and return errors for devices that fail
 	mockUpgrader := new(MockCalnexUpgrader)
-	mockUpgrader.On("Firmware", "device", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+	mockUpgrader.On("Firmware", "device", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return(nil).Times(4)
-	mockUpgrader.On("Firmware", "deviceError", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+	mockUpgrader.On("Firmware", "deviceError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return(fmt.Errorf("error")).Times(1)
 
-	errors := ParallelFirmwareUpgrade([]string{"device", "device", "device", "device", "deviceError"}, true, nil, mockUpgrader, true, false)
+	errors := ParallelFirmwareUpgrade([]string{"device", "device", "device", "device", "deviceError"}, true, api.DefaultRetry, nil, mockUpgrader, true, false)
 
 	mockUpgrader.AssertNumberOfCalls(t, "Firmware", 5)
 	require.Len(t, errors, 1)
@@ -179,7 +203,7 @@ func TestParallelFirmwareUpgrade(t *testing.T) {
 func TestParallelFirmwareUpgradeNoDevices(t *testing.T) {
 	mockUpgrader := new(MockCalnexUpgrader)
 
-	errors := ParallelFirmwareUpgrade([]string{}, true, nil, mockUpgrader, true, false)
+	errors := ParallelFirmwareUpgrade([]string{}, true, api.DefaultRetry, nil, mockUpgrader, true, false)
 
 	mockUpgrader.AssertNumberOfCalls(t, "Firmware", 0)
 	require.ElementsMatch(t, errors, []error{})
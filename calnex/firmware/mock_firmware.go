@@ -27,8 +27,8 @@ type MockCalnexUpgrader struct {
 }
 
 // Firmware mock
-func (m *MockCalnexUpgrader) Firmware(target string, insecureTLS bool, fw FW, apply bool, force bool) error {
-	args := m.Called(target, insecureTLS, fw, apply, force)
+func (m *MockCalnexUpgrader) Firmware(target string, insecureTLS bool, retry api.Retry, fw FW, apply bool, force bool) error {
+	args := m.Called(target, insecureTLS, retry, fw, apply, force)
 	return args.Error(0)
 }
 
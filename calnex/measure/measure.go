@@ -0,0 +1,177 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measure
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/facebook/time/calnex/api"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrNotLocked is returned when GNSS is not locked before the measurement
+	ErrNotLocked = errors.New("gnss is not locked")
+	// ErrRunning is returned when measurement is already running and reset is not requested
+	ErrRunning = errors.New("measurement is already running")
+	// ErrStopped is returned when measurement stopped before the end of the schedule
+	ErrStopped = errors.New("measurement stopped before completion")
+)
+
+// Schedule describes an unattended measurement
+type Schedule struct {
+	// At is the start time of the measurement. Zero means now
+	At time.Time
+	// Duration of the measurement
+	Duration time.Duration
+	// Reset stops the measurement which is already running and starts from scratch
+	Reset bool
+	// CheckInterval is how often measurement is checked to be running
+	CheckInterval time.Duration
+}
+
+// PreCheck verifies the device is ready to measure and returns channels in use
+func PreCheck(calnexAPI api.Device) ([]api.Channel, error) {
+	g, err := calnexAPI.GnssStatus()
+	if err != nil {
+		return nil, err
+	}
+	if !g.Locked {
+		return nil, fmt.Errorf("%w: antenna %s, %d satellites", ErrNotLocked, g.AntennaStatus, g.LockedSatellites)
+	}
+
+	status, err := calnexAPI.FetchStatus()
+	if err != nil {
+		return nil, err
+	}
+	if !status.ReferenceReady {
+		return nil, errors.New("reference is not ready")
+	}
+	if !status.ModulesReady {
+		return nil, errors.New("modules are not ready")
+	}
+
+	channels, err := calnexAPI.FetchUsedChannels()
+	if err != nil {
+		return nil, err
+	}
+	if len(channels) == 0 {
+		return nil, errors.New("no channels are armed")
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i].Calnex() < channels[j].Calnex() })
+
+	is, err := calnexAPI.FetchInstrumentStatus()
+	if err != nil {
+		return nil, err
+	}
+	for _, ch := range channels {
+		cs, ok := is.Channels[ch.Physical()]
+		if !ok {
+			return nil, fmt.Errorf("channel %s: no module installed", ch)
+		}
+		if !api.ModuleReady(cs.State) {
+			return nil, fmt.Errorf("channel %s: module is %s", ch, cs.State)
+		}
+	}
+
+	return channels, nil
+}
+
+// Run runs scheduled measurement on the Sentinel device
+func Run(target string, insecureTLS bool, s Schedule) ([]api.Channel, error) {
+	calnexAPI, err := api.Connect(target, insecureTLS, time.Minute, api.DefaultRetry)
+	if err != nil {
+		return nil, err
+	}
+	return RunDevice(calnexAPI, target, s)
+}
+
+// RunDevice waits for the scheduled time, starts the measurement, then stops it after the duration.
+// It returns channels which were measured
+func RunDevice(calnexAPI api.Device, target string, s Schedule) ([]api.Channel, error) {
+	if wait := time.Until(s.At); wait > 0 {
+		log.Infof("%s: measurement starts at %s", target, s.At.Format(time.RFC3339))
+		time.Sleep(wait)
+	}
+
+	channels, err := PreCheck(calnexAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := calnexAPI.FetchStatus()
+	if err != nil {
+		return nil, err
+	}
+	if status.MeasurementActive {
+		if !s.Reset {
+			return nil, ErrRunning
+		}
+		log.Infof("%s: stopping running measurement", target)
+		if err = calnexAPI.StopMeasure(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = calnexAPI.StartMeasure(); err != nil {
+		return nil, err
+	}
+	end := time.Now().Add(s.Duration)
+	log.Infof("%s: measuring channels %v until %s", target, channels, end.Format(time.RFC3339))
+
+	if err = wait(calnexAPI, target, end, s.CheckInterval); err != nil {
+		return nil, err
+	}
+
+	log.Infof("%s: stopping measurement", target)
+	if err = calnexAPI.StopMeasure(); err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+// wait waits until the end of measurement, checking it's still running
+func wait(calnexAPI api.Device, target string, end time.Time, interval time.Duration) error {
+	for {
+		left := time.Until(end)
+		if left <= 0 {
+			return nil
+		}
+		if interval > 0 && interval < left {
+			left = interval
+		}
+		time.Sleep(left)
+
+		// device may be briefly unreachable or busy during overnight run, it's fatal only if measurement stopped
+		// or we are not allowed to check it anymore
+		status, err := calnexAPI.FetchStatus()
+		if errors.Is(err, api.ErrAuth) {
+			return err
+		}
+		if err != nil {
+			log.Warningf("%s: failed to check measurement: %v", target, err)
+			continue
+		}
+		if !status.MeasurementActive {
+			return ErrStopped
+		}
+		log.Debugf("%s: measurement is running, %s left", target, time.Until(end).Round(time.Second))
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measure
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/facebook/time/calnex/api"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDevice struct {
+	sync.Mutex
+	locked      bool
+	active      bool
+	moduleState string
+	settings    string
+	// stopAfter stops measurement after so many status checks while it's running
+	stopAfter int
+	// denyAfter rejects status checks after so many of them while it's running
+	denyAfter int
+	calls     []string
+}
+
+func newFakeDevice() *fakeDevice {
+	return &fakeDevice{
+		locked:      true,
+		moduleState: "Ready",
+		settings:    "[measure]\nch0\\installed=1\nch0\\used=Yes\nch9\\installed=1\nch9\\used=Yes\nch10\\installed=1\nch10\\used=No\n",
+	}
+}
+
+func (d *fakeDevice) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.Lock()
+	defer d.Unlock()
+	switch r.URL.Path {
+	case "/api/version":
+		fmt.Fprintln(w, "{\"firmware\": \"R21.0.0.9705\"}")
+	case "/api/gnss/status":
+		fmt.Fprintf(w, "{\"AntennaStatus\": \"OK\", \"Locked\": %t, \"LockedSatellites\": 3}\n", d.locked)
+	case "/api/getstatus":
+		if d.active && d.denyAfter > 0 {
+			d.denyAfter--
+			if d.denyAfter == 0 {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+		if d.active && d.stopAfter > 0 {
+			d.stopAfter--
+			d.active = d.stopAfter > 0
+		}
+		fmt.Fprintf(w, "{\"ReferenceReady\": true, \"ModulesReady\": true, \"MeasurementActive\": %t}\n", d.active)
+	case "/api/getsettings":
+		fmt.Fprint(w, d.settings)
+	case "/api/instrument/status":
+		fmt.Fprintf(w, "{\"Channels\":{\"1\":{\"State\":\"%s\"},\"A\":{\"State\":\"Ready\"}}}\n", d.moduleState)
+	case "/api/startmeasurement":
+		d.active = true
+		d.calls = append(d.calls, "start")
+		fmt.Fprintln(w, "{\"result\": true}")
+	case "/api/stopmeasurement":
+		d.active = false
+		d.calls = append(d.calls, "stop")
+		fmt.Fprintln(w, "{\"result\": true}")
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPreCheck(t *testing.T) {
+	d := newFakeDevice()
+	ts := httptest.NewTLSServer(d)
+	defer ts.Close()
+	parsed, _ := url.Parse(ts.URL)
+	calnexAPI := api.NewAPI(parsed.Host, true, time.Second)
+	calnexAPI.Client = ts.Client()
+
+	channels, err := PreCheck(calnexAPI)
+	require.NoError(t, err)
+	require.Equal(t, []api.Channel{api.ChannelA, api.ChannelVP1}, channels)
+
+	d.moduleState = "Failed"
+	_, err = PreCheck(calnexAPI)
+	require.EqualError(t, err, "channel VP1: module is Failed")
+
+	d.settings = "[measure]\nch0\\installed=1\nch0\\used=No\n"
+	_, err = PreCheck(calnexAPI)
+	require.EqualError(t, err, "no channels are armed")
+
+	d.settings = "[measure]\nch1\\installed=1\nch1\\used=Yes\n"
+	_, err = PreCheck(calnexAPI)
+	require.EqualError(t, err, "channel B: no module installed")
+
+	d.locked = false
+	_, err = PreCheck(calnexAPI)
+	require.ErrorIs(t, err, ErrNotLocked)
+	require.EqualError(t, err, "gnss is not locked: antenna OK, 3 satellites")
+}
+
+func TestRun(t *testing.T) {
+	d := newFakeDevice()
+	ts := httptest.NewTLSServer(d)
+	defer ts.Close()
+	parsed, _ := url.Parse(ts.URL)
+
+	start := time.Now()
+	s := Schedule{
+		At:            start.Add(50 * time.Millisecond),
+		Duration:      100 * time.Millisecond,
+		CheckInterval: 30 * time.Millisecond,
+	}
+	channels, err := Run(parsed.Host, true, s)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	require.Equal(t, []api.Channel{api.ChannelA, api.ChannelVP1}, channels)
+	require.Equal(t, []string{"start", "stop"}, d.calls)
+}
+
+func TestRunReset(t *testing.T) {
+	d := newFakeDevice()
+	d.active = true
+	ts := httptest.NewTLSServer(d)
+	defer ts.Close()
+	parsed, _ := url.Parse(ts.URL)
+
+	s := Schedule{Duration: 10 * time.Millisecond}
+	_, err := Run(parsed.Host, true, s)
+	require.ErrorIs(t, err, ErrRunning)
+	require.Empty(t, d.calls)
+
+	s.Reset = true
+	_, err = Run(parsed.Host, true, s)
+	require.NoError(t, err)
+	require.Equal(t, []string{"stop", "start", "stop"}, d.calls)
+}
+
+func TestRunStopped(t *testing.T) {
+	d := newFakeDevice()
+	d.stopAfter = 2
+	ts := httptest.NewTLSServer(d)
+	defer ts.Close()
+	parsed, _ := url.Parse(ts.URL)
+
+	s := Schedule{Duration: time.Minute, CheckInterval: 10 * time.Millisecond}
+	_, err := Run(parsed.Host, true, s)
+	require.ErrorIs(t, err, ErrStopped)
+	require.Equal(t, []string{"start"}, d.calls)
+}
+
+func TestRunDenied(t *testing.T) {
+	d := newFakeDevice()
+	d.denyAfter = 2
+	ts := httptest.NewTLSServer(d)
+	defer ts.Close()
+	parsed, _ := url.Parse(ts.URL)
+
+	s := Schedule{Duration: time.Minute, CheckInterval: 10 * time.Millisecond}
+	_, err := Run(parsed.Host, true, s)
+	require.ErrorIs(t, err, api.ErrAuth)
+	require.Equal(t, []string{"start"}, d.calls)
+}
+
+func TestRunPreCheckFail(t *testing.T) {
+	d := newFakeDevice()
+	d.locked = false
+	ts := httptest.NewTLSServer(d)
+	defer ts.Close()
+	parsed, _ := url.Parse(ts.URL)
+
+	_, err := Run(parsed.Host, true, Schedule{Duration: time.Minute})
+	require.ErrorIs(t, err, ErrNotLocked)
+	require.Empty(t, d.calls)
+}
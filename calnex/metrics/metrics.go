@@ -0,0 +1,277 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/facebook/time/calnex/analyze"
+	"github.com/facebook/time/calnex/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+// promNamespace is prepended to names of all metrics exposed in Prometheus format
+const promNamespace = "calnex"
+
+func newPromDesc(name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(promNamespace, "", name), help, labels, nil)
+}
+
+var (
+	promTE        = newPromDesc("te_seconds", "Latest time error measured on the channel", "channel", "protocol", "target")
+	promTEMin     = newPromDesc("te_min_seconds", "Min time error measured on the channel during the interval", "channel", "protocol", "target")
+	promTEMax     = newPromDesc("te_max_seconds", "Max time error measured on the channel during the interval", "channel", "protocol", "target")
+	promSamples   = newPromDesc("samples", "Samples measured on the channel during the interval", "channel", "protocol", "target")
+	promGNSS      = newPromDesc("gnss_locked", "Whether GNSS receiver is locked")
+	promSatellite = newPromDesc("gnss_satellites", "Number of satellites GNSS receiver is locked to")
+	promActive    = newPromDesc("measurement_active", "Whether measurement is running")
+	promErrors    = newPromDesc("poll_errors", "Failed requests to the device during the last poll")
+)
+
+// ChannelMeasurement is a summary of data measured on the channel since the previous poll, time error is in seconds
+type ChannelMeasurement struct {
+	Channel api.Channel
+	Probe   api.Probe
+	Target  string
+	TE      float64
+	MinTE   float64
+	MaxTE   float64
+	Samples int
+}
+
+// Measurements is the state of the device at the last poll
+type Measurements struct {
+	Channels          []ChannelMeasurement
+	GNSSLocked        bool
+	LockedSatellites  int
+	MeasurementActive bool
+	Errors            int
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// toMap returns flat map of the measurements, time error is in nanoseconds
+func (m *Measurements) toMap() map[string]int64 {
+	res := map[string]int64{
+		"calnex.gnss.locked":        int64(boolToFloat(m.GNSSLocked)),
+		"calnex.gnss.satellites":    int64(m.LockedSatellites),
+		"calnex.measurement.active": int64(boolToFloat(m.MeasurementActive)),
+		"calnex.poll.errors":        int64(m.Errors),
+	}
+	for _, c := range m.Channels {
+		prefix := fmt.Sprintf("calnex.%s.", c.Channel)
+		res[prefix+"samples"] = int64(c.Samples)
+		if c.Samples == 0 {
+			continue
+		}
+		res[prefix+"te_ns"] = int64(math.Round(c.TE * 1e9))
+		res[prefix+"te_min_ns"] = int64(math.Round(c.MinTE * 1e9))
+		res[prefix+"te_max_ns"] = int64(math.Round(c.MaxTE * 1e9))
+	}
+	return res
+}
+
+// Poller periodically fetches new measurement data from the device
+type Poller struct {
+	api      api.Device
+	source   string
+	channels []api.Channel
+
+	detected bool
+
+	sync.Mutex
+	last Measurements
+}
+
+// NewPoller returns a new Poller of source Sentinel device. All used channels are polled if none are specified
+func NewPoller(source string, insecureTLS bool, channels []api.Channel) *Poller {
+	return NewDevicePoller(api.NewAPI(source, insecureTLS, time.Minute), source, channels)
+}
+
+// NewDevicePoller returns a new Poller of the device. All used channels are polled if none are specified
+func NewDevicePoller(device api.Device, source string, channels []api.Channel) *Poller {
+	return &Poller{
+		api:      device,
+		source:   source,
+		channels: channels,
+	}
+}
+
+// pollChannel fetches data measured on the channel since the previous poll
+func (p *Poller) pollChannel(channel api.Channel) (*ChannelMeasurement, error) {
+	probe, err := p.api.FetchChannelProbe(channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch protocol from channel %s: %w", channel, err)
+	}
+	target, err := p.api.FetchChannelTarget(channel, *probe)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch target from channel %s: %w", channel, err)
+	}
+	c := &ChannelMeasurement{Channel: channel, Probe: *probe, Target: target}
+	csvLines, err := p.api.FetchCsv(channel, false)
+	if errors.Is(err, api.ErrNoNewData) {
+		log.Debugf("%s: no data from channel %s: %v", p.source, channel, err)
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch data from channel %s: %w", channel, err)
+	}
+	points, err := analyze.PointsFromCSV(csvLines)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse data from channel %s: %w", channel, err)
+	}
+	for i, point := range points {
+		if i == 0 {
+			c.MinTE, c.MaxTE = point.Value, point.Value
+		}
+		c.MinTE = math.Min(c.MinTE, point.Value)
+		c.MaxTE = math.Max(c.MaxTE, point.Value)
+		c.TE = point.Value
+	}
+	c.Samples = len(points)
+	return c, nil
+}
+
+// Poll fetches new measurement data and device status
+func (p *Poller) Poll() *Measurements {
+	m := &Measurements{}
+	if !p.detected {
+		// adapt to the firmware once, it only changes on upgrade which reboots the device
+		if _, err := p.api.DetectFirmware(); err != nil {
+			log.Warningf("%s: failed to detect firmware version: %v", p.source, err)
+			m.Errors++
+		} else {
+			p.detected = true
+		}
+	}
+	if status, err := p.api.FetchStatus(); err != nil {
+		log.Warningf("%s: failed to fetch status: %v", p.source, err)
+		m.Errors++
+	} else {
+		m.MeasurementActive = status.MeasurementActive
+	}
+	if gnss, err := p.api.GnssStatus(); err != nil {
+		log.Warningf("%s: failed to fetch GNSS status: %v", p.source, err)
+		m.Errors++
+	} else {
+		m.GNSSLocked = gnss.Locked
+		m.LockedSatellites = gnss.LockedSatellites
+	}
+
+	channels := p.channels
+	if len(channels) == 0 {
+		var err error
+		if channels, err = p.api.FetchUsedChannels(); err != nil {
+			log.Warningf("%s: failed to fetch used channels: %v", p.source, err)
+			m.Errors++
+		}
+	}
+	for _, channel := range channels {
+		c, err := p.pollChannel(channel)
+		if err != nil {
+			log.Warningf("%s: %v", p.source, err)
+			m.Errors++
+			continue
+		}
+		m.Channels = append(m.Channels, *c)
+	}
+
+	p.Lock()
+	p.last = *m
+	p.Unlock()
+	return m
+}
+
+// Run polls the device every interval, forever
+func (p *Poller) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for ; true; <-ticker.C { // first run without delay, then at interval
+		m := p.Poll()
+		log.Debugf("%s: polled %d channels, %d errors", p.source, len(m.Channels), m.Errors)
+	}
+}
+
+// Last returns measurements of the last poll
+func (p *Poller) Last() Measurements {
+	p.Lock()
+	defer p.Unlock()
+	return p.last
+}
+
+// Describe implements prometheus.Collector
+func (p *Poller) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{promTE, promTEMin, promTEMax, promSamples, promGNSS, promSatellite, promActive, promErrors} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector
+func (p *Poller) Collect(ch chan<- prometheus.Metric) {
+	m := p.Last()
+	ch <- prometheus.MustNewConstMetric(promGNSS, prometheus.GaugeValue, boolToFloat(m.GNSSLocked))
+	ch <- prometheus.MustNewConstMetric(promSatellite, prometheus.GaugeValue, float64(m.LockedSatellites))
+	ch <- prometheus.MustNewConstMetric(promActive, prometheus.GaugeValue, boolToFloat(m.MeasurementActive))
+	ch <- prometheus.MustNewConstMetric(promErrors, prometheus.GaugeValue, float64(m.Errors))
+	for _, c := range m.Channels {
+		labels := []string{string(c.Channel), string(c.Probe), c.Target}
+		ch <- prometheus.MustNewConstMetric(promSamples, prometheus.GaugeValue, float64(c.Samples), labels...)
+		if c.Samples == 0 {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(promTE, prometheus.GaugeValue, c.TE, labels...)
+		ch <- prometheus.MustNewConstMetric(promTEMin, prometheus.GaugeValue, c.MinTE, labels...)
+		ch <- prometheus.MustNewConstMetric(promTEMax, prometheus.GaugeValue, c.MaxTE, labels...)
+	}
+}
+
+// handleRequest serves measurements of the last poll as flat JSON map
+func (p *Poller) handleRequest(w http.ResponseWriter, _ *http.Request) {
+	m := p.Last()
+	js, err := json.Marshal(m.toMap())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(js); err != nil {
+		log.Errorf("Failed to reply: %v", err)
+	}
+}
+
+// Handler returns handler serving JSON on / and Prometheus metrics on /metrics
+func (p *Poller) Handler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(p)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", p.handleRequest)
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	return mux
+}
@@ -0,0 +1,135 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/facebook/time/calnex/api"
+	"github.com/stretchr/testify/require"
+)
+
+func testDevice(t *testing.T) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		if strings.Contains(r.URL.Path, "getsettings") {
+			fmt.Fprintln(w, "[measure]\nch0\\used=Yes\nch0\\installed=1\nch9\\used=Yes\nch9\\installed=1")
+		} else if strings.Contains(r.URL.Path, "version") {
+			fmt.Fprintln(w, "{\"firmware\": \"R21.0.0.9705\"}")
+		} else if strings.Contains(r.URL.Path, "getstatus") {
+			fmt.Fprintln(w, "{\"MeasurementActive\": true}")
+		} else if strings.Contains(r.URL.Path, "gnss/status") {
+			fmt.Fprintln(w, "{\"Locked\": true, \"LockedSatellites\": 12}")
+		} else if strings.Contains(r.URL.Path, "ch0/signal_type") {
+			fmt.Fprintln(w, "measure/ch0/signal_type=1 PPS")
+		} else if strings.Contains(r.URL.Path, "ch9/ptp_synce/mode/probe_type") {
+			fmt.Fprintln(w, "measure/ch9/ptp_synce/mode/probe_type=0")
+		} else if strings.Contains(r.URL.Path, "ch0/server_ip") {
+			fmt.Fprintln(w, "ch0/server_ip=127.0.0.1")
+		} else if strings.Contains(r.URL.Path, "ch9/ptp_synce/ptp/master_ip_ipv6") {
+			fmt.Fprintln(w, "measure/ch9/ptp_synce/ptp/master_ip_ipv6=::1")
+		} else if r.URL.Query().Get("channel") == "A" {
+			require.Equal(t, "false", r.URL.Query().Get("reset"))
+			fmt.Fprintln(w, "1607961193.773740,-000.000000250501\n1607961194.773740,-000.000000000002\n1607961195.773740,-000.000000000003")
+		} else if strings.Contains(r.URL.Path, "ch10/ptp_synce/mode/probe_type") {
+			fmt.Fprintln(w, "measure/ch10/ptp_synce/mode/probe_type=0")
+		} else if strings.Contains(r.URL.Path, "ch10/ptp_synce/ptp/master_ip_ipv6") {
+			fmt.Fprintln(w, "measure/ch10/ptp_synce/ptp/master_ip_ipv6=::2")
+		} else if r.URL.Query().Get("channel") == "VP1" {
+			fmt.Fprintln(w, "{\"message\": \"No data available\", \"result\": true}")
+		} else if r.URL.Query().Get("channel") == "VP2" {
+			fmt.Fprintln(w, "{\"message\": \"Measurement failed\", \"result\": false}")
+		}
+	}))
+}
+
+func TestPoll(t *testing.T) {
+	ts := testDevice(t)
+	defer ts.Close()
+	parsed, _ := url.Parse(ts.URL)
+
+	p := NewPoller(parsed.Host, true, nil)
+	m := p.Poll()
+	require.Equal(t, 0, m.Errors)
+	require.True(t, m.MeasurementActive)
+	require.True(t, m.GNSSLocked)
+	require.Equal(t, 12, m.LockedSatellites)
+	require.ElementsMatch(t, []ChannelMeasurement{
+		{Channel: api.ChannelA, Probe: api.ProbePPS, Target: "127.0.0.1", TE: -3e-12, MinTE: -250.501e-9, MaxTE: -2e-12, Samples: 3},
+		{Channel: api.ChannelVP1, Probe: api.ProbePTP, Target: "::1"},
+	}, m.Channels)
+	require.Equal(t, *m, p.Last())
+
+	p = NewPoller(parsed.Host, true, []api.Channel{api.ChannelB})
+	m = p.Poll()
+	require.Equal(t, 1, m.Errors)
+	require.Empty(t, m.Channels)
+}
+
+func TestPollDataError(t *testing.T) {
+	ts := testDevice(t)
+	defer ts.Close()
+	parsed, _ := url.Parse(ts.URL)
+
+	// no data is not an error, failed request is
+	p := NewPoller(parsed.Host, true, []api.Channel{api.ChannelVP1, api.ChannelVP2})
+	m := p.Poll()
+	require.Equal(t, 1, m.Errors)
+	require.Equal(t, []ChannelMeasurement{{Channel: api.ChannelVP1, Probe: api.ProbePTP, Target: "::1"}}, m.Channels)
+}
+
+func TestHandler(t *testing.T) {
+	ts := testDevice(t)
+	defer ts.Close()
+	parsed, _ := url.Parse(ts.URL)
+
+	p := NewPoller(parsed.Host, true, []api.Channel{api.ChannelA, api.ChannelVP1})
+	p.Poll()
+	h := p.Handler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	counters := map[string]int64{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &counters))
+	require.Equal(t, map[string]int64{
+		"calnex.gnss.locked":        1,
+		"calnex.gnss.satellites":    12,
+		"calnex.measurement.active": 1,
+		"calnex.poll.errors":        0,
+		"calnex.A.samples":          3,
+		"calnex.A.te_ns":            0,
+		"calnex.A.te_min_ns":        -251,
+		"calnex.A.te_max_ns":        0,
+		"calnex.VP1.samples":        0,
+	}, counters)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	require.Contains(t, body, "calnex_gnss_satellites 12\n")
+	require.Contains(t, body, "calnex_te_min_seconds{channel=\"A\",protocol=\"PPS\",target=\"127.0.0.1\"} -2.50501e-07\n")
+	require.Contains(t, body, "calnex_samples{channel=\"VP1\",protocol=\"PTP\",target=\"::1\"} 0\n")
+	require.NotContains(t, body, "calnex_te_seconds{channel=\"VP1\"")
+}
@@ -16,10 +16,12 @@ limitations under the License.
 
 package checks
 
+import "github.com/facebook/time/calnex/api"
+
 // Check abstracts the checks to be executed
 type Check interface {
 	Name() string
-	Run(name string, insecureTLS bool) error
+	Run(name string, insecureTLS bool, retry api.Retry) error
 	Remediate() (string, error)
 }
 
@@ -44,7 +44,7 @@ func TestGNSS(t *testing.T) {
 	calnexAPI := api.NewAPI(parsed.Host, true, time.Second)
 	calnexAPI.Client = ts.Client()
 
-	err := c.Run(parsed.Host, true)
+	err := c.Run(parsed.Host, true, api.DefaultRetry)
 	require.NoError(t, err)
 }
 
@@ -64,7 +64,7 @@ func TestGNSSNoSatellites(t *testing.T) {
 	calnexAPI := api.NewAPI(parsed.Host, true, time.Second)
 	calnexAPI.Client = ts.Client()
 
-	err := c.Run(parsed.Host, true)
+	err := c.Run(parsed.Host, true, api.DefaultRetry)
 	require.ErrorContains(t, err, "gnss: not enough satellites")
 }
 
@@ -73,7 +73,7 @@ func TestGNSSError(t *testing.T) {
 	c := GNSS{Remediation: r}
 	require.Equal(t, "GNSS", c.Name())
 
-	err := c.Run("1.2.3.4", false)
+	err := c.Run("1.2.3.4", false, api.DefaultRetry)
 	require.Error(t, err)
 
 	want, _ := r.Remediate()
@@ -98,7 +98,7 @@ func TestHTTP(t *testing.T) {
 	calnexAPI := api.NewAPI(parsed.Host, true, time.Second)
 	calnexAPI.Client = ts.Client()
 
-	err := c.Run(parsed.Host, true)
+	err := c.Run(parsed.Host, true, api.DefaultRetry)
 	require.NoError(t, err)
 }
 
@@ -107,7 +107,7 @@ func TestHTTPError(t *testing.T) {
 	c := HTTP{Remediation: r}
 	require.Equal(t, "HTTP", c.Name())
 
-	err := c.Run("1.2.3.4", false)
+	err := c.Run("1.2.3.4", false, api.DefaultRetry)
 	require.Error(t, err)
 
 	want, _ := r.Remediate()
@@ -132,7 +132,7 @@ func TestPSU(t *testing.T) {
 	calnexAPI := api.NewAPI(parsed.Host, true, time.Second)
 	calnexAPI.Client = ts.Client()
 
-	err := c.Run(parsed.Host, true)
+	err := c.Run(parsed.Host, true, api.DefaultRetry)
 	require.NoError(t, err)
 }
 
@@ -152,7 +152,7 @@ func TestPSUSingleBad(t *testing.T) {
 	calnexAPI := api.NewAPI(parsed.Host, true, time.Second)
 	calnexAPI.Client = ts.Client()
 
-	err := c.Run(parsed.Host, true)
+	err := c.Run(parsed.Host, true, api.DefaultRetry)
 	require.ErrorContains(t, err, "psu: failed power supply #1: PSU_module_B")
 }
 
@@ -172,7 +172,7 @@ func TestPSUBBad(t *testing.T) {
 	calnexAPI := api.NewAPI(parsed.Host, true, time.Second)
 	calnexAPI.Client = ts.Client()
 
-	err := c.Run(parsed.Host, true)
+	err := c.Run(parsed.Host, true, api.DefaultRetry)
 	require.ErrorContains(t, err, "psu: failed power supply")
 }
 
@@ -181,7 +181,7 @@ func TestPSUError(t *testing.T) {
 	c := PSU{Remediation: r}
 	require.Equal(t, "PSU", c.Name())
 
-	err := c.Run("1.2.3.4", false)
+	err := c.Run("1.2.3.4", false, api.DefaultRetry)
 	require.Error(t, err)
 
 	want, _ := r.Remediate()
@@ -206,7 +206,7 @@ func TestModule(t *testing.T) {
 	calnexAPI := api.NewAPI(parsed.Host, true, time.Second)
 	calnexAPI.Client = ts.Client()
 
-	err := c.Run(parsed.Host, true)
+	err := c.Run(parsed.Host, true, api.DefaultRetry)
 	require.NoError(t, err)
 }
 
@@ -226,7 +226,7 @@ func TestModuleBad(t *testing.T) {
 	calnexAPI := api.NewAPI(parsed.Host, true, time.Second)
 	calnexAPI.Client = ts.Client()
 
-	err := c.Run(parsed.Host, true)
+	err := c.Run(parsed.Host, true, api.DefaultRetry)
 	require.ErrorContains(t, err, "module: failed module 1: state: Fault")
 }
 
@@ -235,7 +235,7 @@ func TestModuleError(t *testing.T) {
 	c := Module{Remediation: r}
 	require.Equal(t, "Module", c.Name())
 
-	err := c.Run("1.2.3.4", false)
+	err := c.Run("1.2.3.4", false, api.DefaultRetry)
 	require.Error(t, err)
 
 	want, _ := r.Remediate()
@@ -34,8 +34,11 @@ func (p *GNSS) Name() string {
 }
 
 // Run executes the check
-func (p *GNSS) Run(target string, insecureTLS bool) error {
-	api := api.NewAPI(target, insecureTLS, 10*time.Second)
+func (p *GNSS) Run(target string, insecureTLS bool, retry api.Retry) error {
+	api, err := api.Connect(target, insecureTLS, 10*time.Second, retry)
+	if err != nil {
+		return err
+	}
 
 	g, err := api.GnssStatus()
 	if err != nil {
@@ -34,10 +34,13 @@ func (p *HTTP) Name() string {
 }
 
 // Run executes the check
-func (p *HTTP) Run(target string, insecureTLS bool) error {
-	api := api.NewAPI(target, insecureTLS, 10*time.Second)
+func (p *HTTP) Run(target string, insecureTLS bool, retry api.Retry) error {
+	api, err := api.Connect(target, insecureTLS, 10*time.Second, retry)
+	if err != nil {
+		return fmt.Errorf("https: %w", err)
+	}
 
-	_, err := api.FetchStatus()
+	_, err = api.FetchStatus()
 	if err != nil {
 		return fmt.Errorf("https: %w", err)
 	}
@@ -40,8 +40,11 @@ func (m *Module) Name() string {
 }
 
 // Run executes the check
-func (m *Module) Run(target string, insecureTLS bool) error {
-	api := api.NewAPI(target, insecureTLS, 10*time.Second)
+func (m *Module) Run(target string, insecureTLS bool, retry api.Retry) error {
+	api, err := api.Connect(target, insecureTLS, 10*time.Second, retry)
+	if err != nil {
+		return err
+	}
 
 	ms, err := api.FetchInstrumentStatus()
 	if err != nil {
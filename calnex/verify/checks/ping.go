@@ -21,6 +21,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/facebook/time/calnex/api"
 	"golang.org/x/net/icmp"
 	"golang.org/x/net/ipv6"
 )
@@ -38,7 +39,7 @@ func (p *Ping) Name() string {
 }
 
 // Run executes the check
-func (p *Ping) Run(target string, _ bool) error {
+func (p *Ping) Run(target string, _ bool, _ api.Retry) error {
 	ip, err := net.ResolveIPAddr("ip", target)
 	if err != nil {
 		return err
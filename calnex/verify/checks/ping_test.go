@@ -22,6 +22,7 @@ package checks
 import (
 	"testing"
 
+	"github.com/facebook/time/calnex/api"
 	"github.com/stretchr/testify/require"
 )
 
@@ -30,7 +31,7 @@ func TestPing(t *testing.T) {
 	c := Ping{Remediation: r}
 	require.Equal(t, "Ping", c.Name())
 
-	err := c.Run("::1", false)
+	err := c.Run("::1", false, api.DefaultRetry)
 	require.NoError(t, err)
 }
 
@@ -39,7 +40,7 @@ func TestPingError(t *testing.T) {
 	c := Ping{Remediation: r}
 	require.Equal(t, "Ping", c.Name())
 
-	err := c.Run("1.2.3.4", false)
+	err := c.Run("1.2.3.4", false, api.DefaultRetry)
 	require.Error(t, err)
 
 	want, _ := r.Remediate()
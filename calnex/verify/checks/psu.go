@@ -34,8 +34,11 @@ func (p *PSU) Name() string {
 }
 
 // Run executes the check
-func (p *PSU) Run(target string, insecureTLS bool) error {
-	api := api.NewAPI(target, insecureTLS, 10*time.Second)
+func (p *PSU) Run(target string, insecureTLS bool, retry api.Retry) error {
+	api, err := api.Connect(target, insecureTLS, 10*time.Second, retry)
+	if err != nil {
+		return err
+	}
 
 	pu, err := api.PowerSupplyStatus()
 	if err != nil {
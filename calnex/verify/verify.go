@@ -17,6 +17,7 @@ limitations under the License.
 package verify
 
 import (
+	"github.com/facebook/time/calnex/api"
 	"github.com/facebook/time/calnex/verify/checks"
 	log "github.com/sirupsen/logrus"
 )
@@ -27,9 +28,9 @@ type VF struct {
 }
 
 // Verify runs health checks and report diagnosis
-func Verify(target string, insecureTLS bool, verify *VF, apply bool) error {
+func Verify(target string, insecureTLS bool, retry api.Retry, verify *VF, apply bool) error {
 	for _, c := range verify.Checks {
-		if err := c.Run(target, insecureTLS); err != nil {
+		if err := c.Run(target, insecureTLS, retry); err != nil {
 			log.Warningf("%s: %s check fail: %v", target, c.Name(), err)
 			if apply {
 				result, err := c.Remediate()
@@ -19,6 +19,7 @@ package verify
 import (
 	"testing"
 
+	"github.com/facebook/time/calnex/api"
 	"github.com/facebook/time/calnex/verify/checks"
 	"github.com/stretchr/testify/require"
 )
@@ -28,9 +29,9 @@ func TestVerify(t *testing.T) {
 		&checks.Ping{Remediation: checks.PingRemediation{}},
 	}}
 
-	err := Verify("localhost", false, v, true)
+	err := Verify("localhost", false, api.DefaultRetry, v, true)
 	require.NoError(t, err)
 
-	err = Verify("1.2.3.4", false, v, true)
+	err = Verify("1.2.3.4", false, api.DefaultRetry, v, true)
 	require.NoError(t, err)
 }
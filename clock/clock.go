@@ -97,6 +97,16 @@ func Step(clockid int32, step time.Duration) (state int, err error) {
 	return unix.ClockAdjtime(clockid, tx)
 }
 
+// AdjPhase adjusts clock phase by given offset using ADJ_OFFSET.
+// For PHC devices this is handled by the driver adjphase callback, usually servo-in-hardware.
+func AdjPhase(clockid int32, offset time.Duration) (state int, err error) {
+	tx := &unix.Timex{}
+	tx.Modes = AdjOffset | AdjNano
+	// this way we can have platform-dependent code isolated
+	setOffset(tx, offset)
+	return unix.ClockAdjtime(clockid, tx)
+}
+
 // MaxFreqPPB returns maximum frequency adjustment supported by the clock
 func MaxFreqPPB(clockid int32) (freqPPB float64, state int, err error) {
 	tx := &unix.Timex{}
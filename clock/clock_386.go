@@ -33,3 +33,7 @@ func setTime(tx *unix.Timex, sec, usec time.Duration) {
 	tx.Time.Sec = int32(sec)
 	tx.Time.Usec = int32(usec)
 }
+
+func setOffset(tx *unix.Timex, offset time.Duration) {
+	tx.Offset = int32(offset)
+}
@@ -33,3 +33,7 @@ func setTime(tx *unix.Timex, sec, usec time.Duration) {
 	tx.Time.Sec = int64(sec)
 	tx.Time.Usec = int64(usec)
 }
+
+func setOffset(tx *unix.Timex, offset time.Duration) {
+	tx.Offset = int64(offset)
+}
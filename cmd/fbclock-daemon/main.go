@@ -22,9 +22,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/facebook/time/fbclock/daemon"
+	"github.com/facebook/time/fbclock/rpc"
 	"github.com/facebook/time/fbclock/stats"
 	ptp "github.com/facebook/time/ptp/protocol"
 
@@ -42,6 +44,7 @@ func main() {
 		logSampleRate  int
 		verbose        bool
 		monitoringPort int
+		simulatePath   string
 	)
 
 	flag.Usage = func() {
@@ -53,6 +56,9 @@ func main() {
 	flag.StringVar(&cfg.Iface, "iface", "eth0", "Network interface to use PHC device from. Used for linearizability tests as well. Must match what PTP client is configured to use")
 	flag.StringVar(&cfg.PTPClientAddress, "ptpclientaddress", ptp.PTP4lSock, "Path to PTP client management address")
 	flag.BoolVar(&cfg.SPTP, "sptp", false, "Connect to sptp instead ot ptp4l")
+	flag.BoolVar(&cfg.AutoSource, "autosource", false, "Use sptp at -sptpaddress if it responds, ptp4l at -ptpclientaddress otherwise. Overrides -sptp")
+	flag.StringVar(&cfg.SPTPAddress, "sptpaddress", "localhost:4269", "sptp monitoring address, used with -autosource")
+	flag.StringVar(&cfg.DataSource, "datasource", "", fmt.Sprintf("Data source to use, one of %s. Overrides -sptp and -autosource", strings.Join(daemon.DataSources(), ", ")))
 	flag.IntVar(&monitoringPort, "monitoringport", 21039, "Port to run monitoring server on")
 	flag.IntVar(&cfg.RingSize, "buffer", daemon.MathDefaultHistory, "Size of ring buffers, must be at least size of largest num of samples used in M and W formulas")
 	flag.StringVar(&cfg.Math.M, "m", daemon.MathDefaultM, "Math expression for M")
@@ -62,12 +68,17 @@ func main() {
 	flag.DurationVar(&cfg.LinearizabilityTestInterval, "I", time.Minute, "Interval at which we run linearizability tests. 0 means disabled")
 	flag.DurationVar(&cfg.LinearizabilityTestMaxGMOffset, "o", 10*time.Microsecond, "Max offset between GMs before linearizability test considered failed")
 	flag.DurationVar(&cfg.BootDelay, "b", 0, "Postpone startup by this time after boot")
+	flag.DurationVar(&cfg.StepThreshold, "stepthreshold", time.Millisecond, "Report PHC changes relative to monotonic clock by more than this between updates as clock steps. 0 means disabled")
 	flag.StringVar(&cfgPath, "cfg", "", "Path to config")
+	flag.StringVar(&cfg.HistoryPath, "historypath", "", "Keep history of error bounds in this file. Empty means disabled")
+	flag.IntVar(&cfg.HistorySize, "historysize", 7*24*3600, "Max number of records in history file, older ones are overwritten")
+	flag.StringVar(&cfg.RPCSocket, "rpcsocket", "", fmt.Sprintf("Serve GetTime/GetError JSON-RPC on this unix socket, like %q. Empty means disabled", rpc.DefaultSocketPath))
 	flag.BoolVar(&manageDevice, "manage", true, fmt.Sprintf("Manage device. This will setup %q as a copy of PHC device associated with given network interface", daemon.ManagedPTPDevicePath))
 	flag.BoolVar(&csvLog, "csvlog", true, "Log all the metrics as CSV to log")
 	flag.StringVar(&csvPath, "csvpath", "", "write CSV log into this file")
 	flag.IntVar(&logSampleRate, "logsamplerate", 1, "Sample metrics logs at this rate. 0 means metrics logging is turned off. 1 means every sample is logged, 100 means roughly one in 100 samples will be logged")
 	flag.BoolVar(&verbose, "verbose", false, "Verbose logging")
+	flag.StringVar(&simulatePath, "simulate", "", "Publish error bounds from this scenario file instead of talking to PTP client. For integration tests only")
 
 	flag.Parse()
 
@@ -119,10 +130,28 @@ func main() {
 	}
 	s := stats.NewJSONStats()
 	go s.Start(monitoringPort)
+	if simulatePath != "" {
+		scenario, err := daemon.ReadScenario(simulatePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sim, err := daemon.NewSimulator(cfg, scenario, s)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := sim.Run(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	d, err := daemon.New(cfg, s, l)
 	if err != nil {
 		log.Fatal(err)
 	}
+	s.SetDebugState(func() any { return d.DebugState() })
+	if cfgPath != "" {
+		go d.HandleSighup(cfgPath)
+	}
 	ctx := context.Background()
 	if err := d.Run(ctx); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,104 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/facebook/time/fbclock/daemon"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	var (
+		path   string
+		at     string
+		from   string
+		to     string
+		format string
+	)
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Export history of error bounds recorded by fbclock daemon with -historypath\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&path, "path", "", "Path to history file")
+	flag.StringVar(&at, "at", "", "Only print the record in effect at this time, RFC3339")
+	flag.StringVar(&from, "from", "", "Only export records made at or after this time, RFC3339")
+	flag.StringVar(&to, "to", "", "Only export records made at or before this time, RFC3339")
+	flag.StringVar(&format, "format", "csv", "Output format: csv or json")
+	flag.Parse()
+
+	if path == "" {
+		log.Fatal("-path is required")
+	}
+	if format != "csv" && format != "json" {
+		log.Fatalf("unsupported format %q", format)
+	}
+	records, err := daemon.ReadHistory(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if at != "" {
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			log.Fatalf("parsing -at: %v", err)
+		}
+		r, err := daemon.RecordAt(records, t)
+		if err != nil {
+			log.Fatal(err)
+		}
+		records = []daemon.HistoryRecord{*r}
+	} else {
+		fromT, toT := time.Time{}, time.Unix(1<<62, 0)
+		if from != "" {
+			if fromT, err = time.Parse(time.RFC3339, from); err != nil {
+				log.Fatalf("parsing -from: %v", err)
+			}
+		}
+		if to != "" {
+			if toT, err = time.Parse(time.RFC3339, to); err != nil {
+				log.Fatalf("parsing -to: %v", err)
+			}
+		}
+		filtered := []daemon.HistoryRecord{}
+		for _, r := range records {
+			if !r.Time().Before(fromT) && !r.Time().After(toT) {
+				filtered = append(filtered, r)
+			}
+		}
+		records = filtered
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(records); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := daemon.ExportHistoryCSV(os.Stdout, records); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -125,21 +125,9 @@ func (n *ChronyCheck) Run() (*NTPCheckResult, error) {
 		// get ntpdata when using a unix socket
 		var ntpData *chrony.NTPData
 		if sourceData.Mode != chrony.SourceModeRef && n.Unix() {
-			ntpDataReq := chrony.NewNTPDataPacket(sourceData.IPAddr)
-			packet, err = n.Client.Communicate(ntpDataReq)
-			if err != nil {
+			if ntpData, err = n.ntpData(sourceData.IPAddr); err != nil {
 				return nil, fmt.Errorf("failed to get 'ntpdata' response for source #%d: %w", i, err)
 			}
-			rpyNTPData, ok := packet.(*chrony.ReplyNTPData)
-			if ok {
-				ntpData = &rpyNTPData.NTPData
-			} else {
-				rpyNTPData2, ok := packet.(*chrony.ReplyNTPData2)
-				if !ok {
-					return nil, fmt.Errorf("Got wrong 'ntpdata' response %+v", packet)
-				}
-				ntpData = &rpyNTPData2.NTPData
-			}
 		}
 		// get peer sourcename using a unix socket
 		var ntpSourceName *chrony.ReplyNTPSourceName
@@ -172,6 +160,106 @@ func (n *ChronyCheck) Run() (*NTPCheckResult, error) {
 	return result, nil
 }
 
+// ntpData requests 'ntpdata' of the source, which chronyd only reports over unix socket
+func (n *ChronyCheck) ntpData(ip net.IP) (*chrony.NTPData, error) {
+	packet, err := n.Client.Communicate(chrony.NewNTPDataPacket(ip))
+	if err != nil {
+		return nil, err
+	}
+	switch p := packet.(type) {
+	case *chrony.ReplyNTPData:
+		return &p.NTPData, nil
+	case *chrony.ReplyNTPData2:
+		return &p.NTPData, nil
+	}
+	return nil, fmt.Errorf("Got wrong 'ntpdata' response %+v", packet)
+}
+
+// ChronySource is everything chronyd reports about single source
+type ChronySource struct {
+	Name        string
+	SourceData  *chrony.SourceData
+	SourceStats *chrony.SourceStats
+	// only available over unix socket, and not for reference clocks
+	NTPData *chrony.NTPData
+}
+
+// Tracking returns 'tracking' report, same as 'chronyc tracking'
+func (n *ChronyCheck) Tracking() (*chrony.Tracking, error) {
+	packet, err := n.Client.Communicate(chrony.NewTrackingPacket())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get 'tracking' response: %w", err)
+	}
+	tracking, ok := packet.(*chrony.ReplyTracking)
+	if !ok {
+		return nil, fmt.Errorf("Got wrong 'tracking' response %+v", packet)
+	}
+	return &tracking.Tracking, nil
+}
+
+// Sources returns reports about all sources, same as 'chronyc sources', 'sourcestats' and 'ntpdata' together
+func (n *ChronyCheck) Sources() ([]*ChronySource, error) {
+	packet, err := n.Client.Communicate(chrony.NewSourcesPacket())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get 'sources' response: %w", err)
+	}
+	sources, ok := packet.(*chrony.ReplySources)
+	if !ok {
+		return nil, fmt.Errorf("Got wrong 'sources' response %+v", packet)
+	}
+	result := make([]*ChronySource, 0, sources.NSources)
+	for i := 0; i < sources.NSources; i++ {
+		packet, err = n.Client.Communicate(chrony.NewSourceDataPacket(int32(i)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get 'sourcedata' response for source #%d: %w", i, err)
+		}
+		sourceData, ok := packet.(*chrony.ReplySourceData)
+		if !ok {
+			return nil, fmt.Errorf("Got wrong 'sourcedata' response %+v", packet)
+		}
+		packet, err = n.Client.Communicate(chrony.NewSourceStatsPacket(int32(i)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get 'sourcestats' response for source #%d: %w", i, err)
+		}
+		sourceStats, ok := packet.(*chrony.ReplySourceStats)
+		if !ok {
+			return nil, fmt.Errorf("Got wrong 'sourcestats' response %+v", packet)
+		}
+		source := &ChronySource{SourceData: &sourceData.SourceData, SourceStats: &sourceStats.SourceStats}
+		if sourceData.Mode != chrony.SourceModeRef {
+			packet, err = n.Client.Communicate(chrony.NewNTPSourceNamePacket(sourceData.IPAddr))
+			if err != nil {
+				return nil, fmt.Errorf("failed to get 'sourcename' response for source #%d: %w", i, err)
+			}
+			name, ok := packet.(*chrony.ReplyNTPSourceName)
+			if !ok {
+				return nil, fmt.Errorf("Got wrong 'sourcename' response %+v", packet)
+			}
+			source.Name = name.Name
+			if n.Unix() {
+				if source.NTPData, err = n.ntpData(sourceData.IPAddr); err != nil {
+					return nil, fmt.Errorf("failed to get 'ntpdata' response for source #%d: %w", i, err)
+				}
+			}
+		}
+		result = append(result, source)
+	}
+	return result, nil
+}
+
+// RTCData returns 'rtcdata' report, same as 'chronyc rtcdata'
+func (n *ChronyCheck) RTCData() (*chrony.RTCData, error) {
+	packet, err := n.Client.Communicate(chrony.NewRTCDataPacket())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get 'rtcdata' response: %w", err)
+	}
+	rtc, ok := packet.(*chrony.ReplyRTCData)
+	if !ok {
+		return nil, fmt.Errorf("Got wrong 'rtcdata' response %+v", packet)
+	}
+	return &rtc.RTCData, nil
+}
+
 // ServerStats return server stats
 func (n *ChronyCheck) ServerStats() (*ServerStats, error) {
 	statsReq := chrony.NewServerStatsPacket()
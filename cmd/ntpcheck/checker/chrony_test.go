@@ -294,3 +294,76 @@ func TestChronyCheckServerStats(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, want, got)
 }
+
+func TestChronyCheckTracking(t *testing.T) {
+	check := &ChronyCheck{
+		Client: &fakeChronyClient{readCount: 0, outputs: []chrony.ResponsePacket{replyTracking}},
+	}
+	got, err := check.Tracking()
+	require.NoError(t, err)
+	require.Equal(t, &replyTracking.Tracking, got)
+
+	_, err = check.Tracking()
+	require.Error(t, err)
+}
+
+func TestChronyCheckSources(t *testing.T) {
+	stats0 := &chrony.ReplySourceStats{SourceStats: chrony.SourceStats{RefID: 123456, NSamples: 8}}
+	stats1 := &chrony.ReplySourceStats{SourceStats: chrony.SourceStats{RefID: 654321, NSamples: 4}}
+	prepdOutputs := []chrony.ResponsePacket{
+		// get list of sources
+		replySources,
+		// first source
+		replySD0,
+		stats0,
+		replyNTPSourceName0,
+		replyNTPData0,
+		// second source
+		replySD1,
+		stats1,
+		replyNTPSourceName1,
+		replyNTPData1,
+	}
+	check := &ChronyCheck{
+		Client:   &fakeChronyClient{readCount: 0, outputs: prepdOutputs},
+		unixConn: true,
+	}
+	got, err := check.Sources()
+	require.NoError(t, err)
+	want := []*ChronySource{
+		{
+			Name:        "ntp_peer001.sample.facebook.com",
+			SourceData:  &replySD0.SourceData,
+			SourceStats: &stats0.SourceStats,
+			NTPData:     &replyNTPData0.NTPData,
+		},
+		{
+			SourceData:  &replySD1.SourceData,
+			SourceStats: &stats1.SourceStats,
+			NTPData:     &replyNTPData1.NTPData,
+		},
+	}
+	require.Equal(t, want, got)
+
+	// ntpdata is only requested over unix socket
+	check = &ChronyCheck{
+		Client: &fakeChronyClient{readCount: 0, outputs: []chrony.ResponsePacket{&chrony.ReplySources{NSources: 1}, replySD0, stats0, replyNTPSourceName0}},
+	}
+	got, err = check.Sources()
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Nil(t, got[0].NTPData)
+}
+
+func TestChronyCheckRTCData(t *testing.T) {
+	rtc := &chrony.ReplyRTCData{RTCData: chrony.RTCData{RefTime: refTime, NSamples: 3, RTCSecondsFast: 0.5}}
+	check := &ChronyCheck{
+		Client: &fakeChronyClient{readCount: 0, outputs: []chrony.ResponsePacket{rtc, replyTracking}},
+	}
+	got, err := check.RTCData()
+	require.NoError(t, err)
+	require.Equal(t, &rtc.RTCData, got)
+
+	_, err = check.RTCData()
+	require.Error(t, err)
+}
@@ -0,0 +1,152 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// QueryFunc performs single NTP exchange with the server
+type QueryFunc func(server string) (*Exchange, error)
+
+// ServerOffset is offset of a single server from the local clock
+type ServerOffset struct {
+	Server string `json:"server"`
+	// offset and delay are in ms
+	Offset      float64 `json:"offset"`
+	Delay       float64 `json:"delay"`
+	Stratum     uint8   `json:"stratum"`
+	Error       string  `json:"error,omitempty"`
+	Falseticker bool    `json:"falseticker"`
+}
+
+// Disagreement is difference between offsets of two servers, in ms
+type Disagreement struct {
+	A          string  `json:"a"`
+	B          string  `json:"b"`
+	Difference float64 `json:"difference"`
+	Exceeded   bool    `json:"exceeded"`
+}
+
+// CompareResult is a result of comparing multiple servers
+type CompareResult struct {
+	Servers       []*ServerOffset `json:"servers"`
+	Disagreements []*Disagreement `json:"disagreements"`
+	// median offset of reachable servers, in ms
+	Median float64 `json:"median"`
+}
+
+// Falsetickers returns servers which disagree with the majority
+func (r *CompareResult) Falsetickers() []string {
+	res := []string{}
+	for _, s := range r.Servers {
+		if s.Falseticker {
+			res = append(res, s.Server)
+		}
+	}
+	return res
+}
+
+// Unreachable returns servers which failed to respond
+func (r *CompareResult) Unreachable() []string {
+	res := []string{}
+	for _, s := range r.Servers {
+		if s.Error != "" {
+			res = append(res, s.Server)
+		}
+	}
+	return res
+}
+
+func durationToMS(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// QueryServers queries all servers concurrently and returns their offsets from the local clock in the same order
+func QueryServers(servers []string, query QueryFunc) []*ServerOffset {
+	result := make([]*ServerOffset, len(servers))
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func(i int, server string) {
+			defer wg.Done()
+			so := &ServerOffset{Server: server}
+			result[i] = so
+			e, err := query(server)
+			if err != nil {
+				so.Error = err.Error()
+				return
+			}
+			so.Offset = durationToMS(e.Offset())
+			so.Delay = durationToMS(e.Delay())
+			so.Stratum = e.Response.Stratum
+		}(i, server)
+	}
+	wg.Wait()
+	return result
+}
+
+// CompareServers queries all servers concurrently and compares their offsets from the local clock.
+// Server is a falseticker if its offset is more than threshold (in ms) away from the median offset of all reachable servers,
+// pairwise disagreements above threshold are marked as exceeded.
+func CompareServers(servers []string, query QueryFunc, threshold float64) *CompareResult {
+	result := &CompareResult{Servers: QueryServers(servers, query), Disagreements: []*Disagreement{}}
+
+	reachable := []*ServerOffset{}
+	offsets := []float64{}
+	for _, so := range result.Servers {
+		if so.Error == "" {
+			reachable = append(reachable, so)
+			offsets = append(offsets, so.Offset)
+		}
+	}
+	result.Median = median(offsets)
+	// with less than 3 servers there is no majority to disagree with
+	if len(reachable) >= 3 {
+		for _, so := range reachable {
+			diff := so.Offset - result.Median
+			so.Falseticker = diff > threshold || diff < -threshold
+		}
+	}
+	for i, a := range reachable {
+		for _, b := range reachable[i+1:] {
+			diff := a.Offset - b.Offset
+			result.Disagreements = append(result.Disagreements, &Disagreement{
+				A:          a.Server,
+				B:          b.Server,
+				Difference: diff,
+				Exceeded:   diff > threshold || diff < -threshold,
+			})
+		}
+	}
+	return result
+}
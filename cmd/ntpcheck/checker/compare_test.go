@@ -0,0 +1,96 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+func fakeQuery(offsets map[string]time.Duration) QueryFunc {
+	return func(server string) (*Exchange, error) {
+		offset, ok := offsets[server]
+		if !ok {
+			return nil, fmt.Errorf("timeout")
+		}
+		now := time.Now()
+		return &Exchange{
+			Response:           &ntp.Packet{Stratum: 2},
+			OriginTime:         now,
+			ServerReceiveTime:  now.Add(offset + time.Millisecond),
+			ServerTransmitTime: now.Add(offset + time.Millisecond),
+			ClientReceiveTime:  now.Add(2 * time.Millisecond),
+		}, nil
+	}
+}
+
+func TestCompareServers(t *testing.T) {
+	query := fakeQuery(map[string]time.Duration{
+		"a": time.Millisecond,
+		"b": 2 * time.Millisecond,
+		"c": 3 * time.Millisecond,
+		"d": 50 * time.Millisecond,
+	})
+	r := CompareServers([]string{"a", "b", "c", "d", "e"}, query, 10)
+	require.Len(t, r.Servers, 5)
+	require.Equal(t, "a", r.Servers[0].Server)
+	require.InDelta(t, 1, r.Servers[0].Offset, 0.001)
+	require.InDelta(t, 2, r.Servers[0].Delay, 0.001)
+	require.Equal(t, uint8(2), r.Servers[0].Stratum)
+	require.InDelta(t, 2.5, r.Median, 0.001)
+	require.Equal(t, []string{"d"}, r.Falsetickers())
+	require.Equal(t, []string{"e"}, r.Unreachable())
+	require.Equal(t, "timeout", r.Servers[4].Error)
+
+	// 4 reachable servers give 6 pairs
+	require.Len(t, r.Disagreements, 6)
+	require.Equal(t, "a", r.Disagreements[0].A)
+	require.Equal(t, "b", r.Disagreements[0].B)
+	require.InDelta(t, -1, r.Disagreements[0].Difference, 0.001)
+	require.False(t, r.Disagreements[0].Exceeded)
+	exceeded := 0
+	for _, d := range r.Disagreements {
+		if d.Exceeded {
+			exceeded++
+			require.Equal(t, "d", d.B)
+		}
+	}
+	require.Equal(t, 3, exceeded)
+}
+
+func TestCompareServersNoMajority(t *testing.T) {
+	query := fakeQuery(map[string]time.Duration{
+		"a": time.Millisecond,
+		"b": 50 * time.Millisecond,
+	})
+	r := CompareServers([]string{"a", "b"}, query, 10)
+	require.Empty(t, r.Falsetickers())
+	require.Empty(t, r.Unreachable())
+	require.Len(t, r.Disagreements, 1)
+	require.True(t, r.Disagreements[0].Exceeded)
+}
+
+func TestMedian(t *testing.T) {
+	require.Equal(t, float64(0), median(nil))
+	require.Equal(t, float64(2), median([]float64{3, 1, 2}))
+	require.Equal(t, 2.5, median([]float64{4, 1, 3, 2}))
+}
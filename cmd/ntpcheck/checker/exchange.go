@@ -0,0 +1,109 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+)
+
+// ntpModeServer is mode of server responses
+const ntpModeServer = 4
+
+// Exchange is a single NTP client request and the server response to it
+type Exchange struct {
+	Response *ntp.Packet
+	// when client sent the request, as echoed by server in origin timestamp (T1)
+	OriginTime time.Time
+	// when server received the request (T2)
+	ServerReceiveTime time.Time
+	// when server sent the response (T3)
+	ServerTransmitTime time.Time
+	// when client received the response (T4)
+	ClientReceiveTime time.Time
+}
+
+// Offset returns offset of server clock from the local one
+func (e *Exchange) Offset() time.Duration {
+	return time.Duration(ntp.Offset(e.OriginTime, e.ServerReceiveTime, e.ServerTransmitTime, e.ClientReceiveTime))
+}
+
+// Delay returns round trip network delay, without the time server spent processing the request
+func (e *Exchange) Delay() time.Duration {
+	return time.Duration(ntp.RoundTripDelay(e.OriginTime, e.ServerReceiveTime, e.ServerTransmitTime, e.ClientReceiveTime))
+}
+
+// RunExchange sends NTPv4 client request over conn and waits for the response to it until timeout.
+// Responses to earlier requests are skipped, Kiss-o'-Death responses are returned along with the error
+func RunExchange(conn net.Conn, timeout time.Duration) (*Exchange, error) {
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	sec, frac := ntp.Time(time.Now())
+	request, err := (&ntp.Packet{Settings: 0x23, TxTimeSec: sec, TxTimeFrac: frac}).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(request); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	buf := make([]byte, 1024)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		clientReceiveTime := time.Now()
+		if n < ntp.PacketSizeBytes {
+			continue
+		}
+		response, err := ntp.BytesToPacket(buf[:ntp.PacketSizeBytes])
+		if err != nil {
+			return nil, err
+		}
+		if response.OrigTimeSec != sec || response.OrigTimeFrac != frac || response.Settings&0x7 != ntpModeServer {
+			continue
+		}
+		e := &Exchange{
+			Response:           response,
+			OriginTime:         ntp.Unix(sec, frac),
+			ServerReceiveTime:  ntp.Unix(response.RxTimeSec, response.RxTimeFrac),
+			ServerTransmitTime: ntp.Unix(response.TxTimeSec, response.TxTimeFrac),
+			ClientReceiveTime:  clientReceiveTime,
+		}
+		if response.Stratum == 0 {
+			code := make([]byte, 4)
+			binary.BigEndian.PutUint32(code, response.ReferenceID)
+			return e, fmt.Errorf("kiss-o'-death %q", code)
+		}
+		return e, nil
+	}
+}
+
+// QueryServer sends single NTP client request to address and returns the exchange
+func QueryServer(address string, timeout time.Duration) (*Exchange, error) {
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return RunExchange(conn, timeout)
+}
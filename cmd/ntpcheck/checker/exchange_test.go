@@ -0,0 +1,94 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+// serveNTP answers single request on conn with server clock shifted by offset
+func serveNTP(t *testing.T, conn net.PacketConn, offset time.Duration, stratum uint8) {
+	buf := make([]byte, 1024)
+	n, addr, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	request, err := ntp.BytesToPacket(buf[:n])
+	require.NoError(t, err)
+	// stale response to some earlier request must be ignored
+	stale := &ntp.Packet{Settings: 0x24, Stratum: stratum, OrigTimeSec: request.TxTimeSec - 1}
+	b, err := stale.Bytes()
+	require.NoError(t, err)
+	_, err = conn.WriteTo(b, addr)
+	require.NoError(t, err)
+
+	rxSec, rxFrac := ntp.Time(time.Now().Add(offset))
+	txSec, txFrac := ntp.Time(time.Now().Add(offset))
+	response := &ntp.Packet{
+		Settings:     0x24,
+		Stratum:      stratum,
+		ReferenceID:  0x52415445, // RATE
+		OrigTimeSec:  request.TxTimeSec,
+		OrigTimeFrac: request.TxTimeFrac,
+		RxTimeSec:    rxSec,
+		RxTimeFrac:   rxFrac,
+		TxTimeSec:    txSec,
+		TxTimeFrac:   txFrac,
+	}
+	b, err = response.Bytes()
+	require.NoError(t, err)
+	_, err = conn.WriteTo(b, addr)
+	require.NoError(t, err)
+}
+
+func TestQueryServer(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	go serveNTP(t, conn, time.Hour, 1)
+
+	e, err := QueryServer(conn.LocalAddr().String(), time.Second)
+	require.NoError(t, err)
+	require.Equal(t, uint8(1), e.Response.Stratum)
+	require.InDelta(t, time.Hour, e.Offset(), float64(100*time.Millisecond))
+	require.GreaterOrEqual(t, e.Delay(), time.Duration(0))
+	require.Less(t, e.Delay(), 100*time.Millisecond)
+}
+
+func TestQueryServerKoD(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	go serveNTP(t, conn, 0, 0)
+
+	e, err := QueryServer(conn.LocalAddr().String(), time.Second)
+	require.EqualError(t, err, "kiss-o'-death \"RATE\"")
+	require.NotNil(t, e)
+}
+
+func TestQueryServerTimeout(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = QueryServer(conn.LocalAddr().String(), 50*time.Millisecond)
+	require.ErrorContains(t, err, "failed to read response")
+}
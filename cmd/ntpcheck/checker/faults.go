@@ -0,0 +1,346 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+)
+
+// Expectation is how the server is expected to react to a crafted packet
+type Expectation int
+
+// Possible expectations
+const (
+	// ExpectResponse means request is valid and server must answer it
+	ExpectResponse Expectation = iota
+	// ExpectDrop means request is invalid and server must not answer it
+	ExpectDrop
+	// ExpectAny means both are fine, behavior is just reported
+	ExpectAny
+)
+
+// Fault is a deliberately malformed or edge-case packet
+type Fault struct {
+	Name        string
+	Description string
+	Expect      Expectation
+	// Craft returns packet to send with given transmit timestamp
+	Craft func(sec, frac uint32) []byte
+}
+
+// FaultResult is how the server reacted to a Fault
+type FaultResult struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Responded   bool     `json:"responded"`
+	Size        int      `json:"size,omitempty"`
+	Leap        uint8    `json:"leap,omitempty"`
+	Version     uint8    `json:"version,omitempty"`
+	Mode        uint8    `json:"mode,omitempty"`
+	Stratum     uint8    `json:"stratum,omitempty"`
+	RefID       uint32   `json:"refid,omitempty"`
+	Problems    []string `json:"problems"`
+}
+
+// OK returns true if server behaved as expected
+func (r *FaultResult) OK() bool {
+	return len(r.Problems) == 0
+}
+
+func settings(leap, version, mode uint8) uint8 {
+	return leap<<6 | version<<3 | mode
+}
+
+// craftRequest returns crafter of NTPv4 client request modified by modify
+func craftRequest(modify func(p *ntp.Packet)) func(sec, frac uint32) []byte {
+	return func(sec, frac uint32) []byte {
+		p := &ntp.Packet{Settings: settings(0, 4, 3), TxTimeSec: sec, TxTimeFrac: frac}
+		modify(p)
+		b, _ := p.Bytes()
+		return b
+	}
+}
+
+// craftWithTrailer returns crafter of valid NTPv4 client request followed by trailer
+func craftWithTrailer(trailer []byte) func(sec, frac uint32) []byte {
+	request := craftRequest(func(_ *ntp.Packet) {})
+	return func(sec, frac uint32) []byte {
+		return append(request(sec, frac), trailer...)
+	}
+}
+
+func refID(s string) uint32 {
+	return binary.BigEndian.Uint32([]byte(s))
+}
+
+// Faults are all packets ntpcheck knows how to craft
+var Faults = []*Fault{
+	{
+		Name:        "valid",
+		Description: "valid NTPv4 client request, as a baseline",
+		Expect:      ExpectResponse,
+		Craft:       craftRequest(func(_ *ntp.Packet) {}),
+	},
+	{
+		Name:        "version3",
+		Description: "valid NTPv3 client request",
+		Expect:      ExpectResponse,
+		Craft:       craftRequest(func(p *ntp.Packet) { p.Settings = settings(0, 3, 3) }),
+	},
+	{
+		Name:        "version0",
+		Description: "client request with version 0",
+		Expect:      ExpectDrop,
+		Craft:       craftRequest(func(p *ntp.Packet) { p.Settings = settings(0, 0, 3) }),
+	},
+	{
+		Name:        "version5",
+		Description: "client request with version 5",
+		Expect:      ExpectDrop,
+		Craft:       craftRequest(func(p *ntp.Packet) { p.Settings = settings(0, 5, 3) }),
+	},
+	{
+		Name:        "version7",
+		Description: "client request with version 7",
+		Expect:      ExpectDrop,
+		Craft:       craftRequest(func(p *ntp.Packet) { p.Settings = settings(0, 7, 3) }),
+	},
+	{
+		Name:        "mode0",
+		Description: "packet with reserved mode 0",
+		Expect:      ExpectDrop,
+		Craft:       craftRequest(func(p *ntp.Packet) { p.Settings = settings(0, 4, 0) }),
+	},
+	{
+		Name:        "mode4",
+		Description: "server response sent to the server, answering it may cause loops",
+		Expect:      ExpectDrop,
+		Craft:       craftRequest(func(p *ntp.Packet) { p.Settings = settings(0, 4, 4); p.Stratum = 1 }),
+	},
+	{
+		Name:        "mode5",
+		Description: "broadcast packet",
+		Expect:      ExpectDrop,
+		Craft:       craftRequest(func(p *ntp.Packet) { p.Settings = settings(0, 4, 5); p.Stratum = 1 }),
+	},
+	{
+		Name:        "mode6",
+		Description: "control (mode 6) read status request, answering it leaks server details and amplifies traffic",
+		Expect:      ExpectDrop,
+		Craft: func(_, _ uint32) []byte {
+			return []byte{settings(0, 2, 6), 1, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0}
+		},
+	},
+	{
+		Name:        "mode7",
+		Description: "private (mode 7) monlist request, well known amplification vector",
+		Expect:      ExpectDrop,
+		Craft: func(_, _ uint32) []byte {
+			b := make([]byte, 192)
+			copy(b, []byte{settings(0, 2, 7), 0, 3, 42})
+			return b
+		},
+	},
+	{
+		Name:        "leap-alarm",
+		Description: "client request with leap indicator set to alarm (unsynchronized client)",
+		Expect:      ExpectResponse,
+		Craft:       craftRequest(func(p *ntp.Packet) { p.Settings = settings(3, 4, 3) }),
+	},
+	{
+		Name:        "leap-insert",
+		Description: "client request announcing leap second insertion",
+		Expect:      ExpectAny,
+		Craft:       craftRequest(func(p *ntp.Packet) { p.Settings = settings(1, 4, 3) }),
+	},
+	{
+		Name:        "kod-rate",
+		Description: "client request carrying RATE Kiss-o'-Death code, which only servers may send",
+		Expect:      ExpectResponse,
+		Craft:       craftRequest(func(p *ntp.Packet) { p.ReferenceID = refID("RATE") }),
+	},
+	{
+		Name:        "kod-deny",
+		Description: "client request carrying DENY Kiss-o'-Death code, which only servers may send",
+		Expect:      ExpectResponse,
+		Craft:       craftRequest(func(p *ntp.Packet) { p.ReferenceID = refID("DENY") }),
+	},
+	{
+		Name:        "stratum16",
+		Description: "client request with stratum 16 (unsynchronized)",
+		Expect:      ExpectResponse,
+		Craft:       craftRequest(func(p *ntp.Packet) { p.Stratum = 16 }),
+	},
+	{
+		Name:        "root-dispersion",
+		Description: "client request with maximal root delay and dispersion",
+		Expect:      ExpectResponse,
+		Craft: craftRequest(func(p *ntp.Packet) {
+			p.RootDelay = 0xffffffff
+			p.RootDispersion = 0xffffffff
+		}),
+	},
+	{
+		Name:        "era-end",
+		Description: "client request with transmit timestamp at the end of NTP era 0",
+		Expect:      ExpectResponse,
+		Craft: func(_, _ uint32) []byte {
+			return craftRequest(func(_ *ntp.Packet) {})(0xffffffff, 0xfffffff0)
+		},
+	},
+	{
+		Name:        "zero-transmit",
+		Description: "client request with zero transmit timestamp",
+		Expect:      ExpectAny,
+		Craft: func(_, _ uint32) []byte {
+			return craftRequest(func(_ *ntp.Packet) {})(0, 0)
+		},
+	},
+	{
+		Name:        "short",
+		Description: "client request truncated to 47 bytes",
+		Expect:      ExpectDrop,
+		Craft: func(sec, frac uint32) []byte {
+			return craftRequest(func(_ *ntp.Packet) {})(sec, frac)[:ntp.PacketSizeBytes-1]
+		},
+	},
+	{
+		Name:        "zero",
+		Description: "48 zero bytes",
+		Expect:      ExpectDrop,
+		Craft: func(_, _ uint32) []byte {
+			return make([]byte, ntp.PacketSizeBytes)
+		},
+	},
+	{
+		Name:        "bad-extension",
+		Description: "client request with extension field of invalid length",
+		Expect:      ExpectDrop,
+		Craft:       craftWithTrailer([]byte{0x12, 0x34, 0x00, 0x03, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}),
+	},
+	{
+		Name:        "unknown-extension",
+		Description: "client request with unknown extension field, which must be ignored",
+		Expect:      ExpectResponse,
+		Craft:       craftWithTrailer(ntp.AppendExtensionField(nil, &ntp.ExtensionField{Type: 0x1234, Value: make([]byte, 24)})),
+	},
+	{
+		Name:        "unknown-key",
+		Description: "client request with MAC of unknown key",
+		Expect:      ExpectAny,
+		Craft:       craftWithTrailer(append([]byte{0xde, 0xad, 0xbe, 0xef}, make([]byte, 16)...)),
+	},
+	{
+		Name:        "garbage-trailer",
+		Description: "client request followed by 8 bytes which are neither extension field nor MAC",
+		Expect:      ExpectAny,
+		Craft:       craftWithTrailer([]byte{1, 2, 3, 4, 5, 6, 7, 8}),
+	},
+}
+
+// checkResponse lists problems with server response to request
+func checkResponse(request, response []byte) []string {
+	problems := []string{}
+	if len(response) > len(request) {
+		problems = append(problems, fmt.Sprintf("response is %d bytes, larger than %d bytes request", len(response), len(request)))
+	}
+	if len(response) < ntp.PacketSizeBytes {
+		return append(problems, fmt.Sprintf("response is truncated to %d bytes", len(response)))
+	}
+	p, err := ntp.BytesToPacket(response[:ntp.PacketSizeBytes])
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	if mode := p.Settings & 0x7; mode != ntpModeServer {
+		problems = append(problems, fmt.Sprintf("response mode is %d instead of %d", mode, ntpModeServer))
+	}
+	// Kiss-o'-Death responses may not have anything else set
+	if p.Stratum == 0 {
+		return problems
+	}
+	if version := (p.Settings >> 3) & 0x7; len(request) > 0 && version != (request[0]>>3)&0x7 {
+		problems = append(problems, fmt.Sprintf("response version %d doesn't match request version %d", version, (request[0]>>3)&0x7))
+	}
+	if len(request) >= ntp.PacketSizeBytes && (p.OrigTimeSec != binary.BigEndian.Uint32(request[40:]) || p.OrigTimeFrac != binary.BigEndian.Uint32(request[44:])) {
+		problems = append(problems, "origin timestamp doesn't match transmit timestamp of the request")
+	}
+	return problems
+}
+
+// RunFault sends crafted packet over conn and checks server reaction to it, waiting for response until timeout
+func RunFault(conn net.Conn, f *Fault, timeout time.Duration) (*FaultResult, error) {
+	r := &FaultResult{Name: f.Name, Description: f.Description, Problems: []string{}}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	sec, frac := ntp.Time(time.Now())
+	request := f.Craft(sec, frac)
+	if _, err := conn.Write(request); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	buf := make([]byte, 2048)
+	n, err := conn.Read(buf)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		if f.Expect == ExpectResponse {
+			r.Problems = append(r.Problems, "no response to valid request")
+		}
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	response := buf[:n]
+	r.Responded = true
+	r.Size = n
+	if n >= ntp.PacketSizeBytes {
+		r.Leap = response[0] >> 6
+		r.Version = (response[0] >> 3) & 0x7
+		r.Mode = response[0] & 0x7
+		r.Stratum = response[1]
+		r.RefID = binary.BigEndian.Uint32(response[12:])
+	}
+	if f.Expect == ExpectDrop {
+		r.Problems = append(r.Problems, "responded to invalid packet")
+	}
+	r.Problems = append(r.Problems, checkResponse(request, response)...)
+	return r, nil
+}
+
+// InjectFaults sends each of the faults to the server at address from a new socket, so late responses don't mix up
+func InjectFaults(address string, faults []*Fault, timeout time.Duration) ([]*FaultResult, error) {
+	results := []*FaultResult{}
+	for _, f := range faults {
+		conn, err := net.DialTimeout("udp", address, timeout)
+		if err != nil {
+			return nil, err
+		}
+		r, err := RunFault(conn, f, timeout)
+		conn.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+// serveFaults answers requests on conn with respond until conn is closed, nil response means drop
+func serveFaults(conn net.PacketConn, respond func(request []byte) []byte) {
+	buf := make([]byte, 2048)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if response := respond(buf[:n]); response != nil {
+			_, _ = conn.WriteTo(response, addr)
+		}
+	}
+}
+
+// goodResponse answers valid NTPv1-4 client requests only, like our responder does
+func goodResponse(request []byte) []byte {
+	if len(request) < ntp.PacketSizeBytes {
+		return nil
+	}
+	p, err := ntp.BytesToPacket(request[:ntp.PacketSizeBytes])
+	if err != nil || !p.ValidSettingsFormat() {
+		return nil
+	}
+	if _, _, err := ntp.ParseExtensionFields(request[ntp.PacketSizeBytes:]); err != nil {
+		return nil
+	}
+	response := &ntp.Packet{
+		Settings:     p.Settings&0x38 | ntpModeServer,
+		Stratum:      1,
+		OrigTimeSec:  p.TxTimeSec,
+		OrigTimeFrac: p.TxTimeFrac,
+	}
+	b, _ := response.Bytes()
+	return b
+}
+
+func TestCheckResponse(t *testing.T) {
+	request := craftRequest(func(_ *ntp.Packet) {})(1, 2)
+	require.Empty(t, checkResponse(request, goodResponse(request)))
+
+	response := goodResponse(request)
+	response[0] = settings(0, 3, 5)
+	response[31]++
+	require.Equal(t, []string{
+		"response mode is 5 instead of 4",
+		"response version 3 doesn't match request version 4",
+		"origin timestamp doesn't match transmit timestamp of the request",
+	}, checkResponse(request, response))
+
+	require.Equal(t, []string{
+		"response is 60 bytes, larger than 48 bytes request",
+	}, checkResponse(request, append(goodResponse(request), make([]byte, 12)...)))
+
+	require.Equal(t, []string{
+		"response is truncated to 12 bytes",
+	}, checkResponse(request, goodResponse(request)[:12]))
+
+	// Kiss-o'-Death
+	kod := &ntp.Packet{Settings: settings(3, 3, 4), ReferenceID: refID("RATE")}
+	b, err := kod.Bytes()
+	require.NoError(t, err)
+	require.Empty(t, checkResponse(request, b))
+}
+
+func TestFaultsCraft(t *testing.T) {
+	names := map[string]bool{}
+	for _, f := range Faults {
+		require.False(t, names[f.Name], "duplicate fault %s", f.Name)
+		names[f.Name] = true
+		require.NotEmpty(t, f.Craft(1, 2), f.Name)
+	}
+	require.Len(t, Faults[0].Craft(1, 2), ntp.PacketSizeBytes)
+}
+
+func TestInjectFaultsGoodServer(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	go serveFaults(conn, goodResponse)
+
+	results, err := InjectFaults(conn.LocalAddr().String(), Faults, 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, results, len(Faults))
+	for _, r := range results {
+		require.True(t, r.OK(), "%s: %v", r.Name, r.Problems)
+	}
+	require.True(t, results[0].Responded)
+	require.Equal(t, uint8(4), results[0].Mode)
+	require.Equal(t, uint8(1), results[0].Stratum)
+}
+
+func TestInjectFaultsBadServer(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	// echo every packet back, doubled
+	go serveFaults(conn, func(request []byte) []byte { return append(request, request...) })
+
+	faults := []*Fault{Faults[0], Faults[2]}
+	results, err := InjectFaults(conn.LocalAddr().String(), faults, 100*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"response is 96 bytes, larger than 48 bytes request",
+		"response mode is 3 instead of 4",
+	}, results[0].Problems)
+	require.Equal(t, []string{
+		"responded to invalid packet",
+		"response is 96 bytes, larger than 48 bytes request",
+		"response mode is 3 instead of 4",
+	}, results[1].Problems)
+}
+
+func TestInjectFaultsNoResponse(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	go serveFaults(conn, func(_ []byte) []byte { return nil })
+
+	results, err := InjectFaults(conn.LocalAddr().String(), Faults[:3], 50*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, []string{"no response to valid request"}, results[0].Problems)
+	require.False(t, results[0].Responded)
+	require.True(t, results[2].OK())
+}
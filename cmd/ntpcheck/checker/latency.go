@@ -0,0 +1,143 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"errors"
+	"net"
+	"os"
+	"slices"
+	"time"
+)
+
+// LatencySample is a breakdown of a single exchange, all values are in ms.
+// Outbound and Inbound include clock offset, so they are only meaningful relative to each other
+type LatencySample struct {
+	Offset float64
+	Delay  float64
+	// server receive minus client transmit (T2-T1)
+	Outbound float64
+	// client receive minus server transmit (T4-T3)
+	Inbound float64
+	// server transmit minus server receive (T3-T2)
+	Processing float64
+}
+
+// NewLatencySample computes latency breakdown of the exchange
+func NewLatencySample(e *Exchange) LatencySample {
+	return LatencySample{
+		Offset:     durationToMS(e.Offset()),
+		Delay:      durationToMS(e.Delay()),
+		Outbound:   durationToMS(e.ServerReceiveTime.Sub(e.OriginTime)),
+		Inbound:    durationToMS(e.ClientReceiveTime.Sub(e.ServerTransmitTime)),
+		Processing: durationToMS(e.ServerTransmitTime.Sub(e.ServerReceiveTime)),
+	}
+}
+
+// LatencyReport separates path and server contribution to offset errors over repeated exchanges, all values are in ms.
+// Exchange with the lowest delay is taken as the baseline, as it is the least affected by queuing on the path.
+type LatencyReport struct {
+	Samples []LatencySample
+	Lost    int
+
+	MinDelay    float64
+	MedianDelay float64
+	// offset measured by the baseline exchange, best estimate of the real one
+	BaselineOffset float64
+	MedianOffset   float64
+	// how much median outbound and inbound latency exceed the baseline ones, independent of clock offset
+	OutboundQueuing float64
+	InboundQueuing  float64
+	// median offset error caused by queuing being different in each direction,
+	// positive when requests are delayed more than responses
+	PathAsymmetry float64
+	// offset error caused by constant path asymmetry can't be measured, but it's limited by half of the delay
+	MaxAsymmetryError float64
+
+	MinProcessing    float64
+	MedianProcessing float64
+	MaxProcessing    float64
+}
+
+// ServerBound returns true if variation of server processing time is larger than variation of network delay
+func (r *LatencyReport) ServerBound() bool {
+	return r.MedianProcessing-r.MinProcessing > r.MedianDelay-r.MinDelay
+}
+
+// AnalyzeLatency computes latency report from exchanges
+func AnalyzeLatency(exchanges []*Exchange, lost int) *LatencyReport {
+	r := &LatencyReport{Lost: lost}
+	if len(exchanges) == 0 {
+		return r
+	}
+	r.Samples = make([]LatencySample, len(exchanges))
+	for i, e := range exchanges {
+		r.Samples[i] = NewLatencySample(e)
+	}
+	baseline := slices.MinFunc(r.Samples, func(a, b LatencySample) int {
+		if a.Delay < b.Delay {
+			return -1
+		}
+		if a.Delay > b.Delay {
+			return 1
+		}
+		return 0
+	})
+	var delays, offsets, outbound, inbound, asymmetry, processing []float64
+	for _, s := range r.Samples {
+		delays = append(delays, s.Delay)
+		offsets = append(offsets, s.Offset)
+		outbound = append(outbound, s.Outbound-baseline.Outbound)
+		inbound = append(inbound, s.Inbound-baseline.Inbound)
+		asymmetry = append(asymmetry, s.Offset-baseline.Offset)
+		processing = append(processing, s.Processing)
+	}
+	r.MinDelay = baseline.Delay
+	r.MedianDelay = median(delays)
+	r.BaselineOffset = baseline.Offset
+	r.MedianOffset = median(offsets)
+	r.OutboundQueuing = median(outbound)
+	r.InboundQueuing = median(inbound)
+	r.PathAsymmetry = median(asymmetry)
+	r.MaxAsymmetryError = baseline.Delay / 2
+	r.MinProcessing = slices.Min(processing)
+	r.MedianProcessing = median(processing)
+	r.MaxProcessing = slices.Max(processing)
+	return r
+}
+
+// ProbeLatency runs count exchanges over conn, interval apart.
+// Timed out exchanges are counted as lost, any other error stops the probe
+func ProbeLatency(conn net.Conn, count int, interval, timeout time.Duration) ([]*Exchange, int, error) {
+	exchanges := []*Exchange{}
+	lost := 0
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		e, err := RunExchange(conn, timeout)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			lost++
+			continue
+		}
+		if err != nil {
+			return exchanges, lost, err
+		}
+		exchanges = append(exchanges, e)
+	}
+	return exchanges, lost, nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// latencyExchange builds exchange with server clock ahead by offset and given one-way latencies
+func latencyExchange(start time.Time, offset, outbound, processing, inbound time.Duration) *Exchange {
+	return &Exchange{
+		OriginTime:         start,
+		ServerReceiveTime:  start.Add(offset + outbound),
+		ServerTransmitTime: start.Add(offset + outbound + processing),
+		ClientReceiveTime:  start.Add(outbound + processing + inbound),
+	}
+}
+
+func TestNewLatencySample(t *testing.T) {
+	e := latencyExchange(time.Unix(1700000000, 0), 10*time.Millisecond, 3*time.Millisecond, time.Millisecond, 5*time.Millisecond)
+	s := NewLatencySample(e)
+	require.InDelta(t, 9.0, s.Offset, 1e-9)
+	require.InDelta(t, 8.0, s.Delay, 1e-9)
+	require.InDelta(t, 13.0, s.Outbound, 1e-9)
+	require.InDelta(t, -5.0, s.Inbound, 1e-9)
+	require.InDelta(t, 1.0, s.Processing, 1e-9)
+}
+
+func TestAnalyzeLatency(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	offset := 10 * time.Millisecond
+	exchanges := []*Exchange{
+		// baseline, symmetric 2ms path
+		latencyExchange(start, offset, 2*time.Millisecond, 100*time.Microsecond, 2*time.Millisecond),
+		// requests get queued
+		latencyExchange(start, offset, 4*time.Millisecond, 100*time.Microsecond, 2*time.Millisecond),
+		latencyExchange(start, offset, 6*time.Millisecond, 300*time.Microsecond, 2*time.Millisecond),
+	}
+	r := AnalyzeLatency(exchanges, 1)
+	require.Len(t, r.Samples, 3)
+	require.Equal(t, 1, r.Lost)
+	require.InDelta(t, 4.0, r.MinDelay, 1e-9)
+	require.InDelta(t, 6.0, r.MedianDelay, 1e-9)
+	require.InDelta(t, 10.0, r.BaselineOffset, 1e-9)
+	require.InDelta(t, 11.0, r.MedianOffset, 1e-9)
+	require.InDelta(t, 2.0, r.OutboundQueuing, 1e-9)
+	require.InDelta(t, 0.0, r.InboundQueuing, 1e-9)
+	require.InDelta(t, 1.0, r.PathAsymmetry, 1e-9)
+	require.InDelta(t, 2.0, r.MaxAsymmetryError, 1e-9)
+	require.InDelta(t, 0.1, r.MinProcessing, 1e-9)
+	require.InDelta(t, 0.1, r.MedianProcessing, 1e-9)
+	require.InDelta(t, 0.3, r.MaxProcessing, 1e-9)
+	require.False(t, r.ServerBound())
+
+	// stable path, busy server
+	exchanges = []*Exchange{
+		latencyExchange(start, offset, 2*time.Millisecond, 100*time.Microsecond, 2*time.Millisecond),
+		latencyExchange(start, offset, 2*time.Millisecond, 3*time.Millisecond, 2*time.Millisecond),
+		latencyExchange(start, offset, 2*time.Millisecond, 5*time.Millisecond, 2*time.Millisecond),
+	}
+	r = AnalyzeLatency(exchanges, 0)
+	require.InDelta(t, 0.0, r.PathAsymmetry, 1e-9)
+	require.InDelta(t, 3.0, r.MedianProcessing, 1e-9)
+	require.True(t, r.ServerBound())
+}
+
+func TestAnalyzeLatencyEmpty(t *testing.T) {
+	r := AnalyzeLatency(nil, 3)
+	require.Empty(t, r.Samples)
+	require.Equal(t, 3, r.Lost)
+}
+
+func TestProbeLatency(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	go func() {
+		for i := 0; i < 3; i++ {
+			serveNTP(t, conn, time.Hour, 1)
+		}
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	exchanges, lost, err := ProbeLatency(client, 3, time.Millisecond, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 0, lost)
+	require.Len(t, exchanges, 3)
+	r := AnalyzeLatency(exchanges, lost)
+	require.InDelta(t, durationToMS(time.Hour), r.BaselineOffset, 100)
+}
+
+func TestProbeLatencyLost(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	exchanges, lost, err := ProbeLatency(client, 2, time.Millisecond, 20*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, 2, lost)
+	require.Empty(t, exchanges)
+}
+
+func TestProbeLatencyKoD(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	go serveNTP(t, conn, 0, 0)
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	_, _, err = ProbeLatency(client, 3, time.Millisecond, time.Second)
+	require.EqualError(t, err, "kiss-o'-death \"RATE\"")
+}
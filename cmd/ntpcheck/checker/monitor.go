@@ -0,0 +1,127 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"encoding/csv"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// MonitorSample is a single measurement taken in monitoring mode
+type MonitorSample struct {
+	Time time.Time
+	// stats of the local NTP daemon, nil if it wasn't checked or check failed
+	Stats *NTPStats
+	Error string
+	// offsets of servers queried directly
+	Servers []*ServerOffset
+}
+
+// TakeSample checks local NTP daemon with check (if not nil) and queries servers directly with query
+func TakeSample(check func() (*NTPCheckResult, error), servers []string, query QueryFunc) *MonitorSample {
+	s := &MonitorSample{Time: time.Now()}
+	if check != nil {
+		stats, err := sampleStats(check)
+		if err != nil {
+			s.Error = err.Error()
+		} else {
+			s.Stats = stats
+		}
+	}
+	if len(servers) > 0 {
+		s.Servers = QueryServers(servers, query)
+	}
+	return s
+}
+
+func sampleStats(check func() (*NTPCheckResult, error)) (*NTPStats, error) {
+	result, err := check()
+	if err != nil {
+		return nil, err
+	}
+	return NewNTPStats(result)
+}
+
+var monitorCSVColumns = []string{"time", "offset", "correction", "frequency", "root_delay", "peer_offset", "peer_delay", "peer_jitter", "peer_stratum", "peer_count"}
+
+// MonitorCSVWriter writes monitoring samples as CSV, one row per sample.
+// Offsets and delays are in ms, columns of servers queried directly are named after them, values not measured are left empty
+type MonitorCSVWriter struct {
+	w             *csv.Writer
+	servers       []string
+	headerWritten bool
+}
+
+// NewMonitorCSVWriter returns MonitorCSVWriter for samples with given directly queried servers
+func NewMonitorCSVWriter(w io.Writer, servers []string) *MonitorCSVWriter {
+	return &MonitorCSVWriter{w: csv.NewWriter(w), servers: servers}
+}
+
+// SkipHeader makes Write omit the header, for appending to files which already have it
+func (m *MonitorCSVWriter) SkipHeader() {
+	m.headerWritten = true
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Write writes the sample, preceded by the header on the first call
+func (m *MonitorCSVWriter) Write(s *MonitorSample) error {
+	if !m.headerWritten {
+		header := slices.Clone(monitorCSVColumns)
+		for _, server := range m.servers {
+			header = append(header, server+"_offset", server+"_delay")
+		}
+		if err := m.w.Write(header); err != nil {
+			return err
+		}
+		m.headerWritten = true
+	}
+	row := make([]string, len(monitorCSVColumns), len(monitorCSVColumns)+2*len(m.servers))
+	row[0] = s.Time.UTC().Format(time.RFC3339Nano)
+	if st := s.Stats; st != nil {
+		copy(row[1:], []string{
+			formatFloat(st.Offset),
+			formatFloat(st.Correction),
+			formatFloat(st.Frequency),
+			formatFloat(st.RootDelay),
+			formatFloat(st.PeerOffset),
+			formatFloat(st.PeerDelay),
+			formatFloat(st.PeerJitter),
+			strconv.Itoa(st.PeerStratum),
+			strconv.Itoa(st.PeerCount),
+		})
+	}
+	for _, server := range m.servers {
+		offset, delay := "", ""
+		for _, so := range s.Servers {
+			if so.Server == server && so.Error == "" {
+				offset, delay = formatFloat(so.Offset), formatFloat(so.Delay)
+			}
+		}
+		row = append(row, offset, delay)
+	}
+	if err := m.w.Write(row); err != nil {
+		return err
+	}
+	m.w.Flush()
+	return m.w.Error()
+}
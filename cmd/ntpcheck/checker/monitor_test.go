@@ -0,0 +1,86 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/facebook/time/ntp/control"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTakeSample(t *testing.T) {
+	check := func() (*NTPCheckResult, error) {
+		return &NTPCheckResult{
+			SysVars: &SystemVariables{Offset: 0.003, RootDelay: 3.14},
+			Peers: map[uint16]*Peer{
+				0: {Selection: control.SelSYSPeer, Offset: 0.045, Delay: 3.21, Stratum: 4},
+			},
+		}, nil
+	}
+	query := fakeQuery(map[string]time.Duration{"a": time.Millisecond})
+	s := TakeSample(check, []string{"a", "b"}, query)
+	require.NotNil(t, s.Stats)
+	require.Empty(t, s.Error)
+	require.Equal(t, 0.003, s.Stats.Offset)
+	require.Len(t, s.Servers, 2)
+	require.InDelta(t, 1, s.Servers[0].Offset, 0.001)
+	require.Equal(t, "timeout", s.Servers[1].Error)
+
+	s = TakeSample(func() (*NTPCheckResult, error) { return nil, fmt.Errorf("no daemon") }, nil, query)
+	require.Nil(t, s.Stats)
+	require.Equal(t, "no daemon", s.Error)
+	require.Empty(t, s.Servers)
+
+	s = TakeSample(nil, []string{"a"}, query)
+	require.Nil(t, s.Stats)
+	require.Empty(t, s.Error)
+	require.Len(t, s.Servers, 1)
+}
+
+func TestMonitorCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewMonitorCSVWriter(&buf, []string{"a:123", "b:123"})
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, w.Write(&MonitorSample{
+		Time:  ts,
+		Stats: &NTPStats{Offset: 0.003, RootDelay: 3.14, PeerStratum: 4, PeerCount: 2},
+		Servers: []*ServerOffset{
+			{Server: "a:123", Offset: 1.5, Delay: 0.25},
+			{Server: "b:123", Error: "timeout"},
+		},
+	}))
+	require.NoError(t, w.Write(&MonitorSample{Time: ts.Add(10 * time.Second), Error: "no daemon"}))
+	want := `time,offset,correction,frequency,root_delay,peer_offset,peer_delay,peer_jitter,peer_stratum,peer_count,a:123_offset,a:123_delay,b:123_offset,b:123_delay
+2024-01-01T00:00:00Z,0.003,0,0,3.14,0,0,0,4,2,1.5,0.25,,
+2024-01-01T00:00:10Z,,,,,,,,,,,,,
+`
+	require.Equal(t, want, buf.String())
+}
+
+func TestMonitorCSVWriterSkipHeader(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewMonitorCSVWriter(&buf, nil)
+	w.SkipHeader()
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, w.Write(&MonitorSample{Time: ts, Stats: &NTPStats{Offset: 0.003, PeerStratum: 4, PeerCount: 2}}))
+	require.Equal(t, "2024-01-01T00:00:00Z,0.003,0,0,0,0,0,0,4,2\n", buf.String())
+}
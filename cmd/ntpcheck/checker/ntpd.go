@@ -19,7 +19,9 @@ package checker
 import (
 	"fmt"
 	"io"
+	"strings"
 
+	"github.com/facebook/time/ntp/auth"
 	"github.com/facebook/time/ntp/control"
 
 	log "github.com/sirupsen/logrus"
@@ -27,6 +29,9 @@ import (
 
 var vnMode = control.MakeVnMode(3, control.Mode)
 
+// ControlKey, if set, is used by NTPCheck to authenticate control queries, for ntpd instances which require it
+var ControlKey *auth.Key
+
 type ntpClient interface {
 	Communicate(packet *control.NTPControlMsgHead) (*control.NTPControlMsg, error)
 	CommunicateWithData(packet *control.NTPControlMsgHead, data []uint8) (*control.NTPControlMsg, error)
@@ -80,6 +85,20 @@ func (n *NTPCheck) ReadServerVariables() (*control.NTPControlMsg, error) {
 	return n.Client.CommunicateWithData(packet, []uint8(vars))
 }
 
+// ReadVariablesByName sends Read Variables packet for associationID asking for variables by names
+// (default set if names are empty) and returns parsed variables
+func (n *NTPCheck) ReadVariablesByName(associationID uint16, names []string) (map[string]string, error) {
+	packet := n.getReadVariablesPacket(associationID)
+	response, err := n.Client.CommunicateWithData(packet, []uint8(strings.Join(names, ",")))
+	if err != nil {
+		return nil, err
+	}
+	if response.HasError() {
+		return nil, fmt.Errorf("server returned error: %s", control.ErrorDesc[response.GetErrorCode()&0x7])
+	}
+	return response.GetAssociationInfo()
+}
+
 // Run is the main method of NTPCheck and it fetches all information to return NTPCheckResult.
 // Essentially we request system status that contains list of peers, and then request variables
 // for our server and each peer individually.
@@ -189,6 +208,6 @@ func (n *NTPCheck) ServerStats() (*ServerStats, error) {
 // NewNTPCheck is a constructor for NTPCheck
 func NewNTPCheck(conn io.ReadWriter) *NTPCheck {
 	return &NTPCheck{
-		Client: &control.NTPClient{Sequence: 1, Connection: conn},
+		Client: &control.NTPClient{Sequence: 1, Connection: conn, Key: ControlKey},
 	}
 }
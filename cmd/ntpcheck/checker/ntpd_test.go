@@ -173,3 +173,33 @@ func TestNTPCheckServerStats(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, want, got)
 }
+
+func TestNTPCheckReadVariablesByName(t *testing.T) {
+	prepdOutputs := []*control.NTPControlMsg{
+		{
+			NTPControlMsgHead: control.NTPControlMsgHead{
+				VnMode:        vnMode,
+				REMOp:         control.MakeREMOp(true, false, false, control.OpReadVariables),
+				AssociationID: 0,
+			},
+			Data: []uint8("authdelay=0.000000,ss_badauth=5"),
+		},
+		{
+			NTPControlMsgHead: control.NTPControlMsgHead{
+				VnMode: vnMode,
+				REMOp:  control.MakeREMOp(true, true, false, control.OpReadVariables),
+				Status: 0x0500,
+			},
+		},
+	}
+	check := &NTPCheck{
+		Client: &fakeNTPClient{readCount: 0, outputs: prepdOutputs},
+	}
+
+	got, err := check.ReadVariablesByName(0, []string{"authdelay", "ss_badauth"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"authdelay": "0.000000", "ss_badauth": "5"}, got)
+
+	_, err = check.ReadVariablesByName(0, []string{"nope"})
+	require.EqualError(t, err, "server returned error: unknown variable")
+}
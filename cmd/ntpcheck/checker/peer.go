@@ -75,7 +75,8 @@ func sanityCheckPeerVars(p *Peer) error {
 	if p == nil {
 		return errors.New("No peer")
 	}
-	if p.Stratum == 0 {
+	// reference clocks have stratum 0 unless it's fudged
+	if _, refclock := ntpdRefClockType(p.SRCAdr); p.Stratum == 0 && !refclock {
 		return errors.New("Incomplete data, stratum 0 in peer variables")
 	}
 	if p.PPoll == 0 || p.HPoll == 0 {
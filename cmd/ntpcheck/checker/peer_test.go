@@ -85,6 +85,32 @@ func TestNewPeerFromNTP(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "refclock with stratum 0",
+			p: &control.NTPControlMsg{
+				NTPControlMsgHead: control.NTPControlMsgHead{
+					VnMode: control.MakeVnMode(3, control.Mode),
+					REMOp:  control.OpReadVariables,
+					Status: (&control.PeerStatusWord{
+						PeerStatus:    control.PeerStatus{Reachable: true, Configured: true},
+						PeerSelection: control.SelPPSPeer,
+					}).Word(),
+				},
+				Data: []uint8("srcadr=127.127.22.0,stratum=0,refid=PPS,hpoll=4,ppoll=4"),
+			},
+			want: &Peer{
+				SRCAdr:     "127.127.22.0",
+				RefID:      "PPS",
+				HPoll:      4,
+				PPoll:      4,
+				Flashers:   []string{},
+				Configured: true,
+				Reachable:  true,
+				Selection:  control.SelPPSPeer,
+				Condition:  control.PeerSelect[control.SelPPSPeer],
+			},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,199 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/facebook/time/ntp/chrony"
+	"github.com/facebook/time/ntp/control"
+	log "github.com/sirupsen/logrus"
+)
+
+// ntpd refclock addresses are 127.127.<driver type>.<unit>
+const ntpdRefClockPrefix = "127.127."
+
+// ntpd refclock driver type of PPS (ATOM) driver
+const ntpdDriverPPS = 22
+
+// ntpdDrivers maps ntpd refclock driver types to names, as described in http://doc.ntp.org/current-stable/refclock.html
+var ntpdDrivers = map[int]string{
+	1:  "LOCAL",
+	4:  "WWVB_SPEC",
+	6:  "IRIG",
+	8:  "PARSE",
+	18: "ACTS",
+	20: "GPS_NMEA",
+	22: "PPS",
+	26: "GPS_HP",
+	28: "SHM",
+	29: "GPS_PALISADE",
+	30: "GPS_ONCORE",
+	40: "JJY",
+	45: "TSYNCPCI",
+	46: "GPSD_JSON",
+}
+
+// RefClock is status of a reference clock (GNSS receiver, PPS signal, etc.) of NTP server
+type RefClock struct {
+	// srcadr for ntpd, refid for chrony
+	Name string `json:"name"`
+	// driver name, ntpd only
+	Driver string `json:"driver,omitempty"`
+	RefID  string `json:"refid"`
+	PPS    bool   `json:"pps"`
+	// Locked means clock replied to the last poll and is used or can be used for synchronization
+	Locked bool   `json:"locked"`
+	State  string `json:"state"`
+	Reach  uint8  `json:"reach"`
+	// offset and jitter are in ms
+	Offset float64 `json:"offset"`
+	Jitter float64 `json:"jitter"`
+	// clock driver status, ntpd only
+	Device    string `json:"device,omitempty"`
+	Timecode  string `json:"timecode,omitempty"`
+	Polls     int    `json:"polls,omitempty"`
+	NoReply   int    `json:"noreply,omitempty"`
+	BadFormat int    `json:"badformat,omitempty"`
+	BadData   int    `json:"baddata,omitempty"`
+}
+
+// ntpdRefClockType returns driver type if srcadr is ntpd refclock address
+func ntpdRefClockType(srcadr string) (int, bool) {
+	if !strings.HasPrefix(srcadr, ntpdRefClockPrefix) {
+		return 0, false
+	}
+	t, _, _ := strings.Cut(strings.TrimPrefix(srcadr, ntpdRefClockPrefix), ".")
+	driver, err := strconv.Atoi(t)
+	if err != nil {
+		return 0, false
+	}
+	return driver, true
+}
+
+// NewRefClockFromNTP constructs RefClock from ntpd peer with refclock address and its clock variables
+func NewRefClockFromNTP(p *Peer, clockVars map[string]string) *RefClock {
+	driver, _ := ntpdRefClockType(p.SRCAdr)
+	driverName, ok := ntpdDrivers[driver]
+	if !ok {
+		driverName = fmt.Sprintf("type %d", driver)
+	}
+	// it's ok to have some fields missing, thus we don't check for errors below
+	polls, _ := strconv.Atoi(clockVars["poll"])
+	noreply, _ := strconv.Atoi(clockVars["noreply"])
+	badformat, _ := strconv.Atoi(clockVars["badformat"])
+	baddata, _ := strconv.Atoi(clockVars["baddata"])
+	usable := p.Selection == control.SelSYSPeer || p.Selection == control.SelPPSPeer || p.Selection == control.SelCandidate
+	return &RefClock{
+		Name:      p.SRCAdr,
+		Driver:    driverName,
+		RefID:     p.RefID,
+		PPS:       driver == ntpdDriverPPS || p.Selection == control.SelPPSPeer,
+		Locked:    usable && p.Reach&1 != 0,
+		State:     control.PeerSelect[p.Selection&0x7],
+		Reach:     p.Reach,
+		Offset:    p.Offset,
+		Jitter:    p.Jitter,
+		Device:    clockVars["device"],
+		Timecode:  clockVars["timecode"],
+		Polls:     polls,
+		NoReply:   noreply,
+		BadFormat: badformat,
+		BadData:   baddata,
+	}
+}
+
+// NewRefClockFromChrony constructs RefClock from chrony reference clock source.
+// chronyd doesn't report driver names, so PPS clocks are recognized by refid starting with PPS, which is the default for PPS driver
+func NewRefClockFromChrony(s *ChronySource) *RefClock {
+	// refclock sources carry refid instead of IPv4 address
+	var refID uint32
+	if ip := s.SourceData.IPAddr.To4(); ip != nil {
+		refID = binary.BigEndian.Uint32(ip)
+	}
+	name := chrony.RefidToString(refID)
+	usable := s.SourceData.State == chrony.SourceStateSync || s.SourceData.State == chrony.SourceStateCandidate
+	rc := &RefClock{
+		Name:   name,
+		RefID:  name,
+		PPS:    strings.HasPrefix(name, "PPS"),
+		Locked: usable && s.SourceData.Reachability&1 != 0,
+		State:  s.SourceData.State.String(),
+		Reach:  uint8(s.SourceData.Reachability),
+		Offset: -1 * secToMS(s.SourceData.OrigLatestMeas), // same sign as in peers
+	}
+	if s.SourceStats != nil {
+		rc.Jitter = secToMS(s.SourceStats.StandardDeviation)
+	}
+	return rc
+}
+
+// ReadClockVariables sends Read Clock Variables packet for associationID of refclock and returns parsed variables
+func (n *NTPCheck) ReadClockVariables(associationID uint16) (map[string]string, error) {
+	packet := n.getReadVariablesPacket(associationID)
+	packet.REMOp = control.OpReadClockVariables
+	response, err := n.Client.Communicate(packet)
+	if err != nil {
+		return nil, err
+	}
+	if response.HasError() {
+		return nil, fmt.Errorf("server returned error: %s", control.ErrorDesc[response.GetErrorCode()&0x7])
+	}
+	return response.GetClockVariables()
+}
+
+// RefClocks returns status of all reference clocks of ntpd
+func (n *NTPCheck) RefClocks() ([]*RefClock, error) {
+	result, err := n.Run()
+	if err != nil {
+		return nil, err
+	}
+	refclocks := []*RefClock{}
+	for id, p := range result.Peers {
+		if _, ok := ntpdRefClockType(p.SRCAdr); !ok {
+			continue
+		}
+		clockVars, err := n.ReadClockVariables(id)
+		if err != nil {
+			// status of refclock is still useful without driver details
+			log.Warningf("failed to read clock variables of %s: %v", p.SRCAdr, err)
+			clockVars = map[string]string{}
+		}
+		refclocks = append(refclocks, NewRefClockFromNTP(p, clockVars))
+	}
+	slices.SortFunc(refclocks, func(a, b *RefClock) int { return strings.Compare(a.Name, b.Name) })
+	return refclocks, nil
+}
+
+// RefClocks returns status of all reference clocks of chronyd
+func (n *ChronyCheck) RefClocks() ([]*RefClock, error) {
+	sources, err := n.Sources()
+	if err != nil {
+		return nil, err
+	}
+	refclocks := []*RefClock{}
+	for _, s := range sources {
+		if s.SourceData.Mode == chrony.SourceModeRef {
+			refclocks = append(refclocks, NewRefClockFromChrony(s))
+		}
+	}
+	return refclocks, nil
+}
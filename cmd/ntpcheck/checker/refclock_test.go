@@ -0,0 +1,225 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"net"
+	"testing"
+
+	"github.com/facebook/time/ntp/chrony"
+	"github.com/facebook/time/ntp/control"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNTPDRefClockType(t *testing.T) {
+	driver, ok := ntpdRefClockType("127.127.22.0")
+	require.True(t, ok)
+	require.Equal(t, 22, driver)
+	_, ok = ntpdRefClockType("127.0.0.1")
+	require.False(t, ok)
+	_, ok = ntpdRefClockType("127.127.x.0")
+	require.False(t, ok)
+}
+
+func TestNewRefClockFromNTP(t *testing.T) {
+	p := &Peer{SRCAdr: "127.127.20.0", RefID: "GPS", Selection: control.SelSYSPeer, Reach: 0xff, Offset: 0.5, Jitter: 0.2}
+	rc := NewRefClockFromNTP(p, map[string]string{
+		"device":    "NMEA GPS Clock",
+		"timecode":  "$GPRMC,123519,A",
+		"poll":      "100",
+		"noreply":   "1",
+		"badformat": "2",
+		"baddata":   "3",
+	})
+	want := &RefClock{
+		Name:      "127.127.20.0",
+		Driver:    "GPS_NMEA",
+		RefID:     "GPS",
+		Locked:    true,
+		State:     "sys.peer",
+		Reach:     0xff,
+		Offset:    0.5,
+		Jitter:    0.2,
+		Device:    "NMEA GPS Clock",
+		Timecode:  "$GPRMC,123519,A",
+		Polls:     100,
+		NoReply:   1,
+		BadFormat: 2,
+		BadData:   3,
+	}
+	require.Equal(t, want, rc)
+
+	// PPS which missed the last poll
+	rc = NewRefClockFromNTP(&Peer{SRCAdr: "127.127.22.0", Selection: control.SelPPSPeer, Reach: 0xfe}, map[string]string{})
+	require.True(t, rc.PPS)
+	require.False(t, rc.Locked)
+	require.Equal(t, "PPS", rc.Driver)
+
+	rc = NewRefClockFromNTP(&Peer{SRCAdr: "127.127.99.1", Selection: control.SelReject, Reach: 0xff}, map[string]string{})
+	require.Equal(t, "type 99", rc.Driver)
+	require.False(t, rc.Locked)
+}
+
+func TestNewRefClockFromChrony(t *testing.T) {
+	s := &ChronySource{
+		SourceData: &chrony.SourceData{
+			IPAddr:         net.IPv4(0x50, 0x50, 0x53, 0x00), // PPS
+			State:          chrony.SourceStateSync,
+			Mode:           chrony.SourceModeRef,
+			Reachability:   0377,
+			OrigLatestMeas: -0.000001,
+		},
+		SourceStats: &chrony.SourceStats{StandardDeviation: 0.0000002},
+	}
+	want := &RefClock{
+		Name:   "PPS",
+		RefID:  "PPS",
+		PPS:    true,
+		Locked: true,
+		State:  "sync",
+		Reach:  0xff,
+		Offset: 0.001,
+		Jitter: 0.0002,
+	}
+	rc := NewRefClockFromChrony(s)
+	require.InDelta(t, want.Offset, rc.Offset, 1e-9)
+	require.InDelta(t, want.Jitter, rc.Jitter, 1e-9)
+	rc.Offset, rc.Jitter = want.Offset, want.Jitter
+	require.Equal(t, want, rc)
+
+	s.SourceData.IPAddr = net.IPv4(0x47, 0x50, 0x53, 0x00) // GPS
+	s.SourceData.State = chrony.SourceStateFalseTicker
+	rc = NewRefClockFromChrony(s)
+	require.Equal(t, "GPS", rc.Name)
+	require.False(t, rc.PPS)
+	require.False(t, rc.Locked)
+	require.Equal(t, "falseticker", rc.State)
+}
+
+func TestNTPCheckRefClocks(t *testing.T) {
+	refclockPSWord := (&control.PeerStatusWord{
+		PeerStatus:    control.PeerStatus{Reachable: true, Configured: true},
+		PeerSelection: control.SelPPSPeer,
+	}).Word()
+	prepdOutputs := []*control.NTPControlMsg{
+		// status with two associations
+		{
+			NTPControlMsgHead: control.NTPControlMsgHead{
+				VnMode: vnMode,
+				REMOp:  control.MakeREMOp(true, false, false, control.OpReadStatus),
+				Count:  8,
+			},
+			Data: append(assocIDpair(1, psWordBinary), assocIDpair(2, refclockPSWord)...),
+		},
+		// system variables
+		{
+			NTPControlMsgHead: control.NTPControlMsgHead{
+				VnMode: vnMode,
+				REMOp:  control.MakeREMOp(true, false, false, control.OpReadVariables),
+			},
+			Data: []uint8("stratum=1,offset=0.01"),
+		},
+	}
+	peers := map[uint16]*control.NTPControlMsg{
+		1: {
+			NTPControlMsgHead: control.NTPControlMsgHead{
+				VnMode: vnMode,
+				REMOp:  control.MakeREMOp(true, false, false, control.OpReadVariables),
+				Status: psWordBinary,
+			},
+			Data: []uint8("srcadr=192.0.2.1,stratum=2,hpoll=6,ppoll=6"),
+		},
+		2: {
+			NTPControlMsgHead: control.NTPControlMsgHead{
+				VnMode: vnMode,
+				REMOp:  control.MakeREMOp(true, false, false, control.OpReadVariables),
+				Status: refclockPSWord,
+			},
+			Data: []uint8("srcadr=127.127.22.0,stratum=0,refid=PPS,hpoll=4,ppoll=4,reach=0xff,jitter=0.002"),
+		},
+	}
+	clockVars := &control.NTPControlMsg{
+		NTPControlMsgHead: control.NTPControlMsgHead{
+			VnMode: vnMode,
+			REMOp:  control.MakeREMOp(true, false, false, control.OpReadClockVariables),
+		},
+		Data: []uint8(`device="PPS Clock Discipline",poll=42`),
+	}
+	client := &fakeRefClockNTPClient{fakeNTPClient: fakeNTPClient{outputs: prepdOutputs}, peers: peers, clockVars: clockVars}
+	check := &NTPCheck{Client: client}
+	got, err := check.RefClocks()
+	require.NoError(t, err)
+	require.Equal(t, []*RefClock{{
+		Name:   "127.127.22.0",
+		Driver: "PPS",
+		RefID:  "PPS",
+		PPS:    true,
+		Locked: true,
+		State:  "pps.peer",
+		Reach:  0xff,
+		Jitter: 0.002,
+		Device: "PPS Clock Discipline",
+		Polls:  42,
+	}}, got)
+	require.Equal(t, []uint16{2}, client.clockVarsRequested)
+}
+
+// fakeRefClockNTPClient answers peer variables requests by association id, as their order is random
+type fakeRefClockNTPClient struct {
+	fakeNTPClient
+	peers              map[uint16]*control.NTPControlMsg
+	clockVars          *control.NTPControlMsg
+	clockVarsRequested []uint16
+}
+
+func (c *fakeRefClockNTPClient) Communicate(packet *control.NTPControlMsgHead) (*control.NTPControlMsg, error) {
+	if packet.REMOp == control.OpReadClockVariables {
+		c.clockVarsRequested = append(c.clockVarsRequested, packet.AssociationID)
+		return c.clockVars, nil
+	}
+	if packet.AssociationID != 0 {
+		return c.peers[packet.AssociationID], nil
+	}
+	return c.fakeNTPClient.Communicate(packet)
+}
+
+func TestChronyCheckRefClocks(t *testing.T) {
+	refSD := &chrony.ReplySourceData{SourceData: chrony.SourceData{
+		IPAddr:       net.IPv4(0x50, 0x50, 0x53, 0x00),
+		Mode:         chrony.SourceModeRef,
+		State:        chrony.SourceStateCandidate,
+		Reachability: 0377,
+	}}
+	prepdOutputs := []chrony.ResponsePacket{
+		&chrony.ReplySources{NSources: 2},
+		replySD0,
+		&chrony.ReplySourceStats{},
+		replyNTPSourceName0,
+		refSD,
+		&chrony.ReplySourceStats{},
+	}
+	check := &ChronyCheck{
+		Client: &fakeChronyClient{readCount: 0, outputs: prepdOutputs},
+	}
+	got, err := check.RefClocks()
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, "PPS", got[0].Name)
+	require.True(t, got[0].Locked)
+	require.Equal(t, "candidate", got[0].State)
+}
@@ -18,9 +18,11 @@ package checker
 
 import (
 	"bufio"
+	"io"
 	"net"
 	"os"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/facebook/time/ntp/chrony"
@@ -31,6 +33,7 @@ import (
 type Runner interface {
 	Run() (*NTPCheckResult, error)
 	ServerStats() (*ServerStats, error)
+	RefClocks() ([]*RefClock, error)
 }
 
 type flavour int
@@ -110,6 +113,27 @@ func RunCheck(address string) (*NTPCheckResult, error) {
 	return checker.Run()
 }
 
+// RunRefClocks is a simple wrapper to connect to address and run NTPCheck.RefClocks()
+func RunRefClocks(address string) ([]*RefClock, error) {
+	timeout := 5 * time.Second
+	deadline := time.Now().Add(timeout)
+	flavour := getFlavour()
+	if address == "" {
+		address = getPublicServer(flavour)
+	}
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+	checker := getChecker(flavour, conn)
+	log.Debugf("connected to %s", address)
+	return checker.RefClocks()
+}
+
 // RunNTPData is a simple wrapper to connect to address and run NTPCheck.Run()
 // If using chrony it gathers extra info about the peers using the unix socket
 func RunNTPData(address string) (*NTPCheckResult, error) {
@@ -138,6 +162,31 @@ func RunNTPData(address string) (*NTPCheckResult, error) {
 	return checker.Run()
 }
 
+// DialChrony connects to chronyd at address, which is a unix socket path or host:port.
+// Private reports are only available over the unix socket, which is the default
+func DialChrony(address string) (*ChronyCheck, io.Closer, error) {
+	var err error
+	var conn net.Conn
+	timeout := 5 * time.Second
+	if address == "" {
+		address = chrony.ChronySocketPath
+	}
+	if strings.HasPrefix(address, "/") {
+		conn, err = dialUnix(address)
+	} else {
+		conn, err = net.DialTimeout("udp", address, timeout)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	log.Debugf("connected to %s", address)
+	return NewChronyCheck(conn), conn, nil
+}
+
 // RunServerStats is a simple wrapper to connect to address and run NTPCheck.ServerStats()
 func RunServerStats(address string) (*ServerStats, error) {
 	var err error
@@ -164,3 +213,21 @@ func RunServerStats(address string) (*ServerStats, error) {
 	log.Debugf("connected to %s", address)
 	return checker.ServerStats()
 }
+
+// RunReadVariables is a simple wrapper to connect to ntpd at address and run NTPCheck.ReadVariablesByName()
+func RunReadVariables(address string, associationID uint16, names []string) (map[string]string, error) {
+	timeout := 5 * time.Second
+	if address == "" {
+		address = getPublicServer(flavourNTPD)
+	}
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	log.Debugf("connected to %s", address)
+	return NewNTPCheck(conn).ReadVariablesByName(associationID, names)
+}
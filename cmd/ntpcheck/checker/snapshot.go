@@ -0,0 +1,185 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/facebook/time/ntp/control"
+)
+
+// Snapshot is a saved NTPCheckResult to compare live state against later
+type Snapshot struct {
+	Time   time.Time       `json:"time"`
+	Result *NTPCheckResult `json:"result"`
+}
+
+// WriteSnapshot saves result to the file at path
+func WriteSnapshot(path string, r *NTPCheckResult) error {
+	b, err := json.MarshalIndent(&Snapshot{Time: time.Now(), Result: r}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// ReadSnapshot reads snapshot from the file at path
+func ReadSnapshot(path string) (*Snapshot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("parsing snapshot %s: %w", path, err)
+	}
+	if s.Result == nil {
+		return nil, fmt.Errorf("parsing snapshot %s: no check result", path)
+	}
+	return s, nil
+}
+
+// DiffThresholds are changes of peer offset and delay (in ms) which are reported
+type DiffThresholds struct {
+	Offset float64
+	Delay  float64
+}
+
+// PeerDiff describes how a peer changed between snapshots
+type PeerDiff struct {
+	Peer    string   `json:"peer"`
+	Added   bool     `json:"added,omitempty"`
+	Removed bool     `json:"removed,omitempty"`
+	Changes []string `json:"changes,omitempty"`
+}
+
+// SnapshotDiff describes how NTP state changed between snapshots
+type SnapshotDiff struct {
+	Changes []string    `json:"changes"`
+	Peers   []*PeerDiff `json:"peers"`
+}
+
+// Empty returns true if nothing changed
+func (d *SnapshotDiff) Empty() bool {
+	return len(d.Changes) == 0 && len(d.Peers) == 0
+}
+
+// peerKey identifies the peer across snapshots, association ids are not stable between daemon restarts
+func peerKey(p *Peer) string {
+	if p.Hostname != "" {
+		return p.Hostname
+	}
+	return p.SRCAdr
+}
+
+func peersByKey(r *NTPCheckResult) map[string]*Peer {
+	m := map[string]*Peer{}
+	for _, p := range r.Peers {
+		m[peerKey(p)] = p
+	}
+	return m
+}
+
+func selectionDesc(selection uint8) string {
+	if int(selection) < len(control.PeerSelect) {
+		return control.PeerSelect[selection]
+	}
+	return fmt.Sprintf("%d", selection)
+}
+
+func diffPeer(old, cur *Peer, th DiffThresholds) []string {
+	changes := []string{}
+	if old.Stratum != cur.Stratum {
+		changes = append(changes, fmt.Sprintf("stratum changed from %d to %d", old.Stratum, cur.Stratum))
+	}
+	if old.Selection != cur.Selection {
+		changes = append(changes, fmt.Sprintf("selection changed from %s to %s", selectionDesc(old.Selection), selectionDesc(cur.Selection)))
+	}
+	if old.RefID != cur.RefID {
+		changes = append(changes, fmt.Sprintf("refid changed from %s to %s", old.RefID, cur.RefID))
+	}
+	// reach is a shift register of last 8 polls, so only report lost replies
+	if old.Reachable && !cur.Reachable {
+		changes = append(changes, "became unreachable")
+	} else if bits.OnesCount8(cur.Reach) < bits.OnesCount8(old.Reach) {
+		changes = append(changes, fmt.Sprintf("reach dropped from %o to %o", old.Reach, cur.Reach))
+	} else if !old.Reachable && cur.Reachable {
+		changes = append(changes, "became reachable")
+	}
+	if diff := cur.Offset - old.Offset; math.Abs(diff) > th.Offset {
+		changes = append(changes, fmt.Sprintf("offset changed by %.3fms, from %.3fms to %.3fms", diff, old.Offset, cur.Offset))
+	}
+	if diff := cur.Delay - old.Delay; math.Abs(diff) > th.Delay {
+		changes = append(changes, fmt.Sprintf("delay changed by %.3fms, from %.3fms to %.3fms", diff, old.Delay, cur.Delay))
+	}
+	return changes
+}
+
+func sysPeerName(r *NTPCheckResult) string {
+	p, err := r.FindSysPeer()
+	if err != nil {
+		return "none"
+	}
+	return peerKey(p)
+}
+
+// DiffSnapshots compares old and current NTP state and returns changes: sys peer, leap indicator and stratum changes,
+// added and removed peers, and peers which changed stratum, selection, reach, or offset and delay beyond thresholds
+func DiffSnapshots(old, cur *NTPCheckResult, th DiffThresholds) *SnapshotDiff {
+	d := &SnapshotDiff{Changes: []string{}, Peers: []*PeerDiff{}}
+	if oldSys, curSys := sysPeerName(old), sysPeerName(cur); oldSys != curSys {
+		d.Changes = append(d.Changes, fmt.Sprintf("sys peer changed from %s to %s", oldSys, curSys))
+	}
+	if old.LIDesc != cur.LIDesc {
+		d.Changes = append(d.Changes, fmt.Sprintf("leap indicator changed from %s to %s", old.LIDesc, cur.LIDesc))
+	}
+	if old.SysVars != nil && cur.SysVars != nil && old.SysVars.Stratum != cur.SysVars.Stratum {
+		d.Changes = append(d.Changes, fmt.Sprintf("stratum changed from %d to %d", old.SysVars.Stratum, cur.SysVars.Stratum))
+	}
+
+	oldPeers, curPeers := peersByKey(old), peersByKey(cur)
+	keys := []string{}
+	for k := range oldPeers {
+		keys = append(keys, k)
+	}
+	for k := range curPeers {
+		if _, ok := oldPeers[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		o, c := oldPeers[k], curPeers[k]
+		switch {
+		case o == nil:
+			d.Peers = append(d.Peers, &PeerDiff{Peer: k, Added: true})
+		case c == nil:
+			d.Peers = append(d.Peers, &PeerDiff{Peer: k, Removed: true})
+		default:
+			if changes := diffPeer(o, c, th); len(changes) > 0 {
+				d.Peers = append(d.Peers, &PeerDiff{Peer: k, Changes: changes})
+			}
+		}
+	}
+	return d
+}
@@ -0,0 +1,112 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/facebook/time/ntp/control"
+
+	"github.com/stretchr/testify/require"
+)
+
+func snapshotResult() *NTPCheckResult {
+	return &NTPCheckResult{
+		LIDesc:  "none",
+		SysVars: &SystemVariables{Stratum: 2},
+		Peers: map[uint16]*Peer{
+			1: {SRCAdr: "192.0.2.1", Stratum: 1, Selection: control.SelSYSPeer, Reachable: true, Reach: 0xff, RefID: "GPS", Offset: 0.1, Delay: 1},
+			2: {SRCAdr: "192.0.2.2", Stratum: 1, Selection: control.SelCandidate, Reachable: true, Reach: 0xff, RefID: "GPS", Offset: 0.2, Delay: 1},
+			3: {SRCAdr: "192.0.2.3", Hostname: "time3", Stratum: 2, Selection: control.SelCandidate, Reachable: true, Reach: 0xff, Offset: 0.3, Delay: 1},
+		},
+	}
+}
+
+func TestSnapshotReadWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	r := snapshotResult()
+	require.NoError(t, WriteSnapshot(path, r))
+	s, err := ReadSnapshot(path)
+	require.NoError(t, err)
+	require.Equal(t, r, s.Result)
+	require.False(t, s.Time.IsZero())
+
+	require.NoError(t, os.WriteFile(path, []byte("{}"), 0644))
+	_, err = ReadSnapshot(path)
+	require.ErrorContains(t, err, "no check result")
+
+	_, err = ReadSnapshot(filepath.Join(t.TempDir(), "nope.json"))
+	require.Error(t, err)
+}
+
+func TestDiffSnapshotsEmpty(t *testing.T) {
+	d := DiffSnapshots(snapshotResult(), snapshotResult(), DiffThresholds{Offset: 1, Delay: 1})
+	require.True(t, d.Empty())
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	old := snapshotResult()
+	cur := snapshotResult()
+	cur.LIDesc = "add_sec"
+	cur.SysVars.Stratum = 3
+	// association ids don't matter
+	cur.Peers[10] = cur.Peers[1]
+	delete(cur.Peers, 1)
+	cur.Peers[10].Selection = control.SelCandidate
+	cur.Peers[10].Reach = 0xfe
+	cur.Peers[2].Selection = control.SelSYSPeer
+	cur.Peers[2].Stratum = 2
+	cur.Peers[2].RefID = "192.0.2.10"
+	cur.Peers[2].Offset = 2.2
+	cur.Peers[2].Delay = 3.5
+	delete(cur.Peers, 3)
+	cur.Peers[4] = &Peer{SRCAdr: "192.0.2.4", Stratum: 1}
+
+	d := DiffSnapshots(old, cur, DiffThresholds{Offset: 1, Delay: 1})
+	require.Equal(t, []string{
+		"sys peer changed from 192.0.2.1 to 192.0.2.2",
+		"leap indicator changed from none to add_sec",
+		"stratum changed from 2 to 3",
+	}, d.Changes)
+	require.Equal(t, []*PeerDiff{
+		{Peer: "192.0.2.1", Changes: []string{
+			"selection changed from sys.peer to candidate",
+			"reach dropped from 377 to 376",
+		}},
+		{Peer: "192.0.2.2", Changes: []string{
+			"stratum changed from 1 to 2",
+			"selection changed from candidate to sys.peer",
+			"refid changed from GPS to 192.0.2.10",
+			"offset changed by 2.000ms, from 0.200ms to 2.200ms",
+			"delay changed by 2.500ms, from 1.000ms to 3.500ms",
+		}},
+		{Peer: "192.0.2.4", Added: true},
+		{Peer: "time3", Removed: true},
+	}, d.Peers)
+	require.False(t, d.Empty())
+}
+
+func TestDiffPeerReachability(t *testing.T) {
+	old := &Peer{Reachable: true, Reach: 0xff}
+	cur := &Peer{Reachable: false, Reach: 0}
+	require.Equal(t, []string{"became unreachable"}, diffPeer(old, cur, DiffThresholds{}))
+	require.Equal(t, []string{"became reachable"}, diffPeer(cur, &Peer{Reachable: true, Reach: 1}, DiffThresholds{}))
+	// reach register shifting with all replies received is not a change
+	require.Empty(t, diffPeer(&Peer{Reachable: true, Reach: 0x7f}, &Peer{Reachable: true, Reach: 0xff}, DiffThresholds{}))
+}
@@ -0,0 +1,75 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/facebook/time/cmd/ntpcheck/checker"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var chronyServer string
+
+// runChronyReport connects to chronyd and prints the report in JSON format
+func runChronyReport(report func(c *checker.ChronyCheck) (any, error)) error {
+	c, conn, err := checker.DialChrony(chronyServer)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	r, err := report(c)
+	if err != nil {
+		return err
+	}
+	return printJSON(r)
+}
+
+// chronyReportCmd returns subcommand printing the report
+func chronyReportCmd(use, short string, report func(c *checker.ChronyCheck) (any, error)) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Run: func(_ *cobra.Command, _ []string) {
+			ConfigureVerbosity()
+			if err := runChronyReport(report); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(chronyCmd)
+	chronyCmd.PersistentFlags().StringVarP(&chronyServer, "server", "S", "", "chronyd unix socket or host:port to connect to, private reports need the unix socket")
+	chronyCmd.AddCommand(chronyReportCmd("tracking", "Print 'tracking' report in JSON format", func(c *checker.ChronyCheck) (any, error) {
+		return c.Tracking()
+	}))
+	chronyCmd.AddCommand(chronyReportCmd("sources", "Print 'sourcedata', 'sourcestats', 'sourcename' and 'ntpdata' reports of all sources in JSON format", func(c *checker.ChronyCheck) (any, error) {
+		return c.Sources()
+	}))
+	chronyCmd.AddCommand(chronyReportCmd("serverstats", "Print 'serverstats' report in JSON format", func(c *checker.ChronyCheck) (any, error) {
+		return c.ServerStats()
+	}))
+	chronyCmd.AddCommand(chronyReportCmd("rtcdata", "Print 'rtcdata' report in JSON format", func(c *checker.ChronyCheck) (any, error) {
+		return c.RTCData()
+	}))
+}
+
+var chronyCmd = &cobra.Command{
+	Use:   "chrony",
+	Short: "Print chronyd reports, same as chronyc does",
+}
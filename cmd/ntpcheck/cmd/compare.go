@@ -0,0 +1,108 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/facebook/time/cmd/ntpcheck/checker"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var compareThreshold float64
+var compareTimeout time.Duration
+
+// ntpAddress adds default NTP port to the server unless it already has one
+func ntpAddress(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), "123")
+}
+
+func printCompare(r *checker.CompareResult, threshold float64) {
+	fmt.Printf("%-40s %8s %12s %12s\n", "SERVER", "STRATUM", "OFFSET", "DELAY")
+	for _, s := range r.Servers {
+		if s.Error != "" {
+			fmt.Printf("%-40s %s\n", s.Server, color.RedString(s.Error))
+			continue
+		}
+		offset := fmt.Sprintf("%10.3fms", s.Offset)
+		if s.Falseticker {
+			offset = color.RedString(offset)
+		}
+		fmt.Printf("%-40s %8d %12s %10.3fms\n", s.Server, s.Stratum, offset, s.Delay)
+	}
+	fmt.Printf("Median offset: %.3fms\n", r.Median)
+	for _, d := range r.Disagreements {
+		if d.Exceeded {
+			fmt.Printf("%s %s and %s disagree by %s, we expect them to be within %s\n",
+				warnString,
+				color.BlueString(d.A),
+				color.BlueString(d.B),
+				color.YellowString("%.3fms", d.Difference),
+				color.BlueString("%.1fms", threshold),
+			)
+		}
+	}
+	if falsetickers := r.Falsetickers(); len(falsetickers) > 0 {
+		fmt.Printf("%s Suspected falsetickers:\n%s\n", failString, formatPeers(falsetickers))
+	}
+	if unreachable := r.Unreachable(); len(unreachable) > 0 {
+		fmt.Printf("%s Unreachable servers:\n%s\n", failString, formatPeers(unreachable))
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(compareCmd)
+	compareCmd.Flags().Float64VarP(&compareThreshold, "threshold", "T", 1.0, "max offset from the median (and between servers) in ms before server is reported")
+	compareCmd.Flags().DurationVarP(&compareTimeout, "timeout", "t", time.Second, "timeout of a single NTP exchange")
+}
+
+var compareCmd = &cobra.Command{
+	Use:   "compare <server> <server> [server...]",
+	Short: "Compare time reported by multiple NTP servers",
+	Long:  "'compare' queries all servers concurrently, computes their offsets from the local clock and reports pairwise disagreement and suspected falsetickers. Exits with non-zero code if any server is unreachable or is a falseticker",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(_ *cobra.Command, args []string) {
+		ConfigureVerbosity()
+		servers := make([]string, len(args))
+		for i, arg := range args {
+			servers[i] = ntpAddress(arg)
+		}
+		query := func(server string) (*checker.Exchange, error) {
+			return checker.QueryServer(server, compareTimeout)
+		}
+		r := checker.CompareServers(servers, query, compareThreshold)
+		if jsonOutput {
+			if err := printJSON(r); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		} else {
+			printCompare(r, compareThreshold)
+		}
+		if len(r.Falsetickers()) > 0 || len(r.Unreachable()) > 0 {
+			os.Exit(1)
+		}
+	},
+}
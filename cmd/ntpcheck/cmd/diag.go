@@ -39,6 +39,19 @@ const (
 	CRITICAL
 )
 
+var statusToString = []string{"OK", "WARN", "FAIL", "CRITICAL"}
+
+// MarshalText status to byte slice
+func (s status) MarshalText() ([]byte, error) {
+	return []byte(statusToString[s]), nil
+}
+
+// checkOutput is a result of a single check in JSON output
+type checkOutput struct {
+	Status  status `json:"status"`
+	Message string `json:"message"`
+}
+
 // diagnoser is function that does checks on NTPCheckResult
 type diagnoser func(r *checker.NTPCheckResult) (status, string)
 
@@ -231,8 +244,16 @@ var diagnosers = []diagnoser{
 }
 
 func runDiagnosers(r *checker.NTPCheckResult) {
+	output := []checkOutput{}
 	for _, check := range diagnosers {
 		status, msg := check(r)
+		if jsonOutput {
+			output = append(output, checkOutput{Status: status, Message: msg})
+			if status == CRITICAL {
+				break
+			}
+			continue
+		}
 		switch status {
 		case CRITICAL:
 			fmt.Printf("%s %s\n", failString, msg)
@@ -241,6 +262,15 @@ func runDiagnosers(r *checker.NTPCheckResult) {
 			fmt.Printf("%s %s\n", statusToColor[status], msg)
 		}
 	}
+	if !jsonOutput {
+		return
+	}
+	if err := printJSON(output); err != nil {
+		log.Fatal(err)
+	}
+	if len(output) > 0 && output[len(output)-1].Status == CRITICAL {
+		os.Exit(1)
+	}
 }
 
 func init() {
@@ -0,0 +1,111 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/facebook/time/cmd/ntpcheck/checker"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var faultsTimeout time.Duration
+var faultsOnly []string
+
+// formatRefID formats reference id as ASCII for stratum 0 and 1 (Kiss-o'-Death code or reference clock), and as IPv4 address otherwise
+func formatRefID(stratum uint8, refID uint32) string {
+	b := binary.BigEndian.AppendUint32(nil, refID)
+	if stratum <= 1 {
+		return strings.TrimRight(string(b), "\x00")
+	}
+	return net.IP(b).String()
+}
+
+func printFaults(results []*checker.FaultResult) {
+	for _, r := range results {
+		st := OK
+		if !r.OK() {
+			st = FAIL
+		}
+		behavior := "no response"
+		if r.Responded {
+			behavior = fmt.Sprintf("%d bytes response: leap=%d version=%d mode=%d stratum=%d refid=%s",
+				r.Size, r.Leap, r.Version, r.Mode, r.Stratum, formatRefID(r.Stratum, r.RefID))
+		}
+		fmt.Printf("%s %s (%s): %s\n", statusToColor[st], color.BlueString(r.Name), r.Description, behavior)
+		for _, problem := range r.Problems {
+			fmt.Printf("\t%s\n", problem)
+		}
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(faultsCmd)
+	faultsCmd.Flags().DurationVarP(&faultsTimeout, "timeout", "t", time.Second, "how long to wait for response to each packet")
+	faultsCmd.Flags().StringSliceVarP(&faultsOnly, "only", "o", nil, "names of packets to send, all by default")
+}
+
+var faultsCmd = &cobra.Command{
+	Use:   "faults <server>",
+	Short: "Send malformed and edge-case NTP packets to the server and report its behavior",
+	Long:  "'faults' sends deliberately malformed or edge-case packets (bad versions and modes, Kiss-o'-Death codes, huge root dispersion, leap flags, broken extension fields) to the server, one at a time, and reports whether it answers them the way a robust server should. Exits with non-zero code if it doesn't",
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		ConfigureVerbosity()
+		faults := checker.Faults
+		if len(faultsOnly) > 0 {
+			faults = []*checker.Fault{}
+			for _, f := range checker.Faults {
+				if slices.Contains(faultsOnly, f.Name) {
+					faults = append(faults, f)
+				}
+			}
+			if len(faults) != len(faultsOnly) {
+				fmt.Println("unknown packet names, supported are:")
+				for _, f := range checker.Faults {
+					fmt.Printf("\t%s: %s\n", f.Name, f.Description)
+				}
+				os.Exit(1)
+			}
+		}
+		results, err := checker.InjectFaults(ntpAddress(args[0]), faults, faultsTimeout)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if jsonOutput {
+			if err := printJSON(results); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		} else {
+			printFaults(results)
+		}
+		for _, r := range results {
+			if !r.OK() {
+				os.Exit(1)
+			}
+		}
+	},
+}
@@ -1,4 +1,4 @@
-//go:build 386 && !darwin
+//go:build 386 && !darwin && !windows
 
 /*
 Copyright (c) Facebook, Inc. and its affiliates.
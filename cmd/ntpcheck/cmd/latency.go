@@ -0,0 +1,96 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/facebook/time/cmd/ntpcheck/checker"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var latencyCount int
+var latencyInterval time.Duration
+var latencyTimeout time.Duration
+
+func printLatency(server string, r *checker.LatencyReport) {
+	fmt.Printf("%8s %12s %12s %12s %12s %12s\n", "#", "OFFSET", "DELAY", "OUTBOUND", "INBOUND", "PROCESSING")
+	for i, s := range r.Samples {
+		fmt.Printf("%8d %10.3fms %10.3fms %10.3fms %10.3fms %10.3fms\n", i, s.Offset, s.Delay, s.Outbound, s.Inbound, s.Processing)
+	}
+	fmt.Printf("%s: %d exchanges, %d lost\n", color.BlueString(server), len(r.Samples), r.Lost)
+	fmt.Printf("Delay: min %.3fms, median %.3fms\n", r.MinDelay, r.MedianDelay)
+	fmt.Printf("Offset: baseline %.3fms, median %.3fms\n", r.BaselineOffset, r.MedianOffset)
+	fmt.Printf("Queuing: outbound %.3fms, inbound %.3fms\n", r.OutboundQueuing, r.InboundQueuing)
+	fmt.Printf("Path asymmetry error: median %.3fms, at most %.3fms from constant asymmetry\n", r.PathAsymmetry, r.MaxAsymmetryError)
+	fmt.Printf("Server processing: min %.3fms, median %.3fms, max %.3fms\n", r.MinProcessing, r.MedianProcessing, r.MaxProcessing)
+	if r.ServerBound() {
+		fmt.Printf("%s Delay variation is dominated by the server\n", warnString)
+	} else {
+		fmt.Printf("%s Delay variation is dominated by the network path\n", okString)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(latencyCmd)
+	latencyCmd.Flags().IntVarP(&latencyCount, "count", "c", 10, "number of exchanges")
+	latencyCmd.Flags().DurationVarP(&latencyInterval, "interval", "i", time.Second, "interval between exchanges")
+	latencyCmd.Flags().DurationVarP(&latencyTimeout, "timeout", "t", time.Second, "timeout of a single NTP exchange")
+}
+
+var latencyCmd = &cobra.Command{
+	Use:   "latency <server>",
+	Short: "Break down NTP exchange latency into network path and server processing",
+	Long:  "'latency' runs repeated exchanges with the server and separates queuing on the network path in each direction from server processing time, to find out whether offset errors come from the path or the server. Exits with non-zero code if all exchanges are lost",
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		ConfigureVerbosity()
+		if latencyCount < 1 {
+			fmt.Println("count must be positive")
+			os.Exit(1)
+		}
+		server := ntpAddress(args[0])
+		conn, err := net.DialTimeout("udp", server, latencyTimeout)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer conn.Close()
+		exchanges, lost, err := checker.ProbeLatency(conn, latencyCount, latencyInterval, latencyTimeout)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		r := checker.AnalyzeLatency(exchanges, lost)
+		if jsonOutput {
+			if err := printJSON(r); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		} else if len(r.Samples) > 0 {
+			printLatency(server, r)
+		}
+		if len(r.Samples) == 0 {
+			fmt.Printf("%s No response from %s\n", failString, server)
+			os.Exit(1)
+		}
+	},
+}
@@ -0,0 +1,224 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/facebook/time/cmd/ntpcheck/checker"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var monitorInterval time.Duration
+var monitorCount int
+var monitorFormat string
+var monitorOutput string
+var monitorListen string
+var monitorTimeout time.Duration
+var monitorNoDaemon bool
+
+// monitorMetrics are Prometheus gauges updated with every sample
+type monitorMetrics struct {
+	up           prometheus.Gauge
+	offset       prometheus.Gauge
+	correction   prometheus.Gauge
+	frequency    prometheus.Gauge
+	rootDelay    prometheus.Gauge
+	peerOffset   prometheus.Gauge
+	peerDelay    prometheus.Gauge
+	peerJitter   prometheus.Gauge
+	peerStratum  prometheus.Gauge
+	peerCount    prometheus.Gauge
+	serverUp     *prometheus.GaugeVec
+	serverOffset *prometheus.GaugeVec
+	serverDelay  *prometheus.GaugeVec
+}
+
+func newGauge(name, help string) prometheus.Gauge {
+	return prometheus.NewGauge(prometheus.GaugeOpts{Namespace: "ntpcheck", Name: name, Help: help})
+}
+
+func newServerGauge(name, help string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: "ntpcheck", Name: name, Help: help}, []string{"server"})
+}
+
+// newMonitorMetrics registers gauges in registry, ones for local NTP daemon only if daemon is checked
+func newMonitorMetrics(registry *prometheus.Registry, daemon bool) *monitorMetrics {
+	m := &monitorMetrics{
+		up:           newGauge("up", "1 if local NTP daemon was checked successfully"),
+		offset:       newGauge("offset_ms", "tracking clock offset in ms"),
+		correction:   newGauge("correction", "current correction"),
+		frequency:    newGauge("frequency_ppm", "clock frequency in PPM"),
+		rootDelay:    newGauge("root_delay_ms", "tracking root delay in ms"),
+		peerOffset:   newGauge("peer_offset_ms", "sys peer offset in ms"),
+		peerDelay:    newGauge("peer_delay_ms", "sys peer delay in ms"),
+		peerJitter:   newGauge("peer_jitter_ms", "sys peer jitter in ms"),
+		peerStratum:  newGauge("peer_stratum", "sys peer stratum"),
+		peerCount:    newGauge("peer_count", "number of upstream peers"),
+		serverUp:     newServerGauge("server_up", "1 if server responded"),
+		serverOffset: newServerGauge("server_offset_ms", "server offset from the local clock in ms"),
+		serverDelay:  newServerGauge("server_delay_ms", "round trip delay to the server in ms"),
+	}
+	if daemon {
+		registry.MustRegister(
+			m.up, m.offset, m.correction, m.frequency, m.rootDelay,
+			m.peerOffset, m.peerDelay, m.peerJitter, m.peerStratum, m.peerCount,
+		)
+	}
+	registry.MustRegister(m.serverUp, m.serverOffset, m.serverDelay)
+	return m
+}
+
+func (m *monitorMetrics) update(s *checker.MonitorSample) {
+	m.up.Set(0)
+	if st := s.Stats; st != nil {
+		m.up.Set(1)
+		m.offset.Set(st.Offset)
+		m.correction.Set(st.Correction)
+		m.frequency.Set(st.Frequency)
+		m.rootDelay.Set(st.RootDelay)
+		m.peerOffset.Set(st.PeerOffset)
+		m.peerDelay.Set(st.PeerDelay)
+		m.peerJitter.Set(st.PeerJitter)
+		m.peerStratum.Set(float64(st.PeerStratum))
+		m.peerCount.Set(float64(st.PeerCount))
+	}
+	for _, so := range s.Servers {
+		if so.Error != "" {
+			m.serverUp.WithLabelValues(so.Server).Set(0)
+			m.serverOffset.DeleteLabelValues(so.Server)
+			m.serverDelay.DeleteLabelValues(so.Server)
+			continue
+		}
+		m.serverUp.WithLabelValues(so.Server).Set(1)
+		m.serverOffset.WithLabelValues(so.Server).Set(so.Offset)
+		m.serverDelay.WithLabelValues(so.Server).Set(so.Delay)
+	}
+}
+
+// runMonitor takes samples every interval, count times (forever if count is 0), and passes them to handle
+func runMonitor(servers []string, handle func(*checker.MonitorSample) error) error {
+	if monitorInterval <= 0 {
+		return fmt.Errorf("interval must be positive, got %v", monitorInterval)
+	}
+	var check func() (*checker.NTPCheckResult, error)
+	if !monitorNoDaemon {
+		check = func() (*checker.NTPCheckResult, error) { return checker.RunCheck(server) }
+	}
+	query := func(server string) (*checker.Exchange, error) {
+		return checker.QueryServer(server, monitorTimeout)
+	}
+	ticker := time.NewTicker(monitorInterval)
+	defer ticker.Stop()
+	for i := 0; monitorCount == 0 || i < monitorCount; i++ {
+		if i > 0 {
+			<-ticker.C
+		}
+		s := checker.TakeSample(check, servers, query)
+		if s.Error != "" {
+			log.Warningf("failed to check NTP daemon: %s", s.Error)
+		}
+		if err := handle(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func monitor(servers []string) error {
+	switch monitorFormat {
+	case "csv":
+		var out io.Writer = os.Stdout
+		appending := false
+		if monitorOutput != "" {
+			f, err := os.OpenFile(monitorOutput, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			fi, err := f.Stat()
+			if err != nil {
+				return err
+			}
+			// the header is already there from the previous run
+			appending = fi.Size() > 0
+			out = f
+		}
+		w := checker.NewMonitorCSVWriter(out, servers)
+		if appending {
+			w.SkipHeader()
+		}
+		return runMonitor(servers, w.Write)
+	case "prometheus":
+		registry := prometheus.NewRegistry()
+		metrics := newMonitorMetrics(registry, !monitorNoDaemon)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		errCh := make(chan error, 1)
+		go func() {
+			log.Infof("serving metrics on http://%s/metrics", monitorListen)
+			errCh <- http.ListenAndServe(monitorListen, mux) //#nosec G114
+		}()
+		go func() {
+			errCh <- runMonitor(servers, func(s *checker.MonitorSample) error {
+				metrics.update(s)
+				return nil
+			})
+		}()
+		return <-errCh
+	default:
+		return fmt.Errorf("unsupported format %q", monitorFormat)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(monitorCmd)
+	monitorCmd.Flags().StringVarP(&server, "server", "S", "", "NTP daemon to monitor")
+	monitorCmd.Flags().BoolVarP(&monitorNoDaemon, "no-daemon", "n", false, "don't check local NTP daemon, only query servers given as arguments")
+	monitorCmd.Flags().DurationVarP(&monitorInterval, "interval", "i", 10*time.Second, "interval between samples")
+	monitorCmd.Flags().IntVarP(&monitorCount, "count", "c", 0, "number of samples to take, 0 means forever")
+	monitorCmd.Flags().StringVarP(&monitorFormat, "format", "f", "csv", "output format: csv or prometheus")
+	monitorCmd.Flags().StringVarP(&monitorOutput, "output", "o", "", "file to append CSV samples to instead of stdout")
+	monitorCmd.Flags().StringVarP(&monitorListen, "listen", "l", "localhost:9855", "address to serve Prometheus metrics on")
+	monitorCmd.Flags().DurationVarP(&monitorTimeout, "timeout", "t", time.Second, "timeout of a single NTP exchange with servers")
+}
+
+var monitorCmd = &cobra.Command{
+	Use:   "monitor [server...]",
+	Short: "Continuously sample NTP daemon tracking data and offsets of NTP servers",
+	Long:  "'monitor' keeps sampling tracking data of local NTP daemon, as well as offsets of servers given as arguments, and writes them as CSV or exposes them as Prometheus metrics",
+	Run: func(_ *cobra.Command, args []string) {
+		ConfigureVerbosity()
+		if monitorNoDaemon && len(args) == 0 {
+			log.Fatal("nothing to monitor: no servers given and --no-daemon is set")
+		}
+		servers := make([]string, len(args))
+		for i, arg := range args {
+			servers[i] = ntpAddress(arg)
+		}
+		if err := monitor(servers); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
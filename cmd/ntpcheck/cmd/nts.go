@@ -0,0 +1,257 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/facebook/time/ntp/nts"
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var ntsKEPort int
+var ntsTimeout time.Duration
+var ntsCAFile string
+
+// certExpiryWarning is how soon certificate expiry is worth a warning
+const certExpiryWarning = 14 * 24 * time.Hour
+
+var aeadNames = map[uint16]string{
+	nts.AEADAESSIVCMAC256: "AEAD_AES_SIV_CMAC_256",
+}
+
+func aeadName(id uint16) string {
+	if name, ok := aeadNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown (%d)", id)
+}
+
+// ntsReporter prints check results, or collects them for JSON output, and remembers if any of them failed
+type ntsReporter struct {
+	failed bool
+	output []checkOutput
+}
+
+func (r *ntsReporter) report(s status, format string, args ...any) {
+	if s >= FAIL {
+		r.failed = true
+		s = FAIL
+	}
+	if jsonOutput {
+		r.output = append(r.output, checkOutput{Status: s, Message: fmt.Sprintf(format, args...)})
+		return
+	}
+	fmt.Printf("%s %s\n", statusToColor[s], fmt.Sprintf(format, args...))
+}
+
+// checkCertificates verifies certificate chain presented by the server without failing the handshake, so all problems can be reported
+func checkCertificates(r *ntsReporter, state tls.ConnectionState, host string, roots *x509.CertPool, now time.Time) {
+	if len(state.PeerCertificates) == 0 {
+		r.report(FAIL, "Server presented no certificates")
+		return
+	}
+	leaf := state.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, c := range state.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	if err != nil {
+		r.report(FAIL, "Certificate of %s is not valid: %v", color.BlueString(leaf.Subject.String()), err)
+	} else {
+		r.report(OK, "Certificate chain of %s is valid for %s", color.BlueString(leaf.Subject.String()), color.BlueString(host))
+	}
+	for _, c := range state.PeerCertificates {
+		if left := c.NotAfter.Sub(now); left > 0 && left < certExpiryWarning {
+			r.report(WARN, "Certificate %s expires in %v", color.BlueString(c.Subject.String()), left.Round(time.Hour))
+		}
+	}
+}
+
+// ntsExchange sends NTS-protected request and authenticates the response, returning offset and round trip delay
+func ntsExchange(addr string, ke *nts.KEResult, timeout time.Duration) (*ntp.Packet, time.Duration, time.Duration, int, error) {
+	conn, err := net.DialTimeout("udp", addr, timeout)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, 0, 0, 0, err
+	}
+	originTime := time.Now()
+	sec, frac := ntp.Time(originTime)
+	header, err := (&ntp.Packet{Settings: 0x23, TxTimeSec: sec, TxTimeFrac: frac}).Bytes()
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	request, uniqueID, err := nts.AppendRequest(header, ke.Cookies[0], ke.C2S, 0)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	if _, err := conn.Write(request); err != nil {
+		return nil, 0, 0, 0, err
+	}
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	clientReceiveTime := time.Now()
+	cookies, err := nts.ParseResponse(buf[:n], uniqueID, ke.S2C)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	response, err := ntp.BytesToPacket(buf[:n])
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	originTime = ntp.Unix(sec, frac)
+	serverReceiveTime := ntp.Unix(response.RxTimeSec, response.RxTimeFrac)
+	serverTransmitTime := ntp.Unix(response.TxTimeSec, response.TxTimeFrac)
+	offset := time.Duration(ntp.Offset(originTime, serverReceiveTime, serverTransmitTime, clientReceiveTime))
+	delay := time.Duration(ntp.RoundTripDelay(originTime, serverReceiveTime, serverTransmitTime, clientReceiveTime))
+	return response, offset, delay, len(cookies), nil
+}
+
+// ntsCheck performs NTS-KE with the server followed by an authenticated NTP exchange, and reports every step.
+// It returns false if any of the checks failed
+func ntsCheck(host string, port int, roots *x509.CertPool, timeout time.Duration) bool {
+	r := &ntsReporter{output: []checkOutput{}}
+	defer func() {
+		if jsonOutput {
+			if err := printJSON(r.output); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}()
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	dialer := &net.Dialer{Timeout: timeout}
+	// certificates are verified separately to report problems instead of failing the handshake
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{
+		ServerName:         host,
+		NextProtos:         []string{nts.ALPN},
+		MinVersion:         tls.VersionTLS13,
+		InsecureSkipVerify: true, //#nosec G402
+	})
+	if err != nil {
+		r.report(FAIL, "TLS handshake with %s failed: %v", address, err)
+		return false
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		r.report(FAIL, "Setting deadline: %v", err)
+		return false
+	}
+	state := conn.ConnectionState()
+	r.report(OK, "Connected to %s with %s", address, color.BlueString(tls.CipherSuiteName(state.CipherSuite)))
+	checkCertificates(r, state, host, roots, time.Now())
+
+	ke, err := nts.KE(conn)
+	if err != nil {
+		r.report(FAIL, "NTS-KE failed: %v", err)
+		return false
+	}
+	for _, w := range ke.Warnings {
+		r.report(WARN, "Server sent NTS-KE warning %d", w)
+	}
+	if ke.C2S == nil {
+		aeads := []string{}
+		for _, a := range ke.AEADs {
+			aeads = append(aeads, aeadName(a))
+		}
+		r.report(FAIL, "No common protocol and AEAD algorithm, server offered protocols %v and AEAD algorithms %v", ke.Protocols, aeads)
+		return false
+	}
+	r.report(OK, "Negotiated NTPv4 with %s", color.BlueString(aeadName(nts.AEADAESSIVCMAC256)))
+	if len(ke.Cookies) == 0 {
+		r.report(FAIL, "Server sent no cookies")
+		return false
+	}
+	s := OK
+	if len(ke.Cookies) < 8 {
+		s = WARN
+	}
+	r.report(s, "Got %d cookies of %d bytes, RFC 8915 recommends 8", len(ke.Cookies), len(ke.Cookies[0]))
+
+	ntpHost, ntpPort := host, 123
+	if ke.Server != "" {
+		ntpHost = ke.Server
+	}
+	if ke.Port != 0 {
+		ntpPort = ke.Port
+	}
+	ntpAddress := net.JoinHostPort(ntpHost, strconv.Itoa(ntpPort))
+	response, offset, delay, cookies, err := ntsExchange(ntpAddress, ke, timeout)
+	if err != nil {
+		r.report(FAIL, "Authenticated NTP exchange with %s failed: %v", ntpAddress, err)
+		return false
+	}
+	r.report(OK, "Authenticated NTP exchange with %s: stratum %d, offset %v, delay %v", ntpAddress, response.Stratum, offset, delay)
+	if cookies == 0 {
+		r.report(WARN, "Server sent no fresh cookie in NTP response")
+	} else {
+		r.report(OK, "Got %d fresh cookie(s) in NTP response", cookies)
+	}
+	return !r.failed
+}
+
+func init() {
+	RootCmd.AddCommand(ntsCmd)
+	ntsCmd.Flags().IntVarP(&ntsKEPort, "port", "p", nts.DefaultKEPort, "NTS-KE port of the server")
+	ntsCmd.Flags().DurationVarP(&ntsTimeout, "timeout", "t", 5*time.Second, "timeout of NTS-KE session and NTP exchange")
+	ntsCmd.Flags().StringVarP(&ntsCAFile, "cacert", "c", "", "PEM file with CA certificates to verify server with instead of system ones")
+}
+
+var ntsCmd = &cobra.Command{
+	Use:   "nts <server>",
+	Short: "Check NTS-KE and authenticated NTP exchange with the server",
+	Long:  "'nts' performs NTS Key Establishment over TLS, reports negotiated algorithms, cookies and certificate chain problems, then sends NTS-protected NTP request using the cookie",
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		ConfigureVerbosity()
+		var roots *x509.CertPool
+		if ntsCAFile != "" {
+			pem, err := os.ReadFile(ntsCAFile)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			roots = x509.NewCertPool()
+			if !roots.AppendCertsFromPEM(pem) {
+				fmt.Printf("no certificates in %s\n", ntsCAFile)
+				os.Exit(1)
+			}
+		}
+		if !ntsCheck(args[0], ntsKEPort, roots, ntsTimeout) {
+			os.Exit(1)
+		}
+	},
+}
@@ -29,6 +29,11 @@ func printOffset(r *checker.NTPCheckResult) error {
 	if err != nil {
 		return err
 	}
+	if jsonOutput {
+		return printJSON(struct {
+			Offset float64 `json:"offset"`
+		}{Offset: stats.Offset})
+	}
 	fmt.Printf("%.3f\n", stats.Offset)
 	return nil
 }
@@ -17,9 +17,6 @@ limitations under the License.
 package cmd
 
 import (
-	"encoding/json"
-	"fmt"
-
 	"github.com/facebook/time/cmd/ntpcheck/checker"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -30,12 +27,7 @@ func printPeerStats(r *checker.NTPCheckResult, noDNS bool) error {
 	if err != nil {
 		return err
 	}
-	toPrint, err := json.Marshal(output)
-	if err != nil {
-		return err
-	}
-	fmt.Println(string(toPrint))
-	return nil
+	return printJSON(output)
 }
 
 func init() {
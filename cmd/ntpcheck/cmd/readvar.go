@@ -0,0 +1,67 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/facebook/time/cmd/ntpcheck/checker"
+	"github.com/spf13/cobra"
+)
+
+var readvarAssociationID uint16
+
+func printVariables(vars map[string]string) error {
+	if jsonOutput {
+		return printJSON(vars)
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Printf("%s=%s\n", name, vars[name])
+	}
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(readvarCmd)
+	readvarCmd.Flags().StringVarP(&server, "server", "S", "", "server to connect to")
+	readvarCmd.Flags().Uint16VarP(&readvarAssociationID, "assoc", "a", 0, "association id of the peer, 0 means system variables")
+}
+
+var readvarCmd = &cobra.Command{
+	Use:   "readvar [variable...]",
+	Short: "Read ntpd variables by name",
+	Long:  "'readvar' reads system or peer variables from ntpd, like 'ntpq -c rv' does. Use --keyid to authenticate the query if ntpd requires it",
+	Run: func(_ *cobra.Command, args []string) {
+		ConfigureVerbosity()
+		vars, err := checker.RunReadVariables(server, readvarAssociationID, args)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := printVariables(vars); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
@@ -0,0 +1,114 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/facebook/time/cmd/ntpcheck/checker"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var refclockMaxJitter float64
+var refclockRequirePPS bool
+
+// checkRefClocks checks that there are reference clocks, all of them are locked, and their jitter is within maxJitter (in ms)
+func checkRefClocks(refclocks []*checker.RefClock, maxJitter float64, requirePPS bool) []checkOutput {
+	if len(refclocks) == 0 {
+		return []checkOutput{{Status: CRITICAL, Message: "No reference clocks"}}
+	}
+	results := []checkOutput{}
+	pps := false
+	for _, rc := range refclocks {
+		name := color.BlueString(rc.Name)
+		if rc.Driver != "" {
+			name = fmt.Sprintf("%s (%s)", name, rc.Driver)
+		}
+		pps = pps || rc.PPS
+		if !rc.Locked {
+			results = append(results, checkOutput{Status: FAIL, Message: fmt.Sprintf("Reference clock %s is not locked: state %s, reach %o", name, color.RedString(rc.State), rc.Reach)})
+			continue
+		}
+		// PPS jitter is in single microseconds, so ms with 3 digits as in diag is not precise enough
+		st, jitter := OK, color.GreenString("%.6fms", rc.Jitter)
+		if rc.Jitter > maxJitter {
+			st, jitter = WARN, color.YellowString("%.6fms", rc.Jitter)
+		}
+		results = append(results, checkOutput{Status: st, Message: fmt.Sprintf(
+			"Reference clock %s is locked, offset is %.6fms, jitter is %s, we expect it to be within %s",
+			name, rc.Offset, jitter, color.BlueString("%.6fms", maxJitter),
+		)})
+		if rc.BadFormat > 0 || rc.BadData > 0 {
+			results = append(results, checkOutput{Status: WARN, Message: fmt.Sprintf("Reference clock %s reported %d bad format and %d bad data timecodes", name, rc.BadFormat, rc.BadData)})
+		}
+	}
+	if requirePPS && !pps {
+		results = append(results, checkOutput{Status: FAIL, Message: "No PPS reference clock"})
+	}
+	return results
+}
+
+func init() {
+	RootCmd.AddCommand(refclockCmd)
+	refclockCmd.Flags().StringVarP(&server, "server", "S", "", "server to connect to")
+	refclockCmd.Flags().Float64VarP(&refclockMaxJitter, "max-jitter", "j", 0.01, "max reference clock jitter in ms before warning")
+	refclockCmd.Flags().BoolVarP(&refclockRequirePPS, "require-pps", "p", false, "fail if there is no PPS reference clock")
+}
+
+var refclockCmd = &cobra.Command{
+	Use:   "refclock",
+	Short: "Check reference clocks (GNSS, PPS) of NTP server",
+	Long:  "'refclock' reports reference clocks of ntpd or chronyd, whether they are locked, their offset and jitter, and driver status. Exits with non-zero code if any of them is not locked",
+	Run: func(_ *cobra.Command, _ []string) {
+		ConfigureVerbosity()
+		refclocks, err := checker.RunRefClocks(server)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		results := checkRefClocks(refclocks, refclockMaxJitter, refclockRequirePPS)
+		worst := OK
+		for _, r := range results {
+			worst = max(worst, r.Status)
+		}
+		if jsonOutput {
+			err = printJSON(struct {
+				RefClocks []*checker.RefClock `json:"refclocks"`
+				Checks    []checkOutput       `json:"checks"`
+			}{RefClocks: refclocks, Checks: results})
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		} else {
+			for _, r := range results {
+				// CRITICAL is reported as FAIL, same as in diag
+				fmt.Printf("%s %s\n", statusToColor[min(r.Status, FAIL)], r.Message)
+			}
+			for _, rc := range refclocks {
+				if rc.Timecode != "" {
+					fmt.Printf("%s last timecode: %s\n", rc.Name, rc.Timecode)
+				}
+			}
+		}
+		if worst >= FAIL {
+			os.Exit(1)
+		}
+	},
+}
@@ -17,9 +17,13 @@ limitations under the License.
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 
+	"github.com/facebook/time/cmd/ntpcheck/checker"
+	"github.com/facebook/time/ntp/auth"
+	"github.com/fatih/color"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -28,22 +32,64 @@ import (
 var RootCmd = &cobra.Command{
 	Use:   "ntpcheck",
 	Short: "Swiss Army Knife for NTP",
+	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		if err := loadControlKey(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
 }
 
 var verbose bool
+var jsonOutput bool
 var server string
 var noDNS bool
+var keyFile string
+var keyID uint32
 
 func init() {
 	RootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	RootCmd.PersistentFlags().BoolVarP(&jsonOutput, "json", "", false, "machine-readable JSON output. Stats and chrony subcommands always output JSON")
+	RootCmd.PersistentFlags().StringVarP(&keyFile, "keyfile", "", "/etc/ntp.keys", "file with symmetric keys in ntp.keys format")
+	RootCmd.PersistentFlags().Uint32VarP(&keyID, "keyid", "", 0, "id of the key from keyfile to authenticate ntpd control queries with, 0 means no authentication")
 }
 
-// ConfigureVerbosity configures log verbosity based on parsed flags. Needs to be called by any subcommand.
+// loadControlKey loads the key to authenticate ntpd control queries with, if requested
+func loadControlKey() error {
+	if keyID == 0 {
+		return nil
+	}
+	keys, err := auth.ReadKeys(keyFile)
+	if err != nil {
+		return fmt.Errorf("reading keys: %w", err)
+	}
+	key, ok := keys[keyID]
+	if !ok {
+		return fmt.Errorf("no key %d in %s", keyID, keyFile)
+	}
+	checker.ControlKey = key
+	return nil
+}
+
+// ConfigureVerbosity configures log verbosity and output format based on parsed flags. Needs to be called by any subcommand.
 func ConfigureVerbosity() {
 	log.SetLevel(log.InfoLevel)
 	if verbose {
 		log.SetLevel(log.DebugLevel)
 	}
+	if jsonOutput {
+		color.NoColor = true
+	}
+}
+
+// printJSON prints v in JSON format on a single line
+func printJSON(v any) error {
+	toPrint, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(toPrint))
+	return nil
 }
 
 // Execute is the main entry point for CLI interface
@@ -17,9 +17,6 @@ limitations under the License.
 package cmd
 
 import (
-	"encoding/json"
-	"fmt"
-
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
@@ -27,12 +24,7 @@ import (
 )
 
 func printServerStats(r *checker.ServerStats) error {
-	toPrint, err := json.Marshal(r)
-	if err != nil {
-		return err
-	}
-	fmt.Println(string(toPrint))
-	return nil
+	return printJSON(r)
 }
 
 func init() {
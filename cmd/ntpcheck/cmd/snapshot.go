@@ -0,0 +1,110 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/facebook/time/cmd/ntpcheck/checker"
+	"github.com/fatih/color"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var diffThresholds checker.DiffThresholds
+
+func printSnapshotDiff(s *checker.Snapshot, d *checker.SnapshotDiff) {
+	fmt.Printf("Comparing with snapshot taken at %s\n", s.Time.Format("2006-01-02 15:04:05 MST"))
+	if d.Empty() {
+		fmt.Printf("%s No changes\n", okString)
+		return
+	}
+	for _, change := range d.Changes {
+		fmt.Printf("%s %s\n", warnString, change)
+	}
+	for _, p := range d.Peers {
+		switch {
+		case p.Added:
+			fmt.Printf("%s Peer %s was added\n", warnString, color.BlueString(p.Peer))
+		case p.Removed:
+			fmt.Printf("%s Peer %s was removed\n", failString, color.BlueString(p.Peer))
+		default:
+			fmt.Printf("%s Peer %s changed:\n%s\n", warnString, color.BlueString(p.Peer), formatPeers(p.Changes))
+		}
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.PersistentFlags().StringVarP(&server, "server", "S", "", "server to connect to")
+	snapshotCmd.AddCommand(snapshotSaveCmd)
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+	snapshotDiffCmd.Flags().Float64VarP(&diffThresholds.Offset, "offset", "o", 1.0, "report peers which offset changed by more than this, in ms")
+	snapshotDiffCmd.Flags().Float64VarP(&diffThresholds.Delay, "delay", "d", 1.0, "report peers which delay changed by more than this, in ms")
+}
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save peers and tracking state, and compare live state against it later",
+	Long:  "'snapshot' saves peers and tracking state to a file, so it can be compared with live state later, for example before and after network maintenance",
+}
+
+var snapshotSaveCmd = &cobra.Command{
+	Use:   "save <file>",
+	Short: "Save current peers and tracking state to a file",
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		ConfigureVerbosity()
+		result, err := checker.RunCheck(server)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := checker.WriteSnapshot(args[0], result); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff <file>",
+	Short: "Compare current peers and tracking state with the snapshot",
+	Long:  "'diff' highlights sys peer, leap and stratum changes, added and removed peers, and peers which changed stratum, selection or reach, or which offset or delay changed beyond thresholds. Exits with non-zero code if anything changed",
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		ConfigureVerbosity()
+		snapshot, err := checker.ReadSnapshot(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		result, err := checker.RunCheck(server)
+		if err != nil {
+			log.Fatal(err)
+		}
+		d := checker.DiffSnapshots(snapshot.Result, result, diffThresholds)
+		if jsonOutput {
+			if err := printJSON(d); err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			printSnapshotDiff(snapshot, d)
+		}
+		if !d.Empty() {
+			os.Exit(1)
+		}
+	},
+}
@@ -17,8 +17,6 @@ limitations under the License.
 package cmd
 
 import (
-	"encoding/json"
-	"fmt"
 	"math"
 
 	log "github.com/sirupsen/logrus"
@@ -42,19 +40,9 @@ func printStats(r *checker.NTPCheckResult, legacy bool) error {
 			NTPStats:      *output,
 			SystemNTPStat: math.Abs(output.Offset),
 		}
-		toPrint, err := json.Marshal(extraOutput)
-		if err != nil {
-			return err
-		}
-		fmt.Println(string(toPrint))
-		return nil
-	}
-	toPrint, err := json.Marshal(output)
-	if err != nil {
-		return err
+		return printJSON(extraOutput)
 	}
-	fmt.Println(string(toPrint))
-	return nil
+	return printJSON(output)
 }
 
 var legacyOutput = false
@@ -20,7 +20,6 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/binary"
-	"errors"
 	"fmt"
 	"math"
 	"net"
@@ -32,10 +31,8 @@ import (
 	"github.com/facebook/time/leaphash"
 	"github.com/facebook/time/leapsectz"
 	ntp "github.com/facebook/time/ntp/protocol"
-	"github.com/facebook/time/timestamp"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"golang.org/x/sys/unix"
 )
 
 // refID converts ip into ReFID format and prints it on stdout
@@ -91,30 +88,16 @@ func stripZeroes(num float64) string {
 	return strings.TrimRight(strings.TrimRight(s, "0"), ".")
 }
 
-// receiveNTPPacketWithRetries receives NTP packet from the socket and returns it.
-// In the perfect world we simply set socket to blocking mode and read from it, which blocks until we have a packet.
-// However currently there is a bug in the kernel which causes a race between receiving packet and unblocking recvmsg syscall,
-// which then can block forever.
-// To work around this, we are using socket-level timeouts and retrying to read packet.
-func receiveNTPPacketWithRetries(connFd int, buf, oob []byte, tries int) (*ntp.Packet, time.Time, error) {
-	var err error
-	var n int
-	var clientReceiveTime time.Time
-	for try := 0; try < tries; try++ {
-		n, _, clientReceiveTime, err = timestamp.ReadPacketWithRXTimestampBuf(connFd, buf, oob)
-		if errors.Is(err, unix.EINTR) || errors.Is(err, unix.EAGAIN) {
-			log.Debug("got timeout reading response packet, retrying")
-			continue
-		}
-		if err == nil {
-			break
-		}
-	}
-	if err != nil {
-		return nil, clientReceiveTime, err
-	}
-	response, err := ntp.BytesToPacket(buf[:n])
-	return response, clientReceiveTime, err
+// ntpDateOutput is ntpdate result in JSON output, offsets and delays are in seconds
+type ntpDateOutput struct {
+	Server      string    `json:"server"`
+	Stratum     uint8     `json:"stratum"`
+	Requests    int       `json:"requests"`
+	Offset      float64   `json:"offset"`
+	Delay       float64   `json:"delay"`
+	CorrectTime time.Time `json:"correct_time"`
+	AvgOffset   float64   `json:"avg_offset"`
+	AvgDelay    float64   `json:"avg_delay"`
 }
 
 // ntpDate prints data similar to 'ntptime' command output
@@ -140,17 +123,7 @@ func ntpDate(remoteServerAddr string, remoteServerPort string, requests int, ntp
 	}
 	defer conn.Close()
 
-	// get connection file descriptor
-	connFd, err := timestamp.ConnFd(conn.(*net.UDPConn))
-	if err != nil {
-		return err
-	}
-
-	// Allow reading of kernel timestamps via socket
-	if err := timestamp.EnableSWTimestampsRx(connFd); err != nil {
-		return err
-	}
-	err = unix.SetNonblock(connFd, false)
+	dc, err := newDateConn(conn.(*net.UDPConn), singleAttemptTimeout, tries)
 	if err != nil {
 		return err
 	}
@@ -168,16 +141,7 @@ func ntpDate(remoteServerAddr string, remoteServerPort string, requests int, ntp
 			log.Warningf("total %d packets skipped", skipped)
 		}
 	}()
-	// set socket level timeout to work around a kernel bug when recvmsg blocks forever.
-	// see receiveNTPPacketWithRetries for details
-	err = setSocketTimeout(connFd, singleAttemptTimeout)
-	if err != nil {
-		return err
-	}
-
-	// buffers
-	buf := make([]byte, 1024)
-	oob := make([]byte, 1024)
+	output := &ntpDateOutput{Server: addr, Requests: requests}
 
 	for i = 0; i < requests; i++ {
 		clientTransmitTime := time.Now()
@@ -194,7 +158,7 @@ func ntpDate(remoteServerAddr string, remoteServerPort string, requests int, ntp
 		if err := binary.Write(conn, binary.BigEndian, request); err != nil {
 			return fmt.Errorf("failed to send request, %w", err)
 		}
-		response, clientReceiveTime, err := receiveNTPPacketWithRetries(connFd, buf, oob, tries)
+		response, clientReceiveTime, err := dc.receive()
 		if err != nil {
 			log.Errorf("Error reading response to %d after %d tries, err = %v", i, tries, err)
 			continue
@@ -212,7 +176,7 @@ func ntpDate(remoteServerAddr string, remoteServerPort string, requests int, ntp
 		// sanity check: origin time must be same as client transmit time
 		// if it is not so, we probably have extra packet in the kernel queue which we need to read and discard
 		if response.OrigTimeSec != sec || response.OrigTimeFrac != frac {
-			response, clientReceiveTime, err = receiveNTPPacketWithRetries(connFd, buf, oob, tries)
+			response, clientReceiveTime, err = dc.receive()
 			if err != nil {
 				log.Errorf("Client TX timestamp %v not equal to Origin TX timestamp %v", clientTransmitTime, originTime)
 				return err
@@ -231,7 +195,12 @@ func ntpDate(remoteServerAddr string, remoteServerPort string, requests int, ntp
 		sumDelay += delay
 		sumOffset += offset
 
-		if i == requests-1 {
+		if i == requests-1 && jsonOutput {
+			output.Stratum = response.Stratum
+			output.Offset = float64(offset) / float64(time.Second.Nanoseconds())
+			output.Delay = float64(delay) / float64(time.Second.Nanoseconds())
+			output.CorrectTime = correctTime
+		} else if i == requests-1 {
 			fmt.Printf("\nServer: %s, Stratum: %d, Requests %d\n", addr, response.Stratum, requests)
 			fmt.Print("Last Request:")
 			fmt.Printf("Offset: %fs (%sus) | Delay: %fs (%sus)\n",
@@ -244,6 +213,11 @@ func ntpDate(remoteServerAddr string, remoteServerPort string, requests int, ntp
 	}
 	avgDelay := float64(sumDelay) / float64(requests)
 	avgOffset := float64(sumOffset) / float64(requests)
+	if jsonOutput {
+		output.AvgOffset = avgOffset / float64(time.Second.Nanoseconds())
+		output.AvgDelay = avgDelay / float64(time.Second.Nanoseconds())
+		return printJSON(output)
+	}
 	fmt.Printf("Average (%d requests):\n", requests)
 	fmt.Printf("Offset: %fs (%sus) | Delay: %fs (%sus)\n",
 		avgOffset/float64(time.Second.Nanoseconds()),
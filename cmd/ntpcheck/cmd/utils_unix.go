@@ -0,0 +1,95 @@
+//go:build !windows
+
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/facebook/time/timestamp"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// dateConn receives NTP responses with kernel RX timestamps
+type dateConn struct {
+	connFd int
+	tries  int
+	buf    []byte
+	oob    []byte
+}
+
+func newDateConn(conn *net.UDPConn, timeout time.Duration, tries int) (*dateConn, error) {
+	// get connection file descriptor
+	connFd, err := timestamp.ConnFd(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Allow reading of kernel timestamps via socket
+	if err := timestamp.EnableSWTimestampsRx(connFd); err != nil {
+		return nil, err
+	}
+	if err := unix.SetNonblock(connFd, false); err != nil {
+		return nil, err
+	}
+	// set socket level timeout to work around a kernel bug when recvmsg blocks forever.
+	// see receiveNTPPacketWithRetries for details
+	if err := setSocketTimeout(connFd, timeout); err != nil {
+		return nil, err
+	}
+	return &dateConn{
+		connFd: connFd,
+		tries:  tries,
+		buf:    make([]byte, 1024),
+		oob:    make([]byte, 1024),
+	}, nil
+}
+
+func (c *dateConn) receive() (*ntp.Packet, time.Time, error) {
+	return receiveNTPPacketWithRetries(c.connFd, c.buf, c.oob, c.tries)
+}
+
+// receiveNTPPacketWithRetries receives NTP packet from the socket and returns it.
+// In the perfect world we simply set socket to blocking mode and read from it, which blocks until we have a packet.
+// However currently there is a bug in the kernel which causes a race between receiving packet and unblocking recvmsg syscall,
+// which then can block forever.
+// To work around this, we are using socket-level timeouts and retrying to read packet.
+func receiveNTPPacketWithRetries(connFd int, buf, oob []byte, tries int) (*ntp.Packet, time.Time, error) {
+	var err error
+	var n int
+	var clientReceiveTime time.Time
+	for try := 0; try < tries; try++ {
+		n, _, clientReceiveTime, err = timestamp.ReadPacketWithRXTimestampBuf(connFd, buf, oob)
+		if errors.Is(err, unix.EINTR) || errors.Is(err, unix.EAGAIN) {
+			log.Debug("got timeout reading response packet, retrying")
+			continue
+		}
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, clientReceiveTime, err
+	}
+	response, err := ntp.BytesToPacket(buf[:n])
+	return response, clientReceiveTime, err
+}
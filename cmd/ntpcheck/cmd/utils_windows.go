@@ -0,0 +1,54 @@
+//go:build windows
+
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"net"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/facebook/time/timestamp"
+)
+
+// dateConn receives NTP responses with RX timestamps taken in userspace
+type dateConn struct {
+	conn    *timestamp.UserspaceConn
+	timeout time.Duration
+	buf     []byte
+}
+
+func newDateConn(conn *net.UDPConn, timeout time.Duration, tries int) (*dateConn, error) {
+	return &dateConn{
+		conn:    timestamp.NewUserspaceConn(conn),
+		timeout: time.Duration(tries) * timeout,
+		buf:     make([]byte, 1024),
+	}, nil
+}
+
+func (c *dateConn) receive() (*ntp.Packet, time.Time, error) {
+	if err := c.conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return nil, time.Time{}, err
+	}
+	n, _, clientReceiveTime, err := c.conn.ReadPacketWithRXTimestamp(c.buf)
+	if err != nil {
+		return nil, clientReceiveTime, err
+	}
+	response, err := ntp.BytesToPacket(c.buf[:n])
+	return response, clientReceiveTime, err
+}
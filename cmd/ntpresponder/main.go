@@ -26,7 +26,11 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"strings"
+	"time"
 
+	"github.com/facebook/time/fbclock/rpc"
+	"github.com/facebook/time/ntp/nts"
 	"github.com/facebook/time/ntp/responder/announce"
 	"github.com/facebook/time/ntp/responder/checker"
 	"github.com/facebook/time/ntp/responder/server"
@@ -52,12 +56,42 @@ func main() {
 	flag.IntVar(&s.Config.MonitoringPort, "monitoringport", 0, "Port to run monitoring server on")
 	flag.IntVar(&s.Config.Stratum, "stratum", 1, "Stratum of the server")
 	flag.IntVar(&s.Config.Workers, "workers", runtime.NumCPU()*100, "How many workers (routines) to run")
+	flag.IntVar(&s.Config.ReusePort, "reuseport", 0, "How many SO_REUSEPORT sockets per IP to open, each read and served by its own worker instead of the shared pool. 0 disables")
+	flag.BoolVar(&s.Config.PinWorkers, "pinworkers", false, "Pin SO_REUSEPORT workers to CPUs, one worker per CPU in a round robin")
 	flag.Var(&s.Config.IPs, "ip", fmt.Sprintf("IP to listen to. Repeat for multiple. Default: %s", server.DefaultServerIPs))
 	flag.BoolVar(&debugger, "pprof", false, "Enable pprof")
 	flag.BoolVar(&s.Config.ShouldAnnounce, "announce", false, "Advertize IPs")
+	flag.BoolVar(&s.Config.Anycast, "anycast", false, "IPs are anycast: add IPv6 ones as /128 without duplicate address detection and bind to IPs before they are on the host")
+	flag.DurationVar(&s.Config.WithdrawGrace, "withdrawgrace", 0, "How long to keep serving after withdrawing IPs on shutdown, before deleting them from the interface")
 	flag.DurationVar(&s.Config.ExtraOffset, "extraoffset", 0, "Extra offset to return to clients")
+	flag.DurationVar(&s.Config.BusyPoll.Timeout, "busypoll", 0, "Busy poll the NIC queue for that long on socket reads, 0 disables busy polling")
+	flag.IntVar(&s.Config.BusyPoll.Budget, "busypollbudget", 0, "Max packets processed per busy poll (SO_BUSY_POLL_BUDGET), requires CAP_NET_ADMIN. 0 keeps the kernel default")
+	flag.BoolVar(&s.Config.BusyPoll.Prefer, "preferbusypoll", false, "Suppress NIC interrupts while busy polling (SO_PREFER_BUSY_POLL)")
+	flag.DurationVar(&s.Config.LeapSmear, "leapsmear", 0, "Smear leap seconds over this window centered on the leap second, e.g. 24h. 0 disables smearing")
+	flag.TextVar(&s.Config.LeapSmearShape, "leapsmearshape", server.SmearLinear, fmt.Sprintf("Leap smear shape. Can be: %s, %s", server.SmearLinear, server.SmearCosine))
+	flag.Var(&s.Config.ACL, "acl", fmt.Sprintf("Access control rule prefix=action, the most specific prefix wins. Repeat for multiple. Action can be: %s, %s, %s", server.ACLServe, server.ACLIgnore, server.ACLDeny))
+	flag.TextVar(&s.Config.ACLDefault, "acldefault", server.ACLServe, "Action for clients no access control rule matches")
+	flag.Float64Var(&s.Config.RateLimit, "ratelimit", 0, "Requests per second allowed per client prefix, clients over the limit get RATE Kiss-o'-Death. 0 disables rate limiting")
+	flag.IntVar(&s.Config.RateLimitBurst, "ratelimitburst", 8, "Requests client can send at once before rate limiting kicks in")
+	flag.IntVar(&s.Config.RateLimitPrefixV4, "ratelimitprefix4", 32, "IPv4 prefix length clients are rate limited by")
+	flag.IntVar(&s.Config.RateLimitPrefixV6, "ratelimitprefix6", 64, "IPv6 prefix length clients are rate limited by")
+	flag.TextVar(&s.Config.SyncSource, "syncsource", server.SyncStatic, fmt.Sprintf("Where to take advertised stratum, reference id and root dispersion from. Can be: %s, %s, %s", server.SyncStatic, server.SyncFBClock, server.SyncSPTP))
+	flag.StringVar(&s.Config.SyncAddress, "syncaddress", rpc.DefaultSocketPath, "Address of the sync source: fbclock RPC socket or sptp monitoring host:port")
+	flag.DurationVar(&s.Config.SyncMaxError, "syncmaxerror", 0, "Advertise stratum 16 when error bound reported by the sync source is above this. 0 disables the check")
+	flag.StringVar(&s.Config.AuthKeys, "authkeys", "", "File with symmetric keys in ntp.keys format. Enables MD5/SHA1/SHA256 MAC authentication for clients which use them")
+	flag.StringVar(&s.Config.NTSCookieKeys, "ntscookiekeys", "", "File with hex-encoded NTS cookie keys, one per line, current first. Enables NTS")
+	flag.IntVar(&s.Config.NTSKEPort, "ntskeport", 0, fmt.Sprintf("Port to run NTS-KE on, usually %d. 0 disables NTS-KE", nts.DefaultKEPort))
+	flag.IntVar(&s.Config.NTSKEMaxSessions, "ntskemaxsessions", 1024, "Max concurrent NTS-KE sessions, clients over the limit wait for a free one")
+	flag.StringVar(&s.Config.NTSCert, "ntscert", "", "TLS certificate for NTS-KE")
+	flag.StringVar(&s.Config.NTSKey, "ntskey", "", "TLS key for NTS-KE")
+	flag.StringVar(&s.Config.ControlSocket, "controlsocket", "", "Unix socket to serve drain/undrain and health control API on. Empty disables the API")
+	flag.StringVar(&s.Config.ChronySocket, "chronysocket", "", "Unix socket to serve chronyc-compatible 'tracking' and 'serverstats' monitoring on, like /var/run/chrony/chronyd.sock. Empty disables it")
+	flag.DurationVar(&s.Config.DrainStep, "drainstep", time.Minute, "How often advertised stratum gets worse by one while drained in stratum mode")
+	flag.StringVar(&s.Config.CapturePath, "capturepath", "", "pcapng file to write sampled requests and responses to, with software and hardware timestamps. Empty disables capture")
+	flag.IntVar(&s.Config.CaptureSample, "capturesample", 1000, "Capture 1 in this many requests")
 	flag.BoolVar(&s.Config.ManageLoopback, "manage-loopback", true, "Add/remove IPs. If false, these must be managed elsewhere")
-	flag.TextVar(&s.Config.TimestampType, "timestamptype", timestamp.SWRX, fmt.Sprintf("Timestamp type. Can be: %s, %s", timestamp.HWRX, timestamp.SWRX))
+	flag.TextVar(&s.Config.TimestampType, "timestamptype", timestamp.SWRX, fmt.Sprintf("Timestamp type. Can be: %s, %s, %s. %s also enables hardware transmit timestamps and interleaved mode", timestamp.HW, timestamp.HWRX, timestamp.SWRX, timestamp.HW))
+	flag.TextVar(&timestamp.HWRXFilter, "rxfilter", timestamp.RXFilterAuto, fmt.Sprintf("Hardware RX timestamping filter. Can be: %s", strings.Join(timestamp.RXFilterNames(), ", ")))
 
 	flag.Parse()
 	s.Config.IPs.SetDefault()
@@ -102,6 +136,10 @@ func main() {
 		ExpectedListeners: int64(len(s.Config.IPs)),
 		ExpectedWorkers:   int64(s.Config.Workers),
 	}
+	if s.Config.ReusePort > 0 {
+		ch.ExpectedListeners = int64(len(s.Config.IPs) * s.Config.ReusePort)
+		ch.ExpectedWorkers = ch.ExpectedListeners
+	}
 
 	// context is used in server in case work needs to be interrupted internally
 	ctx, cancelFunc := context.WithCancel(context.Background())
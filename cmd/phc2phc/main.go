@@ -0,0 +1,124 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/facebook/time/phc"
+)
+
+func serveStats(syncer *phc.PHCSyncer, monitoringPort int) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		js, err := json.Marshal(syncer.Stats())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(js); err != nil {
+			log.Errorf("Failed to reply: %v", err)
+		}
+	})
+	addr := fmt.Sprintf(":%d", monitoringPort)
+	log.Infof("Starting http json server on %s", addr)
+	server := &http.Server{
+		Addr:         addr,
+		ReadTimeout:  time.Second,
+		WriteTimeout: time.Second,
+		Handler:      mux,
+	}
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("Failed to start listener: %v", err)
+	}
+}
+
+func doWork(source string, destinations []string, interval, stepth, offset time.Duration, monitoringPort int) error {
+	src, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("opening source device %q: %w", source, err)
+	}
+	defer src.Close()
+	srcdev := phc.FromFile(src)
+
+	syncer := &phc.PHCSyncer{Interval: interval}
+	for _, destination := range destinations {
+		// we need RW permissions to issue CLOCK_ADJTIME on the device, even with empty struct
+		dst, err := os.OpenFile(destination, os.O_RDWR, 0)
+		if err != nil {
+			return fmt.Errorf("opening destination device %q: %w", destination, err)
+		}
+		defer dst.Close()
+		name := fmt.Sprintf("%s->%s", filepath.Base(source), filepath.Base(destination))
+		pair, err := phc.NewSyncPair(name, srcdev, phc.FromFile(dst), interval, stepth, offset)
+		if err != nil {
+			return err
+		}
+		syncer.Pairs = append(syncer.Pairs, pair)
+	}
+	if monitoringPort != 0 {
+		go serveStats(syncer, monitoringPort)
+	}
+	return syncer.Run(context.Background())
+}
+
+func main() {
+	var (
+		verboseFlag        bool
+		sourceFlag         string
+		destinationsFlag   string
+		intervalFlag       time.Duration
+		stepthFlag         time.Duration
+		offsetFlag         time.Duration
+		monitoringPortFlag int
+	)
+
+	flag.BoolVar(&verboseFlag, "verbose", false, "verbose output")
+	flag.StringVar(&sourceFlag, "source", "/dev/ptp0", "source PHC device")
+	flag.StringVar(&destinationsFlag, "destinations", "/dev/ptp2", "comma-separated list of PHC devices to synchronize to the source")
+	flag.DurationVar(&intervalFlag, "interval", time.Second, "interval between syncs")
+	flag.DurationVar(&stepthFlag, "step", 0, "first step threshold")
+	flag.DurationVar(&offsetFlag, "offset", 0, "static offset of the destination from the source time, destination = source + offset")
+	flag.IntVar(&monitoringPortFlag, "monitoringport", 4270, "port to start monitoring http server on, disabled if 0")
+
+	flag.Parse()
+
+	log.SetLevel(log.InfoLevel)
+	if verboseFlag {
+		log.SetLevel(log.DebugLevel)
+	}
+	destinations := strings.Split(destinationsFlag, ",")
+	for _, d := range destinations {
+		if d == sourceFlag {
+			log.Fatalf("destination %s is the same as the source", d)
+		}
+	}
+	if err := doWork(sourceFlag, destinations, intervalFlag, stepthFlag, offsetFlag, monitoringPortFlag); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/facebook/time/phc"
+)
+
+func serveStats(p *phc.PHC2Sys, monitoringPort int) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		js, err := json.Marshal(p.Stats())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(js); err != nil {
+			log.Errorf("Failed to reply: %v", err)
+		}
+	})
+	addr := fmt.Sprintf(":%d", monitoringPort)
+	log.Infof("Starting http json server on %s", addr)
+	server := &http.Server{
+		Addr:         addr,
+		ReadTimeout:  time.Second,
+		WriteTimeout: time.Second,
+		Handler:      mux,
+	}
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("Failed to start listener: %v", err)
+	}
+}
+
+func doWork(device string, cfg *phc.PHC2SysConfig, monitoringPort int) error {
+	f, err := os.Open(device)
+	if err != nil {
+		return fmt.Errorf("opening device %q: %w", device, err)
+	}
+	defer f.Close()
+
+	p, err := phc.NewPHC2Sys(cfg, phc.FromFile(f), &phc.RealtimeClock{})
+	if err != nil {
+		return err
+	}
+	if monitoringPort != 0 {
+		go serveStats(p, monitoringPort)
+	}
+	return p.Run(context.Background())
+}
+
+func main() {
+	var (
+		verboseFlag        bool
+		deviceFlag         string
+		ifaceFlag          string
+		monitoringPortFlag int
+	)
+	cfg := &phc.PHC2SysConfig{}
+
+	flag.BoolVar(&verboseFlag, "verbose", false, "verbose output")
+	flag.StringVar(&deviceFlag, "device", "", "PHC device to sync the system clock from")
+	flag.StringVar(&ifaceFlag, "iface", "eth0", "network interface to use the PHC of, if device is not specified")
+	flag.DurationVar(&cfg.Interval, "interval", time.Second, "interval between syncs")
+	flag.DurationVar(&cfg.FirstStepThreshold, "firststep", 20*time.Microsecond, "step the clock on the first update if the offset is larger, 0 disables")
+	flag.DurationVar(&cfg.StepThreshold, "step", 0, "step the clock anytime the offset is larger, 0 disables")
+	flag.DurationVar(&cfg.MaxStep, "maxstep", 0, "refuse to step the clock by more than this, 0 means no limit")
+	flag.DurationVar(&cfg.Offset, "offset", 0, "static offset of the system clock from the PHC, like -37s for PHC in TAI")
+	flag.IntVar(&monitoringPortFlag, "monitoringport", 4271, "port to start monitoring http server on, disabled if 0")
+
+	flag.Parse()
+
+	log.SetLevel(log.InfoLevel)
+	if verboseFlag {
+		log.SetLevel(log.DebugLevel)
+	}
+	device := deviceFlag
+	if device == "" {
+		var err error
+		device, err = phc.IfaceToPHCDevice(ifaceFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := doWork(device, cfg, monitoringPortFlag); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -26,7 +26,6 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
-	"github.com/davecgh/go-spew/spew"
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 	"github.com/google/gopacket/pcapgo"
@@ -190,13 +189,16 @@ func run(input string, filter []ptp.MessageType) error {
 				dstPort = udp.DstPort
 			}
 			// dump ip:port info on stdout
-			spew.Printf("%s -> %s\n",
+			fmt.Printf("%s -> %s\n",
 				net.JoinHostPort(srcIP.String(), strconv.Itoa(int(srcPort))),
 				net.JoinHostPort(dstIP.String(), strconv.Itoa(int(dstPort))),
 			)
 			// dump the packet itself
-			spew.Dump(ptpContent.Packet)
-			spew.Println()
+			dump, err := ptp.Dump(ptpContent.Contents)
+			if err != nil {
+				return fmt.Errorf("failed to dump: %w", err)
+			}
+			fmt.Println(dump)
 		}
 		if err := packet.ErrorLayer(); err != nil {
 			return fmt.Errorf("failed to decode: %w", err.Error())
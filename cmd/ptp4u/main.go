@@ -22,6 +22,7 @@ import (
 	"net"
 	"net/http"
 	_ "net/http/pprof"
+	"strings"
 	"time"
 
 	"github.com/facebook/time/ptp/ptp4u/drain"
@@ -47,18 +48,24 @@ func main() {
 
 	var ipaddr string
 
+	flag.BoolVar(&c.OneStep, "onestep", false, "Send one-step Sync messages timestamped by the NIC on the fly, without Follow Up. Requires hardware timestamps")
+	flag.DurationVar(&c.BusyPoll.Timeout, "busypoll", 0, "Busy poll the NIC queue for that long on event socket reads, 0 disables busy polling")
+	flag.IntVar(&c.BusyPoll.Budget, "busypollbudget", 0, "Max packets processed per busy poll (SO_BUSY_POLL_BUDGET), requires CAP_NET_ADMIN. 0 keeps the kernel default")
+	flag.BoolVar(&c.BusyPoll.Prefer, "preferbusypoll", false, "Suppress NIC interrupts while busy polling (SO_PREFER_BUSY_POLL)")
 	flag.IntVar(&c.DSCP, "dscp", 0, "DSCP for PTP packets, valid values are between 0-63 (used by send workers)")
 	flag.IntVar(&c.MonitoringPort, "monitoringport", 8888, "Port to run monitoring server on")
 	flag.IntVar(&c.QueueSize, "queue", 0, "Size of the queue to send out packets")
 	flag.IntVar(&c.RecvWorkers, "recvworkers", 10, "Set the number of receive workers")
 	flag.IntVar(&c.SendWorkers, "workers", 100, "Set the number of send workers")
 	flag.UintVar(&c.DomainNumber, "domainnumber", 0, "Set the PTP domain by its number. Valid values are [0-255]")
+	flag.UintVar(&c.SdoID, "sdoid", 0, "Set the sdoId (majorSdoId and minorSdoId) of the PTP instance. Messages with other sdoId are ignored. Valid values are [0-4095]")
 	flag.StringVar(&c.ConfigFile, "config", "", "Path to a config with dynamic settings")
 	flag.StringVar(&c.DebugAddr, "pprofaddr", "", "host:port for the pprof to bind")
 	flag.StringVar(&c.Interface, "iface", "eth0", "Set the interface")
 	flag.StringVar(&c.LogLevel, "loglevel", "warning", "Set a log level. Can be: debug, info, warning, error")
 	flag.StringVar(&c.PidFile, "pidfile", "/var/run/ptp4u.pid", "Pid file location")
 	flag.TextVar(&c.TimestampType, "timestamptype", timestamp.HW, fmt.Sprintf("Timestamp type. Can be: %s, %s", timestamp.HW, timestamp.SW))
+	flag.TextVar(&timestamp.HWRXFilter, "rxfilter", timestamp.RXFilterAuto, fmt.Sprintf("Hardware RX timestamping filter. Can be: %s", strings.Join(timestamp.RXFilterNames(), ", ")))
 	flag.StringVar(&ipaddr, "ip", "::", "IP to bind on")
 	flag.StringVar(&c.DrainFileName, "drainfile", "/var/tmp/kill_ptp4u", "ptp4u drain file location")
 	flag.StringVar(&c.UndrainFileName, "undrainfile", "/var/tmp/unkill_ptp4u", "ptp4u force undrain file location")
@@ -93,6 +100,14 @@ func main() {
 		log.Fatalf("Unsupported DomainNumber value %v", c.DomainNumber)
 	}
 
+	if c.SdoID > 0xfff {
+		log.Fatalf("Unsupported SdoID value %v", c.SdoID)
+	}
+
+	if err := c.BusyPoll.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
 	switch c.TimestampType {
 	case timestamp.SW:
 		log.Warning("Software timestamps greatly reduce the precision")
@@ -103,6 +118,10 @@ func main() {
 		log.Fatalf("Unrecognized timestamp type: %s", c.TimestampType)
 	}
 
+	if c.OneStep && c.TimestampType != timestamp.HW {
+		log.Fatalf("One-step sync requires %s timestamps", timestamp.HW)
+	}
+
 	c.IP = net.ParseIP(ipaddr)
 	found, err := c.IfaceHasIP()
 	if err != nil {
@@ -17,6 +17,7 @@ limitations under the License.
 package checker
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -80,6 +81,11 @@ func PrepareMgmtClient(address string) (c *ptp.MgmtClient, cleanup func(), err e
 	}, cleanup, err
 }
 
+// isNotSupported checks if management error says the TLV is not implemented by the daemon
+func isNotSupported(err error) bool {
+	return errors.Is(err, ptp.ErrorNotSupported) || errors.Is(err, ptp.ErrorNoSuchID)
+}
+
 // RunPTP4L will talk over conn and return PTPCheckResult
 func RunPTP4L(c *ptp.MgmtClient) (*PTPCheckResult, error) {
 	var err error
@@ -122,7 +128,9 @@ func RunPTP4L(c *ptp.MgmtClient) (*PTPCheckResult, error) {
 
 	portStats, err := c.PortStatsNP()
 	// it's a non-standard ptp4l thing, might be missing
-	if err != nil {
+	if isNotSupported(err) {
+		log.Debugf("PortStatsNP is not supported: %v", err)
+	} else if err != nil {
 		log.Warningf("couldn't get PortStatsNP: %v", err)
 	} else {
 		log.Debugf("PortStatsNP: %+v", portStats)
@@ -134,7 +142,9 @@ func RunPTP4L(c *ptp.MgmtClient) (*PTPCheckResult, error) {
 
 	timeStatus, err := c.TimeStatusNP()
 	// it's a non-standard ptp4l thing, might be missing
-	if err != nil {
+	if isNotSupported(err) {
+		log.Debugf("TimeStatusNP is not supported: %v", err)
+	} else if err != nil {
 		log.Warningf("couldn't get TimeStatusNP: %v", err)
 	} else {
 		log.Debugf("TimeStatusNP: %+v", timeStatus)
@@ -143,7 +153,9 @@ func RunPTP4L(c *ptp.MgmtClient) (*PTPCheckResult, error) {
 
 	portServiceStats, err := c.PortServiceStatsNP()
 	// it's a non-standard ptp4l thing, might be missing
-	if err != nil {
+	if isNotSupported(err) {
+		log.Debugf("PortServiceStatsNP is not supported: %v", err)
+	} else if err != nil {
 		log.Warningf("couldn't get PortServiceStatsNP: %v", err)
 	} else {
 		log.Debugf("PortServiceStatsNP: %+v", portServiceStats)
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
@@ -549,3 +550,11 @@ func TestPrepareLocalConnError(t *testing.T) {
 	require.Nil(t, conn)
 	require.NotNil(t, cleanup)
 }
+
+func TestIsNotSupported(t *testing.T) {
+	require.True(t, isNotSupported(&ptp.ManagementError{ErrorID: ptp.ErrorNotSupported}))
+	require.True(t, isNotSupported(fmt.Errorf("wrapped: %w", &ptp.ManagementError{ErrorID: ptp.ErrorNoSuchID})))
+	require.False(t, isNotSupported(&ptp.ManagementError{ErrorID: ptp.ErrorWrongLength}))
+	require.False(t, isNotSupported(io.EOF))
+	require.False(t, isNotSupported(nil))
+}
@@ -28,7 +28,6 @@ import (
 	"golang.org/x/exp/constraints"
 
 	"github.com/facebook/time/cmd/ptpcheck/checker"
-	"github.com/facebook/time/phc"
 )
 
 // flag
@@ -128,10 +127,7 @@ func checkSyncActive(r *checker.PTPCheckResult) (status, string) {
 	if r.IngressTimeNS == 0 {
 		return WARN, "No ingress time data available"
 	}
-	phcTime, err := phc.Time(diagIfaceFlag, phc.MethodIoctlSysOffsetPrecise)
-	if err != nil {
-		phcTime, err = phc.Time(diagIfaceFlag, phc.MethodIoctlSysOffsetExtended)
-	}
+	phcTime, err := readPHCTime(diagIfaceFlag)
 	if err != nil {
 		return WARN, fmt.Sprintf("No PHC time data available: %v", err)
 	}
@@ -1,3 +1,5 @@
+//go:build linux
+
 /*
 Copyright (c) Facebook, Inc. and its affiliates.
 
@@ -1,3 +1,5 @@
+//go:build linux
+
 /*
 Copyright (c) Facebook, Inc. and its affiliates.
 
@@ -128,11 +130,12 @@ func tunePHC(device string, freq float64) error {
 }
 
 func printPHC(device string) error {
-	timeAndOffset, err := phc.TimeAndOffsetFromDevice(device, phc.MethodIoctlSysOffsetPrecise)
-	if err != nil {
-		timeAndOffset, err = phc.TimeAndOffsetFromDevice(device, phc.MethodIoctlSysOffsetExtended)
-	}
-	if err != nil {
+	var timeAndOffset phc.SysoffResult
+	measurement, err := phc.OffsetFromSystem(device)
+	if err == nil {
+		timeAndOffset = measurement.SysoffResult
+		log.Debugf("Measured offset using %s", measurement.Method)
+	} else {
 		log.Warningf("Falling back to clock_gettime method: %v", err)
 		timeAndOffset, err = phc.TimeAndOffsetFromDevice(device, phc.MethodSyscallClockGettime)
 		if err != nil {
@@ -0,0 +1,34 @@
+//go:build linux
+
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/facebook/time/phc"
+)
+
+// readPHCTime returns time of the PHC of the interface, using the most precise method available
+func readPHCTime(iface string) (time.Time, error) {
+	t, err := phc.Time(iface, phc.MethodIoctlSysOffsetPrecise)
+	if err != nil {
+		t, err = phc.Time(iface, phc.MethodIoctlSysOffsetExtended)
+	}
+	return t, err
+}
@@ -1,3 +1,5 @@
+//go:build linux
+
 /*
 Copyright (c) Facebook, Inc. and its affiliates.
 
@@ -144,6 +146,11 @@ func (p *ptping) runReader() error {
 			p.ts.t1 = announce.OriginTimestamp.Time()
 		default:
 			log.Infof("got unsupported packet %v:", msgType)
+			if log.IsLevelEnabled(log.DebugLevel) {
+				if dump, err := ptp.Dump(buf[:bbuf]); err == nil {
+					log.Debug(dump)
+				}
+			}
 		}
 	}
 }
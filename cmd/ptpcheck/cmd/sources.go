@@ -24,7 +24,6 @@ import (
 	"time"
 
 	"github.com/facebook/time/cmd/ptpcheck/checker"
-	"github.com/facebook/time/phc"
 	ptp "github.com/facebook/time/ptp/protocol"
 	"github.com/facebook/time/ptp/sptp/stats"
 
@@ -68,10 +67,7 @@ func sourcesRunPTP4l(server string, noDNS bool) error {
 	// obtain time from clock that ptp4l uses
 	var currentTime time.Time
 	if ppn.Timestamping == ptp.TimestampingHardware {
-		currentTime, err = phc.Time(string(ppn.Interface), phc.MethodIoctlSysOffsetPrecise)
-		if err != nil {
-			currentTime, err = phc.Time(string(ppn.Interface), phc.MethodIoctlSysOffsetExtended)
-		}
+		currentTime, err = readPHCTime(string(ppn.Interface))
 		if err != nil {
 			log.Errorf("No PHC time data available: %v", err)
 		}
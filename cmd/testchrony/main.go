@@ -56,6 +56,7 @@ var commands = []string{
 	"ntpdata",
 	"sourcename",
 	"selectdata",
+	"rtcdata",
 }
 
 func runCommand(address string, cmd string) error {
@@ -128,6 +129,13 @@ func runCommand(address string, cmd string) error {
 			return err
 		}
 		fmt.Printf("%+v\n", response)
+	case "rtcdata":
+		req := chrony.NewRTCDataPacket()
+		response, err := client.Communicate(req)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%+v\n", response)
 	case "serverstats":
 		req := chrony.NewServerStatsPacket()
 		response, err := client.Communicate(req)
@@ -38,7 +38,7 @@ func formSignalingPacket(hop int, routeIndex int) *ptp.Signaling {
 				PortNumber: uint16(routeIndex),
 			},
 			ControlField:       ZiffyHexa, //identifier for zi(0xff)y
-			LogMessageInterval: 0x7f,
+			LogMessageInterval: ptp.LogIntervalUnspecified,
 		},
 		TargetPortIdentity: ptp.PortIdentity{
 			PortNumber:    0xffff,
@@ -73,7 +73,7 @@ func formSyncPacket(msgType ptp.MessageType, hop int, routeIndex int) *ptp.SyncD
 				PortNumber: uint16(routeIndex),
 			},
 			ControlField:       ZiffyHexa, //identifier for zi(0xff)y
-			LogMessageInterval: 0x7f,
+			LogMessageInterval: ptp.LogIntervalUnspecified,
 		},
 	}
 }
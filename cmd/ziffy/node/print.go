@@ -86,7 +86,7 @@ func debugPrint(routes []*PathInfo) {
 			if swIndex == len(route.switches)-1 {
 				continue
 			}
-			corrField := route.switches[swIndex+1].corrField - route.switches[swIndex].corrField
+			corrField := route.switches[swIndex+1].corrField.Sub(route.switches[swIndex].corrField)
 			if corrField == ptp.Correction(0) && !tested[swh.ip] {
 				notTCEnabled = append(notTCEnabled, swh)
 			}
@@ -122,7 +122,7 @@ func debugPrint(routes []*PathInfo) {
 
 func correctionField(c *Config, swIndex int, switches []SwitchTrafficInfo) ptp.Correction {
 	if !c.Raw {
-		return switches[swIndex+1].corrField - switches[swIndex].corrField
+		return switches[swIndex+1].corrField.Sub(switches[swIndex].corrField)
 	}
 	return switches[swIndex+1].corrField
 }
@@ -176,7 +176,7 @@ func computeInfo(c *Config, routes []*PathInfo, cfThreshold ptp.Correction) map[
 			} else {
 				discovered[pair].routes++
 				if !last {
-					discovered[pair].totalCF += corrField
+					discovered[pair].totalCF = discovered[pair].totalCF.Add(corrField)
 					discovered[pair].divRoutes++
 					if discovered[pair].maxCF < corrField {
 						discovered[pair].maxCF = corrField
@@ -159,12 +159,16 @@ func parseSyncPacket(packet gopacket.Packet) (*ptp.SyncDelayReq, string, string,
 		return nil, "", "", fmt.Errorf("unable to parse UDP Header")
 	}
 
-	ptpPacket, err := ptp.DecodePacket(packet.ApplicationLayer().Payload())
+	ptpPacket, err := ptp.ParsePacket(packet.ApplicationLayer().Payload())
 	if err != nil {
 		return nil, "", "", fmt.Errorf("unable to decode PTP packet: %w", err)
 	}
 	if ptpPacket.MessageType() != ptp.MessageSync && ptpPacket.MessageType() != ptp.MessageDelayReq {
 		return nil, "", "", fmt.Errorf("not parsing %v: not a SYNC/DELAY_REQ packet", ptpPacket.MessageType().String())
 	}
-	return ptpPacket.(*ptp.SyncDelayReq), ipHeader.SrcIP.String(), strconv.Itoa(int(udpHeader.SrcPort)), nil
+	syncDelayReq, ok := ptpPacket.(*ptp.SyncDelayReq)
+	if !ok {
+		return nil, "", "", fmt.Errorf("not parsing %T: not a PTPv2 packet", ptpPacket)
+	}
+	return syncDelayReq, ipHeader.SrcIP.String(), strconv.Itoa(int(udpHeader.SrcPort)), nil
 }
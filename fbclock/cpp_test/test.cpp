@@ -66,6 +66,29 @@ TEST(fbclockTest, test_write_read) {
   remove(test_shm);
 }
 
+TEST(fbclockTest, test_shmdata_version) {
+  fbclock_shmdata shmp = {};
+  fbclock_clockdata read_data;
+
+  // version 1 layout had no header
+  EXPECT_EQ(fbclock_shmdata_version(&shmp), 1);
+  EXPECT_EQ(fbclock_clockdata_load_data(&shmp, &read_data), 0);
+
+  shmp.header.magic = FBCLOCK_SHM_MAGIC;
+  shmp.header.version = FBCLOCK_SHM_VERSION + 1;
+  shmp.header.compat_version = FBCLOCK_SHM_VERSION;
+  EXPECT_EQ(fbclock_shmdata_version(&shmp), FBCLOCK_SHM_VERSION + 1);
+  EXPECT_EQ(fbclock_clockdata_load_data(&shmp, &read_data), 0);
+
+  shmp.header.compat_version = FBCLOCK_SHM_VERSION + 1;
+  EXPECT_EQ(fbclock_shmdata_version(&shmp), FBCLOCK_E_SHMEM_VERSION);
+  EXPECT_EQ(
+      fbclock_clockdata_load_data(&shmp, &read_data), FBCLOCK_E_SHMEM_VERSION);
+
+  shmp.header.magic = 0xdeadbeef;
+  EXPECT_EQ(fbclock_shmdata_version(&shmp), FBCLOCK_E_SHMEM_VERSION);
+}
+
 int writer_thread(int sfd_rw, int tries) {
   int err;
   fbclock_clockdata data = {
@@ -359,3 +382,126 @@ int main(int argc, char** argv) {
   ::testing::InitGoogleTest(&argc, argv);
   return RUN_ALL_TESTS();
 }
+
+TEST(fbclockTest, test_fbclock_leap_indicator) {
+  fbclock_clockdata state = {};
+  // no tzdata information
+  EXPECT_EQ(fbclock_leap_indicator(&state, 1e18), FBCLOCK_LEAP_NONE);
+
+  state.utc_offset_pre_s = 37;
+  state.utc_offset_post_s = 38;
+  state.clock_smearing_start_s = 1000;
+  state.clock_smearing_end_s = 2000;
+  EXPECT_EQ(fbclock_leap_indicator(&state, 999e9), FBCLOCK_LEAP_PENDING);
+  EXPECT_EQ(fbclock_leap_indicator(&state, 1000e9), FBCLOCK_LEAP_SMEARING);
+  EXPECT_EQ(fbclock_leap_indicator(&state, 2000e9), FBCLOCK_LEAP_SMEARING);
+  EXPECT_EQ(fbclock_leap_indicator(&state, 2001e9), FBCLOCK_LEAP_NONE);
+
+  // offsets are equal after the event passed
+  state.utc_offset_pre_s = 38;
+  EXPECT_EQ(fbclock_leap_indicator(&state, 999e9), FBCLOCK_LEAP_NONE);
+}
+
+TEST(fbclockTest, test_fbclock_check_data_age) {
+  fbclock_shmdata shmp = {};
+  // no heartbeat from version 1 writers
+  EXPECT_EQ(fbclock_shmdata_age_ns(&shmp, 1000), -1);
+  EXPECT_EQ(fbclock_check_data_age(&shmp, 10, 1000), FBCLOCK_E_NO_ERROR);
+
+  shmp.header.magic = FBCLOCK_SHM_MAGIC;
+  shmp.header.version = FBCLOCK_SHM_VERSION;
+  shmp.header.compat_version = FBCLOCK_SHM_COMPAT_VERSION;
+  shmp.heartbeat_ns = 900;
+  EXPECT_EQ(fbclock_shmdata_age_ns(&shmp, 1000), 100);
+  EXPECT_EQ(fbclock_check_data_age(&shmp, 100, 1000), FBCLOCK_E_NO_ERROR);
+  EXPECT_EQ(fbclock_check_data_age(&shmp, 99, 1000), FBCLOCK_E_DATA_STALE);
+  // check disabled
+  EXPECT_EQ(fbclock_check_data_age(&shmp, 0, 1000), FBCLOCK_E_NO_ERROR);
+}
+
+TEST(fbclockTest, test_seqlock) {
+  fbclock_shmdata shmp = {};
+  fbclock_clockdata data = {
+      .ingress_time_ns = 1, .error_bound_ns = 2, .holdover_multiplier_ns = 3};
+  fbclock_clockdata read_data;
+  char* test_shm = std::tmpnam(nullptr);
+  FILE* f = fopen(test_shm, "wb+");
+  int sfd_rw = fileno(f);
+  ASSERT_NE(sfd_rw, -1);
+  ASSERT_EQ(ftruncate(sfd_rw, FBCLOCK_SHMDATA_SIZE), 0);
+  ASSERT_EQ(fbclock_clockdata_store_data(sfd_rw, &data), 0);
+  ASSERT_EQ(pread(sfd_rw, &shmp, sizeof(shmp), 0), (ssize_t)sizeof(shmp));
+  fclose(f);
+  remove(test_shm);
+
+  // every write bumps the sequence by 2, leaving it even
+  EXPECT_EQ((uint64_t)shmp.seq, 2);
+  EXPECT_EQ(fbclock_clockdata_load_data(&shmp, &read_data), 0);
+  EXPECT_EQ(read_data.ingress_time_ns, 1);
+
+  // writer is stuck mid-update
+  shmp.seq = 3;
+  EXPECT_EQ(
+      fbclock_clockdata_load_data(&shmp, &read_data), FBCLOCK_E_CRC_MISMATCH);
+}
+
+TEST(fbclockTest, test_fbclock_wait_interval_ns) {
+  fbclock_truetime truetime = {.earliest_ns = 1000000, .latest_ns = 1000200};
+  EXPECT_EQ(fbclock_wait_interval_ns(&truetime, 999999), 0);
+  EXPECT_EQ(fbclock_wait_interval_ns(&truetime, 1000200), 1000);
+  EXPECT_EQ(fbclock_wait_interval_ns(&truetime, 1005000), 5000);
+  // the target itself has to be in the past
+  EXPECT_EQ(fbclock_wait_interval_ns(&truetime, 1000000), 1000);
+}
+
+TEST(fbclockTest, test_shmdata_load_step) {
+  fbclock_shmdata shmp = {};
+  fbclock_step step = {.count = 42};
+
+  // older writers don't detect steps
+  EXPECT_EQ(fbclock_shmdata_load_step(&shmp, &step), 0);
+  EXPECT_EQ(step.count, 0);
+
+  shmp.header.magic = FBCLOCK_SHM_MAGIC;
+  shmp.header.version = FBCLOCK_SHM_VERSION;
+  shmp.header.compat_version = FBCLOCK_SHM_COMPAT_VERSION;
+  shmp.step.count = 2;
+  shmp.step.step_ns = -1000;
+  shmp.step.phctime_ns = 1647359186979431900;
+  EXPECT_EQ(fbclock_shmdata_load_step(&shmp, &step), 0);
+  EXPECT_EQ(step.count, 2);
+  EXPECT_EQ(step.step_ns, -1000);
+  EXPECT_EQ(step.phctime_ns, 1647359186979431900);
+
+  // writer is stuck mid-update
+  shmp.seq = 1;
+  EXPECT_EQ(fbclock_shmdata_load_step(&shmp, &step), FBCLOCK_E_CRC_MISMATCH);
+}
+
+TEST(fbclockTest, test_fbclock_monotonic_to_truetime) {
+  fbclock_monotonic_map map = {
+      .monotonic_ns = 3600000000000,
+      .truetime = {.earliest_ns = 1700000000000000000 - 100,
+                   .latest_ns = 1700000000000000000 + 100},
+      .drift_ppb = FBCLOCK_MONOTONIC_MAX_DRIFT_PPB};
+  fbclock_truetime truetime;
+
+  ASSERT_EQ(
+      fbclock_monotonic_to_truetime(&map, map.monotonic_ns, &truetime), 0);
+  EXPECT_EQ(truetime.earliest_ns, map.truetime.earliest_ns);
+  EXPECT_EQ(truetime.latest_ns, map.truetime.latest_ns);
+
+  // 500ppm of 1s later
+  ASSERT_EQ(
+      fbclock_monotonic_to_truetime(
+          &map, map.monotonic_ns + 1000000000, &truetime),
+      0);
+  EXPECT_EQ(truetime.earliest_ns, 1700000001000000000 - 500100);
+  EXPECT_EQ(truetime.latest_ns, 1700000001000000000 + 500100);
+
+  // before epoch
+  map.truetime.earliest_ns = 1000000000;
+  EXPECT_EQ(
+      fbclock_monotonic_to_truetime(&map, 0, &truetime),
+      FBCLOCK_E_PHC_IN_THE_PAST);
+}
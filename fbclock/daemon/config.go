@@ -19,6 +19,9 @@ package daemon
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -35,8 +38,19 @@ type Config struct {
 	Iface                          string        // network interface to use
 	LinearizabilityTestInterval    time.Duration // perform the linearizability test every so often
 	SPTP                           bool          // denotes whether we are running in sptp or ptp4l mode
+	AutoSource                     bool          // pick between sptp at SPTPAddress and ptp4l at PTPClientAddress automatically, overrides SPTP
+	SPTPAddress                    string        // sptp monitoring address, used with AutoSource
 	LinearizabilityTestMaxGMOffset time.Duration // max offset between GMs before linearizability test considered failed
 	BootDelay                      time.Duration // postpone startup by this time after boot
+	RPCSocket                      string        // serve GetTime/GetError over JSON-RPC on this unix socket, disabled if empty
+	Sources                        []Source      // additional PTP clients synchronizing other PHCs on this host, aggregated with the main one
+	HistoryPath                    string        // keep history of error bounds in this file, disabled if empty
+	HistorySize                    int           // max number of records in history file, older ones are overwritten
+	DataSource                     string        // name of registered data source, overrides sptp and autosource if set
+	StaticData                     *DataPoint    // data returned by static data source
+	StepThreshold                  time.Duration // PHC changes relative to monotonic clock by more than this between updates are reported as steps, 0 disables detection
+	Hooks                          []Hook        // run commands or webhooks when WOU crosses threshold or data goes stale
+	ShmPaths                       []string      // publish shm into these paths as well, directory can be a glob like /var/lib/containers/*/rootfs/run/fbclock_data_v1
 }
 
 // EvalAndValidate makes sure config is valid and evaluates expressions for further use.
@@ -44,6 +58,17 @@ func (c *Config) EvalAndValidate() error {
 	if c.PTPClientAddress == "" {
 		return fmt.Errorf("bad config: 'ptpclientaddress'")
 	}
+	if c.DataSource != "" {
+		if !slices.Contains(DataSources(), c.DataSource) {
+			return fmt.Errorf("bad config: unknown data source %q", c.DataSource)
+		}
+		if c.DataSource == DataSourceStatic && c.StaticData == nil {
+			return fmt.Errorf("bad config: 'staticdata' is required with %q data source", DataSourceStatic)
+		}
+	}
+	if (c.AutoSource || c.DataSource == DataSourceAuto) && c.SPTPAddress == "" {
+		return fmt.Errorf("bad config: 'sptpaddress' is required with 'autosource'")
+	}
 	if c.RingSize <= 0 {
 		return fmt.Errorf("bad config: 'ringsize' must be >0")
 	}
@@ -51,6 +76,14 @@ func (c *Config) EvalAndValidate() error {
 		return fmt.Errorf("bad config: 'interval' must be between 0 and 1 minute")
 	}
 
+	if c.HistoryPath != "" && c.HistorySize <= 0 {
+		return fmt.Errorf("bad config: 'historysize' must be >0")
+	}
+
+	if c.StepThreshold < 0 {
+		return fmt.Errorf("bad config: 'stepthreshold' must not be negative")
+	}
+
 	if c.LinearizabilityTestInterval < 0 {
 		return fmt.Errorf("bad config: 'test interval' must be positive")
 	}
@@ -58,6 +91,34 @@ func (c *Config) EvalAndValidate() error {
 	if c.LinearizabilityTestMaxGMOffset < 0 {
 		return fmt.Errorf("bad config: 'offset' must be positive")
 	}
+	for _, p := range c.ShmPaths {
+		if !filepath.IsAbs(p) {
+			return fmt.Errorf("bad config: shm path %q must be absolute", p)
+		}
+		if strings.ContainsAny(filepath.Base(p), "*?[\\") {
+			return fmt.Errorf("bad config: shm path %q must not have a pattern in file name", p)
+		}
+	}
+	names := map[string]bool{}
+	for _, src := range c.Sources {
+		if err := src.validate(); err != nil {
+			return err
+		}
+		if names[src.Name] {
+			return fmt.Errorf("bad config: duplicate source %q", src.Name)
+		}
+		names[src.Name] = true
+	}
+	hooks := map[string]bool{}
+	for _, h := range c.Hooks {
+		if err := h.validate(); err != nil {
+			return err
+		}
+		if hooks[h.Name] {
+			return fmt.Errorf("bad config: duplicate hook %q", h.Name)
+		}
+		hooks[h.Name] = true
+	}
 	return c.Math.Prepare()
 }
 
@@ -37,6 +37,10 @@ func TestEvalAndValidate(t *testing.T) {
 	require.Equal(t, fmt.Errorf("bad config: 'ptpclientaddress'"), c.EvalAndValidate())
 
 	c.PTPClientAddress = "some address"
+	c.AutoSource = true
+	require.Equal(t, fmt.Errorf("bad config: 'sptpaddress' is required with 'autosource'"), c.EvalAndValidate())
+
+	c.SPTPAddress = "localhost:4269"
 	require.Equal(t, fmt.Errorf("bad config: 'ringsize' must be >0"), c.EvalAndValidate())
 
 	c.RingSize = 42
@@ -50,6 +54,56 @@ func TestEvalAndValidate(t *testing.T) {
 
 	c.LinearizabilityTestMaxGMOffset = 1 * time.Microsecond
 	require.Nil(t, c.EvalAndValidate())
+
+	c.HistoryPath = "/var/lib/fbclock/history"
+	require.Equal(t, fmt.Errorf("bad config: 'historysize' must be >0"), c.EvalAndValidate())
+
+	c.HistorySize = 3600
+	require.Nil(t, c.EvalAndValidate())
+	c.HistoryPath = ""
+
+	c.ShmPaths = []string{"run/containers/*/shm/fbclock_data"}
+	require.Equal(t, fmt.Errorf("bad config: shm path \"run/containers/*/shm/fbclock_data\" must be absolute"), c.EvalAndValidate())
+
+	c.ShmPaths = []string{"/run/containers/*/shm/fbclock_*"}
+	require.Equal(t, fmt.Errorf("bad config: shm path \"/run/containers/*/shm/fbclock_*\" must not have a pattern in file name"), c.EvalAndValidate())
+
+	c.ShmPaths = []string{"/run/containers/*/shm/fbclock_data"}
+	require.Nil(t, c.EvalAndValidate())
+	c.ShmPaths = nil
+
+	c.DataSource = "nope"
+	require.Equal(t, fmt.Errorf("bad config: unknown data source \"nope\""), c.EvalAndValidate())
+
+	c.DataSource = DataSourceStatic
+	require.Equal(t, fmt.Errorf("bad config: 'staticdata' is required with \"static\" data source"), c.EvalAndValidate())
+
+	c.StaticData = &DataPoint{ClockAccuracyNS: 25}
+	require.Nil(t, c.EvalAndValidate())
+	c.DataSource = ""
+	c.StaticData = nil
+
+	c.StepThreshold = -time.Millisecond
+	require.Equal(t, fmt.Errorf("bad config: 'stepthreshold' must not be negative"), c.EvalAndValidate())
+	c.StepThreshold = time.Millisecond
+	require.Nil(t, c.EvalAndValidate())
+
+	c.Hooks = []Hook{{Name: "drain", MaxWOU: time.Millisecond, Exec: "/usr/local/bin/drain"}}
+	require.Nil(t, c.EvalAndValidate())
+	c.Hooks = append(c.Hooks, Hook{Name: "drain", StaleAfter: time.Minute, URL: "http://localhost/drain"})
+	require.Equal(t, fmt.Errorf("bad config: duplicate hook \"drain\""), c.EvalAndValidate())
+	c.Hooks = []Hook{{Name: "drain", Exec: "/usr/local/bin/drain"}}
+	require.Equal(t, fmt.Errorf("bad config: hook \"drain\": either 'maxwou' or 'staleafter' is required"), c.EvalAndValidate())
+	c.Hooks = nil
+
+	c.Sources = []Source{{Name: "eth1", Iface: "eth1", PTPClientAddress: "/var/run/ptp4l.eth1"}}
+	require.Nil(t, c.EvalAndValidate())
+
+	c.Sources = append(c.Sources, Source{Name: "eth1", Iface: "eth2", PTPClientAddress: "/var/run/ptp4l.eth2"})
+	require.Equal(t, fmt.Errorf("bad config: duplicate source \"eth1\""), c.EvalAndValidate())
+
+	c.Sources = []Source{{Name: "eth1", PTPClientAddress: "/var/run/ptp4l.eth1"}}
+	require.Equal(t, fmt.Errorf("bad config: source \"eth1\": 'iface' is required"), c.EvalAndValidate())
 }
 
 func TestPostponeStart(t *testing.T) {
@@ -22,13 +22,16 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"os/signal"
 	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/sys/unix"
 
 	"github.com/facebook/time/fbclock"
+	"github.com/facebook/time/fbclock/rpc"
 	"github.com/facebook/time/fbclock/stats"
 
 	"github.com/facebook/time/leapsectz"
@@ -105,8 +108,18 @@ type Daemon struct {
 	DataFetcher
 	cfg   *Config
 	state *daemonState
-	stats stats.Server
-	l     Logger
+	// history of what we wrote to shm, optional
+	history *History
+	// copies of shm for containers, optional
+	publisher *shmPublisher
+	// PHC step detection
+	steps stepDetector
+	// hooks triggered on health changes, optional
+	hooks *hookRunner
+	// protects cfg.Math, the only part of config which can be reloaded
+	mathLock sync.RWMutex
+	stats    stats.Server
+	l        Logger
 
 	// function to get PHC time from configured PHC device
 	getPHCTime func() (time.Time, error)
@@ -190,11 +203,11 @@ func New(cfg *Config, stats stats.Server, l Logger) (*Daemon, error) {
 		cfg:   cfg,
 		l:     l,
 	}
-	if cfg.SPTP {
-		s.DataFetcher = &HTTPFetcher{}
-	} else {
-		s.DataFetcher = &SockFetcher{}
+	fetcher, err := newDataFetcher(cfg)
+	if err != nil {
+		return nil, err
 	}
+	s.DataFetcher = fetcher
 
 	phcDevice, err := phc.IfaceToPHCDevice(cfg.Iface)
 	if err != nil {
@@ -207,6 +220,12 @@ func New(cfg *Config, stats stats.Server, l Logger) (*Daemon, error) {
 		return nil, err
 	}
 	dev := phc.FromFile(f)
+	if len(cfg.Sources) > 0 {
+		s.DataFetcher, err = NewMultiFetcher(s.DataFetcher, f, cfg, stats)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// function to get time from phc
 	s.getPHCTime = func() (time.Time, error) { return dev.Time() }
@@ -219,6 +238,19 @@ func New(cfg *Config, stats stats.Server, l Logger) (*Daemon, error) {
 	s.stats.SetCounter("data_error", 0)
 	s.stats.SetCounter("processing_error", 0)
 	s.stats.SetCounter("data_sanity_check_error", 0)
+	s.stats.SetCounter("config_reload", 0)
+	s.stats.SetCounter("config_reload_error", 0)
+	s.stats.SetCounter("shm_write", 0)
+	s.stats.SetCounter("shm_write_error", 0)
+	s.stats.SetCounter("shm_published", 0)
+	s.stats.SetCounter("hook_events", 0)
+	s.stats.SetCounter("step_count", 0)
+	s.stats.SetCounter("step_ns", 0)
+	s.stats.SetCounter("calc_latency_us", 0)
+	s.stats.SetCounter("wou_ns", 0)
+	// data source health
+	s.stats.SetCounter("source_up", 0)
+	s.stats.SetCounter("source_sptp", 0)
 	// values collected from ptp4l
 	s.stats.SetCounter("ingress_time_ns", 0)
 	s.stats.SetCounter("master_offset_ns", 0)
@@ -229,9 +261,51 @@ func New(cfg *Config, stats stats.Server, l Logger) (*Daemon, error) {
 	s.stats.SetCounter("master_offset_ns.60.abs_max", 0)
 	s.stats.SetCounter("path_delay_ns.60.abs_max", 0)
 	s.stats.SetCounter("freq_adj_ppb.60.abs_max", 0)
+	s.stats.SetCounter("wou_ns.60.p50", 0)
+	s.stats.SetCounter("wou_ns.60.p99", 0)
+	s.stats.SetCounter("wou_ns.60.max", 0)
 	return s, nil
 }
 
+// currentMath returns math currently in use, which can be changed by ReloadConfig
+func (s *Daemon) currentMath() Math {
+	s.mathLock.RLock()
+	defer s.mathLock.RUnlock()
+	return s.cfg.Math
+}
+
+// ReloadConfig reads config from path and applies its math without restart.
+// Other config values are only applied on restart.
+func (s *Daemon) ReloadConfig(path string) error {
+	c, err := ReadConfig(path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	if err := c.EvalAndValidate(); err != nil {
+		return fmt.Errorf("validating config: %w", err)
+	}
+	s.mathLock.Lock()
+	s.cfg.Math = c.Math
+	s.mathLock.Unlock()
+	log.Infof("reloaded math: M=%q, W=%q, Drift=%q, coefficients: %v", c.Math.M, c.Math.W, c.Math.Drift, c.Math.Coefficients)
+	return nil
+}
+
+// HandleSighup reloads config from path on every SIGHUP
+func (s *Daemon) HandleSighup(path string) {
+	sigchan := make(chan os.Signal, 1)
+	signal.Notify(sigchan, unix.SIGHUP)
+	for range sigchan {
+		log.Info("SIGHUP received, reloading config")
+		if err := s.ReloadConfig(path); err != nil {
+			log.Errorf("failed to reload config: %v. Moving on", err)
+			s.stats.UpdateCounterBy("config_reload_error", 1)
+			continue
+		}
+		s.stats.UpdateCounterBy("config_reload", 1)
+	}
+}
+
 func (s *Daemon) calcW() (float64, error) {
 	lastN := s.state.takeDataPoint(s.cfg.RingSize)
 	params := prepareMathParameters(lastN)
@@ -247,29 +321,30 @@ func (s *Daemon) calcW() (float64, error) {
 		FreqAdjustmentStddevPPB: stddev(params["freq"]),
 		ClockAccuracyMean:       mean(params["clockaccuracie"]),
 	}
-	mRaw, err := s.cfg.Math.mExpr.Evaluate(mapOfInterface(params))
+	m := s.currentMath()
+	mRaw, err := m.mExpr.Evaluate(m.parameters(mapOfInterface(params)))
 	if err != nil {
 		return 0, err
 	}
-	m := mRaw.(float64)
-	logSample.MeasurementNS = m
-	s.stats.SetCounter("m_ns", int64(m))
+	mValue := mRaw.(float64)
+	logSample.MeasurementNS = mValue
+	s.stats.SetCounter("m_ns", int64(mValue))
 
 	// push m to ring buffer
-	s.state.pushM(m)
+	s.state.pushM(mValue)
 
 	ms := s.state.takeM(s.cfg.RingSize)
 	if len(ms) != s.cfg.RingSize {
 		return 0, fmt.Errorf("%w getting W: want %d, got %d", errNotEnoughData, s.cfg.RingSize, len(ms))
 	}
 
-	parameters := map[string]interface{}{
+	parameters := m.parameters(map[string]interface{}{
 		"m": ms,
-	}
+	})
 	logSample.MeasurementMeanNS = mean(ms)
 	logSample.MeasurementStddevNS = stddev(ms)
 
-	wRaw, err := s.cfg.Math.wExpr.Evaluate(parameters)
+	wRaw, err := m.wExpr.Evaluate(parameters)
 	if err != nil {
 		return 0, err
 	}
@@ -288,7 +363,8 @@ func (s *Daemon) calcDriftPPB() (float64, error) {
 		return 0, fmt.Errorf("%w calculating drift: want %d, got %d", errNotEnoughData, s.cfg.RingSize, len(lastN))
 	}
 	params := prepareMathParameters(lastN)
-	driftRaw, err := s.cfg.Math.driftExpr.Evaluate(mapOfInterface(params))
+	m := s.currentMath()
+	driftRaw, err := m.driftExpr.Evaluate(m.parameters(mapOfInterface(params)))
 	if err != nil {
 		return 0, err
 	}
@@ -367,7 +443,9 @@ func (s *Daemon) doWork(shm *fbclock.Shm, data *DataPoint) error {
 		log.Warningf("Failed to get leap seconds: %v", err)
 	}
 	// store everything in shared memory
+	calcStart := time.Now()
 	d, err := s.calculateSHMData(data, leaps)
+	s.stats.SetCounter("calc_latency_us", time.Since(calcStart).Microseconds())
 	if err != nil {
 		if errors.Is(err, errNotEnoughData) {
 			log.Warning(err)
@@ -376,13 +454,47 @@ func (s *Daemon) doWork(shm *fbclock.Shm, data *DataPoint) error {
 		return err
 	}
 	if err := fbclock.StoreFBClockData(shm.File.Fd(), *d); err != nil {
+		s.stats.UpdateCounterBy("shm_write_error", 1)
 		return err
 	}
+	s.stats.UpdateCounterBy("shm_write", 1)
+	if s.publisher != nil {
+		s.stats.UpdateCounterBy("shm_write_error", int64(s.publisher.storeData(*d)))
+		s.stats.SetCounter("shm_published", int64(len(s.publisher.open)))
+	}
+	now := time.Now()
+	s.state.updateLastData(d, now)
+	// what clients see right after the write
+	wou := float64(d.ErrorBoundNS)
+	if it > 0 {
+		seconds := float64(phcTime.UnixNano()-it) / float64(time.Second)
+		wou += d.HoldoverMultiplierNS * seconds
+	}
+	s.stats.SetCounter("wou_ns", int64(wou))
+	if s.history != nil {
+		r := &HistoryRecord{
+			TimeNS:               now.UnixNano(),
+			IngressTimeNS:        d.IngressTimeNS,
+			OffsetNS:             data.MasterOffsetNS,
+			ErrorBoundNS:         d.ErrorBoundNS,
+			HoldoverMultiplierNS: d.HoldoverMultiplierNS,
+			WOUNS:                uint64(wou),
+		}
+		if err := s.history.Append(r); err != nil {
+			log.Errorf("writing history: %v", err)
+		}
+	}
 	// aggregated stats over 1 minute
 	maxDp := s.state.aggregateDataPointsMax(minRingSize(s.cfg.RingSize, s.cfg.Interval))
 	s.stats.SetCounter("master_offset_ns.60.abs_max", int64(maxDp.MasterOffsetNS))
 	s.stats.SetCounter("path_delay_ns.60.abs_max", int64(maxDp.PathDelayNS))
 	s.stats.SetCounter("freq_adj_ppb.60.abs_max", int64(maxDp.FreqAdjustmentPPB))
+	// distribution of WOU over 1 minute
+	s.state.pushWOU(wou)
+	wous := s.state.takeWOU(minRingSize(s.cfg.RingSize, s.cfg.Interval))
+	s.stats.SetCounter("wou_ns.60.p50", int64(percentile(wous, 50)))
+	s.stats.SetCounter("wou_ns.60.p99", int64(percentile(wous, 99)))
+	s.stats.SetCounter("wou_ns.60.max", int64(percentile(wous, 100)))
 	return nil
 }
 
@@ -404,6 +516,49 @@ func targetsDiff(oldTargets []string, targets []string) (added []string, removed
 	return
 }
 
+// DebugState is a snapshot of the daemon state served by /debug/state endpoint
+type DebugState struct {
+	Source        string
+	SourceAddress string
+	Math          Math
+	RingSize      int
+	DataPoints    []*DataPoint  // most recent first
+	Data          *fbclock.Data // last data written to shm
+	LastWrite     time.Time
+}
+
+// DebugState returns current state of the daemon
+func (s *Daemon) DebugState() *DebugState {
+	sptp, address := s.source()
+	source := "ptp4l"
+	if sptp {
+		source = "sptp"
+	}
+	d, lastWrite := s.state.lastDataWritten()
+	return &DebugState{
+		Source:        source,
+		SourceAddress: address,
+		Math:          s.currentMath(),
+		RingSize:      s.cfg.RingSize,
+		DataPoints:    s.state.takeDataPoint(s.cfg.RingSize),
+		Data:          d,
+		LastWrite:     lastWrite,
+	}
+}
+
+// source returns whether we get data from sptp, and PTP client address
+func (s *Daemon) source() (sptp bool, address string) {
+	fetcher := s.DataFetcher
+	if mf, ok := fetcher.(*MultiFetcher); ok {
+		fetcher = mf.DataFetcher
+	}
+	if af, ok := fetcher.(*AutoFetcher); ok {
+		return af.Source(s.cfg)
+	}
+	_, isHTTP := fetcher.(*HTTPFetcher)
+	return s.cfg.SPTP || isHTTP, s.cfg.PTPClientAddress
+}
+
 func (s *Daemon) runLinearizabilityTests(ctx context.Context) {
 	testers := map[string]linearizability.Tester{}
 	oldTargets := []string{}
@@ -441,8 +596,8 @@ func (s *Daemon) runLinearizabilityTests(ctx context.Context) {
 			log.Debugf("talking to %s", server)
 			lt, found := testers[server]
 			if !found {
-				if s.cfg.SPTP {
-					lt, err = linearizability.NewSPTPTester(server, fmt.Sprintf("http://%s/", s.cfg.PTPClientAddress), s.cfg.LinearizabilityTestMaxGMOffset)
+				if sptp, address := s.source(); sptp {
+					lt, err = linearizability.NewSPTPTester(server, fmt.Sprintf("http://%s/", address), s.cfg.LinearizabilityTestMaxGMOffset)
 				} else {
 					lt, err = linearizability.NewPTP4lTester(server, s.cfg.Iface)
 				}
@@ -483,6 +638,43 @@ func (s *Daemon) runLinearizabilityTests(ctx context.Context) {
 	}
 }
 
+// fbclockSource provides TrueTime to RPC API from fbclock shared memory
+type fbclockSource struct {
+	c *fbclock.FBClock
+}
+
+// GetTime returns TAI TrueTime
+func (f *fbclockSource) GetTime() (time.Time, time.Time, error) {
+	tt, err := f.c.GetTime()
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return tt.Earliest, tt.Latest, nil
+}
+
+// GetTimeUTC returns UTC TrueTime
+func (f *fbclockSource) GetTimeUTC() (time.Time, time.Time, error) {
+	tt, err := f.c.GetTimeUTC()
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return tt.Earliest, tt.Latest, nil
+}
+
+// runRPC serves TrueTime over unix socket, reading it from the shared memory we populate
+func (s *Daemon) runRPC(ctx context.Context) {
+	c, err := fbclock.NewFBClock()
+	if err != nil {
+		log.Errorf("not serving RPC: %v", err)
+		return
+	}
+	defer c.Close()
+	log.Infof("serving RPC on %s", s.cfg.RPCSocket)
+	if err := rpc.Serve(ctx, s.cfg.RPCSocket, rpc.NewService(&fbclockSource{c: c})); err != nil {
+		log.Errorf("serving RPC: %v", err)
+	}
+}
+
 // Run a daemon
 func (s *Daemon) Run(ctx context.Context) error {
 	shm, err := fbclock.OpenFBClockSHM()
@@ -491,19 +683,66 @@ func (s *Daemon) Run(ctx context.Context) error {
 	}
 	defer shm.Close()
 
+	if s.cfg.HistoryPath != "" {
+		s.history, err = OpenHistory(s.cfg.HistoryPath, s.cfg.HistorySize)
+		if err != nil {
+			return fmt.Errorf("opening history: %w", err)
+		}
+		defer s.history.Close()
+	}
+
+	if len(s.cfg.ShmPaths) > 0 {
+		s.publisher = newShmPublisher(s.cfg.ShmPaths)
+		defer s.publisher.Close()
+	}
+
+	if len(s.cfg.Hooks) > 0 {
+		s.hooks = newHookRunner(s.cfg.Hooks)
+		s.hooks.start(ctx)
+	}
+	started := time.Now()
+
 	if s.cfg.LinearizabilityTestInterval != 0 {
 		go s.runLinearizabilityTests(ctx)
 	}
+	if s.cfg.RPCSocket != "" {
+		go s.runRPC(ctx)
+	}
 	ticker := time.NewTicker(s.cfg.Interval)
 	defer ticker.Stop()
 	for ; true; <-ticker.C { // first run without delay, then at interval
+		// let clients know we are alive even if we fail to update the data
+		if err := fbclock.StoreFBClockHeartbeat(shm.File.Fd()); err != nil {
+			log.Errorf("storing heartbeat: %v", err)
+		}
+		if s.publisher != nil {
+			s.publisher.refresh()
+			s.publisher.storeHeartbeat()
+		}
+		if s.cfg.StepThreshold > 0 {
+			if err := s.detectStep(shm); err != nil {
+				log.Errorf("detecting clock step: %v", err)
+			}
+		}
+		if s.hooks != nil {
+			if err := s.checkHooks(started); err != nil {
+				log.Errorf("checking hooks: %v", err)
+			}
+		}
 		data, err := s.DataFetcher.FetchStats(s.cfg)
+		if sptp, _ := s.source(); sptp {
+			s.stats.SetCounter("source_sptp", 1)
+		} else {
+			s.stats.SetCounter("source_sptp", 0)
+		}
 		if err != nil {
 			log.Error(err)
 			s.stats.UpdateCounterBy("data_error", 1)
+			s.stats.SetCounter("source_up", 0)
 			continue
 		}
 		s.stats.SetCounter("data_error", 0)
+		s.stats.SetCounter("source_up", 1)
 		// get PHC freq adjustment
 		freqPPB, err := s.getPHCFreqPPB()
 		if err != nil {
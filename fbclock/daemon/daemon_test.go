@@ -18,7 +18,9 @@ package daemon
 
 import (
 	"context"
+	"math"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -254,6 +256,66 @@ func TestMathPrepare(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "coefficients",
+			in: &Math{
+				M:            "base + abs(mean(offset, 30))",
+				W:            "mean(m, 30) + k * stddev(m, 30)",
+				Drift:        "holdover * mean(freqchangeabs, 29)",
+				Coefficients: map[string]float64{"base": 10, "k": 4.0, "holdover": 1.5},
+			},
+			wantErr: false,
+		},
+		{
+			name: "undefined coefficient",
+			in: &Math{
+				M:            "base + abs(mean(offset, 30))",
+				W:            "mean(m, 30) + k * stddev(m, 30)",
+				Drift:        "1 - 0",
+				Coefficients: map[string]float64{"base": 10},
+			},
+			wantErr: true,
+		},
+		{
+			name: "coefficient shadows variable",
+			in: &Math{
+				M:            "1 + 3",
+				W:            "4 / 1",
+				Drift:        "1 - 0",
+				Coefficients: map[string]float64{"offset": 10},
+			},
+			wantErr: true,
+		},
+		{
+			name: "coefficient shadows function",
+			in: &Math{
+				M:            "1 + 3",
+				W:            "4 / 1",
+				Drift:        "1 - 0",
+				Coefficients: map[string]float64{"mean": 10},
+			},
+			wantErr: true,
+		},
+		{
+			name: "bad coefficient name",
+			in: &Math{
+				M:            "1 + 3",
+				W:            "4 / 1",
+				Drift:        "1 - 0",
+				Coefficients: map[string]float64{"Bad-Name": 10},
+			},
+			wantErr: true,
+		},
+		{
+			name: "infinite coefficient",
+			in: &Math{
+				M:            "1 + 3",
+				W:            "4 / 1",
+				Drift:        "1 - 0",
+				Coefficients: map[string]float64{"k": math.Inf(1)},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -600,6 +662,9 @@ func TestDaemonDoWork(t *testing.T) {
 		ClockAccuracyNS:   25.0,
 	}
 	phcTime = startTime + 62*time.Second
+	historyPath := filepath.Join(t.TempDir(), "history")
+	s.history, err = OpenHistory(historyPath, 10)
+	require.NoError(t, err)
 
 	err = s.doWork(shm, d)
 	require.NoError(t, err)
@@ -616,6 +681,31 @@ func TestDaemonDoWork(t *testing.T) {
 	require.Equal(t, int64(213), c["path_delay_ns.60.abs_max"], "path_delay_ns.60.abs_max after good data")
 	require.Equal(t, int64(212159), c["freq_adj_ppb.60.abs_max"], "freq_adj_ppb.60.abs_max after good data")
 	require.Equal(t, int64(0), c["data_sanity_check_error"])
+	require.Greater(t, c["shm_write"], int64(0))
+	require.Equal(t, int64(0), c["shm_write_error"])
+	// W plus 1 second of holdover
+	require.Equal(t, int64(112), c["wou_ns"], "wou_ns after good data")
+	// WOU published so far: 48 before holdover and 112 now
+	require.Equal(t, int64(48), c["wou_ns.60.p50"])
+	require.Equal(t, int64(112), c["wou_ns.60.p99"])
+	require.Equal(t, int64(112), c["wou_ns.60.max"])
+
+	require.NoError(t, s.history.Close())
+	records, err := ReadHistory(historyPath)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, d.IngressTimeNS, records[0].IngressTimeNS)
+	require.Equal(t, d.MasterOffsetNS, records[0].OffsetNS)
+	require.Equal(t, uint64(48), records[0].ErrorBoundNS)
+	require.Equal(t, uint64(112), records[0].WOUNS)
+	s.history = nil
+
+	state := s.DebugState()
+	require.Equal(t, "ptp4l", state.Source)
+	require.Equal(t, d, state.DataPoints[0])
+	require.Len(t, state.DataPoints, cfg.RingSize)
+	require.Equal(t, d.IngressTimeNS, state.Data.IngressTimeNS)
+	require.False(t, state.LastWrite.IsZero())
 
 	// check that we wrote data correctly
 	want := &fbclock.Data{
@@ -725,3 +815,59 @@ func TestNoPHC(t *testing.T) {
 	err := s.doWork(&fbclock.Shm{}, &DataPoint{})
 	require.ErrorIs(t, err, errNoPHC)
 }
+
+func TestDaemonReloadConfig(t *testing.T) {
+	cfg := &Config{
+		RingSize: 30,
+		Math: Math{
+			M:     "mean(clockaccuracy, 30) + abs(mean(offset, 30)) + 1.0 * stddev(offset, 30)",
+			W:     "mean(m, 30) + 4.0 * stddev(m, 30)",
+			Drift: "1.5 * mean(freqchangeabs, 29)",
+		},
+	}
+	require.NoError(t, cfg.Math.Prepare())
+	s := newTestDaemon(cfg, stats.NewStats())
+
+	f, err := os.CreateTemp("", "fbclock_config")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`ptpclientaddress: /var/run/ptp4l
+ringsize: 30
+interval: 1s
+math:
+  m: mean(clockaccuracy, 30) + abs(mean(offset, 30))
+  w: mean(m, 30) + k * stddev(m, 30)
+  drift: holdover * mean(freqchangeabs, 29)
+  coefficients:
+    k: 6
+    holdover: 2.5
+`)
+	require.NoError(t, err)
+	require.NoError(t, s.ReloadConfig(f.Name()))
+	m := s.currentMath()
+	require.Equal(t, "holdover * mean(freqchangeabs, 29)", m.Drift)
+	require.Equal(t, map[string]float64{"k": 6, "holdover": 2.5}, m.Coefficients)
+
+	for i := 0; i < 31; i++ {
+		s.state.pushDataPoint(&DataPoint{FreqAdjustmentPPB: float64(i % 2)})
+	}
+	drift, err := s.calcDriftPPB()
+	require.NoError(t, err)
+	require.InDelta(t, 2.5, drift, 0.0001)
+
+	// invalid config is not applied
+	require.NoError(t, f.Truncate(0))
+	_, err = f.WriteAt([]byte(`ptpclientaddress: /var/run/ptp4l
+ringsize: 30
+interval: 1s
+math:
+  m: "1"
+  w: "1"
+  drift: missing * 2
+`), 0)
+	require.NoError(t, err)
+	require.Error(t, s.ReloadConfig(f.Name()))
+	require.Equal(t, m.Drift, s.currentMath().Drift)
+
+	require.Error(t, s.ReloadConfig("/does/not/exist"))
+}
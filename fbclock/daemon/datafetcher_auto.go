@@ -0,0 +1,102 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AutoFetcher provides data fetcher implementation which picks PTP client automatically.
+// sptp monitoring API at Config.SPTPAddress is preferred, ptp4l management socket at Config.PTPClientAddress is used otherwise.
+// Once a source works, it's used until it fails.
+type AutoFetcher struct {
+	DataFetcher
+	sptp  HTTPFetcher
+	ptp4l SockFetcher
+
+	sync.Mutex
+	useSPTP  bool
+	selected bool
+}
+
+// Source returns whether currently selected source is sptp, and its address
+func (af *AutoFetcher) Source(cfg *Config) (sptp bool, address string) {
+	af.Lock()
+	defer af.Unlock()
+	if af.useSPTP {
+		return true, cfg.SPTPAddress
+	}
+	return false, cfg.PTPClientAddress
+}
+
+// candidates returns sources to try, in order
+func (af *AutoFetcher) candidates() []bool {
+	af.Lock()
+	defer af.Unlock()
+	if !af.selected {
+		return []bool{true, false}
+	}
+	return []bool{af.useSPTP, !af.useSPTP}
+}
+
+// use remembers the source which worked
+func (af *AutoFetcher) use(sptp bool) {
+	af.Lock()
+	defer af.Unlock()
+	if af.selected && af.useSPTP == sptp {
+		return
+	}
+	if sptp {
+		log.Infof("using sptp as data source")
+	} else {
+		log.Infof("using ptp4l as data source")
+	}
+	af.useSPTP = sptp
+	af.selected = true
+}
+
+// FetchGMs fetches GMs from currently selected source
+func (af *AutoFetcher) FetchGMs(cfg *Config) (targets []string, err error) {
+	if sptp, address := af.Source(cfg); sptp {
+		return af.sptp.fetchGMs(address)
+	}
+	return af.ptp4l.fetchGMs(cfg.PTPClientAddress, cfg.Interval/2)
+}
+
+// FetchStats fetches stats from the first source that works
+func (af *AutoFetcher) FetchStats(cfg *Config) (*DataPoint, error) {
+	var sptpErr, ptp4lErr error
+	for _, sptp := range af.candidates() {
+		var data *DataPoint
+		var err error
+		if sptp {
+			data, err = af.sptp.fetchStats(cfg.SPTPAddress)
+			sptpErr = err
+		} else {
+			data, err = af.ptp4l.fetchStats(cfg.PTPClientAddress, cfg.Interval/2)
+			ptp4lErr = err
+		}
+		if err == nil {
+			af.use(sptp)
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("no working data source: sptp: %w, ptp4l: %w", sptpErr, ptp4lErr)
+}
@@ -0,0 +1,172 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/facebook/time/ntp/chrony"
+	log "github.com/sirupsen/logrus"
+)
+
+// leapStatusUnsynchronised is the chronyd leap status when the clock is not synchronised
+const leapStatusUnsynchronised = 3
+
+// ChronyFetcher provides data fetcher implementation using chronyd monitoring protocol.
+// PTPClientAddress is either a path to chronyd unix socket or host:port of its command port.
+type ChronyFetcher struct {
+	DataFetcher
+}
+
+// FetchGMs fetches NTP sources from chronyd
+func (cf *ChronyFetcher) FetchGMs(cfg *Config) (targets []string, err error) {
+	return cf.fetchGMs(cfg.PTPClientAddress, cfg.Interval/2)
+}
+
+func (cf *ChronyFetcher) fetchGMs(address string, timeout time.Duration) ([]string, error) {
+	client, closer, err := chronyClient(address, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to chronyd: %w", err)
+	}
+	defer closer()
+
+	p, err := client.Communicate(chrony.NewSourcesPacket())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sources: %w", err)
+	}
+	sources, ok := p.(*chrony.ReplySources)
+	if !ok {
+		return nil, fmt.Errorf("unexpected reply to sources request: %T", p)
+	}
+	data := make([]*chrony.SourceData, 0, sources.NSources)
+	for i := 0; i < sources.NSources; i++ {
+		p, err := client.Communicate(chrony.NewSourceDataPacket(int32(i))) //#nosec G115
+		if err != nil {
+			return nil, fmt.Errorf("failed to get source data for source %d: %w", i, err)
+		}
+		sd, ok := p.(*chrony.ReplySourceData)
+		if !ok {
+			return nil, fmt.Errorf("unexpected reply to source data request: %T", p)
+		}
+		data = append(data, &sd.SourceData)
+	}
+	return chronyGMs(data), nil
+}
+
+// chronyGMs returns addresses of usable NTP sources other than the selected one
+func chronyGMs(sources []*chrony.SourceData) []string {
+	targets := []string{}
+	for _, s := range sources {
+		// skip the current sync source
+		if s.State == chrony.SourceStateSync {
+			continue
+		}
+		// skip reference clocks, they don't have an address
+		if s.Mode == chrony.SourceModeRef {
+			continue
+		}
+		// skip sources we didn't get a response from
+		if s.State == chrony.SourceStateUnreach || s.Reachability == 0 {
+			continue
+		}
+		targets = append(targets, s.IPAddr.String())
+	}
+	return targets
+}
+
+// FetchStats fetches tracking data from chronyd
+func (cf *ChronyFetcher) FetchStats(cfg *Config) (*DataPoint, error) {
+	return cf.fetchStats(cfg.PTPClientAddress, cfg.Interval/2)
+}
+
+func (cf *ChronyFetcher) fetchStats(address string, timeout time.Duration) (*DataPoint, error) {
+	client, closer, err := chronyClient(address, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to chronyd: %w", err)
+	}
+	defer closer()
+
+	p, err := client.Communicate(chrony.NewTrackingPacket())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracking: %w", err)
+	}
+	tracking, ok := p.(*chrony.ReplyTracking)
+	if !ok {
+		return nil, fmt.Errorf("unexpected reply to tracking request: %T", p)
+	}
+	log.Debugf("tracking: %+v", tracking.Tracking)
+	return dataPointFromTracking(&tracking.Tracking)
+}
+
+// dataPointFromTracking converts chronyd tracking, reported in seconds, into DataPoint.
+// Root distance is used as clock accuracy, as it's the bound of the error against the reference.
+func dataPointFromTracking(t *chrony.Tracking) (*DataPoint, error) {
+	if t.LeapStatus == leapStatusUnsynchronised {
+		return nil, fmt.Errorf("chronyd is not synchronised")
+	}
+	return &DataPoint{
+		IngressTimeNS:   t.RefTime.UnixNano(),
+		MasterOffsetNS:  t.LastOffset * 1e9,
+		PathDelayNS:     t.RootDelay / 2 * 1e9,
+		ClockAccuracyNS: (t.RootDelay/2 + t.RootDispersion) * 1e9,
+	}, nil
+}
+
+// chronyClient connects to chronyd at given address and returns client and function to clean up after it
+func chronyClient(address string, timeout time.Duration) (*chrony.Client, func(), error) {
+	var conn net.Conn
+	cleanup := func() {}
+	if strings.HasPrefix(address, "/") {
+		base, _ := path.Split(address)
+		local := path.Join(base, fmt.Sprintf("fbclock.%d.chrony.sock", os.Getpid()))
+		uconn, err := net.DialUnix("unixgram",
+			&net.UnixAddr{Name: local, Net: "unixgram"},
+			&net.UnixAddr{Name: address, Net: "unixgram"},
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		conn = uconn
+		// make sure there is no leftover socket
+		cleanup = func() {
+			conn.Close()
+			os.RemoveAll(local)
+		}
+		// chronyd runs as a different user and needs to be able to reply
+		if err := os.Chmod(local, 0666); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	} else {
+		var err error
+		conn, err = net.DialTimeout("udp", address, timeout)
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanup = func() { conn.Close() }
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return &chrony.Client{Connection: conn}, cleanup, nil
+}
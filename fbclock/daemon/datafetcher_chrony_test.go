@@ -0,0 +1,127 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/facebook/time/ntp/chrony"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeChronyMonitor struct {
+	sync.Mutex
+	tracking *chrony.Tracking
+}
+
+func (m *fakeChronyMonitor) setTracking(tracking *chrony.Tracking) {
+	m.Lock()
+	defer m.Unlock()
+	m.tracking = tracking
+}
+
+func (m *fakeChronyMonitor) Tracking() (*chrony.Tracking, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.tracking == nil {
+		return nil, fmt.Errorf("no tracking")
+	}
+	tracking := *m.tracking
+	return &tracking, nil
+}
+
+func (m *fakeChronyMonitor) ServerStats() (*chrony.ServerStats4, error) {
+	return &chrony.ServerStats4{}, nil
+}
+
+func startFakeChronyd(t *testing.T, m chrony.Monitor) string {
+	address := filepath.Join(t.TempDir(), "chronyd.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: address, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	s := &chrony.Server{Monitor: m}
+	go func() { _ = s.Serve(conn) }()
+	return address
+}
+
+func TestChronyFetcherFetchStats(t *testing.T) {
+	tracking := &chrony.Tracking{
+		Stratum:        2,
+		RefTime:        time.Unix(1647359186, 979431900),
+		LastOffset:     -0.000000123,
+		RootDelay:      0.0002,
+		RootDispersion: 0.00001,
+	}
+	m := &fakeChronyMonitor{tracking: tracking}
+	address := startFakeChronyd(t, m)
+
+	cf := &ChronyFetcher{}
+	d, err := cf.FetchStats(&Config{PTPClientAddress: address, Interval: 2 * time.Second})
+	require.NoError(t, err)
+	require.Equal(t, int64(1647359186979431900), d.IngressTimeNS)
+	require.InDelta(t, -123.0, d.MasterOffsetNS, 0.01)
+	require.InDelta(t, 100000.0, d.PathDelayNS, 0.1)
+	require.InDelta(t, 110000.0, d.ClockAccuracyNS, 0.1)
+
+	unsynced := *tracking
+	unsynced.LeapStatus = leapStatusUnsynchronised
+	m.setTracking(&unsynced)
+	_, err = cf.FetchStats(&Config{PTPClientAddress: address, Interval: 2 * time.Second})
+	require.ErrorContains(t, err, "chronyd is not synchronised")
+
+	m.setTracking(nil)
+	_, err = cf.FetchStats(&Config{PTPClientAddress: address, Interval: 2 * time.Second})
+	require.ErrorContains(t, err, "failed to get tracking")
+}
+
+func TestChronyFetcherFetchGMs(t *testing.T) {
+	address := startFakeChronyd(t, &fakeChronyMonitor{})
+
+	cf := &ChronyFetcher{}
+	gms, err := cf.FetchGMs(&Config{PTPClientAddress: address, Interval: 2 * time.Second})
+	require.NoError(t, err)
+	require.Empty(t, gms)
+}
+
+func TestChronyFetcherNoChronyd(t *testing.T) {
+	cf := &ChronyFetcher{}
+	_, err := cf.FetchStats(&Config{PTPClientAddress: filepath.Join(t.TempDir(), "chronyd.sock"), Interval: 2 * time.Second})
+	require.ErrorContains(t, err, "failed to connect to chronyd")
+}
+
+func TestChronyGMs(t *testing.T) {
+	sources := []*chrony.SourceData{
+		{IPAddr: net.ParseIP("2401:db00::1"), State: chrony.SourceStateSync, Mode: chrony.SourceModeClient, Reachability: 255},
+		{IPAddr: net.ParseIP("2401:db00::2"), State: chrony.SourceStateCandidate, Mode: chrony.SourceModeClient, Reachability: 255},
+		{IPAddr: net.ParseIP("2401:db00::3"), State: chrony.SourceStateUnreach, Mode: chrony.SourceModeClient, Reachability: 0},
+		{IPAddr: net.ParseIP("2401:db00::4"), State: chrony.SourceStateCandidate, Mode: chrony.SourceModeClient, Reachability: 0},
+		{IPAddr: net.ParseIP("80.80.80.80"), State: chrony.SourceStateCandidate, Mode: chrony.SourceModeRef, Reachability: 255},
+		{IPAddr: net.ParseIP("192.168.0.1"), State: chrony.SourceStateOutlier, Mode: chrony.SourceModePeer, Reachability: 1},
+	}
+	require.Equal(t, []string{"2401:db00::2", "192.168.0.1"}, chronyGMs(sources))
+}
+
+func TestNewDataFetcherChrony(t *testing.T) {
+	f, err := newDataFetcher(&Config{DataSource: DataSourceChrony})
+	require.NoError(t, err)
+	require.IsType(t, &ChronyFetcher{}, f)
+}
@@ -31,7 +31,11 @@ type HTTPFetcher struct {
 
 // FetchGMs fetches GMs via http
 func (hf *HTTPFetcher) FetchGMs(cfg *Config) (targets []string, err error) {
-	url := fmt.Sprintf("http://%s/", cfg.PTPClientAddress)
+	return hf.fetchGMs(cfg.PTPClientAddress)
+}
+
+func (hf *HTTPFetcher) fetchGMs(address string) (targets []string, err error) {
+	url := fmt.Sprintf("http://%s/", address)
 	sm, err := stats.FetchStats(url)
 	if err != nil {
 		return nil, err
@@ -53,7 +57,11 @@ func (hf *HTTPFetcher) FetchGMs(cfg *Config) (targets []string, err error) {
 
 // FetchStats fetches GMs via http
 func (hf *HTTPFetcher) FetchStats(cfg *Config) (*DataPoint, error) {
-	url := fmt.Sprintf("http://%s/", cfg.PTPClientAddress)
+	return hf.fetchStats(cfg.PTPClientAddress)
+}
+
+func (hf *HTTPFetcher) fetchStats(address string) (*DataPoint, error) {
+	url := fmt.Sprintf("http://%s/", address)
 	sm, err := stats.FetchStats(url)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,97 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DataSourceFactory creates DataFetcher from config
+type DataSourceFactory func(cfg *Config) (DataFetcher, error)
+
+// names of built-in data sources
+const (
+	DataSourcePTP4l  = "ptp4l"
+	DataSourceSPTP   = "sptp"
+	DataSourceAuto   = "auto"
+	DataSourceStatic = "static"
+	DataSourceChrony = "chrony"
+)
+
+var (
+	dataSourcesLock sync.RWMutex
+	dataSources     = map[string]DataSourceFactory{
+		DataSourcePTP4l:  func(_ *Config) (DataFetcher, error) { return &SockFetcher{}, nil },
+		DataSourceSPTP:   func(_ *Config) (DataFetcher, error) { return &HTTPFetcher{}, nil },
+		DataSourceAuto:   func(_ *Config) (DataFetcher, error) { return &AutoFetcher{}, nil },
+		DataSourceStatic: newStaticFetcher,
+		DataSourceChrony: func(_ *Config) (DataFetcher, error) { return &ChronyFetcher{}, nil },
+	}
+)
+
+// RegisterDataSource makes data source available under given name, to be selected with Config.DataSource.
+// It allows site-specific sync daemons to feed fbclock without patching the daemon.
+// Registering the same name twice panics.
+func RegisterDataSource(name string, factory DataSourceFactory) {
+	dataSourcesLock.Lock()
+	defer dataSourcesLock.Unlock()
+	if factory == nil {
+		panic("fbclock: RegisterDataSource factory is nil")
+	}
+	if _, dup := dataSources[name]; dup {
+		panic("fbclock: RegisterDataSource called twice for " + name)
+	}
+	dataSources[name] = factory
+}
+
+// DataSources returns sorted names of registered data sources
+func DataSources() []string {
+	dataSourcesLock.RLock()
+	defer dataSourcesLock.RUnlock()
+	names := make([]string, 0, len(dataSources))
+	for name := range dataSources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newDataFetcher creates DataFetcher selected by config
+func newDataFetcher(cfg *Config) (DataFetcher, error) {
+	name := cfg.DataSource
+	if name == "" {
+		// legacy flags
+		switch {
+		case cfg.AutoSource:
+			name = DataSourceAuto
+		case cfg.SPTP:
+			name = DataSourceSPTP
+		default:
+			name = DataSourcePTP4l
+		}
+	}
+	dataSourcesLock.RLock()
+	factory, ok := dataSources[name]
+	dataSourcesLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown data source %q, available: %s", name, strings.Join(DataSources(), ", "))
+	}
+	return factory(cfg)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testFetcher struct {
+	DataFetcher
+}
+
+func TestRegisterDataSource(t *testing.T) {
+	require.Equal(t, []string{"auto", "chrony", "ptp4l", "sptp", "static"}, DataSources())
+
+	RegisterDataSource("test", func(_ *Config) (DataFetcher, error) { return &testFetcher{}, nil })
+	defer func() {
+		dataSourcesLock.Lock()
+		delete(dataSources, "test")
+		dataSourcesLock.Unlock()
+	}()
+	require.Contains(t, DataSources(), "test")
+	require.Panics(t, func() {
+		RegisterDataSource("test", func(_ *Config) (DataFetcher, error) { return &testFetcher{}, nil })
+	})
+	require.Panics(t, func() { RegisterDataSource("other", nil) })
+
+	f, err := newDataFetcher(&Config{DataSource: "test"})
+	require.NoError(t, err)
+	require.IsType(t, &testFetcher{}, f)
+
+	_, err = newDataFetcher(&Config{DataSource: "nope"})
+	require.ErrorContains(t, err, "unknown data source \"nope\", available: auto, chrony, ptp4l, sptp, static, test")
+}
+
+func TestNewDataFetcherLegacy(t *testing.T) {
+	f, err := newDataFetcher(&Config{})
+	require.NoError(t, err)
+	require.IsType(t, &SockFetcher{}, f)
+
+	f, err = newDataFetcher(&Config{SPTP: true})
+	require.NoError(t, err)
+	require.IsType(t, &HTTPFetcher{}, f)
+
+	f, err = newDataFetcher(&Config{SPTP: true, AutoSource: true})
+	require.NoError(t, err)
+	require.IsType(t, &AutoFetcher{}, f)
+
+	// explicit data source wins
+	f, err = newDataFetcher(&Config{SPTP: true, DataSource: DataSourcePTP4l})
+	require.NoError(t, err)
+	require.IsType(t, &SockFetcher{}, f)
+}
+
+func TestStaticFetcher(t *testing.T) {
+	_, err := newDataFetcher(&Config{DataSource: DataSourceStatic})
+	require.Error(t, err)
+
+	cfg := &Config{DataSource: DataSourceStatic, StaticData: &DataPoint{MasterOffsetNS: 3, PathDelayNS: 200, ClockAccuracyNS: 25}}
+	f, err := newDataFetcher(cfg)
+	require.NoError(t, err)
+	sf := f.(*StaticFetcher)
+	sf.now = func() time.Time { return time.Unix(0, 1647359186979431900) }
+
+	d, err := sf.FetchStats(cfg)
+	require.NoError(t, err)
+	require.Equal(t, &DataPoint{IngressTimeNS: 1647359186979431900, MasterOffsetNS: 3, PathDelayNS: 200, ClockAccuracyNS: 25}, d)
+	// config is not modified
+	require.Equal(t, int64(0), cfg.StaticData.IngressTimeNS)
+
+	sf.Data.IngressTimeNS = 42
+	d, err = sf.FetchStats(cfg)
+	require.NoError(t, err)
+	require.Equal(t, int64(42), d.IngressTimeNS)
+
+	gms, err := sf.FetchGMs(cfg)
+	require.NoError(t, err)
+	require.Empty(t, gms)
+}
@@ -34,9 +34,12 @@ type SockFetcher struct {
 
 // FetchGMs fetches gm data from ptp4l socket
 func (sf *SockFetcher) FetchGMs(cfg *Config) (targets []string, err error) {
+	return sf.fetchGMs(cfg.PTPClientAddress, cfg.Interval/2)
+}
+
+func (sf *SockFetcher) fetchGMs(address string, timeout time.Duration) (targets []string, err error) {
 	local := filepath.Join("/var/run/", fmt.Sprintf("fbclock.%d.linear.sock", os.Getpid()))
-	timeout := cfg.Interval / 2
-	conn, err := connect(cfg.PTPClientAddress, local, timeout)
+	conn, err := connect(address, local, timeout)
 	defer func() {
 		if conn != nil {
 			conn.Close()
@@ -76,9 +79,12 @@ func (sf *SockFetcher) FetchGMs(cfg *Config) (targets []string, err error) {
 
 // FetchStats fetches stats from ptp4l socket
 func (sf *SockFetcher) FetchStats(cfg *Config) (*DataPoint, error) {
+	return sf.fetchStats(cfg.PTPClientAddress, cfg.Interval/2)
+}
+
+func (sf *SockFetcher) fetchStats(address string, timeout time.Duration) (*DataPoint, error) {
 	local := filepath.Join("/var/run/", fmt.Sprintf("fbclock.%d.sock", os.Getpid()))
-	timeout := cfg.Interval / 2
-	conn, err := connect(cfg.PTPClientAddress, local, timeout)
+	conn, err := connect(address, local, timeout)
 	defer func() {
 		if conn != nil {
 			conn.Close()
@@ -0,0 +1,54 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"fmt"
+	"time"
+)
+
+// StaticFetcher provides data fetcher implementation which always returns the same data.
+// It's meant for tests and setups without PTP client.
+type StaticFetcher struct {
+	DataFetcher
+	Data DataPoint
+	GMs  []string
+	// now returns current time, used as ingress time unless Data has it
+	now func() time.Time
+}
+
+func newStaticFetcher(cfg *Config) (DataFetcher, error) {
+	if cfg.StaticData == nil {
+		return nil, fmt.Errorf("'staticdata' is required with %q data source", DataSourceStatic)
+	}
+	return &StaticFetcher{Data: *cfg.StaticData, now: time.Now}, nil
+}
+
+// FetchGMs returns configured GMs
+func (sf *StaticFetcher) FetchGMs(_ *Config) ([]string, error) {
+	return sf.GMs, nil
+}
+
+// FetchStats returns a copy of configured data.
+// Zero ingress time is replaced with current system time, as if sync message was just received.
+func (sf *StaticFetcher) FetchStats(_ *Config) (*DataPoint, error) {
+	d := sf.Data
+	if d.IngressTimeNS == 0 {
+		d.IngressTimeNS = sf.now().UnixNano()
+	}
+	return &d, nil
+}
@@ -0,0 +1,242 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// historyMagic identifies fbclock history files
+var historyMagic = [4]byte{'F', 'B', 'C', 'H'}
+
+const historyVersion uint32 = 1
+
+// ErrNoHistory is returned when there is no history record for requested time
+var ErrNoHistory = errors.New("no history record")
+
+// HistoryRecord is what the daemon believed about the clock at a given time
+type HistoryRecord struct {
+	TimeNS               int64   // system time of the record
+	IngressTimeNS        int64   // PHC time of the last sync message
+	OffsetNS             float64 // offset from GM
+	ErrorBoundNS         uint64  // W, error bound before holdover adjustment
+	HoldoverMultiplierNS float64 // drift, in ns per second of holdover
+	WOUNS                uint64  // window of uncertainty at TimeNS
+}
+
+// Time returns time of the record
+func (r *HistoryRecord) Time() time.Time {
+	return time.Unix(0, r.TimeNS)
+}
+
+// historyHeader starts every history file
+type historyHeader struct {
+	Magic    [4]byte
+	Version  uint32
+	Capacity uint64 // max number of records
+	Next     uint64 // index of the slot to write next
+	Count    uint64 // number of records written, up to Capacity
+}
+
+var (
+	historyHeaderSize = int64(binary.Size(historyHeader{}))
+	historyRecordSize = int64(binary.Size(HistoryRecord{}))
+)
+
+// History is an append-only ring of HistoryRecord stored in a file.
+// Once the ring is full, the oldest records are overwritten.
+type History struct {
+	f      *os.File
+	header historyHeader
+}
+
+// OpenHistory opens history file at path, creating it with given capacity if it doesn't exist
+func OpenHistory(path string, capacity int) (*History, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("history capacity must be >0, got %d", capacity)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	h := &History{f: f}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if st.Size() == 0 {
+		h.header = historyHeader{Magic: historyMagic, Version: historyVersion, Capacity: uint64(capacity)}
+		if err := h.writeHeader(); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return h, nil
+	}
+	if err := h.readHeader(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if h.header.Capacity != uint64(capacity) {
+		f.Close()
+		return nil, fmt.Errorf("history %s has capacity %d, want %d", path, h.header.Capacity, capacity)
+	}
+	return h, nil
+}
+
+// ReadHistory returns all records from history file at path, oldest first
+func ReadHistory(path string) ([]HistoryRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := &History{f: f}
+	if err := h.readHeader(); err != nil {
+		return nil, err
+	}
+	return h.Records()
+}
+
+func (h *History) readHeader() error {
+	b := make([]byte, historyHeaderSize)
+	if _, err := h.f.ReadAt(b, 0); err != nil {
+		return fmt.Errorf("reading history header: %w", err)
+	}
+	if err := binary.Read(bytes.NewReader(b), binary.LittleEndian, &h.header); err != nil {
+		return err
+	}
+	if h.header.Magic != historyMagic {
+		return fmt.Errorf("not a history file")
+	}
+	if h.header.Version != historyVersion {
+		return fmt.Errorf("unsupported history version %d", h.header.Version)
+	}
+	if h.header.Capacity == 0 || h.header.Next >= h.header.Capacity || h.header.Count > h.header.Capacity {
+		return fmt.Errorf("corrupted history header: %+v", h.header)
+	}
+	return nil
+}
+
+func (h *History) writeHeader() error {
+	var b bytes.Buffer
+	if err := binary.Write(&b, binary.LittleEndian, &h.header); err != nil {
+		return err
+	}
+	_, err := h.f.WriteAt(b.Bytes(), 0)
+	return err
+}
+
+// Append adds a record, overwriting the oldest one if history is full
+func (h *History) Append(r *HistoryRecord) error {
+	var b bytes.Buffer
+	if err := binary.Write(&b, binary.LittleEndian, r); err != nil {
+		return err
+	}
+	if _, err := h.f.WriteAt(b.Bytes(), historyHeaderSize+int64(h.header.Next)*historyRecordSize); err != nil {
+		return err
+	}
+	h.header.Next = (h.header.Next + 1) % h.header.Capacity
+	if h.header.Count < h.header.Capacity {
+		h.header.Count++
+	}
+	return h.writeHeader()
+}
+
+// Records returns all records, oldest first
+func (h *History) Records() ([]HistoryRecord, error) {
+	first := uint64(0)
+	if h.header.Count == h.header.Capacity {
+		first = h.header.Next
+	}
+	b := make([]byte, int64(h.header.Capacity)*historyRecordSize)
+	n, err := h.f.ReadAt(b, historyHeaderSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if int64(n) < int64(h.header.Count)*historyRecordSize {
+		return nil, fmt.Errorf("history is truncated: %d records expected", h.header.Count)
+	}
+	records := make([]HistoryRecord, h.header.Count)
+	for i := range records {
+		slot := int64((first + uint64(i)) % h.header.Capacity)
+		r := bytes.NewReader(b[slot*historyRecordSize : (slot+1)*historyRecordSize])
+		if err := binary.Read(r, binary.LittleEndian, &records[i]); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// Close closes history file
+func (h *History) Close() error {
+	return h.f.Close()
+}
+
+// RecordAt returns the latest record made at or before t, from records sorted oldest first
+func RecordAt(records []HistoryRecord, t time.Time) (*HistoryRecord, error) {
+	i := sort.Search(len(records), func(i int) bool { return records[i].TimeNS > t.UnixNano() })
+	if i == 0 {
+		return nil, fmt.Errorf("%w at %v", ErrNoHistory, t)
+	}
+	return &records[i-1], nil
+}
+
+var historyCSVHeader = []string{
+	"time",
+	"ingress_time_ns",
+	"offset_ns",
+	"error_bound_ns",
+	"holdover_multiplier_ns",
+	"wou_ns",
+}
+
+// CSVRecords returns all data from this record as CSV. Must by synced with `historyCSVHeader` variable.
+func (r *HistoryRecord) CSVRecords() []string {
+	return []string{
+		r.Time().UTC().Format(time.RFC3339Nano),
+		strconv.FormatInt(r.IngressTimeNS, 10),
+		strconv.FormatFloat(r.OffsetNS, 'f', -1, 64),
+		strconv.FormatUint(r.ErrorBoundNS, 10),
+		strconv.FormatFloat(r.HoldoverMultiplierNS, 'f', -1, 64),
+		strconv.FormatUint(r.WOUNS, 10),
+	}
+}
+
+// ExportHistoryCSV writes records as CSV with a header
+func ExportHistoryCSV(w io.Writer, records []HistoryRecord) error {
+	csvwriter := csv.NewWriter(w)
+	if err := csvwriter.Write(historyCSVHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := csvwriter.Write(r.CSVRecords()); err != nil {
+			return err
+		}
+	}
+	csvwriter.Flush()
+	return csvwriter.Error()
+}
@@ -0,0 +1,107 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	h, err := OpenHistory(path, 3)
+	require.NoError(t, err)
+
+	records, err := h.Records()
+	require.NoError(t, err)
+	require.Empty(t, records)
+
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, h.Append(&HistoryRecord{TimeNS: int64(i) * int64(time.Second), OffsetNS: float64(-i), ErrorBoundNS: uint64(i * 100), WOUNS: uint64(i * 101)}))
+	}
+	require.NoError(t, h.Close())
+
+	// oldest record was overwritten
+	records, err = ReadHistory(path)
+	require.NoError(t, err)
+	require.Equal(t, []HistoryRecord{
+		{TimeNS: 2 * int64(time.Second), OffsetNS: -2, ErrorBoundNS: 200, WOUNS: 202},
+		{TimeNS: 3 * int64(time.Second), OffsetNS: -3, ErrorBoundNS: 300, WOUNS: 303},
+		{TimeNS: 4 * int64(time.Second), OffsetNS: -4, ErrorBoundNS: 400, WOUNS: 404},
+	}, records)
+
+	// reopen and keep appending
+	h, err = OpenHistory(path, 3)
+	require.NoError(t, err)
+	require.NoError(t, h.Append(&HistoryRecord{TimeNS: 5 * int64(time.Second)}))
+	records, err = h.Records()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, 3*int64(time.Second), records[0].TimeNS)
+	require.Equal(t, 5*int64(time.Second), records[2].TimeNS)
+	require.NoError(t, h.Close())
+
+	_, err = OpenHistory(path, 10)
+	require.ErrorContains(t, err, "has capacity 3")
+	_, err = OpenHistory(path, 0)
+	require.Error(t, err)
+}
+
+func TestHistoryCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	require.NoError(t, os.WriteFile(path, []byte("definitely not a history file, long enough"), 0644))
+	_, err := ReadHistory(path)
+	require.ErrorContains(t, err, "not a history file")
+	_, err = OpenHistory(path, 3)
+	require.ErrorContains(t, err, "not a history file")
+}
+
+func TestRecordAt(t *testing.T) {
+	records := []HistoryRecord{
+		{TimeNS: 10, WOUNS: 1},
+		{TimeNS: 20, WOUNS: 2},
+		{TimeNS: 30, WOUNS: 3},
+	}
+	_, err := RecordAt(records, time.Unix(0, 9))
+	require.ErrorIs(t, err, ErrNoHistory)
+	r, err := RecordAt(records, time.Unix(0, 10))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), r.WOUNS)
+	r, err = RecordAt(records, time.Unix(0, 29))
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), r.WOUNS)
+	r, err = RecordAt(records, time.Unix(0, 100))
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), r.WOUNS)
+}
+
+func TestExportHistoryCSV(t *testing.T) {
+	var b strings.Builder
+	err := ExportHistoryCSV(&b, []HistoryRecord{
+		{TimeNS: 1700000000123456789, IngressTimeNS: 1700000037000000000, OffsetNS: -2.5, ErrorBoundNS: 48, HoldoverMultiplierNS: 64.5, WOUNS: 112},
+	})
+	require.NoError(t, err)
+	want := `time,ingress_time_ns,offset_ns,error_bound_ns,holdover_multiplier_ns,wou_ns
+2023-11-14T22:13:20.123456789Z,1700000037000000000,-2.5,48,64.5,112
+`
+	require.Equal(t, want, b.String())
+}
@@ -0,0 +1,236 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultHookTimeout limits how long hook can run if Hook.Timeout is not set
+const defaultHookTimeout = 5 * time.Second
+
+// Health is what hooks are triggered on
+type Health string
+
+// Health values
+const (
+	HealthOK       Health = "ok"       // WOU is below threshold
+	HealthDegraded Health = "degraded" // WOU is above threshold
+	HealthStale    Health = "stale"    // daemon didn't update shm for too long
+)
+
+// Hook is run every time health changes, so the host can fence itself from WOU-sensitive workloads
+type Hook struct {
+	Name       string
+	MaxWOU     time.Duration // health is degraded when WOU exceeds it, 0 disables the check
+	StaleAfter time.Duration // health is stale when shm wasn't updated for this long, 0 disables the check
+	Exec       string        // shell command to run, with FBCLOCK_HEALTH, FBCLOCK_PREV_HEALTH and FBCLOCK_WOU_NS env variables
+	URL        string        // URL to POST HookEvent as JSON to
+	Timeout    time.Duration // how long the hook can run, 5s if not set
+}
+
+// HookEvent describes health change
+type HookEvent struct {
+	Hook       string    `json:"hook"`
+	Health     Health    `json:"health"`
+	PrevHealth Health    `json:"prev_health"`
+	WOUNS      int64     `json:"wou_ns"`
+	Time       time.Time `json:"time"`
+}
+
+func (h *Hook) validate() error {
+	if h.Name == "" {
+		return fmt.Errorf("bad config: hook 'name' is required")
+	}
+	if h.Exec == "" && h.URL == "" {
+		return fmt.Errorf("bad config: hook %q: either 'exec' or 'url' is required", h.Name)
+	}
+	if h.MaxWOU <= 0 && h.StaleAfter <= 0 {
+		return fmt.Errorf("bad config: hook %q: either 'maxwou' or 'staleafter' is required", h.Name)
+	}
+	if h.MaxWOU < 0 || h.StaleAfter < 0 || h.Timeout < 0 {
+		return fmt.Errorf("bad config: hook %q: durations must not be negative", h.Name)
+	}
+	return nil
+}
+
+// health returns health given current WOU and time since the last shm update
+func (h *Hook) health(wou time.Duration, sinceUpdate time.Duration) Health {
+	if h.StaleAfter > 0 && sinceUpdate > h.StaleAfter {
+		return HealthStale
+	}
+	if h.MaxWOU > 0 && wou > h.MaxWOU {
+		return HealthDegraded
+	}
+	return HealthOK
+}
+
+func (h *Hook) timeout() time.Duration {
+	if h.Timeout > 0 {
+		return h.Timeout
+	}
+	return defaultHookTimeout
+}
+
+// run executes the hook for the event
+func (h *Hook) run(e *HookEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout())
+	defer cancel()
+	if h.Exec != "" {
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", h.Exec)
+		// don't wait for children of the shell holding the output open after it's killed
+		cmd.WaitDelay = h.timeout()
+		cmd.Env = append(os.Environ(),
+			"FBCLOCK_HEALTH="+string(e.Health),
+			"FBCLOCK_PREV_HEALTH="+string(e.PrevHealth),
+			"FBCLOCK_WOU_NS="+strconv.FormatInt(e.WOUNS, 10),
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("running %q: %w, output: %s", h.Exec, err, out)
+		}
+	}
+	if h.URL != "" {
+		body, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("posting to %q: %s", h.URL, resp.Status)
+		}
+	}
+	return nil
+}
+
+// hookRunner tracks health for every hook and runs them on changes.
+// Hooks run in the background, one event at a time per hook, so slow hooks don't block the daemon.
+type hookRunner struct {
+	hooks  []*Hook
+	health []Health
+	events []chan *HookEvent
+	run    func(h *Hook, e *HookEvent) error
+}
+
+func newHookRunner(hooks []Hook) *hookRunner {
+	r := &hookRunner{
+		run: func(h *Hook, e *HookEvent) error { return h.run(e) },
+	}
+	for i := range hooks {
+		h := hooks[i]
+		r.hooks = append(r.hooks, &h)
+		r.health = append(r.health, HealthOK)
+		r.events = append(r.events, make(chan *HookEvent, 16))
+	}
+	return r
+}
+
+// start runs hooks in the background until ctx is done
+func (r *hookRunner) start(ctx context.Context) {
+	for i, h := range r.hooks {
+		go func(h *Hook, events chan *HookEvent) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case e := <-events:
+					log.Infof("running hook %q: health %s -> %s", h.Name, e.PrevHealth, e.Health)
+					if err := r.run(h, e); err != nil {
+						log.Errorf("hook %q: %v", h.Name, err)
+					}
+				}
+			}
+		}(h, r.events[i])
+	}
+}
+
+// check evaluates health of every hook and returns events queued for hooks whose health changed,
+// and the number of events dropped because hooks were too slow.
+// Health of the hook is only updated once its event is queued, so dropped events are retried on the next check.
+func (r *hookRunner) check(wou time.Duration, sinceUpdate time.Duration, now time.Time) ([]*HookEvent, int) {
+	events := []*HookEvent{}
+	dropped := 0
+	for i, h := range r.hooks {
+		health := h.health(wou, sinceUpdate)
+		if health == r.health[i] {
+			continue
+		}
+		e := &HookEvent{Hook: h.Name, Health: health, PrevHealth: r.health[i], WOUNS: wou.Nanoseconds(), Time: now}
+		select {
+		case r.events[i] <- e:
+			r.health[i] = health
+			events = append(events, e)
+		default:
+			dropped++
+			log.Errorf("hook %q is too slow, dropping event %+v, will retry", h.Name, e)
+		}
+	}
+	return events, dropped
+}
+
+// currentWOU returns WOU clients get from the last data written to shm right now
+func (s *Daemon) currentWOU() (time.Duration, error) {
+	d, _ := s.state.lastDataWritten()
+	if d == nil {
+		return 0, nil
+	}
+	phcTime, err := s.getPHCTime()
+	if err != nil {
+		return 0, err
+	}
+	wou := float64(d.ErrorBoundNS)
+	if d.IngressTimeNS > 0 {
+		seconds := float64(phcTime.UnixNano()-d.IngressTimeNS) / float64(time.Second)
+		wou += d.HoldoverMultiplierNS * seconds
+	}
+	return time.Duration(wou), nil
+}
+
+// checkHooks triggers hooks if health changed. Until the first write staleness is counted from started.
+func (s *Daemon) checkHooks(started time.Time) error {
+	wou, err := s.currentWOU()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	_, lastWrite := s.state.lastDataWritten()
+	if lastWrite.IsZero() {
+		lastWrite = started
+	}
+	events, dropped := s.hooks.check(wou, now.Sub(lastWrite), now)
+	s.stats.UpdateCounterBy("hook_events", int64(len(events)))
+	s.stats.UpdateCounterBy("hook_events_dropped", int64(dropped))
+	return nil
+}
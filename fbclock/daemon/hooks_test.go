@@ -0,0 +1,194 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/facebook/time/fbclock"
+	"github.com/facebook/time/fbclock/stats"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHookValidate(t *testing.T) {
+	h := &Hook{}
+	require.Equal(t, fmt.Errorf("bad config: hook 'name' is required"), h.validate())
+
+	h.Name = "drain"
+	require.Equal(t, fmt.Errorf("bad config: hook \"drain\": either 'exec' or 'url' is required"), h.validate())
+
+	h.Exec = "true"
+	require.Equal(t, fmt.Errorf("bad config: hook \"drain\": either 'maxwou' or 'staleafter' is required"), h.validate())
+
+	h.MaxWOU = time.Microsecond
+	h.Timeout = -time.Second
+	require.Equal(t, fmt.Errorf("bad config: hook \"drain\": durations must not be negative"), h.validate())
+
+	h.Timeout = time.Second
+	require.NoError(t, h.validate())
+}
+
+func TestHookHealth(t *testing.T) {
+	h := &Hook{MaxWOU: time.Microsecond, StaleAfter: time.Minute}
+	require.Equal(t, HealthOK, h.health(time.Microsecond, time.Second))
+	require.Equal(t, HealthDegraded, h.health(2*time.Microsecond, time.Second))
+	require.Equal(t, HealthStale, h.health(time.Nanosecond, 2*time.Minute))
+
+	h = &Hook{StaleAfter: time.Minute}
+	require.Equal(t, HealthOK, h.health(time.Hour, time.Second))
+}
+
+func TestHookRunnerCheck(t *testing.T) {
+	r := newHookRunner([]Hook{
+		{Name: "wou", MaxWOU: time.Microsecond},
+		{Name: "stale", StaleAfter: time.Minute},
+	})
+	now := time.Unix(1647359186, 0)
+
+	// healthy from the start, nothing to report
+	events, dropped := r.check(time.Microsecond, time.Second, now)
+	require.Empty(t, events)
+	require.Equal(t, 0, dropped)
+
+	events, _ = r.check(2*time.Microsecond, time.Second, now)
+	require.Equal(t, []*HookEvent{{Hook: "wou", Health: HealthDegraded, PrevHealth: HealthOK, WOUNS: 2000, Time: now}}, events)
+	// only changes are reported
+	events, _ = r.check(3*time.Microsecond, time.Second, now)
+	require.Empty(t, events)
+
+	events, _ = r.check(3*time.Microsecond, 2*time.Minute, now)
+	require.Equal(t, []*HookEvent{
+		{Hook: "stale", Health: HealthStale, PrevHealth: HealthOK, WOUNS: 3000, Time: now},
+	}, events)
+
+	events, _ = r.check(time.Microsecond, time.Second, now)
+	require.Equal(t, []*HookEvent{
+		{Hook: "wou", Health: HealthOK, PrevHealth: HealthDegraded, WOUNS: 1000, Time: now},
+		{Hook: "stale", Health: HealthOK, PrevHealth: HealthStale, WOUNS: 1000, Time: now},
+	}, events)
+}
+
+func TestHookRunnerCheckDropped(t *testing.T) {
+	r := newHookRunner([]Hook{{Name: "wou", MaxWOU: time.Microsecond}})
+	// hook is still busy with the previous event
+	r.events[0] = make(chan *HookEvent, 1)
+	busy := &HookEvent{Hook: "wou"}
+	r.events[0] <- busy
+	now := time.Unix(1647359186, 0)
+
+	events, dropped := r.check(2*time.Microsecond, time.Second, now)
+	require.Empty(t, events)
+	require.Equal(t, 1, dropped)
+	require.Equal(t, HealthOK, r.health[0])
+
+	// hook caught up, the change is reported on the next check
+	require.Equal(t, busy, <-r.events[0])
+	events, dropped = r.check(2*time.Microsecond, time.Second, now)
+	require.Equal(t, []*HookEvent{{Hook: "wou", Health: HealthDegraded, PrevHealth: HealthOK, WOUNS: 2000, Time: now}}, events)
+	require.Equal(t, 0, dropped)
+	require.Equal(t, HealthDegraded, r.health[0])
+	require.Equal(t, events[0], <-r.events[0])
+}
+
+func TestHookRunnerStart(t *testing.T) {
+	r := newHookRunner([]Hook{{Name: "wou", MaxWOU: time.Microsecond}})
+	ran := make(chan *HookEvent)
+	r.run = func(_ *Hook, e *HookEvent) error {
+		ran <- e
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.start(ctx)
+
+	events, _ := r.check(2*time.Microsecond, time.Second, time.Now())
+	require.Len(t, events, 1)
+	require.Equal(t, events[0], <-ran)
+}
+
+func TestHookRunExec(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "health")
+	h := &Hook{Name: "drain", Exec: "echo $FBCLOCK_PREV_HEALTH $FBCLOCK_HEALTH $FBCLOCK_WOU_NS > " + out}
+	require.NoError(t, h.run(&HookEvent{Hook: "drain", Health: HealthDegraded, PrevHealth: HealthOK, WOUNS: 2000}))
+	b, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "ok degraded 2000\n", string(b))
+
+	h.Exec = "exit 1"
+	require.Error(t, h.run(&HookEvent{}))
+
+	h.Exec = "sleep 10"
+	h.Timeout = 10 * time.Millisecond
+	require.Error(t, h.run(&HookEvent{}))
+}
+
+func TestHookRunURL(t *testing.T) {
+	received := &HookEvent{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		if err := json.NewDecoder(r.Body).Decode(received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	e := &HookEvent{Hook: "drain", Health: HealthStale, PrevHealth: HealthOK, WOUNS: 2000, Time: time.Unix(1647359186, 0).UTC()}
+	h := &Hook{Name: "drain", URL: ts.URL}
+	require.NoError(t, h.run(e))
+	require.Equal(t, e, received)
+
+	h.URL = ts.URL + "/nope\x7f"
+	require.Error(t, h.run(e))
+}
+
+func TestDaemonCheckHooks(t *testing.T) {
+	cfg := &Config{Hooks: []Hook{{Name: "wou", MaxWOU: time.Microsecond, StaleAfter: time.Minute}}}
+	st := stats.NewStats()
+	s := newTestDaemon(cfg, st)
+	s.hooks = newHookRunner(cfg.Hooks)
+	phcTime := time.Unix(0, 1647359186979431900)
+	s.getPHCTime = func() (time.Time, error) { return phcTime, nil }
+
+	// nothing is written yet, stale once StaleAfter passes since start
+	require.NoError(t, s.checkHooks(time.Now()))
+	require.Equal(t, int64(0), st.Get()["hook_events"])
+	require.NoError(t, s.checkHooks(time.Now().Add(-2*time.Minute)))
+	require.Equal(t, int64(1), st.Get()["hook_events"])
+
+	// WOU grows in holdover until it's over the threshold
+	s.state.updateLastData(&fbclock.Data{IngressTimeNS: phcTime.UnixNano(), ErrorBoundNS: 500, HoldoverMultiplierNS: 1000}, time.Now())
+	wou, err := s.currentWOU()
+	require.NoError(t, err)
+	require.Equal(t, 500*time.Nanosecond, wou)
+	require.NoError(t, s.checkHooks(time.Now()))
+	require.Equal(t, HealthOK, s.hooks.health[0])
+
+	phcTime = phcTime.Add(time.Second)
+	require.NoError(t, s.checkHooks(time.Now()))
+	require.Equal(t, HealthDegraded, s.hooks.health[0])
+	require.Equal(t, int64(3), st.Get()["hook_events"])
+}
@@ -19,6 +19,8 @@ package daemon
 import (
 	"fmt"
 	"math"
+	"regexp"
+	"slices"
 
 	"github.com/Knetic/govaluate"
 	"github.com/eclesh/welford"
@@ -35,6 +37,7 @@ supported variables:
   clockaccuracy (list of clock accuracy values received from GM)
   freqchange (list of last changes in frequency)
   freqchangeabs (list of last changes in frequency, abs values)
+  any coefficient defined in 'coefficients' section of the config file, like holdover growth rate of the oscillator
 supported functions:
   abs(value) - absolute value of single float64, for example abs(-1) = 1
   mean(values, number) - mean of list of 'number' values, for example mean(offset, 10) will take 10 elements from array 'offset' and return mean for those values
@@ -52,6 +55,9 @@ const (
 	MathDefaultDrift = "1.5 * mean(freqchangeabs, 99)"
 )
 
+// coefficientName is what names of Math coefficients must look like
+var coefficientName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
 // Math stores our math expressions for M ans W values in two forms: string and parsed
 type Math struct {
 	M         string // Measurement, our value for clock quality
@@ -60,26 +66,53 @@ type Math struct {
 	wExpr     *govaluate.EvaluableExpression
 	Drift     string // drift in PPB, for holdover multiplier calculations
 	driftExpr *govaluate.EvaluableExpression
+	// Coefficients are named constants available in all expressions,
+	// so the same formulas can be tuned for different hardware (OCXO vs TCXO)
+	Coefficients map[string]float64
 }
 
-// Prepare will prepare all math expressions
+// Prepare will validate coefficients and prepare all math expressions
 func (m *Math) Prepare() error {
+	coefficients := make([]string, 0, len(m.Coefficients))
+	for name, value := range m.Coefficients {
+		if !coefficientName.MatchString(name) {
+			return fmt.Errorf("bad coefficient name %q", name)
+		}
+		if isSupportedVar(name) {
+			return fmt.Errorf("coefficient %q shadows a variable", name)
+		}
+		if _, ok := functions[name]; ok {
+			return fmt.Errorf("coefficient %q shadows a function", name)
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("coefficient %q must be finite, got %v", name, value)
+		}
+		coefficients = append(coefficients, name)
+	}
 	var err error
-	m.mExpr, err = prepareExpression(m.M)
+	m.mExpr, err = prepareExpression(m.M, coefficients...)
 	if err != nil {
 		return fmt.Errorf("evaluating M: %w", err)
 	}
-	m.wExpr, err = prepareExpression(m.W)
+	m.wExpr, err = prepareExpression(m.W, coefficients...)
 	if err != nil {
 		return fmt.Errorf("evaluating W: %w", err)
 	}
-	m.driftExpr, err = prepareExpression(m.Drift)
+	m.driftExpr, err = prepareExpression(m.Drift, coefficients...)
 	if err != nil {
 		return fmt.Errorf("evaluating Drift: %w", err)
 	}
 	return nil
 }
 
+// parameters returns expression parameters with coefficients added
+func (m *Math) parameters(params map[string]interface{}) map[string]interface{} {
+	for name, value := range m.Coefficients {
+		params[name] = value
+	}
+	return params
+}
+
 func mean(input []float64) float64 {
 	s := welford.New()
 	for _, v := range input {
@@ -104,6 +137,20 @@ func stddev(input []float64) float64 {
 	return s.Stddev()
 }
 
+// percentile returns p-th (0-100) percentile of input using nearest-rank method
+func percentile(input []float64, p float64) float64 {
+	if len(input) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(input)
+	slices.Sort(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
 // convolve mixes two signals together
 func convolve(input, coeffs []float64) ([]float64, error) {
 	if len(input) < len(coeffs) {
@@ -194,13 +241,14 @@ var functions = map[string]govaluate.ExpressionFunction{
 	},
 }
 
-func prepareExpression(exprStr string) (*govaluate.EvaluableExpression, error) {
+// prepareExpression parses expression, which can use supported variables and extraVars
+func prepareExpression(exprStr string, extraVars ...string) (*govaluate.EvaluableExpression, error) {
 	expr, err := govaluate.NewEvaluableExpressionWithFunctions(exprStr, functions)
 	if err != nil {
 		return nil, err
 	}
 	for _, v := range expr.Vars() {
-		if !isSupportedVar(v) {
+		if !isSupportedVar(v) && !slices.Contains(extraVars, v) {
 			return nil, fmt.Errorf("unsupported variable %q", v)
 		}
 	}
@@ -87,6 +87,18 @@ func TestVariance(t *testing.T) {
 	require.Equal(t, want, variance(input))
 }
 
+func TestPercentile(t *testing.T) {
+	require.Equal(t, 0.0, percentile(nil, 50))
+	input := []float64{15, 20, 35, 40, 50}
+	require.Equal(t, 15.0, percentile(input, 0))
+	require.Equal(t, 20.0, percentile(input, 30))
+	require.Equal(t, 35.0, percentile(input, 50))
+	require.Equal(t, 50.0, percentile(input, 99))
+	require.Equal(t, 50.0, percentile(input, 100))
+	// input is not modified
+	require.Equal(t, []float64{15, 20, 35, 40, 50}, input)
+}
+
 func TestPrepareExpression(t *testing.T) {
 	input := "mean(clockaccuracy, 5) + abs(mean(offset, 5)) + 1.0 * stddev(offset, 4) + 1.0 * stddev(delay, 4) + 1.0 * stddev(freq, 5)"
 	expr, err := prepareExpression(input)
@@ -0,0 +1,180 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/facebook/time/fbclock/stats"
+	"github.com/facebook/time/phc"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Source is an additional PTP client on the same host, synchronizing PHC of another network interface
+type Source struct {
+	Name             string // used in per-source counters
+	Iface            string // network interface with PHC synchronized by this PTP client
+	PTPClientAddress string // where should fbclock connect to
+	SPTP             bool   // denotes whether this is sptp or ptp4l
+}
+
+// validate makes sure Source is usable
+func (s *Source) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("bad config: source 'name' is required")
+	}
+	if s.Iface == "" {
+		return fmt.Errorf("bad config: source %q: 'iface' is required", s.Name)
+	}
+	if s.PTPClientAddress == "" {
+		return fmt.Errorf("bad config: source %q: 'ptpclientaddress' is required", s.Name)
+	}
+	return nil
+}
+
+// estimate is an offset of the main PHC from GM as seen by a single source
+type estimate struct {
+	offsetNS      float64
+	uncertaintyNS float64 // half width of the interval around offsetNS
+}
+
+// aggregateEstimates combines estimates into one.
+// When all intervals overlap, sources agree and we center on the intersection.
+// Uncertainty is never below the one of the best source though: intervals which barely overlap
+// don't make us more confident than any single source is.
+// Otherwise we take the union, widening the interval to cover all the sources.
+func aggregateEstimates(estimates []estimate) (e estimate, agree bool) {
+	maxLo, minHi := math.Inf(-1), math.Inf(1)
+	minLo, maxHi := math.Inf(1), math.Inf(-1)
+	minUncertainty := math.Inf(1)
+	for _, e := range estimates {
+		lo, hi := e.offsetNS-e.uncertaintyNS, e.offsetNS+e.uncertaintyNS
+		maxLo = math.Max(maxLo, lo)
+		minHi = math.Min(minHi, hi)
+		minLo = math.Min(minLo, lo)
+		maxHi = math.Max(maxHi, hi)
+		minUncertainty = math.Min(minUncertainty, e.uncertaintyNS)
+	}
+	if maxLo <= minHi {
+		return estimate{offsetNS: (maxLo + minHi) / 2, uncertaintyNS: math.Max((minHi-maxLo)/2, minUncertainty)}, true
+	}
+	return estimate{offsetNS: (minLo + maxHi) / 2, uncertaintyNS: (maxHi - minLo) / 2}, false
+}
+
+// additionalSource is a Source with everything needed to fetch data from it
+type additionalSource struct {
+	Source
+	cfg     *Config
+	fetcher DataFetcher
+	// phcOffset returns offset of the source PHC from the main PHC
+	phcOffset func() (time.Duration, error)
+}
+
+// fetchEstimate returns main PHC offset from GM according to this source
+func (a *additionalSource) fetchEstimate() (estimate, error) {
+	data, err := a.fetcher.FetchStats(a.cfg)
+	if err != nil {
+		return estimate{}, err
+	}
+	phcOffset, err := a.phcOffset()
+	if err != nil {
+		return estimate{}, fmt.Errorf("measuring offset between PHCs: %w", err)
+	}
+	// main PHC - GM = (main PHC - source PHC) + (source PHC - GM)
+	return estimate{
+		offsetNS:      data.MasterOffsetNS - float64(phcOffset.Nanoseconds()),
+		uncertaintyNS: data.ClockAccuracyNS,
+	}, nil
+}
+
+// MultiFetcher provides data fetcher implementation which aggregates data from the main PTP client
+// and additional ones synchronizing other PHCs on the same host
+type MultiFetcher struct {
+	DataFetcher // main source, its PHC is the one clients read
+	sources     []*additionalSource
+	stats       stats.Server
+}
+
+// NewMultiFetcher returns MultiFetcher for cfg.Sources, in addition to main fetcher reading mainPHC
+func NewMultiFetcher(main DataFetcher, mainPHC *os.File, cfg *Config, stats stats.Server) (*MultiFetcher, error) {
+	m := &MultiFetcher{DataFetcher: main, stats: stats}
+	for _, src := range cfg.Sources {
+		device, err := phc.IfaceToPHCDevice(src.Iface)
+		if err != nil {
+			return nil, fmt.Errorf("finding PHC device for %q: %w", src.Iface, err)
+		}
+		// Keep file open for the lifetime of the fbclock
+		f, err := os.Open(device)
+		if err != nil {
+			return nil, err
+		}
+		a := &additionalSource{
+			Source: src,
+			cfg: &Config{
+				PTPClientAddress: src.PTPClientAddress,
+				SPTP:             src.SPTP,
+				Interval:         cfg.Interval,
+			},
+			phcOffset: func() (time.Duration, error) { return phc.OffsetBetweenDevices(mainPHC, f) },
+		}
+		if src.SPTP {
+			a.fetcher = &HTTPFetcher{}
+		} else {
+			a.fetcher = &SockFetcher{}
+		}
+		m.sources = append(m.sources, a)
+	}
+	return m, nil
+}
+
+// FetchStats fetches stats from all sources and aggregates offset and clock accuracy
+func (m *MultiFetcher) FetchStats(cfg *Config) (*DataPoint, error) {
+	data, err := m.DataFetcher.FetchStats(cfg)
+	if err != nil {
+		return nil, err
+	}
+	estimates := []estimate{{offsetNS: data.MasterOffsetNS, uncertaintyNS: data.ClockAccuracyNS}}
+	for _, src := range m.sources {
+		prefix := fmt.Sprintf("source.%s.", src.Name)
+		e, err := src.fetchEstimate()
+		if err != nil {
+			log.Warningf("fetching data from source %q: %v", src.Name, err)
+			m.stats.SetCounter(prefix+"up", 0)
+			continue
+		}
+		m.stats.SetCounter(prefix+"up", 1)
+		m.stats.SetCounter(prefix+"offset_ns", int64(e.offsetNS))
+		m.stats.SetCounter(prefix+"uncertainty_ns", int64(e.uncertaintyNS))
+		estimates = append(estimates, e)
+	}
+	e, agree := aggregateEstimates(estimates)
+	m.stats.SetCounter("sources", int64(len(estimates)))
+	if agree {
+		m.stats.SetCounter("sources_agree", 1)
+	} else {
+		log.Warningf("sources disagree: %+v", estimates)
+		m.stats.SetCounter("sources_agree", 0)
+	}
+	aggregated := *data
+	aggregated.MasterOffsetNS = e.offsetNS
+	aggregated.ClockAccuracyNS = e.uncertaintyNS
+	return &aggregated, nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/facebook/time/fbclock/stats"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeFetcher struct {
+	DataFetcher
+	data *DataPoint
+	err  error
+}
+
+func (f *fakeFetcher) FetchStats(_ *Config) (*DataPoint, error) {
+	return f.data, f.err
+}
+
+func TestAggregateEstimates(t *testing.T) {
+	testCases := []struct {
+		name      string
+		in        []estimate
+		want      estimate
+		wantAgree bool
+	}{
+		{
+			name:      "single",
+			in:        []estimate{{offsetNS: 10, uncertaintyNS: 100}},
+			want:      estimate{offsetNS: 10, uncertaintyNS: 100},
+			wantAgree: true,
+		},
+		{
+			name:      "agree",
+			in:        []estimate{{offsetNS: 10, uncertaintyNS: 100}, {offsetNS: -40, uncertaintyNS: 100}},
+			want:      estimate{offsetNS: -15, uncertaintyNS: 100},
+			wantAgree: true,
+		},
+		{
+			name:      "nested",
+			in:        []estimate{{offsetNS: 10, uncertaintyNS: 100}, {offsetNS: 20, uncertaintyNS: 20}},
+			want:      estimate{offsetNS: 20, uncertaintyNS: 20},
+			wantAgree: true,
+		},
+		{
+			name:      "barely overlap",
+			in:        []estimate{{offsetNS: 0, uncertaintyNS: 100}, {offsetNS: 199, uncertaintyNS: 100}},
+			want:      estimate{offsetNS: 99.5, uncertaintyNS: 100},
+			wantAgree: true,
+		},
+		{
+			name:      "disagree",
+			in:        []estimate{{offsetNS: 10, uncertaintyNS: 100}, {offsetNS: 500, uncertaintyNS: 100}},
+			want:      estimate{offsetNS: 255, uncertaintyNS: 345},
+			wantAgree: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, agree := aggregateEstimates(tc.in)
+			require.Equal(t, tc.want, got)
+			require.Equal(t, tc.wantAgree, agree)
+		})
+	}
+}
+
+func TestMultiFetcherFetchStats(t *testing.T) {
+	st := stats.NewStats()
+	main := &fakeFetcher{data: &DataPoint{IngressTimeNS: 42, MasterOffsetNS: 10, PathDelayNS: 200, ClockAccuracyNS: 100}}
+	second := &fakeFetcher{data: &DataPoint{IngressTimeNS: 43, MasterOffsetNS: 20, PathDelayNS: 300, ClockAccuracyNS: 100}}
+	phcOffset := 60 * time.Nanosecond
+	m := &MultiFetcher{
+		DataFetcher: main,
+		stats:       st,
+		sources: []*additionalSource{
+			{
+				Source:    Source{Name: "eth1"},
+				cfg:       &Config{},
+				fetcher:   second,
+				phcOffset: func() (time.Duration, error) { return phcOffset, nil },
+			},
+		},
+	}
+
+	// second source sees main PHC at 20 - 60 = -40ns from GM
+	d, err := m.FetchStats(&Config{})
+	require.NoError(t, err)
+	require.Equal(t, &DataPoint{IngressTimeNS: 42, MasterOffsetNS: -15, PathDelayNS: 200, ClockAccuracyNS: 100}, d)
+	c := st.Get()
+	require.Equal(t, int64(1), c["sources_agree"])
+	require.Equal(t, int64(2), c["sources"])
+	require.Equal(t, int64(1), c["source.eth1.up"])
+	require.Equal(t, int64(-40), c["source.eth1.offset_ns"])
+	require.Equal(t, int64(100), c["source.eth1.uncertainty_ns"])
+
+	// PHCs diverged
+	phcOffset = -490 * time.Nanosecond
+	d, err = m.FetchStats(&Config{})
+	require.NoError(t, err)
+	require.Equal(t, 260.0, d.MasterOffsetNS)
+	require.Equal(t, 350.0, d.ClockAccuracyNS)
+	require.Equal(t, int64(0), st.Get()["sources_agree"])
+
+	// broken additional source is skipped
+	second.err = fmt.Errorf("ptp4l is down")
+	d, err = m.FetchStats(&Config{})
+	require.NoError(t, err)
+	require.Equal(t, main.data, d)
+	c = st.Get()
+	require.Equal(t, int64(0), c["source.eth1.up"])
+	require.Equal(t, int64(1), c["sources"])
+
+	// broken main source is an error
+	main.err = fmt.Errorf("ptp4l is down")
+	_, err = m.FetchStats(&Config{})
+	require.Error(t, err)
+}
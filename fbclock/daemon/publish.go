@@ -0,0 +1,116 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/facebook/time/fbclock"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// shmPublisher copies fbclock data into additional shm segments,
+// so containers with private /dev/shm can read it without privileged bind mounts.
+// Directories of configured paths can be globs, new matches are picked up on every refresh.
+type shmPublisher struct {
+	paths []string
+	open  map[string]*fbclock.Shm
+}
+
+func newShmPublisher(paths []string) *shmPublisher {
+	return &shmPublisher{paths: paths, open: map[string]*fbclock.Shm{}}
+}
+
+// targets expands configured paths into shm files we should publish to
+func (p *shmPublisher) targets() []string {
+	targets := []string{}
+	for _, path := range p.paths {
+		dirs, err := filepath.Glob(filepath.Dir(path))
+		if err != nil {
+			log.Errorf("expanding shm path %q: %v", path, err)
+			continue
+		}
+		for _, dir := range dirs {
+			st, err := os.Stat(dir)
+			if err != nil || !st.IsDir() {
+				continue
+			}
+			targets = append(targets, filepath.Join(dir, filepath.Base(path)))
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// refresh opens shm for new targets and closes it for gone ones
+func (p *shmPublisher) refresh() {
+	seen := map[string]bool{}
+	for _, path := range p.targets() {
+		seen[path] = true
+		if _, ok := p.open[path]; ok {
+			continue
+		}
+		shm, err := fbclock.OpenFBClockShmCustom(path)
+		if err != nil {
+			log.Errorf("opening fbclock shm %q: %v", path, err)
+			continue
+		}
+		log.Infof("publishing fbclock shm to %s", path)
+		p.open[path] = shm
+	}
+	for path, shm := range p.open {
+		if !seen[path] {
+			log.Infof("stopped publishing fbclock shm to %s", path)
+			shm.Close()
+			delete(p.open, path)
+		}
+	}
+}
+
+// store calls f for every open shm, dropping the ones it fails for so they are reopened on refresh.
+// It returns number of failures.
+func (p *shmPublisher) store(f func(fd uintptr) error) int {
+	failed := 0
+	for path, shm := range p.open {
+		if err := f(shm.File.Fd()); err != nil {
+			log.Errorf("writing fbclock shm %q: %v", path, err)
+			shm.Close()
+			delete(p.open, path)
+			failed++
+		}
+	}
+	return failed
+}
+
+func (p *shmPublisher) storeData(d fbclock.Data) int {
+	return p.store(func(fd uintptr) error { return fbclock.StoreFBClockData(fd, d) })
+}
+
+func (p *shmPublisher) storeHeartbeat() int {
+	return p.store(fbclock.StoreFBClockHeartbeat)
+}
+
+// Close closes all open shm
+func (p *shmPublisher) Close() {
+	for path, shm := range p.open {
+		shm.Close()
+		delete(p.open, path)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/facebook/time/fbclock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShmPublisher(t *testing.T) {
+	root := t.TempDir()
+	for _, c := range []string{"c1", "c2"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, c, "shm"), 0755))
+	}
+	// not a directory, must be skipped
+	require.NoError(t, os.WriteFile(filepath.Join(root, "c3"), nil, 0644))
+	static := filepath.Join(root, "static", "fbclock_data")
+	require.NoError(t, os.MkdirAll(filepath.Dir(static), 0755))
+
+	p := newShmPublisher([]string{filepath.Join(root, "*", "shm", "fbclock_data"), static})
+	defer p.Close()
+	want := []string{
+		filepath.Join(root, "c1", "shm", "fbclock_data"),
+		filepath.Join(root, "c2", "shm", "fbclock_data"),
+		static,
+	}
+	require.Equal(t, want, p.targets())
+
+	p.refresh()
+	require.Len(t, p.open, 3)
+	d := fbclock.Data{IngressTimeNS: 1647359186979431900, ErrorBoundNS: 42, HoldoverMultiplierNS: 1.5}
+	require.Equal(t, 0, p.storeData(d))
+	require.Equal(t, 0, p.storeHeartbeat())
+	for _, path := range want {
+		shm, err := fbclock.OpenFBClockShmCustom(path)
+		require.NoError(t, err)
+		shmp, err := fbclock.MmapShmpData(shm.File.Fd())
+		require.NoError(t, err)
+		got, err := fbclock.ReadFBClockData(shmp)
+		require.NoError(t, err)
+		require.Equal(t, d.IngressTimeNS, got.IngressTimeNS)
+		require.Equal(t, d.ErrorBoundNS, got.ErrorBoundNS)
+		require.NoError(t, shm.Close())
+	}
+
+	// container is gone, new one appeared
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "c1")))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "c4", "shm"), 0755))
+	p.refresh()
+	require.Len(t, p.open, 3)
+	require.NotContains(t, p.open, want[0])
+	require.Contains(t, p.open, filepath.Join(root, "c4", "shm", "fbclock_data"))
+}
@@ -0,0 +1,221 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/facebook/time/fbclock"
+	"github.com/facebook/time/fbclock/stats"
+	"github.com/facebook/time/phc"
+
+	log "github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// SimulationStep is one step of a simulation scenario.
+// For Duration daemon publishes given error bound and holdover multiplier instead of computing them.
+type SimulationStep struct {
+	Duration             time.Duration // how long this step lasts
+	ErrorBoundNS         uint64        // error bound to publish
+	HoldoverMultiplierNS float64       // holdover multiplier to publish, WOU grows by it every second since ingress
+	OffsetNS             float64       // offset from GM to report in counters and history, not used by clients
+	Holdover             bool          // don't advance ingress time, as if PTP client lost its GM
+	Stale                bool          // don't update shm at all, not even heartbeat, as if daemon was dead
+}
+
+// Scenario is a list of steps simulation goes through
+type Scenario struct {
+	Steps []SimulationStep
+	Loop  bool // start over after the last step, otherwise the last step lasts forever
+}
+
+// Validate makes sure scenario is valid
+func (sc *Scenario) Validate() error {
+	if len(sc.Steps) == 0 {
+		return fmt.Errorf("bad scenario: no steps")
+	}
+	for i, step := range sc.Steps {
+		if step.Duration <= 0 {
+			return fmt.Errorf("bad scenario: step %d: 'duration' must be positive", i)
+		}
+		if step.HoldoverMultiplierNS < 0 {
+			return fmt.Errorf("bad scenario: step %d: 'holdovermultiplierns' must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// ReadScenario reads scenario and unmarshals it from yaml
+func ReadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sc := Scenario{}
+	if err := yaml.UnmarshalStrict(data, &sc); err != nil {
+		return nil, err
+	}
+	return &sc, sc.Validate()
+}
+
+// at returns step of the scenario active after elapsed time since start, or nil if the scenario is over
+func (sc *Scenario) at(elapsed time.Duration) *SimulationStep {
+	var total time.Duration
+	for _, step := range sc.Steps {
+		total += step.Duration
+	}
+	if elapsed >= total {
+		if !sc.Loop {
+			return &sc.Steps[len(sc.Steps)-1]
+		}
+		elapsed %= total
+	}
+	for i := range sc.Steps {
+		if elapsed < sc.Steps[i].Duration {
+			return &sc.Steps[i]
+		}
+		elapsed -= sc.Steps[i].Duration
+	}
+	return &sc.Steps[len(sc.Steps)-1]
+}
+
+// Simulator publishes scripted data into fbclock shm without any PTP client.
+// It's meant for integration tests of applications using fbclock.
+type Simulator struct {
+	cfg      *Config
+	scenario *Scenario
+	stats    stats.Server
+	history  *History
+
+	ingressTimeNS int64
+	getPHCTime    func() (time.Time, error)
+}
+
+// NewSimulator creates new Simulator which uses PHC of cfg.Iface as a time source, same as clients do
+func NewSimulator(cfg *Config, scenario *Scenario, stats stats.Server) (*Simulator, error) {
+	phcDevice, err := phc.IfaceToPHCDevice(cfg.Iface)
+	if err != nil {
+		return nil, fmt.Errorf("finding PHC device for %q: %w", cfg.Iface, err)
+	}
+	f, err := os.OpenFile(phcDevice, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	dev := phc.FromFile(f)
+	s := &Simulator{
+		cfg:        cfg,
+		scenario:   scenario,
+		stats:      stats,
+		getPHCTime: func() (time.Time, error) { return dev.Time() },
+	}
+	s.stats.SetCounter("simulation_step", 0)
+	s.stats.SetCounter("master_offset_ns", 0)
+	s.stats.SetCounter("ingress_time_ns", 0)
+	s.stats.SetCounter("wou_ns", 0)
+	s.stats.SetCounter("shm_write", 0)
+	s.stats.SetCounter("shm_write_error", 0)
+	return s, nil
+}
+
+// doWork publishes data for the given step
+func (s *Simulator) doWork(shm *fbclock.Shm, step *SimulationStep) error {
+	if step.Stale {
+		return nil
+	}
+	if err := fbclock.StoreFBClockHeartbeat(shm.File.Fd()); err != nil {
+		return err
+	}
+	phcTime, err := s.getPHCTime()
+	if err != nil {
+		return fmt.Errorf("Failed to get PHC time from %s: %w", s.cfg.Iface, err)
+	}
+	if !step.Holdover || s.ingressTimeNS == 0 {
+		s.ingressTimeNS = phcTime.UnixNano()
+	}
+	d := fbclock.Data{
+		IngressTimeNS:        s.ingressTimeNS,
+		ErrorBoundNS:         step.ErrorBoundNS,
+		HoldoverMultiplierNS: step.HoldoverMultiplierNS,
+	}
+	if err := fbclock.StoreFBClockData(shm.File.Fd(), d); err != nil {
+		s.stats.UpdateCounterBy("shm_write_error", 1)
+		return err
+	}
+	s.stats.UpdateCounterBy("shm_write", 1)
+	seconds := float64(phcTime.UnixNano()-s.ingressTimeNS) / float64(time.Second)
+	wou := float64(d.ErrorBoundNS) + d.HoldoverMultiplierNS*seconds
+	s.stats.SetCounter("master_offset_ns", int64(step.OffsetNS))
+	s.stats.SetCounter("ingress_time_ns", d.IngressTimeNS)
+	s.stats.SetCounter("wou_ns", int64(wou))
+	if s.history != nil {
+		r := &HistoryRecord{
+			TimeNS:               time.Now().UnixNano(),
+			IngressTimeNS:        d.IngressTimeNS,
+			OffsetNS:             step.OffsetNS,
+			ErrorBoundNS:         d.ErrorBoundNS,
+			HoldoverMultiplierNS: d.HoldoverMultiplierNS,
+			WOUNS:                uint64(wou),
+		}
+		if err := s.history.Append(r); err != nil {
+			log.Errorf("writing history: %v", err)
+		}
+	}
+	return nil
+}
+
+// Run goes through the scenario, updating shm at cfg.Interval
+func (s *Simulator) Run(ctx context.Context) error {
+	shm, err := fbclock.OpenFBClockSHM()
+	if err != nil {
+		return fmt.Errorf("opening fbclock shm: %w", err)
+	}
+	defer shm.Close()
+
+	if s.cfg.HistoryPath != "" {
+		s.history, err = OpenHistory(s.cfg.HistoryPath, s.cfg.HistorySize)
+		if err != nil {
+			return fmt.Errorf("opening history: %w", err)
+		}
+		defer s.history.Close()
+	}
+
+	log.Warning("simulation mode: publishing scripted data, not real PTP state")
+	start := time.Now()
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	var current *SimulationStep
+	for {
+		step := s.scenario.at(time.Since(start))
+		if step != current {
+			log.Infof("simulation step: %+v", *step)
+			s.stats.UpdateCounterBy("simulation_step", 1)
+			current = step
+		}
+		if err := s.doWork(shm, step); err != nil {
+			log.Error(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
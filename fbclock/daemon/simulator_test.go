@@ -0,0 +1,134 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/facebook/time/fbclock"
+	"github.com/facebook/time/fbclock/stats"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadScenario(t *testing.T) {
+	f, err := os.CreateTemp("", "fbclock_scenario")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`loop: true
+steps:
+  - duration: 10s
+    errorboundns: 100
+    holdovermultiplierns: 1.5
+  - duration: 1m
+    errorboundns: 100
+    holdovermultiplierns: 1.5
+    holdover: true
+  - duration: 90s
+    stale: true
+`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	sc, err := ReadScenario(f.Name())
+	require.NoError(t, err)
+	want := &Scenario{
+		Loop: true,
+		Steps: []SimulationStep{
+			{Duration: 10 * time.Second, ErrorBoundNS: 100, HoldoverMultiplierNS: 1.5},
+			{Duration: time.Minute, ErrorBoundNS: 100, HoldoverMultiplierNS: 1.5, Holdover: true},
+			{Duration: 90 * time.Second, Stale: true},
+		},
+	}
+	require.Equal(t, want, sc)
+}
+
+func TestScenarioValidate(t *testing.T) {
+	sc := &Scenario{}
+	require.EqualError(t, sc.Validate(), "bad scenario: no steps")
+	sc.Steps = []SimulationStep{{Duration: time.Second}, {}}
+	require.EqualError(t, sc.Validate(), "bad scenario: step 1: 'duration' must be positive")
+	sc.Steps[1] = SimulationStep{Duration: time.Second, HoldoverMultiplierNS: -1}
+	require.EqualError(t, sc.Validate(), "bad scenario: step 1: 'holdovermultiplierns' must not be negative")
+	sc.Steps[1].HoldoverMultiplierNS = 1
+	require.NoError(t, sc.Validate())
+}
+
+func TestScenarioAt(t *testing.T) {
+	sc := &Scenario{
+		Steps: []SimulationStep{
+			{Duration: 10 * time.Second, ErrorBoundNS: 1},
+			{Duration: 5 * time.Second, ErrorBoundNS: 2},
+		},
+	}
+	require.Equal(t, uint64(1), sc.at(0).ErrorBoundNS)
+	require.Equal(t, uint64(1), sc.at(9*time.Second).ErrorBoundNS)
+	require.Equal(t, uint64(2), sc.at(10*time.Second).ErrorBoundNS)
+	// last step lasts forever
+	require.Equal(t, uint64(2), sc.at(time.Hour).ErrorBoundNS)
+
+	sc.Loop = true
+	require.Equal(t, uint64(1), sc.at(16*time.Second).ErrorBoundNS)
+	require.Equal(t, uint64(2), sc.at(26*time.Second).ErrorBoundNS)
+}
+
+func TestSimulatorDoWork(t *testing.T) {
+	st := stats.NewStats()
+	s := &Simulator{cfg: &Config{}, stats: st}
+	phcTime := time.Unix(1700000000, 0)
+	s.getPHCTime = func() (time.Time, error) { return phcTime, nil }
+
+	tmpFile, err := os.CreateTemp("", "simulator_test")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	shm, err := fbclock.OpenFBClockShmCustom(tmpFile.Name())
+	require.NoError(t, err)
+	defer shm.Close()
+	shmpData, err := fbclock.MmapShmpData(shm.File.Fd())
+	require.NoError(t, err)
+
+	// synchronized
+	err = s.doWork(shm, &SimulationStep{ErrorBoundNS: 100, HoldoverMultiplierNS: 2, OffsetNS: -3})
+	require.NoError(t, err)
+	got, err := fbclock.ReadFBClockData(shmpData)
+	require.NoError(t, err)
+	require.Equal(t, phcTime.UnixNano(), got.IngressTimeNS)
+	require.Equal(t, uint64(100), got.ErrorBoundNS)
+	require.InDelta(t, 2.0, got.HoldoverMultiplierNS, 0.001)
+	c := st.Get()
+	require.Equal(t, int64(100), c["wou_ns"])
+	require.Equal(t, int64(-3), c["master_offset_ns"])
+
+	// holdover, ingress time stays the same and WOU grows
+	phcTime = phcTime.Add(10 * time.Second)
+	err = s.doWork(shm, &SimulationStep{ErrorBoundNS: 100, HoldoverMultiplierNS: 2, Holdover: true})
+	require.NoError(t, err)
+	got, err = fbclock.ReadFBClockData(shmpData)
+	require.NoError(t, err)
+	require.Equal(t, phcTime.Add(-10*time.Second).UnixNano(), got.IngressTimeNS)
+	require.Equal(t, int64(120), st.Get()["wou_ns"])
+
+	// stale, nothing is written
+	err = s.doWork(shm, &SimulationStep{ErrorBoundNS: 42, Stale: true})
+	require.NoError(t, err)
+	got, err = fbclock.ReadFBClockData(shmpData)
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), got.ErrorBoundNS)
+	require.Equal(t, int64(2), st.Get()["shm_write"])
+}
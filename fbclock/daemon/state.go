@@ -20,7 +20,9 @@ import (
 	"container/ring"
 	"math"
 	"sync"
+	"time"
 
+	"github.com/facebook/time/fbclock"
 	"github.com/facebook/time/ptp/linearizability"
 )
 
@@ -30,15 +32,19 @@ type daemonState struct {
 
 	DataPoints                 *ring.Ring // DataPoints we collected from ptp4l
 	mmms                       *ring.Ring // M values we calculated
+	wous                       *ring.Ring // WOU values we published
 	linearizabilityTestResults *ring.Ring // linearizability test results
 
 	lastIngressTimeNS int64
+	lastData          *fbclock.Data // last data written to shm
+	lastWrite         time.Time     // when lastData was written
 }
 
 func newDaemonState(ringSize int) *daemonState {
 	s := &daemonState{
 		DataPoints:                 ring.New(ringSize),
 		mmms:                       ring.New(ringSize),
+		wous:                       ring.New(ringSize),
 		linearizabilityTestResults: ring.New(ringSize),
 	}
 	// init ring buffers with nils
@@ -49,6 +55,9 @@ func newDaemonState(ringSize int) *daemonState {
 		s.mmms.Value = nil
 		s.mmms = s.mmms.Next()
 
+		s.wous.Value = nil
+		s.wous = s.wous.Next()
+
 		s.linearizabilityTestResults.Value = nil
 		s.linearizabilityTestResults = s.linearizabilityTestResults.Next()
 	}
@@ -67,6 +76,19 @@ func (s *daemonState) ingressTimeNS() int64 {
 	return s.lastIngressTimeNS
 }
 
+func (s *daemonState) updateLastData(d *fbclock.Data, t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	s.lastData = d
+	s.lastWrite = t
+}
+
+func (s *daemonState) lastDataWritten() (*fbclock.Data, time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	return s.lastData, s.lastWrite
+}
+
 func (s *daemonState) pushDataPoint(data *DataPoint) {
 	s.Lock()
 	defer s.Unlock()
@@ -135,6 +157,28 @@ func (s *daemonState) takeM(n int) []float64 {
 	return result
 }
 
+func (s *daemonState) pushWOU(data float64) {
+	s.Lock()
+	defer s.Unlock()
+	s.wous.Value = data
+	s.wous = s.wous.Next()
+}
+
+func (s *daemonState) takeWOU(n int) []float64 {
+	s.Lock()
+	defer s.Unlock()
+	result := []float64{}
+	r := s.wous.Prev()
+	for j := 0; j < n; j++ {
+		if r.Value == nil {
+			continue
+		}
+		result = append(result, r.Value.(float64))
+		r = r.Prev()
+	}
+	return result
+}
+
 func (s *daemonState) pushLinearizabilityTestResult(data linearizability.TestResult) {
 	s.Lock()
 	defer s.Unlock()
@@ -0,0 +1,103 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"time"
+
+	"github.com/facebook/time/fbclock"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// stepDetector detects PHC steps by comparing how much PHC and CLOCK_MONOTONIC_RAW advanced between observations
+type stepDetector struct {
+	lastPHCNS  int64
+	lastMonoNS int64
+	// start of the step-free window the frequency difference between the clocks is estimated over
+	basePHCNS  int64
+	baseMonoNS int64
+}
+
+// observe returns size of the step since the previous observation, if it exceeds threshold.
+// Frequency difference between the clocks, estimated since the last step, is subtracted,
+// so drift accumulated over long gaps between observations isn't reported as a step.
+func (sd *stepDetector) observe(phcNS, monoNS int64, threshold time.Duration) (time.Duration, bool) {
+	defer func() {
+		sd.lastPHCNS = phcNS
+		sd.lastMonoNS = monoNS
+	}()
+	if sd.lastMonoNS == 0 {
+		sd.basePHCNS = phcNS
+		sd.baseMonoNS = monoNS
+		return 0, false
+	}
+	elapsed := monoNS - sd.lastMonoNS
+	step := time.Duration((phcNS - sd.lastPHCNS) - elapsed - sd.expectedDriftNS(elapsed))
+	if step.Abs() < threshold {
+		return 0, false
+	}
+	// frequency difference before the step says nothing about the offset after it
+	sd.basePHCNS = phcNS
+	sd.baseMonoNS = monoNS
+	return step, true
+}
+
+// expectedDriftNS returns how much PHC is expected to drift away from CLOCK_MONOTONIC_RAW over elapsed nanoseconds
+func (sd *stepDetector) expectedDriftNS(elapsed int64) int64 {
+	window := sd.lastMonoNS - sd.baseMonoNS
+	if window <= 0 {
+		return 0
+	}
+	drift := (sd.lastPHCNS - sd.basePHCNS) - window
+	return int64(float64(drift) / float64(window) * float64(elapsed))
+}
+
+func monotonicRawNS() (int64, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC_RAW, &ts); err != nil {
+		return 0, err
+	}
+	return ts.Nano(), nil
+}
+
+// detectStep publishes clock step event to shm if PHC was stepped since the last call
+func (s *Daemon) detectStep(shm *fbclock.Shm) error {
+	phcTime, err := s.getPHCTime()
+	if err != nil {
+		return err
+	}
+	monoNS, err := monotonicRawNS()
+	if err != nil {
+		return err
+	}
+	step, stepped := s.steps.observe(phcTime.UnixNano(), monoNS, s.cfg.StepThreshold)
+	if !stepped {
+		return nil
+	}
+	log.Warningf("PHC was stepped by %v", step)
+	s.stats.UpdateCounterBy("step_count", 1)
+	s.stats.SetCounter("step_ns", step.Nanoseconds())
+	if err := fbclock.StoreFBClockStep(shm.File.Fd(), step, phcTime); err != nil {
+		return err
+	}
+	if s.publisher != nil {
+		s.publisher.store(func(fd uintptr) error { return fbclock.StoreFBClockStep(fd, step, phcTime) })
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemon
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/facebook/time/fbclock"
+	"github.com/facebook/time/fbclock/stats"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepDetector(t *testing.T) {
+	sd := &stepDetector{}
+	phc := int64(1647359186979431900)
+	mono := int64(time.Hour)
+	// first observation is a baseline
+	_, stepped := sd.observe(phc, mono, time.Millisecond)
+	require.False(t, stepped)
+
+	// clocks advance at slightly different rate
+	phc += int64(time.Second + 50*time.Microsecond)
+	mono += int64(time.Second)
+	_, stepped = sd.observe(phc, mono, time.Millisecond)
+	require.False(t, stepped)
+
+	// step forward
+	phc += int64(time.Second + 50*time.Microsecond + 37*time.Second)
+	mono += int64(time.Second)
+	step, stepped := sd.observe(phc, mono, time.Millisecond)
+	require.True(t, stepped)
+	require.Equal(t, 37*time.Second, step)
+
+	// step back, same as threshold
+	phc += int64(time.Second - time.Millisecond)
+	mono += int64(time.Second)
+	step, stepped = sd.observe(phc, mono, time.Millisecond)
+	require.True(t, stepped)
+	require.Equal(t, -time.Millisecond, step)
+}
+
+func TestStepDetectorLongGap(t *testing.T) {
+	sd := &stepDetector{}
+	phc := int64(1647359186979431900)
+	mono := int64(time.Hour)
+	_, stepped := sd.observe(phc, mono, time.Millisecond)
+	require.False(t, stepped)
+
+	// PHC runs 50ppm faster than monotonic clock
+	for range 10 {
+		phc += int64(time.Second + 50*time.Microsecond)
+		mono += int64(time.Second)
+		_, stepped = sd.observe(phc, mono, time.Millisecond)
+		require.False(t, stepped)
+	}
+
+	// daemon was not scheduled for an hour, drift of 180ms is not a step
+	phc += int64(time.Hour + 180*time.Millisecond)
+	mono += int64(time.Hour)
+	_, stepped = sd.observe(phc, mono, time.Millisecond)
+	require.False(t, stepped)
+
+	// real step after another long gap is reported without the drift
+	phc += int64(time.Hour + 180*time.Millisecond - 5*time.Millisecond)
+	mono += int64(time.Hour)
+	step, stepped := sd.observe(phc, mono, time.Millisecond)
+	require.True(t, stepped)
+	require.Equal(t, -5*time.Millisecond, step)
+}
+
+func TestDaemonDetectStep(t *testing.T) {
+	cfg := &Config{StepThreshold: time.Millisecond}
+	st := stats.NewStats()
+	s := newTestDaemon(cfg, st)
+	phcTime := time.Unix(0, 1647359186979431900)
+	s.getPHCTime = func() (time.Time, error) { return phcTime, nil }
+
+	tmpFile, err := os.CreateTemp("", "step_test")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	shm, err := fbclock.OpenFBClockShmCustom(tmpFile.Name())
+	require.NoError(t, err)
+	defer shm.Close()
+	require.NoError(t, fbclock.StoreFBClockData(shm.File.Fd(), fbclock.Data{IngressTimeNS: 1, ErrorBoundNS: 2}))
+	shmpData, err := fbclock.MmapShmpData(shm.File.Fd())
+	require.NoError(t, err)
+
+	require.NoError(t, s.detectStep(shm))
+	// PHC jumps far ahead of monotonic clock
+	phcTime = phcTime.Add(time.Hour)
+	require.NoError(t, s.detectStep(shm))
+	step, err := fbclock.ReadFBClockStep(shmpData)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), step.Count)
+	require.InDelta(t, time.Hour, step.Size, float64(time.Second))
+	require.Equal(t, phcTime, step.Time)
+	require.Equal(t, int64(1), st.Get()["step_count"])
+}
@@ -24,6 +24,7 @@ limitations under the License.
 #include <sys/mman.h>
 #include <sys/stat.h>
 #include <sys/types.h>
+#include <time.h> // clock_gettime
 #include <unistd.h> // close
 #include "missing.h"
 
@@ -38,6 +39,8 @@ limitations under the License.
 
 #define FBCLOCK_CLOCKDATA_SIZE sizeof(fbclock_clockdata)
 #define FBCLOCK_MAX_READ_TRIES 1000
+// shortest sleep between truetime reads while waiting
+#define FBCLOCK_MIN_WAIT_INTERVAL_NS 1000
 #define NANOSECONDS_IN_SECONDS 1e9
 
 #ifdef __x86_64__
@@ -60,6 +63,12 @@ struct phc_time_res {
   int64_t delay; // mean delay of several requests
 };
 
+static inline uint64_t fbclock_monotonic_ns() {
+  struct timespec ts;
+  clock_gettime(CLOCK_MONOTONIC, &ts);
+  return (uint64_t)ts.tv_sec * NANOSECONDS_IN_SECONDS + ts.tv_nsec;
+}
+
 static inline uint64_t fbclock_clockdata_crc(fbclock_clockdata* value) {
   uint64_t counter = fbclock_crc64(0xFFFFFFFF, value->ingress_time_ns);
   counter = fbclock_crc64(counter, value->error_bound_ns);
@@ -67,6 +76,48 @@ static inline uint64_t fbclock_clockdata_crc(fbclock_clockdata* value) {
   return counter ^ 0xFFFFFFFF;
 }
 
+// fbclock_seqlock_write_begin tells readers update is in progress
+// by making the sequence odd. It stays odd if previous writer died mid-update.
+static inline uint64_t fbclock_seqlock_write_begin(fbclock_shmdata* shmp) {
+  uint64_t seq = atomic_load_explicit(&shmp->seq, memory_order_relaxed) | 1;
+  atomic_store_explicit(&shmp->seq, seq, memory_order_relaxed);
+  atomic_thread_fence(memory_order_release);
+  return seq;
+}
+
+// fbclock_seqlock_write_end publishes the update
+static inline void fbclock_seqlock_write_end(
+    fbclock_shmdata* shmp,
+    uint64_t seq) {
+  atomic_store_explicit(&shmp->seq, seq + 1, memory_order_release);
+}
+
+// fbclock_seqlock_read copies size bytes from src in shmem to dst,
+// retrying while writer is updating it.
+// Unchanged even sequence guarantees consistent data, crc is not needed.
+static int fbclock_seqlock_read(
+    fbclock_shmdata* shmp,
+    void* dst,
+    const void* src,
+    size_t size) {
+  for (int i = 0; i < FBCLOCK_MAX_READ_TRIES; i++) {
+    uint64_t seq = atomic_load_explicit(&shmp->seq, memory_order_acquire);
+    if (seq & 1) {
+      continue;
+    }
+    memcpy(dst, src, size);
+    atomic_thread_fence(memory_order_acquire);
+    if (atomic_load_explicit(&shmp->seq, memory_order_relaxed) != seq) {
+      continue;
+    }
+    fbclock_debug_print("reading shmem took %d tries\n", i + 1);
+    return FBCLOCK_E_NO_ERROR;
+  }
+  fbclock_debug_print(
+      "failed to read shmem after %d tries\n", FBCLOCK_MAX_READ_TRIES);
+  return FBCLOCK_E_CRC_MISMATCH;
+}
+
 int fbclock_clockdata_store_data(uint32_t fd, fbclock_clockdata* data) {
   fbclock_shmdata* shmp = mmap(
       NULL, FBCLOCK_SHMDATA_SIZE, PROT_READ | PROT_WRITE, MAP_SHARED, fd, 0);
@@ -74,15 +125,142 @@ int fbclock_clockdata_store_data(uint32_t fd, fbclock_clockdata* data) {
     return FBCLOCK_E_SHMEM_MAP_FAILED;
   }
   uint64_t crc = fbclock_clockdata_crc(data);
+  uint64_t seq = fbclock_seqlock_write_begin(shmp);
+  shmp->header.magic = FBCLOCK_SHM_MAGIC;
+  shmp->header.version = FBCLOCK_SHM_VERSION;
+  shmp->header.compat_version = FBCLOCK_SHM_COMPAT_VERSION;
   memcpy(&shmp->data, data, FBCLOCK_CLOCKDATA_SIZE);
   atomic_store(&shmp->crc, crc);
+  fbclock_seqlock_write_end(shmp, seq);
+  atomic_store(&shmp->heartbeat_ns, fbclock_monotonic_ns());
+  munmap(shmp, FBCLOCK_SHMDATA_SIZE);
+  return FBCLOCK_E_NO_ERROR;
+}
+
+// fbclock_shmdata_store_heartbeat tells readers daemon is alive
+// even if there is no new data
+int fbclock_shmdata_store_heartbeat(uint32_t fd) {
+  fbclock_shmdata* shmp = mmap(
+      NULL, FBCLOCK_SHMDATA_SIZE, PROT_READ | PROT_WRITE, MAP_SHARED, fd, 0);
+  if (shmp == MAP_FAILED) {
+    return FBCLOCK_E_SHMEM_MAP_FAILED;
+  }
+  // heartbeat is only meaningful with the header
+  if (shmp->header.magic == FBCLOCK_SHM_MAGIC) {
+    atomic_store(&shmp->heartbeat_ns, fbclock_monotonic_ns());
+  }
   munmap(shmp, FBCLOCK_SHMDATA_SIZE);
   return FBCLOCK_E_NO_ERROR;
 }
 
+// fbclock_shmdata_store_step records clock step of step_ns detected at
+// phctime_ns
+int fbclock_shmdata_store_step(
+    uint32_t fd,
+    int64_t step_ns,
+    int64_t phctime_ns) {
+  fbclock_shmdata* shmp = mmap(
+      NULL, FBCLOCK_SHMDATA_SIZE, PROT_READ | PROT_WRITE, MAP_SHARED, fd, 0);
+  if (shmp == MAP_FAILED) {
+    return FBCLOCK_E_SHMEM_MAP_FAILED;
+  }
+  // step is only meaningful with the header
+  if (shmp->header.magic == FBCLOCK_SHM_MAGIC) {
+    uint64_t seq = fbclock_seqlock_write_begin(shmp);
+    shmp->step.count++;
+    shmp->step.step_ns = step_ns;
+    shmp->step.phctime_ns = phctime_ns;
+    fbclock_seqlock_write_end(shmp, seq);
+  }
+  munmap(shmp, FBCLOCK_SHMDATA_SIZE);
+  return FBCLOCK_E_NO_ERROR;
+}
+
+int fbclock_shmdata_load_step(fbclock_shmdata* shmp, fbclock_step* step) {
+  int version = fbclock_shmdata_version(shmp);
+  if (version < 0) {
+    return FBCLOCK_E_SHMEM_VERSION;
+  }
+  if (version < 5) {
+    // writer doesn't detect steps
+    memset(step, 0, sizeof(fbclock_step));
+    return FBCLOCK_E_NO_ERROR;
+  }
+  return fbclock_seqlock_read(shmp, step, &shmp->step, sizeof(fbclock_step));
+}
+
+// fbclock_shmdata_version returns layout version of the data written to shm,
+// or FBCLOCK_E_SHMEM_VERSION if we can't read it.
+int fbclock_shmdata_version(fbclock_shmdata* shmp) {
+  fbclock_shmheader header;
+  memcpy(&header, &shmp->header, sizeof(header));
+  if (header.magic == 0) {
+    // written before the header was introduced
+    return 1;
+  }
+  if (header.magic != FBCLOCK_SHM_MAGIC) {
+    fbclock_debug_print("unexpected shmem magic 0x%x\n", header.magic);
+    return FBCLOCK_E_SHMEM_VERSION;
+  }
+  if (header.compat_version > FBCLOCK_SHM_VERSION) {
+    fbclock_debug_print(
+        "shmem layout version %d requires reader version %d, we support %d\n",
+        header.version,
+        header.compat_version,
+        FBCLOCK_SHM_VERSION);
+    return FBCLOCK_E_SHMEM_VERSION;
+  }
+  return header.version;
+}
+
+// fbclock_shmdata_age_ns returns how long ago the daemon wrote the data,
+// or -1 if the writer doesn't provide heartbeat.
+int64_t fbclock_shmdata_age_ns(fbclock_shmdata* shmp, uint64_t now_ns) {
+  if (fbclock_shmdata_version(shmp) < 3) {
+    return -1;
+  }
+  uint64_t heartbeat_ns = atomic_load(&shmp->heartbeat_ns);
+  if (heartbeat_ns == 0) {
+    return -1;
+  }
+  if (heartbeat_ns > now_ns) {
+    return 0;
+  }
+  return (int64_t)(now_ns - heartbeat_ns);
+}
+
+int fbclock_check_data_age(
+    fbclock_shmdata* shmp,
+    uint64_t max_age_ns,
+    uint64_t now_ns) {
+  if (max_age_ns == 0) {
+    return FBCLOCK_E_NO_ERROR;
+  }
+  int64_t age_ns = fbclock_shmdata_age_ns(shmp, now_ns);
+  // we can't tell with writers without heartbeat
+  if (age_ns < 0) {
+    return FBCLOCK_E_NO_ERROR;
+  }
+  if ((uint64_t)age_ns > max_age_ns) {
+    fbclock_debug_print(
+        "data is %ld ns old, max allowed %lu ns\n", age_ns, max_age_ns);
+    return FBCLOCK_E_DATA_STALE;
+  }
+  return FBCLOCK_E_NO_ERROR;
+}
+
 int fbclock_clockdata_load_data(
     fbclock_shmdata* shmp,
     fbclock_clockdata* data) {
+  int version = fbclock_shmdata_version(shmp);
+  if (version < 0) {
+    return FBCLOCK_E_SHMEM_VERSION;
+  }
+  if (version >= 4) {
+    // data written by version 4+ writers is guarded by seqlock
+    return fbclock_seqlock_read(
+        shmp, data, &shmp->data, FBCLOCK_CLOCKDATA_SIZE);
+  }
   for (int i = 0; i < FBCLOCK_MAX_READ_TRIES; i++) {
     memcpy(data, &shmp->data, FBCLOCK_CLOCKDATA_SIZE);
     uint64_t crc = atomic_load(&shmp->crc);
@@ -168,6 +346,7 @@ int fbclock_init(fbclock_lib* lib, const char* shm_path) {
   }
   lib->dev_fd = ffd;
   lib->min_phc_delay = INT64_MAX;
+  lib->max_data_age_ns = FBCLOCK_DEFAULT_MAX_DATA_AGE_NS;
   struct ptp_sys_offset_extended psoe = {.n_samples = 1};
 
   int r = ioctl(ffd, PTP_SYS_OFFSET_EXTENDED, &psoe);
@@ -183,6 +362,9 @@ int fbclock_init(fbclock_lib* lib, const char* shm_path) {
     return FBCLOCK_E_SHMEM_MAP_FAILED;
   }
   lib->shmp = shmp;
+  if (fbclock_shmdata_version(shmp) < 0) {
+    return FBCLOCK_E_SHMEM_VERSION;
+  }
   return FBCLOCK_E_NO_ERROR;
 }
 
@@ -194,6 +376,30 @@ int fbclock_destroy(fbclock_lib* lib) {
   // we don't want to unlink it, others might still use it
 }
 
+int fbclock_set_max_data_age(fbclock_lib* lib, uint64_t max_age_ns) {
+  __atomic_store_n(&lib->max_data_age_ns, max_age_ns, __ATOMIC_RELAXED);
+  return FBCLOCK_E_NO_ERROR;
+}
+
+// fbclock_update_min_phc_delay atomically stores the minimal PHC request delay
+static inline int64_t fbclock_update_min_phc_delay(
+    fbclock_lib* lib,
+    int64_t delay) {
+  int64_t min_delay = __atomic_load_n(&lib->min_phc_delay, __ATOMIC_RELAXED);
+  while (delay < min_delay) {
+    if (__atomic_compare_exchange_n(
+            &lib->min_phc_delay,
+            &min_delay,
+            delay,
+            1,
+            __ATOMIC_RELAXED,
+            __ATOMIC_RELAXED)) {
+      return delay;
+    }
+  }
+  return min_delay;
+}
+
 uint64_t fbclock_window_of_uncertainty(
     double seconds,
     uint64_t error_bound_ns,
@@ -235,40 +441,91 @@ int fbclock_calculate_time(
   return FBCLOCK_E_NO_ERROR;
 }
 
-int fbclock_gettime_tz(
+// fbclock_read_state loads clock data from shmem and reads PHC time
+static int fbclock_read_state(
     fbclock_lib* lib,
-    fbclock_truetime* truetime,
-    int timezone) {
+    fbclock_clockdata* state,
+    uint64_t* error_bound,
+    double* h_value,
+    int64_t* phctime_ns) {
   struct phc_time_res res;
-  fbclock_clockdata state = {};
-  int rcode = fbclock_clockdata_load_data(lib->shmp, &state);
+  int rcode = fbclock_clockdata_load_data(lib->shmp, state);
+  if (rcode != FBCLOCK_E_NO_ERROR) {
+    return rcode;
+  }
+  rcode = fbclock_check_data_age(
+      lib->shmp,
+      __atomic_load_n(&lib->max_data_age_ns, __ATOMIC_RELAXED),
+      fbclock_monotonic_ns());
   if (rcode != FBCLOCK_E_NO_ERROR) {
     return rcode;
   }
 
   // cannot determine Truetime without these values
-  if (state.error_bound_ns == 0 || state.ingress_time_ns == 0) {
+  if (state->error_bound_ns == 0 || state->ingress_time_ns == 0) {
     return FBCLOCK_E_NO_DATA;
   }
 
   // if the value is stored as UINT32_MAX then it's too big
-  if (state.error_bound_ns == UINT32_MAX ||
-      state.holdover_multiplier_ns == UINT32_MAX) {
+  if (state->error_bound_ns == UINT32_MAX ||
+      state->holdover_multiplier_ns == UINT32_MAX) {
     return FBCLOCK_E_WOU_TOO_BIG;
   }
 
   if (lib->gettime(lib->dev_fd, &res)) {
     return FBCLOCK_E_PTP_READ_OFFSET;
   }
-  // store the minimal PHC request delay
-  if (res.delay < lib->min_phc_delay) {
-    lib->min_phc_delay = res.delay;
+  *error_bound =
+      state->error_bound_ns + fbclock_update_min_phc_delay(lib, res.delay);
+  *h_value = (double)state->holdover_multiplier_ns / FBCLOCK_POW2_16;
+  *phctime_ns = res.ts;
+  return FBCLOCK_E_NO_ERROR;
+}
+
+int fbclock_gettime_tz(
+    fbclock_lib* lib,
+    fbclock_truetime* truetime,
+    int timezone) {
+  fbclock_clockdata state = {};
+  uint64_t error_bound;
+  double h_value;
+  int64_t phctime_ns;
+  int rcode =
+      fbclock_read_state(lib, &state, &error_bound, &h_value, &phctime_ns);
+  if (rcode != FBCLOCK_E_NO_ERROR) {
+    return rcode;
   }
-  uint64_t error_bound = state.error_bound_ns + lib->min_phc_delay;
-  double h_value = (double)state.holdover_multiplier_ns / FBCLOCK_POW2_16;
 
   return fbclock_calculate_time(
-      error_bound, h_value, &state, res.ts, truetime, timezone);
+      error_bound, h_value, &state, phctime_ns, truetime, timezone);
+}
+
+int fbclock_gettime_pair(fbclock_lib* lib, fbclock_truetime_pair* pair) {
+  fbclock_clockdata state = {};
+  uint64_t error_bound;
+  double h_value;
+  int64_t phctime_ns;
+  int rcode =
+      fbclock_read_state(lib, &state, &error_bound, &h_value, &phctime_ns);
+  if (rcode != FBCLOCK_E_NO_ERROR) {
+    return rcode;
+  }
+
+  rcode = fbclock_calculate_time(
+      error_bound, h_value, &state, phctime_ns, &pair->tai, FBCLOCK_TAI);
+  if (rcode != FBCLOCK_E_NO_ERROR) {
+    return rcode;
+  }
+  rcode = fbclock_calculate_time(
+      error_bound, h_value, &state, phctime_ns, &pair->utc, FBCLOCK_UTC);
+  if (rcode != FBCLOCK_E_NO_ERROR) {
+    return rcode;
+  }
+  pair->leap_indicator = fbclock_leap_indicator(&state, phctime_ns);
+  pair->leap_s = state.utc_offset_post_s - state.utc_offset_pre_s;
+  pair->smearing_start_ns = state.clock_smearing_start_s * 1e9;
+  pair->smearing_end_ns = state.clock_smearing_end_s * 1e9;
+  return FBCLOCK_E_NO_ERROR;
 }
 
 int fbclock_gettime(fbclock_lib* lib, fbclock_truetime* truetime) {
@@ -279,6 +536,119 @@ int fbclock_gettime_utc(fbclock_lib* lib, fbclock_truetime* truetime) {
   return fbclock_gettime_tz(lib, truetime, FBCLOCK_UTC);
 }
 
+int fbclock_monotonic_mapping(fbclock_lib* lib, fbclock_monotonic_map* map) {
+  fbclock_truetime truetime;
+  uint64_t before_ns = fbclock_monotonic_ns();
+  int rcode = fbclock_gettime(lib, &truetime);
+  uint64_t after_ns = fbclock_monotonic_ns();
+  if (rcode != FBCLOCK_E_NO_ERROR) {
+    return rcode;
+  }
+  // PHC was read somewhere in between, account for that
+  uint64_t half_ns = (after_ns - before_ns + 1) / 2;
+  map->monotonic_ns = (int64_t)(before_ns + half_ns);
+  map->truetime.earliest_ns = truetime.earliest_ns - half_ns;
+  map->truetime.latest_ns = truetime.latest_ns + half_ns;
+  map->drift_ppb = FBCLOCK_MONOTONIC_MAX_DRIFT_PPB;
+  return FBCLOCK_E_NO_ERROR;
+}
+
+int fbclock_monotonic_to_truetime(
+    const fbclock_monotonic_map* map,
+    int64_t monotonic_ns,
+    fbclock_truetime* truetime) {
+  int64_t distance_ns = monotonic_ns - map->monotonic_ns;
+  uint64_t abs_distance_ns =
+      distance_ns < 0 ? (uint64_t)-distance_ns : (uint64_t)distance_ns;
+  // round up, bounds must not get tighter
+  uint64_t drift_ns =
+      (uint64_t)((abs_distance_ns * (double)map->drift_ppb + 999999999) / 1e9);
+  if ((int64_t)map->truetime.earliest_ns + distance_ns < (int64_t)drift_ns) {
+    return FBCLOCK_E_PHC_IN_THE_PAST;
+  }
+  truetime->earliest_ns = map->truetime.earliest_ns + distance_ns - drift_ns;
+  truetime->latest_ns = map->truetime.latest_ns + distance_ns + drift_ns;
+  return FBCLOCK_E_NO_ERROR;
+}
+
+int fbclock_last_step(fbclock_lib* lib, fbclock_step* step) {
+  return fbclock_shmdata_load_step(lib->shmp, step);
+}
+
+int fbclock_data_age(fbclock_lib* lib, int64_t* age_ns) {
+  if (fbclock_shmdata_version(lib->shmp) < 0) {
+    return FBCLOCK_E_SHMEM_VERSION;
+  }
+  *age_ns = fbclock_shmdata_age_ns(lib->shmp, fbclock_monotonic_ns());
+  return FBCLOCK_E_NO_ERROR;
+}
+
+// fbclock_wait_interval_ns returns how long to sleep before earliest bound of
+// truetime can pass target_ns, or 0 if it already did.
+// Earliest moves roughly as fast as the clock, so it can't pass target sooner.
+uint64_t fbclock_wait_interval_ns(
+    fbclock_truetime* truetime,
+    uint64_t target_ns) {
+  if (truetime->earliest_ns > target_ns) {
+    return 0;
+  }
+  uint64_t wait_ns = target_ns - truetime->earliest_ns;
+  if (wait_ns < FBCLOCK_MIN_WAIT_INTERVAL_NS) {
+    wait_ns = FBCLOCK_MIN_WAIT_INTERVAL_NS;
+  }
+  return wait_ns;
+}
+
+int fbclock_wait_until_after(
+    fbclock_lib* lib,
+    uint64_t target_ns,
+    uint64_t timeout_ns,
+    fbclock_truetime* truetime) {
+  uint64_t deadline_ns = fbclock_monotonic_ns() + timeout_ns;
+  for (;;) {
+    int rcode = fbclock_gettime(lib, truetime);
+    if (rcode != FBCLOCK_E_NO_ERROR) {
+      return rcode;
+    }
+    uint64_t wait_ns = fbclock_wait_interval_ns(truetime, target_ns);
+    if (wait_ns == 0) {
+      return FBCLOCK_E_NO_ERROR;
+    }
+    if (timeout_ns != 0) {
+      uint64_t now_ns = fbclock_monotonic_ns();
+      if (now_ns >= deadline_ns) {
+        return FBCLOCK_E_TIMEOUT;
+      }
+      if (now_ns + wait_ns > deadline_ns) {
+        wait_ns = deadline_ns - now_ns;
+      }
+    }
+    struct timespec ts = {
+        .tv_sec = wait_ns / (uint64_t)NANOSECONDS_IN_SECONDS,
+        .tv_nsec = wait_ns % (uint64_t)NANOSECONDS_IN_SECONDS,
+    };
+    clock_nanosleep(CLOCK_MONOTONIC, 0, &ts, NULL);
+  }
+}
+
+int fbclock_commit_wait(
+    fbclock_lib* lib,
+    uint64_t timeout_ns,
+    uint64_t* commit_ns) {
+  fbclock_truetime truetime;
+  int rcode = fbclock_gettime(lib, &truetime);
+  if (rcode != FBCLOCK_E_NO_ERROR) {
+    return rcode;
+  }
+  uint64_t latest_ns = truetime.latest_ns;
+  rcode = fbclock_wait_until_after(lib, latest_ns, timeout_ns, &truetime);
+  if (rcode != FBCLOCK_E_NO_ERROR) {
+    return rcode;
+  }
+  *commit_ns = latest_ns;
+  return FBCLOCK_E_NO_ERROR;
+}
+
 uint64_t fbclock_apply_smear(
     uint64_t time,
     uint64_t offset_pre_ns,
@@ -333,6 +703,22 @@ uint64_t fbclock_apply_utc_offset(
       multiplier);
 }
 
+int fbclock_leap_indicator(fbclock_clockdata* state, int64_t phctime_ns) {
+  // no tzdata information in shared memory, or no offset change
+  if (state->utc_offset_pre_s == state->utc_offset_post_s) {
+    return FBCLOCK_LEAP_NONE;
+  }
+  uint64_t smear_end_ns = state->clock_smearing_end_s * 1e9;
+  uint64_t smear_start_ns = state->clock_smearing_start_s * 1e9;
+  if ((uint64_t)phctime_ns > smear_end_ns) {
+    return FBCLOCK_LEAP_NONE;
+  }
+  if ((uint64_t)phctime_ns < smear_start_ns) {
+    return FBCLOCK_LEAP_PENDING;
+  }
+  return FBCLOCK_LEAP_SMEARING;
+}
+
 const char* fbclock_strerror(int err_code) {
   const char* err_info = "unknown error";
   switch (err_code) {
@@ -360,6 +746,15 @@ const char* fbclock_strerror(int err_code) {
     case FBCLOCK_E_CRC_MISMATCH:
       err_info = "CRC check failed all tries";
       break;
+    case FBCLOCK_E_SHMEM_VERSION:
+      err_info = "unsupported shmem layout version";
+      break;
+    case FBCLOCK_E_DATA_STALE:
+      err_info = "data in shmem is stale, daemon is not running";
+      break;
+    case FBCLOCK_E_TIMEOUT:
+      err_info = "timed out waiting";
+      break;
     case FBCLOCK_E_NO_ERROR:
       err_info = "no error";
       break;
@@ -28,22 +28,124 @@ package fbclock
 import "C"
 
 import (
+	"errors"
 	"fmt"
 	"time"
 	"unsafe"
+
+	"golang.org/x/sys/unix"
 )
 
+// ErrDataStale means fbclock daemon didn't update shared memory for longer than max data age
+var ErrDataStale = errors.New(C.GoString(C.fbclock_strerror(C.FBCLOCK_E_DATA_STALE)))
+
 func strerror(errCode C.int) string {
 	cStr := C.fbclock_strerror(errCode)
 	return C.GoString(cStr)
 }
 
+// toError turns fbclock error code into error
+func toError(errCode C.int) error {
+	if errCode == C.FBCLOCK_E_DATA_STALE {
+		return ErrDataStale
+	}
+	return errors.New(strerror(errCode))
+}
+
 // TrueTime is a time interval we are confident the clock is right now
 type TrueTime struct {
 	Earliest time.Time
 	Latest   time.Time
 }
 
+// LeapIndicator tells if leap second event affects UTC
+type LeapIndicator int
+
+// Leap second indicator values
+const (
+	LeapNone     LeapIndicator = C.FBCLOCK_LEAP_NONE
+	LeapPending  LeapIndicator = C.FBCLOCK_LEAP_PENDING
+	LeapSmearing LeapIndicator = C.FBCLOCK_LEAP_SMEARING
+)
+
+var leapIndicatorToString = map[LeapIndicator]string{
+	LeapNone:     "NONE",
+	LeapPending:  "PENDING",
+	LeapSmearing: "SMEARING",
+}
+
+func (l LeapIndicator) String() string {
+	if s, ok := leapIndicatorToString[l]; ok {
+		return s
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", int(l))
+}
+
+// TrueTimePair is TAI and UTC TrueTime based on the same PHC reading, with leap second information
+type TrueTimePair struct {
+	TAI  TrueTime
+	UTC  TrueTime
+	Leap LeapIndicator
+	// LeapSeconds is the UTC-TAI offset change, 1 or -1. Only meaningful if Leap is not LeapNone
+	LeapSeconds   int
+	SmearingStart time.Time // TAI
+	SmearingEnd   time.Time // TAI
+}
+
+// Step is the last clock step detected by fbclock daemon.
+// Consumers relying on clock continuity should be invalidated when Count changes.
+type Step struct {
+	Count uint64        // number of steps detected
+	Size  time.Duration // size of the last step
+	Time  time.Time     // PHC time when the last step was detected
+}
+
+func stepFromC(s *C.fbclock_step) *Step {
+	step := &Step{Count: uint64(s.count), Size: time.Duration(s.step_ns)}
+	if s.count > 0 {
+		step.Time = time.Unix(0, int64(s.phctime_ns))
+	}
+	return step
+}
+
+// MonotonicMapping maps CLOCK_MONOTONIC reading to TAI TrueTime
+type MonotonicMapping struct {
+	Monotonic time.Duration // CLOCK_MONOTONIC reading
+	TrueTime  TrueTime      // TAI TrueTime at Monotonic
+	// DriftPPB is max rate difference between CLOCK_MONOTONIC and true time,
+	// used to widen bounds when converting other CLOCK_MONOTONIC readings
+	DriftPPB uint64
+}
+
+// At converts CLOCK_MONOTONIC reading, like one from MonotonicNow, to TAI TrueTime
+func (m *MonotonicMapping) At(monotonic time.Duration) (*TrueTime, error) {
+	cMap := &C.fbclock_monotonic_map{
+		monotonic_ns: C.int64_t(m.Monotonic.Nanoseconds()),
+		truetime: C.fbclock_truetime{
+			earliest_ns: C.uint64_t(m.TrueTime.Earliest.UnixNano()),
+			latest_ns:   C.uint64_t(m.TrueTime.Latest.UnixNano()),
+		},
+		drift_ppb: C.uint64_t(m.DriftPPB),
+	}
+	tt := &C.fbclock_truetime{}
+	errCode := C.fbclock_monotonic_to_truetime(cMap, C.int64_t(monotonic.Nanoseconds()), tt)
+	if errCode != 0 {
+		return nil, fmt.Errorf("converting monotonic time: %w", toError(errCode))
+	}
+	return &TrueTime{
+		Earliest: time.Unix(0, int64(tt.earliest_ns)),
+		Latest:   time.Unix(0, int64(tt.latest_ns)),
+	}, nil
+}
+
+// MonotonicNow returns current CLOCK_MONOTONIC reading
+func MonotonicNow() time.Duration {
+	var ts unix.Timespec
+	// CLOCK_MONOTONIC is always supported
+	_ = unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts)
+	return time.Duration(ts.Nano())
+}
+
 // FBClock wraps around fbclock C lib
 type FBClock struct {
 	cFBClock *C.fbclock_lib
@@ -66,6 +168,52 @@ func NewFBClock() (*FBClock, error) {
 	return NewFBClockCustom(C.FBCLOCK_PATH)
 }
 
+// SetMaxDataAge sets how old data in shared memory can be before reads fail with ErrDataStale.
+// Zero disables the check.
+func (f *FBClock) SetMaxDataAge(d time.Duration) {
+	C.fbclock_set_max_data_age(f.cFBClock, C.uint64_t(d.Nanoseconds()))
+}
+
+// DataAge returns how long ago fbclock daemon updated shared memory, or -1 if the daemon doesn't report it
+func (f *FBClock) DataAge() (time.Duration, error) {
+	var ageNS C.int64_t
+	errCode := C.fbclock_data_age(f.cFBClock, &ageNS)
+	if errCode != 0 {
+		return 0, fmt.Errorf("reading data age: %w", toError(errCode))
+	}
+	if ageNS < 0 {
+		return -1, nil
+	}
+	return time.Duration(ageNS), nil
+}
+
+// GetMonotonicMapping returns mapping between current CLOCK_MONOTONIC reading and TAI TrueTime
+func (f *FBClock) GetMonotonicMapping() (*MonotonicMapping, error) {
+	cMap := &C.fbclock_monotonic_map{}
+	errCode := C.fbclock_monotonic_mapping(f.cFBClock, cMap)
+	if errCode != 0 {
+		return nil, fmt.Errorf("reading FBClock monotonic mapping: %w", toError(errCode))
+	}
+	return &MonotonicMapping{
+		Monotonic: time.Duration(cMap.monotonic_ns),
+		TrueTime: TrueTime{
+			Earliest: time.Unix(0, int64(cMap.truetime.earliest_ns)),
+			Latest:   time.Unix(0, int64(cMap.truetime.latest_ns)),
+		},
+		DriftPPB: uint64(cMap.drift_ppb),
+	}, nil
+}
+
+// LastStep returns the last clock step detected by fbclock daemon
+func (f *FBClock) LastStep() (*Step, error) {
+	cStep := &C.fbclock_step{}
+	errCode := C.fbclock_last_step(f.cFBClock, cStep)
+	if errCode != 0 {
+		return nil, fmt.Errorf("reading last step: %w", toError(errCode))
+	}
+	return stepFromC(cStep), nil
+}
+
 // Close destroys fbclock wrapper
 func (f *FBClock) Close() error {
 	errCode := C.fbclock_destroy(f.cFBClock)
@@ -80,7 +228,7 @@ func (f *FBClock) GetTime() (*TrueTime, error) {
 	tt := &C.fbclock_truetime{}
 	errCode := C.fbclock_gettime(f.cFBClock, tt)
 	if errCode != 0 {
-		return nil, fmt.Errorf("reading FBClock TrueTime: %s", strerror(errCode))
+		return nil, fmt.Errorf("reading FBClock TrueTime: %w", toError(errCode))
 	}
 
 	earliest := time.Unix(0, int64(tt.earliest_ns))
@@ -94,7 +242,7 @@ func (f *FBClock) GetTimeUTC() (*TrueTime, error) {
 	tt := &C.fbclock_truetime{}
 	errCode := C.fbclock_gettime_utc(f.cFBClock, tt)
 	if errCode != 0 {
-		return nil, fmt.Errorf("reading FBClock TrueTime UTC: %s", strerror(errCode))
+		return nil, fmt.Errorf("reading FBClock TrueTime UTC: %w", toError(errCode))
 	}
 
 	earliest := time.Unix(0, int64(tt.earliest_ns))
@@ -102,3 +250,21 @@ func (f *FBClock) GetTimeUTC() (*TrueTime, error) {
 
 	return &TrueTime{Earliest: earliest, Latest: latest}, nil
 }
+
+// GetTimePair returns both TAI and UTC TrueTime with leap second indicator
+func (f *FBClock) GetTimePair() (*TrueTimePair, error) {
+	p := &C.fbclock_truetime_pair{}
+	errCode := C.fbclock_gettime_pair(f.cFBClock, p)
+	if errCode != 0 {
+		return nil, fmt.Errorf("reading FBClock TrueTime pair: %w", toError(errCode))
+	}
+
+	return &TrueTimePair{
+		TAI:           TrueTime{Earliest: time.Unix(0, int64(p.tai.earliest_ns)), Latest: time.Unix(0, int64(p.tai.latest_ns))},
+		UTC:           TrueTime{Earliest: time.Unix(0, int64(p.utc.earliest_ns)), Latest: time.Unix(0, int64(p.utc.latest_ns))},
+		Leap:          LeapIndicator(p.leap_indicator),
+		LeapSeconds:   int(p.leap_s),
+		SmearingStart: time.Unix(0, int64(p.smearing_start_ns)),
+		SmearingEnd:   time.Unix(0, int64(p.smearing_end_ns)),
+	}, nil
+}
@@ -24,6 +24,7 @@ typedef std::atomic_uint_fast64_t atomic_uint64;
 typedef atomic_uint_fast64_t atomic_uint64;
 #endif
 
+#include <stddef.h> /* for offsetof */
 #include <stdint.h> /* for proper fixed width types */
 
 // error codes
@@ -36,6 +37,9 @@ typedef atomic_uint_fast64_t atomic_uint64;
 #define FBCLOCK_E_WOU_TOO_BIG -6
 #define FBCLOCK_E_PHC_IN_THE_PAST -7
 #define FBCLOCK_E_CRC_MISMATCH -8
+#define FBCLOCK_E_SHMEM_VERSION -9
+#define FBCLOCK_E_DATA_STALE -10
+#define FBCLOCK_E_TIMEOUT -11
 
 // Fixed UTC-TAI offset - used when data not present in shared memory
 #define UTC_TAI_OFFSET_NS (int64_t)(-37e9)
@@ -67,16 +71,60 @@ typedef struct fbclock_clockdata {
 
 } fbclock_clockdata;
 
-// fbclock shared memory object
+// fbclock shared memory layout version.
+// FBCLOCK_SHM_VERSION is bumped every time fields are appended to the layout.
+// FBCLOCK_SHM_COMPAT_VERSION is the oldest layout version a reader has to
+// support to interpret the data correctly. It's only bumped when meaning or
+// position of existing fields change, so old readers refuse such data instead
+// of misinterpreting it.
+#define FBCLOCK_SHM_MAGIC 0xfbc10c00
+#define FBCLOCK_SHM_VERSION 5
+#define FBCLOCK_SHM_COMPAT_VERSION 1
+
+// fbclock shared memory layout header.
+// Writers prior to version 2 didn't have it, zero magic means version 1.
+typedef struct fbclock_shmheader {
+  uint32_t magic;
+  // layout version of the writer
+  uint16_t version;
+  // minimal layout version reader must support
+  uint16_t compat_version;
+} fbclock_shmheader;
+
+// clock steps detected by the daemon
+typedef struct fbclock_step {
+  // number of steps detected, changes every time the clock is stepped
+  uint64_t count;
+  // size of the last step
+  int64_t step_ns;
+  // PHC time when the last step was detected
+  int64_t phctime_ns;
+} fbclock_step;
+
+// fbclock shared memory object.
+// Header follows the data to keep the layout readable by version 1 readers,
+// new fields must be appended after the header.
 typedef struct fbclock_shmdata {
   atomic_uint64 crc;
   fbclock_clockdata data;
+  fbclock_shmheader header;
+  // CLOCK_MONOTONIC time of the last write by the daemon, since version 3
+  atomic_uint64 heartbeat_ns;
+  // seqlock sequence, odd while the writer is updating data, since version 4.
+  // Readers of older layouts rely on crc alone.
+  atomic_uint64 seq;
+  // last clock step, since version 5, guarded by seq
+  fbclock_step step;
 } fbclock_shmdata;
 
 #define FBCLOCK_SHMDATA_SIZE sizeof(fbclock_shmdata)
+// size of version 1 layout, without the header
+#define FBCLOCK_SHMDATA_V1_SIZE offsetof(fbclock_shmdata, header)
 #define FBCLOCK_PATH "/run/fbclock_data_v1"
 #define FBCLOCK_POW2_16 ((double)(1ULL << 16))
 #define FBCLOCK_PTPPATH "/dev/fbclock/ptp"
+// default max age of data in shmem before it's considered stale
+#define FBCLOCK_DEFAULT_MAX_DATA_AGE_NS (uint64_t)(60e9)
 
 // supported time standards
 #define FBCLOCK_TAI 0
@@ -88,18 +136,74 @@ typedef struct fbclock_truetime {
   uint64_t latest_ns;
 } fbclock_truetime;
 
-// fbclock library
+// default max rate difference between CLOCK_MONOTONIC and true time,
+// kernel doesn't slew system clock faster than 500ppm
+#define FBCLOCK_MONOTONIC_MAX_DRIFT_PPB 500000
+
+// mapping between CLOCK_MONOTONIC and truetime
+typedef struct fbclock_monotonic_map {
+  // CLOCK_MONOTONIC reading truetime corresponds to
+  int64_t monotonic_ns;
+  // TAI truetime at monotonic_ns, widened by how long the PHC read took
+  fbclock_truetime truetime;
+  // max rate difference between CLOCK_MONOTONIC and true time,
+  // used to widen bounds when converting other CLOCK_MONOTONIC readings
+  uint64_t drift_ppb;
+} fbclock_monotonic_map;
+
+// leap second indicator
+#define FBCLOCK_LEAP_NONE 0 // no leap second event announced
+#define FBCLOCK_LEAP_PENDING 1 // leap second event announced, smearing not started
+#define FBCLOCK_LEAP_SMEARING 2 // UTC is being smeared right now
+
+// response to fbclock_gettime_pair request
+typedef struct fbclock_truetime_pair {
+  // both windows are based on the same PHC reading
+  fbclock_truetime tai;
+  fbclock_truetime utc;
+  // one of FBCLOCK_LEAP_*
+  int leap_indicator;
+  // UTC-TAI offset change by the leap second event, 1 or -1
+  int leap_s;
+  // smearing window (TAI) of the leap second event
+  uint64_t smearing_start_ns;
+  uint64_t smearing_end_ns;
+} fbclock_truetime_pair;
+
+// fbclock library.
+// Reading time (fbclock_gettime*, fbclock_wait_until_after,
+// fbclock_commit_wait, fbclock_data_age, fbclock_last_step) is lock-free and
+// safe to do concurrently from multiple threads sharing the same fbclock_lib:
+// data is read from shmem under seqlock, and the only state we update is
+// min_phc_delay, which is done atomically. fbclock_init and fbclock_destroy
+// must not race with anything else.
 typedef struct fbclock_lib {
   char* ptp_path; // path to PHC clock device
   int shm_fd; // file descriptor of opened shared memory object
   int dev_fd; // file descriptor of opened /dev/ptpN
   int64_t min_phc_delay; // minimal PHC request delay observed
   fbclock_shmdata* shmp; // mmap-ed data
+  uint64_t max_data_age_ns; // max age of data in shmem, 0 means no check
   int (*gettime)(int, struct phc_time_res*); // pointer to gettime function
 } fbclock_lib;
 
 int fbclock_clockdata_store_data(uint32_t fd, fbclock_clockdata* data);
+int fbclock_shmdata_store_heartbeat(uint32_t fd);
+int fbclock_shmdata_store_step(
+    uint32_t fd,
+    int64_t step_ns,
+    int64_t phctime_ns);
+int fbclock_shmdata_load_step(fbclock_shmdata* shm, fbclock_step* step);
 int fbclock_clockdata_load_data(fbclock_shmdata* shm, fbclock_clockdata* data);
+int fbclock_shmdata_version(fbclock_shmdata* shm);
+int64_t fbclock_shmdata_age_ns(fbclock_shmdata* shm, uint64_t now_ns);
+int fbclock_check_data_age(
+    fbclock_shmdata* shm,
+    uint64_t max_age_ns,
+    uint64_t now_ns);
+uint64_t fbclock_wait_interval_ns(
+    fbclock_truetime* truetime,
+    uint64_t target_ns);
 uint64_t fbclock_window_of_uncertainty(
     double seconds,
     uint64_t error_bound_ns,
@@ -112,6 +216,7 @@ int fbclock_calculate_time(
     fbclock_truetime* truetime,
     int timezone);
 uint64_t fbclock_apply_utc_offset(fbclock_clockdata* state, int64_t phctime_ns);
+int fbclock_leap_indicator(fbclock_clockdata* state, int64_t phctime_ns);
 uint64_t fbclock_apply_smear(
     uint64_t time,
     uint64_t offset_pre_ns,
@@ -127,8 +232,42 @@ int fbclock_gettime_tz(
 // methods we provide to end users
 int fbclock_init(fbclock_lib* lib, const char* shm_path);
 int fbclock_destroy(fbclock_lib* lib);
+int fbclock_set_max_data_age(fbclock_lib* lib, uint64_t max_age_ns);
 int fbclock_gettime(fbclock_lib* lib, fbclock_truetime* truetime);
 int fbclock_gettime_utc(fbclock_lib* lib, fbclock_truetime* truetime);
+int fbclock_gettime_pair(fbclock_lib* lib, fbclock_truetime_pair* pair);
+// fbclock_monotonic_mapping maps current CLOCK_MONOTONIC reading to TAI
+// truetime. Events can be timestamped with cheap CLOCK_MONOTONIC reads and
+// converted with fbclock_monotonic_to_truetime later, unaffected by clock
+// steps.
+int fbclock_monotonic_mapping(fbclock_lib* lib, fbclock_monotonic_map* map);
+// fbclock_monotonic_to_truetime converts CLOCK_MONOTONIC reading to TAI
+// truetime using the mapping, widening bounds by drift_ppb of the distance
+int fbclock_monotonic_to_truetime(
+    const fbclock_monotonic_map* map,
+    int64_t monotonic_ns,
+    fbclock_truetime* truetime);
+// fbclock_last_step returns the last clock step detected by the daemon.
+// Consumers relying on clock continuity, like caches of monotonic mappings,
+// should be invalidated when count changes.
+int fbclock_last_step(fbclock_lib* lib, fbclock_step* step);
+// fbclock_data_age returns how long ago daemon updated shmem, -1 if unknown
+int fbclock_data_age(fbclock_lib* lib, int64_t* age_ns);
+// fbclock_wait_until_after blocks until earliest bound of TAI truetime is
+// after target_ns, meaning target_ns is guaranteed to be in the past.
+// Truetime which satisfied the condition is returned in truetime.
+// timeout_ns of 0 means wait for as long as needed.
+int fbclock_wait_until_after(
+    fbclock_lib* lib,
+    uint64_t target_ns,
+    uint64_t timeout_ns,
+    fbclock_truetime* truetime);
+// fbclock_commit_wait picks latest bound of current TAI truetime as commit
+// timestamp and blocks until it's guaranteed to be in the past everywhere.
+int fbclock_commit_wait(
+    fbclock_lib* lib,
+    uint64_t timeout_ns,
+    uint64_t* commit_ns);
 
 // turn error code into err msg
 const char* fbclock_strerror(int err_code);
@@ -0,0 +1,189 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package rpc implements local JSON-RPC API of fbclock daemon over unix socket.
+It's an alternative to reading fbclock shared memory for consumers that can't mount it,
+like some containers, or can't use the C library. The package doesn't require cgo.
+*/
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultSocketPath is the default path of fbclock daemon unix socket
+const DefaultSocketPath = "/var/run/fbclock.sock"
+
+// ServiceName is the name methods are registered under, like "FBClock.GetTime"
+const ServiceName = "FBClock"
+
+// Source provides TrueTime, implemented by fbclock C library
+type Source interface {
+	// GetTime returns earliest and latest TAI time
+	GetTime() (earliest, latest time.Time, err error)
+	// GetTimeUTC returns earliest and latest UTC time
+	GetTimeUTC() (earliest, latest time.Time, err error)
+}
+
+// Request is an argument of all FBClock methods
+type Request struct {
+	// UTC requests UTC instead of TAI
+	UTC bool
+}
+
+// TimeResponse is the result of FBClock.GetTime
+type TimeResponse struct {
+	EarliestNS int64
+	LatestNS   int64
+}
+
+// Earliest returns earliest time as time.Time
+func (r *TimeResponse) Earliest() time.Time {
+	return time.Unix(0, r.EarliestNS)
+}
+
+// Latest returns latest time as time.Time
+func (r *TimeResponse) Latest() time.Time {
+	return time.Unix(0, r.LatestNS)
+}
+
+// ErrorResponse is the result of FBClock.GetError
+type ErrorResponse struct {
+	// WOUNS is the window of uncertainty in nanoseconds, the current time is within now±WOUNS
+	WOUNS int64
+}
+
+// Service serves TrueTime from Source
+type Service struct {
+	source Source
+}
+
+// NewService returns new Service
+func NewService(source Source) *Service {
+	return &Service{source: source}
+}
+
+func (s *Service) get(utc bool) (earliest, latest time.Time, err error) {
+	if utc {
+		return s.source.GetTimeUTC()
+	}
+	return s.source.GetTime()
+}
+
+// GetTime returns TrueTime
+func (s *Service) GetTime(req *Request, resp *TimeResponse) error {
+	earliest, latest, err := s.get(req.UTC)
+	if err != nil {
+		return err
+	}
+	resp.EarliestNS = earliest.UnixNano()
+	resp.LatestNS = latest.UnixNano()
+	return nil
+}
+
+// GetError returns the current window of uncertainty
+func (s *Service) GetError(req *Request, resp *ErrorResponse) error {
+	earliest, latest, err := s.get(req.UTC)
+	if err != nil {
+		return err
+	}
+	resp.WOUNS = int64(latest.Sub(earliest) / 2)
+	return nil
+}
+
+// Serve serves Service on unix socket at path until ctx is cancelled.
+// Socket is accessible by all users, same as fbclock shared memory.
+func Serve(ctx context.Context, path string, service *Service) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName(ServiceName, service); err != nil {
+		return err
+	}
+	// clean up socket left after the previous run
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing %s: %w", path, err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", path, err)
+	}
+	defer os.Remove(path)
+	if err := os.Chmod(path, 0666); err != nil {
+		l.Close()
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("accepting connection on %s: %w", path, err)
+		}
+		log.Debugf("serving fbclock RPC connection")
+		go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// Client talks to fbclock daemon over unix socket
+type Client struct {
+	c *rpc.Client
+}
+
+// Dial connects to fbclock daemon unix socket at path
+func Dial(path string) (*Client, error) {
+	c, err := jsonrpc.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{c: c}, nil
+}
+
+// GetTime returns TrueTime, either TAI or UTC
+func (c *Client) GetTime(utc bool) (*TimeResponse, error) {
+	resp := &TimeResponse{}
+	if err := c.c.Call(ServiceName+".GetTime", &Request{UTC: utc}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetError returns the current window of uncertainty
+func (c *Client) GetError() (time.Duration, error) {
+	resp := &ErrorResponse{}
+	if err := c.c.Call(ServiceName+".GetError", &Request{}, resp); err != nil {
+		return 0, err
+	}
+	return time.Duration(resp.WOUNS), nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.c.Close()
+}
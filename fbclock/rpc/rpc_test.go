@@ -0,0 +1,112 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	earliest time.Time
+	latest   time.Time
+	err      error
+}
+
+func (f *fakeSource) GetTime() (time.Time, time.Time, error) {
+	return f.earliest, f.latest, f.err
+}
+
+func (f *fakeSource) GetTimeUTC() (time.Time, time.Time, error) {
+	return f.earliest.Add(-37 * time.Second), f.latest.Add(-37 * time.Second), f.err
+}
+
+func TestServeAndDial(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fbclock.sock")
+	source := &fakeSource{
+		earliest: time.Unix(1700000000, 100),
+		latest:   time.Unix(1700000000, 300),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Serve(ctx, path, NewService(source))
+	}()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0666), info.Mode().Perm())
+
+	c, err := Dial(path)
+	require.NoError(t, err)
+	defer c.Close()
+
+	tt, err := c.GetTime(false)
+	require.NoError(t, err)
+	require.Equal(t, &TimeResponse{EarliestNS: 1700000000000000100, LatestNS: 1700000000000000300}, tt)
+	require.Equal(t, source.earliest, tt.Earliest())
+	require.Equal(t, source.latest, tt.Latest())
+
+	tt, err = c.GetTime(true)
+	require.NoError(t, err)
+	require.Equal(t, source.earliest.Add(-37*time.Second), tt.Earliest())
+
+	wou, err := c.GetError()
+	require.NoError(t, err)
+	require.Equal(t, 100*time.Nanosecond, wou)
+
+	source.err = fmt.Errorf("reading FBClock TrueTime: no data")
+	_, err = c.GetTime(false)
+	require.EqualError(t, err, "reading FBClock TrueTime: no data")
+	_, err = c.GetError()
+	require.Error(t, err)
+
+	cancel()
+	require.NoError(t, <-done)
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestServeStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fbclock.sock")
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Serve(ctx, path, NewService(&fakeSource{}))
+	}()
+	require.Eventually(t, func() bool {
+		info, err := os.Stat(path)
+		return err == nil && info.Mode()&os.ModeSocket != 0
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestDialNoSocket(t *testing.T) {
+	_, err := Dial(filepath.Join(t.TempDir(), "nope.sock"))
+	require.Error(t, err)
+}
@@ -34,6 +34,7 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"time"
 	"unsafe"
 
 	"golang.org/x/sys/unix"
@@ -42,6 +43,16 @@ import (
 // PTPPath is the path we set for PTP device
 const PTPPath = C.FBCLOCK_PTPPATH
 
+// Shared memory layout versions
+const (
+	// ShmVersion is the layout version written by this library
+	ShmVersion = C.FBCLOCK_SHM_VERSION
+	// ShmCompatVersion is the oldest layout version readers need to support to read ShmVersion data
+	ShmCompatVersion = C.FBCLOCK_SHM_COMPAT_VERSION
+	// ShmV1Size is the size of version 1 layout, which had no version header
+	ShmV1Size = C.FBCLOCK_SHMDATA_V1_SIZE
+)
+
 // Shm is POSIX shared memory
 type Shm struct {
 	Path string
@@ -153,6 +164,26 @@ func StoreFBClockData(fd uintptr, d Data) error {
 	return nil
 }
 
+// StoreFBClockHeartbeat tells fbclock readers the daemon is alive without updating data,
+// fd param should be open file descriptor of that shared mem.
+func StoreFBClockHeartbeat(fd uintptr) error {
+	res := C.fbclock_shmdata_store_heartbeat(C.uint(fd))
+	if res != 0 {
+		return fmt.Errorf("failed to store heartbeat: %s", strerror(res))
+	}
+	return nil
+}
+
+// StoreFBClockStep tells fbclock readers the clock was stepped by step at PHC time at,
+// fd param should be open file descriptor of that shared mem.
+func StoreFBClockStep(fd uintptr, step time.Duration, at time.Time) error {
+	res := C.fbclock_shmdata_store_step(C.uint(fd), C.int64_t(step.Nanoseconds()), C.int64_t(at.UnixNano()))
+	if res != 0 {
+		return fmt.Errorf("failed to store step: %s", strerror(res))
+	}
+	return nil
+}
+
 // MmapShmpData mmaps open file as fbclock shared memory. Used in tests only.
 func MmapShmpData(fd uintptr) (unsafe.Pointer, error) {
 	data, err := unix.Mmap(int(fd), 0, C.FBCLOCK_SHMDATA_SIZE, unix.PROT_READ, unix.MAP_SHARED)
@@ -162,6 +193,16 @@ func MmapShmpData(fd uintptr) (unsafe.Pointer, error) {
 	return unsafe.Pointer(&data[0]), nil
 }
 
+// ReadFBClockStep will read the last clock step from mmaped fbclock shared memory. Used in tests only
+func ReadFBClockStep(shmp unsafe.Pointer) (*Step, error) {
+	cStep := &C.fbclock_step{}
+	res := C.fbclock_shmdata_load_step((*C.fbclock_shmdata)(shmp), cStep)
+	if res != 0 {
+		return nil, fmt.Errorf("failed to read step: %s", strerror(res))
+	}
+	return stepFromC(cStep), nil
+}
+
 // ReadFBClockData will read Data from mmaped fbclock shared memory. Used in tests only
 func ReadFBClockData(shmp unsafe.Pointer) (*Data, error) {
 	cData := &C.fbclock_clockdata{}
@@ -169,7 +210,7 @@ func ReadFBClockData(shmp unsafe.Pointer) (*Data, error) {
 	// fbclock_clockdata_load_data comes from fbclock.c
 	res := C.fbclock_clockdata_load_data(shmpData, cData)
 	if res != 0 {
-		return nil, fmt.Errorf("failed to store data: %s", strerror(res))
+		return nil, fmt.Errorf("failed to read data: %s", strerror(res))
 	}
 	return &Data{
 		IngressTimeNS:        int64(cData.ingress_time_ns),
@@ -177,3 +218,30 @@ func ReadFBClockData(shmp unsafe.Pointer) (*Data, error) {
 		HoldoverMultiplierNS: Uint32AsFloat(uint32(cData.holdover_multiplier_ns)),
 	}, nil
 }
+
+// ReadFBClockVersion returns layout version of mmaped fbclock shared memory. Used in tests only
+func ReadFBClockVersion(shmp unsafe.Pointer) (int, error) {
+	res := C.fbclock_shmdata_version((*C.fbclock_shmdata)(shmp))
+	if res < 0 {
+		return 0, fmt.Errorf("failed to read version: %s", strerror(res))
+	}
+	return int(res), nil
+}
+
+// ReadFBClockDataAge returns how long ago data in mmaped fbclock shared memory was written,
+// and false if the writer doesn't provide heartbeat. Used in tests only
+func ReadFBClockDataAge(shmp unsafe.Pointer, maxAge time.Duration) (time.Duration, bool, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return 0, false, err
+	}
+	shmpData := (*C.fbclock_shmdata)(shmp)
+	age := C.fbclock_shmdata_age_ns(shmpData, C.uint64_t(ts.Nano()))
+	if res := C.fbclock_check_data_age(shmpData, C.uint64_t(maxAge.Nanoseconds()), C.uint64_t(ts.Nano())); res != 0 {
+		return time.Duration(age), true, fmt.Errorf("failed to check data age: %s", strerror(res))
+	}
+	if age < 0 {
+		return 0, false, nil
+	}
+	return time.Duration(age), true, nil
+}
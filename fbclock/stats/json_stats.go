@@ -19,14 +19,28 @@ package stats
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// promPrefix is prepended to all counter names exposed in Prometheus format
+const promPrefix = "fbclock_"
+
+// promBadChars are characters not allowed in Prometheus metric names
+var promBadChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
 // JSONStats is what we want to report as stats via http
 type JSONStats struct {
 	Stats
+
+	debugLock  sync.Mutex
+	debugState func() any
 }
 
 // NewJSONStats returns a new JSONStats
@@ -38,6 +52,8 @@ func NewJSONStats() *JSONStats {
 func (s *JSONStats) Start(monitoringport int) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", s.handleRequest)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/debug/state", s.handleDebugState)
 	addr := fmt.Sprintf(":%d", monitoringport)
 	log.Infof("Starting http json server on %s", addr)
 	err := http.ListenAndServe(addr, mux)
@@ -48,7 +64,63 @@ func (s *JSONStats) Start(monitoringport int) {
 
 // handleRequest is a handler used for all http monitoring requests
 func (s *JSONStats) handleRequest(w http.ResponseWriter, _ *http.Request) {
-	js, err := json.Marshal(s.counters)
+	js, err := json.Marshal(s.Get())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(js); err != nil {
+		log.Errorf("Failed to reply: %v", err)
+	}
+}
+
+// SetDebugState sets function returning state of the daemon for /debug/state endpoint
+func (s *JSONStats) SetDebugState(f func() any) {
+	s.debugLock.Lock()
+	defer s.debugLock.Unlock()
+	s.debugState = f
+}
+
+// PromName turns counter name into a valid Prometheus metric name
+func PromName(key string) string {
+	return promPrefix + promBadChars.ReplaceAllString(key, "_")
+}
+
+// WriteProm writes counters in Prometheus text exposition format
+func WriteProm(w io.Writer, counters map[string]int64) error {
+	keys := make([]string, 0, len(counters))
+	for k := range counters {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		name := PromName(k)
+		fmt.Fprintf(&b, "# TYPE %s gauge\n%s %d\n", name, name, counters[k])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// handleMetrics serves counters in Prometheus format
+func (s *JSONStats) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := WriteProm(w, s.Get()); err != nil {
+		log.Errorf("Failed to reply: %v", err)
+	}
+}
+
+// handleDebugState serves state of the daemon as JSON
+func (s *JSONStats) handleDebugState(w http.ResponseWriter, _ *http.Request) {
+	s.debugLock.Lock()
+	f := s.debugState
+	s.debugLock.Unlock()
+	if f == nil {
+		http.Error(w, "no debug state available", http.StatusNotFound)
+		return
+	}
+	js, err := json.MarshalIndent(f(), "", "  ")
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -0,0 +1,77 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteProm(t *testing.T) {
+	var b strings.Builder
+	err := WriteProm(&b, map[string]int64{
+		"w_ns":                        48,
+		"master_offset_ns.60.abs_max": -23,
+		"linearizability.fd00::1":     1,
+	})
+	require.NoError(t, err)
+	want := `# TYPE fbclock_linearizability_fd00__1 gauge
+fbclock_linearizability_fd00__1 1
+# TYPE fbclock_master_offset_ns_60_abs_max gauge
+fbclock_master_offset_ns_60_abs_max -23
+# TYPE fbclock_w_ns gauge
+fbclock_w_ns 48
+`
+	require.Equal(t, want, b.String())
+}
+
+func TestJSONStatsHandlers(t *testing.T) {
+	s := NewJSONStats()
+	s.SetCounter("w_ns", 48)
+
+	get := func(h http.HandlerFunc) *http.Response {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Result()
+	}
+
+	resp := get(s.handleRequest)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, `{"w_ns":48}`, string(body))
+
+	resp = get(s.handleMetrics)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "# TYPE fbclock_w_ns gauge\nfbclock_w_ns 48\n", string(body))
+
+	resp = get(s.handleDebugState)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	s.SetDebugState(func() any { return map[string]string{"Source": "sptp"} })
+	resp = get(s.handleDebugState)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	state := map[string]string{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&state))
+	require.Equal(t, "sptp", state["Source"])
+}
@@ -0,0 +1,69 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	lib "github.com/facebook/time/fbclock"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeapIndicatorString(t *testing.T) {
+	require.Equal(t, "NONE", lib.LeapNone.String())
+	require.Equal(t, "PENDING", lib.LeapPending.String())
+	require.Equal(t, "SMEARING", lib.LeapSmearing.String())
+	require.Equal(t, "UNKNOWN(42)", lib.LeapIndicator(42).String())
+}
+
+func TestMonotonicMappingAt(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m := &lib.MonotonicMapping{
+		Monotonic: time.Hour,
+		TrueTime:  lib.TrueTime{Earliest: now.Add(-100 * time.Nanosecond), Latest: now.Add(100 * time.Nanosecond)},
+		DriftPPB:  500000,
+	}
+	tt, err := m.At(time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, m.TrueTime, *tt)
+
+	// 500ppm of 1s later
+	tt, err = m.At(time.Hour + time.Second)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(time.Second-500100*time.Nanosecond), tt.Earliest)
+	require.Equal(t, now.Add(time.Second+500100*time.Nanosecond), tt.Latest)
+
+	// and earlier, bounds are rounded up
+	tt, err = m.At(time.Hour - time.Microsecond - 1)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(-time.Microsecond-1-101*time.Nanosecond), tt.Earliest)
+	require.Equal(t, now.Add(-time.Microsecond-1+101*time.Nanosecond), tt.Latest)
+
+	// before epoch
+	m.TrueTime = lib.TrueTime{Earliest: time.Unix(1, 0), Latest: time.Unix(1, 0)}
+	_, err = m.At(0)
+	require.Error(t, err)
+}
+
+func TestMonotonicNow(t *testing.T) {
+	a := lib.MonotonicNow()
+	b := lib.MonotonicNow()
+	require.Greater(t, a, time.Duration(0))
+	require.GreaterOrEqual(t, b, a)
+}
@@ -17,9 +17,11 @@ limitations under the License.
 package test
 
 import (
+	"encoding/binary"
 	"math"
 	"os"
 	"testing"
+	"time"
 
 	lib "github.com/facebook/time/fbclock"
 
@@ -71,3 +73,133 @@ func TestShmem(t *testing.T) {
 	require.Equal(t, d.ErrorBoundNS, readD.ErrorBoundNS)
 	require.InDelta(t, d.HoldoverMultiplierNS, readD.HoldoverMultiplierNS, 0.001)
 }
+
+func TestShmemVersion(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "shmemtest")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+	shm, err := lib.OpenFBClockShmCustom(tmpfile.Name())
+	require.NoError(t, err)
+	defer shm.Close()
+	d := lib.Data{
+		IngressTimeNS:        1648137249050666302,
+		ErrorBoundNS:         314000,
+		HoldoverMultiplierNS: 1.001,
+	}
+	err = lib.StoreFBClockData(shm.File.Fd(), d)
+	require.NoError(t, err)
+
+	shmdata, err := lib.MmapShmpData(shm.File.Fd())
+	require.NoError(t, err)
+
+	version, err := lib.ReadFBClockVersion(shmdata)
+	require.NoError(t, err)
+	require.Equal(t, lib.ShmVersion, version)
+
+	writeHeader := func(magic uint32, version, compat uint16) {
+		header := binary.NativeEndian.AppendUint32(nil, magic)
+		header = binary.NativeEndian.AppendUint16(header, version)
+		header = binary.NativeEndian.AppendUint16(header, compat)
+		_, err := shm.File.WriteAt(header, lib.ShmV1Size)
+		require.NoError(t, err)
+	}
+
+	// data written before the header existed
+	writeHeader(0, 0, 0)
+	version, err = lib.ReadFBClockVersion(shmdata)
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+	readD, err := lib.ReadFBClockData(shmdata)
+	require.NoError(t, err)
+	require.Equal(t, d.IngressTimeNS, readD.IngressTimeNS)
+
+	// newer writer with fields we can skip
+	writeHeader(0xfbc10c00, lib.ShmVersion+1, lib.ShmCompatVersion)
+	version, err = lib.ReadFBClockVersion(shmdata)
+	require.NoError(t, err)
+	require.Equal(t, lib.ShmVersion+1, version)
+	_, err = lib.ReadFBClockData(shmdata)
+	require.NoError(t, err)
+
+	// newer writer with incompatible layout
+	writeHeader(0xfbc10c00, lib.ShmVersion+1, lib.ShmVersion+1)
+	_, err = lib.ReadFBClockVersion(shmdata)
+	require.ErrorContains(t, err, "unsupported shmem layout version")
+	_, err = lib.ReadFBClockData(shmdata)
+	require.ErrorContains(t, err, "unsupported shmem layout version")
+
+	// garbage
+	writeHeader(0xdeadbeef, 1, 1)
+	_, err = lib.ReadFBClockData(shmdata)
+	require.ErrorContains(t, err, "unsupported shmem layout version")
+}
+
+func TestShmemDataAge(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "shmemtest")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+	shm, err := lib.OpenFBClockShmCustom(tmpfile.Name())
+	require.NoError(t, err)
+	defer shm.Close()
+
+	shmdata, err := lib.MmapShmpData(shm.File.Fd())
+	require.NoError(t, err)
+
+	// nothing written yet
+	_, ok, err := lib.ReadFBClockDataAge(shmdata, time.Millisecond)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	err = lib.StoreFBClockData(shm.File.Fd(), lib.Data{IngressTimeNS: 1648137249050666302, ErrorBoundNS: 314000})
+	require.NoError(t, err)
+
+	age, ok, err := lib.ReadFBClockDataAge(shmdata, time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Less(t, age, time.Minute)
+
+	time.Sleep(50 * time.Millisecond)
+	_, _, err = lib.ReadFBClockDataAge(shmdata, 20*time.Millisecond)
+	require.ErrorContains(t, err, "data in shmem is stale")
+
+	// daemon is alive, even if data is not updated
+	err = lib.StoreFBClockHeartbeat(shm.File.Fd())
+	require.NoError(t, err)
+	_, _, err = lib.ReadFBClockDataAge(shmdata, 20*time.Millisecond)
+	require.NoError(t, err)
+
+	// check is disabled
+	_, _, err = lib.ReadFBClockDataAge(shmdata, 0)
+	require.NoError(t, err)
+}
+
+func TestShmemStep(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "shmemtest")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+	shm, err := lib.OpenFBClockShmCustom(tmpfile.Name())
+	require.NoError(t, err)
+	defer shm.Close()
+	shmdata, err := lib.MmapShmpData(shm.File.Fd())
+	require.NoError(t, err)
+
+	// ignored until data is written
+	at := time.Unix(0, 1648137249050666302)
+	require.NoError(t, lib.StoreFBClockStep(shm.File.Fd(), time.Millisecond, at))
+	step, err := lib.ReadFBClockStep(shmdata)
+	require.NoError(t, err)
+	require.Equal(t, &lib.Step{}, step)
+
+	require.NoError(t, lib.StoreFBClockData(shm.File.Fd(), lib.Data{IngressTimeNS: 1, ErrorBoundNS: 2}))
+	require.NoError(t, lib.StoreFBClockStep(shm.File.Fd(), time.Millisecond, at))
+	require.NoError(t, lib.StoreFBClockStep(shm.File.Fd(), -2*time.Second, at.Add(time.Minute)))
+	step, err = lib.ReadFBClockStep(shmdata)
+	require.NoError(t, err)
+	require.Equal(t, &lib.Step{Count: 2, Size: -2 * time.Second, Time: at.Add(time.Minute)}, step)
+
+	// data writes don't affect it
+	require.NoError(t, lib.StoreFBClockData(shm.File.Fd(), lib.Data{IngressTimeNS: 2, ErrorBoundNS: 2}))
+	step, err = lib.ReadFBClockStep(shmdata)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), step.Count)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	lib "github.com/facebook/time/fbclock"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	wou   time.Duration
+	err   error
+	calls int
+}
+
+func (s *fakeSource) GetTime() (*lib.TrueTime, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	now := time.Now()
+	return &lib.TrueTime{Earliest: now.Add(-s.wou / 2), Latest: now.Add(s.wou / 2)}, nil
+}
+
+func TestWaitUntilAfter(t *testing.T) {
+	src := &fakeSource{wou: 10 * time.Millisecond}
+	target := time.Now()
+	tt, err := lib.WaitUntilAfter(context.Background(), src, target)
+	require.NoError(t, err)
+	require.True(t, tt.Earliest.After(target))
+	require.True(t, time.Now().After(target.Add(5*time.Millisecond)))
+
+	// already in the past
+	src.calls = 0
+	tt, err = lib.WaitUntilAfter(context.Background(), src, time.Unix(0, 0))
+	require.NoError(t, err)
+	require.NotNil(t, tt)
+	require.Equal(t, 1, src.calls)
+}
+
+func TestWaitUntilAfterCancel(t *testing.T) {
+	src := &fakeSource{wou: 10 * time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := lib.WaitUntilAfter(ctx, src, time.Now().Add(time.Hour))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWaitUntilAfterError(t *testing.T) {
+	src := &fakeSource{err: errors.New("no data")}
+	_, err := lib.WaitUntilAfter(context.Background(), src, time.Now())
+	require.ErrorContains(t, err, "no data")
+	_, err = lib.CommitWait(context.Background(), src)
+	require.ErrorContains(t, err, "no data")
+}
+
+func TestCommitWait(t *testing.T) {
+	src := &fakeSource{wou: 10 * time.Millisecond}
+	ts, err := lib.CommitWait(context.Background(), src)
+	require.NoError(t, err)
+	tt, err := src.GetTime()
+	require.NoError(t, err)
+	require.True(t, tt.Earliest.After(ts))
+}
@@ -0,0 +1,80 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fbclock
+
+import (
+	"context"
+	"time"
+)
+
+// minWaitInterval is the shortest sleep between TrueTime reads while waiting
+const minWaitInterval = time.Microsecond
+
+// TrueTimeSource is anything providing TrueTime, like FBClock
+type TrueTimeSource interface {
+	GetTime() (*TrueTime, error)
+}
+
+// WaitUntilAfter blocks until Earliest bound of TrueTime from src is after t,
+// meaning t is guaranteed to be in the past. It returns the TrueTime which satisfied the condition.
+func WaitUntilAfter(ctx context.Context, src TrueTimeSource, t time.Time) (*TrueTime, error) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		tt, err := src.GetTime()
+		if err != nil {
+			return nil, err
+		}
+		if tt.Earliest.After(t) {
+			return tt, nil
+		}
+		// Earliest moves roughly as fast as the clock, so it can't pass t sooner than that
+		wait := t.Sub(tt.Earliest)
+		if wait < minWaitInterval {
+			wait = minWaitInterval
+		}
+		timer.Reset(wait)
+	}
+}
+
+// CommitWait picks a commit timestamp as Latest bound of current TrueTime from src
+// and blocks until that timestamp is guaranteed to be in the past everywhere.
+func CommitWait(ctx context.Context, src TrueTimeSource) (time.Time, error) {
+	tt, err := src.GetTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if _, err := WaitUntilAfter(ctx, src, tt.Latest); err != nil {
+		return time.Time{}, err
+	}
+	return tt.Latest, nil
+}
+
+// WaitUntilAfter blocks until Earliest bound of TrueTime is after t
+func (f *FBClock) WaitUntilAfter(ctx context.Context, t time.Time) (*TrueTime, error) {
+	return WaitUntilAfter(ctx, f, t)
+}
+
+// CommitWait returns commit timestamp after waiting until it's guaranteed to be in the past
+func (f *FBClock) CommitWait(ctx context.Context) (time.Time, error) {
+	return CommitWait(ctx, f)
+}
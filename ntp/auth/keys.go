@@ -0,0 +1,185 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package auth implements NTP symmetric key authentication as described in RFC 5905, section 7.3:
+shared keys in ntp.keys format and the MAC appended to NTP packets.
+It's only meant for legacy clients which can't do NTS.
+*/
+package auth
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Algorithm is a digest algorithm of the key
+type Algorithm string
+
+// Supported digest algorithms
+const (
+	MD5    Algorithm = "MD5"
+	SHA1   Algorithm = "SHA1"
+	SHA256 Algorithm = "SHA256"
+)
+
+// maxDigestSize is the longest digest NTPv4 MAC carries, longer ones are truncated
+const maxDigestSize = 20
+
+// maxASCIISecretSize is the longest secret which is not hex-encoded in ntp.keys
+const maxASCIISecretSize = 20
+
+// Key is a symmetric key shared with the client
+type Key struct {
+	ID        uint32
+	Algorithm Algorithm
+	Secret    []byte
+}
+
+func (k *Key) hash() hash.Hash {
+	switch k.Algorithm {
+	case MD5:
+		return md5.New()
+	case SHA1:
+		return sha1.New()
+	}
+	return sha256.New()
+}
+
+// Digest returns digest of the message, truncated to fit into MAC
+func (k *Key) Digest(msg []byte) []byte {
+	h := k.hash()
+	h.Write(k.Secret)
+	h.Write(msg)
+	d := h.Sum(nil)
+	return d[:min(len(d), maxDigestSize)]
+}
+
+// DigestSize returns size of the digest in MAC
+func (k *Key) DigestSize() int {
+	return min(k.hash().Size(), maxDigestSize)
+}
+
+// parseAlgorithm parses key type, both ntpd and chrony names are accepted
+func parseAlgorithm(s string) (Algorithm, error) {
+	switch strings.ToUpper(s) {
+	case "M", "MD5":
+		return MD5, nil
+	case "SHA1", "SHA-1":
+		return SHA1, nil
+	case "SHA256", "SHA-256":
+		return SHA256, nil
+	}
+	return "", fmt.Errorf("unsupported key type %q", s)
+}
+
+// parseSecret parses key secret: short ones are ASCII, long ones are hex, unless it's prefixed with ASCII: or HEX: like in chrony
+func parseSecret(s string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(s, "ASCII:"):
+		return []byte(strings.TrimPrefix(s, "ASCII:")), nil
+	case strings.HasPrefix(s, "HEX:"):
+		return hex.DecodeString(strings.TrimPrefix(s, "HEX:"))
+	case len(s) <= maxASCIISecretSize:
+		return []byte(s), nil
+	}
+	return hex.DecodeString(s)
+}
+
+// ParseKeys parses keys in ntp.keys format: key id, type and secret per line, # starts a comment
+func ParseKeys(r io.Reader) (map[uint32]*Key, error) {
+	keys := map[uint32]*Key{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected key id, type and secret", n)
+		}
+		id, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("line %d: invalid key id %q", n, fields[0])
+		}
+		alg, err := parseAlgorithm(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		secret, err := parseSecret(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: parsing secret: %w", n, err)
+		}
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("line %d: empty secret", n)
+		}
+		if _, ok := keys[uint32(id)]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key id %d", n, id)
+		}
+		keys[uint32(id)] = &Key{ID: uint32(id), Algorithm: alg, Secret: secret}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// ReadKeys reads keys from ntp.keys file
+func ReadKeys(path string) (map[uint32]*Key, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseKeys(f)
+}
+
+// Keyring is a set of trusted keys which can be replaced while in use
+type Keyring struct {
+	sync.RWMutex
+	keys map[uint32]*Key
+}
+
+// NewKeyring returns Keyring with keys
+func NewKeyring(keys map[uint32]*Key) *Keyring {
+	return &Keyring{keys: keys}
+}
+
+// SetKeys replaces all keys
+func (r *Keyring) SetKeys(keys map[uint32]*Key) {
+	r.Lock()
+	r.keys = keys
+	r.Unlock()
+}
+
+// Key returns key by id
+func (r *Keyring) Key(id uint32) (*Key, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	k, ok := r.keys[id]
+	return k, ok
+}
@@ -0,0 +1,93 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testKeys = `
+# ntpd style
+1 M secret
+2 SHA1 0123456789abcdef0123456789abcdef01234567 # hex
+3 sha256 0123456789abcdef0123456789abcdef01234567
+# chrony style
+10 MD5 ASCII:0123456789abcdef0123456789
+11 SHA1 HEX:00ff
+`
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys(strings.NewReader(testKeys))
+	require.NoError(t, err)
+	hexSecret := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67}
+	require.Equal(t, map[uint32]*Key{
+		1:  {ID: 1, Algorithm: MD5, Secret: []byte("secret")},
+		2:  {ID: 2, Algorithm: SHA1, Secret: hexSecret},
+		3:  {ID: 3, Algorithm: SHA256, Secret: hexSecret},
+		10: {ID: 10, Algorithm: MD5, Secret: []byte("0123456789abcdef0123456789")},
+		11: {ID: 11, Algorithm: SHA1, Secret: []byte{0x00, 0xff}},
+	}, keys)
+}
+
+func TestParseKeysInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		in  string
+		err string
+	}{
+		{in: "1 MD5", err: "line 1: expected key id, type and secret"},
+		{in: "0 MD5 secret", err: "line 1: invalid key id \"0\""},
+		{in: "\nkey MD5 secret", err: "line 2: invalid key id \"key\""},
+		{in: "1 AES128CMAC secret", err: "line 1: unsupported key type \"AES128CMAC\""},
+		{in: "1 SHA1 0123456789abcdef0123456789abcdef0123456z", err: "line 1: parsing secret: encoding/hex: invalid byte: U+007A 'z'"},
+		{in: "1 SHA1 HEX:", err: "line 1: empty secret"},
+		{in: "1 MD5 a\n1 MD5 b", err: "line 2: duplicate key id 1"},
+	} {
+		_, err := ParseKeys(strings.NewReader(tc.in))
+		require.EqualError(t, err, tc.err, tc.in)
+	}
+}
+
+func TestReadKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ntp.keys")
+	_, err := ReadKeys(path)
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(testKeys), 0600))
+	keys, err := ReadKeys(path)
+	require.NoError(t, err)
+	require.Len(t, keys, 5)
+}
+
+func TestKeyring(t *testing.T) {
+	r := NewKeyring(map[uint32]*Key{1: {ID: 1, Algorithm: MD5, Secret: []byte("secret")}})
+	k, ok := r.Key(1)
+	require.True(t, ok)
+	require.Equal(t, uint32(1), k.ID)
+	_, ok = r.Key(2)
+	require.False(t, ok)
+
+	r.SetKeys(map[uint32]*Key{2: {ID: 2, Algorithm: SHA1, Secret: []byte("secret")}})
+	_, ok = r.Key(1)
+	require.False(t, ok)
+	_, ok = r.Key(2)
+	require.True(t, ok)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+)
+
+// keyIDSize is the size of key id preceding the digest in MAC
+const keyIDSize = 4
+
+// md5DigestSize is the size of MD5 digest in MAC
+const md5DigestSize = 16
+
+// Errors returned by Verify
+var (
+	// ErrNoMAC means packet is not authenticated
+	ErrNoMAC = errors.New("no MAC")
+	// ErrUnknownKey means packet is signed with the key we don't have
+	ErrUnknownKey = errors.New("unknown key")
+	// ErrBadMAC means digest doesn't match
+	ErrBadMAC = errors.New("bad MAC")
+)
+
+// SplitMAC splits NTPv4 packet without extension fields into message and MAC.
+// It returns false if packet doesn't have a MAC
+func SplitMAC(b []byte) (msg []byte, keyID uint32, digest []byte, ok bool) {
+	switch len(b) - ntp.PacketSizeBytes {
+	// MD5, and SHA1 or truncated longer digests
+	case keyIDSize + md5DigestSize, keyIDSize + maxDigestSize:
+	default:
+		return nil, 0, nil, false
+	}
+	msg = b[:ntp.PacketSizeBytes]
+	return msg, binary.BigEndian.Uint32(b[ntp.PacketSizeBytes:]), b[ntp.PacketSizeBytes+keyIDSize:], true
+}
+
+// Verify checks MAC of the packet and returns the key it's signed with
+func (r *Keyring) Verify(b []byte) (*Key, error) {
+	msg, id, digest, ok := SplitMAC(b)
+	if !ok {
+		return nil, ErrNoMAC
+	}
+	k, ok := r.Key(id)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if len(digest) != k.DigestSize() || subtle.ConstantTimeCompare(digest, k.Digest(msg)) != 1 {
+		return nil, ErrBadMAC
+	}
+	return k, nil
+}
+
+// Sign returns packet with MAC appended
+func (k *Key) Sign(packet []byte) []byte {
+	b := make([]byte, 0, len(packet)+keyIDSize+maxDigestSize)
+	b = append(b, packet...)
+	b = binary.BigEndian.AppendUint32(b, k.ID)
+	return append(b, k.Digest(packet)...)
+}
+
+// CryptoNAK returns response with crypto-NAK appended: MAC with key id 0 and no digest,
+// which tells the client its request failed authentication
+func CryptoNAK(response []byte) []byte {
+	b := make([]byte, 0, len(response)+keyIDSize)
+	b = append(b, response...)
+	return binary.BigEndian.AppendUint32(b, 0)
+}
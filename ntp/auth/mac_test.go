@@ -0,0 +1,99 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"encoding/hex"
+	"testing"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func testPacket() []byte {
+	b := make([]byte, ntp.PacketSizeBytes)
+	b[0] = 0x23 // NTPv4 client
+	return b
+}
+
+func TestKeyDigest(t *testing.T) {
+	k := &Key{ID: 1, Algorithm: MD5, Secret: []byte("secret")}
+	require.Equal(t, "e090dd0f2a35e38783a54445cb1e6933", hex.EncodeToString(k.Digest(testPacket())))
+	require.Equal(t, 16, k.DigestSize())
+
+	secret, err := hex.DecodeString("0123456789abcdef0123456789abcdef01234567")
+	require.NoError(t, err)
+	k = &Key{ID: 3, Algorithm: SHA256, Secret: secret}
+	// truncated to 20 bytes
+	require.Equal(t, "a716961c4b1e796464baa8d0cb0d8c423dd6c28c", hex.EncodeToString(k.Digest(testPacket())))
+	require.Equal(t, 20, k.DigestSize())
+}
+
+func TestSplitMAC(t *testing.T) {
+	_, _, _, ok := SplitMAC(testPacket())
+	require.False(t, ok)
+	// extension field
+	_, _, _, ok = SplitMAC(append(testPacket(), make([]byte, 28)...))
+	require.False(t, ok)
+
+	k := &Key{ID: 42, Algorithm: SHA1, Secret: []byte("secret")}
+	msg, id, digest, ok := SplitMAC(k.Sign(testPacket()))
+	require.True(t, ok)
+	require.Equal(t, testPacket(), msg)
+	require.Equal(t, uint32(42), id)
+	require.Equal(t, k.Digest(testPacket()), digest)
+}
+
+func TestKeyringVerify(t *testing.T) {
+	md5Key := &Key{ID: 1, Algorithm: MD5, Secret: []byte("secret")}
+	sha1Key := &Key{ID: 2, Algorithm: SHA1, Secret: []byte("secret")}
+	r := NewKeyring(map[uint32]*Key{1: md5Key, 2: sha1Key})
+
+	k, err := r.Verify(md5Key.Sign(testPacket()))
+	require.NoError(t, err)
+	require.Equal(t, md5Key, k)
+	k, err = r.Verify(sha1Key.Sign(testPacket()))
+	require.NoError(t, err)
+	require.Equal(t, sha1Key, k)
+
+	_, err = r.Verify(testPacket())
+	require.ErrorIs(t, err, ErrNoMAC)
+
+	_, err = r.Verify((&Key{ID: 3, Algorithm: MD5, Secret: []byte("secret")}).Sign(testPacket()))
+	require.ErrorIs(t, err, ErrUnknownKey)
+
+	// wrong secret
+	_, err = r.Verify((&Key{ID: 1, Algorithm: MD5, Secret: []byte("public")}).Sign(testPacket()))
+	require.ErrorIs(t, err, ErrBadMAC)
+
+	// tampered packet
+	b := md5Key.Sign(testPacket())
+	b[1] = 1
+	_, err = r.Verify(b)
+	require.ErrorIs(t, err, ErrBadMAC)
+
+	// digest of a different size
+	_, err = r.Verify((&Key{ID: 1, Algorithm: SHA1, Secret: []byte("secret")}).Sign(testPacket()))
+	require.ErrorIs(t, err, ErrBadMAC)
+}
+
+func TestCryptoNAK(t *testing.T) {
+	b := CryptoNAK(testPacket())
+	require.Len(t, b, ntp.PacketSizeBytes+4)
+	require.Equal(t, testPacket(), b[:ntp.PacketSizeBytes])
+	require.Equal(t, []byte{0, 0, 0, 0}, b[ntp.PacketSizeBytes:])
+}
@@ -44,6 +44,9 @@ const (
 const (
 	floatExpBits  = 7
 	floatCoefBits = (4*8 - floatExpBits)
+	floatExpMin   = -(1 << (floatExpBits - 1))
+	floatExpMax   = -floatExpMin - 1
+	floatCoefMax  = (1 << (floatCoefBits - 1)) - 1
 )
 
 type ipAddr struct {
@@ -78,6 +81,18 @@ type timeSpec struct {
 	Nsec    uint32
 }
 
+func newTimeSpec(t time.Time) *timeSpec {
+	if t.IsZero() {
+		return &timeSpec{}
+	}
+	sec := uint64(t.Unix())
+	return &timeSpec{
+		SecHigh: uint32(sec >> 32),
+		SecLow:  uint32(sec),
+		Nsec:    uint32(t.Nanosecond()),
+	}
+}
+
 func (t *timeSpec) ToTime() time.Time {
 	highU64 := uint64(t.SecHigh)
 	if t.SecHigh == noHighSec {
@@ -115,6 +130,53 @@ func (f chronyFloat) ToFloat() float64 {
 	return float64(coef) * math.Pow(2.0, float64(exp))
 }
 
+// newChronyFloat does the same magic to encode float as int32.
+// Code is copied and translated to Go from original C sources.
+func newChronyFloat(x float64) chronyFloat {
+	var exp, coef int32
+	neg := int32(0)
+
+	if x < 0 {
+		x = -x
+		neg = 1
+	} else if math.IsNaN(x) {
+		// save NaN as zero
+		x = 0
+	}
+
+	switch {
+	case x < 1.0e-100:
+		exp, coef = 0, 0
+	case x > 1.0e100:
+		exp, coef = floatExpMax, floatCoefMax+neg
+	default:
+		exp = int32(math.Log2(x) + 1)
+		coef = int32(x*math.Pow(2.0, float64(-exp+floatCoefBits)) + 0.5)
+		// we may need to shift up to two bits down
+		for coef > floatCoefMax+neg {
+			coef >>= 1
+			exp++
+		}
+		if exp > floatExpMax {
+			exp, coef = floatExpMax, floatCoefMax+neg
+		} else if exp < floatExpMin {
+			// underflow
+			if exp+floatCoefBits >= floatExpMin {
+				coef >>= floatExpMin - exp
+				exp = floatExpMin
+			} else {
+				exp, coef = 0, 0
+			}
+		}
+	}
+
+	// negate back
+	if neg == 1 {
+		coef = int32(uint32(-coef) << floatExpBits >> floatExpBits)
+	}
+	return chronyFloat(uint32(exp)<<floatCoefBits | uint32(coef))
+}
+
 // RefidAsHEX prints ref id as hex
 func RefidAsHEX(refID uint32) string {
 	return fmt.Sprintf("%08X", refID)
@@ -17,7 +17,9 @@ limitations under the License.
 package chrony
 
 import (
+	"math"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -52,6 +54,24 @@ func TestFloat(t *testing.T) {
 	}
 }
 
+func TestNewChronyFloat(t *testing.T) {
+	require.Equal(t, chronyFloat(0), newChronyFloat(0))
+	require.Equal(t, chronyFloat(0), newChronyFloat(math.NaN()))
+	// too small to be represented
+	require.Equal(t, chronyFloat(0), newChronyFloat(1e-30))
+	for _, f := range []float64{-0.490620, 0.039435696, 1, 0.7, -1e-9, 123456.789, 1e-15} {
+		require.InEpsilon(t, f, newChronyFloat(f).ToFloat(), 1e-7, f)
+	}
+	require.Equal(t, chronyFloat(17091950), newChronyFloat(chronyFloat(17091950).ToFloat()))
+	require.Equal(t, chronyFloat(-90077357), newChronyFloat(chronyFloat(-90077357).ToFloat()))
+}
+
+func TestTimeSpec(t *testing.T) {
+	now := time.Unix(1700000000, 123456789)
+	require.Equal(t, now, newTimeSpec(now).ToTime())
+	require.Equal(t, &timeSpec{}, newTimeSpec(time.Time{}))
+}
+
 func TestRefidToString(t *testing.T) {
 	testCases := []struct {
 		in  uint32
@@ -72,6 +72,7 @@ const (
 	reqSourceData    CommandType = 15
 	reqTracking      CommandType = 33
 	reqSourceStats   CommandType = 34
+	reqRTCReport     CommandType = 35
 	reqActivity      CommandType = 44
 	reqServerStats   CommandType = 54
 	reqNTPData       CommandType = 57
@@ -81,10 +82,12 @@ const (
 
 // reply types
 const (
+	RpyNull          ReplyType = 1
 	RpyNSources      ReplyType = 2
 	RpySourceData    ReplyType = 3
 	RpyTracking      ReplyType = 5
 	RpySourceStats   ReplyType = 6
+	RpyRTC           ReplyType = 7
 	RpyActivity      ReplyType = 12
 	RpyServerStats   ReplyType = 14
 	RpyNTPData       ReplyType = 16
@@ -317,6 +320,13 @@ type RequestSourceStats struct {
 	data [maxDataLen - 4]uint8
 }
 
+// RequestRTCData - packet to request 'rtcdata' data
+type RequestRTCData struct {
+	RequestHead
+	// we actually need this to send proper packet
+	data [maxDataLen]uint8
+}
+
 // RequestActivity - packet to request 'activity' data
 type RequestActivity struct {
 	RequestHead
@@ -731,6 +741,42 @@ type ReplyNTPSourceName struct {
 	NTPSourceName
 }
 
+type replyRTCContent struct {
+	RefTime        timeSpec
+	NSamples       uint16
+	NRuns          uint16
+	SpanSeconds    uint32
+	RTCSecondsFast chronyFloat
+	RTCGainRatePPM chronyFloat
+}
+
+// RTCData contains parsed version of 'rtcdata' reply
+type RTCData struct {
+	RefTime        time.Time
+	NSamples       uint16
+	NRuns          uint16
+	SpanSeconds    uint32
+	RTCSecondsFast float64
+	RTCGainRatePPM float64
+}
+
+func newRTCData(r *replyRTCContent) *RTCData {
+	return &RTCData{
+		RefTime:        r.RefTime.ToTime(),
+		NSamples:       r.NSamples,
+		NRuns:          r.NRuns,
+		SpanSeconds:    r.SpanSeconds,
+		RTCSecondsFast: r.RTCSecondsFast.ToFloat(),
+		RTCGainRatePPM: r.RTCGainRatePPM.ToFloat(),
+	}
+}
+
+// ReplyRTCData is a usable version of 'rtcdata' response
+type ReplyRTCData struct {
+	ReplyHead
+	RTCData
+}
+
 // Activity contains parsed version of 'activity' reply
 type Activity struct {
 	Online       int32
@@ -969,6 +1015,19 @@ func NewServerStatsPacket() *RequestServerStats {
 	}
 }
 
+// NewRTCDataPacket creates new packet to request 'rtcdata' information.
+// Server replies with NORTC status unless it tracks the real-time clock
+func NewRTCDataPacket() *RequestRTCData {
+	return &RequestRTCData{
+		RequestHead: RequestHead{
+			Version: protoVersionNumber,
+			PKTType: pktTypeCmdRequest,
+			Command: reqRTCReport,
+		},
+		data: [maxDataLen]uint8{},
+	}
+}
+
 // NewActivityPacket creates new packet to request 'activity' information
 func NewActivityPacket() *RequestActivity {
 	return &RequestActivity{
@@ -1076,6 +1135,16 @@ func decodePacket(response []byte) (ResponsePacket, error) {
 			ReplyHead:   *head,
 			SourceStats: *newSourceStats(data),
 		}, nil
+	case RpyRTC:
+		data := new(replyRTCContent)
+		if err = binary.Read(r, binary.BigEndian, data); err != nil {
+			return nil, err
+		}
+		Logger.Printf("response data: %+v", data)
+		return &ReplyRTCData{
+			ReplyHead: *head,
+			RTCData:   *newRTCData(data),
+		}, nil
 	case RpyActivity:
 		data := new(Activity)
 		if err = binary.Read(r, binary.BigEndian, data); err != nil {
@@ -202,6 +202,40 @@ func TestDecodeTracking(t *testing.T) {
 	require.Equal(t, want, packet)
 }
 
+func TestDecodeRTCData(t *testing.T) {
+	raw := []uint8{
+		0x06, 0x02, 0x00, 0x00, 0x00, 0x23, 0x00, 0x07, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2a,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x61, 0x38, 0xe1, 0x81, 0x36, 0x94, 0x8d, 0xd5,
+		0x00, 0x0c, 0x00, 0x05, 0x00, 0x00, 0x1a, 0x27, 0x2f, 0xff,
+		0xff, 0xff, 0x30, 0x00, 0x00, 0x03,
+	}
+	packet, err := decodePacket(raw)
+	require.Nil(t, err)
+	want := &ReplyRTCData{
+		ReplyHead: ReplyHead{
+			Version:  protoVersionNumber,
+			PKTType:  pktTypeCmdReply,
+			Res1:     0,
+			Res2:     0,
+			Command:  reqRTCReport,
+			Reply:    RpyRTC,
+			Status:   sttSuccess,
+			Sequence: 42,
+		},
+		RTCData: RTCData{
+			RefTime:        time.Unix(0, 1631117697915705301),
+			NSamples:       12,
+			NRuns:          5,
+			SpanSeconds:    6695,
+			RTCSecondsFast: -0.25,
+			RTCGainRatePPM: 1.5,
+		},
+	}
+	require.Equal(t, want, packet)
+}
+
 /* private part of the protocol */
 
 func TestDecodeServerStats(t *testing.T) {
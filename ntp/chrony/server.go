@@ -0,0 +1,151 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chrony
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync/atomic"
+)
+
+// Monitor provides data Server replies with
+type Monitor interface {
+	Tracking() (*Tracking, error)
+	ServerStats() (*ServerStats4, error)
+}
+
+// Server implements chronyd side of the monitoring protocol, so chronyc-compatible tools can query other NTP servers.
+// Only 'tracking', 'serverstats' and 'sources' requests are supported, the server reports no sources.
+type Server struct {
+	Monitor Monitor
+	cmdHits atomic.Uint64
+}
+
+// Serve replies to requests read from conn until it's closed
+func (s *Server) Serve(conn net.PacketConn) error {
+	request := make([]uint8, 1024)
+	for {
+		read, addr, err := conn.ReadFrom(request)
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		reply := s.handleRequest(request[:read])
+		if reply == nil {
+			continue
+		}
+		// unbound unixgram sockets can't be replied to
+		if ua, ok := addr.(*net.UnixAddr); addr == nil || (ok && (ua == nil || ua.Name == "")) {
+			Logger.Printf("can't reply to unnamed socket")
+			continue
+		}
+		if _, err := conn.WriteTo(reply, addr); err != nil {
+			Logger.Printf("failed to reply to %v: %v", addr, err)
+		}
+	}
+}
+
+// handleRequest decodes request and returns encoded reply, nil if request should be ignored
+func (s *Server) handleRequest(request []byte) []byte {
+	head := new(RequestHead)
+	if err := binary.Read(bytes.NewReader(request), binary.BigEndian, head); err != nil {
+		Logger.Printf("failed to decode request: %v", err)
+		return nil
+	}
+	Logger.Printf("request head: %+v", head)
+	if head.PKTType != pktTypeCmdRequest {
+		return nil
+	}
+	s.cmdHits.Add(1)
+	reply := &ReplyHead{
+		Version:  protoVersionNumber,
+		PKTType:  pktTypeCmdReply,
+		Command:  head.Command,
+		Reply:    RpyNull,
+		Status:   sttSuccess,
+		Sequence: head.Sequence,
+	}
+	if head.Version != protoVersionNumber {
+		reply.Status = sttBadPktVersion
+		return encodeReply(reply, nil)
+	}
+	var data any
+	switch head.Command {
+	case reqNSources:
+		reply.Reply = RpyNSources
+		data = &replySourcesContent{}
+	case reqTracking:
+		tracking, err := s.Monitor.Tracking()
+		if err != nil {
+			Logger.Printf("failed to get tracking: %v", err)
+			reply.Status = sttFailed
+			break
+		}
+		reply.Reply = RpyTracking
+		data = newReplyTrackingContent(tracking)
+	case reqServerStats:
+		stats, err := s.Monitor.ServerStats()
+		if err != nil {
+			Logger.Printf("failed to get server stats: %v", err)
+			reply.Status = sttFailed
+			break
+		}
+		stats.CMDHits = s.cmdHits.Load()
+		reply.Reply = RpyServerStats4
+		data = stats
+	default:
+		reply.Status = sttInvalid
+	}
+	return encodeReply(reply, data)
+}
+
+// encodeReply encodes reply head and content, if any
+func encodeReply(head *ReplyHead, data any) []byte {
+	buf := &bytes.Buffer{}
+	// writes to bytes.Buffer of fixed size structs can't fail
+	_ = binary.Write(buf, binary.BigEndian, head)
+	if data != nil {
+		_ = binary.Write(buf, binary.BigEndian, data)
+	}
+	return buf.Bytes()
+}
+
+func newReplyTrackingContent(t *Tracking) *replyTrackingContent {
+	r := &replyTrackingContent{
+		RefID:              t.RefID,
+		Stratum:            t.Stratum,
+		LeapStatus:         t.LeapStatus,
+		RefTime:            *newTimeSpec(t.RefTime),
+		CurrentCorrection:  newChronyFloat(t.CurrentCorrection),
+		LastOffset:         newChronyFloat(t.LastOffset),
+		RMSOffset:          newChronyFloat(t.RMSOffset),
+		FreqPPM:            newChronyFloat(t.FreqPPM),
+		ResidFreqPPM:       newChronyFloat(t.ResidFreqPPM),
+		SkewPPM:            newChronyFloat(t.SkewPPM),
+		RootDelay:          newChronyFloat(t.RootDelay),
+		RootDispersion:     newChronyFloat(t.RootDispersion),
+		LastUpdateInterval: newChronyFloat(t.LastUpdateInterval),
+	}
+	if t.IPAddr != nil {
+		r.IPAddr = *newIPAddr(t.IPAddr)
+	}
+	return r
+}
@@ -0,0 +1,126 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chrony
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMonitor struct {
+	tracking *Tracking
+	stats    *ServerStats4
+	err      error
+}
+
+func (m *fakeMonitor) Tracking() (*Tracking, error) {
+	return m.tracking, m.err
+}
+
+func (m *fakeMonitor) ServerStats() (*ServerStats4, error) {
+	return m.stats, m.err
+}
+
+func requestBytes(t *testing.T, req RequestPacket) []byte {
+	buf := &bytes.Buffer{}
+	require.NoError(t, binary.Write(buf, binary.BigEndian, req))
+	return buf.Bytes()
+}
+
+func TestServerHandleRequest(t *testing.T) {
+	m := &fakeMonitor{
+		tracking: &Tracking{
+			RefID:          0x41544f4d,
+			Stratum:        1,
+			RefTime:        time.Unix(1700000000, 0),
+			RootDispersion: 0.000015,
+		},
+		stats: &ServerStats4{NTPHits: 42, NTPDrops: 3},
+	}
+	s := &Server{Monitor: m}
+
+	req := NewTrackingPacket()
+	req.SetSequence(7)
+	p, err := decodePacket(s.handleRequest(requestBytes(t, req)))
+	require.NoError(t, err)
+	tracking, ok := p.(*ReplyTracking)
+	require.True(t, ok)
+	require.Equal(t, uint32(7), tracking.Sequence)
+	require.Equal(t, reqTracking, tracking.Command)
+	require.Equal(t, uint32(0x41544f4d), tracking.RefID)
+	require.Equal(t, uint16(1), tracking.Stratum)
+	require.Equal(t, time.Unix(1700000000, 0), tracking.RefTime)
+	require.InDelta(t, 0.000015, tracking.RootDispersion, 1e-12)
+
+	p, err = decodePacket(s.handleRequest(requestBytes(t, NewServerStatsPacket())))
+	require.NoError(t, err)
+	stats, ok := p.(*ReplyServerStats4)
+	require.True(t, ok)
+	require.Equal(t, uint64(42), stats.NTPHits)
+	require.Equal(t, uint64(3), stats.NTPDrops)
+	require.Equal(t, uint64(2), stats.CMDHits)
+
+	p, err = decodePacket(s.handleRequest(requestBytes(t, NewSourcesPacket())))
+	require.NoError(t, err)
+	require.Equal(t, 0, p.(*ReplySources).NSources)
+
+	_, err = decodePacket(s.handleRequest(requestBytes(t, NewActivityPacket())))
+	require.EqualError(t, err, "got status INVALID (3)")
+
+	req = NewTrackingPacket()
+	req.Version = 5
+	_, err = decodePacket(s.handleRequest(requestBytes(t, req)))
+	require.EqualError(t, err, "got status BADPKTVERSION (18)")
+
+	m.err = errors.New("no sync")
+	_, err = decodePacket(s.handleRequest(requestBytes(t, NewTrackingPacket())))
+	require.EqualError(t, err, "got status FAILED (1)")
+
+	// replies and garbage are ignored
+	require.Nil(t, s.handleRequest([]byte{1, 2, 3}))
+	req = NewTrackingPacket()
+	req.PKTType = pktTypeCmdReply
+	require.Nil(t, s.handleRequest(requestBytes(t, req)))
+}
+
+func TestServerServe(t *testing.T) {
+	dir := t.TempDir()
+	addr := &net.UnixAddr{Name: filepath.Join(dir, "chronyd.sock"), Net: "unixgram"}
+	conn, err := net.ListenUnixgram("unixgram", addr)
+	require.NoError(t, err)
+	s := &Server{Monitor: &fakeMonitor{stats: &ServerStats4{NTPHits: 1}}}
+	done := make(chan error)
+	go func() { done <- s.Serve(conn) }()
+
+	client, err := net.DialUnix("unixgram", &net.UnixAddr{Name: filepath.Join(dir, "chronyc.sock"), Net: "unixgram"}, addr)
+	require.NoError(t, err)
+	defer client.Close()
+	c := &Client{Connection: client}
+	p, err := c.Communicate(NewServerStatsPacket())
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), p.(*ReplyServerStats4).NTPHits)
+
+	require.NoError(t, conn.Close())
+	require.NoError(t, <-done)
+}
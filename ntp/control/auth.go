@@ -0,0 +1,74 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/facebook/time/ntp/auth"
+)
+
+// headSize is the size of NTPControlMsgHead on the wire
+const headSize = 12
+
+// ErrorDesc stores human-readable descriptions of error codes returned in Status field of error responses
+var ErrorDesc = [8]string{
+	"unspecified",       // 0
+	"permission denied", // 1
+	"bad format",        // 2
+	"bad operation",     // 3
+	"bad association",   // 4
+	"unknown variable",  // 5
+	"bad value",         // 6
+	"restricted",        // 7
+}
+
+// GetErrorCode returns error code of error response
+func (n NTPControlMsgHead) GetErrorCode() uint8 {
+	return uint8(n.Status >> 8) // high octet of status
+}
+
+// macOffset returns where MAC starts in the message of given size.
+// Like ntpq and ntpd do, data is padded to 32 bit boundary and then the whole message to 64 bit one
+func macOffset(size int) int {
+	return (size + 7) &^ 7
+}
+
+// signMessage pads the message and appends MAC computed with the key
+func signMessage(key *auth.Key, msg []byte) []byte {
+	padded := make([]byte, macOffset(len(msg)))
+	copy(padded, msg)
+	return key.Sign(padded)
+}
+
+// verifyMessage checks MAC of the message carrying count octets of data
+func verifyMessage(key *auth.Key, b []byte, count uint16) error {
+	offset := macOffset(headSize + int(count))
+	if len(b) < offset+4 {
+		return auth.ErrNoMAC
+	}
+	if keyID := binary.BigEndian.Uint32(b[offset:]); keyID != key.ID {
+		return fmt.Errorf("response is signed with key %d instead of %d: %w", keyID, key.ID, auth.ErrUnknownKey)
+	}
+	digest := b[offset+4:]
+	if len(digest) != key.DigestSize() || subtle.ConstantTimeCompare(digest, key.Digest(b[:offset])) != 1 {
+		return auth.ErrBadMAC
+	}
+	return nil
+}
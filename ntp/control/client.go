@@ -19,13 +19,18 @@ package control
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
+
+	"github.com/facebook/time/ntp/auth"
 )
 
 // NTPClient is our client to talk to network. The main reason it exists is keeping track of Sequence number.
 type NTPClient struct {
 	Sequence   uint16
 	Connection io.ReadWriter
+	// Key, if set, is used to sign requests and verify responses, so ntpd answers queries which require authentication
+	Key *auth.Key
 }
 
 // CommunicateWithData sends package + data over connection, bumps Sequence num and parses (possibly multiple) response packets into NTPControlMsg packet.
@@ -47,8 +52,12 @@ func (n *NTPClient) CommunicateWithData(packet *NTPControlMsgHead, data []uint8)
 	if err != nil {
 		return nil, err
 	}
+	payload := buf.Bytes()
+	if n.Key != nil {
+		payload = signMessage(n.Key, payload)
+	}
 	// send full payload
-	_, err = n.Connection.Write(buf.Bytes())
+	_, err = n.Connection.Write(payload)
 	if err != nil {
 		return nil, err
 	}
@@ -58,16 +67,25 @@ func (n *NTPClient) CommunicateWithData(packet *NTPControlMsgHead, data []uint8)
 	for {
 		response := make([]uint8, 1024)
 		head := new(NTPControlMsgHead)
-		_, err := n.Connection.Read(response)
+		size, err := n.Connection.Read(response)
 		if err != nil {
 			return nil, err
 		}
-		r := bytes.NewReader(response[:12])
+		r := bytes.NewReader(response[:headSize])
 		if err = binary.Read(r, binary.BigEndian, head); err != nil {
 			return nil, err
 		}
+		if n.Key != nil {
+			// ntpd doesn't sign error responses to requests which failed authentication
+			if head.HasError() {
+				return nil, fmt.Errorf("server returned error: %s", ErrorDesc[head.GetErrorCode()&0x7])
+			}
+			if err := verifyMessage(n.Key, response[:size], head.Count); err != nil {
+				return nil, fmt.Errorf("verifying response: %w", err)
+			}
+		}
 		data := make([]uint8, head.Count)
-		copy(data, response[12:12+head.Count])
+		copy(data, response[headSize:headSize+head.Count])
 		resultData = append(resultData, data...)
 		if !head.HasMore() {
 			resultHead = head
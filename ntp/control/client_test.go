@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/facebook/time/ntp/auth"
+
 	"github.com/stretchr/testify/require"
 )
 
@@ -30,6 +32,7 @@ var vnMode = MakeVnMode(3, Mode)
 type fakeConn struct {
 	readCount int
 	outputs   []*bytes.Buffer
+	written   []byte
 }
 
 func newConn(outputs []*bytes.Buffer) *fakeConn {
@@ -50,6 +53,7 @@ func (c *fakeConn) Read(p []byte) (n int, err error) {
 
 func (c *fakeConn) Write(p []byte) (n int, err error) {
 	// here we may require writes
+	c.written = append(c.written, p...)
 	return 0, nil
 }
 
@@ -123,3 +127,74 @@ func TestCommunicateMulti(t *testing.T) {
 	}
 	require.Equal(t, expected, p)
 }
+
+var testKey = &auth.Key{ID: 42, Algorithm: auth.SHA1, Secret: []byte("secret")}
+
+// Test if authenticated request is signed and response MAC is verified
+func TestCommunicateAuthenticated(t *testing.T) {
+	response := signMessage(testKey, []byte{
+		0x1e, 0x82, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x03, // count set to 3
+		0x61, 0x3d, 0x31, // 3 octets of data
+	})
+	conn := newConn([]*bytes.Buffer{bytes.NewBuffer(response)})
+	client := NTPClient{Sequence: 1, Connection: conn, Key: testKey}
+	p, err := client.CommunicateWithData(&NTPControlMsgHead{
+		VnMode: vnMode,
+		REMOp:  OpReadVariables,
+	}, []uint8("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("a=1"), p.Data)
+
+	// 12 octets of head, 1 of data, padded to 16, then key id and SHA1 digest
+	require.Len(t, conn.written, 16+4+20)
+	require.Equal(t, []byte{0, 0, 0, 42}, conn.written[16:20])
+	require.Equal(t, testKey.Digest(conn.written[:16]), conn.written[20:])
+	require.NoError(t, verifyMessage(testKey, conn.written, 1))
+}
+
+// Test if response with bad or missing MAC is rejected
+func TestCommunicateAuthenticatedBadMAC(t *testing.T) {
+	head := []byte{
+		0x1e, 0x82, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}
+	response := signMessage(testKey, head)
+	response[len(response)-1]++
+	otherKey := &auth.Key{ID: 1, Algorithm: auth.SHA1, Secret: []byte("secret")}
+	for _, tc := range []struct {
+		response []byte
+		err      error
+	}{
+		{response, auth.ErrBadMAC},
+		{head, auth.ErrNoMAC},
+		{signMessage(otherKey, head), auth.ErrUnknownKey},
+	} {
+		conn := newConn([]*bytes.Buffer{bytes.NewBuffer(tc.response)})
+		client := NTPClient{Sequence: 1, Connection: conn, Key: testKey}
+		_, err := client.Communicate(&NTPControlMsgHead{
+			VnMode: vnMode,
+			REMOp:  OpReadVariables,
+		})
+		require.ErrorIs(t, err, tc.err)
+	}
+}
+
+// Test if error response to authenticated request is reported
+func TestCommunicateAuthenticatedError(t *testing.T) {
+	conn := newConn([]*bytes.Buffer{
+		bytes.NewBuffer([]byte{
+			0x1e, 0xc2, 0x00, 0x01, // error bit is set
+			0x01, 0x00, 0x00, 0x00, // permission denied
+			0x00, 0x00, 0x00, 0x00,
+		}),
+	})
+	client := NTPClient{Sequence: 1, Connection: conn, Key: testKey}
+	_, err := client.Communicate(&NTPControlMsgHead{
+		VnMode: vnMode,
+		REMOp:  OpReadVariables,
+	})
+	require.EqualError(t, err, "server returned error: permission denied")
+}
@@ -27,16 +27,35 @@ const Mode = 6
 
 // Supported operation codes
 const (
-	OpReadStatus    = 1
-	OpReadVariables = 2
+	OpReadStatus         = 1
+	OpReadVariables      = 2
+	OpReadClockVariables = 4
 )
 
-// NormalizeData turns bytes that contain kv ASCII string info a map[string]string
+// splitUnquoted slices s into substrings separated by sep, ignoring separators inside double quotes
+func splitUnquoted(s string, sep rune) []string {
+	result := []string{}
+	quoted := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == sep && !quoted:
+			result = append(result, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(result, s[start:])
+}
+
+// NormalizeData turns bytes that contain kv ASCII string info a map[string]string.
+// Quoted values may contain commas, like NMEA sentences in refclock timecodes do
 func NormalizeData(data []byte) (map[string]string, error) {
 	result := map[string]string{}
-	pairs := strings.Split(string(data), ",")
+	pairs := splitUnquoted(string(data), ',')
 	for _, pair := range pairs {
-		split := strings.Split(pair, "=")
+		split := splitUnquoted(pair, '=')
 		if len(split) != 2 {
 			log.Printf("WARNING: Malformed packet, bad k=v pair '%s'", pair)
 			continue
@@ -366,3 +385,11 @@ func (n NTPControlMsg) GetAssociationInfo() (map[string]string, error) {
 	}
 	return data, nil
 }
+
+// GetClockVariables returns parsed normalized reference clock variables if present
+func (n NTPControlMsg) GetClockVariables() (map[string]string, error) {
+	if n.GetOperation() != OpReadClockVariables {
+		return map[string]string{}, fmt.Errorf("no clock variables supported for operation=%d", n.GetOperation())
+	}
+	return NormalizeData(n.Data)
+}
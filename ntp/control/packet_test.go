@@ -57,6 +57,20 @@ frequency=0.314, sys_jitter=0.246, clk_jitter=0.140, clk_wander=0.009
 	require.Equal(t, expected, parsed)
 }
 
+func TestNormalizeDataQuoted(t *testing.T) {
+	data := []byte(`device="NMEA GPS Clock", timecode="$GPRMC,123519,A,4807.038,N,01131.000,E,,,230394,003.1,W*6A", poll=4, fudgetime2=`)
+	parsed, err := NormalizeData(data)
+
+	require.NoError(t, err)
+	expected := map[string]string{
+		"device":     "NMEA GPS Clock",
+		"timecode":   "$GPRMC,123519,A,4807.038,N,01131.000,E,,,230394,003.1,W*6A",
+		"poll":       "4",
+		"fudgetime2": "",
+	}
+	require.Equal(t, expected, parsed)
+}
+
 // test that we skip bad pairs
 func TestNormalizeDataCorrupted(t *testing.T) {
 	data := []byte(`srcadr=2401:db00:3110:5068:face:0:5c:0, srcport=123,
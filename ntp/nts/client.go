@@ -0,0 +1,209 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nts
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// KEResult is what client learns from NTS-KE
+type KEResult struct {
+	// next protocols and AEAD algorithms server agreed to, empty if nothing is in common
+	Protocols []uint16
+	AEADs     []uint16
+	// NTP server and port to use, empty and 0 mean the NTS-KE host and the default port
+	Server string
+	Port   int
+	// warnings server reported
+	Warnings []uint16
+	Cookies  [][]byte
+	// keys exported from TLS session, only set if NTPv4 with AEAD_AES_SIV_CMAC_256 was negotiated
+	C2S []byte
+	S2C []byte
+}
+
+// KE performs NTS Key Establishment over TLS connection, asking for NTPv4 protected with AEAD_AES_SIV_CMAC_256.
+// Connection must be established with ALPN protocol set, errors reported by server are returned as errors
+func KE(conn *tls.Conn) (*KEResult, error) {
+	if err := conn.Handshake(); err != nil {
+		return nil, err
+	}
+	state := conn.ConnectionState()
+	if state.NegotiatedProtocol != ALPN {
+		return nil, fmt.Errorf("server didn't negotiate %q", ALPN)
+	}
+	if err := WriteMessage(conn, []*Record{
+		uint16Record(RecordNextProtocol, ProtocolNTPv4),
+		uint16Record(RecordAEADAlgorithm, AEADAESSIVCMAC256),
+	}); err != nil {
+		return nil, err
+	}
+	response, err := ReadMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+	r := &KEResult{}
+	for _, rec := range response {
+		switch rec.Type {
+		case RecordNextProtocol:
+			if r.Protocols, err = uint16s(rec.Body); err != nil {
+				return nil, err
+			}
+		case RecordAEADAlgorithm:
+			if r.AEADs, err = uint16s(rec.Body); err != nil {
+				return nil, err
+			}
+		case RecordError:
+			if len(rec.Body) != 2 {
+				return nil, fmt.Errorf("malformed error record")
+			}
+			code := binary.BigEndian.Uint16(rec.Body)
+			return nil, &errKE{code: code, msg: keErrorDesc(code)}
+		case RecordWarning:
+			w, err := uint16s(rec.Body)
+			if err != nil {
+				return nil, err
+			}
+			r.Warnings = append(r.Warnings, w...)
+		case RecordNewCookie:
+			r.Cookies = append(r.Cookies, rec.Body)
+		case RecordServerNegotiation:
+			r.Server = string(rec.Body)
+		case RecordPortNegotiation:
+			p, err := uint16s(rec.Body)
+			if err != nil || len(p) != 1 {
+				return nil, fmt.Errorf("malformed port negotiation record")
+			}
+			r.Port = int(p[0])
+		default:
+			if rec.Critical {
+				return nil, fmt.Errorf("unrecognized critical record %d", rec.Type)
+			}
+		}
+	}
+	if !slices.Contains(r.Protocols, ProtocolNTPv4) || !slices.Contains(r.AEADs, AEADAESSIVCMAC256) {
+		return r, nil
+	}
+	// context is next protocol, AEAD and direction
+	keyContext := []byte{0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(keyContext, ProtocolNTPv4)
+	binary.BigEndian.PutUint16(keyContext[2:], AEADAESSIVCMAC256)
+	if r.C2S, err = state.ExportKeyingMaterial(exporterLabel, keyContext, aeadKeySize); err != nil {
+		return nil, err
+	}
+	keyContext[4] = 1
+	if r.S2C, err = state.ExportKeyingMaterial(exporterLabel, keyContext, aeadKeySize); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// keErrorDesc returns description of NTS-KE error code
+func keErrorDesc(code uint16) string {
+	switch code {
+	case ErrorUnrecognizedCritical:
+		return "unrecognized critical record"
+	case ErrorBadRequest:
+		return "bad request"
+	case ErrorInternalServer:
+		return "internal server error"
+	default:
+		return "unknown error"
+	}
+}
+
+// AppendRequest appends NTS extension fields to NTP request header: random unique identifier,
+// cookie, placeholders asking for more cookies and authenticator. It returns the request and its unique identifier
+func AppendRequest(header []byte, cookie []byte, c2s []byte, placeholders int) ([]byte, []byte, error) {
+	uniqueID := make([]byte, minUniqueIdentifierLen)
+	if _, err := rand.Read(uniqueID); err != nil {
+		return nil, nil, err
+	}
+	b := append([]byte(nil), header...)
+	b = appendExtensionField(b, ExtUniqueIdentifier, uniqueID)
+	b = appendExtensionField(b, ExtCookie, cookie)
+	for i := 0; i < placeholders; i++ {
+		b = appendExtensionField(b, ExtCookiePlaceholder, make([]byte, len(cookie)))
+	}
+	a, err := NewAESSIVCMAC(c2s)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, sivBlockSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return appendAuthenticator(b, nonce, a.Seal(nil, nonce, nil, b)), uniqueID, nil
+}
+
+// ParseResponse authenticates NTS response to the request with uniqueID and returns new cookies.
+// Errors wrap ErrNAK if server responded with NTS NAK
+func ParseResponse(b []byte, uniqueID []byte, s2c []byte) ([][]byte, error) {
+	if len(b) <= headerSizeBytes {
+		return nil, fmt.Errorf("%w: no extension fields", ErrMalformed)
+	}
+	fields, err := parseExtensionFields(b[headerSizeBytes:], headerSizeBytes)
+	if err != nil {
+		return nil, err
+	}
+	var gotID bool
+	var auth *extensionField
+	for i, f := range fields {
+		switch f.Type {
+		case ExtUniqueIdentifier:
+			gotID = string(f.Value) == string(uniqueID)
+		case ExtAuthenticator:
+			auth = &fields[i]
+		}
+	}
+	if !gotID {
+		return nil, fmt.Errorf("%w: unique identifier doesn't match the request", ErrMalformed)
+	}
+	if auth == nil {
+		if b[1] == 0 && string(b[12:16]) == KissCode {
+			return nil, ErrNAK
+		}
+		return nil, fmt.Errorf("%w: no authenticator", ErrMalformed)
+	}
+	nonce, ciphertext, err := parseAuthenticator(auth.Value)
+	if err != nil {
+		return nil, err
+	}
+	a, err := NewAESSIVCMAC(s2c)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := a.Open(nil, nonce, ciphertext, b[:auth.start])
+	if err != nil {
+		return nil, fmt.Errorf("authenticating response: %w", err)
+	}
+	encrypted, err := parseExtensionFields(plaintext, 0)
+	if err != nil {
+		return nil, err
+	}
+	cookies := [][]byte{}
+	for _, f := range encrypted {
+		if f.Type == ExtCookie {
+			cookies = append(cookies, f.Value)
+		}
+	}
+	return cookies, nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nts
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRoundTrip(t *testing.T) {
+	j, err := NewCookieJar(make([]byte, CookieKeySize))
+	require.NoError(t, err)
+	c := &Cookie{AEAD: AEADAESSIVCMAC256, C2S: bytes.Repeat([]byte{1}, 32), S2C: bytes.Repeat([]byte{2}, 32)}
+	cookie, err := j.Encode(c)
+	require.NoError(t, err)
+
+	header := make([]byte, headerSizeBytes)
+	header[0] = 0x23
+	request, uniqueID, err := AppendRequest(header, cookie, c.C2S, 1)
+	require.NoError(t, err)
+	require.Len(t, uniqueID, 32)
+	r, err := j.ParseRequest(request)
+	require.NoError(t, err)
+	require.Equal(t, uniqueID, r.UniqueID)
+
+	response, err := j.Response(make([]byte, headerSizeBytes), r)
+	require.NoError(t, err)
+	cookies, err := ParseResponse(response, uniqueID, c.S2C)
+	require.NoError(t, err)
+	require.Len(t, cookies, 2)
+
+	_, err = ParseResponse(response, bytes.Repeat([]byte{0}, 32), c.S2C)
+	require.ErrorIs(t, err, ErrMalformed)
+	_, err = ParseResponse(response, uniqueID, c.C2S)
+	require.Error(t, err)
+	_, err = ParseResponse(response[:headerSizeBytes], uniqueID, c.S2C)
+	require.ErrorIs(t, err, ErrMalformed)
+
+	_, err = ParseResponse(NAK(make([]byte, headerSizeBytes), uniqueID), uniqueID, c.S2C)
+	require.ErrorIs(t, err, ErrNAK)
+}
+
+func TestKE(t *testing.T) {
+	j, err := NewCookieJar(make([]byte, CookieKeySize))
+	require.NoError(t, err)
+	cert := testCertificate(t)
+	k := &KEServer{TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}}, Cookies: j, NTPPort: 1234}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		_ = k.Serve(ln)
+	}()
+
+	clientConfig := &tls.Config{InsecureSkipVerify: true, NextProtos: []string{ALPN}}
+	conn, err := tls.Dial("tcp", ln.Addr().String(), clientConfig)
+	require.NoError(t, err)
+	defer conn.Close()
+	r, err := KE(conn)
+	require.NoError(t, err)
+	require.Equal(t, []uint16{ProtocolNTPv4}, r.Protocols)
+	require.Equal(t, []uint16{AEADAESSIVCMAC256}, r.AEADs)
+	require.Equal(t, 1234, r.Port)
+	require.Len(t, r.Cookies, cookiesPerKE)
+	c, err := j.Decode(r.Cookies[0])
+	require.NoError(t, err)
+	require.Equal(t, &Cookie{AEAD: AEADAESSIVCMAC256, C2S: r.C2S, S2C: r.S2C}, c)
+
+	// no ALPN
+	conn, err = tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = KE(conn)
+	require.Error(t, err)
+}
+
+func TestKEError(t *testing.T) {
+	cert := testCertificate(t)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}, NextProtos: []string{ALPN}})
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err := ReadMessage(conn); err != nil {
+			return
+		}
+		_ = WriteMessage(conn, []*Record{uint16Record(RecordError, ErrorBadRequest)})
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{ALPN}})
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = KE(conn)
+	require.EqualError(t, err, "nts-ke error 1: bad request")
+}
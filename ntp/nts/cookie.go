@@ -0,0 +1,172 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nts
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// CookieKeySize is a size of master key used to encrypt cookies
+const CookieKeySize = 32
+
+// cookieNonceSize is a size of nonce used to encrypt cookies
+const cookieNonceSize = 16
+
+// Cookie is the state server keeps on the client side: negotiated AEAD and keys
+type Cookie struct {
+	AEAD uint16
+	C2S  []byte // client to server key
+	S2C  []byte // server to client key
+}
+
+type cookieKey struct {
+	id   uint32
+	aead cipher.AEAD
+}
+
+// CookieJar encrypts and decrypts cookies with master keys.
+// All servers behind the same NTS-KE must share master keys, so any of them can decrypt cookies the client got
+type CookieJar struct {
+	sync.RWMutex
+	keys []cookieKey // first one is used to encrypt, the others are accepted during rotation
+}
+
+// NewCookieJar returns CookieJar with master keys, the first one is used to encrypt new cookies
+func NewCookieJar(keys ...[]byte) (*CookieJar, error) {
+	j := &CookieJar{}
+	return j, j.SetKeys(keys...)
+}
+
+// SetKeys replaces master keys, the first one is used to encrypt new cookies
+func (j *CookieJar) SetKeys(keys ...[]byte) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("no cookie keys")
+	}
+	ck := make([]cookieKey, 0, len(keys))
+	for _, key := range keys {
+		if len(key) != CookieKeySize {
+			return fmt.Errorf("cookie key must be %d bytes, got %d", CookieKeySize, len(key))
+		}
+		a, err := NewAESSIVCMAC(key)
+		if err != nil {
+			return err
+		}
+		// key id is derived from the key itself, so servers sharing keys agree on ids without extra coordination
+		sum := sha256.Sum256(key)
+		ck = append(ck, cookieKey{id: binary.BigEndian.Uint32(sum[:]), aead: a})
+	}
+	j.Lock()
+	j.keys = ck
+	j.Unlock()
+	return nil
+}
+
+// Encode encrypts cookie with the current master key
+func (j *CookieJar) Encode(c *Cookie) ([]byte, error) {
+	j.RLock()
+	key := j.keys[0]
+	j.RUnlock()
+
+	if len(c.C2S) != len(c.S2C) {
+		return nil, fmt.Errorf("keys must be of the same size")
+	}
+	b := make([]byte, 4+cookieNonceSize, 4+cookieNonceSize+key.aead.Overhead()+4+len(c.C2S)+len(c.S2C))
+	binary.BigEndian.PutUint32(b, key.id)
+	if _, err := rand.Read(b[4:]); err != nil {
+		return nil, err
+	}
+	// aead and key size keep cookie aligned to 4 bytes, as extension fields are padded to
+	plaintext := make([]byte, 4, 4+len(c.C2S)+len(c.S2C))
+	binary.BigEndian.PutUint16(plaintext, c.AEAD)
+	binary.BigEndian.PutUint16(plaintext[2:], uint16(len(c.C2S)))
+	plaintext = append(plaintext, c.C2S...)
+	plaintext = append(plaintext, c.S2C...)
+	return key.aead.Seal(b, b[4:], plaintext, b[:4]), nil
+}
+
+// Decode decrypts cookie encrypted with any of the master keys
+func (j *CookieJar) Decode(b []byte) (*Cookie, error) {
+	if len(b) < 4+cookieNonceSize {
+		return nil, fmt.Errorf("cookie is too short: %d bytes", len(b))
+	}
+	id := binary.BigEndian.Uint32(b)
+	j.RLock()
+	var key *cookieKey
+	for i := range j.keys {
+		if j.keys[i].id == id {
+			key = &j.keys[i]
+			break
+		}
+	}
+	j.RUnlock()
+	if key == nil {
+		return nil, fmt.Errorf("unknown cookie key %08x", id)
+	}
+	plaintext, err := key.aead.Open(nil, b[4:4+cookieNonceSize], b[4+cookieNonceSize:], b[:4])
+	if err != nil {
+		return nil, fmt.Errorf("decrypting cookie: %w", err)
+	}
+	if len(plaintext) < 4 {
+		return nil, fmt.Errorf("malformed cookie")
+	}
+	keySize := int(binary.BigEndian.Uint16(plaintext[2:]))
+	if len(plaintext) != 4+2*keySize {
+		return nil, fmt.Errorf("malformed cookie")
+	}
+	return &Cookie{
+		AEAD: binary.BigEndian.Uint16(plaintext),
+		C2S:  plaintext[4 : 4+keySize],
+		S2C:  plaintext[4+keySize:],
+	}, nil
+}
+
+// ReadCookieKeys reads hex-encoded master keys from file, one per line. Empty lines and lines starting with # are ignored.
+// The first key is used to encrypt new cookies, so to rotate keys add a new one on top and remove the last one
+// when all clients are expected to have new cookies
+func ReadCookieKeys(path string) ([][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	keys := [][]byte{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, err := hex.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("parsing cookie key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
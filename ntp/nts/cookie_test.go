@@ -0,0 +1,85 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nts
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCookieJar(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, CookieKeySize)
+	newKey := bytes.Repeat([]byte{2}, CookieKeySize)
+	j, err := NewCookieJar(oldKey)
+	require.NoError(t, err)
+
+	c := &Cookie{AEAD: AEADAESSIVCMAC256, C2S: bytes.Repeat([]byte{3}, 32), S2C: bytes.Repeat([]byte{4}, 32)}
+	oldCookie, err := j.Encode(c)
+	require.NoError(t, err)
+	require.Len(t, oldCookie, 4+16+16+4+32+32)
+	another, err := j.Encode(c)
+	require.NoError(t, err)
+	require.NotEqual(t, oldCookie, another, "cookies must not be linkable")
+
+	decoded, err := j.Decode(oldCookie)
+	require.NoError(t, err)
+	require.Equal(t, c, decoded)
+
+	// rotation: old cookies are still accepted
+	require.NoError(t, j.SetKeys(newKey, oldKey))
+	decoded, err = j.Decode(oldCookie)
+	require.NoError(t, err)
+	require.Equal(t, c, decoded)
+	newCookie, err := j.Encode(c)
+	require.NoError(t, err)
+	require.NotEqual(t, oldCookie[:4], newCookie[:4])
+
+	require.NoError(t, j.SetKeys(newKey))
+	_, err = j.Decode(oldCookie)
+	require.ErrorContains(t, err, "unknown cookie key")
+	newCookie[len(newCookie)-1] ^= 1
+	_, err = j.Decode(newCookie)
+	require.ErrorContains(t, err, "decrypting cookie")
+	_, err = j.Decode(newCookie[:10])
+	require.ErrorContains(t, err, "cookie is too short")
+
+	_, err = j.Encode(&Cookie{AEAD: AEADAESSIVCMAC256, C2S: make([]byte, 32), S2C: make([]byte, 16)})
+	require.Error(t, err)
+
+	require.ErrorContains(t, j.SetKeys(), "no cookie keys")
+	require.ErrorContains(t, j.SetKeys([]byte("short")), "cookie key must be 32 bytes")
+}
+
+func TestReadCookieKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	content := "# current\n0101010101010101010101010101010101010101010101010101010101010101\n\n0202020202020202020202020202020202020202020202020202020202020202\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	keys, err := ReadCookieKeys(path)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)}, keys)
+
+	require.NoError(t, os.WriteFile(path, []byte("nothex\n"), 0600))
+	_, err = ReadCookieKeys(path)
+	require.ErrorContains(t, err, "parsing cookie key")
+
+	_, err = ReadCookieKeys(filepath.Join(t.TempDir(), "nope"))
+	require.Error(t, err)
+}
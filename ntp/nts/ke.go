@@ -0,0 +1,389 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package nts implements Network Time Security for NTP as described in RFC 8915:
+NTS Key Establishment server and processing of NTS extension fields in NTP packets.
+*/
+package nts
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ALPN is the protocol id NTS-KE negotiates in TLS
+const ALPN = "ntske/1"
+
+// DefaultKEPort is a default NTS-KE TCP port
+const DefaultKEPort = 4460
+
+// exporterLabel is the label used to export NTS keys from TLS session
+const exporterLabel = "EXPORTER-network-time-security"
+
+// cookiesPerKE is how many cookies client gets from NTS-KE
+const cookiesPerKE = 8
+
+// maxRecords limits number of records client can send to us
+const maxRecords = 64
+
+// defaultMaxSessions limits number of concurrent client sessions if KEServer.MaxSessions is not set
+const defaultMaxSessions = 1024
+
+// maxAcceptDelay is the longest we wait before accepting connections again after temporary errors
+const maxAcceptDelay = time.Second
+
+// RecordType is NTS-KE record type
+type RecordType uint16
+
+// NTS-KE record types
+const (
+	RecordEndOfMessage      RecordType = 0
+	RecordNextProtocol      RecordType = 1
+	RecordError             RecordType = 2
+	RecordWarning           RecordType = 3
+	RecordAEADAlgorithm     RecordType = 4
+	RecordNewCookie         RecordType = 5
+	RecordServerNegotiation RecordType = 6
+	RecordPortNegotiation   RecordType = 7
+)
+
+// NTS-KE error codes
+const (
+	ErrorUnrecognizedCritical uint16 = 0
+	ErrorBadRequest           uint16 = 1
+	ErrorInternalServer       uint16 = 2
+)
+
+// ProtocolNTPv4 is the only next protocol we support
+const ProtocolNTPv4 uint16 = 0
+
+// AEADAESSIVCMAC256 is AEAD_AES_SIV_CMAC_256 id, the only AEAD we support
+const AEADAESSIVCMAC256 uint16 = 15
+
+// aeadKeySize is size of C2S and S2C keys for AEAD_AES_SIV_CMAC_256
+const aeadKeySize = 32
+
+// Record is NTS-KE record
+/*
+   0                   1                   2                   3
+   0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+  +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+  |C|         Record Type         |          Body Length          |
+  +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+  |                                                               |
+  ~                           Record Body                         ~
+  |                                                               |
+  +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+type Record struct {
+	Critical bool
+	Type     RecordType
+	Body     []byte
+}
+
+// MarshalBinary converts Record to []bytes
+func (r *Record) MarshalBinary() ([]byte, error) {
+	if len(r.Body) > 0xffff {
+		return nil, fmt.Errorf("record body is too long: %d", len(r.Body))
+	}
+	b := make([]byte, 4, 4+len(r.Body))
+	t := uint16(r.Type) & 0x7fff
+	if r.Critical {
+		t |= 0x8000
+	}
+	binary.BigEndian.PutUint16(b, t)
+	binary.BigEndian.PutUint16(b[2:], uint16(len(r.Body)))
+	return append(b, r.Body...), nil
+}
+
+// ReadRecord reads single NTS-KE record
+func ReadRecord(r io.Reader) (*Record, error) {
+	var h [4]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return nil, err
+	}
+	t := binary.BigEndian.Uint16(h[:])
+	rec := &Record{
+		Critical: t&0x8000 != 0,
+		Type:     RecordType(t & 0x7fff),
+		Body:     make([]byte, binary.BigEndian.Uint16(h[2:])),
+	}
+	if _, err := io.ReadFull(r, rec.Body); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// ReadMessage reads NTS-KE records until End of Message
+func ReadMessage(r io.Reader) ([]*Record, error) {
+	records := []*Record{}
+	for len(records) < maxRecords {
+		rec, err := ReadRecord(r)
+		if err != nil {
+			return nil, err
+		}
+		if rec.Type == RecordEndOfMessage {
+			return records, nil
+		}
+		records = append(records, rec)
+	}
+	return nil, fmt.Errorf("too many records")
+}
+
+// WriteMessage writes NTS-KE records followed by End of Message
+func WriteMessage(w io.Writer, records []*Record) error {
+	var buf bytes.Buffer
+	for _, rec := range append(records, &Record{Critical: true, Type: RecordEndOfMessage}) {
+		b, err := rec.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func uint16s(b []byte) ([]uint16, error) {
+	if len(b)%2 != 0 {
+		return nil, fmt.Errorf("odd body length %d", len(b))
+	}
+	r := make([]uint16, 0, len(b)/2)
+	for i := 0; i < len(b); i += 2 {
+		r = append(r, binary.BigEndian.Uint16(b[i:]))
+	}
+	return r, nil
+}
+
+func uint16Record(t RecordType, v ...uint16) *Record {
+	rec := &Record{Critical: true, Type: t, Body: make([]byte, 0, 2*len(v))}
+	for _, i := range v {
+		rec.Body = binary.BigEndian.AppendUint16(rec.Body, i)
+	}
+	return rec
+}
+
+// errKE is an error reported to NTS-KE client
+type errKE struct {
+	code uint16
+	msg  string
+}
+
+func (e *errKE) Error() string {
+	return fmt.Sprintf("nts-ke error %d: %s", e.code, e.msg)
+}
+
+// KEServer is NTS Key Establishment server
+type KEServer struct {
+	TLSConfig *tls.Config
+	Cookies   *CookieJar
+	// NTP server and port to advertise to clients, if they are different from this host and the default port
+	NTPServer string
+	NTPPort   int
+	// Timeout for the whole client session
+	Timeout time.Duration
+	// MaxSessions limits number of concurrent client sessions, new connections wait in the listen backlog until one ends
+	MaxSessions int
+}
+
+// ListenAndServe listens on addr and serves NTS-KE clients until ctx is done
+func (k *KEServer) ListenAndServe(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	return k.Serve(ln)
+}
+
+// Serve serves NTS-KE clients on the listener
+func (k *KEServer) Serve(ln net.Listener) error {
+	tlsConfig := k.TLSConfig.Clone()
+	tlsConfig.MinVersion = tls.VersionTLS13
+	tlsConfig.NextProtos = []string{ALPN}
+	tlsLn := tls.NewListener(ln, tlsConfig)
+	maxSessions := k.MaxSessions
+	if maxSessions <= 0 {
+		maxSessions = defaultMaxSessions
+	}
+	sessions := make(chan struct{}, maxSessions)
+	var delay time.Duration
+	for {
+		sessions <- struct{}{}
+		conn, err := tlsLn.Accept()
+		if err != nil {
+			<-sessions
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			if !isTemporary(err) {
+				return err
+			}
+			// like running out of file descriptors, back off and let sessions finish
+			delay = min(max(2*delay, 5*time.Millisecond), maxAcceptDelay)
+			log.Warningf("[nts-ke] accept error: %v, retrying in %v", err, delay)
+			time.Sleep(delay)
+			continue
+		}
+		delay = 0
+		go func() {
+			defer func() { <-sessions }()
+			defer conn.Close()
+			if err := k.handle(conn.(*tls.Conn)); err != nil {
+				log.Debugf("[nts-ke] %s: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// isTemporary returns true for Accept errors which go away once other connections are closed or time passes
+func isTemporary(err error) bool {
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	for _, errno := range []syscall.Errno{syscall.EMFILE, syscall.ENFILE, syscall.ENOBUFS, syscall.ENOMEM, syscall.ECONNABORTED} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// handle runs single NTS-KE session
+func (k *KEServer) handle(conn *tls.Conn) error {
+	timeout := k.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	if err := conn.Handshake(); err != nil {
+		return err
+	}
+	state := conn.ConnectionState()
+	if state.NegotiatedProtocol != ALPN {
+		return fmt.Errorf("client didn't negotiate %q", ALPN)
+	}
+	request, err := ReadMessage(conn)
+	if err != nil {
+		return err
+	}
+	response, err := k.respond(request, state.ExportKeyingMaterial)
+	var ke *errKE
+	if errors.As(err, &ke) {
+		// let the client know what's wrong, and still report the error
+		response = []*Record{uint16Record(RecordError, ke.code)}
+	} else if err != nil {
+		response = []*Record{uint16Record(RecordError, ErrorInternalServer)}
+	}
+	if werr := WriteMessage(conn, response); werr != nil {
+		return werr
+	}
+	return err
+}
+
+// respond negotiates protocol and AEAD and returns records with fresh cookies
+func (k *KEServer) respond(request []*Record, export func(label string, context []byte, length int) ([]byte, error)) ([]*Record, error) {
+	var protocols, aeads []uint16
+	var err error
+	for _, rec := range request {
+		switch rec.Type {
+		case RecordNextProtocol:
+			if protocols != nil {
+				return nil, &errKE{code: ErrorBadRequest, msg: "duplicate next protocol record"}
+			}
+			if protocols, err = uint16s(rec.Body); err != nil {
+				return nil, &errKE{code: ErrorBadRequest, msg: err.Error()}
+			}
+		case RecordAEADAlgorithm:
+			if aeads != nil {
+				return nil, &errKE{code: ErrorBadRequest, msg: "duplicate aead record"}
+			}
+			if aeads, err = uint16s(rec.Body); err != nil {
+				return nil, &errKE{code: ErrorBadRequest, msg: err.Error()}
+			}
+		case RecordServerNegotiation, RecordPortNegotiation, RecordWarning:
+			// we tell clients where to go, not the other way around
+		default:
+			if rec.Critical {
+				return nil, &errKE{code: ErrorUnrecognizedCritical, msg: fmt.Sprintf("unrecognized critical record %d", rec.Type)}
+			}
+		}
+	}
+	if protocols == nil {
+		return nil, &errKE{code: ErrorBadRequest, msg: "no next protocol record"}
+	}
+	if !slices.Contains(protocols, ProtocolNTPv4) {
+		// nothing in common, empty next protocol record tells client so
+		return []*Record{uint16Record(RecordNextProtocol)}, nil
+	}
+	// clients must send AEAD algorithms along with NTPv4, RFC 8915 4.1.5
+	if aeads == nil {
+		return nil, &errKE{code: ErrorBadRequest, msg: "no aead record"}
+	}
+	if !slices.Contains(aeads, AEADAESSIVCMAC256) {
+		return []*Record{uint16Record(RecordNextProtocol, ProtocolNTPv4), uint16Record(RecordAEADAlgorithm)}, nil
+	}
+
+	c := &Cookie{AEAD: AEADAESSIVCMAC256}
+	// context is next protocol, AEAD and direction
+	keyContext := []byte{0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(keyContext, ProtocolNTPv4)
+	binary.BigEndian.PutUint16(keyContext[2:], AEADAESSIVCMAC256)
+	if c.C2S, err = export(exporterLabel, keyContext, aeadKeySize); err != nil {
+		return nil, err
+	}
+	keyContext[4] = 1
+	if c.S2C, err = export(exporterLabel, keyContext, aeadKeySize); err != nil {
+		return nil, err
+	}
+
+	response := []*Record{
+		uint16Record(RecordNextProtocol, ProtocolNTPv4),
+		uint16Record(RecordAEADAlgorithm, AEADAESSIVCMAC256),
+	}
+	if k.NTPServer != "" {
+		response = append(response, &Record{Critical: true, Type: RecordServerNegotiation, Body: []byte(k.NTPServer)})
+	}
+	if k.NTPPort != 0 {
+		response = append(response, uint16Record(RecordPortNegotiation, uint16(k.NTPPort)))
+	}
+	for i := 0; i < cookiesPerKE; i++ {
+		cookie, err := k.Cookies.Encode(c)
+		if err != nil {
+			return nil, err
+		}
+		response = append(response, &Record{Type: RecordNewCookie, Body: cookie})
+	}
+	return response, nil
+}
@@ -0,0 +1,255 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nts
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func fakeExport(label string, context []byte, length int) ([]byte, error) {
+	return bytes.Repeat([]byte{context[4]}, length), nil
+}
+
+func TestRecordMarshalBinary(t *testing.T) {
+	r := uint16Record(RecordNextProtocol, ProtocolNTPv4)
+	b, err := r.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x80, 0x01, 0x00, 0x02, 0x00, 0x00}, b)
+
+	parsed, err := ReadRecord(bytes.NewReader(b))
+	require.NoError(t, err)
+	require.Equal(t, r, parsed)
+
+	_, err = ReadRecord(bytes.NewReader(b[:5]))
+	require.Error(t, err)
+
+	r.Body = make([]byte, 0x10000)
+	_, err = r.MarshalBinary()
+	require.Error(t, err)
+}
+
+func TestReadWriteMessage(t *testing.T) {
+	var buf bytes.Buffer
+	records := []*Record{uint16Record(RecordNextProtocol, ProtocolNTPv4), {Type: RecordNewCookie, Body: []byte("cookie")}}
+	require.NoError(t, WriteMessage(&buf, records))
+	parsed, err := ReadMessage(&buf)
+	require.NoError(t, err)
+	require.Equal(t, records, parsed)
+
+	// no end of message
+	_, err = ReadMessage(bytes.NewReader([]byte{0x80, 0x01, 0x00, 0x00}))
+	require.Error(t, err)
+}
+
+func TestKERespond(t *testing.T) {
+	j, err := NewCookieJar(make([]byte, CookieKeySize))
+	require.NoError(t, err)
+	k := &KEServer{Cookies: j, NTPServer: "ntp.example.com", NTPPort: 1123}
+
+	response, err := k.respond([]*Record{
+		uint16Record(RecordNextProtocol, ProtocolNTPv4),
+		uint16Record(RecordAEADAlgorithm, 16, AEADAESSIVCMAC256),
+		{Type: 0x4000, Body: []byte("unknown but not critical")},
+	}, fakeExport)
+	require.NoError(t, err)
+	require.Len(t, response, 4+cookiesPerKE)
+	require.Equal(t, uint16Record(RecordNextProtocol, ProtocolNTPv4), response[0])
+	require.Equal(t, uint16Record(RecordAEADAlgorithm, AEADAESSIVCMAC256), response[1])
+	require.Equal(t, &Record{Critical: true, Type: RecordServerNegotiation, Body: []byte("ntp.example.com")}, response[2])
+	require.Equal(t, uint16Record(RecordPortNegotiation, 1123), response[3])
+	for _, rec := range response[4:] {
+		require.Equal(t, RecordNewCookie, rec.Type)
+		c, err := j.Decode(rec.Body)
+		require.NoError(t, err)
+		require.Equal(t, &Cookie{AEAD: AEADAESSIVCMAC256, C2S: make([]byte, 32), S2C: bytes.Repeat([]byte{1}, 32)}, c)
+	}
+
+	// nothing in common
+	response, err = k.respond([]*Record{uint16Record(RecordNextProtocol, 42)}, fakeExport)
+	require.NoError(t, err)
+	require.Equal(t, []*Record{uint16Record(RecordNextProtocol)}, response)
+	response, err = k.respond([]*Record{uint16Record(RecordNextProtocol, ProtocolNTPv4), uint16Record(RecordAEADAlgorithm, 16)}, fakeExport)
+	require.NoError(t, err)
+	require.Equal(t, []*Record{uint16Record(RecordNextProtocol, ProtocolNTPv4), uint16Record(RecordAEADAlgorithm)}, response)
+
+	// bad requests
+	for _, request := range [][]*Record{
+		{},
+		{uint16Record(RecordNextProtocol, ProtocolNTPv4), uint16Record(RecordNextProtocol, ProtocolNTPv4)},
+		{{Critical: true, Type: RecordNextProtocol, Body: []byte{0}}},
+		{uint16Record(RecordNextProtocol, ProtocolNTPv4), {Critical: true, Type: RecordAEADAlgorithm, Body: []byte{0}}},
+		// no aead record
+		{uint16Record(RecordNextProtocol, ProtocolNTPv4)},
+	} {
+		_, err = k.respond(request, fakeExport)
+		require.Equal(t, ErrorBadRequest, err.(*errKE).code)
+	}
+	_, err = k.respond([]*Record{uint16Record(RecordNextProtocol, ProtocolNTPv4), {Critical: true, Type: 0x4000}}, fakeExport)
+	require.Equal(t, ErrorUnrecognizedCritical, err.(*errKE).code)
+}
+
+func TestKEServer(t *testing.T) {
+	j, err := NewCookieJar(make([]byte, CookieKeySize))
+	require.NoError(t, err)
+	cert := testCertificate(t)
+	k := &KEServer{TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}}, Cookies: j}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		_ = k.Serve(ln)
+	}()
+
+	pool := x509.NewCertPool()
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	pool.AddCert(parsed)
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{RootCAs: pool, ServerName: "localhost", NextProtos: []string{ALPN}})
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, WriteMessage(conn, []*Record{
+		uint16Record(RecordNextProtocol, ProtocolNTPv4),
+		uint16Record(RecordAEADAlgorithm, AEADAESSIVCMAC256),
+	}))
+	response, err := ReadMessage(conn)
+	require.NoError(t, err)
+	require.Len(t, response, 2+cookiesPerKE)
+
+	// client and server derived the same keys
+	state := conn.ConnectionState()
+	c2s, err := state.ExportKeyingMaterial(exporterLabel, []byte{0, 0, 0, 15, 0}, 32)
+	require.NoError(t, err)
+	s2c, err := state.ExportKeyingMaterial(exporterLabel, []byte{0, 0, 0, 15, 1}, 32)
+	require.NoError(t, err)
+	c, err := j.Decode(response[2].Body)
+	require.NoError(t, err)
+	require.Equal(t, &Cookie{AEAD: AEADAESSIVCMAC256, C2S: c2s, S2C: s2c}, c)
+
+	// errors are reported to the client
+	conn, err = tls.Dial("tcp", ln.Addr().String(), &tls.Config{RootCAs: pool, ServerName: "localhost", NextProtos: []string{ALPN}})
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, WriteMessage(conn, nil))
+	response, err = ReadMessage(conn)
+	require.NoError(t, err)
+	require.Equal(t, []*Record{uint16Record(RecordError, ErrorBadRequest)}, response)
+}
+
+func TestIsTemporary(t *testing.T) {
+	require.True(t, isTemporary(&net.OpError{Op: "accept", Err: os.NewSyscallError("accept", syscall.EMFILE)}))
+	require.True(t, isTemporary(os.ErrDeadlineExceeded))
+	require.False(t, isTemporary(net.ErrClosed))
+	require.False(t, isTemporary(&net.OpError{Op: "accept", Err: os.NewSyscallError("accept", syscall.EINVAL)}))
+}
+
+// flakyListener fails Accept with given errors, then behaves as closed
+type flakyListener struct {
+	net.Listener
+	errs    []error
+	accepts int
+}
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	l.accepts++
+	if len(l.errs) > 0 {
+		err := l.errs[0]
+		l.errs = l.errs[1:]
+		return nil, err
+	}
+	return nil, net.ErrClosed
+}
+
+func TestKEServerAcceptErrors(t *testing.T) {
+	k := &KEServer{TLSConfig: &tls.Config{}}
+	emfile := &net.OpError{Op: "accept", Err: os.NewSyscallError("accept", syscall.EMFILE)}
+	ln := &flakyListener{errs: []error{emfile, emfile}}
+	require.NoError(t, k.Serve(ln))
+	require.Equal(t, 3, ln.accepts)
+
+	ln = &flakyListener{errs: []error{errors.New("boom")}}
+	require.EqualError(t, k.Serve(ln), "boom")
+	require.Equal(t, 1, ln.accepts)
+}
+
+func TestKEServerMaxSessions(t *testing.T) {
+	j, err := NewCookieJar(make([]byte, CookieKeySize))
+	require.NoError(t, err)
+	cert := testCertificate(t)
+	k := &KEServer{TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}}, Cookies: j, MaxSessions: 1}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		_ = k.Serve(ln)
+	}()
+
+	pool := x509.NewCertPool()
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	pool.AddCert(parsed)
+	clientConfig := &tls.Config{RootCAs: pool, ServerName: "localhost", NextProtos: []string{ALPN}}
+
+	// first client holds the only session
+	first, err := tls.Dial("tcp", ln.Addr().String(), clientConfig)
+	require.NoError(t, err)
+	// second one waits in the backlog, so handshake doesn't complete
+	_, err = tls.DialWithDialer(&net.Dialer{Timeout: 200 * time.Millisecond}, "tcp", ln.Addr().String(), clientConfig)
+	require.Error(t, err)
+
+	// session is released once the first client is done
+	require.NoError(t, first.Close())
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", ln.Addr().String(), clientConfig)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, WriteMessage(conn, []*Record{
+		uint16Record(RecordNextProtocol, ProtocolNTPv4),
+		uint16Record(RecordAEADAlgorithm, AEADAESSIVCMAC256),
+	}))
+	response, err := ReadMessage(conn)
+	require.NoError(t, err)
+	require.Len(t, response, 2+cookiesPerKE)
+}
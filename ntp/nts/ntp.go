@@ -0,0 +1,258 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nts
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+)
+
+// headerSizeBytes is a size of NTP packet without extension fields
+const headerSizeBytes = 48
+
+// NTS extension field types
+const (
+	ExtUniqueIdentifier  uint16 = 0x0104
+	ExtCookie            uint16 = 0x0204
+	ExtCookiePlaceholder uint16 = 0x0304
+	ExtAuthenticator     uint16 = 0x0404
+)
+
+func init() {
+	_ = ntp.RegisterExtension(ExtUniqueIdentifier, "ntsuniqueid")
+	_ = ntp.RegisterExtension(ExtCookie, "ntscookie")
+	_ = ntp.RegisterExtension(ExtCookiePlaceholder, "ntscookieplaceholder")
+	_ = ntp.RegisterExtension(ExtAuthenticator, "ntsauthenticator")
+}
+
+// minUniqueIdentifierLen is a minimum size of unique identifier client must send
+const minUniqueIdentifierLen = 32
+
+// KissCode is sent by server that failed to validate cookie or authenticate the request
+const KissCode = "NTSN"
+
+// ErrMalformed means the packet is not a valid NTS request and must be dropped
+var ErrMalformed = errors.New("malformed nts request")
+
+// ErrNAK means request can't be authenticated and server should respond with NTS NAK
+var ErrNAK = errors.New("nts authentication failed")
+
+// extensionField is NTPv4 extension field from RFC 7822
+/*
+   0                   1                   2                   3
+   0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+  +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+  |          Field Type           |            Length             |
+  +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+  .                                                               .
+  .                            Value                              .
+  .                                                               .
+  +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+  |                       Padding (as needed)                     |
+  +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+type extensionField struct {
+	Type  uint16
+	Value []byte // including padding
+	start int    // offset of the field in the packet
+}
+
+// parseExtensionFields parses extension fields until the end of b
+func parseExtensionFields(b []byte, offset int) ([]extensionField, error) {
+	fields := []extensionField{}
+	for pos := 0; pos < len(b); {
+		f, length, err := ntp.ReadExtensionField(b[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+		}
+		fields = append(fields, extensionField{Type: f.Type, Value: f.Value, start: offset + pos})
+		pos += length
+	}
+	return fields, nil
+}
+
+// appendExtensionField appends extension field with value padded to 4 bytes
+func appendExtensionField(b []byte, t uint16, value []byte) []byte {
+	return ntp.AppendExtensionField(b, &ntp.ExtensionField{Type: t, Value: value})
+}
+
+// Request is NTP request protected with NTS
+type Request struct {
+	UniqueID     []byte
+	Cookie       *Cookie
+	Placeholders int // how many extra cookies client asked for
+	cookieLen    int
+}
+
+// IsNTS returns whether the NTP packet may be an NTS request.
+// Its first extension field has to be NTS unique identifier or cookie, so requests with legacy MAC aren't mistaken for NTS
+func IsNTS(b []byte) bool {
+	if len(b) <= headerSizeBytes || ntp.IsMAC(b[headerSizeBytes:]) {
+		return false
+	}
+	f, _, err := ntp.ReadExtensionField(b[headerSizeBytes:])
+	if err != nil {
+		return false
+	}
+	switch f.Type {
+	case ExtUniqueIdentifier:
+		return len(f.Value) >= minUniqueIdentifierLen
+	case ExtCookie:
+		return len(f.Value) > 0
+	}
+	return false
+}
+
+// ParseRequest parses and authenticates NTS request.
+// Errors wrap ErrNAK if request is well-formed but can't be authenticated,
+// the returned request still has UniqueID the NAK must echo in that case
+func (j *CookieJar) ParseRequest(b []byte) (*Request, error) {
+	if len(b) <= headerSizeBytes {
+		return nil, fmt.Errorf("%w: no extension fields", ErrMalformed)
+	}
+	fields, err := parseExtensionFields(b[headerSizeBytes:], headerSizeBytes)
+	if err != nil {
+		return nil, err
+	}
+	r := &Request{}
+	placeholders := []int{}
+	var cookie []byte
+	var auth *extensionField
+	for i, f := range fields {
+		if auth != nil {
+			return nil, fmt.Errorf("%w: extension fields after authenticator", ErrMalformed)
+		}
+		switch f.Type {
+		case ExtUniqueIdentifier:
+			if r.UniqueID != nil || len(f.Value) < minUniqueIdentifierLen {
+				return nil, fmt.Errorf("%w: bad unique identifier", ErrMalformed)
+			}
+			r.UniqueID = f.Value
+		case ExtCookie:
+			if cookie != nil {
+				return nil, fmt.Errorf("%w: more than one cookie", ErrMalformed)
+			}
+			cookie = f.Value
+		case ExtCookiePlaceholder:
+			placeholders = append(placeholders, len(f.Value))
+		case ExtAuthenticator:
+			auth = &fields[i]
+		}
+	}
+	if r.UniqueID == nil || cookie == nil || auth == nil {
+		return nil, fmt.Errorf("%w: unique identifier, cookie and authenticator are required", ErrMalformed)
+	}
+	r.cookieLen = len(cookie)
+	nonce, ciphertext, err := parseAuthenticator(auth.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Cookie, err = j.Decode(cookie); err != nil {
+		return r, fmt.Errorf("%w: %w", ErrNAK, err)
+	}
+	if r.Cookie.AEAD != AEADAESSIVCMAC256 {
+		return r, fmt.Errorf("%w: unsupported aead %d", ErrNAK, r.Cookie.AEAD)
+	}
+	a, err := NewAESSIVCMAC(r.Cookie.C2S)
+	if err != nil {
+		return r, fmt.Errorf("%w: %w", ErrNAK, err)
+	}
+	plaintext, err := a.Open(nil, nonce, ciphertext, b[:auth.start])
+	if err != nil {
+		return r, fmt.Errorf("%w: %w", ErrNAK, err)
+	}
+	// encrypted extension fields can ask for more cookies as well
+	encrypted, err := parseExtensionFields(plaintext, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range encrypted {
+		if f.Type == ExtCookiePlaceholder {
+			placeholders = append(placeholders, len(f.Value))
+		}
+	}
+	// placeholders must be as big as cookies, so response is never bigger than the request
+	for _, l := range placeholders {
+		if l >= len(cookie) && r.Placeholders < cookiesPerKE-1 {
+			r.Placeholders++
+		}
+	}
+	return r, nil
+}
+
+// parseAuthenticator returns nonce and ciphertext from NTS Authenticator and Encrypted Extension Fields value
+func parseAuthenticator(v []byte) (nonce, ciphertext []byte, err error) {
+	if len(v) < 4 {
+		return nil, nil, fmt.Errorf("%w: truncated authenticator", ErrMalformed)
+	}
+	nonceLen := int(binary.BigEndian.Uint16(v))
+	ciphertextLen := int(binary.BigEndian.Uint16(v[2:]))
+	nonceEnd := 4 + (nonceLen+3)&^3
+	if nonceLen == 0 || nonceEnd+ciphertextLen > len(v) {
+		return nil, nil, fmt.Errorf("%w: bad authenticator lengths", ErrMalformed)
+	}
+	return v[4 : 4+nonceLen], v[nonceEnd : nonceEnd+ciphertextLen], nil
+}
+
+// appendAuthenticator appends NTS Authenticator and Encrypted Extension Fields extension field
+func appendAuthenticator(b []byte, nonce, ciphertext []byte) []byte {
+	v := binary.BigEndian.AppendUint16(nil, uint16(len(nonce)))
+	v = binary.BigEndian.AppendUint16(v, uint16(len(ciphertext)))
+	v = append(v, nonce...)
+	v = append(v, make([]byte, (len(nonce)+3)&^3-len(nonce))...)
+	v = append(v, ciphertext...)
+	return appendExtensionField(b, ExtAuthenticator, v)
+}
+
+// Response appends NTS extension fields with fresh cookies to NTP response header
+func (j *CookieJar) Response(header []byte, r *Request) ([]byte, error) {
+	b := make([]byte, 0, len(header)+(r.Placeholders+2)*(r.cookieLen+64))
+	b = append(b, header...)
+	b = appendExtensionField(b, ExtUniqueIdentifier, r.UniqueID)
+	plaintext := []byte{}
+	for i := 0; i <= r.Placeholders; i++ {
+		cookie, err := j.Encode(r.Cookie)
+		if err != nil {
+			return nil, err
+		}
+		plaintext = appendExtensionField(plaintext, ExtCookie, cookie)
+	}
+	a, err := NewAESSIVCMAC(r.Cookie.S2C)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, sivBlockSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return appendAuthenticator(b, nonce, a.Seal(nil, nonce, plaintext, b)), nil
+}
+
+// NAK turns NTP response header into NTS NAK: Kiss-o'-Death with NTSN kiss code echoing unique identifier of the request
+func NAK(header []byte, uniqueID []byte) []byte {
+	b := make([]byte, 0, len(header)+4+len(uniqueID))
+	b = append(b, header...)
+	// stratum 0 and kiss code in reference id
+	b[1] = 0
+	copy(b[12:16], KissCode)
+	return appendExtensionField(b, ExtUniqueIdentifier, uniqueID)
+}
@@ -0,0 +1,200 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nts
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testRequest builds NTS request the way client does
+func testRequest(t *testing.T, cookie []byte, c2s []byte, placeholders int) []byte {
+	b := make([]byte, headerSizeBytes)
+	b[0] = 0x23
+	b = appendExtensionField(b, ExtUniqueIdentifier, bytes.Repeat([]byte{0xaa}, 32))
+	b = appendExtensionField(b, ExtCookie, cookie)
+	for i := 0; i < placeholders; i++ {
+		b = appendExtensionField(b, ExtCookiePlaceholder, make([]byte, len(cookie)))
+	}
+	a, err := NewAESSIVCMAC(c2s)
+	require.NoError(t, err)
+	nonce := bytes.Repeat([]byte{0xbb}, 16)
+	return appendAuthenticator(b, nonce, a.Seal(nil, nonce, nil, b))
+}
+
+func TestParseExtensionFields(t *testing.T) {
+	b := appendExtensionField(nil, ExtUniqueIdentifier, []byte("odd"))
+	b = appendExtensionField(b, ExtCookie, []byte("cookie"))
+	fields, err := parseExtensionFields(b, 48)
+	require.NoError(t, err)
+	require.Equal(t, []extensionField{
+		{Type: ExtUniqueIdentifier, Value: []byte("odd\x00"), start: 48},
+		{Type: ExtCookie, Value: []byte("cookie\x00\x00"), start: 56},
+	}, fields)
+
+	for _, bad := range [][]byte{b[:2], b[:6], {0x01, 0x04, 0x00, 0x03}, {0x01, 0x04, 0x00, 0x02}} {
+		_, err = parseExtensionFields(bad, 48)
+		require.ErrorIs(t, err, ErrMalformed)
+	}
+}
+
+func TestRequestResponse(t *testing.T) {
+	j, err := NewCookieJar(make([]byte, CookieKeySize))
+	require.NoError(t, err)
+	c := &Cookie{AEAD: AEADAESSIVCMAC256, C2S: bytes.Repeat([]byte{1}, 32), S2C: bytes.Repeat([]byte{2}, 32)}
+	cookie, err := j.Encode(c)
+	require.NoError(t, err)
+
+	require.False(t, IsNTS(make([]byte, headerSizeBytes)))
+	request := testRequest(t, cookie, c.C2S, 2)
+	require.True(t, IsNTS(request))
+	r, err := j.ParseRequest(request)
+	require.NoError(t, err)
+	require.Equal(t, bytes.Repeat([]byte{0xaa}, 32), r.UniqueID)
+	require.Equal(t, c, r.Cookie)
+	require.Equal(t, 2, r.Placeholders)
+
+	header := bytes.Repeat([]byte{0x24}, headerSizeBytes)
+	response, err := j.Response(header, r)
+	require.NoError(t, err)
+	require.LessOrEqual(t, len(response), len(request))
+	require.Equal(t, header, response[:headerSizeBytes])
+
+	// client side: unique identifier is echoed and cookies are authenticated with S2C key
+	fields, err := parseExtensionFields(response[headerSizeBytes:], headerSizeBytes)
+	require.NoError(t, err)
+	require.Len(t, fields, 2)
+	require.Equal(t, ExtUniqueIdentifier, fields[0].Type)
+	require.Equal(t, r.UniqueID, fields[0].Value)
+	require.Equal(t, ExtAuthenticator, fields[1].Type)
+	nonce, ciphertext, err := parseAuthenticator(fields[1].Value)
+	require.NoError(t, err)
+	a, err := NewAESSIVCMAC(c.S2C)
+	require.NoError(t, err)
+	plaintext, err := a.Open(nil, nonce, ciphertext, response[:fields[1].start])
+	require.NoError(t, err)
+	cookies, err := parseExtensionFields(plaintext, 0)
+	require.NoError(t, err)
+	require.Len(t, cookies, 3)
+	for _, f := range cookies {
+		require.Equal(t, ExtCookie, f.Type)
+		decoded, err := j.Decode(f.Value)
+		require.NoError(t, err)
+		require.Equal(t, c, decoded)
+	}
+
+	// placeholders smaller than the cookie don't count
+	request = appendExtensionField(make([]byte, headerSizeBytes), ExtUniqueIdentifier, bytes.Repeat([]byte{0xaa}, 32))
+	request = appendExtensionField(request, ExtCookie, cookie)
+	request = appendExtensionField(request, ExtCookiePlaceholder, make([]byte, 4))
+	nonce = bytes.Repeat([]byte{0xbb}, 16)
+	a, err = NewAESSIVCMAC(c.C2S)
+	require.NoError(t, err)
+	request = appendAuthenticator(request, nonce, a.Seal(nil, nonce, nil, request))
+	r, err = j.ParseRequest(request)
+	require.NoError(t, err)
+	require.Equal(t, 0, r.Placeholders)
+}
+
+func TestIsNTS(t *testing.T) {
+	header := make([]byte, headerSizeBytes)
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(append([][]byte{header}, parts...), nil)
+	}
+	uid := appendExtensionField(nil, ExtUniqueIdentifier, bytes.Repeat([]byte{0xaa}, 32))
+	cookie := appendExtensionField(nil, ExtCookie, []byte("cookie"))
+	for name, tc := range map[string]struct {
+		b    []byte
+		want bool
+	}{
+		"header only":             {header, false},
+		"unique identifier first": {join(uid, cookie), true},
+		"cookie first":            {join(cookie, uid), true},
+		"sha1 mac":                {join(bytes.Repeat([]byte{1}, 24)), false},
+		"md5 mac":                 {join(bytes.Repeat([]byte{1}, 20)), false},
+		"short unique identifier": {join(appendExtensionField(nil, ExtUniqueIdentifier, []byte("short"))), false},
+		"other extension field":   {join(appendExtensionField(nil, ExtCookiePlaceholder, []byte("cookie"))), false},
+		"malformed field":         {join([]byte{0x01, 0x04, 0xff, 0xff}), false},
+	} {
+		require.Equal(t, tc.want, IsNTS(tc.b), name)
+	}
+}
+
+func TestParseRequestNAK(t *testing.T) {
+	j, err := NewCookieJar(make([]byte, CookieKeySize))
+	require.NoError(t, err)
+	c := &Cookie{AEAD: AEADAESSIVCMAC256, C2S: bytes.Repeat([]byte{1}, 32), S2C: bytes.Repeat([]byte{2}, 32)}
+	cookie, err := j.Encode(c)
+	require.NoError(t, err)
+
+	// wrong key
+	r, err := j.ParseRequest(testRequest(t, cookie, c.S2C, 0))
+	require.ErrorIs(t, err, ErrNAK)
+	require.Equal(t, bytes.Repeat([]byte{0xaa}, 32), r.UniqueID)
+
+	// cookie from another server
+	other, err := NewCookieJar(bytes.Repeat([]byte{1}, CookieKeySize))
+	require.NoError(t, err)
+	_, err = other.ParseRequest(testRequest(t, cookie, c.C2S, 0))
+	require.ErrorIs(t, err, ErrNAK)
+
+	// tampered header
+	request := testRequest(t, cookie, c.C2S, 0)
+	request[1] = 1
+	_, err = j.ParseRequest(request)
+	require.ErrorIs(t, err, ErrNAK)
+
+	header := make([]byte, headerSizeBytes)
+	header[1] = 1
+	nak := NAK(header, r.UniqueID)
+	require.Equal(t, uint8(1), header[1])
+	require.Equal(t, uint8(0), nak[1])
+	require.Equal(t, KissCode, string(nak[12:16]))
+	fields, err := parseExtensionFields(nak[headerSizeBytes:], headerSizeBytes)
+	require.NoError(t, err)
+	require.Equal(t, []extensionField{{Type: ExtUniqueIdentifier, Value: r.UniqueID, start: headerSizeBytes}}, fields)
+}
+
+func TestParseRequestMalformed(t *testing.T) {
+	j, err := NewCookieJar(make([]byte, CookieKeySize))
+	require.NoError(t, err)
+	header := make([]byte, headerSizeBytes)
+	uid := appendExtensionField(nil, ExtUniqueIdentifier, bytes.Repeat([]byte{0xaa}, 32))
+	cookie := appendExtensionField(nil, ExtCookie, []byte("cookie"))
+	auth := appendAuthenticator(nil, bytes.Repeat([]byte{0xbb}, 16), make([]byte, 16))
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(append([][]byte{header}, parts...), nil)
+	}
+	for name, request := range map[string][]byte{
+		"no extension fields":       header,
+		"no unique identifier":      join(cookie, auth),
+		"short unique identifier":   join(appendExtensionField(nil, ExtUniqueIdentifier, []byte("short")), cookie, auth),
+		"no cookie":                 join(uid, auth),
+		"two cookies":               join(uid, cookie, cookie, auth),
+		"no authenticator":          join(uid, cookie),
+		"fields after auth":         join(uid, cookie, auth, uid),
+		"bad authenticator":         join(uid, cookie, appendExtensionField(nil, ExtAuthenticator, []byte{0, 16, 0, 16})),
+		"truncated authenticator":   join(uid, cookie, appendExtensionField(nil, ExtAuthenticator, []byte{0})),
+		"truncated extension field": join(uid, cookie, auth[:6]),
+	} {
+		_, err := j.ParseRequest(request)
+		require.True(t, errors.Is(err, ErrMalformed), "%s: %v", name, err)
+	}
+}
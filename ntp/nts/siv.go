@@ -0,0 +1,179 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nts
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+)
+
+// sivBlockSize is a size of AES block, S2V and tag
+const sivBlockSize = aes.BlockSize
+
+var errOpen = errors.New("message authentication failed")
+
+// siv implements AES-SIV-CMAC AEAD as described in RFC 5297.
+// Unlike most AEADs it accepts nonces of any length, which NTS needs for client-chosen nonces.
+type siv struct {
+	mac      cipher.Block // K1, used by S2V
+	ctr      cipher.Block // K2, used for encryption
+	k1, k2   [sivBlockSize]byte
+	zeroCMAC [sivBlockSize]byte
+}
+
+// NewAESSIVCMAC returns AES-SIV-CMAC AEAD. Key must be 32, 48 or 64 bytes long for
+// AEAD_AES_SIV_CMAC_256, AEAD_AES_SIV_CMAC_384 or AEAD_AES_SIV_CMAC_512 respectively
+func NewAESSIVCMAC(key []byte) (cipher.AEAD, error) {
+	switch len(key) {
+	case 32, 48, 64:
+	default:
+		return nil, fmt.Errorf("invalid AES-SIV-CMAC key size %d", len(key))
+	}
+	half := len(key) / 2
+	mac, err := aes.NewCipher(key[:half])
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := aes.NewCipher(key[half:])
+	if err != nil {
+		return nil, err
+	}
+	s := &siv{mac: mac, ctr: ctr}
+	// CMAC subkeys
+	var l [sivBlockSize]byte
+	mac.Encrypt(l[:], l[:])
+	s.k1 = dbl(l)
+	s.k2 = dbl(s.k1)
+	s.zeroCMAC = s.cmac(make([]byte, sivBlockSize))
+	return s, nil
+}
+
+// NonceSize returns recommended nonce size, any length is accepted
+func (s *siv) NonceSize() int {
+	return sivBlockSize
+}
+
+// Overhead returns size of synthetic IV prepended to the ciphertext
+func (s *siv) Overhead() int {
+	return sivBlockSize
+}
+
+// Seal encrypts and authenticates plaintext, authenticates additional data and nonce, and appends V || C to dst
+func (s *siv) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	v := s.s2v(additionalData, nonce, plaintext)
+	ret, out := sliceForAppend(dst, sivBlockSize+len(plaintext))
+	copy(out, v[:])
+	s.xorCTR(out[sivBlockSize:], plaintext, v)
+	return ret
+}
+
+// Open decrypts and authenticates ciphertext produced by Seal, and appends the plaintext to dst
+func (s *siv) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < sivBlockSize {
+		return nil, errOpen
+	}
+	var v [sivBlockSize]byte
+	copy(v[:], ciphertext)
+	ret, out := sliceForAppend(dst, len(ciphertext)-sivBlockSize)
+	s.xorCTR(out, ciphertext[sivBlockSize:], v)
+	t := s.s2v(additionalData, nonce, out)
+	if subtle.ConstantTimeCompare(t[:], v[:]) != 1 {
+		clear(out)
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+// xorCTR runs AES-CTR with counter derived from synthetic IV
+func (s *siv) xorCTR(dst, src []byte, v [sivBlockSize]byte) {
+	// clear 31st and 63rd bits so implementations can use 32 and 64 bit counters
+	v[8] &= 0x7f
+	v[12] &= 0x7f
+	cipher.NewCTR(s.ctr, v[:]).XORKeyStream(dst, src)
+}
+
+// s2v is a pseudo-random function over a vector of strings: additional data, nonce (if any) and plaintext
+func (s *siv) s2v(additionalData, nonce, plaintext []byte) [sivBlockSize]byte {
+	d := s.zeroCMAC
+	d = xorBlock(dbl(d), s.cmac(additionalData))
+	if len(nonce) > 0 {
+		d = xorBlock(dbl(d), s.cmac(nonce))
+	}
+	if len(plaintext) >= sivBlockSize {
+		t := make([]byte, len(plaintext))
+		copy(t, plaintext)
+		subtle.XORBytes(t[len(t)-sivBlockSize:], t[len(t)-sivBlockSize:], d[:])
+		return s.cmac(t)
+	}
+	var t [sivBlockSize]byte
+	copy(t[:], plaintext)
+	t[len(plaintext)] = 0x80
+	t = xorBlock(dbl(d), t)
+	return s.cmac(t[:])
+}
+
+// cmac computes AES-CMAC as described in RFC 4493
+func (s *siv) cmac(msg []byte) [sivBlockSize]byte {
+	var x [sivBlockSize]byte
+	for len(msg) > sivBlockSize {
+		subtle.XORBytes(x[:], x[:], msg[:sivBlockSize])
+		s.mac.Encrypt(x[:], x[:])
+		msg = msg[sivBlockSize:]
+	}
+	var last [sivBlockSize]byte
+	copy(last[:], msg)
+	if len(msg) == sivBlockSize {
+		last = xorBlock(last, s.k1)
+	} else {
+		last[len(msg)] = 0x80
+		last = xorBlock(last, s.k2)
+	}
+	x = xorBlock(x, last)
+	s.mac.Encrypt(x[:], x[:])
+	return x
+}
+
+// dbl multiplies block by x in GF(2^128)
+func dbl(b [sivBlockSize]byte) [sivBlockSize]byte {
+	var r [sivBlockSize]byte
+	carry := b[0] >> 7
+	for i := 0; i < sivBlockSize-1; i++ {
+		r[i] = b[i]<<1 | b[i+1]>>7
+	}
+	r[sivBlockSize-1] = b[sivBlockSize-1]<<1 ^ 0x87*carry
+	return r
+}
+
+func xorBlock(a, b [sivBlockSize]byte) [sivBlockSize]byte {
+	subtle.XORBytes(a[:], a[:], b[:])
+	return a
+}
+
+// sliceForAppend extends dst by n bytes, returning the whole slice and the tail
+func sliceForAppend(dst []byte, n int) (head, tail []byte) {
+	if total := len(dst) + n; cap(dst) >= total {
+		head = dst[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, dst)
+	}
+	tail = head[len(dst):]
+	return
+}
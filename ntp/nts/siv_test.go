@@ -0,0 +1,84 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nts
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestCMAC(t *testing.T) {
+	// RFC 4493 examples, CMAC key is the first half of SIV key
+	a, err := NewAESSIVCMAC(unhex(t, "2b7e151628aed2a6abf7158809cf4f3c000102030405060708090a0b0c0d0e0f"))
+	require.NoError(t, err)
+	s := a.(*siv)
+	tag := s.cmac(nil)
+	require.Equal(t, "bb1d6929e95937287fa37d129b756746", hex.EncodeToString(tag[:]))
+	tag = s.cmac(unhex(t, "6bc1bee22e409f96e93d7e117393172a"))
+	require.Equal(t, "070a16b46b4d4144f79bdd9dd04a287c", hex.EncodeToString(tag[:]))
+}
+
+func TestSIVDeterministic(t *testing.T) {
+	// RFC 5297 A.1
+	a, err := NewAESSIVCMAC(unhex(t, "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff"))
+	require.NoError(t, err)
+	ad := unhex(t, "101112131415161718191a1b1c1d1e1f2021222324252627")
+	plaintext := unhex(t, "112233445566778899aabbccddee")
+	sealed := a.Seal(nil, nil, plaintext, ad)
+	require.Equal(t, "85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c", hex.EncodeToString(sealed))
+
+	opened, err := a.Open(nil, nil, sealed, ad)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, opened)
+}
+
+func TestSIVSealOpen(t *testing.T) {
+	a, err := NewAESSIVCMAC(make([]byte, 32))
+	require.NoError(t, err)
+	require.Equal(t, 16, a.NonceSize())
+	require.Equal(t, 16, a.Overhead())
+	nonce := []byte("0123456789abcdef")
+	ad := []byte("header")
+	for _, plaintext := range [][]byte{nil, []byte("short"), []byte("exactly 16 bytes"), []byte("longer than a single AES block")} {
+		sealed := a.Seal([]byte("prefix"), nonce, plaintext, ad)
+		require.Equal(t, "prefix", string(sealed[:6]))
+		opened, err := a.Open(nil, nonce, sealed[6:], ad)
+		require.NoError(t, err)
+		require.Equal(t, string(plaintext), string(opened))
+
+		_, err = a.Open(nil, []byte("another nonce"), sealed[6:], ad)
+		require.Error(t, err)
+		_, err = a.Open(nil, nonce, sealed[6:], []byte("another header"))
+		require.Error(t, err)
+		sealed[len(sealed)-1] ^= 1
+		_, err = a.Open(nil, nonce, sealed[6:], ad)
+		require.Error(t, err)
+	}
+	_, err = a.Open(nil, nonce, []byte("short"), ad)
+	require.Error(t, err)
+
+	_, err = NewAESSIVCMAC(make([]byte, 16))
+	require.Error(t, err)
+}
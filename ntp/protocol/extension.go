@@ -0,0 +1,149 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// extensionHeaderSize is the size of Field Type and Length of extension field
+const extensionHeaderSize = 4
+
+// legacy MAC sizes: key id followed by MD5, or SHA1 and truncated longer digests
+const (
+	macSizeMD5  = 4 + 16
+	macSizeSHA1 = 4 + 20
+)
+
+// ErrMalformedExtension means extension fields of the packet can't be parsed
+var ErrMalformedExtension = errors.New("malformed extension field")
+
+// ExtensionField is NTPv4 extension field from RFC 7822
+/*
+   0                   1                   2                   3
+   0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+  +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+  |          Field Type           |            Length             |
+  +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+  .                                                               .
+  .                            Value                              .
+  .                                                               .
+  +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+  |                       Padding (as needed)                     |
+  +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+type ExtensionField struct {
+	Type  uint16
+	Value []byte // including padding
+}
+
+// Len returns size of the field on the wire
+func (f *ExtensionField) Len() int {
+	return extensionHeaderSize + (len(f.Value)+3)&^3
+}
+
+// Name returns registered name of the field type
+func (f *ExtensionField) Name() string {
+	if name, ok := ExtensionName(f.Type); ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", f.Type)
+}
+
+// ReadExtensionField parses a single extension field at the start of b and returns its size on the wire
+func ReadExtensionField(b []byte) (ExtensionField, int, error) {
+	if len(b) < extensionHeaderSize {
+		return ExtensionField{}, 0, fmt.Errorf("%w: truncated extension field", ErrMalformedExtension)
+	}
+	length := int(binary.BigEndian.Uint16(b[2:]))
+	if length < extensionHeaderSize || length%4 != 0 || length > len(b) {
+		return ExtensionField{}, 0, fmt.Errorf("%w: bad extension field length %d", ErrMalformedExtension, length)
+	}
+	f := ExtensionField{
+		Type:  binary.BigEndian.Uint16(b),
+		Value: b[extensionHeaderSize:length],
+	}
+	return f, length, nil
+}
+
+// IsMAC returns whether b, following NTP header or the last extension field, is a legacy MAC
+func IsMAC(b []byte) bool {
+	return len(b) == macSizeMD5 || len(b) == macSizeSHA1
+}
+
+// ParseExtensionFields parses extension fields following NTP header.
+// Trailing 20 or 24 bytes are a legacy MAC, which RFC 7822 tells apart from the last extension field
+// by requiring it to be at least 28 bytes long when there is no MAC
+func ParseExtensionFields(b []byte) (fields []ExtensionField, mac []byte, err error) {
+	for pos := 0; pos < len(b); {
+		if IsMAC(b[pos:]) {
+			return fields, b[pos:], nil
+		}
+		f, length, err := ReadExtensionField(b[pos:])
+		if err != nil {
+			return nil, nil, err
+		}
+		fields = append(fields, f)
+		pos += length
+	}
+	return fields, nil, nil
+}
+
+// AppendExtensionField appends extension field with value padded to 4 bytes
+func AppendExtensionField(b []byte, f *ExtensionField) []byte {
+	padded := f.Len() - extensionHeaderSize
+	b = binary.BigEndian.AppendUint16(b, f.Type)
+	b = binary.BigEndian.AppendUint16(b, uint16(f.Len()))
+	b = append(b, f.Value...)
+	return append(b, make([]byte, padded-len(f.Value))...)
+}
+
+// extensions holds names of registered extension field types
+var extensions = struct {
+	sync.RWMutex
+	m map[uint16]string
+}{m: map[uint16]string{}}
+
+// RegisterExtension registers extension field type, so it's recognized instead of being reported as unknown.
+// It's safe to call concurrently with parsing.
+func RegisterExtension(t uint16, name string) error {
+	extensions.Lock()
+	defer extensions.Unlock()
+	if registered, ok := extensions.m[t]; ok {
+		return fmt.Errorf("extension field 0x%04x is already registered as %q", t, registered)
+	}
+	extensions.m[t] = name
+	return nil
+}
+
+// UnregisterExtension removes extension field type registered with RegisterExtension
+func UnregisterExtension(t uint16) {
+	extensions.Lock()
+	defer extensions.Unlock()
+	delete(extensions.m, t)
+}
+
+// ExtensionName returns name of registered extension field type
+func ExtensionName(t uint16) (string, bool) {
+	extensions.RLock()
+	defer extensions.RUnlock()
+	name, ok := extensions.m[t]
+	return name, ok
+}
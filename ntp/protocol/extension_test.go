@@ -0,0 +1,95 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtensionFields(t *testing.T) {
+	b := AppendExtensionField(nil, &ExtensionField{Type: 0x0104, Value: []byte("odd")})
+	b = AppendExtensionField(b, &ExtensionField{Type: 0x0204, Value: make([]byte, 24)})
+	require.Len(t, b, 8+28)
+	fields, mac, err := ParseExtensionFields(b)
+	require.NoError(t, err)
+	require.Nil(t, mac)
+	require.Equal(t, []ExtensionField{
+		{Type: 0x0104, Value: []byte{'o', 'd', 'd', 0}},
+		{Type: 0x0204, Value: make([]byte, 24)},
+	}, fields)
+
+	// legacy MAC after extension fields
+	b = append(b, make([]byte, macSizeSHA1)...)
+	fields, mac, err = ParseExtensionFields(b)
+	require.NoError(t, err)
+	require.Len(t, fields, 2)
+	require.Len(t, mac, macSizeSHA1)
+
+	// just the MAC
+	fields, mac, err = ParseExtensionFields(make([]byte, macSizeMD5))
+	require.NoError(t, err)
+	require.Empty(t, fields)
+	require.Len(t, mac, macSizeMD5)
+
+	for name, bad := range map[string][]byte{
+		"truncated":   {0x01, 0x04},
+		"short":       {0x01, 0x04, 0, 2},
+		"unaligned":   {0x01, 0x04, 0, 6, 0, 0},
+		"overrunning": {0x01, 0x04, 0, 32, 0, 0, 0, 0},
+	} {
+		_, _, err = ParseExtensionFields(bad)
+		require.ErrorIs(t, err, ErrMalformedExtension, name)
+	}
+}
+
+func TestReadExtensionField(t *testing.T) {
+	b := AppendExtensionField(nil, &ExtensionField{Type: 0x0104, Value: make([]byte, 32)})
+	b = append(b, 0xff, 0xff)
+	f, length, err := ReadExtensionField(b)
+	require.NoError(t, err)
+	require.Equal(t, 36, length)
+	require.Equal(t, ExtensionField{Type: 0x0104, Value: make([]byte, 32)}, f)
+
+	_, _, err = ReadExtensionField([]byte{0x01, 0x04, 0, 8, 0, 0})
+	require.ErrorIs(t, err, ErrMalformedExtension)
+}
+
+func TestIsMAC(t *testing.T) {
+	require.True(t, IsMAC(make([]byte, macSizeMD5)))
+	require.True(t, IsMAC(make([]byte, macSizeSHA1)))
+	require.False(t, IsMAC(make([]byte, 28)))
+	require.False(t, IsMAC(nil))
+}
+
+func TestRegisterExtension(t *testing.T) {
+	f := &ExtensionField{Type: 0xf000}
+	require.Equal(t, "0xf000", f.Name())
+
+	require.NoError(t, RegisterExtension(0xf000, "experimental"))
+	defer UnregisterExtension(0xf000)
+	require.Error(t, RegisterExtension(0xf000, "other"))
+	require.Equal(t, "experimental", f.Name())
+	name, ok := ExtensionName(0xf000)
+	require.True(t, ok)
+	require.Equal(t, "experimental", name)
+
+	UnregisterExtension(0xf000)
+	_, ok = ExtensionName(0xf000)
+	require.False(t, ok)
+}
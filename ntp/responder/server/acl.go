@@ -0,0 +1,149 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// ACLAction is what to do with requests matching ACL rule
+type ACLAction string
+
+// Supported ACL actions
+const (
+	// ACLServe serves requests as usual
+	ACLServe ACLAction = "serve"
+	// ACLIgnore silently drops requests
+	ACLIgnore ACLAction = "ignore"
+	// ACLDeny responds with DENY Kiss-o'-Death, telling clients to stop using the server
+	ACLDeny ACLAction = "deny"
+)
+
+// KissCodeDeny is the kiss code telling client access is denied
+const KissCodeDeny = "DENY"
+
+// MarshalText ACL action to byte slice
+func (a ACLAction) MarshalText() ([]byte, error) {
+	return []byte(a), nil
+}
+
+// UnmarshalText ACL action from byte slice
+func (a *ACLAction) UnmarshalText(value []byte) error {
+	switch v := ACLAction(value); v {
+	case ACLServe, ACLIgnore, ACLDeny:
+		*a = v
+		return nil
+	}
+	return fmt.Errorf("unknown acl action %q", value)
+}
+
+// ACLRule applies action to requests from the prefix
+type ACLRule struct {
+	Prefix netip.Prefix
+	Action ACLAction
+}
+
+// String returns rule as prefix=action
+func (r ACLRule) String() string {
+	return fmt.Sprintf("%s=%s", r.Prefix, r.Action)
+}
+
+// ACL is a list of rules, the most specific prefix matching the client wins.
+// It allows to set rules with flag parser
+type ACL []ACLRule
+
+// Set adds prefix=action rule
+func (a *ACL) Set(rule string) error {
+	p, action, ok := strings.Cut(rule, "=")
+	if !ok {
+		return fmt.Errorf("invalid acl rule %q, expected prefix=action", rule)
+	}
+	prefix, err := netip.ParsePrefix(p)
+	if err != nil {
+		return fmt.Errorf("invalid acl rule %q: %w", rule, err)
+	}
+	r := ACLRule{Prefix: prefix.Masked()}
+	if err := r.Action.UnmarshalText([]byte(action)); err != nil {
+		return fmt.Errorf("invalid acl rule %q: %w", rule, err)
+	}
+	for _, o := range *a {
+		if o.Prefix == r.Prefix {
+			return fmt.Errorf("duplicate acl rule for %s", r.Prefix)
+		}
+	}
+	*a = append(*a, r)
+	return nil
+}
+
+// String returns joined list of rules
+func (a *ACL) String() string {
+	rules := make([]string, 0, len(*a))
+	for _, r := range *a {
+		rules = append(rules, r.String())
+	}
+	return strings.Join(rules, ", ")
+}
+
+type aclEntry struct {
+	ACLRule
+	hits atomic.Int64
+}
+
+// aclMatcher finds ACL rule matching the client and counts requests per rule
+type aclMatcher struct {
+	rules       []*aclEntry // most specific first
+	defaultRule aclEntry
+}
+
+func newACLMatcher(acl ACL, defaultAction ACLAction) *aclMatcher {
+	m := &aclMatcher{defaultRule: aclEntry{ACLRule: ACLRule{Action: defaultAction}}}
+	for _, r := range acl {
+		m.rules = append(m.rules, &aclEntry{ACLRule: r})
+	}
+	slices.SortStableFunc(m.rules, func(a, b *aclEntry) int {
+		return cmp.Compare(b.Prefix.Bits(), a.Prefix.Bits())
+	})
+	return m
+}
+
+// match returns action for the client address
+func (m *aclMatcher) match(addr netip.Addr) ACLAction {
+	addr = addr.Unmap()
+	for _, r := range m.rules {
+		if r.Prefix.Contains(addr) {
+			r.hits.Add(1)
+			return r.Action
+		}
+	}
+	m.defaultRule.hits.Add(1)
+	return m.defaultRule.Action
+}
+
+// counters returns number of requests matched by every rule
+func (m *aclMatcher) counters() map[string]int64 {
+	c := make(map[string]int64, len(m.rules)+1)
+	for _, r := range m.rules {
+		c[r.Prefix.String()] = r.hits.Load()
+	}
+	c["default"] = m.defaultRule.hits.Load()
+	return c
+}
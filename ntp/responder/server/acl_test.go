@@ -0,0 +1,127 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/facebook/time/ntp/responder/checker"
+	"github.com/facebook/time/ntp/responder/stats"
+	"github.com/facebook/time/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestACLActionUnmarshalText(t *testing.T) {
+	var a ACLAction
+	require.NoError(t, a.UnmarshalText([]byte("deny")))
+	require.Equal(t, ACLDeny, a)
+	require.Error(t, a.UnmarshalText([]byte("allow")))
+	b, err := ACLIgnore.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, "ignore", string(b))
+}
+
+func TestACLSet(t *testing.T) {
+	acl := ACL{}
+	require.NoError(t, acl.Set("10.0.0.0/8=serve"))
+	// host bits are masked
+	require.NoError(t, acl.Set("2001:db8::1/32=deny"))
+	require.Equal(t, ACL{
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Action: ACLServe},
+		{Prefix: netip.MustParsePrefix("2001:db8::/32"), Action: ACLDeny},
+	}, acl)
+	require.Equal(t, "10.0.0.0/8=serve, 2001:db8::/32=deny", acl.String())
+
+	require.EqualError(t, acl.Set("10.0.0.0/8"), "invalid acl rule \"10.0.0.0/8\", expected prefix=action")
+	require.Error(t, acl.Set("10.0.0.0/33=serve"))
+	require.Error(t, acl.Set("10.1.0.0/16=allow"))
+	require.EqualError(t, acl.Set("10.1.0.0/8=ignore"), "duplicate acl rule for 10.0.0.0/8")
+}
+
+func TestACLMatcher(t *testing.T) {
+	acl := ACL{}
+	require.NoError(t, acl.Set("0.0.0.0/0=deny"))
+	require.NoError(t, acl.Set("10.0.0.0/8=serve"))
+	require.NoError(t, acl.Set("10.1.0.0/16=ignore"))
+	m := newACLMatcher(acl, ACLIgnore)
+
+	require.Equal(t, ACLServe, m.match(netip.MustParseAddr("10.0.0.1")))
+	require.Equal(t, ACLIgnore, m.match(netip.MustParseAddr("10.1.0.1")))
+	require.Equal(t, ACLDeny, m.match(netip.MustParseAddr("192.0.2.1")))
+	// IPv4 clients on dual stack socket
+	require.Equal(t, ACLServe, m.match(netip.MustParseAddr("::ffff:10.0.0.2")))
+	require.Equal(t, ACLIgnore, m.match(netip.MustParseAddr("2001:db8::1")))
+
+	require.Equal(t, map[string]int64{
+		"0.0.0.0/0":   1,
+		"10.0.0.0/8":  2,
+		"10.1.0.0/16": 1,
+		"default":     1,
+	}, m.counters())
+}
+
+func TestServerACL(t *testing.T) {
+	acl := ACL{}
+	require.NoError(t, acl.Set("127.0.0.1/32=deny"))
+	require.NoError(t, acl.Set("127.0.0.2/32=ignore"))
+	st := &stats.JSONStats{}
+	s := &Server{
+		Checker: &checker.SimpleChecker{ExpectedListeners: 1, ExpectedWorkers: 1},
+		Stats:   st,
+		tasks:   make(chan task, 1),
+		Config:  Config{Workers: 1, TimestampType: timestamp.SW, Stratum: 1},
+		acl:     newACLMatcher(acl, ACLServe),
+	}
+	go s.startWorker()
+	conn := tryListenUDP(t)
+	defer conn.Close()
+	go s.startListener(conn)
+	time.Sleep(100 * time.Millisecond)
+
+	sendConn, err := net.DialTimeout("udp", conn.LocalAddr().String(), time.Second)
+	require.NoError(t, err)
+	defer sendConn.Close()
+	request, err := ntpRequest.Bytes()
+	require.NoError(t, err)
+	_, err = sendConn.Write(request)
+	require.NoError(t, err)
+
+	buf := make([]byte, 1024)
+	require.NoError(t, sendConn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := sendConn.Read(buf)
+	require.NoError(t, err)
+	response, err := ntp.BytesToPacket(buf[:n])
+	require.NoError(t, err)
+	require.Equal(t, uint8(0), response.Stratum)
+	require.Equal(t, uint8(3), response.Settings>>6)
+	require.Equal(t, refID(KissCodeDeny), response.ReferenceID)
+
+	// ignored clients get nothing
+	ignoredConn, err := net.DialUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.2")}, conn.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+	defer ignoredConn.Close()
+	_, err = ignoredConn.Write(request)
+	require.NoError(t, err)
+	require.NoError(t, ignoredConn.SetReadDeadline(time.Now().Add(200*time.Millisecond)))
+	_, err = ignoredConn.Read(buf)
+	require.Error(t, err)
+	require.Equal(t, map[string]int64{"127.0.0.1/32": 1, "127.0.0.2/32": 1, "default": 0}, s.acl.counters())
+}
@@ -0,0 +1,72 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/facebook/time/ntp/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// authKeysReloadInterval is how often symmetric keys are re-read, so keys can be changed without restart
+const authKeysReloadInterval = time.Minute
+
+// startAuth loads symmetric keys and keeps them up to date
+func (s *Server) startAuth(ctx context.Context) error {
+	keys, err := auth.ReadKeys(s.Config.AuthKeys)
+	if err != nil {
+		return fmt.Errorf("reading symmetric keys: %w", err)
+	}
+	s.keys = auth.NewKeyring(keys)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(authKeysReloadInterval):
+				keys, err := auth.ReadKeys(s.Config.AuthKeys)
+				if err != nil {
+					log.Errorf("[auth] failed to reload symmetric keys: %v", err)
+					continue
+				}
+				s.keys.SetKeys(keys)
+			}
+		}
+	}()
+	return nil
+}
+
+// authResponse verifies MAC of the request and signs the response with the same key.
+// Requests failing authentication get crypto-NAK
+func (t *task) authResponse(response []byte) []byte {
+	k, err := t.keys.Verify(t.raw)
+	if errors.Is(err, auth.ErrNoMAC) {
+		// some extension fields we don't know about
+		return response
+	}
+	if err != nil {
+		log.Debugf("Authentication failed, sending crypto-NAK: %v", err)
+		t.stats.IncAuthFailures()
+		return auth.CryptoNAK(response)
+	}
+	t.stats.IncAuthRequests()
+	return k.Sign(response)
+}
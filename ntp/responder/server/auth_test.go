@@ -0,0 +1,147 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/facebook/time/ntp/auth"
+	"github.com/facebook/time/ntp/nts"
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/facebook/time/ntp/responder/checker"
+	"github.com/facebook/time/ntp/responder/stats"
+	"github.com/facebook/time/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerAuth(t *testing.T) {
+	key := &auth.Key{ID: 42, Algorithm: auth.SHA1, Secret: []byte("secret")}
+	keys := auth.NewKeyring(map[uint32]*auth.Key{42: key})
+	s := &Server{
+		Checker: &checker.SimpleChecker{ExpectedListeners: 1, ExpectedWorkers: 1},
+		Stats:   &stats.JSONStats{},
+		tasks:   make(chan task, 1),
+		Config:  Config{Workers: 1, TimestampType: timestamp.SW},
+		keys:    keys,
+	}
+	go s.startWorker()
+	conn := tryListenUDP(t)
+	defer conn.Close()
+	go s.startListener(conn)
+	time.Sleep(100 * time.Millisecond)
+
+	sendConn, err := net.DialTimeout("udp", conn.LocalAddr().String(), time.Second)
+	require.NoError(t, err)
+	defer sendConn.Close()
+	require.NoError(t, sendConn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 1024)
+	exchange := func(request []byte) []byte {
+		_, err := sendConn.Write(request)
+		require.NoError(t, err)
+		n, err := sendConn.Read(buf)
+		require.NoError(t, err)
+		return buf[:n]
+	}
+
+	sec, frac := ntp.Time(time.Now())
+	header, err := (&ntp.Packet{Settings: 0x23, TxTimeSec: sec, TxTimeFrac: frac}).Bytes()
+	require.NoError(t, err)
+
+	// unauthenticated requests are served as usual
+	response := exchange(header)
+	require.Len(t, response, ntp.PacketSizeBytes)
+
+	// response is signed with the key of the request
+	response = exchange(key.Sign(header))
+	k, err := keys.Verify(response)
+	require.NoError(t, err)
+	require.Equal(t, key, k)
+	p, err := ntp.BytesToPacket(response)
+	require.NoError(t, err)
+	require.Equal(t, sec, p.OrigTimeSec)
+	require.Equal(t, frac, p.OrigTimeFrac)
+
+	// unknown key gets crypto-NAK
+	response = exchange((&auth.Key{ID: 1, Algorithm: auth.MD5, Secret: []byte("secret")}).Sign(header))
+	require.Len(t, response, ntp.PacketSizeBytes+4)
+	require.Equal(t, []byte{0, 0, 0, 0}, response[ntp.PacketSizeBytes:])
+
+	// so does the wrong secret
+	response = exchange((&auth.Key{ID: 42, Algorithm: auth.SHA1, Secret: []byte("public")}).Sign(header))
+	require.Len(t, response, ntp.PacketSizeBytes+4)
+}
+
+func TestServerNTSAndAuth(t *testing.T) {
+	key := &auth.Key{ID: 42, Algorithm: auth.SHA1, Secret: []byte("secret")}
+	keys := auth.NewKeyring(map[uint32]*auth.Key{42: key})
+	cookies, err := nts.NewCookieJar(make([]byte, nts.CookieKeySize))
+	require.NoError(t, err)
+	s := &Server{
+		Checker: &checker.SimpleChecker{ExpectedListeners: 1, ExpectedWorkers: 1},
+		Stats:   &stats.JSONStats{},
+		tasks:   make(chan task, 1),
+		Config:  Config{Workers: 1, TimestampType: timestamp.SW},
+		keys:    keys,
+		cookies: cookies,
+	}
+	go s.startWorker()
+	conn := tryListenUDP(t)
+	defer conn.Close()
+	go s.startListener(conn)
+	time.Sleep(100 * time.Millisecond)
+
+	sendConn, err := net.DialTimeout("udp", conn.LocalAddr().String(), time.Second)
+	require.NoError(t, err)
+	defer sendConn.Close()
+	require.NoError(t, sendConn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 1024)
+	exchange := func(request []byte) []byte {
+		_, err := sendConn.Write(request)
+		require.NoError(t, err)
+		n, err := sendConn.Read(buf)
+		require.NoError(t, err)
+		return buf[:n]
+	}
+
+	sec, frac := ntp.Time(time.Now())
+	header, err := (&ntp.Packet{Settings: 0x23, TxTimeSec: sec, TxTimeFrac: frac}).Bytes()
+	require.NoError(t, err)
+
+	// unauthenticated request
+	response := exchange(header)
+	require.Len(t, response, ntp.PacketSizeBytes)
+
+	// request signed with legacy MAC is not taken for NTS
+	response = exchange(key.Sign(header))
+	k, err := keys.Verify(response)
+	require.NoError(t, err)
+	require.Equal(t, key, k)
+
+	// NTS request
+	c := &nts.Cookie{AEAD: nts.AEADAESSIVCMAC256, C2S: bytes.Repeat([]byte{1}, 32), S2C: bytes.Repeat([]byte{2}, 32)}
+	cookie, err := cookies.Encode(c)
+	require.NoError(t, err)
+	request, uniqueID, err := nts.AppendRequest(header, cookie, c.C2S, 1)
+	require.NoError(t, err)
+	response = exchange(request)
+	newCookies, err := nts.ParseResponse(response, uniqueID, c.S2C)
+	require.NoError(t, err)
+	require.Len(t, newCookies, 2)
+}
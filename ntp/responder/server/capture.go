@@ -0,0 +1,166 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	log "github.com/sirupsen/logrus"
+)
+
+// pcapng interfaces packets are recorded on, by the kind of timestamp they carry
+const (
+	captureSoftware = iota
+	captureHardware
+)
+
+// captureQueueSize is how many packets can wait to be written before new ones are dropped
+const captureQueueSize = 1024
+
+// captureMaxBytes is how big capture file can grow, sampling stops after that
+const captureMaxBytes = 256 << 20
+
+// capturedPacket is the packet waiting to be written to the capture file
+type capturedPacket struct {
+	iface int
+	ts    time.Time
+	data  []byte
+}
+
+// sampler picks 1 in N requests and records them along with responses in pcapng format
+type sampler struct {
+	every   uint64
+	count   atomic.Uint64
+	packets chan capturedPacket
+	stats   Stats
+}
+
+func newSampler(every int, stats Stats) *sampler {
+	return &sampler{every: uint64(every), packets: make(chan capturedPacket, captureQueueSize), stats: stats}
+}
+
+// sample returns whether the next request must be recorded
+func (c *sampler) sample() bool {
+	return c.count.Add(1)%c.every == 0
+}
+
+// record queues UDP packet from src to dst with the payload for writing, without blocking
+func (c *sampler) record(iface int, ts time.Time, src, dst netip.AddrPort, payload []byte) {
+	data, err := capturePacket(src, dst, payload)
+	if err != nil {
+		log.Debugf("[capture] failed to build packet: %v", err)
+		return
+	}
+	select {
+	case c.packets <- capturedPacket{iface: iface, ts: ts, data: data}:
+	default:
+		c.stats.IncCaptureDropped()
+	}
+}
+
+// run writes recorded packets until ctx is done or w is full
+func (c *sampler) run(ctx context.Context, w io.Writer) error {
+	opts := pcapgo.DefaultNgWriterOptions
+	opts.SectionInfo.Application = "ntpresponder"
+	ng, err := pcapgo.NewNgWriterInterface(w, pcapgo.NgInterface{
+		Name:        "software",
+		Description: "system clock",
+		LinkType:    layers.LinkTypeRaw,
+	}, opts)
+	if err != nil {
+		return err
+	}
+	if _, err := ng.AddInterface(pcapgo.NgInterface{
+		Name:        "hardware",
+		Description: "NIC timestamps converted to system clock",
+		LinkType:    layers.LinkTypeRaw,
+	}); err != nil {
+		return err
+	}
+	written := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return ng.Flush()
+		case p := <-c.packets:
+			if written+len(p.data) > captureMaxBytes {
+				log.Warningf("[capture] capture file reached %d bytes, stopped sampling", written)
+				return ng.Flush()
+			}
+			ci := gopacket.CaptureInfo{Timestamp: p.ts, CaptureLength: len(p.data), Length: len(p.data), InterfaceIndex: p.iface}
+			if err := ng.WritePacket(ci, p.data); err != nil {
+				return err
+			}
+			// sampling is sparse, make what we have readable right away
+			if err := ng.Flush(); err != nil {
+				return err
+			}
+			written += len(p.data)
+			c.stats.IncCaptured()
+		}
+	}
+}
+
+// startCapture creates the capture file and starts writing samples to it
+func (s *Server) startCapture(ctx context.Context) error {
+	f, err := os.Create(s.Config.CapturePath)
+	if err != nil {
+		return fmt.Errorf("creating capture file: %w", err)
+	}
+	s.capture = newSampler(s.Config.CaptureSample, s.Stats)
+	go func() {
+		defer f.Close()
+		if err := s.capture.run(ctx, f); err != nil {
+			log.Errorf("[capture] failed to write capture file: %v", err)
+		}
+	}()
+	return nil
+}
+
+// capturePacket returns IP packet with UDP datagram from src to dst with the payload
+func capturePacket(src, dst netip.AddrPort, payload []byte) ([]byte, error) {
+	udp := &layers.UDP{SrcPort: layers.UDPPort(src.Port()), DstPort: layers.UDPPort(dst.Port())}
+	var ip gopacket.SerializableLayer
+	if src.Addr().Is4() {
+		ip4 := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: src.Addr().AsSlice(), DstIP: dst.Addr().AsSlice()}
+		if err := udp.SetNetworkLayerForChecksum(ip4); err != nil {
+			return nil, err
+		}
+		ip = ip4
+	} else {
+		ip6 := &layers.IPv6{Version: 6, HopLimit: 64, NextHeader: layers.IPProtocolUDP, SrcIP: src.Addr().AsSlice(), DstIP: dst.Addr().AsSlice()}
+		if err := udp.SetNetworkLayerForChecksum(ip6); err != nil {
+			return nil, err
+		}
+		ip = ip6
+	}
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, ip, udp, gopacket.Payload(payload)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/facebook/time/ntp/responder/checker"
+	"github.com/facebook/time/ntp/responder/stats"
+	"github.com/facebook/time/timestamp"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapturePacket(t *testing.T) {
+	for _, tc := range []struct {
+		src, dst netip.AddrPort
+		layer    gopacket.LayerType
+	}{
+		{netip.MustParseAddrPort("192.0.2.1:123"), netip.MustParseAddrPort("192.0.2.2:40000"), layers.LayerTypeIPv4},
+		{netip.MustParseAddrPort("[2001:db8::1]:123"), netip.MustParseAddrPort("[2001:db8::2]:40000"), layers.LayerTypeIPv6},
+	} {
+		payload, err := (&ntp.Packet{Settings: 0x24}).Bytes()
+		require.NoError(t, err)
+		b, err := capturePacket(tc.src, tc.dst, payload)
+		require.NoError(t, err)
+		p := gopacket.NewPacket(b, tc.layer, gopacket.Default)
+		require.Nil(t, p.ErrorLayer())
+		udp := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		require.Equal(t, layers.UDPPort(123), udp.SrcPort)
+		require.Equal(t, layers.UDPPort(40000), udp.DstPort)
+		require.Equal(t, payload, udp.Payload)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	c := newSampler(3, &stats.JSONStats{})
+	sampled := 0
+	for i := 0; i < 9; i++ {
+		if c.sample() {
+			sampled++
+		}
+	}
+	require.Equal(t, 3, sampled)
+}
+
+func TestServerCapture(t *testing.T) {
+	s := &Server{
+		Checker: &checker.SimpleChecker{ExpectedListeners: 1, ExpectedWorkers: 1},
+		Stats:   &stats.JSONStats{},
+		tasks:   make(chan task, 1),
+		Config: Config{
+			Workers:       1,
+			TimestampType: timestamp.SW,
+			CapturePath:   filepath.Join(t.TempDir(), "capture.pcapng"),
+			CaptureSample: 1,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, s.startCapture(ctx))
+	go s.startWorker()
+	conn := tryListenUDP(t)
+	defer conn.Close()
+	go s.startListener(conn)
+	time.Sleep(100 * time.Millisecond)
+
+	sendConn, err := net.DialTimeout("udp", conn.LocalAddr().String(), time.Second)
+	require.NoError(t, err)
+	defer sendConn.Close()
+	request, err := (&ntp.Packet{Settings: 0x23}).Bytes()
+	require.NoError(t, err)
+	_, err = sendConn.Write(request)
+	require.NoError(t, err)
+	require.NoError(t, sendConn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 1024)
+	n, err := sendConn.Read(buf)
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	f, err := os.Open(s.Config.CapturePath)
+	require.NoError(t, err)
+	defer f.Close()
+	r, err := pcapgo.NewNgReader(f, pcapgo.DefaultNgReaderOptions)
+	require.NoError(t, err)
+	payloads := [][]byte{}
+	for {
+		data, ci, err := r.ReadPacketData()
+		if err != nil {
+			break
+		}
+		require.Equal(t, captureSoftware, ci.InterfaceIndex)
+		p := gopacket.NewPacket(data, layers.LayerTypeIPv4, gopacket.Default)
+		if conn.LocalAddr().(*net.UDPAddr).IP.To4() == nil {
+			p = gopacket.NewPacket(data, layers.LayerTypeIPv6, gopacket.Default)
+		}
+		udp := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		payloads = append(payloads, udp.Payload)
+	}
+	// request and response
+	require.Equal(t, [][]byte{request, buf[:n]}, payloads)
+}
@@ -0,0 +1,162 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/facebook/time/ntp/chrony"
+	log "github.com/sirupsen/logrus"
+)
+
+// chronyStats counts what chronyd reports in 'serverstats' on top of the usual stats
+type chronyStats struct {
+	Stats
+	started      time.Time
+	ntpHits      atomic.Uint64
+	ntpDrops     atomic.Uint64
+	authHits     atomic.Uint64
+	interleaved  atomic.Uint64
+	txTimestamps atomic.Uint64
+}
+
+// IncRequests atomically add 1 to the counter
+func (c *chronyStats) IncRequests() {
+	c.ntpHits.Add(1)
+	c.Stats.IncRequests()
+}
+
+// IncRateLimited atomically add 1 to the counter
+func (c *chronyStats) IncRateLimited() {
+	c.ntpDrops.Add(1)
+	c.Stats.IncRateLimited()
+}
+
+// IncDrained atomically add 1 to the counter
+func (c *chronyStats) IncDrained() {
+	c.ntpDrops.Add(1)
+	c.Stats.IncDrained()
+}
+
+// IncAuthRequests atomically add 1 to the counter
+func (c *chronyStats) IncAuthRequests() {
+	c.authHits.Add(1)
+	c.Stats.IncAuthRequests()
+}
+
+// IncNTSRequests atomically add 1 to the counter
+func (c *chronyStats) IncNTSRequests() {
+	c.authHits.Add(1)
+	c.Stats.IncNTSRequests()
+}
+
+// IncInterleaved atomically add 1 to the counter
+func (c *chronyStats) IncInterleaved() {
+	c.interleaved.Add(1)
+	c.Stats.IncInterleaved()
+}
+
+// IncTXTimestamps atomically add 1 to the counter
+func (c *chronyStats) IncTXTimestamps() {
+	c.txTimestamps.Add(1)
+	c.Stats.IncTXTimestamps()
+}
+
+// chronyMonitor answers chronyc-compatible 'tracking' and 'serverstats' requests with the state of the responder
+type chronyMonitor struct {
+	s     *Server
+	stats *chronyStats
+}
+
+// Tracking reports what responder advertises to clients as chronyd tracking data
+func (m *chronyMonitor) Tracking() (*chrony.Tracking, error) {
+	now := time.Now()
+	st := &syncState{stratum: uint8(m.s.Config.Stratum), referenceID: refID(m.s.Config.RefID), rootDispersion: 1}
+	if m.s.sync != nil {
+		st = m.s.sync.state.Load()
+	}
+	stratum := st.stratum
+	if m.s.drain != nil {
+		stratum = m.s.drain.stratum(stratum, now)
+	}
+	return &chrony.Tracking{
+		RefID:             st.referenceID,
+		Stratum:           uint16(stratum),
+		LeapStatus:        uint16(st.leap),
+		RefTime:           now,
+		CurrentCorrection: m.s.servedOffset(now).Seconds(),
+		RootDelay:         shortTimeSeconds(st.rootDelay),
+		RootDispersion:    shortTimeSeconds(st.rootDispersion),
+	}, nil
+}
+
+// ServerStats reports request counters as chronyd server stats
+func (m *chronyMonitor) ServerStats() (*chrony.ServerStats4, error) {
+	hits := m.stats.ntpHits.Load()
+	s := &chrony.ServerStats4{
+		NTPHits:            hits,
+		NTPDrops:           m.stats.ntpDrops.Load(),
+		NTPAuthHits:        m.stats.authHits.Load(),
+		NTPInterleavedHits: m.stats.interleaved.Load(),
+		NTPSpanSeconds:     uint64(time.Since(m.stats.started).Seconds()),
+		NTPHwTxTimestamps:  m.stats.txTimestamps.Load(),
+	}
+	if m.s.phcTime.Load() {
+		s.NTPHwRxTimestamps = hits
+	} else {
+		s.NTPKernelRxtimestamps = hits
+	}
+	return s, nil
+}
+
+// shortTimeSeconds converts NTP short format to seconds
+func shortTimeSeconds(v uint32) float64 {
+	return float64(v) / (1 << 16)
+}
+
+// startChrony serves chronyc-compatible monitoring protocol on Config.ChronySocket.
+// It must run before anything else uses s.Stats, as it wraps them to count served requests
+func (s *Server) startChrony(ctx context.Context) error {
+	if err := os.Remove(s.Config.ChronySocket); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing stale chrony socket: %w", err)
+	}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: s.Config.ChronySocket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("listening on chrony socket: %w", err)
+	}
+	st := &chronyStats{
+		Stats:   s.Stats,
+		started: time.Now(),
+	}
+	s.Stats = st
+	srv := &chrony.Server{Monitor: &chronyMonitor{s: s, stats: st}}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go func() {
+		if err := srv.Serve(conn); err != nil {
+			log.Errorf("[chrony] failed to serve monitoring protocol: %v", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/facebook/time/ntp/chrony"
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/facebook/time/ntp/responder/checker"
+	"github.com/facebook/time/ntp/responder/stats"
+	"github.com/facebook/time/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerChrony(t *testing.T) {
+	dir := t.TempDir()
+	s := &Server{
+		Checker: &checker.SimpleChecker{ExpectedListeners: 1, ExpectedWorkers: 1},
+		Stats:   &stats.JSONStats{},
+		tasks:   make(chan task, 1),
+		Config: Config{
+			Workers:       1,
+			Stratum:       1,
+			RefID:         "ATOM",
+			TimestampType: timestamp.SW,
+			ChronySocket:  filepath.Join(dir, "chronyd.sock"),
+			DrainStep:     time.Hour,
+		},
+	}
+	s.drain = newDrainer(s.Config.DrainStep)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, s.startChrony(ctx))
+	go s.startWorker()
+	conn := tryListenUDP(t)
+	defer conn.Close()
+	go s.startListener(conn)
+	time.Sleep(100 * time.Millisecond)
+
+	sendConn, err := net.DialTimeout("udp", conn.LocalAddr().String(), time.Second)
+	require.NoError(t, err)
+	defer sendConn.Close()
+	request, err := (&ntp.Packet{Settings: 0x23}).Bytes()
+	require.NoError(t, err)
+	_, err = sendConn.Write(request)
+	require.NoError(t, err)
+	require.NoError(t, sendConn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = sendConn.Read(make([]byte, 1024))
+	require.NoError(t, err)
+
+	chronyConn, err := net.DialUnix("unixgram",
+		&net.UnixAddr{Name: filepath.Join(dir, "chronyc.sock"), Net: "unixgram"},
+		&net.UnixAddr{Name: s.Config.ChronySocket, Net: "unixgram"},
+	)
+	require.NoError(t, err)
+	defer chronyConn.Close()
+	client := &chrony.Client{Connection: chronyConn}
+
+	p, err := client.Communicate(chrony.NewTrackingPacket())
+	require.NoError(t, err)
+	tracking := p.(*chrony.ReplyTracking)
+	require.Equal(t, refID("ATOM"), tracking.RefID)
+	require.Equal(t, uint16(1), tracking.Stratum)
+	require.Equal(t, uint16(0), tracking.LeapStatus)
+	require.InDelta(t, 0.000015, tracking.RootDispersion, 0.000001)
+
+	p, err = client.Communicate(chrony.NewServerStatsPacket())
+	require.NoError(t, err)
+	serverStats := p.(*chrony.ReplyServerStats4)
+	require.Equal(t, uint64(1), serverStats.NTPHits)
+	require.Equal(t, uint64(1), serverStats.NTPKernelRxtimestamps)
+	require.Equal(t, uint64(2), serverStats.CMDHits)
+
+	// drained responder reports worse stratum, as it advertises to clients
+	s.drain.drain(DrainStratum, time.Now())
+	p, err = client.Communicate(chrony.NewTrackingPacket())
+	require.NoError(t, err)
+	require.Equal(t, uint16(2), p.(*chrony.ReplyTracking).Stratum)
+}
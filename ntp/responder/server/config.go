@@ -30,18 +30,53 @@ var DefaultServerIPs = MultiIPs{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}
 
 // Config is a server config structure
 type Config struct {
-	ExtraOffset    time.Duration
-	Iface          string
-	IPs            MultiIPs
-	ManageLoopback bool
-	MonitoringPort int
-	Port           int
-	RefID          string
-	ShouldAnnounce bool
-	Stratum        int
-	TimestampType  timestamp.Timestamp
-	Workers        int
-	phcOffset      time.Duration
+	ACL               ACL
+	ACLDefault        ACLAction
+	Anycast           bool
+	AuthKeys          string
+	BusyPoll          timestamp.BusyPoll
+	CapturePath       string
+	CaptureSample     int
+	ChronySocket      string
+	ControlSocket     string
+	DrainStep         time.Duration
+	ExtraOffset       time.Duration
+	Iface             string
+	IPs               MultiIPs
+	LeapSmear         time.Duration
+	LeapSmearShape    SmearShape
+	ManageLoopback    bool
+	MonitoringPort    int
+	NTSCert           string
+	NTSCookieKeys     string
+	NTSKey            string
+	NTSKEPort         int
+	NTSKEMaxSessions  int
+	PinWorkers        bool
+	Port              int
+	RateLimit         float64
+	RateLimitBurst    int
+	RateLimitPrefixV4 int
+	RateLimitPrefixV6 int
+	RefID             string
+	ReusePort         int
+	ShouldAnnounce    bool
+	Stratum           int
+	SyncAddress       string
+	SyncMaxError      time.Duration
+	SyncSource        SyncSource
+	TimestampType     timestamp.Timestamp
+	WithdrawGrace     time.Duration
+	Workers           int
+	phcOffset         time.Duration
+}
+
+// aclDefault returns action for clients no ACL rule matches
+func (c *Config) aclDefault() ACLAction {
+	if c.ACLDefault == "" {
+		return ACLServe
+	}
+	return c.ACLDefault
 }
 
 // MultiIPs is a wrapper allowing to set multiple IPs with flag parser
@@ -80,8 +115,68 @@ func (c *Config) Validate() error {
 	if c.Workers < 1 {
 		return fmt.Errorf("will not start without workers")
 	}
-	if c.TimestampType != timestamp.HWRX && c.TimestampType != timestamp.SWRX {
+	if c.TimestampType != timestamp.HW && c.TimestampType != timestamp.HWRX && c.TimestampType != timestamp.SWRX {
 		return fmt.Errorf("unsupported timestamp type %s", c.TimestampType)
 	}
+	if c.ReusePort < 0 {
+		return fmt.Errorf("number of SO_REUSEPORT workers must not be negative")
+	}
+	if c.PinWorkers && c.ReusePort == 0 {
+		return fmt.Errorf("pinning workers to CPUs requires SO_REUSEPORT workers")
+	}
+	if err := c.BusyPoll.Validate(); err != nil {
+		return err
+	}
+	if c.LeapSmear < 0 {
+		return fmt.Errorf("leap smear duration must not be negative")
+	}
+	if c.LeapSmear > 0 && c.LeapSmearShape != SmearLinear && c.LeapSmearShape != SmearCosine {
+		return fmt.Errorf("unsupported leap smear shape %q", c.LeapSmearShape)
+	}
+	switch c.ACLDefault {
+	case "", ACLServe, ACLIgnore, ACLDeny:
+	default:
+		return fmt.Errorf("unsupported default acl action %q", c.ACLDefault)
+	}
+	if c.RateLimit < 0 {
+		return fmt.Errorf("rate limit must not be negative")
+	}
+	if c.RateLimit > 0 {
+		if c.RateLimitBurst < 1 {
+			return fmt.Errorf("rate limit burst must be positive")
+		}
+		if c.RateLimitPrefixV4 < 1 || c.RateLimitPrefixV4 > 32 || c.RateLimitPrefixV6 < 1 || c.RateLimitPrefixV6 > 128 {
+			return fmt.Errorf("invalid rate limit prefix length /%d or /%d", c.RateLimitPrefixV4, c.RateLimitPrefixV6)
+		}
+	}
+	switch c.SyncSource {
+	case "", SyncStatic:
+	case SyncFBClock, SyncSPTP:
+		if c.SyncAddress == "" {
+			return fmt.Errorf("sync source %s requires address", c.SyncSource)
+		}
+		if c.SyncMaxError < 0 {
+			return fmt.Errorf("sync max error must not be negative")
+		}
+	default:
+		return fmt.Errorf("unsupported sync source %q", c.SyncSource)
+	}
+	if c.CapturePath != "" && c.CaptureSample < 1 {
+		return fmt.Errorf("capture sample rate must be positive")
+	}
+	if c.WithdrawGrace < 0 {
+		return fmt.Errorf("withdraw grace period must not be negative")
+	}
+	if c.ControlSocket != "" && c.DrainStep <= 0 {
+		return fmt.Errorf("drain step must be positive")
+	}
+	if c.NTSKEPort > 0 {
+		if c.NTSCookieKeys == "" {
+			return fmt.Errorf("nts-ke requires cookie keys")
+		}
+		if c.NTSCert == "" || c.NTSKey == "" {
+			return fmt.Errorf("nts-ke requires tls certificate and key")
+		}
+	}
 	return nil
 }
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/facebook/time/timestamp"
 	"github.com/stretchr/testify/require"
@@ -77,5 +78,97 @@ func TestConfigValidate(t *testing.T) {
 	require.Error(t, c.Validate())
 	c.TimestampType = timestamp.HWRX
 
+	// Leap smear
+	c.LeapSmear = -time.Hour
+	require.Error(t, c.Validate())
+	c.LeapSmear = 24 * time.Hour
+	require.Error(t, c.Validate())
+	c.LeapSmearShape = SmearCosine
+	require.NoError(t, c.Validate())
+	c.LeapSmear = 0
+
+	// Busy poll
+	c.BusyPoll = timestamp.BusyPoll{Budget: 16}
+	require.Error(t, c.Validate())
+	c.BusyPoll.Timeout = 50 * time.Microsecond
+	require.NoError(t, c.Validate())
+	c.BusyPoll = timestamp.BusyPoll{}
+
+	// Rate limit
+	c.RateLimit = -1
+	require.Error(t, c.Validate())
+	c.RateLimit = 0.5
+	require.Error(t, c.Validate())
+	c.RateLimitBurst = 8
+	require.Error(t, c.Validate())
+	c.RateLimitPrefixV4 = 32
+	c.RateLimitPrefixV6 = 129
+	require.Error(t, c.Validate())
+	c.RateLimitPrefixV6 = 64
+	require.NoError(t, c.Validate())
+	c.RateLimit = 0
+
+	// NTS-KE
+	c.NTSKEPort = 4460
+	require.Error(t, c.Validate())
+	c.NTSCookieKeys = "/etc/ntp/nts.keys"
+	require.Error(t, c.Validate())
+	c.NTSCert = "/etc/ntp/nts.crt"
+	c.NTSKey = "/etc/ntp/nts.key"
+	require.NoError(t, c.Validate())
+	c.NTSKEPort = 0
+
+	// ACL
+	c.ACLDefault = "allow"
+	require.Error(t, c.Validate())
+	c.ACLDefault = ACLDeny
+	require.NoError(t, c.Validate())
+	c.ACLDefault = ""
+
+	// SO_REUSEPORT workers
+	c.ReusePort = -1
+	require.Error(t, c.Validate())
+	c.ReusePort = 0
+	c.PinWorkers = true
+	require.Error(t, c.Validate())
+	c.ReusePort = 4
+	require.NoError(t, c.Validate())
+	c.ReusePort = 0
+	c.PinWorkers = false
+
+	// sync source
+	c.SyncSource = "chrony"
+	require.Error(t, c.Validate())
+	c.SyncSource = SyncSPTP
+	require.Error(t, c.Validate())
+	c.SyncAddress = "localhost:4269"
+	c.SyncMaxError = -time.Millisecond
+	require.Error(t, c.Validate())
+	c.SyncMaxError = time.Millisecond
+	require.NoError(t, c.Validate())
+	c.SyncSource = SyncStatic
+
+	// capture
+	c.CapturePath = "/var/tmp/ntpresponder.pcapng"
+	require.Error(t, c.Validate())
+	c.CaptureSample = 1000
+	require.NoError(t, c.Validate())
+	c.CapturePath = ""
+
+	// anycast
+	c.Anycast = true
+	c.WithdrawGrace = -time.Second
+	require.Error(t, c.Validate())
+	c.WithdrawGrace = 10 * time.Second
+	require.NoError(t, c.Validate())
+	c.Anycast = false
+
+	// control API
+	c.ControlSocket = "/run/ntpresponder.sock"
+	require.Error(t, c.Validate())
+	c.DrainStep = time.Minute
+	require.NoError(t, c.Validate())
+	c.ControlSocket = ""
+
 	require.NoError(t, c.Validate())
 }
@@ -0,0 +1,157 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Health statuses reported by control API
+const (
+	HealthOK       = "ok"
+	HealthDraining = "draining"
+	HealthFailing  = "failing"
+)
+
+// Health is the control API response describing responder state
+type Health struct {
+	Status string    `json:"status"`
+	Drain  DrainMode `json:"drain,omitempty"`
+	// DrainedSince is unix time drain started at, 0 if responder is not drained
+	DrainedSince int64 `json:"drained_since,omitempty"`
+	// Stratum is the stratum advertised to clients now
+	Stratum int    `json:"stratum"`
+	Error   string `json:"error,omitempty"`
+}
+
+// startControl serves control API on the unix socket until ctx is done
+func (s *Server) startControl(ctx context.Context) error {
+	if err := os.Remove(s.Config.ControlSocket); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing stale control socket: %w", err)
+	}
+	l, err := net.Listen("unix", s.Config.ControlSocket)
+	if err != nil {
+		return fmt.Errorf("listening on control socket: %w", err)
+	}
+	srv := &http.Server{Handler: s.controlHandler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	go func() {
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Errorf("[control] failed to serve control API: %v", err)
+		}
+	}()
+	return nil
+}
+
+// controlHandler returns handler of control API:
+// GET /health reports the state, POST /drain?mode=stratum|silent and POST /undrain change it.
+// Every endpoint responds with Health, and the status is 503 unless responder is serving and healthy
+func (s *Server) controlHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
+		s.writeHealth(w)
+	})
+	mux.HandleFunc("/drain", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		mode := DrainStratum
+		if m := r.URL.Query().Get("mode"); m != "" {
+			if err := mode.UnmarshalText([]byte(m)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		log.Warningf("[control] draining (%s)", mode)
+		s.drain.drain(mode, time.Now())
+		s.Stats.SetDrain(1)
+		if s.Config.ShouldAnnounce {
+			// traffic moves away as withdrawal propagates, requests still routed here are served as usual
+			if err := s.Announce.Withdraw(); err != nil {
+				log.Errorf("[control] failed to withdraw announce: %v", err)
+			} else {
+				s.Stats.ResetAnnounce()
+			}
+		}
+		s.writeHealth(w)
+	})
+	mux.HandleFunc("/undrain", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		log.Warning("[control] undraining")
+		s.drain.undrain()
+		s.Stats.SetDrain(0)
+		if s.Config.ShouldAnnounce {
+			if err := s.Announce.Advertise(s.Config.IPs); err != nil {
+				log.Errorf("[control] failed to announce: %v", err)
+			} else {
+				s.Stats.SetAnnounce()
+			}
+		}
+		s.writeHealth(w)
+	})
+	return mux
+}
+
+// health returns the current state of the responder
+func (s *Server) health(now time.Time) *Health {
+	stratum := uint8(s.Config.Stratum)
+	if s.sync != nil {
+		stratum = s.sync.state.Load().stratum
+	}
+	h := &Health{Status: HealthOK, Stratum: int(s.drain.stratum(stratum, now))}
+	if st := s.drain.state.Load(); st != nil {
+		h.Status = HealthDraining
+		h.Drain = st.mode
+		h.DrainedSince = st.since.Unix()
+	}
+	if err := s.Checker.Check(); err != nil {
+		h.Status = HealthFailing
+		h.Error = err.Error()
+	}
+	return h
+}
+
+func (s *Server) writeHealth(w http.ResponseWriter) {
+	h := s.health(time.Now())
+	js, err := json.Marshal(h)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if h.Status != HealthOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if _, err = w.Write(js); err != nil {
+		log.Errorf("[control] failed to reply: %v", err)
+	}
+}
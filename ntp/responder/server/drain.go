@@ -0,0 +1,101 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// DrainMode is how responder gets rid of clients while drained
+type DrainMode string
+
+// Supported drain modes
+const (
+	// DrainStratum keeps answering, advertising worse stratum over time, so clients move to other servers on their own
+	DrainStratum DrainMode = "stratum"
+	// DrainSilent stops answering right away
+	DrainSilent DrainMode = "silent"
+)
+
+// MarshalText drain mode to byte slice
+func (m DrainMode) MarshalText() ([]byte, error) {
+	return []byte(m), nil
+}
+
+// UnmarshalText drain mode from byte slice
+func (m *DrainMode) UnmarshalText(value []byte) error {
+	switch v := DrainMode(value); v {
+	case DrainStratum, DrainSilent:
+		*m = v
+		return nil
+	}
+	return fmt.Errorf("unknown drain mode %q", value)
+}
+
+// drainState is the drain requested via control API
+type drainState struct {
+	mode  DrainMode
+	since time.Time
+}
+
+// drainer keeps drain state for workers, nil state means responder is serving
+type drainer struct {
+	// step is how often advertised stratum gets worse by one in DrainStratum mode
+	step  time.Duration
+	state atomic.Pointer[drainState]
+}
+
+func newDrainer(step time.Duration) *drainer {
+	return &drainer{step: step}
+}
+
+// drain starts draining, keeping the start time if responder is already drained in the same mode
+func (d *drainer) drain(mode DrainMode, now time.Time) {
+	if st := d.state.Load(); st != nil && st.mode == mode {
+		return
+	}
+	d.state.Store(&drainState{mode: mode, since: now})
+}
+
+// undrain makes responder serve as usual
+func (d *drainer) undrain() {
+	d.state.Store(nil)
+}
+
+// drained returns whether responder is drained in any mode, drainer may be nil if control API is disabled
+func (d *drainer) drained() bool {
+	return d != nil && d.state.Load() != nil
+}
+
+// silent returns whether requests must be dropped
+func (d *drainer) silent() bool {
+	st := d.state.Load()
+	return st != nil && st.mode == DrainSilent
+}
+
+// stratum returns stratum to advertise instead of the base one:
+// one worse right after drain starts and one more every step, until clients see responder as unsynchronized
+func (d *drainer) stratum(base uint8, now time.Time) uint8 {
+	st := d.state.Load()
+	if st == nil || st.mode != DrainStratum {
+		return base
+	}
+	steps := 1 + int(now.Sub(st.since)/d.step)
+	return uint8(min(int(base)+steps, stratumUnsynchronized))
+}
@@ -0,0 +1,207 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/facebook/time/ntp/responder/checker"
+	"github.com/facebook/time/ntp/responder/stats"
+	"github.com/facebook/time/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainMode(t *testing.T) {
+	var m DrainMode
+	require.NoError(t, m.UnmarshalText([]byte("silent")))
+	require.Equal(t, DrainSilent, m)
+	require.Error(t, m.UnmarshalText([]byte("kill")))
+	b, err := DrainStratum.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, []byte("stratum"), b)
+}
+
+func TestDrainer(t *testing.T) {
+	d := newDrainer(time.Minute)
+	now := time.Now()
+	require.False(t, d.silent())
+	require.Equal(t, uint8(1), d.stratum(1, now))
+
+	d.drain(DrainStratum, now)
+	require.False(t, d.silent())
+	require.Equal(t, uint8(2), d.stratum(1, now))
+	require.Equal(t, uint8(4), d.stratum(1, now.Add(2*time.Minute)))
+	require.Equal(t, uint8(16), d.stratum(1, now.Add(time.Hour)))
+
+	// draining again doesn't restart the ramp
+	d.drain(DrainStratum, now.Add(2*time.Minute))
+	require.Equal(t, uint8(4), d.stratum(1, now.Add(2*time.Minute)))
+
+	d.drain(DrainSilent, now)
+	require.True(t, d.silent())
+	require.Equal(t, uint8(1), d.stratum(1, now))
+
+	d.undrain()
+	require.False(t, d.silent())
+	require.Equal(t, uint8(1), d.stratum(1, now))
+}
+
+func TestServerControl(t *testing.T) {
+	s := &Server{
+		Checker: &checker.SimpleChecker{ExpectedListeners: 1, ExpectedWorkers: 1},
+		Stats:   &stats.JSONStats{},
+		tasks:   make(chan task, 1),
+		Config: Config{
+			Workers:       1,
+			Stratum:       1,
+			TimestampType: timestamp.SW,
+			ControlSocket: filepath.Join(t.TempDir(), "control.sock"),
+			DrainStep:     time.Hour,
+		},
+	}
+	s.drain = newDrainer(s.Config.DrainStep)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, s.startControl(ctx))
+	go s.startWorker()
+	conn := tryListenUDP(t)
+	defer conn.Close()
+	go s.startListener(conn)
+	time.Sleep(100 * time.Millisecond)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", s.Config.ControlSocket)
+		},
+	}}
+	call := func(method, path string) (int, *Health) {
+		req, err := http.NewRequest(method, "http://ntpresponder"+path, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		h := &Health{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(h))
+		return resp.StatusCode, h
+	}
+
+	sendConn, err := net.DialTimeout("udp", conn.LocalAddr().String(), time.Second)
+	require.NoError(t, err)
+	defer sendConn.Close()
+	exchange := func() *ntp.Packet {
+		request, err := (&ntp.Packet{Settings: 0x23}).Bytes()
+		require.NoError(t, err)
+		_, err = sendConn.Write(request)
+		require.NoError(t, err)
+		require.NoError(t, sendConn.SetReadDeadline(time.Now().Add(200*time.Millisecond)))
+		buf := make([]byte, 1024)
+		n, err := sendConn.Read(buf)
+		if err != nil {
+			return nil
+		}
+		p, err := ntp.BytesToPacket(buf[:n])
+		require.NoError(t, err)
+		return p
+	}
+
+	code, h := call(http.MethodGet, "/health")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, &Health{Status: HealthOK, Stratum: 1}, h)
+	require.Equal(t, uint8(1), exchange().Stratum)
+
+	// stratum mode keeps answering
+	code, h = call(http.MethodPost, "/drain")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, HealthDraining, h.Status)
+	require.Equal(t, DrainStratum, h.Drain)
+	require.Equal(t, 2, h.Stratum)
+	require.Equal(t, uint8(2), exchange().Stratum)
+
+	// silent mode doesn't
+	code, h = call(http.MethodPost, "/drain?mode=silent")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, DrainSilent, h.Drain)
+	require.Nil(t, exchange())
+
+	code, h = call(http.MethodPost, "/undrain")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, &Health{Status: HealthOK, Stratum: 1}, h)
+	require.Equal(t, uint8(1), exchange().Stratum)
+
+	req, err := http.NewRequest(http.MethodPost, "http://ntpresponder/drain?mode=kill", nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+type testAnnounce struct {
+	advertised bool
+}
+
+func (a *testAnnounce) Advertise([]net.IP) error {
+	a.advertised = true
+	return nil
+}
+
+func (a *testAnnounce) Withdraw() error {
+	a.advertised = false
+	return nil
+}
+
+func TestControlAnnounce(t *testing.T) {
+	a := &testAnnounce{advertised: true}
+	s := &Server{
+		Checker:  &checker.SimpleChecker{},
+		Stats:    &stats.JSONStats{},
+		Announce: a,
+		Config:   Config{ShouldAnnounce: true, DrainStep: time.Minute},
+		drain:    newDrainer(time.Minute),
+	}
+	h := s.controlHandler()
+
+	// drain withdraws anycast addresses, so traffic moves away gracefully
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/drain", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.False(t, a.advertised)
+	require.True(t, s.drain.drained())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/undrain", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.True(t, a.advertised)
+	require.False(t, s.drain.drained())
+
+	// only POST changes the state
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/drain", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	require.True(t, a.advertised)
+
+	var d *drainer
+	require.False(t, d.drained())
+}
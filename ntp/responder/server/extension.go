@@ -0,0 +1,44 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	ntp "github.com/facebook/time/ntp/protocol"
+	log "github.com/sirupsen/logrus"
+)
+
+// extensionUnknown is what extension fields of types nobody registered are counted as
+const extensionUnknown = "unknown"
+
+// countExtensions validates extension fields following NTP header and counts them by type.
+// Unknown fields are ignored as RFC 7822 requires, so only malformed packets are discarded
+func (s *Server) countExtensions(b []byte) bool {
+	fields, _, err := ntp.ParseExtensionFields(b)
+	if err != nil {
+		log.Debugf("Invalid extension fields, discarding: %v", err)
+		s.Stats.IncInvalidFormat()
+		return false
+	}
+	for _, f := range fields {
+		name, ok := ntp.ExtensionName(f.Type)
+		if !ok {
+			name = extensionUnknown
+		}
+		s.Stats.IncExtension(name)
+	}
+	return true
+}
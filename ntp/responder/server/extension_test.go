@@ -0,0 +1,72 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/facebook/time/ntp/responder/checker"
+	"github.com/facebook/time/ntp/responder/stats"
+	"github.com/facebook/time/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerExtensionFields(t *testing.T) {
+	s := &Server{
+		Checker: &checker.SimpleChecker{ExpectedListeners: 1, ExpectedWorkers: 1},
+		Stats:   &stats.JSONStats{},
+		tasks:   make(chan task, 1),
+		Config:  Config{Workers: 1, TimestampType: timestamp.SW},
+	}
+	go s.startWorker()
+	conn := tryListenUDP(t)
+	defer conn.Close()
+	go s.startListener(conn)
+	time.Sleep(100 * time.Millisecond)
+
+	sendConn, err := net.DialTimeout("udp", conn.LocalAddr().String(), time.Second)
+	require.NoError(t, err)
+	defer sendConn.Close()
+
+	sec, frac := ntp.Time(time.Now())
+	header, err := (&ntp.Packet{Settings: 0x23, TxTimeSec: sec, TxTimeFrac: frac}).Bytes()
+	require.NoError(t, err)
+	buf := make([]byte, 1024)
+
+	// unknown extension fields are ignored
+	request := ntp.AppendExtensionField(header, &ntp.ExtensionField{Type: 0xf000, Value: make([]byte, 24)})
+	_, err = sendConn.Write(request)
+	require.NoError(t, err)
+	require.NoError(t, sendConn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := sendConn.Read(buf)
+	require.NoError(t, err)
+	response, err := ntp.BytesToPacket(buf[:n])
+	require.NoError(t, err)
+	require.Equal(t, sec, response.OrigTimeSec)
+	require.Equal(t, frac, response.OrigTimeFrac)
+
+	// malformed ones are not
+	request = append(header, 0xf0, 0x00, 0x00, 0x06, 0x00, 0x00)
+	_, err = sendConn.Write(request)
+	require.NoError(t, err)
+	require.NoError(t, sendConn.SetReadDeadline(time.Now().Add(200*time.Millisecond)))
+	_, err = sendConn.Read(buf)
+	require.Error(t, err)
+}
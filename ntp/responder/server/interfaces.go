@@ -38,6 +38,50 @@ type Stats interface {
 	IncWorkers()
 	// IncReadError atomically add 1 to the counter
 	IncReadError()
+	// IncWorkerRequests atomically add 1 to the per worker counter
+	IncWorkerRequests(int)
+	// IncNTSRequests atomically add 1 to the counter
+	IncNTSRequests()
+	// IncNTSNAKs atomically add 1 to the counter
+	IncNTSNAKs()
+	// IncAuthRequests atomically add 1 to the counter
+	IncAuthRequests()
+	// IncAuthFailures atomically add 1 to the counter
+	IncAuthFailures()
+	// IncTXTimestamps atomically add 1 to the counter
+	IncTXTimestamps()
+	// IncInterleaved atomically add 1 to the counter
+	IncInterleaved()
+	// IncTimestamping atomically add 1 to the number of sockets with the timestamping type
+	IncTimestamping(string)
+	// IncExtension atomically add 1 to the number of received extension fields of the type
+	IncExtension(string)
+	// IncRateLimited atomically add 1 to the counter
+	IncRateLimited()
+	// IncKoD atomically add 1 to the counter and to the counter of the kiss code
+	IncKoD(string)
+	// IncRequestVersion atomically add 1 to the counter of requests of the NTP version
+	IncRequestVersion(int)
+	// IncACLIgnored atomically add 1 to the counter
+	IncACLIgnored()
+	// SetACLCounters replaces per ACL rule counters of requests
+	SetACLCounters(map[string]int64)
+	// SetRateLimitOffenders replaces per prefix counters of rate limited requests
+	SetRateLimitOffenders(map[string]int64)
+	// IncCaptured atomically add 1 to the counter
+	IncCaptured()
+	// IncCaptureDropped atomically add 1 to the counter
+	IncCaptureDropped()
+	// IncDrained atomically add 1 to the counter
+	IncDrained()
+	// SetDrain atomically sets drain status
+	SetDrain(int64)
+	// SetServedOffset atomically sets offset of served time from the reference clock in nanoseconds
+	SetServedOffset(int64)
+	// SetStratum atomically sets advertised stratum
+	SetStratum(int64)
+	// SetRootDispersion atomically sets advertised error bound in nanoseconds
+	SetRootDispersion(int64)
 
 	// DecListeners atomically removes 1 from the counter
 	DecListeners()
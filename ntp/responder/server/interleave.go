@@ -0,0 +1,169 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"hash/maphash"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+)
+
+const (
+	// interleaveShards reduces lock contention between workers
+	interleaveShards = 64
+	// interleaveMaxEntries limits memory used to track clients, clients beyond that are served in basic mode
+	interleaveMaxEntries = 1 << 20
+	// interleaveTTL is how long we keep the last exchange of the client, longer than the longest poll interval
+	interleaveTTL = 2 * time.Hour
+	// txDelayWeight is the weight of a new sample in the running average of transmit delay
+	txDelayWeight = 16
+	// maxTXDelay is the biggest difference between software and hardware transmit time we consider sane
+	maxTXDelay = 10 * time.Millisecond
+)
+
+// txRecord is what we need to know about the response when its transmit timestamp arrives
+type txRecord struct {
+	addr   netip.Addr
+	rx     uint64        // receive timestamp we sent
+	sent   time.Time     // system time transmit timestamp in the response is based on
+	offset time.Duration // extra offset and smear applied to timestamps in the response
+	// onTransmit is called with hardware transmit timestamp of the response, only set for sampled responses
+	onTransmit func(time.Time)
+}
+
+// exchange is the last response we sent to the client
+type exchange struct {
+	rx   uint64 // receive timestamp we sent
+	tx   uint64 // hardware transmit timestamp of the response, 0 until we know it
+	last time.Time
+}
+
+type interleaveShard struct {
+	sync.Mutex
+	clients map[netip.Addr]*exchange
+}
+
+// interleaveTable keeps hardware transmit timestamps of the last response to every client for the interleaved mode:
+// client which puts our receive timestamp into origin timestamp of the next request gets transmit timestamp of the previous response
+// in the transmit timestamp, which is accurate unlike the one we put into a packet before sending it.
+// It also keeps the running average of how much later responses leave the NIC than we timestamp them in software
+type interleaveTable struct {
+	seed    maphash.Seed
+	shards  [interleaveShards]interleaveShard
+	txDelay atomic.Int64
+}
+
+func newInterleaveTable() *interleaveTable {
+	t := &interleaveTable{seed: maphash.MakeSeed()}
+	for i := range t.shards {
+		t.shards[i].clients = map[netip.Addr]*exchange{}
+	}
+	return t
+}
+
+func (t *interleaveTable) shard(addr netip.Addr) *interleaveShard {
+	b := addr.As16()
+	return &t.shards[maphash.Bytes(t.seed, b[:])%interleaveShards]
+}
+
+// lookup returns transmit timestamp of the previous response if the client asks for interleaved mode
+func (t *interleaveTable) lookup(addr netip.Addr, orig uint64) (uint64, bool) {
+	s := t.shard(addr)
+	s.Lock()
+	defer s.Unlock()
+	e, ok := s.clients[addr]
+	if !ok || e.rx != orig || e.tx == 0 {
+		return 0, false
+	}
+	return e.tx, true
+}
+
+// sent records the response sent to the client, its transmit timestamp is not known yet
+func (t *interleaveTable) sent(addr netip.Addr, rx uint64, now time.Time) {
+	s := t.shard(addr)
+	s.Lock()
+	defer s.Unlock()
+	e, ok := s.clients[addr]
+	if !ok {
+		if len(s.clients) >= interleaveMaxEntries/interleaveShards {
+			t.cleanupShard(s, now)
+			if len(s.clients) >= interleaveMaxEntries/interleaveShards {
+				return
+			}
+		}
+		e = &exchange{}
+		s.clients[addr] = e
+	}
+	*e = exchange{rx: rx, last: now}
+}
+
+// transmitted records hardware transmit timestamp of the response, unless the client got a newer one already
+func (t *interleaveTable) transmitted(addr netip.Addr, rx, tx uint64) {
+	s := t.shard(addr)
+	s.Lock()
+	defer s.Unlock()
+	if e, ok := s.clients[addr]; ok && e.rx == rx {
+		e.tx = tx
+	}
+}
+
+func (t *interleaveTable) cleanupShard(s *interleaveShard, now time.Time) {
+	for addr, e := range s.clients {
+		if now.Sub(e.last) > interleaveTTL {
+			delete(s.clients, addr)
+		}
+	}
+}
+
+// cleanup forgets clients we didn't hear from for a while
+func (t *interleaveTable) cleanup(now time.Time) {
+	for i := range t.shards {
+		s := &t.shards[i]
+		s.Lock()
+		t.cleanupShard(s, now)
+		s.Unlock()
+	}
+}
+
+// observeTXDelay updates the running average of transmit delay with a new sample
+func (t *interleaveTable) observeTXDelay(d time.Duration) {
+	if d < 0 || d > maxTXDelay {
+		return
+	}
+	avg := time.Duration(t.txDelay.Load())
+	if avg == 0 {
+		avg = d
+	} else {
+		avg += (d - avg) / txDelayWeight
+	}
+	t.txDelay.Store(int64(avg))
+}
+
+// delay returns the running average of transmit delay, so transmit timestamps in basic mode can be corrected
+func (t *interleaveTable) delay() time.Duration {
+	return time.Duration(t.txDelay.Load())
+}
+
+// ntpTime returns timestamp in NTP format as a single number
+func ntpTime(t time.Time) uint64 {
+	sec, frac := ntp.Time(t)
+	return uint64(sec)<<32 | uint64(frac)
+}
@@ -0,0 +1,87 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNTPTime(t *testing.T) {
+	sec, frac := ntp.Time(ts)
+	require.Equal(t, uint64(sec)<<32|uint64(frac), ntpTime(ts))
+}
+
+func TestInterleaveTable(t *testing.T) {
+	table := newInterleaveTable()
+	client := netip.MustParseAddr("192.0.2.1")
+	other := netip.MustParseAddr("2001:db8::1")
+	now := time.Unix(1585231321, 0)
+
+	_, ok := table.lookup(client, 1)
+	require.False(t, ok)
+
+	// transmit timestamp is not known yet
+	table.sent(client, 1, now)
+	_, ok = table.lookup(client, 1)
+	require.False(t, ok)
+
+	table.transmitted(client, 1, 42)
+	tx, ok := table.lookup(client, 1)
+	require.True(t, ok)
+	require.Equal(t, uint64(42), tx)
+	// origin doesn't match our receive timestamp, basic mode
+	_, ok = table.lookup(client, 2)
+	require.False(t, ok)
+	_, ok = table.lookup(other, 1)
+	require.False(t, ok)
+
+	// newer response was sent before the older transmit timestamp arrived
+	table.sent(client, 3, now)
+	table.transmitted(client, 1, 43)
+	_, ok = table.lookup(client, 3)
+	require.False(t, ok)
+	table.transmitted(client, 3, 44)
+	tx, ok = table.lookup(client, 3)
+	require.True(t, ok)
+	require.Equal(t, uint64(44), tx)
+
+	// idle clients are forgotten
+	table.sent(other, 5, now.Add(interleaveTTL))
+	table.cleanup(now.Add(interleaveTTL + time.Second))
+	_, ok = table.shard(client).clients[client]
+	require.False(t, ok)
+	_, ok = table.shard(other).clients[other]
+	require.True(t, ok)
+}
+
+func TestInterleaveTableTXDelay(t *testing.T) {
+	table := newInterleaveTable()
+	require.Equal(t, time.Duration(0), table.delay())
+	table.observeTXDelay(16 * time.Microsecond)
+	require.Equal(t, 16*time.Microsecond, table.delay())
+	table.observeTXDelay(32 * time.Microsecond)
+	require.Equal(t, 17*time.Microsecond, table.delay())
+	// outliers are ignored
+	table.observeTXDelay(-time.Microsecond)
+	table.observeTXDelay(time.Second)
+	require.Equal(t, 17*time.Microsecond, table.delay())
+}
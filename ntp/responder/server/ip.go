@@ -23,12 +23,36 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// bitsInBytes is a number of bits in byte
+const bitsInBytes = 8
+
+// ipv4Len is the IPv4 len in bits
+const ipv4Len = net.IPv4len * bitsInBytes
+
+// ipv6Len is the IPv6 len in bits
+const ipv6Len = net.IPv6len * bitsInBytes
+
 // ipv4Mask is a mask we will be assigning to the IPv4 address in interface
 const ipv4Mask = 32
 
-// ipv6Mask is a mask we will be assigning to the IPv4 address in interface
+// ipv6Mask is a mask we will be assigning to the IPv6 address in interface
 const ipv6Mask = 64
 
+// anycastIPv6Mask is a mask we will be assigning to the anycast IPv6 address in interface,
+// so it doesn't claim the whole /64 other hosts may have addresses in
+const anycastIPv6Mask = 128
+
+// ipNet returns ip with the mask it's assigned to the interface with
+func (s *Server) ipNet(ip net.IP) *net.IPNet {
+	if ip.To4() != nil {
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(ipv4Mask, ipv4Len)}
+	}
+	if s.Config.Anycast {
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(anycastIPv6Mask, ipv6Len)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(ipv6Mask, ipv6Len)}
+}
+
 // AddIPOnInterface adds ip to interface
 func (s *Server) addIPToInterface(vip net.IP) error {
 	if !s.Config.ManageLoopback {
@@ -41,7 +65,8 @@ func (s *Server) addIPToInterface(vip net.IP) error {
 		return fmt.Errorf("failed to add IP to the %s interface: %w", s.Config.Iface, err)
 	}
 
-	return addIfaceIP(iface, &vip)
+	// anycast address is on other hosts too, duplicate address detection would only delay it being usable
+	return addIfaceIP(iface, s.ipNet(vip), s.Config.Anycast)
 }
 
 // deleteIPFromInterface deletes ip from interface
@@ -56,7 +81,7 @@ func (s *Server) deleteIPFromInterface(vip net.IP) error {
 		return err
 	}
 
-	return deleteIfaceIP(iface, &vip)
+	return deleteIfaceIP(iface, s.ipNet(vip))
 }
 
 // DeleteAllIPs deletes all IPs from interface specified in config
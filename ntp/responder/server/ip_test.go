@@ -42,3 +42,14 @@ func TestDeleteIPFromInterfaceError(t *testing.T) {
 	err := s.deleteIPFromInterface(net.ParseIP(testIP))
 	require.NotNil(t, err)
 }
+
+func TestIPNet(t *testing.T) {
+	s := &Server{}
+	require.Equal(t, "1.2.3.4/32", s.ipNet(net.ParseIP(testIP)).String())
+	require.Equal(t, "2001:db8::1/64", s.ipNet(net.ParseIP("2001:db8::1")).String())
+
+	// anycast address must not claim the subnet
+	s.Config.Anycast = true
+	require.Equal(t, "1.2.3.4/32", s.ipNet(net.ParseIP(testIP)).String())
+	require.Equal(t, "2001:db8::1/128", s.ipNet(net.ParseIP("2001:db8::1")).String())
+}
@@ -0,0 +1,117 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/facebook/time/ntp/nts"
+	log "github.com/sirupsen/logrus"
+)
+
+// ntsKeysReloadInterval is how often cookie keys are re-read, so keys can be rotated without restart
+const ntsKeysReloadInterval = time.Minute
+
+// startNTS loads cookie keys and starts NTS-KE server if configured
+func (s *Server) startNTS(ctx context.Context) error {
+	keys, err := nts.ReadCookieKeys(s.Config.NTSCookieKeys)
+	if err != nil {
+		return fmt.Errorf("reading nts cookie keys: %w", err)
+	}
+	s.cookies, err = nts.NewCookieJar(keys...)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(ntsKeysReloadInterval):
+				keys, err := nts.ReadCookieKeys(s.Config.NTSCookieKeys)
+				if err == nil {
+					err = s.cookies.SetKeys(keys...)
+				}
+				if err != nil {
+					log.Errorf("[nts] failed to reload cookie keys: %v", err)
+				}
+			}
+		}
+	}()
+
+	if s.Config.NTSKEPort == 0 {
+		// NTS-KE runs elsewhere and shares cookie keys with us
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(s.Config.NTSCert, s.Config.NTSKey)
+	if err != nil {
+		return fmt.Errorf("loading nts-ke certificate: %w", err)
+	}
+	ke := &nts.KEServer{
+		TLSConfig:   &tls.Config{Certificates: []tls.Certificate{cert}},
+		Cookies:     s.cookies,
+		NTPPort:     s.ntsAdvertisedPort(),
+		MaxSessions: s.Config.NTSKEMaxSessions,
+	}
+	for _, ip := range s.Config.IPs {
+		addr := net.JoinHostPort(ip.String(), strconv.Itoa(s.Config.NTSKEPort))
+		log.Infof("Starting NTS-KE listener on %s", addr)
+		go func() {
+			if err := ke.ListenAndServe(ctx, addr); err != nil {
+				log.Errorf("[nts-ke] listener on %s failed: %v", addr, err)
+			}
+		}()
+	}
+	return nil
+}
+
+// ntsAdvertisedPort returns NTP port to advertise to NTS-KE clients, 0 if it's the default one
+func (s *Server) ntsAdvertisedPort() int {
+	if s.Config.Port == 123 {
+		return 0
+	}
+	return s.Config.Port
+}
+
+// ntsResponse authenticates NTS request and protects the response.
+// It returns nil if the request must be dropped
+func (t *task) ntsResponse(response []byte) []byte {
+	r, err := t.cookies.ParseRequest(t.raw)
+	if errors.Is(err, nts.ErrNAK) {
+		log.Debugf("NTS NAK: %v", err)
+		t.stats.IncNTSNAKs()
+		return nts.NAK(response, r.UniqueID)
+	}
+	if err != nil {
+		log.Debugf("Invalid NTS request, discarding: %v", err)
+		t.stats.IncInvalidFormat()
+		return nil
+	}
+	b, err := t.cookies.Response(response, r)
+	if err != nil {
+		log.Errorf("Failed to generate NTS response: %v", err)
+		return nil
+	}
+	t.stats.IncNTSRequests()
+	return b
+}
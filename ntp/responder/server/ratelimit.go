@@ -0,0 +1,189 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"cmp"
+	"hash/maphash"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// rateLimitShards reduces lock contention between workers
+	rateLimitShards = 64
+	// rateLimitMaxEntries limits memory used to track clients, clients beyond that are not limited
+	rateLimitMaxEntries = 1 << 20
+	// kodInterval is how often rate limited client gets Kiss-o'-Death, the rest of its requests is dropped,
+	// so we never send more than a KoD per interval to a spoofed address
+	kodInterval = time.Second
+	// maxOffenders is how many top offenders are reported in stats
+	maxOffenders = 10
+)
+
+// KissCodeRate is the kiss code telling client to reduce its polling rate
+const KissCodeRate = "RATE"
+
+// rateLimitAction is what to do with the request
+type rateLimitAction int
+
+const (
+	rateLimitServe rateLimitAction = iota
+	rateLimitKoD
+	rateLimitDrop
+)
+
+// bucket is a token bucket of a single client prefix
+type bucket struct {
+	tokens  float64
+	last    time.Time
+	lastKoD time.Time
+	limited int64
+}
+
+type rateLimitShard struct {
+	sync.Mutex
+	buckets map[netip.Prefix]*bucket
+}
+
+// rateLimiter limits request rate per client prefix with token buckets.
+// Prefixes are used so clients can't evade the limit by rotating addresses within their IPv6 subnet
+type rateLimiter struct {
+	rate     float64 // tokens per second
+	burst    float64 // bucket size
+	prefixV4 int
+	prefixV6 int
+	seed     maphash.Seed
+	shards   [rateLimitShards]rateLimitShard
+}
+
+func newRateLimiter(rate float64, burst int, prefixV4, prefixV6 int) *rateLimiter {
+	r := &rateLimiter{rate: rate, burst: float64(burst), prefixV4: prefixV4, prefixV6: prefixV6, seed: maphash.MakeSeed()}
+	for i := range r.shards {
+		r.shards[i].buckets = map[netip.Prefix]*bucket{}
+	}
+	return r
+}
+
+// prefix returns client prefix of the socket address
+func (r *rateLimiter) prefix(sa unix.Sockaddr) (netip.Prefix, bool) {
+	var addr netip.Addr
+	switch a := sa.(type) {
+	case *unix.SockaddrInet4:
+		addr = netip.AddrFrom4(a.Addr)
+	case *unix.SockaddrInet6:
+		addr = netip.AddrFrom16(a.Addr).Unmap()
+	default:
+		return netip.Prefix{}, false
+	}
+	bits := r.prefixV6
+	if addr.Is4() {
+		bits = r.prefixV4
+	}
+	p, err := addr.Prefix(bits)
+	return p, err == nil
+}
+
+func (r *rateLimiter) shard(p netip.Prefix) *rateLimitShard {
+	b := p.Addr().As16()
+	return &r.shards[maphash.Bytes(r.seed, b[:])%rateLimitShards]
+}
+
+// check takes a token from the client bucket and returns what to do with the request
+func (r *rateLimiter) check(sa unix.Sockaddr, now time.Time) rateLimitAction {
+	p, ok := r.prefix(sa)
+	if !ok {
+		return rateLimitServe
+	}
+	s := r.shard(p)
+	s.Lock()
+	defer s.Unlock()
+	b, ok := s.buckets[p]
+	if !ok {
+		if len(s.buckets) >= rateLimitMaxEntries/rateLimitShards {
+			r.cleanupShard(s, now)
+			if len(s.buckets) >= rateLimitMaxEntries/rateLimitShards {
+				return rateLimitServe
+			}
+		}
+		b = &bucket{tokens: r.burst, last: now}
+		s.buckets[p] = b
+	}
+	b.tokens = min(r.burst, b.tokens+now.Sub(b.last).Seconds()*r.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return rateLimitServe
+	}
+	b.limited++
+	if now.Sub(b.lastKoD) >= kodInterval {
+		b.lastKoD = now
+		return rateLimitKoD
+	}
+	return rateLimitDrop
+}
+
+// cleanupShard forgets clients whose buckets have refilled, they are indistinguishable from new ones
+func (r *rateLimiter) cleanupShard(s *rateLimitShard, now time.Time) {
+	idle := time.Duration(r.burst / r.rate * float64(time.Second))
+	for p, b := range s.buckets {
+		if now.Sub(b.last) > idle {
+			delete(s.buckets, p)
+		}
+	}
+}
+
+// cleanup forgets idle clients, and returns top offenders which are still limited with number of requests limited
+func (r *rateLimiter) cleanup(now time.Time) map[string]int64 {
+	type offender struct {
+		prefix  netip.Prefix
+		limited int64
+	}
+	offenders := []offender{}
+	for i := range r.shards {
+		s := &r.shards[i]
+		s.Lock()
+		r.cleanupShard(s, now)
+		for p, b := range s.buckets {
+			if b.limited > 0 {
+				offenders = append(offenders, offender{prefix: p, limited: b.limited})
+			}
+		}
+		s.Unlock()
+	}
+	slices.SortFunc(offenders, func(a, b offender) int {
+		return cmp.Compare(b.limited, a.limited)
+	})
+	top := map[string]int64{}
+	for _, o := range offenders[:min(len(offenders), maxOffenders)] {
+		top[o.prefix.String()] = o.limited
+	}
+	return top
+}
+
+// kissOfDeath turns response into Kiss-o'-Death packet with the kiss code
+func kissOfDeath(b []byte, code string) []byte {
+	// leap indicator 3 (clock not synchronized), stratum 0 and kiss code in reference id
+	b[0] |= 0xc0
+	b[1] = 0
+	copy(b[12:16], code)
+	return b
+}
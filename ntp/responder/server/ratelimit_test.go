@@ -0,0 +1,133 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/facebook/time/ntp/responder/checker"
+	"github.com/facebook/time/ntp/responder/stats"
+	"github.com/facebook/time/timestamp"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestRateLimiterPrefix(t *testing.T) {
+	r := newRateLimiter(1, 1, 24, 64)
+	p, ok := r.prefix(timestamp.IPToSockaddr(net.ParseIP("192.0.2.42"), 123))
+	require.True(t, ok)
+	require.Equal(t, "192.0.2.0/24", p.String())
+
+	p, ok = r.prefix(&unix.SockaddrInet6{Addr: [16]byte(net.ParseIP("::ffff:192.0.2.42"))})
+	require.True(t, ok)
+	require.Equal(t, "192.0.2.0/24", p.String())
+
+	p, ok = r.prefix(timestamp.IPToSockaddr(net.ParseIP("2001:db8:1:2:3:4:5:6"), 123))
+	require.True(t, ok)
+	require.Equal(t, "2001:db8:1:2::/64", p.String())
+
+	_, ok = r.prefix(&unix.SockaddrUnix{Name: "/nope"})
+	require.False(t, ok)
+}
+
+func TestRateLimiterCheck(t *testing.T) {
+	r := newRateLimiter(0.5, 2, 32, 64)
+	client := timestamp.IPToSockaddr(net.ParseIP("192.0.2.1"), 123)
+	other := timestamp.IPToSockaddr(net.ParseIP("192.0.2.2"), 123)
+	now := time.Unix(1585231321, 0)
+
+	// burst
+	require.Equal(t, rateLimitServe, r.check(client, now))
+	require.Equal(t, rateLimitServe, r.check(client, now))
+	// over the limit, KoD once per interval and drop the rest
+	require.Equal(t, rateLimitKoD, r.check(client, now))
+	require.Equal(t, rateLimitDrop, r.check(client, now.Add(100*time.Millisecond)))
+	require.Equal(t, rateLimitKoD, r.check(client, now.Add(1100*time.Millisecond)))
+	// other clients are not affected
+	require.Equal(t, rateLimitServe, r.check(other, now))
+	// token is added every 2 seconds
+	require.Equal(t, rateLimitServe, r.check(client, now.Add(3*time.Second)))
+	require.Equal(t, rateLimitKoD, r.check(client, now.Add(3*time.Second)))
+	require.Equal(t, rateLimitDrop, r.check(client, now.Add(3*time.Second)))
+
+	require.Equal(t, map[string]int64{"192.0.2.1/32": 5}, r.cleanup(now.Add(3*time.Second)))
+	// idle clients are forgotten
+	require.Equal(t, map[string]int64{}, r.cleanup(now.Add(time.Minute)))
+	require.Equal(t, rateLimitServe, r.check(client, now.Add(time.Minute)))
+}
+
+func TestRateLimiterTopOffenders(t *testing.T) {
+	r := newRateLimiter(1, 1, 32, 64)
+	now := time.Unix(1585231321, 0)
+	for i := 0; i < maxOffenders+5; i++ {
+		client := timestamp.IPToSockaddr(net.IPv4(192, 0, 2, byte(i)), 123)
+		for j := 0; j <= i+1; j++ {
+			r.check(client, now)
+		}
+	}
+	top := r.cleanup(now)
+	require.Len(t, top, maxOffenders)
+	require.Equal(t, int64(maxOffenders+5), top["192.0.2.14/32"])
+	require.NotContains(t, top, "192.0.2.0/32")
+}
+
+func TestKissOfDeath(t *testing.T) {
+	b, err := ntpRequest.Bytes()
+	require.NoError(t, err)
+	b[0] = 0x24
+	b[1] = 1
+	b = kissOfDeath(b, KissCodeRate)
+	require.Equal(t, uint8(0xe4), b[0])
+	require.Equal(t, uint8(0), b[1])
+	require.Equal(t, "RATE", string(b[12:16]))
+}
+
+func TestServerRateLimit(t *testing.T) {
+	s := &Server{
+		Checker: &checker.SimpleChecker{ExpectedListeners: 1, ExpectedWorkers: 1},
+		Stats:   &stats.JSONStats{},
+		tasks:   make(chan task, 1),
+		Config:  Config{Workers: 1, TimestampType: timestamp.SW, Stratum: 1},
+		limiter: newRateLimiter(0.001, 1, 32, 64),
+	}
+	go s.startWorker()
+	conn := tryListenUDP(t)
+	defer conn.Close()
+	go s.startListener(conn)
+	time.Sleep(100 * time.Millisecond)
+
+	sendConn, err := net.DialTimeout("udp", conn.LocalAddr().String(), time.Second)
+	require.NoError(t, err)
+	defer sendConn.Close()
+	require.NoError(t, sendConn.SetReadDeadline(time.Now().Add(time.Second)))
+
+	for _, stratum := range []uint8{1, 0} {
+		require.NoError(t, binary.Write(sendConn, binary.BigEndian, &ntp.Packet{Settings: 0x1B}))
+		response := &ntp.Packet{}
+		require.NoError(t, binary.Read(sendConn, binary.BigEndian, response))
+		require.Equal(t, stratum, response.Stratum)
+	}
+	// the rest is dropped
+	require.NoError(t, binary.Write(sendConn, binary.BigEndian, &ntp.Packet{Settings: 0x1B}))
+	require.NoError(t, sendConn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, err = sendConn.Read(make([]byte, 128))
+	require.Error(t, err)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net"
+	"runtime"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+	log "github.com/sirupsen/logrus"
+)
+
+// startReusePortWorkers starts Config.ReusePort workers per IP, each reading and serving requests on its own socket
+func (s *Server) startReusePortWorkers() {
+	log.Infof("Starting %d SO_REUSEPORT worker(s) per IP on %d IP(s)", s.Config.ReusePort, len(s.Config.IPs))
+
+	for i, ip := range s.Config.IPs {
+		// Need to be sure IP is on interface:
+		if err := s.addIPToInterface(ip); err != nil {
+			log.Errorf("[server]: %v", err)
+		}
+		addr := &net.UDPAddr{IP: ip, Port: s.Config.Port}
+		for j := 0; j < s.Config.ReusePort; j++ {
+			conn, err := listenUDP(addr, true, s.Config.Anycast)
+			if err != nil {
+				log.Fatalf("listening error: %v", err)
+			}
+			go func(id int) {
+				defer conn.Close()
+				s.Stats.IncListeners()
+				s.startReusePortWorker(conn, id)
+				s.Stats.DecListeners()
+			}(i*s.Config.ReusePort + j)
+		}
+	}
+}
+
+// startReusePortWorker reads requests from conn and serves them on the same OS thread, pinned to a CPU if configured
+func (s *Server) startReusePortWorker(conn *net.UDPConn, id int) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if s.Config.PinWorkers {
+		cpu, err := pinToCPU(id)
+		if err != nil {
+			log.Errorf("[worker %d] failed to pin to CPU: %v", id, err)
+		} else {
+			log.Debugf("[worker %d] pinned to CPU %d", id, cpu)
+		}
+	}
+
+	s.Checker.IncListeners()
+	defer s.Checker.DecListeners()
+	s.Checker.IncWorkers()
+	defer s.Checker.DecWorkers()
+	defer s.Stats.DecWorkers()
+
+	connFd, ts, tx := s.prepareConn(conn)
+	// Pre-allocating response buffer
+	response := &ntp.Packet{}
+	s.fillStaticHeaders(response)
+	s.Stats.IncWorkers()
+	s.receive(conn, connFd, ts, tx, func(t task) {
+		s.Stats.IncWorkerRequests(id)
+		t.serve(response, s.Config.ExtraOffset)
+	})
+}
@@ -0,0 +1,74 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/facebook/time/ntp/responder/checker"
+	"github.com/facebook/time/ntp/responder/stats"
+	"github.com/facebook/time/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenUDPReusePort(t *testing.T) {
+	conn, err := listenUDP(&net.UDPAddr{IP: net.ParseIP("127.0.0.1")}, true, false)
+	require.NoError(t, err)
+	defer conn.Close()
+	// another socket on the same port
+	other, err := listenUDP(conn.LocalAddr().(*net.UDPAddr), true, false)
+	require.NoError(t, err)
+	defer other.Close()
+	require.Equal(t, conn.LocalAddr(), other.LocalAddr())
+}
+
+func TestReusePortWorker(t *testing.T) {
+	st := &stats.JSONStats{}
+	ch := &checker.SimpleChecker{ExpectedListeners: 1, ExpectedWorkers: 1}
+	s := &Server{
+		Checker: ch,
+		Stats:   st,
+		Config:  Config{ReusePort: 1, TimestampType: timestamp.SW},
+	}
+	conn, err := listenUDP(&net.UDPAddr{IP: net.ParseIP("127.0.0.1")}, true, false)
+	require.NoError(t, err)
+	defer conn.Close()
+	go s.startReusePortWorker(conn, 7)
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, ch.Check())
+
+	sendConn, err := net.DialTimeout("udp", conn.LocalAddr().String(), time.Second)
+	require.NoError(t, err)
+	defer sendConn.Close()
+	request, err := ntpRequest.Bytes()
+	require.NoError(t, err)
+	_, err = sendConn.Write(request)
+	require.NoError(t, err)
+
+	buf := make([]byte, 1024)
+	require.NoError(t, sendConn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := sendConn.Read(buf)
+	require.NoError(t, err)
+	response, err := ntp.BytesToPacket(buf[:n])
+	require.NoError(t, err)
+	require.Equal(t, ntpRequest.TxTimeSec, response.OrigTimeSec)
+	require.Equal(t, ntpRequest.TxTimeFrac, response.OrigTimeFrac)
+	require.Equal(t, uint8(ntpRequest.Settings&0x38|4), response.Settings)
+}
@@ -22,18 +22,25 @@ package server
 
 import (
 	"context"
-	"encoding/binary"
 	"errors"
-	"fmt"
 	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/facebook/time/ntp/auth"
+	"github.com/facebook/time/ntp/nts"
 	ntp "github.com/facebook/time/ntp/protocol"
 	"github.com/facebook/time/timestamp"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 )
 
+// maxRequestSizeBytes is the biggest request we read, enough for NTS extension fields
+const maxRequestSizeBytes = 1024
+
 // task is a data structure with everything needed to work independently on NTP packet.
 type task struct {
 	connFd   int
@@ -41,6 +48,21 @@ type task struct {
 	received time.Time
 	request  *ntp.Packet
 	stats    Stats
+	// whole request with extension fields or MAC, only set for NTS, authenticated and sampled requests
+	raw     []byte
+	cookies *nts.CookieJar
+	keys    *auth.Keyring
+	smear   *leapSmear
+	limiter *rateLimiter
+	acl     *aclMatcher
+	sync    *syncTracker
+	drain   *drainer
+	// only set when hardware transmit timestamps are enabled
+	tx *txStamper
+	// only set for requests sampled for capture
+	capture *sampler
+	local   netip.AddrPort
+	rxIface int
 }
 
 // Server is a type for UDP server which handles connections.
@@ -50,40 +72,93 @@ type Server struct {
 	Stats    Stats
 	Checker  Checker
 	tasks    chan task
+	cookies  *nts.CookieJar
+	keys     *auth.Keyring
+	smear    *leapSmear
+	limiter  *rateLimiter
+	acl      *aclMatcher
+	sync     *syncTracker
+	drain    *drainer
+	// last exchange with every client for the interleaved mode
+	interleave *interleaveTable
+	capture    *sampler
+	// phcTime is set once a listener gets hardware timestamps, so time is served from PHC.
+	// It stays unset when listeners fall back to software timestamps
+	phcTime atomic.Bool
+	phcOnce sync.Once
+	cancel  context.CancelFunc
 }
 
 // Start UDP server.
 func (s *Server) Start(ctx context.Context, cancelFunc context.CancelFunc) {
 	log.Infof("Creating %d goroutine workers", s.Config.Workers)
 	s.tasks = make(chan task, s.Config.Workers)
-	// Pre-create workers
-	for i := 0; i < s.Config.Workers; i++ {
-		go s.startWorker()
+	s.cancel = cancelFunc
+	if s.Config.ChronySocket != "" {
+		log.Infof("Serving chronyc-compatible monitoring protocol on %s", s.Config.ChronySocket)
+		if err := s.startChrony(ctx); err != nil {
+			log.Fatalf("failed to start chrony monitoring: %v", err)
+		}
 	}
-
-	log.Infof("Starting %d listener(s)", len(s.Config.IPs))
-
-	for _, ip := range s.Config.IPs {
-		log.Infof("Starting listener on %s:%d", ip.String(), s.Config.Port)
-
-		go func(ip net.IP) {
-			s.Stats.IncListeners()
-			// Need to be sure IP is on interface:
-			if err := s.addIPToInterface(ip); err != nil {
-				log.Errorf("[server]: %v", err)
-			}
-
-			conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: ip, Port: s.Config.Port})
-			if err != nil {
-				log.Fatalf("listening error: %v", err)
+	if s.Config.LeapSmear > 0 {
+		log.Infof("Smearing leap seconds over %v (%s)", s.Config.LeapSmear, s.Config.LeapSmearShape)
+		s.smear = newLeapSmear(s.Config.LeapSmearShape, s.Config.LeapSmear)
+		go s.smear.run(ctx)
+	}
+	if len(s.Config.ACL) > 0 || (s.Config.ACLDefault != "" && s.Config.ACLDefault != ACLServe) {
+		log.Infof("Applying access control list: %s, default %s", s.Config.ACL.String(), s.Config.aclDefault())
+		s.acl = newACLMatcher(s.Config.ACL, s.Config.aclDefault())
+		go func() {
+			for {
+				time.Sleep(time.Minute)
+				s.Stats.SetACLCounters(s.acl.counters())
 			}
-			defer conn.Close()
-			if err != nil {
-				log.Fatalf("failed to start listener: %v", err)
+		}()
+	}
+	if s.Config.RateLimit > 0 {
+		log.Infof("Rate limiting clients to %v requests per second per /%d or /%d", s.Config.RateLimit, s.Config.RateLimitPrefixV4, s.Config.RateLimitPrefixV6)
+		s.limiter = newRateLimiter(s.Config.RateLimit, s.Config.RateLimitBurst, s.Config.RateLimitPrefixV4, s.Config.RateLimitPrefixV6)
+		go func() {
+			for {
+				time.Sleep(time.Minute)
+				s.Stats.SetRateLimitOffenders(s.limiter.cleanup(time.Now()))
 			}
-			s.startListener(conn)
-			s.Stats.DecListeners()
-		}(ip)
+		}()
+	}
+	if s.Config.SyncSource != "" && s.Config.SyncSource != SyncStatic {
+		log.Infof("Advertising sync state from %s at %s", s.Config.SyncSource, s.Config.SyncAddress)
+		s.sync = newSyncTracker(&s.Config, s.Stats)
+		go s.sync.run(ctx)
+	}
+	if s.Config.ControlSocket != "" {
+		log.Infof("Serving control API on %s", s.Config.ControlSocket)
+		s.drain = newDrainer(s.Config.DrainStep)
+		if err := s.startControl(ctx); err != nil {
+			log.Fatalf("failed to start control API: %v", err)
+		}
+	}
+	if s.Config.AuthKeys != "" {
+		log.Info("Enabling symmetric key authentication")
+		if err := s.startAuth(ctx); err != nil {
+			log.Fatalf("failed to start symmetric key authentication: %v", err)
+		}
+	}
+	if s.Config.NTSCookieKeys != "" {
+		log.Info("Enabling NTS")
+		if err := s.startNTS(ctx); err != nil {
+			log.Fatalf("failed to start NTS: %v", err)
+		}
+	}
+	if s.Config.CapturePath != "" {
+		log.Infof("Capturing 1 in %d exchanges to %s", s.Config.CaptureSample, s.Config.CapturePath)
+		if err := s.startCapture(ctx); err != nil {
+			log.Fatalf("failed to start capture: %v", err)
+		}
+	}
+	if s.Config.ReusePort > 0 {
+		s.startReusePortWorkers()
+	} else {
+		s.startListeners()
 	}
 
 	// Run checker periodically
@@ -100,29 +175,21 @@ func (s *Server) Start(ctx context.Context, cancelFunc context.CancelFunc) {
 		}
 	}()
 
-	// Run PHC-SYS offset periodically
-	if s.Config.TimestampType == timestamp.HWRX {
-		log.Info("Starting periodic measurement between phc and sysclock")
-		go func() {
-			for ; ; time.Sleep(time.Second) {
-				offset, err := phcOffset(s.Config.Iface)
-				if err != nil {
-					log.Errorf("[phcoffset] failed to get PHC-SYS offset: %v", err)
-					cancelFunc()
-					return
-				}
-				s.Config.phcOffset = offset
-				log.Debugf("[phcoffset] offset between PHC and SYS: %v", offset)
-			}
-		}()
-	}
+	// Report offset of served time periodically
+	go func() {
+		for ; ; time.Sleep(time.Second) {
+			s.Stats.SetServedOffset(s.servedOffset(time.Now()).Nanoseconds())
+		}
+	}()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-time.After(30 * time.Second):
-			if s.Config.ShouldAnnounce {
+			if s.drain.drained() {
+				log.Debug("Drained, not announcing VIPs")
+			} else if s.Config.ShouldAnnounce {
 				// First run will be 30 seconds delayed
 				log.Debug("Requesting VIPs announce")
 				err := s.Announce.Advertise(s.Config.IPs)
@@ -139,36 +206,189 @@ func (s *Server) Start(ctx context.Context, cancelFunc context.CancelFunc) {
 	}
 }
 
-// Stop will stop announcement, delete IPs from interfaces
+// startListeners starts shared worker pool and a listener per IP feeding it
+func (s *Server) startListeners() {
+	// Pre-create workers
+	for i := 0; i < s.Config.Workers; i++ {
+		go s.startWorker()
+	}
+
+	log.Infof("Starting %d listener(s)", len(s.Config.IPs))
+
+	for _, ip := range s.Config.IPs {
+		log.Infof("Starting listener on %s:%d", ip.String(), s.Config.Port)
+
+		go func(ip net.IP) {
+			s.Stats.IncListeners()
+			// Need to be sure IP is on interface:
+			if err := s.addIPToInterface(ip); err != nil {
+				log.Errorf("[server]: %v", err)
+			}
+
+			conn, err := listenUDP(&net.UDPAddr{IP: ip, Port: s.Config.Port}, false, s.Config.Anycast)
+			if err != nil {
+				log.Fatalf("listening error: %v", err)
+			}
+			defer conn.Close()
+			if err != nil {
+				log.Fatalf("failed to start listener: %v", err)
+			}
+			s.startListener(conn)
+			s.Stats.DecListeners()
+		}(ip)
+	}
+}
+
+// Stop will stop announcement, delete IPs from interfaces.
+// Requests routed here before the withdrawal propagates are still served for Config.WithdrawGrace
 func (s *Server) Stop() {
 	if err := s.Announce.Withdraw(); err != nil {
 		log.Errorf("[server] failed to withdraw announce: %v", err)
 	}
+	if s.Config.WithdrawGrace > 0 {
+		log.Infof("Serving for %v more while withdrawal propagates", s.Config.WithdrawGrace)
+		time.Sleep(s.Config.WithdrawGrace)
+	}
 	s.DeleteAllIPs()
 }
 
+// listenUDP opens UDP socket bound to addr.
+// With reusePort kernel spreads requests between all sockets bound to the same address (SO_REUSEPORT),
+// with freeBind the address doesn't have to be on the host yet
+func listenUDP(addr *net.UDPAddr, reusePort, freeBind bool) (*net.UDPConn, error) {
+	lc := net.ListenConfig{
+		Control: func(_, _ string, c syscall.RawConn) error {
+			var serr error
+			if err := c.Control(func(fd uintptr) {
+				if reusePort {
+					if serr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1); serr != nil {
+						return
+					}
+				}
+				if freeBind {
+					serr = setFreeBind(int(fd), addr.IP)
+				}
+			}); err != nil {
+				return err
+			}
+			return serr
+		},
+	}
+	conn, err := lc.ListenPacket(context.Background(), "udp", addr.String())
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*net.UDPConn), nil
+}
+
+// servedOffset returns offset of time served to clients from the reference clock:
+// PHC with hardware timestamps, system clock otherwise
+func (s *Server) servedOffset(now time.Time) time.Duration {
+	offset := s.Config.ExtraOffset
+	if s.smear != nil {
+		if smear, _, ok := s.smear.offset(now); ok {
+			offset += smear
+		}
+	}
+	if s.phcTime.Load() {
+		offset += s.Config.phcOffset
+	}
+	return offset
+}
+
+// startPHC is called by listeners which got hardware timestamps of the given type.
+// On the first call it starts periodic PHC-SYS offset measurement,
+// and tracking of the exchanges for the interleaved mode if transmit timestamps are enabled too
+func (s *Server) startPHC(ts timestamp.Timestamp) {
+	s.phcOnce.Do(func() {
+		s.phcTime.Store(true)
+		if ts == timestamp.HW {
+			log.Info("Enabling hardware transmit timestamps and interleaved mode")
+			s.interleave = newInterleaveTable()
+			go func() {
+				for {
+					time.Sleep(time.Minute)
+					s.interleave.cleanup(time.Now())
+				}
+			}()
+		}
+		log.Info("Starting periodic measurement between phc and sysclock")
+		go func() {
+			for ; ; time.Sleep(time.Second) {
+				offset, err := phcOffset(s.Config.Iface)
+				if err != nil {
+					log.Errorf("[phcoffset] failed to get PHC-SYS offset: %v", err)
+					s.cancel()
+					return
+				}
+				s.Config.phcOffset = offset
+				log.Debugf("[phcoffset] offset between PHC and SYS: %v", offset)
+			}
+		}()
+	})
+}
+
 func (s *Server) startListener(conn *net.UDPConn) {
 	s.Checker.IncListeners()
 	defer s.Checker.DecListeners()
 
+	connFd, ts, tx := s.prepareConn(conn)
+	s.receive(conn, connFd, ts, tx, func(t task) { s.tasks <- t })
+}
+
+// prepareConn enables timestamps and other socket options on the listener connection.
+// It returns its file descriptor, timestamps which are active and txStamper if hardware transmit timestamps are enabled
+func (s *Server) prepareConn(conn *net.UDPConn) (int, timestamp.Timestamp, *txStamper) {
 	// get connection file descriptor
 	connFd, err := timestamp.ConnFd(conn)
 	if err != nil {
 		log.Fatalf("Getting event connection FD: %s", err)
 	}
 
-	// Enable RX timestamps
-	if err := timestamp.EnableTimestamps(s.Config.TimestampType, connFd, s.Config.Iface); err != nil {
+	// Enable RX timestamps, and TX ones if requested.
+	// NIC may not timestamp all packets, serve with software timestamps then
+	ts, err := timestamp.NegotiateTimestamps(s.Config.TimestampType, connFd, s.Config.Iface)
+	if err != nil {
 		log.Fatal(err)
 	}
+	if ts != s.Config.TimestampType {
+		log.Warningf("%s timestamps are not available on %s, falling back to %s", s.Config.TimestampType, s.Config.Iface, ts)
+	}
+	s.Stats.IncTimestamping(ts.String())
+	if ts == timestamp.HWRX || ts == timestamp.HW {
+		s.startPHC(ts)
+	}
+	var tx *txStamper
+	if ts == timestamp.HW {
+		if tx, err = newTXStamper(connFd, s.interleave, func() time.Duration { return s.Config.phcOffset }, s.Stats); err != nil {
+			log.Fatalf("Failed to enable transmit timestamps: %v", err)
+		}
+		go tx.run()
+	}
+
+	if s.Config.BusyPoll.Timeout > 0 {
+		if err := timestamp.EnableBusyPoll(connFd, s.Config.BusyPoll); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	err = unix.SetNonblock(connFd, false)
 	if err != nil {
 		log.Fatalf("Failed to set socket to blocking: %s", err)
 	}
+	return connFd, ts, tx
+}
 
-	buf := make([]byte, timestamp.PayloadSizeBytes)
+// receive reads requests from the connection and hands them over to handle until connection is closed
+func (s *Server) receive(conn *net.UDPConn, connFd int, ts timestamp.Timestamp, tx *txStamper, handle func(task)) {
+	buf := make([]byte, maxRequestSizeBytes)
 	oob := make([]byte, timestamp.ControlSizeBytes)
+	local := conn.LocalAddr().(*net.UDPAddr).AddrPort()
+	local = netip.AddrPortFrom(local.Addr().Unmap(), local.Port())
+	rxIface := captureSoftware
+	if ts == timestamp.HWRX || ts == timestamp.HW {
+		rxIface = captureHardware
+	}
 
 	for {
 		request := new(ntp.Packet)
@@ -185,7 +405,7 @@ func (s *Server) startListener(conn *net.UDPConn) {
 			continue
 		}
 
-		if s.Config.TimestampType == timestamp.HWRX {
+		if ts == timestamp.HWRX || ts == timestamp.HW {
 			rxTS = rxTS.Add(s.Config.phcOffset)
 		}
 
@@ -195,7 +415,23 @@ func (s *Server) startListener(conn *net.UDPConn) {
 			continue
 		}
 		s.Stats.IncRequests()
-		s.tasks <- task{connFd: connFd, addr: clisa, received: rxTS, request: request, stats: s.Stats}
+		if bbuf > ntp.PacketSizeBytes && !s.countExtensions(buf[ntp.PacketSizeBytes:bbuf]) {
+			continue
+		}
+		t := task{connFd: connFd, addr: clisa, received: rxTS, request: request, stats: s.Stats, smear: s.smear, limiter: s.limiter, acl: s.acl, sync: s.sync, drain: s.drain, tx: tx}
+		if s.cookies != nil && nts.IsNTS(buf[:bbuf]) {
+			t.cookies = s.cookies
+		} else if s.keys != nil && bbuf > ntp.PacketSizeBytes {
+			t.keys = s.keys
+		}
+		if s.capture != nil && s.capture.sample() {
+			t.capture, t.local, t.rxIface = s.capture, local, rxIface
+		}
+		if t.cookies != nil || t.keys != nil || t.capture != nil {
+			// buffer is reused for the next read
+			t.raw = append([]byte(nil), buf[:bbuf]...)
+		}
+		handle(t)
 	}
 }
 
@@ -223,19 +459,124 @@ func (t *task) serve(response *ntp.Packet, extraoffset time.Duration) {
 		t.stats.IncInvalidFormat()
 		return
 	}
+	t.stats.IncRequestVersion(int(t.request.Settings>>3) & 7)
+
+	if t.drain != nil && t.drain.silent() {
+		log.Debugf("Drained, discarding: %v", t.request)
+		t.stats.IncDrained()
+		return
+	}
+
+	now := time.Now()
+	sent := now
+	// kiss code if we respond with Kiss-o'-Death
+	kod := ""
+	if t.acl != nil {
+		switch t.acl.match(timestamp.SockaddrToAddr(t.addr)) {
+		case ACLIgnore:
+			log.Debugf("Ignored by ACL, discarding: %v", t.request)
+			t.stats.IncACLIgnored()
+			return
+		case ACLDeny:
+			kod = KissCodeDeny
+		}
+	}
+	if t.limiter != nil && kod == "" {
+		switch t.limiter.check(t.addr, now) {
+		case rateLimitDrop:
+			log.Debugf("Rate limited, discarding: %v", t.request)
+			t.stats.IncRateLimited()
+			return
+		case rateLimitKoD:
+			t.stats.IncRateLimited()
+			kod = KissCodeRate
+		}
+	}
+	var leap uint8
+	if t.sync != nil {
+		leap = t.sync.apply(response)
+	}
+	if t.drain != nil {
+		if stratum := t.drain.stratum(response.Stratum, now); stratum != response.Stratum {
+			prev := response.Stratum
+			response.Stratum = stratum
+			defer func() { response.Stratum = prev }()
+		}
+	}
+	received := t.received
+	if t.smear != nil {
+		if offset, smear, ok := t.smear.offset(now); ok {
+			now = now.Add(offset)
+			received = received.Add(offset)
+			// advertise the smear to clients which know the convention
+			refID := response.ReferenceID
+			response.ReferenceID = smearRefID(smear)
+			defer func() { response.ReferenceID = refID }()
+		}
+	}
+
+	txTime := now.Add(extraoffset)
+	var client netip.Addr
+	var prevTX uint64
+	interleaved := false
+	if t.tx != nil && kod == "" {
+		client = timestamp.SockaddrToAddr(t.addr).Unmap()
+		prevTX, interleaved = t.tx.table.lookup(client, uint64(t.request.OrigTimeSec)<<32|uint64(t.request.OrigTimeFrac))
+		if !interleaved {
+			// hardware timestamp of this response is only known after it's sent, correct for the average delay instead
+			txTime = txTime.Add(t.tx.table.delay())
+		}
+	}
 
-	generateResponse(time.Now().Add(extraoffset), t.received.Add(extraoffset), t.request, response)
+	generateResponse(txTime, received.Add(extraoffset), t.request, response)
+	response.Settings |= leap << 6
+	if interleaved {
+		// describe the previous exchange: origin is when client received the previous response, transmit is when it left our NIC
+		response.OrigTimeSec, response.OrigTimeFrac = t.request.RxTimeSec, t.request.RxTimeFrac
+		response.TxTimeSec, response.TxTimeFrac = uint32(prevTX>>32), uint32(prevTX)
+		t.stats.IncInterleaved()
+	}
 	responseBytes, err := response.Bytes()
 	if err != nil {
 		log.Errorf("Failed to convert ntp.%v to bytes %v: %v", response, responseBytes, err)
 		return
 	}
+	if kod != "" {
+		t.stats.IncKoD(kod)
+		responseBytes = kissOfDeath(responseBytes, kod)
+	} else if t.cookies != nil {
+		if responseBytes = t.ntsResponse(responseBytes); responseBytes == nil {
+			return
+		}
+	} else if t.keys != nil {
+		responseBytes = t.authResponse(responseBytes)
+	}
 
 	log.Debugf("Writing response: %+v", response)
-	if err := unix.Sendto(t.connFd, responseBytes, unix.O_NONBLOCK, t.addr); err != nil {
+	var peer netip.AddrPort
+	if t.capture != nil {
+		peer = netip.AddrPortFrom(timestamp.SockaddrToAddr(t.addr).Unmap(), uint16(timestamp.SockaddrToPort(t.addr)))
+		t.capture.record(t.rxIface, t.received, peer, t.local, t.raw)
+	}
+	if t.tx != nil && kod == "" {
+		rx := uint64(response.RxTimeSec)<<32 | uint64(response.RxTimeFrac)
+		rec := txRecord{addr: client, rx: rx, sent: sent, offset: now.Sub(sent) + extraoffset}
+		if t.capture != nil {
+			rec.onTransmit = func(hw time.Time) { t.capture.record(captureHardware, hw, t.local, peer, responseBytes) }
+		}
+		err = t.tx.send(responseBytes, t.addr, rec)
+	} else if t.tx != nil {
+		err = t.tx.sendUntracked(responseBytes, t.addr)
+	} else {
+		err = unix.Sendto(t.connFd, responseBytes, unix.O_NONBLOCK, t.addr)
+	}
+	if err != nil {
 		log.Debugf("Failed to respond to the request: %v", err)
 		return
 	}
+	if t.capture != nil {
+		t.capture.record(captureSoftware, time.Now(), t.local, peer, responseBytes)
+	}
 	t.stats.IncResponses()
 }
 
@@ -249,7 +590,7 @@ func (s *Server) fillStaticHeaders(response *ntp.Packet) {
 	// Root dispersion, big-endian 0.000015
 	response.RootDispersion = 1
 	// Reference ID ATOM. Only for stratum 1
-	response.ReferenceID = binary.BigEndian.Uint32([]byte(fmt.Sprintf("%-4s", s.Config.RefID)))
+	response.ReferenceID = refID(s.Config.RefID)
 }
 
 // generateResponse generates response NTP packet
@@ -23,9 +23,10 @@ import (
 	"time"
 )
 
-func addIfaceIP(iface *net.Interface, addr *net.IP) error {
+// duplicate address detection can't be disabled per address on Darwin
+func addIfaceIP(iface *net.Interface, addr *net.IPNet, _ bool) error {
 	// Check if IP is assigned:
-	assigned, err := checkIP(iface, addr)
+	assigned, err := checkIP(iface, &addr.IP)
 	if err != nil {
 		return err
 	}
@@ -33,26 +34,22 @@ func addIfaceIP(iface *net.Interface, addr *net.IP) error {
 		return nil
 	}
 
-	var mask int
-	var proto string
-	if v4 := addr.To4(); v4 == nil {
+	mask, _ := addr.Mask.Size()
+	proto := "inet"
+	if v4 := addr.IP.To4(); v4 == nil {
 		proto = "inet6"
-		mask = ipv6Mask
-	} else {
-		proto = "inet"
-		mask = ipv4Mask
 	}
 
-	cmd := exec.Command("ifconfig", iface.Name, proto, "alias", fmt.Sprintf("%s/%d", addr.String(), mask))
+	cmd := exec.Command("ifconfig", iface.Name, proto, "alias", fmt.Sprintf("%s/%d", addr.IP.String(), mask))
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("can't add address: %w", err)
 	}
 	return nil
 }
 
-func deleteIfaceIP(iface *net.Interface, addr *net.IP) error {
+func deleteIfaceIP(iface *net.Interface, addr *net.IPNet) error {
 	// Check if IP is assigned:
-	assigned, err := checkIP(iface, addr)
+	assigned, err := checkIP(iface, &addr.IP)
 	if err != nil {
 		return err
 	}
@@ -61,13 +58,13 @@ func deleteIfaceIP(iface *net.Interface, addr *net.IP) error {
 	}
 
 	var proto string
-	if v4 := addr.To4(); v4 == nil {
+	if v4 := addr.IP.To4(); v4 == nil {
 		proto = "inet6"
 	} else {
 		proto = "inet"
 	}
 
-	cmd := exec.Command("ifconfig", iface.Name, proto, "-alias", addr.String())
+	cmd := exec.Command("ifconfig", iface.Name, proto, "-alias", addr.IP.String())
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("can't remove address: %w", err)
 	}
@@ -80,3 +77,15 @@ func deleteIfaceIP(iface *net.Interface, addr *net.IP) error {
 func phcOffset(iface string) (time.Duration, error) {
 	return 0, nil
 }
+
+// pinToCPU pins calling thread to n-th CPU
+// Thread affinity is not supported on Darwin
+func pinToCPU(_ int) (int, error) {
+	return 0, fmt.Errorf("cpu pinning is not supported")
+}
+
+// setFreeBind allows binding socket to the address which is not on the host
+// Binding to such addresses is not supported on Darwin
+func setFreeBind(_ int, _ net.IP) error {
+	return fmt.Errorf("free bind is not supported")
+}
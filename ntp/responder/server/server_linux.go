@@ -22,21 +22,14 @@ import (
 	"time"
 
 	"github.com/facebook/time/phc"
+	"github.com/jsimonetti/rtnetlink"
 	"github.com/jsimonetti/rtnetlink/rtnl"
+	"golang.org/x/sys/unix"
 )
 
-// bitsInBytes is a number of bits in byte
-const bitsInBytes = 8
-
-// ipv4Len is the IPv4 len in bits
-const ipv4Len = net.IPv4len * bitsInBytes
-
-// ipv6Len is the IPv6 len in bits
-const ipv6Len = net.IPv6len * bitsInBytes
-
-func addIfaceIP(iface *net.Interface, addr *net.IP) error {
+func addIfaceIP(iface *net.Interface, addr *net.IPNet, nodad bool) error {
 	// Check if IP is assigned:
-	assigned, err := checkIP(iface, addr)
+	assigned, err := checkIP(iface, &addr.IP)
 	if err != nil {
 		return err
 	}
@@ -50,23 +43,32 @@ func addIfaceIP(iface *net.Interface, addr *net.IP) error {
 	}
 	defer conn.Close()
 
-	var mask net.IPMask
-	if v4 := addr.To4(); v4 == nil {
-		mask = net.CIDRMask(ipv6Mask, ipv6Len)
+	if nodad && addr.IP.To4() == nil {
+		// rtnl doesn't let us set address flags
+		prefixLen, _ := addr.Mask.Size()
+		err = conn.Conn.Address.New(&rtnetlink.AddressMessage{
+			Family:       unix.AF_INET6,
+			PrefixLength: uint8(prefixLen),
+			Scope:        unix.RT_SCOPE_UNIVERSE,
+			Index:        uint32(iface.Index),
+			Attributes: &rtnetlink.AddressAttributes{
+				Address: addr.IP,
+				Local:   addr.IP,
+				Flags:   unix.IFA_F_NODAD,
+			},
+		})
 	} else {
-		mask = net.CIDRMask(ipv4Mask, ipv4Len)
+		err = conn.AddrAdd(iface, addr)
 	}
-
-	err = conn.AddrAdd(iface, &net.IPNet{IP: *addr, Mask: mask})
 	if err != nil {
 		return fmt.Errorf("can't add address: %w", err)
 	}
 	return nil
 }
 
-func deleteIfaceIP(iface *net.Interface, addr *net.IP) error {
+func deleteIfaceIP(iface *net.Interface, addr *net.IPNet) error {
 	// Check if IP is assigned:
-	assigned, err := checkIP(iface, addr)
+	assigned, err := checkIP(iface, &addr.IP)
 	if err != nil {
 		return err
 	}
@@ -80,14 +82,7 @@ func deleteIfaceIP(iface *net.Interface, addr *net.IP) error {
 	}
 	defer conn.Close()
 
-	var mask net.IPMask
-	if v4 := addr.To4(); v4 == nil {
-		mask = net.CIDRMask(ipv6Mask, ipv6Len)
-	} else {
-		mask = net.CIDRMask(ipv4Mask, ipv4Len)
-	}
-
-	err = conn.AddrDel(iface, &net.IPNet{IP: *addr, Mask: mask})
+	err = conn.AddrDel(iface, addr)
 	if err != nil {
 		return fmt.Errorf("can't remove address: %w", err)
 	}
@@ -95,6 +90,14 @@ func deleteIfaceIP(iface *net.Interface, addr *net.IP) error {
 	return nil
 }
 
+// setFreeBind allows binding socket to the address which is not on the host (yet)
+func setFreeBind(fd int, ip net.IP) error {
+	if ip.To4() != nil {
+		return unix.SetsockoptInt(fd, unix.SOL_IP, unix.IP_FREEBIND, 1)
+	}
+	return unix.SetsockoptInt(fd, unix.SOL_IPV6, unix.IPV6_FREEBIND, 1)
+}
+
 // PHCOffset periodically checks for PHC-SYS offset and updates it in the config
 func phcOffset(iface string) (time.Duration, error) {
 	device, err := phc.IfaceToPHCDevice(iface)
@@ -108,3 +111,24 @@ func phcOffset(iface string) (time.Duration, error) {
 	}
 	return res.Offset, nil
 }
+
+// pinToCPU pins calling thread to n-th CPU (modulo number of CPUs) process is allowed to run on, and returns the CPU
+func pinToCPU(n int) (int, error) {
+	var allowed unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &allowed); err != nil {
+		return 0, err
+	}
+	cpus := []int{}
+	for cpu := 0; cpu < len(allowed)*64; cpu++ {
+		if allowed.IsSet(cpu) {
+			cpus = append(cpus, cpu)
+		}
+	}
+	if len(cpus) == 0 {
+		return 0, fmt.Errorf("no CPUs in affinity mask")
+	}
+	cpu := cpus[n%len(cpus)]
+	var set unix.CPUSet
+	set.Set(cpu)
+	return cpu, unix.SchedSetaffinity(0, &set)
+}
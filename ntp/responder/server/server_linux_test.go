@@ -18,9 +18,13 @@ package server
 
 import (
 	"net"
+	"runtime"
 	"testing"
 
+	"github.com/facebook/time/ntp/responder/stats"
+	"github.com/facebook/time/timestamp"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
 )
 
 func TestCheckIP(t *testing.T) {
@@ -44,3 +48,44 @@ func TestCheckIPFalse(t *testing.T) {
 	require.NoError(t, err)
 	require.False(t, assigned)
 }
+
+func TestPinToCPU(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	var before unix.CPUSet
+	require.NoError(t, unix.SchedGetaffinity(0, &before))
+	defer func() { _ = unix.SchedSetaffinity(0, &before) }()
+
+	cpu, err := pinToCPU(before.Count())
+	require.NoError(t, err)
+	require.True(t, before.IsSet(cpu))
+	var after unix.CPUSet
+	require.NoError(t, unix.SchedGetaffinity(0, &after))
+	require.Equal(t, 1, after.Count())
+	require.True(t, after.IsSet(cpu))
+}
+
+func TestListenUDPFreeBind(t *testing.T) {
+	// TEST-NET-1 address which is not on the host
+	addr := &net.UDPAddr{IP: net.ParseIP("192.0.2.1")}
+	_, err := listenUDP(addr, false, false)
+	require.Error(t, err)
+
+	conn, err := listenUDP(addr, false, true)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Equal(t, "192.0.2.1", conn.LocalAddr().(*net.UDPAddr).IP.String())
+}
+
+func TestPrepareConnFallback(t *testing.T) {
+	conn := tryListenUDP(t)
+	defer conn.Close()
+	// loopback has no hardware timestamps
+	s := &Server{Config: Config{TimestampType: timestamp.HW, Iface: "lo"}, Stats: &stats.JSONStats{}}
+	_, ts, tx := s.prepareConn(conn)
+	require.Equal(t, timestamp.SW, ts)
+	require.Nil(t, tx)
+	// time is served from the system clock, not PHC
+	require.False(t, s.phcTime.Load())
+	require.Nil(t, s.interleave)
+}
@@ -17,12 +17,14 @@ limitations under the License.
 package server
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"net"
 	"testing"
 	"time"
 
+	"github.com/facebook/time/ntp/nts"
 	ntp "github.com/facebook/time/ntp/protocol"
 	"github.com/facebook/time/ntp/responder/checker"
 	"github.com/facebook/time/ntp/responder/stats"
@@ -124,6 +126,7 @@ func TestListener(t *testing.T) {
 			ExpectedListeners: 1,
 			ExpectedWorkers:   0,
 		},
+		Stats:  &stats.JSONStats{},
 		Config: Config{Workers: 42, TimestampType: timestamp.SW},
 	}
 	conn := tryListenUDP(t)
@@ -216,6 +219,60 @@ func TestServer(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestServerNTS(t *testing.T) {
+	cookies, err := nts.NewCookieJar(make([]byte, nts.CookieKeySize))
+	require.NoError(t, err)
+	s := &Server{
+		Checker: &checker.SimpleChecker{ExpectedListeners: 1, ExpectedWorkers: 1},
+		Stats:   &stats.JSONStats{},
+		tasks:   make(chan task, 1),
+		Config:  Config{Workers: 1, TimestampType: timestamp.SW},
+		cookies: cookies,
+	}
+	go s.startWorker()
+	conn := tryListenUDP(t)
+	defer conn.Close()
+	go s.startListener(conn)
+	time.Sleep(100 * time.Millisecond)
+
+	sendConn, err := net.DialTimeout("udp", conn.LocalAddr().String(), time.Second)
+	require.NoError(t, err)
+	defer sendConn.Close()
+
+	c := &nts.Cookie{AEAD: nts.AEADAESSIVCMAC256, C2S: bytes.Repeat([]byte{1}, 32), S2C: bytes.Repeat([]byte{2}, 32)}
+	cookie, err := cookies.Encode(c)
+	require.NoError(t, err)
+	sec, frac := ntp.Time(time.Now())
+	header, err := (&ntp.Packet{Settings: 0x23, TxTimeSec: sec, TxTimeFrac: frac}).Bytes()
+	require.NoError(t, err)
+	request, uniqueID, err := nts.AppendRequest(header, cookie, c.C2S, 1)
+	require.NoError(t, err)
+
+	_, err = sendConn.Write(request)
+	require.NoError(t, err)
+	buf := make([]byte, 1024)
+	require.NoError(t, sendConn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := sendConn.Read(buf)
+	require.NoError(t, err)
+	response, err := ntp.BytesToPacket(buf[:n])
+	require.NoError(t, err)
+	require.Equal(t, sec, response.OrigTimeSec)
+	require.Equal(t, frac, response.OrigTimeFrac)
+	newCookies, err := nts.ParseResponse(buf[:n], uniqueID, c.S2C)
+	require.NoError(t, err)
+	require.Len(t, newCookies, 2)
+
+	// request authenticated with wrong key gets NAK
+	request, uniqueID, err = nts.AppendRequest(header, cookie, c.S2C, 0)
+	require.NoError(t, err)
+	_, err = sendConn.Write(request)
+	require.NoError(t, err)
+	n, err = sendConn.Read(buf)
+	require.NoError(t, err)
+	_, err = nts.ParseResponse(buf[:n], uniqueID, c.S2C)
+	require.ErrorIs(t, err, nts.ErrNAK)
+}
+
 func Benchmark_generateResponse(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		request := &ntp.Packet{}
@@ -231,3 +288,16 @@ func Benchmark_fillStaticHeaders(b *testing.B) {
 		s.fillStaticHeaders(response)
 	}
 }
+
+func TestServedOffset(t *testing.T) {
+	s := &Server{Config: Config{ExtraOffset: time.Millisecond, TimestampType: timestamp.SWRX, phcOffset: time.Microsecond}}
+	require.Equal(t, time.Millisecond, s.servedOffset(time.Now()))
+
+	// configured hardware timestamps fell back to software ones
+	s.Config.TimestampType = timestamp.HWRX
+	require.Equal(t, time.Millisecond, s.servedOffset(time.Now()))
+
+	// served time is compared to PHC once listeners get hardware timestamps
+	s.phcTime.Store(true)
+	require.Equal(t, time.Millisecond+time.Microsecond, s.servedOffset(time.Now()))
+}
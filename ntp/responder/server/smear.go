@@ -0,0 +1,202 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/facebook/time/leapsectz"
+	log "github.com/sirupsen/logrus"
+)
+
+// SmearShape is how leap second is spread over the smearing window
+type SmearShape string
+
+// Supported smear shapes
+const (
+	// SmearLinear changes clock rate by the same amount over the whole window, like public NTP services do
+	SmearLinear SmearShape = "linear"
+	// SmearCosine starts and ends smearing gradually, so frequency changes smoothly as well
+	SmearCosine SmearShape = "cosine"
+)
+
+// MarshalText smear shape to byte slice
+func (s SmearShape) MarshalText() ([]byte, error) {
+	return []byte(s), nil
+}
+
+// UnmarshalText smear shape from byte slice
+func (s *SmearShape) UnmarshalText(value []byte) error {
+	switch v := SmearShape(value); v {
+	case SmearLinear, SmearCosine:
+		*s = v
+		return nil
+	}
+	return fmt.Errorf("unknown smear shape %q", value)
+}
+
+// smearRefIDPrefix is the first octet of reference id while smearing, followed by the current smear
+const smearRefIDPrefix = 0xfe
+
+// leapFileReloadInterval is how often leap seconds are re-read from tzdata
+const leapFileReloadInterval = time.Hour
+
+// smearWindow describes smearing of a single leap second.
+// Before the leap second system clock and continuous time are the same, continuous time just doesn't step back
+type smearWindow struct {
+	start time.Time     // continuous time smearing starts
+	end   time.Time     // continuous time smearing ends
+	step  time.Duration // 1s for inserted leap second, -1s for deleted
+	// continuous time is refWall plus monotonic time elapsed since refMono.
+	// Refreshed before smearing starts and frozen during the window, so leap second handling by the kernel doesn't affect it
+	refWall time.Time
+	refMono time.Time
+}
+
+// newSmearWindow returns window of duration centered around the next leap second which is not smeared yet, nil if there is none
+func newSmearWindow(leaps []leapsectz.LeapSecond, now time.Time, duration time.Duration) *smearWindow {
+	var w *smearWindow
+	var prev int32
+	for _, l := range leaps {
+		step := time.Duration(l.Nleap-prev) * time.Second
+		prev = l.Nleap
+		leap := l.Time()
+		if !leap.Add(duration / 2).After(now) {
+			continue
+		}
+		if w == nil || leap.Before(w.start.Add(duration/2)) {
+			w = &smearWindow{start: leap.Add(-duration / 2), end: leap.Add(duration / 2), step: step}
+		}
+	}
+	return w
+}
+
+// leapSmear smears leap seconds in served time, so clients which can't handle leap seconds never see one
+type leapSmear struct {
+	shape    SmearShape
+	duration time.Duration
+	window   atomic.Pointer[smearWindow]
+}
+
+// newLeapSmear returns leapSmear which finds leap seconds in the system tzdata
+func newLeapSmear(shape SmearShape, duration time.Duration) *leapSmear {
+	return &leapSmear{shape: shape, duration: duration}
+}
+
+// load finds the next leap second to smear
+func (l *leapSmear) load(leaps []leapsectz.LeapSecond, now time.Time) {
+	if w := l.window.Load(); w != nil && l.inWindow(w, now) {
+		// never replace the window we are in
+		return
+	}
+	w := newSmearWindow(leaps, now, l.duration)
+	if w != nil {
+		l.refresh(w, now)
+		log.Infof("[smear] smearing %v leap second from %v to %v", w.step, w.start, w.end)
+	}
+	l.window.Store(w)
+}
+
+// refresh moves continuous time reference to now, unless we are already smearing
+func (l *leapSmear) refresh(w *smearWindow, now time.Time) {
+	if !w.refMono.IsZero() && !l.continuous(w, now).Before(w.start) {
+		return
+	}
+	w.refWall = now.Round(0)
+	w.refMono = now
+	if !now.Before(w.start.Add(l.duration / 2)) {
+		// started after the leap second, system clock has already stepped
+		w.refWall = w.refWall.Add(w.step)
+	}
+}
+
+// inWindow returns whether we are smearing now
+func (l *leapSmear) inWindow(w *smearWindow, now time.Time) bool {
+	if w.refMono.IsZero() {
+		return false
+	}
+	c := l.continuous(w, now)
+	return !c.Before(w.start) && c.Before(w.end)
+}
+
+// continuous returns system time which didn't step on leap second
+func (l *leapSmear) continuous(w *smearWindow, now time.Time) time.Time {
+	return w.refWall.Add(now.Sub(w.refMono))
+}
+
+// run keeps leap seconds and continuous time reference up to date until ctx is done
+func (l *leapSmear) run(ctx context.Context) {
+	var lastLoad time.Time
+	for {
+		now := time.Now()
+		if now.Sub(lastLoad) >= leapFileReloadInterval {
+			leaps, err := leapsectz.Parse("")
+			if err != nil {
+				log.Errorf("[smear] failed to read leap seconds: %v", err)
+			} else {
+				l.load(leaps, now)
+				lastLoad = now
+			}
+		}
+		if w := l.window.Load(); w != nil {
+			// copy, as workers read the window concurrently
+			updated := *w
+			l.refresh(&updated, now)
+			l.window.Store(&updated)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// offset returns how much to add to system time now to get smeared time, and the current smear
+func (l *leapSmear) offset(now time.Time) (offset time.Duration, smear time.Duration, smearing bool) {
+	w := l.window.Load()
+	if w == nil || !l.inWindow(w, now) {
+		return 0, 0, false
+	}
+	c := l.continuous(w, now)
+	x := float64(c.Sub(w.start)) / float64(w.end.Sub(w.start))
+	if l.shape == SmearCosine {
+		x = (1 - math.Cos(math.Pi*x)) / 2
+	}
+	smear = time.Duration(x * float64(w.step))
+	return c.Sub(now) - smear, smear, true
+}
+
+// smearRefID returns reference id advertising the current smear: 254 followed by
+// 2 bits of seconds and 22 bits of fraction, same as ntpd leap smearing does
+func smearRefID(smear time.Duration) uint32 {
+	if smear < 0 {
+		smear = -smear
+	}
+	sec := uint32(smear / time.Second)
+	frac := uint32((uint64(smear%time.Second) << 32) / uint64(time.Second))
+	// round to the last bit we keep
+	if frac+0x200 < frac {
+		sec++
+	}
+	frac += 0x200
+	return smearRefIDPrefix<<24 | (sec&3)<<22 | frac>>10
+}
@@ -0,0 +1,176 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/facebook/time/leapsectz"
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/facebook/time/ntp/responder/stats"
+	"github.com/facebook/time/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+// leap seconds of 2015-06-30 and 2016-12-31
+var testLeaps = []leapsectz.LeapSecond{
+	{Tleap: 1435708825, Nleap: 26},
+	{Tleap: 1483228826, Nleap: 27},
+}
+
+var testLeap = time.Unix(1483228800, 0)
+
+func TestSmearShapeUnmarshalText(t *testing.T) {
+	var s SmearShape
+	require.NoError(t, s.UnmarshalText([]byte("cosine")))
+	require.Equal(t, SmearCosine, s)
+	require.Error(t, s.UnmarshalText([]byte("sine")))
+	b, err := SmearLinear.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, "linear", string(b))
+}
+
+func TestNewSmearWindow(t *testing.T) {
+	w := newSmearWindow(testLeaps, time.Date(2016, 6, 1, 0, 0, 0, 0, time.UTC), 24*time.Hour)
+	require.Equal(t, &smearWindow{start: testLeap.Add(-12 * time.Hour), end: testLeap.Add(12 * time.Hour), step: time.Second}, w)
+
+	// still smearing
+	w = newSmearWindow(testLeaps, testLeap.Add(6*time.Hour), 24*time.Hour)
+	require.Equal(t, testLeap.Add(12*time.Hour), w.end)
+
+	w = newSmearWindow(testLeaps, testLeap.Add(12*time.Hour), 24*time.Hour)
+	require.Nil(t, w)
+
+	// deleted leap second
+	w = newSmearWindow([]leapsectz.LeapSecond{{Tleap: 1435708825, Nleap: 26}, {Tleap: 1483228824, Nleap: 25}}, testLeap.Add(-time.Hour), 2*time.Hour)
+	require.Equal(t, time.Unix(1483228800, 0), w.start.Add(time.Hour))
+	require.Equal(t, -time.Second, w.step)
+}
+
+// smearAt returns smear with continuous time being c at mono
+func smearAt(shape SmearShape, step time.Duration, c time.Time, mono time.Time) *leapSmear {
+	l := newLeapSmear(shape, 24*time.Hour)
+	l.window.Store(&smearWindow{start: testLeap.Add(-12 * time.Hour), end: testLeap.Add(12 * time.Hour), step: step, refWall: c, refMono: mono})
+	return l
+}
+
+func TestLeapSmearOffset(t *testing.T) {
+	mono := time.Now()
+	start := testLeap.Add(-12 * time.Hour)
+	for _, tc := range []struct {
+		name     string
+		shape    SmearShape
+		step     time.Duration
+		elapsed  time.Duration
+		smear    time.Duration
+		smearing bool
+	}{
+		{name: "before", shape: SmearLinear, step: time.Second, elapsed: -time.Second},
+		{name: "start", shape: SmearLinear, step: time.Second, elapsed: 0, smearing: true},
+		{name: "leap", shape: SmearLinear, step: time.Second, elapsed: 12 * time.Hour, smear: 500 * time.Millisecond, smearing: true},
+		{name: "almost done", shape: SmearLinear, step: time.Second, elapsed: 24*time.Hour - 864*time.Second, smear: 990 * time.Millisecond, smearing: true},
+		{name: "after", shape: SmearLinear, step: time.Second, elapsed: 24 * time.Hour},
+		{name: "cosine", shape: SmearCosine, step: time.Second, elapsed: 6 * time.Hour, smear: 146446609, smearing: true},
+		{name: "cosine leap", shape: SmearCosine, step: time.Second, elapsed: 12 * time.Hour, smear: 500 * time.Millisecond, smearing: true},
+		{name: "deleted", shape: SmearLinear, step: -time.Second, elapsed: 12 * time.Hour, smear: -500 * time.Millisecond, smearing: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// continuous time is ahead of system time by 1s after the leap second
+			l := smearAt(tc.shape, tc.step, start.Add(-time.Hour), mono)
+			now := mono.Add(time.Hour + tc.elapsed)
+			offset, smear, smearing := l.offset(now)
+			require.Equal(t, tc.smearing, smearing)
+			require.InDelta(t, tc.smear, smear, float64(time.Microsecond))
+			if smearing {
+				served := now.Add(offset)
+				require.InDelta(t, start.Add(tc.elapsed-tc.smear).UnixNano(), served.UnixNano(), float64(time.Microsecond))
+			} else {
+				require.Zero(t, offset)
+			}
+		})
+	}
+}
+
+func TestLeapSmearLoad(t *testing.T) {
+	l := newLeapSmear(SmearLinear, 24*time.Hour)
+	offset, _, smearing := l.offset(time.Now())
+	require.False(t, smearing)
+	require.Zero(t, offset)
+
+	// started an hour after the leap second, system clock has already stepped back
+	now := time.Now()
+	leap := now.Add(-time.Hour).Truncate(time.Second)
+	leaps := []leapsectz.LeapSecond{{Tleap: 1435708825, Nleap: 26}, {Tleap: uint64(leap.Unix()) + 26, Nleap: 27}}
+	l.load(leaps, now)
+	offset, smear, smearing := l.offset(now)
+	require.True(t, smearing)
+	expected := time.Duration(float64(13*time.Hour+now.Sub(leap.Add(time.Hour))+time.Second) / float64(24*time.Hour) * float64(time.Second))
+	require.InDelta(t, expected, smear, float64(time.Microsecond))
+	require.InDelta(t, time.Second-smear, offset, float64(time.Microsecond))
+
+	// window we are in is never replaced
+	l.load(nil, now)
+	require.NotNil(t, l.window.Load())
+	// but finished one is
+	l.load(nil, now.Add(12*time.Hour))
+	require.Nil(t, l.window.Load())
+}
+
+func TestLeapSmearRefresh(t *testing.T) {
+	l := newLeapSmear(SmearLinear, 24*time.Hour)
+	mono := time.Now()
+	w := &smearWindow{start: mono.Add(time.Hour), end: mono.Add(25 * time.Hour), step: time.Second}
+	l.refresh(w, mono)
+	require.Equal(t, mono.Round(0), w.refWall)
+
+	// reference is frozen once smearing starts
+	l.refresh(w, mono.Add(2*time.Hour))
+	require.Equal(t, mono.Round(0), w.refWall)
+}
+
+func TestSmearRefID(t *testing.T) {
+	require.Equal(t, uint32(0xfe000000), smearRefID(0))
+	require.Equal(t, uint32(0xfe200000), smearRefID(500*time.Millisecond))
+	require.Equal(t, uint32(0xfe100000), smearRefID(-250*time.Millisecond))
+	require.Equal(t, uint32(0xfe400000), smearRefID(time.Second))
+	require.Equal(t, uint32(0xfe400000), smearRefID(time.Second-time.Nanosecond))
+}
+
+func TestServeSmear(t *testing.T) {
+	conn := tryListenUDP(t)
+	defer conn.Close()
+	connFd, err := timestamp.ConnFd(conn)
+	require.NoError(t, err)
+
+	mono := time.Now()
+	// in the middle of smearing
+	l := smearAt(SmearLinear, time.Second, testLeap.Add(-time.Hour), mono)
+	s := &Server{Config: Config{RefID: "OLEG"}}
+	response := &ntp.Packet{}
+	s.fillStaticHeaders(response)
+	refID := response.ReferenceID
+	tk := task{connFd: connFd, addr: timestamp.IPToSockaddr(net.ParseIP("127.0.0.1"), 0), received: mono, request: ntpRequest, stats: &stats.JSONStats{}, smear: l}
+	tk.serve(response, 0)
+
+	served := ntp.Unix(response.TxTimeSec, response.TxTimeFrac)
+	require.InDelta(t, testLeap.Add(-time.Hour-time.Second*11/24).UnixNano(), served.UnixNano(), float64(time.Second))
+	received := ntp.Unix(response.RxTimeSec, response.RxTimeFrac)
+	require.InDelta(t, served.UnixNano(), received.UnixNano(), float64(time.Second))
+	require.Equal(t, refID, response.ReferenceID, "static reference id must be restored")
+}
@@ -0,0 +1,213 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/facebook/time/fbclock/rpc"
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/facebook/time/ptp/sptp/stats"
+	log "github.com/sirupsen/logrus"
+)
+
+// SyncSource is where responder learns how well system clock is synchronized
+type SyncSource string
+
+// Supported sync sources
+const (
+	// SyncStatic always advertises configured stratum and reference id
+	SyncStatic SyncSource = "static"
+	// SyncFBClock takes window of uncertainty from fbclock daemon RPC socket
+	SyncFBClock SyncSource = "fbclock"
+	// SyncSPTP takes offset, path delay and GM clock accuracy from sptp monitoring API
+	SyncSPTP SyncSource = "sptp"
+)
+
+// MarshalText sync source to byte slice
+func (s SyncSource) MarshalText() ([]byte, error) {
+	return []byte(s), nil
+}
+
+// UnmarshalText sync source from byte slice
+func (s *SyncSource) UnmarshalText(value []byte) error {
+	switch v := SyncSource(value); v {
+	case SyncStatic, SyncFBClock, SyncSPTP:
+		*s = v
+		return nil
+	}
+	return fmt.Errorf("unknown sync source %q", value)
+}
+
+const (
+	// syncRefreshInterval is how often sync state is re-read from the source
+	syncRefreshInterval = time.Second
+	// leapAlarm is leap indicator value of unsynchronized server
+	leapAlarm = 3
+	// stratumUnsynchronized is stratum of unsynchronized server
+	stratumUnsynchronized = 16
+)
+
+// refIDInit is reference id advertised while unsynchronized, same kiss code ntpd uses
+var refIDInit = binary.BigEndian.Uint32([]byte("INIT"))
+
+// syncQuality is what sync source reports about system clock
+type syncQuality struct {
+	rootDelay      time.Duration // round trip delay to the reference clock
+	rootDispersion time.Duration // maximum error relative to the reference clock
+}
+
+// syncState is advertised to clients in response headers
+type syncState struct {
+	leap           uint8
+	stratum        uint8
+	referenceID    uint32
+	rootDelay      uint32
+	rootDispersion uint32
+}
+
+// syncTracker keeps sync state up to date for workers
+type syncTracker struct {
+	read        func() (syncQuality, error)
+	stratum     uint8
+	referenceID uint32
+	maxError    time.Duration
+	stats       Stats
+	state       atomic.Pointer[syncState]
+}
+
+// newSyncTracker returns syncTracker reading sync state from the configured source.
+// Until the first read responder is advertised as unsynchronized
+func newSyncTracker(c *Config, st Stats) *syncTracker {
+	s := &syncTracker{
+		stratum:     uint8(c.Stratum),
+		referenceID: refID(c.RefID),
+		maxError:    c.SyncMaxError,
+		stats:       st,
+	}
+	switch c.SyncSource {
+	case SyncFBClock:
+		s.read = fbclockReader(c.SyncAddress)
+	case SyncSPTP:
+		s.read = sptpReader(c.SyncAddress)
+	}
+	s.state.Store(&syncState{leap: leapAlarm, stratum: stratumUnsynchronized, referenceID: refIDInit})
+	return s
+}
+
+// update reads sync source and stores the new state
+func (s *syncTracker) update() {
+	st := &syncState{leap: leapAlarm, stratum: stratumUnsynchronized, referenceID: refIDInit}
+	q, err := s.read()
+	switch {
+	case err != nil:
+		log.Warningf("[sync] failed to read sync state, advertising unsynchronized: %v", err)
+	case s.maxError > 0 && q.rootDelay/2+q.rootDispersion > s.maxError:
+		log.Warningf("[sync] error bound %v is above %v, advertising unsynchronized", q.rootDelay/2+q.rootDispersion, s.maxError)
+	default:
+		st.leap = 0
+		st.stratum = s.stratum
+		st.referenceID = s.referenceID
+	}
+	if err == nil {
+		st.rootDelay = shortTime(q.rootDelay)
+		st.rootDispersion = shortTime(q.rootDispersion)
+	}
+	log.Debugf("[sync] sync state: %+v", st)
+	s.state.Store(st)
+	s.stats.SetStratum(int64(st.stratum))
+	s.stats.SetRootDispersion((q.rootDelay/2 + q.rootDispersion).Nanoseconds())
+}
+
+// run keeps sync state up to date until ctx is done
+func (s *syncTracker) run(ctx context.Context) {
+	for {
+		s.update()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(syncRefreshInterval):
+		}
+	}
+}
+
+// apply sets stratum, reference id and root delay and dispersion of the response, and returns leap indicator
+func (s *syncTracker) apply(response *ntp.Packet) uint8 {
+	st := s.state.Load()
+	response.Stratum = st.stratum
+	response.ReferenceID = st.referenceID
+	response.RootDelay = st.rootDelay
+	response.RootDispersion = st.rootDispersion
+	return st.leap
+}
+
+// shortTime converts duration to NTP short format: 16 bits of seconds and 16 bits of fraction, rounded up
+func shortTime(d time.Duration) uint32 {
+	if d <= 0 {
+		return 0
+	}
+	if d >= math.MaxUint16*time.Second {
+		return math.MaxUint32
+	}
+	return uint32((uint64(d)<<16 + uint64(time.Second) - 1) / uint64(time.Second))
+}
+
+// refID converts configured reference id to its wire format
+func refID(id string) uint32 {
+	return binary.BigEndian.Uint32([]byte(fmt.Sprintf("%-4s", id)))
+}
+
+// fbclockReader returns reader taking window of uncertainty from fbclock daemon at socket path
+func fbclockReader(path string) func() (syncQuality, error) {
+	return func() (syncQuality, error) {
+		c, err := rpc.Dial(path)
+		if err != nil {
+			return syncQuality{}, err
+		}
+		defer c.Close()
+		wou, err := c.GetError()
+		if err != nil {
+			return syncQuality{}, err
+		}
+		return syncQuality{rootDispersion: wou}, nil
+	}
+}
+
+// sptpReader returns reader taking state of the selected GM from sptp monitoring API at address
+func sptpReader(address string) func() (syncQuality, error) {
+	url := fmt.Sprintf("http://%s/", address)
+	return func() (syncQuality, error) {
+		sm, err := stats.FetchStats(url)
+		if err != nil {
+			return syncQuality{}, err
+		}
+		for _, s := range sm {
+			if s.Selected && s.Error == "" {
+				return syncQuality{
+					rootDelay:      2 * time.Duration(s.MeanPathDelay),
+					rootDispersion: s.ClockQuality.ClockAccuracy.Duration() + time.Duration(math.Abs(s.Offset)),
+				}, nil
+			}
+		}
+		return syncQuality{}, fmt.Errorf("no selected grandmaster")
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/facebook/time/fbclock/rpc"
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/facebook/time/ntp/responder/stats"
+	"github.com/facebook/time/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncSourceUnmarshalText(t *testing.T) {
+	var s SyncSource
+	require.NoError(t, s.UnmarshalText([]byte("fbclock")))
+	require.Equal(t, SyncFBClock, s)
+	require.Error(t, s.UnmarshalText([]byte("chrony")))
+	b, err := SyncSPTP.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, "sptp", string(b))
+}
+
+func TestShortTime(t *testing.T) {
+	require.Equal(t, uint32(0), shortTime(0))
+	require.Equal(t, uint32(0), shortTime(-time.Second))
+	require.Equal(t, uint32(1), shortTime(time.Nanosecond))
+	require.Equal(t, uint32(1), shortTime(15*time.Microsecond))
+	require.Equal(t, uint32(0x8000), shortTime(500*time.Millisecond))
+	require.Equal(t, uint32(0x18000), shortTime(1500*time.Millisecond))
+	require.Equal(t, uint32(0xffffffff), shortTime(24*time.Hour))
+}
+
+func TestSyncTrackerUpdate(t *testing.T) {
+	s := newSyncTracker(&Config{Stratum: 1, RefID: "PTP", SyncMaxError: time.Millisecond}, &stats.JSONStats{})
+	response := &ntp.Packet{}
+
+	// unsynchronized until the first read
+	require.Equal(t, uint8(leapAlarm), s.apply(response))
+	require.Equal(t, uint8(16), response.Stratum)
+	require.Equal(t, refIDInit, response.ReferenceID)
+
+	q := syncQuality{rootDelay: 2 * time.Microsecond, rootDispersion: 500 * time.Microsecond}
+	var err error
+	s.read = func() (syncQuality, error) { return q, err }
+	s.update()
+	require.Equal(t, uint8(0), s.apply(response))
+	require.Equal(t, uint8(1), response.Stratum)
+	require.Equal(t, refID("PTP"), response.ReferenceID)
+	require.Equal(t, shortTime(2*time.Microsecond), response.RootDelay)
+	require.Equal(t, shortTime(500*time.Microsecond), response.RootDispersion)
+
+	// holdover, error bound keeps growing above the limit
+	q.rootDispersion = 2 * time.Millisecond
+	s.update()
+	require.Equal(t, uint8(leapAlarm), s.apply(response))
+	require.Equal(t, uint8(16), response.Stratum)
+	require.Equal(t, refIDInit, response.ReferenceID)
+	require.Equal(t, shortTime(2*time.Millisecond), response.RootDispersion)
+
+	// source is gone
+	err = fmt.Errorf("connection refused")
+	s.update()
+	require.Equal(t, uint8(leapAlarm), s.apply(response))
+	require.Equal(t, uint8(16), response.Stratum)
+	require.Equal(t, uint32(0), response.RootDispersion)
+}
+
+type fakeTrueTime struct {
+	wou time.Duration
+}
+
+func (f *fakeTrueTime) GetTime() (time.Time, time.Time, error) {
+	now := time.Now()
+	return now.Add(-f.wou), now.Add(f.wou), nil
+}
+
+func (f *fakeTrueTime) GetTimeUTC() (time.Time, time.Time, error) {
+	return f.GetTime()
+}
+
+func TestFBClockReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fbclock.sock")
+	read := fbclockReader(path)
+	_, err := read()
+	require.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = rpc.Serve(ctx, path, rpc.NewService(&fakeTrueTime{wou: 42 * time.Microsecond}))
+	}()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	q, err := read()
+	require.NoError(t, err)
+	require.Equal(t, syncQuality{rootDispersion: 42 * time.Microsecond}, q)
+}
+
+func TestSPTPReader(t *testing.T) {
+	sampleResp := `
+[
+	{"gm_address": "127.0.0.1", "selected": false, "clock_quality": {"clock_class": 6, "clock_accuracy": 33}, "offset": -42, "mean_path_delay": 4200, "gm_present": 1, "error": ""},
+	{"gm_address": "::1", "selected": true, "clock_quality": {"clock_class": 6, "clock_accuracy": 33}, "offset": -43, "mean_path_delay": 4300, "gm_present": 1, "error": ""}
+]
+`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, sampleResp)
+	}))
+	defer ts.Close()
+	surl, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	read := sptpReader(surl.Host)
+
+	q, err := read()
+	require.NoError(t, err)
+	require.Equal(t, syncQuality{rootDelay: 8600 * time.Nanosecond, rootDispersion: 143 * time.Nanosecond}, q)
+
+	// holdover, no grandmaster to sync to
+	sampleResp = `[{"gm_address": "::1", "selected": false, "error": "announce timeout"}]`
+	_, err = read()
+	require.EqualError(t, err, "no selected grandmaster")
+}
+
+func TestServeSyncState(t *testing.T) {
+	conn := tryListenUDP(t)
+	defer conn.Close()
+	connFd, err := timestamp.ConnFd(conn)
+	require.NoError(t, err)
+
+	s := &Server{Config: Config{Stratum: 1, RefID: "OLEG"}}
+	response := &ntp.Packet{}
+	s.fillStaticHeaders(response)
+	sync := newSyncTracker(&s.Config, &stats.JSONStats{})
+	tk := task{connFd: connFd, addr: timestamp.IPToSockaddr(net.ParseIP("127.0.0.1"), 0), received: time.Now(), request: ntpRequest, stats: &stats.JSONStats{}, sync: sync}
+
+	tk.serve(response, 0)
+	require.Equal(t, uint8(leapAlarm<<6|ntpRequest.Settings&0x38|4), response.Settings)
+	require.Equal(t, uint8(16), response.Stratum)
+
+	sync.read = func() (syncQuality, error) { return syncQuality{rootDispersion: time.Millisecond}, nil }
+	sync.update()
+	tk.serve(response, 0)
+	require.Equal(t, ntpRequest.Settings&0x38|4, response.Settings)
+	require.Equal(t, uint8(1), response.Stratum)
+	require.Equal(t, refID("OLEG"), response.ReferenceID)
+	require.Equal(t, uint32(66), response.RootDispersion)
+}
@@ -0,0 +1,44 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// txStamper sends responses with hardware transmit timestamps
+// Hardware timestamps are not supported on FreeBSD
+type txStamper struct {
+	table *interleaveTable
+}
+
+func newTXStamper(_ int, _ *interleaveTable, _ func() time.Duration, _ Stats) (*txStamper, error) {
+	return nil, fmt.Errorf("hardware transmit timestamps are not supported")
+}
+
+func (s *txStamper) send(_ []byte, _ unix.Sockaddr, _ txRecord) error {
+	return fmt.Errorf("hardware transmit timestamps are not supported")
+}
+
+func (s *txStamper) sendUntracked(_ []byte, _ unix.Sockaddr) error {
+	return fmt.Errorf("hardware transmit timestamps are not supported")
+}
+
+func (s *txStamper) run() {}
@@ -0,0 +1,119 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/facebook/time/timestamp"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// txBatchSize is how many transmit timestamps are read from the error queue at once
+const txBatchSize = 64
+
+// txStamper sends responses on the socket with hardware transmit timestamps enabled
+// and matches the timestamps to the responses
+type txStamper struct {
+	sync.Mutex
+	connFd     int
+	correlator *timestamp.TXCorrelator
+	// nextID is the timestamp id kernel assigns to the next packet sent
+	nextID    uint32
+	table     *interleaveTable
+	phcOffset func() time.Duration
+	stats     Stats
+}
+
+// newTXStamper enables transmit timestamp ids on the socket which already has hardware timestamps enabled
+func newTXStamper(connFd int, table *interleaveTable, phcOffset func() time.Duration, stats Stats) (*txStamper, error) {
+	if err := timestamp.EnableOptID(connFd); err != nil {
+		return nil, err
+	}
+	return &txStamper{
+		connFd:     connFd,
+		correlator: timestamp.NewTXCorrelator(timestamp.DefaultTXCorrelatorTTL),
+		table:      table,
+		phcOffset:  phcOffset,
+		stats:      stats,
+	}, nil
+}
+
+// send sends the response, transmit timestamp is recorded when it arrives
+func (s *txStamper) send(b []byte, addr unix.Sockaddr, rec txRecord) error {
+	s.Lock()
+	defer s.Unlock()
+	s.table.sent(rec.addr, rec.rx, rec.sent)
+	return s.sendLocked(b, addr, rec)
+}
+
+// sendUntracked sends the packet we don't need transmit timestamp for, like Kiss-o'-Death.
+// It still takes the next timestamp id, so the correlator must register it.
+func (s *txStamper) sendUntracked(b []byte, addr unix.Sockaddr) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.sendLocked(b, addr, nil)
+}
+
+func (s *txStamper) sendLocked(b []byte, addr unix.Sockaddr, data any) error {
+	// kernel assigns ids in the order packets are sent.
+	// Register the packet first, as its timestamp may be read before Sendto returns
+	s.correlator.Add(s.nextID, data)
+	if err := unix.Sendto(s.connFd, b, unix.O_NONBLOCK, addr); err != nil {
+		// the id wasn't taken, next packet reuses it
+		return err
+	}
+	s.nextID++
+	return nil
+}
+
+// run reads transmit timestamps until the socket is closed
+func (s *txStamper) run() {
+	batch := timestamp.NewTXBatch(txBatchSize)
+	lastExpire := time.Now()
+	for {
+		tss, err := batch.Read(s.connFd)
+		if errors.Is(err, unix.EBADF) {
+			return
+		}
+		for _, m := range s.correlator.MatchAll(tss) {
+			rec, ok := m.Data.(txRecord)
+			if !ok {
+				// untracked packet
+				continue
+			}
+			hw := m.Time.Add(s.phcOffset())
+			s.table.observeTXDelay(hw.Sub(rec.sent))
+			s.table.transmitted(rec.addr, rec.rx, ntpTime(hw.Add(rec.offset)))
+			if rec.onTransmit != nil {
+				rec.onTransmit(hw)
+			}
+			s.stats.IncTXTimestamps()
+		}
+		if err != nil && len(tss) == 0 {
+			// nothing was sent for a while
+			log.Debugf("[txstamp] %v", err)
+		}
+		if time.Since(lastExpire) >= time.Second {
+			s.correlator.Expire()
+			lastExpire = time.Now()
+		}
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	ntp "github.com/facebook/time/ntp/protocol"
+	"github.com/facebook/time/ntp/responder/stats"
+	"github.com/facebook/time/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+// runTXStamper starts reading transmit timestamps of the conn until the test ends
+func runTXStamper(t *testing.T, conn *net.UDPConn, st Stats) *txStamper {
+	connFd, err := timestamp.ConnFd(conn)
+	require.NoError(t, err)
+	// loopback only has software timestamps, they go through the same error queue
+	require.NoError(t, timestamp.EnableSWTimestamps(connFd))
+	tx, err := newTXStamper(connFd, newInterleaveTable(), func() time.Duration { return 0 }, st)
+	require.NoError(t, err)
+	done := make(chan struct{})
+	go func() {
+		tx.run()
+		close(done)
+	}()
+	// stop reading before the fd number is reused by another test
+	t.Cleanup(func() {
+		conn.Close()
+		<-done
+	})
+	return tx
+}
+
+func TestServeInterleaved(t *testing.T) {
+	st := &stats.JSONStats{}
+	tx := runTXStamper(t, tryListenUDP(t), st)
+	connFd := tx.connFd
+
+	clientConn := tryListenUDP(t)
+	defer clientConn.Close()
+	client := clientConn.LocalAddr().(*net.UDPAddr)
+	buf := make([]byte, 1024)
+	exchange := func(request *ntp.Packet) *ntp.Packet {
+		response := &ntp.Packet{}
+		tk := task{connFd: connFd, addr: timestamp.IPToSockaddr(client.IP, client.Port), received: time.Now(), request: request, stats: st, tx: tx}
+		tk.serve(response, 0)
+		require.NoError(t, clientConn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, err := clientConn.Read(buf)
+		require.NoError(t, err)
+		p, err := ntp.BytesToPacket(buf[:n])
+		require.NoError(t, err)
+		return p
+	}
+
+	// basic mode
+	sec, frac := ntp.Time(time.Now())
+	first := exchange(&ntp.Packet{Settings: 0x23, TxTimeSec: sec, TxTimeFrac: frac})
+	require.Equal(t, sec, first.OrigTimeSec)
+	require.Equal(t, frac, first.OrigTimeFrac)
+	require.Eventually(t, func() bool {
+		_, ok := tx.table.lookup(client.AddrPort().Addr().Unmap(), uint64(first.RxTimeSec)<<32|uint64(first.RxTimeFrac))
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	// client puts our receive timestamp into origin and asks for the interleaved mode
+	clientRxSec, clientRxFrac := ntp.Time(time.Now())
+	sec, frac = ntp.Time(time.Now())
+	second := exchange(&ntp.Packet{
+		Settings:    0x23,
+		OrigTimeSec: first.RxTimeSec, OrigTimeFrac: first.RxTimeFrac,
+		RxTimeSec: clientRxSec, RxTimeFrac: clientRxFrac,
+		TxTimeSec: sec, TxTimeFrac: frac,
+	})
+	require.Equal(t, clientRxSec, second.OrigTimeSec)
+	require.Equal(t, clientRxFrac, second.OrigTimeFrac)
+	// transmit timestamp of the first response, taken after it was built
+	firstTX := ntp.Unix(second.TxTimeSec, second.TxTimeFrac)
+	require.False(t, firstTX.Before(ntp.Unix(first.TxTimeSec, first.TxTimeFrac)))
+	require.True(t, firstTX.Before(ntp.Unix(second.RxTimeSec, second.RxTimeFrac)))
+	require.Positive(t, tx.table.delay())
+}
+
+func TestServeInterleavedAfterKoD(t *testing.T) {
+	st := &stats.JSONStats{}
+	tx := runTXStamper(t, tryListenUDP(t), st)
+	connFd := tx.connFd
+
+	clientConn := tryListenUDP(t)
+	defer clientConn.Close()
+	client := clientConn.LocalAddr().(*net.UDPAddr)
+	buf := make([]byte, 1024)
+	exchange := func(request *ntp.Packet, acl *aclMatcher) *ntp.Packet {
+		response := &ntp.Packet{}
+		tk := task{connFd: connFd, addr: timestamp.IPToSockaddr(client.IP, client.Port), received: time.Now(), request: request, stats: st, acl: acl, tx: tx}
+		tk.serve(response, 0)
+		require.NoError(t, clientConn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, err := clientConn.Read(buf)
+		require.NoError(t, err)
+		p, err := ntp.BytesToPacket(buf[:n])
+		require.NoError(t, err)
+		return p
+	}
+
+	// KoD takes a transmit timestamp id without being tracked
+	sec, frac := ntp.Time(time.Now())
+	kod := exchange(&ntp.Packet{Settings: 0x23, TxTimeSec: sec, TxTimeFrac: frac}, newACLMatcher(nil, ACLDeny))
+	require.Equal(t, uint8(0), kod.Stratum)
+
+	sec, frac = ntp.Time(time.Now())
+	first := exchange(&ntp.Packet{Settings: 0x23, TxTimeSec: sec, TxTimeFrac: frac}, nil)
+	require.Eventually(t, func() bool {
+		_, ok := tx.table.lookup(client.AddrPort().Addr().Unmap(), uint64(first.RxTimeSec)<<32|uint64(first.RxTimeFrac))
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	clientRxSec, clientRxFrac := ntp.Time(time.Now())
+	sec, frac = ntp.Time(time.Now())
+	second := exchange(&ntp.Packet{
+		Settings:    0x23,
+		OrigTimeSec: first.RxTimeSec, OrigTimeFrac: first.RxTimeFrac,
+		RxTimeSec: clientRxSec, RxTimeFrac: clientRxFrac,
+		TxTimeSec: sec, TxTimeFrac: frac,
+	}, nil)
+	require.Equal(t, clientRxSec, second.OrigTimeSec)
+	// transmit timestamp of the first response, not the KoD sent before it
+	firstTX := ntp.Unix(second.TxTimeSec, second.TxTimeFrac)
+	require.False(t, firstTX.Before(ntp.Unix(first.TxTimeSec, first.TxTimeFrac)))
+	require.True(t, firstTX.Before(ntp.Unix(second.RxTimeSec, second.RxTimeFrac)))
+	require.Zero(t, tx.correlator.Stats().Misses)
+}
@@ -25,8 +25,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 	"sync/atomic"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -42,6 +45,43 @@ type JSONStats struct {
 	workers       int64
 	readError     int64
 	announce      int64
+	ntsRequests   int64
+	ntsNAKs       int64
+	rateLimited   int64
+	kod           int64
+	authRequests  int64
+	authFailures  int64
+	txTimestamps  int64
+	interleaved   int64
+	aclIgnored    int64
+	drained       int64
+	captured      int64
+	captureDrops  int64
+	drain         int64
+	servedOffset  int64
+	stratum       int64
+	rootDisp      int64
+
+	// requests per NTP version
+	versionRequests [8]int64
+
+	// per SO_REUSEPORT worker requests, worker id to *int64
+	workerRequests sync.Map
+
+	kodCodesLock sync.Mutex
+	kodCodes     map[string]int64
+
+	timestampingLock sync.Mutex
+	timestamping     map[string]int64
+
+	extensionsLock sync.Mutex
+	extensions     map[string]int64
+
+	aclLock sync.Mutex
+	acl     map[string]int64
+
+	offendersLock sync.Mutex
+	offenders     map[string]int64
 }
 
 // toMap converts struct to a map
@@ -55,6 +95,56 @@ func (j *JSONStats) toMap() (export map[string]int64) {
 	export["workers"] = j.workers
 	export["readError"] = j.readError
 	export["announce"] = j.announce
+	export["ntsrequests"] = j.ntsRequests
+	export["ntsnaks"] = j.ntsNAKs
+	export["ratelimited"] = j.rateLimited
+	export["kod"] = j.kod
+	export["authrequests"] = j.authRequests
+	export["authfailures"] = j.authFailures
+	export["txtimestamps"] = j.txTimestamps
+	export["interleaved"] = j.interleaved
+	export["aclignored"] = j.aclIgnored
+	export["drained"] = j.drained
+	export["captured"] = j.captured
+	export["capturedropped"] = j.captureDrops
+	export["drain"] = j.drain
+	export["servedoffset"] = j.servedOffset
+	export["stratum"] = j.stratum
+	for v := range j.versionRequests {
+		if n := atomic.LoadInt64(&j.versionRequests[v]); n > 0 {
+			export[fmt.Sprintf("version.%d.requests", v)] = n
+		}
+	}
+	export["rootdispersion"] = j.rootDisp
+	j.workerRequests.Range(func(id, v any) bool {
+		export[fmt.Sprintf("worker.%d.requests", id)] = atomic.LoadInt64(v.(*int64))
+		return true
+	})
+	j.kodCodesLock.Lock()
+	for code, v := range j.kodCodes {
+		export["kod."+code] = v
+	}
+	j.kodCodesLock.Unlock()
+	j.timestampingLock.Lock()
+	for mode, v := range j.timestamping {
+		export["timestamping."+mode] = v
+	}
+	j.timestampingLock.Unlock()
+	j.extensionsLock.Lock()
+	for name, v := range j.extensions {
+		export["extension."+name] = v
+	}
+	j.extensionsLock.Unlock()
+	j.aclLock.Lock()
+	for rule, v := range j.acl {
+		export["acl."+rule] = v
+	}
+	j.aclLock.Unlock()
+	j.offendersLock.Lock()
+	for prefix, v := range j.offenders {
+		export["ratelimited."+prefix] = v
+	}
+	j.offendersLock.Unlock()
 
 	return export
 }
@@ -74,7 +164,10 @@ func (j *JSONStats) handleRequest(w http.ResponseWriter, _ *http.Request) {
 
 // Start with launch 303 thrift and report ODS metrics periodically
 func (j *JSONStats) Start(port int) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(j)
 	http.HandleFunc("/", j.handleRequest)
+	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
 	addr := fmt.Sprintf(":%d", port)
 	log.Debugf("Starting http json server on %s", addr)
 	err := http.ListenAndServe(addr, nil)
@@ -113,6 +206,65 @@ func (j *JSONStats) IncReadError() {
 	atomic.AddInt64(&j.readError, 1)
 }
 
+// IncWorkerRequests atomically add 1 to the per worker counter
+func (j *JSONStats) IncWorkerRequests(id int) {
+	v, ok := j.workerRequests.Load(id)
+	if !ok {
+		v, _ = j.workerRequests.LoadOrStore(id, new(int64))
+	}
+	atomic.AddInt64(v.(*int64), 1)
+}
+
+// IncNTSRequests atomically add 1 to the counter
+func (j *JSONStats) IncNTSRequests() {
+	atomic.AddInt64(&j.ntsRequests, 1)
+}
+
+// IncNTSNAKs atomically add 1 to the counter
+func (j *JSONStats) IncNTSNAKs() {
+	atomic.AddInt64(&j.ntsNAKs, 1)
+}
+
+// IncRateLimited atomically add 1 to the counter
+func (j *JSONStats) IncRateLimited() {
+	atomic.AddInt64(&j.rateLimited, 1)
+}
+
+// IncKoD atomically add 1 to the counter and to the counter of the kiss code
+func (j *JSONStats) IncKoD(code string) {
+	atomic.AddInt64(&j.kod, 1)
+	j.kodCodesLock.Lock()
+	defer j.kodCodesLock.Unlock()
+	if j.kodCodes == nil {
+		j.kodCodes = map[string]int64{}
+	}
+	j.kodCodes[code]++
+}
+
+// IncRequestVersion atomically add 1 to the counter of requests of the NTP version
+func (j *JSONStats) IncRequestVersion(version int) {
+	atomic.AddInt64(&j.versionRequests[version&7], 1)
+}
+
+// IncACLIgnored atomically add 1 to the counter
+func (j *JSONStats) IncACLIgnored() {
+	atomic.AddInt64(&j.aclIgnored, 1)
+}
+
+// SetACLCounters replaces per ACL rule counters of requests
+func (j *JSONStats) SetACLCounters(counters map[string]int64) {
+	j.aclLock.Lock()
+	j.acl = counters
+	j.aclLock.Unlock()
+}
+
+// SetRateLimitOffenders replaces per prefix counters of rate limited requests
+func (j *JSONStats) SetRateLimitOffenders(offenders map[string]int64) {
+	j.offendersLock.Lock()
+	j.offenders = offenders
+	j.offendersLock.Unlock()
+}
+
 // DecListeners atomically removes 1 from the counter
 func (j *JSONStats) DecListeners() {
 	atomic.AddInt64(&j.listeners, -1)
@@ -123,6 +275,81 @@ func (j *JSONStats) DecWorkers() {
 	atomic.AddInt64(&j.workers, -1)
 }
 
+// IncAuthRequests atomically add 1 to the counter
+func (j *JSONStats) IncAuthRequests() {
+	atomic.AddInt64(&j.authRequests, 1)
+}
+
+// IncAuthFailures atomically add 1 to the counter
+func (j *JSONStats) IncAuthFailures() {
+	atomic.AddInt64(&j.authFailures, 1)
+}
+
+// IncTXTimestamps atomically add 1 to the counter
+func (j *JSONStats) IncTXTimestamps() {
+	atomic.AddInt64(&j.txTimestamps, 1)
+}
+
+// IncInterleaved atomically add 1 to the counter
+func (j *JSONStats) IncInterleaved() {
+	atomic.AddInt64(&j.interleaved, 1)
+}
+
+// IncTimestamping atomically add 1 to the number of sockets with the timestamping type
+func (j *JSONStats) IncTimestamping(mode string) {
+	j.timestampingLock.Lock()
+	defer j.timestampingLock.Unlock()
+	if j.timestamping == nil {
+		j.timestamping = map[string]int64{}
+	}
+	j.timestamping[mode]++
+}
+
+// IncExtension atomically add 1 to the number of received extension fields of the type
+func (j *JSONStats) IncExtension(name string) {
+	j.extensionsLock.Lock()
+	defer j.extensionsLock.Unlock()
+	if j.extensions == nil {
+		j.extensions = map[string]int64{}
+	}
+	j.extensions[name]++
+}
+
+// IncCaptured atomically add 1 to the counter
+func (j *JSONStats) IncCaptured() {
+	atomic.AddInt64(&j.captured, 1)
+}
+
+// IncCaptureDropped atomically add 1 to the counter
+func (j *JSONStats) IncCaptureDropped() {
+	atomic.AddInt64(&j.captureDrops, 1)
+}
+
+// IncDrained atomically add 1 to the counter
+func (j *JSONStats) IncDrained() {
+	atomic.AddInt64(&j.drained, 1)
+}
+
+// SetDrain atomically sets drain status
+func (j *JSONStats) SetDrain(v int64) {
+	atomic.StoreInt64(&j.drain, v)
+}
+
+// SetServedOffset atomically sets offset of served time from the reference clock in nanoseconds
+func (j *JSONStats) SetServedOffset(offset int64) {
+	atomic.StoreInt64(&j.servedOffset, offset)
+}
+
+// SetStratum atomically sets advertised stratum
+func (j *JSONStats) SetStratum(stratum int64) {
+	atomic.StoreInt64(&j.stratum, stratum)
+}
+
+// SetRootDispersion atomically sets advertised error bound in nanoseconds
+func (j *JSONStats) SetRootDispersion(ns int64) {
+	atomic.StoreInt64(&j.rootDisp, ns)
+}
+
 // SetAnnounce atomically sets counter to 1
 func (j *JSONStats) SetAnnounce() {
 	atomic.StoreInt64(&j.announce, 1)
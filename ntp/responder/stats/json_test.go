@@ -70,6 +70,133 @@ func TestJSONStatsReadError(t *testing.T) {
 	require.Equal(t, int64(1), stats.readError)
 }
 
+func TestJSONStatsNTS(t *testing.T) {
+	stats := JSONStats{}
+
+	stats.IncNTSRequests()
+	require.Equal(t, int64(1), stats.ntsRequests)
+
+	stats.IncNTSNAKs()
+	require.Equal(t, int64(1), stats.ntsNAKs)
+}
+
+func TestJSONStatsRateLimit(t *testing.T) {
+	stats := JSONStats{}
+
+	stats.IncRateLimited()
+	require.Equal(t, int64(1), stats.rateLimited)
+
+	stats.IncKoD("RATE")
+	require.Equal(t, int64(1), stats.kod)
+	require.Equal(t, int64(1), stats.toMap()["kod.RATE"])
+
+	stats.SetRateLimitOffenders(map[string]int64{"192.0.2.1/32": 42})
+	require.Equal(t, int64(42), stats.toMap()["ratelimited.192.0.2.1/32"])
+	stats.SetRateLimitOffenders(map[string]int64{})
+	require.NotContains(t, stats.toMap(), "ratelimited.192.0.2.1/32")
+}
+
+func TestJSONStatsWorkerRequests(t *testing.T) {
+	stats := JSONStats{}
+
+	stats.IncWorkerRequests(0)
+	stats.IncWorkerRequests(3)
+	stats.IncWorkerRequests(3)
+	require.Equal(t, int64(1), stats.toMap()["worker.0.requests"])
+	require.Equal(t, int64(2), stats.toMap()["worker.3.requests"])
+}
+
+func TestJSONStatsAuth(t *testing.T) {
+	stats := JSONStats{}
+
+	stats.IncAuthRequests()
+	require.Equal(t, int64(1), stats.authRequests)
+
+	stats.IncAuthFailures()
+	require.Equal(t, int64(1), stats.authFailures)
+}
+
+func TestJSONStatsTimestamping(t *testing.T) {
+	stats := JSONStats{}
+
+	stats.IncTXTimestamps()
+	require.Equal(t, int64(1), stats.txTimestamps)
+
+	stats.IncInterleaved()
+	require.Equal(t, int64(1), stats.interleaved)
+
+	stats.IncTimestamping("hardware")
+	stats.IncTimestamping("hardware")
+	stats.IncTimestamping("software")
+	require.Equal(t, int64(2), stats.toMap()["timestamping.hardware"])
+	require.Equal(t, int64(1), stats.toMap()["timestamping.software"])
+}
+
+func TestJSONStatsExtension(t *testing.T) {
+	stats := JSONStats{}
+
+	stats.IncExtension("ntscookie")
+	stats.IncExtension("unknown")
+	stats.IncExtension("unknown")
+	require.Equal(t, int64(1), stats.toMap()["extension.ntscookie"])
+	require.Equal(t, int64(2), stats.toMap()["extension.unknown"])
+}
+
+func TestJSONStatsDrain(t *testing.T) {
+	stats := JSONStats{}
+
+	stats.IncDrained()
+	require.Equal(t, int64(1), stats.drained)
+
+	stats.SetDrain(1)
+	require.Equal(t, int64(1), stats.drain)
+}
+
+func TestJSONStatsRequestVersion(t *testing.T) {
+	stats := JSONStats{}
+
+	stats.IncRequestVersion(4)
+	stats.IncRequestVersion(4)
+	stats.IncRequestVersion(3)
+	require.Equal(t, int64(2), stats.toMap()["version.4.requests"])
+	require.Equal(t, int64(1), stats.toMap()["version.3.requests"])
+	require.NotContains(t, stats.toMap(), "version.2.requests")
+
+	stats.SetServedOffset(-42)
+	require.Equal(t, int64(-42), stats.servedOffset)
+}
+
+func TestJSONStatsCapture(t *testing.T) {
+	stats := JSONStats{}
+
+	stats.IncCaptured()
+	require.Equal(t, int64(1), stats.captured)
+
+	stats.IncCaptureDropped()
+	require.Equal(t, int64(1), stats.captureDrops)
+}
+
+func TestJSONStatsSyncState(t *testing.T) {
+	stats := JSONStats{}
+
+	stats.SetStratum(16)
+	require.Equal(t, int64(16), stats.stratum)
+
+	stats.SetRootDispersion(42)
+	require.Equal(t, int64(42), stats.rootDisp)
+}
+
+func TestJSONStatsACL(t *testing.T) {
+	stats := JSONStats{}
+
+	stats.IncACLIgnored()
+	require.Equal(t, int64(1), stats.aclIgnored)
+
+	stats.SetACLCounters(map[string]int64{"10.0.0.0/8": 42, "default": 1})
+	require.Equal(t, int64(42), stats.toMap()["acl.10.0.0.0/8"])
+	require.Equal(t, int64(1), stats.toMap()["acl.default"])
+}
+
 func TestJSONStatsAnnounce(t *testing.T) {
 	stats := JSONStats{}
 
@@ -89,6 +216,22 @@ func TestJSONStatsToMap(t *testing.T) {
 		workers:       5,
 		readError:     6,
 		announce:      7,
+		ntsRequests:   8,
+		ntsNAKs:       9,
+		rateLimited:   10,
+		kod:           11,
+		stratum:       12,
+		rootDisp:      13,
+		authRequests:  14,
+		authFailures:  15,
+		txTimestamps:  16,
+		interleaved:   17,
+		aclIgnored:    18,
+		drained:       19,
+		drain:         20,
+		servedOffset:  21,
+		captured:      22,
+		captureDrops:  23,
 	}
 	result := j.toMap()
 
@@ -100,6 +243,22 @@ func TestJSONStatsToMap(t *testing.T) {
 	expectedMap["workers"] = 5
 	expectedMap["readError"] = 6
 	expectedMap["announce"] = 7
+	expectedMap["ntsrequests"] = 8
+	expectedMap["ntsnaks"] = 9
+	expectedMap["ratelimited"] = 10
+	expectedMap["kod"] = 11
+	expectedMap["stratum"] = 12
+	expectedMap["rootdispersion"] = 13
+	expectedMap["authrequests"] = 14
+	expectedMap["authfailures"] = 15
+	expectedMap["txtimestamps"] = 16
+	expectedMap["interleaved"] = 17
+	expectedMap["aclignored"] = 18
+	expectedMap["drained"] = 19
+	expectedMap["drain"] = 20
+	expectedMap["servedoffset"] = 21
+	expectedMap["captured"] = 22
+	expectedMap["capturedropped"] = 23
 
 	require.Equal(t, expectedMap, result)
 }
@@ -0,0 +1,131 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// promNamespace is prepended to names of all metrics exposed in Prometheus format
+const promNamespace = "ntpresponder"
+
+func newPromDesc(name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(promNamespace, "", name), help, labels, nil)
+}
+
+// promMetric is a metric backed by a single JSONStats field
+type promMetric struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	value     func(j *JSONStats) float64
+}
+
+func promCounter(name, help string, field func(j *JSONStats) *int64) promMetric {
+	return promMetric{newPromDesc(name, help), prometheus.CounterValue, func(j *JSONStats) float64 { return float64(atomic.LoadInt64(field(j))) }}
+}
+
+func promGauge(name, help string, field func(j *JSONStats) *int64) promMetric {
+	return promMetric{newPromDesc(name, help), prometheus.GaugeValue, func(j *JSONStats) float64 { return float64(atomic.LoadInt64(field(j))) }}
+}
+
+// promSeconds is a gauge of nanoseconds field exposed in seconds
+func promSeconds(name, help string, field func(j *JSONStats) *int64) promMetric {
+	return promMetric{newPromDesc(name, help), prometheus.GaugeValue, func(j *JSONStats) float64 {
+		return time.Duration(atomic.LoadInt64(field(j))).Seconds()
+	}}
+}
+
+var promMetrics = []promMetric{
+	promCounter("requests_total", "NTP requests received", func(j *JSONStats) *int64 { return &j.requests }),
+	promCounter("responses_total", "NTP responses sent", func(j *JSONStats) *int64 { return &j.responses }),
+	promCounter("invalid_format_total", "Requests dropped because of invalid format", func(j *JSONStats) *int64 { return &j.invalidFormat }),
+	promCounter("read_errors_total", "Failed socket reads", func(j *JSONStats) *int64 { return &j.readError }),
+	promCounter("nts_requests_total", "NTS requests served", func(j *JSONStats) *int64 { return &j.ntsRequests }),
+	promCounter("nts_naks_total", "NTS NAKs sent", func(j *JSONStats) *int64 { return &j.ntsNAKs }),
+	promCounter("auth_requests_total", "Symmetric key authenticated requests served", func(j *JSONStats) *int64 { return &j.authRequests }),
+	promCounter("auth_failures_total", "Requests failing symmetric key authentication", func(j *JSONStats) *int64 { return &j.authFailures }),
+	promCounter("rate_limited_total", "Rate limited requests", func(j *JSONStats) *int64 { return &j.rateLimited }),
+	promCounter("acl_ignored_total", "Requests dropped by access control list", func(j *JSONStats) *int64 { return &j.aclIgnored }),
+	promCounter("drained_total", "Requests dropped while drained", func(j *JSONStats) *int64 { return &j.drained }),
+	promCounter("captured_total", "Sampled packets written to capture file", func(j *JSONStats) *int64 { return &j.captured }),
+	promCounter("capture_dropped_total", "Sampled packets dropped because capture file writer was behind", func(j *JSONStats) *int64 { return &j.captureDrops }),
+	promCounter("tx_timestamps_total", "Hardware transmit timestamps of responses", func(j *JSONStats) *int64 { return &j.txTimestamps }),
+	promCounter("interleaved_total", "Responses in interleaved mode", func(j *JSONStats) *int64 { return &j.interleaved }),
+	promGauge("listeners", "Running listeners", func(j *JSONStats) *int64 { return &j.listeners }),
+	promGauge("workers", "Running workers", func(j *JSONStats) *int64 { return &j.workers }),
+	promGauge("announce", "Whether IPs are announced", func(j *JSONStats) *int64 { return &j.announce }),
+	promGauge("drain", "Whether responder is drained", func(j *JSONStats) *int64 { return &j.drain }),
+	promGauge("stratum", "Advertised stratum", func(j *JSONStats) *int64 { return &j.stratum }),
+	promSeconds("root_dispersion_seconds", "Advertised root dispersion", func(j *JSONStats) *int64 { return &j.rootDisp }),
+	promSeconds("served_offset_seconds", "Offset of served time from the reference clock", func(j *JSONStats) *int64 { return &j.servedOffset }),
+}
+
+// metrics with labels, built from JSONStats maps
+var (
+	promVersionRequests = newPromDesc("version_requests_total", "NTP requests by NTP version", "version")
+	promKoD             = newPromDesc("kod_total", "Kiss-o'-Death responses by kiss code", "code")
+	promWorkerRequests  = newPromDesc("worker_requests_total", "Requests served by SO_REUSEPORT worker", "worker")
+	promTimestamping    = newPromDesc("timestamping_sockets", "Sockets by active timestamping", "mode")
+	promExtensions      = newPromDesc("extension_fields_total", "Extension fields received by type", "type")
+	promACL             = newPromDesc("acl_requests_total", "Requests matched by access control rule", "rule")
+	promOffenders       = newPromDesc("rate_limited_offender_requests", "Rate limited requests of top offenders", "prefix")
+)
+
+// Describe implements prometheus.Collector
+func (j *JSONStats) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range promMetrics {
+		ch <- m.desc
+	}
+	for _, d := range []*prometheus.Desc{promVersionRequests, promKoD, promWorkerRequests, promTimestamping, promExtensions, promACL, promOffenders} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector
+func (j *JSONStats) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range promMetrics {
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, m.value(j))
+	}
+	for v := range j.versionRequests {
+		if n := atomic.LoadInt64(&j.versionRequests[v]); n > 0 {
+			ch <- prometheus.MustNewConstMetric(promVersionRequests, prometheus.CounterValue, float64(n), strconv.Itoa(v))
+		}
+	}
+	j.workerRequests.Range(func(id, v any) bool {
+		ch <- prometheus.MustNewConstMetric(promWorkerRequests, prometheus.CounterValue, float64(atomic.LoadInt64(v.(*int64))), strconv.Itoa(id.(int)))
+		return true
+	})
+	collectMap(ch, promKoD, prometheus.CounterValue, &j.kodCodesLock, &j.kodCodes)
+	collectMap(ch, promTimestamping, prometheus.GaugeValue, &j.timestampingLock, &j.timestamping)
+	collectMap(ch, promExtensions, prometheus.CounterValue, &j.extensionsLock, &j.extensions)
+	collectMap(ch, promACL, prometheus.CounterValue, &j.aclLock, &j.acl)
+	collectMap(ch, promOffenders, prometheus.GaugeValue, &j.offendersLock, &j.offenders)
+}
+
+// collectMap sends metric for every entry of the map guarded by lock, with the key as the label
+func collectMap(ch chan<- prometheus.Metric, desc *prometheus.Desc, valueType prometheus.ValueType, lock *sync.Mutex, m *map[string]int64) {
+	lock.Lock()
+	defer lock.Unlock()
+	for label, v := range *m {
+		ch <- prometheus.MustNewConstMetric(desc, valueType, float64(v), label)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONStatsPrometheus(t *testing.T) {
+	stats := &JSONStats{}
+	stats.IncRequests()
+	stats.IncRequests()
+	stats.IncRequestVersion(4)
+	stats.IncRequestVersion(3)
+	stats.IncKoD("RATE")
+	stats.IncTimestamping("hardware")
+	stats.SetServedOffset(1500000)
+	stats.SetACLCounters(map[string]int64{"10.0.0.0/8": 42})
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(stats))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	metrics := map[string]float64{}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			name := f.GetName()
+			for _, l := range m.GetLabel() {
+				name += "{" + l.GetName() + "=" + l.GetValue() + "}"
+			}
+			if c := m.GetCounter(); c != nil {
+				metrics[name] = c.GetValue()
+			} else {
+				metrics[name] = m.GetGauge().GetValue()
+			}
+		}
+	}
+	require.Equal(t, 2.0, metrics["ntpresponder_requests_total"])
+	require.Equal(t, 1.0, metrics["ntpresponder_version_requests_total{version=4}"])
+	require.Equal(t, 1.0, metrics["ntpresponder_version_requests_total{version=3}"])
+	require.Equal(t, 1.0, metrics["ntpresponder_kod_total{code=RATE}"])
+	require.Equal(t, 1.0, metrics["ntpresponder_timestamping_sockets{mode=hardware}"])
+	require.Equal(t, 0.0015, metrics["ntpresponder_served_offset_seconds"])
+	require.Equal(t, 42.0, metrics["ntpresponder_acl_requests_total{rule=10.0.0.0/8}"])
+	require.Equal(t, 0.0, metrics["ntpresponder_responses_total"])
+	require.Contains(t, metrics, "ntpresponder_responses_total")
+}
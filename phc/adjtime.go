@@ -62,6 +62,15 @@ func clockStep(dev *Device, step time.Duration) error {
 	return err
 }
 
+// clockAdjPhase adjusts PHC clock phase by given offset
+func clockAdjPhase(dev *Device, offset time.Duration) error {
+	state, err := clock.AdjPhase(dev.ClockID(), offset)
+	if err == nil && state != unix.TIME_OK {
+		return &errorClockState{path: dev.File().Name(), st: state}
+	}
+	return err
+}
+
 func clockSetTime(dev *Device, t time.Time) error {
 	ts, err := unix.TimeToTimespec(t)
 	if err != nil {
@@ -0,0 +1,123 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// The kernel reports n_alarm in PTP_CLOCK_GETCAPS, but there is no ioctl to program PHC alarms
+// and dynamic posix clocks don't support timer_create. Alarms are emulated on the system clock:
+// the PHC-to-system offset is re-measured while waiting, so the alarm follows PHC time
+// even if either clock is adjusted meanwhile.
+
+const (
+	// alarmResyncInterval is how often the offset is re-measured while waiting for the alarm
+	alarmResyncInterval = 100 * time.Millisecond
+)
+
+// ErrAlarmStopped is returned by Alarm.Wait if the alarm was stopped before it fired
+var ErrAlarmStopped = errors.New("alarm stopped")
+
+// AlarmEvent is delivered when the alarm fires
+type AlarmEvent struct {
+	// Scheduled is the PHC time the alarm was armed for
+	Scheduled time.Time
+	// PHCTime is the PHC time estimated at the moment the alarm fired
+	PHCTime time.Time
+	// Err is set if the PHC could not be read while waiting
+	Err error
+}
+
+// Late returns how late the alarm fired in PHC time
+func (e AlarmEvent) Late() time.Duration {
+	return e.PHCTime.Sub(e.Scheduled)
+}
+
+// Alarm fires once PHC time reaches the scheduled timestamp
+type Alarm struct {
+	// C receives exactly one AlarmEvent unless the alarm is stopped
+	C    <-chan AlarmEvent
+	at   time.Time
+	dev  OffsetMeasurer
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewAlarm arms an alarm at PHC timestamp at. It works regardless of Caps().NumAlarms as alarms are emulated.
+func NewAlarm(dev OffsetMeasurer, at time.Time) *Alarm {
+	c := make(chan AlarmEvent, 1)
+	a := &Alarm{
+		C:    c,
+		at:   at,
+		dev:  dev,
+		stop: make(chan struct{}),
+	}
+	go a.run(c)
+	return a
+}
+
+// AfterPHC arms an alarm after duration d of PHC time
+func AfterPHC(dev OffsetMeasurer, d time.Duration) (*Alarm, error) {
+	m, err := dev.OffsetFromSystem()
+	if err != nil {
+		return nil, err
+	}
+	return NewAlarm(dev, m.PHCTime.Add(d)), nil
+}
+
+// Stop disarms the alarm. It's safe to call Stop multiple times.
+func (a *Alarm) Stop() {
+	a.once.Do(func() { close(a.stop) })
+}
+
+// Wait blocks until the alarm fires or is stopped
+func (a *Alarm) Wait() (AlarmEvent, error) {
+	select {
+	case e := <-a.C:
+		return e, e.Err
+	case <-a.stop:
+		return AlarmEvent{Scheduled: a.at}, ErrAlarmStopped
+	}
+}
+
+func (a *Alarm) run(c chan<- AlarmEvent) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-timer.C:
+		}
+		m, err := a.dev.OffsetFromSystem()
+		if err != nil {
+			c <- AlarmEvent{Scheduled: a.at, Err: err}
+			return
+		}
+		// offset is system time minus PHC time
+		phcNow := time.Now().Add(-m.Offset)
+		left := a.at.Sub(phcNow)
+		if left <= 0 {
+			c <- AlarmEvent{Scheduled: a.at, PHCTime: phcNow}
+			return
+		}
+		timer.Reset(min(left, alarmResyncInterval))
+	}
+}
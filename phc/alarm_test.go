@@ -0,0 +1,65 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlarmFires(t *testing.T) {
+	dev := NewSimDevice("/dev/ptp0", SimConfig{Start: time.Now().Add(37 * time.Second)})
+	alarm, err := AfterPHC(dev, 20*time.Millisecond)
+	require.NoError(t, err)
+	e, err := alarm.Wait()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, e.Late(), time.Duration(0))
+	require.Less(t, e.Late(), 50*time.Millisecond)
+}
+
+func TestAlarmPast(t *testing.T) {
+	dev := NewSimDevice("/dev/ptp0", SimConfig{})
+	alarm := NewAlarm(dev, time.Unix(1667818190, 0))
+	e, err := alarm.Wait()
+	require.NoError(t, err)
+	require.Greater(t, e.Late(), time.Duration(0))
+}
+
+func TestAlarmStop(t *testing.T) {
+	dev := NewSimDevice("/dev/ptp0", SimConfig{})
+	alarm, err := AfterPHC(dev, time.Hour)
+	require.NoError(t, err)
+	alarm.Stop()
+	alarm.Stop()
+	_, err = alarm.Wait()
+	require.ErrorIs(t, err, ErrAlarmStopped)
+}
+
+func TestAlarmReadError(t *testing.T) {
+	dev := NewSimDevice("/dev/ptp0", SimConfig{})
+	readErr := errors.New("read failed")
+	dev.SetError(SimOpSysoff, readErr)
+	_, err := AfterPHC(dev, time.Hour)
+	require.ErrorIs(t, err, readErr)
+
+	alarm := NewAlarm(dev, time.Now().Add(time.Hour))
+	_, err = alarm.Wait()
+	require.ErrorIs(t, err, readErr)
+}
@@ -67,6 +67,27 @@ func (dev *Device) ReadSysoffPrecise() (*PTPSysOffsetPrecise, error) {
 	return dev.readSysoffPrecise()
 }
 
+// ReadSysoff reads the PHC time along with SYS time using the basic PTP_SYS_OFFSET ioctl.
+// The nsamples parameter is set to ExtendedNumProbes.
+func (dev *Device) ReadSysoff() (*PTPSysOffset, error) {
+	return dev.readSysoff(ExtendedNumProbes)
+}
+
+// ReadSysoff1 reads the PHC time along with SYS time using the basic PTP_SYS_OFFSET ioctl.
+// The samples parameter is set to 1.
+func (dev *Device) ReadSysoff1() (*PTPSysOffset, error) {
+	return dev.readSysoff(1)
+}
+
+func (dev *Device) readSysoff(samples uint) (*PTPSysOffset, error) {
+	value, err := unix.IoctlPtpSysOffset(int(dev.Fd()), samples)
+	if err != nil {
+		return nil, err
+	}
+	our := PTPSysOffset(*value)
+	return &our, nil
+}
+
 func (dev *Device) readSysoffExtended(samples uint) (*PTPSysOffsetExtended, error) {
 	value, err := unix.IoctlPtpSysOffsetExtended(int(dev.Fd()), samples)
 	if err != nil {
@@ -90,6 +111,42 @@ func (dev *Device) readCaps() (*PtpClockCaps, error) {
 	return unix.IoctlPtpClockGetcaps(int(dev.Fd()))
 }
 
+// Caps is a typed representation of PTP_CLOCK_GETCAPS result
+type Caps struct {
+	MaxAdjPPB         float64
+	NumAlarms         int
+	NumExtTS          int
+	NumPerOut         int
+	PPS               bool
+	NumPins           int
+	CrossTimestamping bool
+	AdjustPhase       bool
+	MaxPhaseAdj       time.Duration
+}
+
+func capsFromRaw(raw *PtpClockCaps) *Caps {
+	return &Caps{
+		MaxAdjPPB:         maxAdj(raw),
+		NumAlarms:         int(raw.N_alarm),
+		NumExtTS:          int(raw.N_ext_ts),
+		NumPerOut:         int(raw.N_per_out),
+		PPS:               raw.Pps != 0,
+		NumPins:           int(raw.N_pins),
+		CrossTimestamping: raw.Cross_timestamping != 0,
+		AdjustPhase:       raw.Adjust_phase != 0,
+		MaxPhaseAdj:       time.Duration(raw.Max_phase_adj),
+	}
+}
+
+// Caps reads PTP capabilities of the device so callers can feature-detect before using them
+func (dev *Device) Caps() (*Caps, error) {
+	raw, err := dev.readCaps()
+	if err != nil {
+		return nil, fmt.Errorf("%s: ioctl(PTP_CLOCK_GETCAPS) failed: %w", dev.File().Name(), err)
+	}
+	return capsFromRaw(raw), nil
+}
+
 // setPinFunc sets the function on a single PTP pin descriptor
 func (dev *Device) setPinFunc(index uint, pf int, ch uint) error {
 	raw := unix.PtpPinDesc{
@@ -119,6 +176,14 @@ func maxAdj(caps *PtpClockCaps) float64 {
 	return float64(caps.Max_adj)
 }
 
+// EnablePPS enables or disables PPS events delivery from the PHC to the kernel PPS subsystem
+func (dev *Device) EnablePPS(enable bool) error {
+	if err := unix.IoctlPtpEnablePPS(int(dev.Fd()), enable); err != nil {
+		return fmt.Errorf("%s: ioctl(PTP_ENABLE_PPS) failed: %w", dev.File().Name(), err)
+	}
+	return nil
+}
+
 func (dev *Device) setPTPPerout(req *PtpPeroutRequest) error {
 	return unix.IoctlPtpPeroutRequest(int(dev.Fd()), req)
 }
@@ -136,6 +201,9 @@ func (dev *Device) AdjFreq(freqPPB float64) error { return clockAdjFreq(dev, fre
 // Step steps the PHC clock by given duration
 func (dev *Device) Step(step time.Duration) error { return clockStep(dev, step) }
 
+// AdjPhase adjusts the PHC clock phase by given offset, see Caps().AdjustPhase
+func (dev *Device) AdjPhase(offset time.Duration) error { return clockAdjPhase(dev, offset) }
+
 // SetTime sets the time of the PHC clock
 func (dev *Device) SetTime(t time.Time) error { return clockSetTime(dev, t) }
 
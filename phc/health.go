@@ -0,0 +1,231 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// HealthEventType is a type of problem found by the HealthMonitor
+type HealthEventType int
+
+// Health problems we detect
+const (
+	// HealthReadError means the PHC can't be read
+	HealthReadError HealthEventType = iota
+	// HealthStuck means PHC time doesn't advance while system time does
+	HealthStuck
+	// HealthJump means offset changed by more than JumpThreshold between samples
+	HealthJump
+	// HealthFreqRunaway means the PHC drifts faster than MaxDriftPPB
+	HealthFreqRunaway
+)
+
+var healthEventTypeToString = map[HealthEventType]string{
+	HealthReadError:   "read_error",
+	HealthStuck:       "stuck",
+	HealthJump:        "jump",
+	HealthFreqRunaway: "freq_runaway",
+}
+
+func (t HealthEventType) String() string {
+	if s, ok := healthEventTypeToString[t]; ok {
+		return s
+	}
+	return "unsupported"
+}
+
+// HealthEvent describes a problem found by the HealthMonitor
+type HealthEvent struct {
+	Type HealthEventType
+	// Reference is "sys" for the system clock or a peer PHC name
+	Reference string
+	Time      time.Time
+	// Offset is the offset change between samples, or the last offset for stuck clock
+	Offset   time.Duration
+	DriftPPB float64
+	Err      error
+}
+
+func (e HealthEvent) String() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s vs %s: %v", e.Type, e.Reference, e.Err)
+	}
+	return fmt.Sprintf("%s vs %s: offset change %v, drift %.0f PPB", e.Type, e.Reference, e.Offset, e.DriftPPB)
+}
+
+// HealthConfig configures the HealthMonitor
+type HealthConfig struct {
+	Interval      time.Duration
+	JumpThreshold time.Duration
+	MaxDriftPPB   float64
+}
+
+// DefaultHealthConfig returns HealthConfig with sane defaults
+func DefaultHealthConfig() *HealthConfig {
+	return &HealthConfig{
+		Interval:      time.Second,
+		JumpThreshold: time.Millisecond,
+		MaxDriftPPB:   100000,
+	}
+}
+
+// HealthTarget is a PHC we can monitor
+type HealthTarget interface {
+	OffsetMeasurer
+	SysoffReader
+}
+
+// HealthStats is a snapshot of HealthMonitor counters
+type HealthStats struct {
+	Samples    int64            `json:"samples"`
+	LastOffset time.Duration    `json:"last_offset"`
+	Events     map[string]int64 `json:"events"`
+}
+
+type healthSample struct {
+	sysTime time.Time
+	phcTime time.Time
+	offset  time.Duration
+}
+
+type healthPeer struct {
+	name string
+	dev  SysoffReader
+	last *healthSample
+}
+
+// HealthMonitor samples a PHC against the system clock and other PHCs
+// looking for stuck clocks, jumps and frequency runaway.
+// We've seen NIC firmware wedge PHC silently.
+type HealthMonitor struct {
+	cfg     *HealthConfig
+	dev     HealthTarget
+	peers   []*healthPeer
+	handler func(HealthEvent)
+	last    *healthSample
+
+	sync.Mutex
+	stats HealthStats
+}
+
+// NewHealthMonitor creates a HealthMonitor for the device. handler is called for every problem found.
+func NewHealthMonitor(cfg *HealthConfig, dev HealthTarget, handler func(HealthEvent)) *HealthMonitor {
+	return &HealthMonitor{
+		cfg:     cfg,
+		dev:     dev,
+		handler: handler,
+		stats:   HealthStats{Events: map[string]int64{}},
+	}
+}
+
+// AddPeer adds another PHC to compare the monitored PHC against
+func (m *HealthMonitor) AddPeer(name string, peer SysoffReader) {
+	m.peers = append(m.peers, &healthPeer{name: name, dev: peer})
+}
+
+func (m *HealthMonitor) emit(e HealthEvent) {
+	m.Lock()
+	m.stats.Events[e.Type.String()]++
+	m.Unlock()
+	if m.handler != nil {
+		m.handler(e)
+	}
+}
+
+// compare checks two consecutive samples against the same reference
+func (m *HealthMonitor) compare(reference string, prev, cur *healthSample) {
+	sysElapsed := cur.sysTime.Sub(prev.sysTime)
+	if sysElapsed <= 0 {
+		return
+	}
+	if !cur.phcTime.After(prev.phcTime) {
+		m.emit(HealthEvent{Type: HealthStuck, Reference: reference, Time: cur.sysTime, Offset: cur.offset})
+		return
+	}
+	delta := cur.offset - prev.offset
+	drift := float64(delta) / float64(sysElapsed) * 1e9
+	if m.cfg.JumpThreshold > 0 && delta.Abs() > m.cfg.JumpThreshold {
+		m.emit(HealthEvent{Type: HealthJump, Reference: reference, Time: cur.sysTime, Offset: delta, DriftPPB: drift})
+		return
+	}
+	if m.cfg.MaxDriftPPB > 0 && (drift > m.cfg.MaxDriftPPB || drift < -m.cfg.MaxDriftPPB) {
+		m.emit(HealthEvent{Type: HealthFreqRunaway, Reference: reference, Time: cur.sysTime, Offset: delta, DriftPPB: drift})
+	}
+}
+
+// Check takes a single sample and reports problems found
+func (m *HealthMonitor) Check() {
+	res, err := m.dev.OffsetFromSystem()
+	if err != nil {
+		m.emit(HealthEvent{Type: HealthReadError, Reference: "sys", Time: time.Now(), Err: err})
+		m.last = nil
+	} else {
+		cur := &healthSample{sysTime: res.SysTime, phcTime: res.PHCTime, offset: res.Offset}
+		if m.last != nil {
+			m.compare("sys", m.last, cur)
+		}
+		m.last = cur
+		m.Lock()
+		m.stats.Samples++
+		m.stats.LastOffset = res.Offset
+		m.Unlock()
+	}
+
+	for _, peer := range m.peers {
+		offset, peerSysoff, _, err := measurePair(m.dev, peer.dev)
+		if err != nil {
+			m.emit(HealthEvent{Type: HealthReadError, Reference: peer.name, Time: time.Now(), Err: err})
+			peer.last = nil
+			continue
+		}
+		// peer PHC time is compared as is, PHC time of the monitored clock is derived from the offset
+		cur := &healthSample{sysTime: peerSysoff.SysTime, phcTime: peerSysoff.PHCTime.Add(-offset), offset: offset}
+		if peer.last != nil {
+			m.compare(peer.name, peer.last, cur)
+		}
+		peer.last = cur
+	}
+}
+
+// Stats returns HealthMonitor counters
+func (m *HealthMonitor) Stats() HealthStats {
+	m.Lock()
+	defer m.Unlock()
+	events := make(map[string]int64, len(m.stats.Events))
+	for k, v := range m.stats.Events {
+		events[k] = v
+	}
+	return HealthStats{Samples: m.stats.Samples, LastOffset: m.stats.LastOffset, Events: events}
+}
+
+// Run checks the device every Interval until the context is cancelled
+func (m *HealthMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		m.Check()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
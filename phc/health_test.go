@@ -0,0 +1,120 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHealthTarget struct {
+	fakeSyncTarget
+	sys     time.Time
+	phcTime time.Time
+	err     error
+}
+
+func (f *fakeHealthTarget) OffsetFromSystem() (SysoffMeasurement, error) {
+	if f.err != nil {
+		return SysoffMeasurement{}, f.err
+	}
+	return SysoffMeasurement{SysoffResult: SysoffResult{SysTime: f.sys, PHCTime: f.phcTime, Offset: f.sys.Sub(f.phcTime)}}, nil
+}
+
+func (f *fakeHealthTarget) advance(sys, phcTime time.Duration) {
+	f.sys = f.sys.Add(sys)
+	f.phcTime = f.phcTime.Add(phcTime)
+	f.extended = extendedAt(f.sys, f.phcTime)
+}
+
+func TestHealthEventTypeString(t *testing.T) {
+	require.Equal(t, "stuck", HealthStuck.String())
+	require.Equal(t, "unsupported", HealthEventType(42).String())
+}
+
+func TestHealthMonitorCheck(t *testing.T) {
+	start := time.Unix(1667818190, 0)
+	dev := &fakeHealthTarget{sys: start, phcTime: start}
+	events := []HealthEvent{}
+	m := NewHealthMonitor(DefaultHealthConfig(), dev, func(e HealthEvent) { events = append(events, e) })
+
+	// healthy clock drifting by 1ppm
+	m.Check()
+	dev.advance(time.Second, time.Second+time.Microsecond)
+	m.Check()
+	require.Empty(t, events)
+
+	// stuck clock
+	dev.advance(time.Second, 0)
+	m.Check()
+	require.Len(t, events, 1)
+	require.Equal(t, HealthStuck, events[0].Type)
+	require.Equal(t, "sys", events[0].Reference)
+
+	// large jump
+	dev.advance(time.Second, time.Second+10*time.Millisecond)
+	m.Check()
+	require.Len(t, events, 2)
+	require.Equal(t, HealthJump, events[1].Type)
+	require.Equal(t, -10*time.Millisecond, events[1].Offset)
+
+	// frequency runaway: 500us over 1s is 500000 PPB
+	dev.advance(time.Second, time.Second+500*time.Microsecond)
+	m.Check()
+	require.Len(t, events, 3)
+	require.Equal(t, HealthFreqRunaway, events[2].Type)
+	require.InDelta(t, -500000.0, events[2].DriftPPB, 0.001)
+
+	// read error
+	dev.err = fmt.Errorf("ioctl failed")
+	m.Check()
+	require.Len(t, events, 4)
+	require.Equal(t, HealthReadError, events[3].Type)
+
+	st := m.Stats()
+	require.Equal(t, int64(5), st.Samples)
+	require.Equal(t, map[string]int64{"stuck": 1, "jump": 1, "freq_runaway": 1, "read_error": 1}, st.Events)
+}
+
+func TestHealthMonitorPeer(t *testing.T) {
+	start := time.Unix(1667818190, 0)
+	dev := &fakeHealthTarget{sys: start, phcTime: start}
+	dev.advance(0, 0)
+	peer := &fakeHealthTarget{sys: start, phcTime: start}
+	peer.advance(0, 0)
+	events := []HealthEvent{}
+	m := NewHealthMonitor(DefaultHealthConfig(), dev, func(e HealthEvent) { events = append(events, e) })
+	m.AddPeer("ptp1", peer)
+
+	m.Check()
+	dev.advance(time.Second, time.Second)
+	peer.advance(time.Second, time.Second)
+	m.Check()
+	require.Empty(t, events)
+
+	// peer PHC jumps
+	dev.advance(time.Second, time.Second)
+	peer.advance(time.Second, time.Second+5*time.Millisecond)
+	m.Check()
+	require.Len(t, events, 1)
+	require.Equal(t, HealthJump, events[0].Type)
+	require.Equal(t, "ptp1", events[0].Reference)
+	require.Equal(t, 5*time.Millisecond, events[0].Offset)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/facebook/time/phc/unix" // a temporary shim for "golang.org/x/sys/unix" until v0.27.0 is cut
+)
+
+// Info describes a single PHC device found on the host
+type Info struct {
+	Device     string
+	Index      int
+	ClockName  string
+	Driver     string
+	Interfaces []string
+	// Vclocks lists virtual clocks created on top of this PHC
+	Vclocks []string
+	// Caps is nil if the device can't be opened
+	Caps *Caps
+}
+
+// Inventory lists all PHC devices on the host along with their driver,
+// network interfaces and capabilities
+func Inventory() ([]*Info, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket for ioctl: %w", err)
+	}
+	defer unix.Close(fd)
+
+	phcIfaces := map[int][]string{}
+	for _, iface := range ifaces {
+		info, err := unix.IoctlGetEthtoolTsInfo(fd, iface.Name)
+		if err != nil || info.Phc_index < 0 {
+			continue
+		}
+		index := int(info.Phc_index)
+		phcIfaces[index] = append(phcIfaces[index], iface.Name)
+	}
+	return inventory(phcIfaces)
+}
+
+// inventory builds PHC list from sysfs, using the given map of PHC index to network interfaces
+func inventory(phcIfaces map[int][]string) ([]*Info, error) {
+	entries, err := os.ReadDir(ptpSysfsPath)
+	if err != nil {
+		return nil, fmt.Errorf("listing PHC devices: %w", err)
+	}
+	result := []*Info{}
+	for _, e := range entries {
+		name := e.Name()
+		index, err := strconv.Atoi(strings.TrimPrefix(name, "ptp"))
+		if err != nil || !strings.HasPrefix(name, "ptp") {
+			continue
+		}
+		info := &Info{
+			Device:     fmt.Sprintf("/dev/%s", name),
+			Index:      index,
+			Interfaces: phcIfaces[index],
+		}
+		sort.Strings(info.Interfaces)
+		if clockName, err := os.ReadFile(filepath.Join(ptpSysfsPath, name, "clock_name")); err == nil {
+			info.ClockName = strings.TrimSpace(string(clockName))
+		}
+		if driver, err := filepath.EvalSymlinks(filepath.Join(ptpSysfsPath, name, "device", "driver")); err == nil {
+			info.Driver = filepath.Base(driver)
+		}
+		if vclocks, err := vclocksByName(name); err == nil && len(vclocks) > 0 {
+			info.Vclocks = vclocks
+		}
+		if f, err := os.Open(info.Device); err == nil {
+			if caps, err := FromFile(f).Caps(); err == nil {
+				info.Caps = caps
+			}
+			f.Close()
+		}
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Index < result[j].Index })
+	return result, nil
+}
@@ -0,0 +1,59 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInventory(t *testing.T) {
+	dir := t.TempDir()
+	ptpSysfsPath = filepath.Join(dir, "class", "ptp")
+	defer func() { ptpSysfsPath = "/sys/class/ptp" }()
+
+	driver := filepath.Join(dir, "bus", "pci", "drivers", "mlx5_core")
+	require.NoError(t, os.MkdirAll(driver, 0755))
+	devices := map[string]string{
+		"ptp10": "ptp_ocp",
+		"ptp2":  "mlx5_ptp",
+		"ptp3":  "ptp virtual clock",
+	}
+	for name, clockName := range devices {
+		require.NoError(t, os.MkdirAll(filepath.Join(ptpSysfsPath, name, "device"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(ptpSysfsPath, name, "clock_name"), []byte(clockName+"\n"), 0644))
+	}
+	require.NoError(t, os.Symlink(driver, filepath.Join(ptpSysfsPath, "ptp2", "device", "driver")))
+	require.NoError(t, os.MkdirAll(filepath.Join(ptpSysfsPath, "ptp2", "ptp3"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(ptpSysfsPath, "not-a-ptp"), 0755))
+
+	got, err := inventory(map[int][]string{2: {"eth1", "eth0"}})
+	require.NoError(t, err)
+	want := []*Info{
+		{Device: "/dev/ptp2", Index: 2, ClockName: "mlx5_ptp", Driver: "mlx5_core", Interfaces: []string{"eth0", "eth1"}, Vclocks: []string{"/dev/ptp3"}},
+		{Device: "/dev/ptp3", Index: 3, ClockName: "ptp virtual clock"},
+		{Device: "/dev/ptp10", Index: 10, ClockName: "ptp_ocp"},
+	}
+	// capabilities depend on the host we run on, ignore them
+	for _, info := range got {
+		info.Caps = nil
+	}
+	require.Equal(t, want, got)
+}
@@ -32,6 +32,9 @@ const (
 // PTPSysOffsetExtended wraps unix.PtpSysOffsetExtended to add methods
 type PTPSysOffsetExtended unix.PtpSysOffsetExtended
 
+// PTPSysOffset wraps unix.PtpSysOffset to add methods
+type PTPSysOffset unix.PtpSysOffset
+
 // PTPSysOffsetPrecise wraps unix.PtpSysOffsetPrecise to add methods
 type PTPSysOffsetPrecise unix.PtpSysOffsetPrecise
 
@@ -98,6 +101,97 @@ func (extended *PTPSysOffsetExtended) BestSample() SysoffResult {
 	return best
 }
 
+// BestSample finds a sample which took the least time to be read.
+// Basic PTP_SYS_OFFSET returns interleaved sys and PHC timestamps: sys, phc, sys, ..., phc, sys
+func (basic *PTPSysOffset) BestSample() SysoffResult {
+	sample := func(i int) [3]PtpClockTime {
+		return [3]PtpClockTime{basic.Ts[2*i], basic.Ts[2*i+1], basic.Ts[2*i+2]}
+	}
+	best := sysoffFromExtendedTS(sample(0))
+	for i := 1; i < int(basic.Samples); i++ {
+		sysoff := sysoffFromExtendedTS(sample(i))
+		if sysoff.Delay < best.Delay {
+			best = sysoff
+		}
+	}
+	return best
+}
+
+// SysoffMeasurement is a SysoffResult along with the method used to get it
+// and the estimated uncertainty of the offset
+type SysoffMeasurement struct {
+	SysoffResult
+	Method      TimeMethod
+	Uncertainty time.Duration
+}
+
+// OffsetFromSystem measures the offset between PHC and system clock using the best method the device supports.
+// It prefers PTP_SYS_OFFSET_PRECISE, falls back to PTP_SYS_OFFSET_EXTENDED and then to PTP_SYS_OFFSET.
+// Uncertainty is half of the read delay, which is 0 for precise cross timestamps.
+func (dev *Device) OffsetFromSystem() (SysoffMeasurement, error) {
+	precise, err := dev.ReadSysoffPrecise()
+	if err == nil {
+		return SysoffMeasurement{SysoffResult: SysoffFromPrecise(precise), Method: MethodIoctlSysOffsetPrecise}, nil
+	}
+	extended, err := dev.ReadSysoffExtended()
+	if err == nil {
+		best := extended.BestSample()
+		return SysoffMeasurement{SysoffResult: best, Method: MethodIoctlSysOffsetExtended, Uncertainty: best.Delay / 2}, nil
+	}
+	basic, err := dev.ReadSysoff()
+	if err != nil {
+		return SysoffMeasurement{}, fmt.Errorf("%s: no PTP_SYS_OFFSET method is supported: %w", dev.File().Name(), err)
+	}
+	best := basic.BestSample()
+	return SysoffMeasurement{SysoffResult: best, Method: MethodIoctlSysOffset, Uncertainty: best.Delay / 2}, nil
+}
+
+// OffsetFromSystem opens the PHC device and measures its offset from the system clock using the best available method
+func OffsetFromSystem(device string) (SysoffMeasurement, error) {
+	f, err := os.Open(device)
+	if err != nil {
+		return SysoffMeasurement{}, err
+	}
+	defer f.Close()
+	return FromFile(f).OffsetFromSystem()
+}
+
+// ReadWithUncertainty reads PHC time with clock_gettime bracketed by system clock reads up to attempts times,
+// and returns the sample with the smallest system clock read window along with the window size.
+// It stops early once the window is within maxWindow; if none is, the best sample is returned along with an error.
+// maxWindow of 0 means all attempts are performed.
+func (dev *Device) ReadWithUncertainty(attempts int, maxWindow time.Duration) (SysoffResult, time.Duration, error) {
+	return readWithUncertainty(attempts, maxWindow, dev.Time, time.Now)
+}
+
+func readWithUncertainty(attempts int, maxWindow time.Duration, read func() (time.Time, error), now func() time.Time) (SysoffResult, time.Duration, error) {
+	if attempts < 1 {
+		return SysoffResult{}, 0, fmt.Errorf("number of attempts %d must be positive", attempts)
+	}
+	var best SysoffResult
+	found := false
+	for i := 0; i < attempts; i++ {
+		ts1 := now()
+		phcTime, err := read()
+		ts2 := now()
+		if err != nil {
+			return SysoffResult{}, 0, err
+		}
+		sample := SysoffEstimateBasic(ts1, phcTime, ts2)
+		if !found || sample.Delay < best.Delay {
+			best = sample
+			found = true
+		}
+		if maxWindow > 0 && best.Delay <= maxWindow {
+			return best, best.Delay, nil
+		}
+	}
+	if maxWindow > 0 {
+		return best, best.Delay, fmt.Errorf("smallest read window %v after %d attempts exceeds %v", best.Delay, attempts, maxWindow)
+	}
+	return best, best.Delay, nil
+}
+
 // TimeAndOffset returns time we got from network card + offset
 func TimeAndOffset(iface string, method TimeMethod) (SysoffResult, error) {
 	device, err := IfaceToPHCDevice(iface)
@@ -139,6 +233,12 @@ func TimeAndOffsetFromDevice(device string, method TimeMethod) (SysoffResult, er
 			return SysoffResult{}, err
 		}
 		return SysoffFromPrecise(precise), nil
+	case MethodIoctlSysOffset:
+		basic, err := dev.ReadSysoff()
+		if err != nil {
+			return SysoffResult{}, err
+		}
+		return basic.BestSample(), nil
 	}
 	return SysoffResult{}, fmt.Errorf("unknown method to get PHC time %q", method)
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package phc
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -57,6 +58,34 @@ func TestSysoffBestSample(t *testing.T) {
 	require.Equal(t, want, got)
 }
 
+func TestSysoffBasicBestSample(t *testing.T) {
+	basic := &PTPSysOffset{
+		Samples: 3,
+		Ts: [51]PtpClockTime{
+			{Sec: 1667818190, Nsec: 552297411},
+			{Sec: 1667818153, Nsec: 552297462},
+			{Sec: 1667818190, Nsec: 552297522},
+			{Sec: 1667818153, Nsec: 552297582},
+			{Sec: 1667818190, Nsec: 552297622},
+			{Sec: 1667818153, Nsec: 552297661},
+			{Sec: 1667818190, Nsec: 552297722},
+		},
+	}
+	got := basic.BestSample()
+	want := SysoffResult{
+		SysTime: time.Unix(0, 1667818190552297572),
+		PHCTime: time.Unix(0, 1667818153552297582),
+		Delay:   time.Duration(100),
+		Offset:  time.Duration(36999999990),
+	}
+	require.Equal(t, want, got)
+}
+
+func TestOffsetFromSystemNotPHC(t *testing.T) {
+	_, err := OffsetFromSystem("/dev/null")
+	require.Error(t, err)
+}
+
 func TestSysoffFromExtendedTS(t *testing.T) {
 	extendedTS := [3]PtpClockTime{
 		{Sec: 1667818190, Nsec: 552297411},
@@ -130,3 +159,43 @@ func TestOffsetBetweenExtendedReadings(t *testing.T) {
 	offset := extendedB.Sub(extendedA)
 	require.Equal(t, time.Duration(-815), offset)
 }
+
+func TestReadWithUncertainty(t *testing.T) {
+	sys := time.Unix(1667818190, 0)
+	windows := []time.Duration{300, 100, 200}
+	calls := 0
+	now := func() time.Time {
+		// every read is bracketed by 2 calls
+		if calls%2 == 1 {
+			sys = sys.Add(windows[(calls/2)%len(windows)])
+		}
+		calls++
+		return sys
+	}
+	read := func() (time.Time, error) { return sys.Add(-37 * time.Second), nil }
+
+	got, window, err := readWithUncertainty(3, 0, read, now)
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(100), window)
+	require.Equal(t, time.Duration(100), got.Delay)
+	require.Equal(t, 37*time.Second+50, got.Offset)
+	require.Equal(t, 6, calls)
+
+	// stops as soon as the window is good enough
+	calls = 0
+	_, window, err = readWithUncertainty(3, 150, read, now)
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(100), window)
+	require.Equal(t, 4, calls)
+
+	calls = 0
+	_, window, err = readWithUncertainty(3, 50, read, now)
+	require.Error(t, err)
+	require.Equal(t, time.Duration(100), window)
+
+	_, _, err = readWithUncertainty(0, 0, read, now)
+	require.Error(t, err)
+
+	_, _, err = readWithUncertainty(3, 0, func() (time.Time, error) { return time.Time{}, fmt.Errorf("nope") }, now)
+	require.Error(t, err)
+}
@@ -35,6 +35,7 @@ const (
 	MethodSyscallClockGettime    TimeMethod = "syscall_clock_gettime"
 	MethodIoctlSysOffsetExtended TimeMethod = "ioctl_PTP_SYS_OFFSET_EXTENDED"
 	MethodIoctlSysOffsetPrecise  TimeMethod = "ioctl_PTP_SYS_OFFSET_PRECISE"
+	MethodIoctlSysOffset         TimeMethod = "ioctl_PTP_SYS_OFFSET"
 )
 
 type (
@@ -99,6 +100,13 @@ func Time(iface string, method TimeMethod) (time.Time, error) {
 		}
 		tp := precise.Device
 		return time.Unix(tp.Sec, int64(tp.Nsec)), nil
+	case MethodIoctlSysOffset:
+		basic, err := dev.ReadSysoff1()
+		if err != nil {
+			return time.Time{}, err
+		}
+		tp := basic.Ts[1]
+		return time.Unix(tp.Sec, int64(tp.Nsec)), nil
 	default:
 		return time.Time{}, fmt.Errorf("unknown method to get PHC time %q", method)
 	}
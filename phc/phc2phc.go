@@ -0,0 +1,213 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/facebook/time/servo"
+)
+
+// SysoffReader is a PHC which can be read along with the system clock
+type SysoffReader interface {
+	ReadSysoffPrecise() (*PTPSysOffsetPrecise, error)
+	ReadSysoffExtended() (*PTPSysOffsetExtended, error)
+}
+
+// SyncTarget is a PHC which can be read and disciplined
+type SyncTarget interface {
+	SysoffReader
+	FrequencyGetter
+	AdjFreq(freqPPB float64) error
+	Step(step time.Duration) error
+}
+
+// PhaseAdjuster is a PHC supporting phase adjustments (ADJ_OFFSET)
+type PhaseAdjuster interface {
+	Caps() (*Caps, error)
+	AdjPhase(offset time.Duration) error
+}
+
+// PairStats is a snapshot of synchronization state of a single PHC pair
+type PairStats struct {
+	Offset     time.Duration `json:"offset"`
+	Delay      time.Duration `json:"delay"`
+	FreqPPB    float64       `json:"freq"`
+	ServoState string        `json:"servo_state"`
+	Samples    int64         `json:"samples"`
+	Steps      int64         `json:"steps"`
+	Errors     int64         `json:"errors"`
+}
+
+// SyncPair keeps destination PHC synchronized to the source PHC
+type SyncPair struct {
+	Name   string
+	src    SysoffReader
+	dst    SyncTarget
+	pi     ServoController
+	offset time.Duration
+	// phase is set when dst supports phase adjustments
+	phase       PhaseAdjuster
+	maxPhaseAdj time.Duration
+
+	sync.Mutex
+	stats PairStats
+}
+
+// NewSyncPair creates a SyncPair with a PI servo configured for the destination device.
+// offset is a static offset of the destination from the source, the servo keeps dst = src + offset,
+// like 37s for destination PHC in TAI and source PHC in UTC.
+func NewSyncPair(name string, src SysoffReader, dst SyncTarget, interval, firstStepth, offset time.Duration) (*SyncPair, error) {
+	pi, err := NewPiServo(interval, firstStepth, 0, dst, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: creating servo: %w", name, err)
+	}
+	p := &SyncPair{Name: name, src: src, dst: dst, pi: pi, offset: offset}
+	// prefer servo-in-hardware phase adjustments once the servo is locked
+	if phase, ok := dst.(PhaseAdjuster); ok {
+		if caps, err := phase.Caps(); err == nil && caps.AdjustPhase {
+			p.phase = phase
+			p.maxPhaseAdj = caps.MaxPhaseAdj
+		}
+	}
+	return p, nil
+}
+
+// measurePair returns offset of dst from src, using PTP_SYS_OFFSET_PRECISE if both devices support it
+func measurePair(src, dst SysoffReader) (offset time.Duration, dstSysoff SysoffResult, delay time.Duration, err error) {
+	if preciseSrc, err := src.ReadSysoffPrecise(); err == nil {
+		preciseDst, err := dst.ReadSysoffPrecise()
+		if err == nil {
+			return preciseDst.Sub(preciseSrc), SysoffFromPrecise(preciseDst), 0, nil
+		}
+	}
+	extendedSrc, err := src.ReadSysoffExtended()
+	if err != nil {
+		return 0, SysoffResult{}, 0, fmt.Errorf("reading source: %w", err)
+	}
+	extendedDst, err := dst.ReadSysoffExtended()
+	if err != nil {
+		return 0, SysoffResult{}, 0, fmt.Errorf("reading destination: %w", err)
+	}
+	dstSysoff = extendedDst.BestSample()
+	return extendedDst.Sub(extendedSrc), dstSysoff, extendedSrc.BestSample().Delay + dstSysoff.Delay, nil
+}
+
+// Sync performs a single synchronization iteration
+func (p *SyncPair) Sync() error {
+	err := p.sync()
+	if err != nil {
+		p.Lock()
+		p.stats.Errors++
+		p.Unlock()
+	}
+	return err
+}
+
+func (p *SyncPair) sync() error {
+	phcOffset, dstSysoff, delay, err := measurePair(p.src, p.dst)
+	if err != nil {
+		return fmt.Errorf("%s: %w", p.Name, err)
+	}
+	phcOffset -= p.offset
+	freqAdj, state := p.pi.Sample(int64(phcOffset), uint64(dstSysoff.SysTime.UnixNano())) // unix nano is never negative
+
+	p.Lock()
+	p.stats.Offset = phcOffset
+	p.stats.Delay = delay
+	p.stats.FreqPPB = freqAdj
+	p.stats.ServoState = state.String()
+	p.stats.Samples++
+	p.Unlock()
+
+	switch state {
+	case servo.StateJump:
+		if err := p.dst.AdjFreq(-freqAdj); err != nil {
+			return fmt.Errorf("%s: failed to adjust freq to %v: %w", p.Name, -freqAdj, err)
+		}
+		if err := p.dst.Step(-phcOffset); err != nil {
+			p.pi.Unlock()
+			return fmt.Errorf("%s: failed to step clock by %v: %w", p.Name, -phcOffset, err)
+		}
+		p.Lock()
+		p.stats.Steps++
+		p.Unlock()
+	case servo.StateLocked:
+		if p.phase != nil && (p.maxPhaseAdj == 0 || phcOffset.Abs() <= p.maxPhaseAdj) {
+			if err := p.phase.AdjPhase(-phcOffset); err != nil {
+				return fmt.Errorf("%s: failed to adjust phase by %v: %w", p.Name, -phcOffset, err)
+			}
+			return nil
+		}
+		if err := p.dst.AdjFreq(-freqAdj); err != nil {
+			p.pi.Unlock()
+			return fmt.Errorf("%s: failed to adjust freq to %v: %w", p.Name, -freqAdj, err)
+		}
+	case servo.StateInit:
+		return nil
+	default:
+		return fmt.Errorf("%s: skipping clock update: servo state is %v", p.Name, state)
+	}
+	return nil
+}
+
+// Stats returns current synchronization stats of the pair
+func (p *SyncPair) Stats() PairStats {
+	p.Lock()
+	defer p.Unlock()
+	return p.stats
+}
+
+// PHCSyncer keeps several destination PHCs synchronized to one source PHC
+type PHCSyncer struct {
+	Interval time.Duration
+	Pairs    []*SyncPair
+}
+
+// Run synchronizes all pairs every Interval until the context is cancelled
+func (s *PHCSyncer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		for _, p := range s.Pairs {
+			if err := p.Sync(); err != nil {
+				log.Printf("sync failed: %v", err)
+				continue
+			}
+			st := p.Stats()
+			log.Printf("%s offset %10d servo %s freq %+7.0f path delay %5d", p.Name, st.Offset.Nanoseconds(), st.ServoState, st.FreqPPB, st.Delay.Nanoseconds())
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stats returns stats of all pairs keyed by pair name
+func (s *PHCSyncer) Stats() map[string]PairStats {
+	result := make(map[string]PairStats, len(s.Pairs))
+	for _, p := range s.Pairs {
+		result[p.Name] = p.Stats()
+	}
+	return result
+}
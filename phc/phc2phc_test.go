@@ -0,0 +1,166 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/facebook/time/servo"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSyncTarget struct {
+	precise  *PTPSysOffsetPrecise
+	extended *PTPSysOffsetExtended
+	freq     float64
+	steps    []time.Duration
+}
+
+func (f *fakeSyncTarget) ReadSysoffPrecise() (*PTPSysOffsetPrecise, error) {
+	if f.precise == nil {
+		return nil, fmt.Errorf("not supported")
+	}
+	return f.precise, nil
+}
+
+func (f *fakeSyncTarget) ReadSysoffExtended() (*PTPSysOffsetExtended, error) {
+	if f.extended == nil {
+		return nil, fmt.Errorf("not supported")
+	}
+	return f.extended, nil
+}
+
+func (f *fakeSyncTarget) MaxFreqAdjPPB() (float64, error) { return DefaultMaxClockFreqPPB, nil }
+func (f *fakeSyncTarget) FreqPPB() (float64, error)       { return f.freq, nil }
+func (f *fakeSyncTarget) AdjFreq(freqPPB float64) error   { f.freq = freqPPB; return nil }
+func (f *fakeSyncTarget) Step(step time.Duration) error {
+	f.steps = append(f.steps, step)
+	return nil
+}
+
+func extendedAt(sys, phcTime time.Time) *PTPSysOffsetExtended {
+	ts := func(t time.Time) PtpClockTime {
+		return PtpClockTime{Sec: t.Unix(), Nsec: uint32(t.Nanosecond())} //#nosec G115
+	}
+	return &PTPSysOffsetExtended{
+		Samples: 1,
+		Ts: [25][3]PtpClockTime{
+			{ts(sys), ts(phcTime), ts(sys.Add(100 * time.Nanosecond))},
+		},
+	}
+}
+
+func TestMeasurePairPrecise(t *testing.T) {
+	src := &fakeSyncTarget{precise: &PTPSysOffsetPrecise{
+		Realtime: PtpClockTime{Sec: 1667818190},
+		Device:   PtpClockTime{Sec: 1667818153},
+	}}
+	dst := &fakeSyncTarget{precise: &PTPSysOffsetPrecise{
+		Realtime: PtpClockTime{Sec: 1667818190, Nsec: 1000},
+		Device:   PtpClockTime{Sec: 1667818153, Nsec: 1500},
+	}}
+	offset, dstSysoff, delay, err := measurePair(src, dst)
+	require.NoError(t, err)
+	require.Equal(t, 500*time.Nanosecond, offset)
+	require.Equal(t, time.Unix(1667818190, 1000), dstSysoff.SysTime)
+	require.Equal(t, time.Duration(0), delay)
+}
+
+func TestMeasurePairExtendedFallback(t *testing.T) {
+	sys := time.Unix(1667818190, 0)
+	// source supports precise, destination doesn't
+	src := &fakeSyncTarget{
+		precise:  &PTPSysOffsetPrecise{},
+		extended: extendedAt(sys, sys.Add(-37*time.Second)),
+	}
+	dst := &fakeSyncTarget{extended: extendedAt(sys, sys.Add(-37*time.Second+200*time.Nanosecond))}
+	offset, _, delay, err := measurePair(src, dst)
+	require.NoError(t, err)
+	require.Equal(t, 200*time.Nanosecond, offset)
+	require.Equal(t, 200*time.Nanosecond, delay)
+
+	_, _, _, err = measurePair(src, &fakeSyncTarget{})
+	require.Error(t, err)
+}
+
+func TestSyncPairSync(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	servoMock := NewMockServoController(ctrl)
+
+	sys := time.Unix(1667818190, 0)
+	src := &fakeSyncTarget{extended: extendedAt(sys, sys)}
+	dst := &fakeSyncTarget{extended: extendedAt(sys, sys.Add(time.Microsecond))}
+	pair := &SyncPair{Name: "ptp0->ptp1", src: src, dst: dst, pi: servoMock}
+
+	servoMock.EXPECT().Sample(int64(time.Microsecond), gomock.Any()).Return(0.0, servo.StateInit)
+	require.NoError(t, pair.Sync())
+	require.Equal(t, PairStats{Offset: time.Microsecond, Delay: 200, ServoState: "INIT", Samples: 1}, pair.Stats())
+
+	servoMock.EXPECT().Sample(int64(time.Microsecond), gomock.Any()).Return(12.0, servo.StateJump)
+	require.NoError(t, pair.Sync())
+	require.Equal(t, -12.0, dst.freq)
+	require.Equal(t, []time.Duration{-time.Microsecond}, dst.steps)
+
+	servoMock.EXPECT().Sample(int64(time.Microsecond), gomock.Any()).Return(10.0, servo.StateLocked)
+	require.NoError(t, pair.Sync())
+	require.Equal(t, -10.0, dst.freq)
+
+	servoMock.EXPECT().Sample(int64(time.Microsecond), gomock.Any()).Return(10.0, servo.StateFilter)
+	require.Error(t, pair.Sync())
+
+	st := pair.Stats()
+	require.Equal(t, int64(4), st.Samples)
+	require.Equal(t, int64(1), st.Steps)
+	require.Equal(t, int64(1), st.Errors)
+
+	syncer := &PHCSyncer{Pairs: []*SyncPair{pair}}
+	require.Equal(t, map[string]PairStats{"ptp0->ptp1": st}, syncer.Stats())
+}
+
+func TestSyncPairSyncOffset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	servoMock := NewMockServoController(ctrl)
+
+	sys := time.Unix(1667818190, 0)
+	src := &fakeSyncTarget{extended: extendedAt(sys, sys)}
+	// destination is 37s ahead of the source and 1us more than requested
+	dst := &fakeSyncTarget{extended: extendedAt(sys, sys.Add(37*time.Second+time.Microsecond))}
+	pair := &SyncPair{Name: "ptp0->ptp1", src: src, dst: dst, pi: servoMock, offset: 37 * time.Second}
+
+	servoMock.EXPECT().Sample(int64(time.Microsecond), gomock.Any()).Return(12.0, servo.StateJump)
+	require.NoError(t, pair.Sync())
+	require.Equal(t, time.Microsecond, pair.Stats().Offset)
+	require.Equal(t, []time.Duration{-time.Microsecond}, dst.steps)
+
+	// destination exactly at source + offset is in sync
+	dst.extended = extendedAt(sys, sys.Add(37*time.Second))
+	servoMock.EXPECT().Sample(int64(0), gomock.Any()).Return(0.0, servo.StateLocked)
+	require.NoError(t, pair.Sync())
+	require.Equal(t, time.Duration(0), pair.Stats().Offset)
+}
+
+func TestNewSyncPair(t *testing.T) {
+	dst := &fakeSyncTarget{freq: 42}
+	pair, err := NewSyncPair("ptp0->ptp1", &fakeSyncTarget{}, dst, time.Second, time.Millisecond, 0)
+	require.NoError(t, err)
+	require.Equal(t, "ptp0->ptp1", pair.Name)
+}
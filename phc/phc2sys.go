@@ -0,0 +1,192 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/facebook/time/clock"
+	"github.com/facebook/time/phc/unix" // a temporary shim for "golang.org/x/sys/unix" until v0.27.0 is cut
+	"github.com/facebook/time/servo"
+)
+
+// OffsetMeasurer is a PHC which can measure its offset from the system clock
+type OffsetMeasurer interface {
+	OffsetFromSystem() (SysoffMeasurement, error)
+}
+
+// SystemClock is a clock disciplined by PHC2Sys
+type SystemClock interface {
+	FrequencyGetter
+	AdjFreq(freqPPB float64) error
+	Step(step time.Duration) error
+	SetSync() error
+}
+
+// RealtimeClock is CLOCK_REALTIME
+type RealtimeClock struct{}
+
+// FreqPPB reads CLOCK_REALTIME frequency in PPB
+func (c *RealtimeClock) FreqPPB() (float64, error) {
+	freqPPB, _, err := clock.FrequencyPPB(unix.CLOCK_REALTIME)
+	return freqPPB, err
+}
+
+// MaxFreqAdjPPB returns maximum frequency adjustment supported by CLOCK_REALTIME
+func (c *RealtimeClock) MaxFreqAdjPPB() (float64, error) {
+	freqPPB, _, err := clock.MaxFreqPPB(unix.CLOCK_REALTIME)
+	return freqPPB, err
+}
+
+// AdjFreq adjusts CLOCK_REALTIME frequency in PPB
+func (c *RealtimeClock) AdjFreq(freqPPB float64) error {
+	_, err := clock.AdjFreqPPB(unix.CLOCK_REALTIME, freqPPB)
+	return err
+}
+
+// Step steps CLOCK_REALTIME by given duration
+func (c *RealtimeClock) Step(step time.Duration) error {
+	_, err := clock.Step(unix.CLOCK_REALTIME, step)
+	return err
+}
+
+// SetSync sets CLOCK_REALTIME status to TIME_OK
+func (c *RealtimeClock) SetSync() error {
+	return clock.SetSync(unix.CLOCK_REALTIME)
+}
+
+// PHC2SysConfig configures PHC2Sys
+type PHC2SysConfig struct {
+	Interval time.Duration
+	// FirstStepThreshold allows stepping the clock on the first update if the offset is larger. 0 disables the first step.
+	FirstStepThreshold time.Duration
+	// StepThreshold allows stepping the clock anytime the offset is larger. 0 disables stepping after the first update.
+	StepThreshold time.Duration
+	// MaxStep is the sanity limit, larger steps are refused. 0 means no limit.
+	MaxStep time.Duration
+	// Offset is a static offset of the system clock from the PHC, like -37s for PHC in TAI
+	Offset time.Duration
+}
+
+// PHC2Sys disciplines the system clock from a PHC, a Go version of linuxptp phc2sys
+type PHC2Sys struct {
+	cfg *PHC2SysConfig
+	src OffsetMeasurer
+	clk SystemClock
+	pi  ServoController
+
+	sync.Mutex
+	stats PairStats
+}
+
+// NewPHC2Sys creates PHC2Sys with a PI servo configured for the system clock
+func NewPHC2Sys(cfg *PHC2SysConfig, src OffsetMeasurer, clk SystemClock) (*PHC2Sys, error) {
+	pi, err := NewPiServo(cfg.Interval, cfg.FirstStepThreshold, cfg.StepThreshold, clk, 0)
+	if err != nil {
+		return nil, fmt.Errorf("creating servo: %w", err)
+	}
+	return &PHC2Sys{cfg: cfg, src: src, clk: clk, pi: pi}, nil
+}
+
+// Sync performs a single synchronization iteration
+func (p *PHC2Sys) Sync() error {
+	err := p.sync()
+	if err != nil {
+		p.Lock()
+		p.stats.Errors++
+		p.Unlock()
+	}
+	return err
+}
+
+func (p *PHC2Sys) sync() error {
+	m, err := p.src.OffsetFromSystem()
+	if err != nil {
+		return err
+	}
+	offset := m.Offset - p.cfg.Offset
+	freqAdj, state := p.pi.Sample(int64(offset), uint64(m.SysTime.UnixNano())) // unix nano is never negative
+
+	p.Lock()
+	p.stats.Offset = offset
+	p.stats.Delay = m.Delay
+	p.stats.FreqPPB = freqAdj
+	p.stats.ServoState = state.String()
+	p.stats.Samples++
+	p.Unlock()
+
+	switch state {
+	case servo.StateJump:
+		if p.cfg.MaxStep > 0 && offset.Abs() > p.cfg.MaxStep {
+			p.pi.Unlock()
+			return fmt.Errorf("refusing to step clock by %v: larger than max step %v", -offset, p.cfg.MaxStep)
+		}
+		if err := p.clk.AdjFreq(-freqAdj); err != nil {
+			return fmt.Errorf("failed to adjust freq to %v: %w", -freqAdj, err)
+		}
+		if err := p.clk.Step(-offset); err != nil {
+			p.pi.Unlock()
+			return fmt.Errorf("failed to step clock by %v: %w", -offset, err)
+		}
+		p.Lock()
+		p.stats.Steps++
+		p.Unlock()
+	case servo.StateLocked:
+		if err := p.clk.AdjFreq(-freqAdj); err != nil {
+			p.pi.Unlock()
+			return fmt.Errorf("failed to adjust freq to %v: %w", -freqAdj, err)
+		}
+		if err := p.clk.SetSync(); err != nil {
+			return fmt.Errorf("failed to set sys clock sync state: %w", err)
+		}
+	case servo.StateInit:
+		return nil
+	default:
+		return fmt.Errorf("skipping clock update: servo state is %v", state)
+	}
+	return nil
+}
+
+// Stats returns current synchronization stats
+func (p *PHC2Sys) Stats() PairStats {
+	p.Lock()
+	defer p.Unlock()
+	return p.stats
+}
+
+// Run synchronizes the system clock every Interval until the context is cancelled
+func (p *PHC2Sys) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Sync(); err != nil {
+			log.Printf("sync failed: %v", err)
+		} else {
+			st := p.Stats()
+			log.Printf("sys offset %10d servo %s freq %+7.0f delay %5d", st.Offset.Nanoseconds(), st.ServoState, st.FreqPPB, st.Delay.Nanoseconds())
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
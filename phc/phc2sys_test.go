@@ -0,0 +1,87 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/facebook/time/servo"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOffsetMeasurer struct {
+	m SysoffMeasurement
+}
+
+func (f *fakeOffsetMeasurer) OffsetFromSystem() (SysoffMeasurement, error) { return f.m, nil }
+
+type fakeSystemClock struct {
+	fakeSyncTarget
+	synced bool
+}
+
+func (f *fakeSystemClock) SetSync() error {
+	f.synced = true
+	return nil
+}
+
+func TestPHC2SysSync(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	servoMock := NewMockServoController(ctrl)
+
+	src := &fakeOffsetMeasurer{m: SysoffMeasurement{
+		SysoffResult: SysoffResult{Offset: 37*time.Second + time.Microsecond, Delay: 50, SysTime: time.Unix(1667818190, 0)},
+	}}
+	clk := &fakeSystemClock{}
+	cfg := &PHC2SysConfig{Interval: time.Second, Offset: 37 * time.Second, MaxStep: time.Millisecond}
+	p := &PHC2Sys{cfg: cfg, src: src, clk: clk, pi: servoMock}
+
+	servoMock.EXPECT().Sample(int64(time.Microsecond), uint64(1667818190000000000)).Return(0.0, servo.StateInit)
+	require.NoError(t, p.Sync())
+	require.Equal(t, PairStats{Offset: time.Microsecond, Delay: 50, ServoState: "INIT", Samples: 1}, p.Stats())
+
+	servoMock.EXPECT().Sample(int64(time.Microsecond), gomock.Any()).Return(20.0, servo.StateJump)
+	require.NoError(t, p.Sync())
+	require.Equal(t, []time.Duration{-time.Microsecond}, clk.steps)
+	require.Equal(t, -20.0, clk.freq)
+
+	servoMock.EXPECT().Sample(int64(time.Microsecond), gomock.Any()).Return(10.0, servo.StateLocked)
+	require.NoError(t, p.Sync())
+	require.Equal(t, -10.0, clk.freq)
+	require.True(t, clk.synced)
+
+	// sanity check prevents huge steps
+	src.m.Offset = 38 * time.Second
+	servoMock.EXPECT().Sample(int64(time.Second), gomock.Any()).Return(10.0, servo.StateJump)
+	servoMock.EXPECT().Unlock()
+	require.Error(t, p.Sync())
+	require.Len(t, clk.steps, 1)
+
+	st := p.Stats()
+	require.Equal(t, int64(4), st.Samples)
+	require.Equal(t, int64(1), st.Steps)
+	require.Equal(t, int64(1), st.Errors)
+}
+
+func TestNewPHC2Sys(t *testing.T) {
+	cfg := &PHC2SysConfig{Interval: time.Second, FirstStepThreshold: time.Millisecond}
+	_, err := NewPHC2Sys(cfg, &fakeOffsetMeasurer{}, &fakeSystemClock{})
+	require.NoError(t, err)
+}
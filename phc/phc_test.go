@@ -18,6 +18,7 @@ package phc
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -38,6 +39,34 @@ func TestMaxAdjFreq(t *testing.T) {
 	}
 }
 
+func TestCapsFromRaw(t *testing.T) {
+	raw := &PtpClockCaps{
+		Max_adj:            100000000,
+		N_alarm:            0,
+		N_ext_ts:           2,
+		N_per_out:          1,
+		Pps:                1,
+		N_pins:             4,
+		Cross_timestamping: 1,
+		Adjust_phase:       0,
+		Max_phase_adj:      32767999,
+	}
+	want := &Caps{
+		MaxAdjPPB:         100000000.0,
+		NumExtTS:          2,
+		NumPerOut:         1,
+		PPS:               true,
+		NumPins:           4,
+		CrossTimestamping: true,
+		AdjustPhase:       false,
+		MaxPhaseAdj:       32767999 * time.Nanosecond,
+	}
+	require.Equal(t, want, capsFromRaw(raw))
+
+	// zero max_adj falls back to the default
+	require.Equal(t, DefaultMaxClockFreqPPB, capsFromRaw(&PtpClockCaps{}).MaxAdjPPB)
+}
+
 func TestIfaceToPHCDeviceNotSupported(t *testing.T) {
 	dev, err := IfaceToPHCDevice("lo")
 	require.Error(t, err)
@@ -0,0 +1,116 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/facebook/time/phc/unix" // a temporary shim for "golang.org/x/sys/unix" until v0.27.0 is cut
+)
+
+// ppsSysfsPath is where kernel PPS sources are listed
+var ppsSysfsPath = "/sys/class/pps"
+
+// ErrPPSTimeout is returned when no PPS event arrived within the requested timeout
+var ErrPPSTimeout = errors.New("timeout waiting for PPS event")
+
+// PPSDevice represents a kernel PPS device (/dev/ppsN)
+type PPSDevice os.File
+
+// PPSDeviceFromFile returns a *PPSDevice corresponding to an *os.File
+func PPSDeviceFromFile(file *os.File) *PPSDevice { return (*PPSDevice)(file) }
+
+// File returns the underlying *os.File
+func (pps *PPSDevice) File() *os.File { return (*os.File)(pps) }
+
+// Fd returns the underlying file descriptor
+func (pps *PPSDevice) Fd() uintptr { return pps.File().Fd() }
+
+// PPSEvent is a result of PPS_FETCH, an equivalent of pps_info_t from RFC 2783
+type PPSEvent struct {
+	AssertSequence uint32
+	AssertTime     time.Time
+	ClearSequence  uint32
+	ClearTime      time.Time
+	Mode           int
+}
+
+func ppsEventFromKInfo(info *unix.PPSKInfo) PPSEvent {
+	return PPSEvent{
+		AssertSequence: info.Assert_sequence,
+		AssertTime:     time.Unix(info.Assert_tu.Sec, int64(info.Assert_tu.Nsec)),
+		ClearSequence:  info.Clear_sequence,
+		ClearTime:      time.Unix(info.Clear_tu.Sec, int64(info.Clear_tu.Nsec)),
+		Mode:           int(info.Current_mode),
+	}
+}
+
+// Fetch waits up to timeout for the next PPS event, an equivalent of time_pps_fetch.
+// Negative timeout means wait forever, zero timeout returns the last event immediately.
+func (pps *PPSDevice) Fetch(timeout time.Duration) (PPSEvent, error) {
+	data := unix.PPSFData{}
+	if timeout < 0 {
+		data.Timeout.Flags = unix.PPS_TIME_INVALID
+	} else {
+		data.Timeout.Sec = int64(timeout / time.Second)
+		data.Timeout.Nsec = int32(timeout % time.Second) //#nosec G115
+	}
+	if err := unix.IoctlPPSFetch(int(pps.Fd()), &data); err != nil {
+		if errors.Is(err, unix.ETIMEDOUT) {
+			return PPSEvent{}, ErrPPSTimeout
+		}
+		return PPSEvent{}, fmt.Errorf("%s: ioctl(PPS_FETCH) failed: %w", pps.File().Name(), err)
+	}
+	return ppsEventFromKInfo(&data.Info), nil
+}
+
+// Caps returns the PPS_* capabilities bitmask of the PPS device
+func (pps *PPSDevice) Caps() (int, error) {
+	caps, err := unix.IoctlPPSGetCap(int(pps.Fd()))
+	if err != nil {
+		return 0, fmt.Errorf("%s: ioctl(PPS_GETCAP) failed: %w", pps.File().Name(), err)
+	}
+	return caps, nil
+}
+
+// PPSDeviceFromPHC returns path to the kernel PPS device (/dev/ppsN) registered by the given PHC device.
+// PTP_ENABLE_PPS has to be issued on the PHC for the PPS device to produce events.
+func PPSDeviceFromPHC(phcDevice string) (string, error) {
+	name, err := phcName(phcDevice)
+	if err != nil {
+		return "", err
+	}
+	sources, err := os.ReadDir(ppsSysfsPath)
+	if err != nil {
+		return "", fmt.Errorf("listing PPS sources: %w", err)
+	}
+	for _, source := range sources {
+		sourceName, err := os.ReadFile(filepath.Join(ppsSysfsPath, source.Name(), "name"))
+		if err != nil {
+			continue
+		}
+		if strings.TrimSpace(string(sourceName)) == name {
+			return filepath.Join("/dev", source.Name()), nil
+		}
+	}
+	return "", fmt.Errorf("%s: no PPS device found", phcDevice)
+}
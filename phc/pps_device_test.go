@@ -0,0 +1,73 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/facebook/time/phc/unix" // a temporary shim for "golang.org/x/sys/unix" until v0.27.0 is cut
+	"github.com/stretchr/testify/require"
+)
+
+func TestPPSEventFromKInfo(t *testing.T) {
+	info := &unix.PPSKInfo{
+		Assert_sequence: 42,
+		Clear_sequence:  41,
+		Assert_tu:       unix.PPSKTime{Sec: 1667818190, Nsec: 12},
+		Clear_tu:        unix.PPSKTime{Sec: 1667818189, Nsec: 500000012},
+		Current_mode:    unix.PPS_CAPTUREASSERT | unix.PPS_CANWAIT,
+	}
+	want := PPSEvent{
+		AssertSequence: 42,
+		AssertTime:     time.Unix(1667818190, 12),
+		ClearSequence:  41,
+		ClearTime:      time.Unix(1667818189, 500000012),
+		Mode:           0x101,
+	}
+	require.Equal(t, want, ppsEventFromKInfo(info))
+}
+
+func TestPPSDeviceFromPHC(t *testing.T) {
+	dir := t.TempDir()
+	ppsSysfsPath = filepath.Join(dir, "pps")
+	defer func() { ppsSysfsPath = "/sys/class/pps" }()
+
+	for source, name := range map[string]string{"pps0": "ktimer\n", "pps1": "ptp7\n"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(ppsSysfsPath, source), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(ppsSysfsPath, source, "name"), []byte(name), 0644))
+	}
+	phcPath := filepath.Join(dir, "ptp7")
+	require.NoError(t, os.WriteFile(phcPath, nil, 0644))
+	link := filepath.Join(dir, "ptp_nic")
+	require.NoError(t, os.Symlink(phcPath, link))
+
+	got, err := PPSDeviceFromPHC(phcPath)
+	require.NoError(t, err)
+	require.Equal(t, "/dev/pps1", got)
+
+	got, err = PPSDeviceFromPHC(link)
+	require.NoError(t, err)
+	require.Equal(t, "/dev/pps1", got)
+
+	other := filepath.Join(dir, "ptp1")
+	require.NoError(t, os.WriteFile(other, nil, 0644))
+	_, err = PPSDeviceFromPHC(other)
+	require.Error(t, err)
+}
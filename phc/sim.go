@@ -0,0 +1,325 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// SimOp is an operation of SimDevice we can inject failures into
+type SimOp int
+
+// SimDevice operations
+const (
+	SimOpTime SimOp = iota
+	SimOpSysoff
+	SimOpAdjFreq
+	SimOpStep
+	SimOpPins
+)
+
+// simFd is a file descriptor number reported by SimDevice, it's never a valid fd
+const simFd = math.MaxInt32
+
+// SimConfig configures SimDevice
+type SimConfig struct {
+	// Start is the PHC time at creation, zero means current system time
+	Start time.Time
+	// DriftPPB is the natural frequency error of the simulated oscillator
+	DriftPPB float64
+	// Jitter is the maximum random error added to every PHC read
+	Jitter time.Duration
+	// ReadDelay is how long a single PHC read takes in PTP_SYS_OFFSET_EXTENDED
+	ReadDelay time.Duration
+	// MaxFreqPPB is the maximum frequency adjustment, 0 means DefaultMaxClockFreqPPB
+	MaxFreqPPB float64
+	// AdjustPhase enables AdjPhase support, applied as an immediate step
+	AdjustPhase bool
+	// Seed for the jitter generator
+	Seed int64
+	// Now returns the system time, time.Now if not set
+	Now func() time.Time
+}
+
+// SimDevice is an in-memory PHC with configurable drift, jitter and failure injection.
+// It implements DeviceController and can be used wherever *Device is used via interfaces.
+type SimDevice struct {
+	sync.Mutex
+	name    string
+	cfg     SimConfig
+	file    *os.File
+	rnd     *rand.Rand
+	sysRef  time.Time
+	phcRef  time.Time
+	freqPPB float64
+	errs    map[SimOp]error
+	events  []PtpExttsEvent
+	pins    map[uint]int
+}
+
+// NewSimDevice creates a SimDevice
+func NewSimDevice(name string, cfg SimConfig) *SimDevice {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	if cfg.MaxFreqPPB == 0 {
+		cfg.MaxFreqPPB = DefaultMaxClockFreqPPB
+	}
+	now := cfg.Now()
+	start := cfg.Start
+	if start.IsZero() {
+		start = now
+	}
+	return &SimDevice{
+		name:   name,
+		cfg:    cfg,
+		file:   os.NewFile(simFd, name),
+		rnd:    rand.New(rand.NewSource(cfg.Seed)), //#nosec G404
+		sysRef: now,
+		phcRef: start,
+		errs:   map[SimOp]error{},
+		pins:   map[uint]int{},
+	}
+}
+
+// SetError makes the operation fail with err until it's reset with nil
+func (s *SimDevice) SetError(op SimOp, err error) {
+	s.Lock()
+	defer s.Unlock()
+	if err == nil {
+		delete(s.errs, op)
+		return
+	}
+	s.errs[op] = err
+}
+
+// QueueExttsEvent adds an external timestamp event to be returned by Read
+func (s *SimDevice) QueueExttsEvent(index uint, t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	s.events = append(s.events, PtpExttsEvent{
+		T:     PtpClockTime{Sec: t.Unix(), Nsec: uint32(t.Nanosecond())}, //#nosec G115
+		Index: uint32(index),                                             //#nosec G115
+	})
+}
+
+// PinFunc returns function set on the pin
+func (s *SimDevice) PinFunc(index uint) int {
+	s.Lock()
+	defer s.Unlock()
+	return s.pins[index]
+}
+
+// phcAt returns PHC time at the given system time. Must be called with lock held.
+func (s *SimDevice) phcAt(sys time.Time) time.Time {
+	elapsed := sys.Sub(s.sysRef)
+	rate := (s.cfg.DriftPPB + s.freqPPB) / 1e9
+	return s.phcRef.Add(elapsed + time.Duration(float64(elapsed)*rate))
+}
+
+// reanchor moves reference point to now. Must be called with lock held.
+func (s *SimDevice) reanchor() {
+	now := s.cfg.Now()
+	s.phcRef = s.phcAt(now)
+	s.sysRef = now
+}
+
+func (s *SimDevice) jitter() time.Duration {
+	if s.cfg.Jitter == 0 {
+		return 0
+	}
+	return time.Duration(s.rnd.Int63n(int64(2*s.cfg.Jitter)+1)) - s.cfg.Jitter
+}
+
+func (s *SimDevice) err(op SimOp) error {
+	if err, ok := s.errs[op]; ok {
+		return fmt.Errorf("%s: %w", s.name, err)
+	}
+	return nil
+}
+
+func toPtpClockTime(t time.Time) PtpClockTime {
+	return PtpClockTime{Sec: t.Unix(), Nsec: uint32(t.Nanosecond())} //#nosec G115
+}
+
+// File returns a placeholder *os.File carrying the device name
+func (s *SimDevice) File() *os.File { return s.file }
+
+// Fd returns a file descriptor number which is never valid
+func (s *SimDevice) Fd() uintptr { return simFd }
+
+// Time returns simulated PHC time
+func (s *SimDevice) Time() (time.Time, error) {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.err(SimOpTime); err != nil {
+		return time.Time{}, err
+	}
+	return s.phcAt(s.cfg.Now()).Add(s.jitter()), nil
+}
+
+// SetTime sets simulated PHC time
+func (s *SimDevice) SetTime(t time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.err(SimOpStep); err != nil {
+		return err
+	}
+	s.sysRef = s.cfg.Now()
+	s.phcRef = t
+	return nil
+}
+
+// ReadSysoffPrecise returns simulated cross timestamp
+func (s *SimDevice) ReadSysoffPrecise() (*PTPSysOffsetPrecise, error) {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.err(SimOpSysoff); err != nil {
+		return nil, err
+	}
+	now := s.cfg.Now()
+	return &PTPSysOffsetPrecise{
+		Device:   toPtpClockTime(s.phcAt(now).Add(s.jitter())),
+		Realtime: toPtpClockTime(now),
+	}, nil
+}
+
+// ReadSysoffExtended returns ExtendedNumProbes simulated samples, each taking ReadDelay
+func (s *SimDevice) ReadSysoffExtended() (*PTPSysOffsetExtended, error) {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.err(SimOpSysoff); err != nil {
+		return nil, err
+	}
+	res := &PTPSysOffsetExtended{Samples: ExtendedNumProbes}
+	sys := s.cfg.Now()
+	for i := 0; i < ExtendedNumProbes; i++ {
+		mid := sys.Add(s.cfg.ReadDelay / 2)
+		end := sys.Add(s.cfg.ReadDelay)
+		res.Ts[i] = [3]PtpClockTime{toPtpClockTime(sys), toPtpClockTime(s.phcAt(mid).Add(s.jitter())), toPtpClockTime(end)}
+		sys = end
+	}
+	return res, nil
+}
+
+// OffsetFromSystem returns simulated offset using the precise method
+func (s *SimDevice) OffsetFromSystem() (SysoffMeasurement, error) {
+	precise, err := s.ReadSysoffPrecise()
+	if err != nil {
+		return SysoffMeasurement{}, err
+	}
+	return SysoffMeasurement{SysoffResult: SysoffFromPrecise(precise), Method: MethodIoctlSysOffsetPrecise}, nil
+}
+
+// FreqPPB returns current frequency adjustment
+func (s *SimDevice) FreqPPB() (float64, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.freqPPB, nil
+}
+
+// MaxFreqAdjPPB returns maximum frequency adjustment
+func (s *SimDevice) MaxFreqAdjPPB() (float64, error) {
+	return s.cfg.MaxFreqPPB, nil
+}
+
+// Caps returns simulated capabilities
+func (s *SimDevice) Caps() (*Caps, error) {
+	return &Caps{MaxAdjPPB: s.cfg.MaxFreqPPB, NumExtTS: 1, NumPerOut: 1, NumPins: 4, CrossTimestamping: true, AdjustPhase: s.cfg.AdjustPhase}, nil
+}
+
+// AdjPhase adjusts simulated PHC phase if AdjustPhase is enabled
+func (s *SimDevice) AdjPhase(offset time.Duration) error {
+	if !s.cfg.AdjustPhase {
+		return fmt.Errorf("%s: phase adjustment is not supported", s.name)
+	}
+	return s.Step(offset)
+}
+
+// AdjFreq adjusts simulated frequency
+func (s *SimDevice) AdjFreq(freqPPB float64) error {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.err(SimOpAdjFreq); err != nil {
+		return err
+	}
+	if math.Abs(freqPPB) > s.cfg.MaxFreqPPB {
+		return fmt.Errorf("%s: frequency %f is out of supported range", s.name, freqPPB)
+	}
+	s.reanchor()
+	s.freqPPB = freqPPB
+	return nil
+}
+
+// Step steps simulated PHC time
+func (s *SimDevice) Step(step time.Duration) error {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.err(SimOpStep); err != nil {
+		return err
+	}
+	s.reanchor()
+	s.phcRef = s.phcRef.Add(step)
+	return nil
+}
+
+// Read returns queued external timestamp events, io.EOF if there are none
+func (s *SimDevice) Read(buf []byte) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.events) == 0 {
+		return 0, io.EOF
+	}
+	size := binary.Size(PtpExttsEvent{})
+	if len(buf) < size {
+		return 0, fmt.Errorf("%s: buffer is too small", s.name)
+	}
+	event := s.events[0]
+	s.events = s.events[1:]
+	copy(buf, unsafe.Slice((*byte)(unsafe.Pointer(&event)), size))
+	return size, nil
+}
+
+func (s *SimDevice) setPinFunc(index uint, pf int, _ uint) error {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.err(SimOpPins); err != nil {
+		return err
+	}
+	s.pins[index] = pf
+	return nil
+}
+
+func (s *SimDevice) setPTPPerout(_ *PtpPeroutRequest) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.err(SimOpPins)
+}
+
+func (s *SimDevice) extTTSRequest(_ *PtpExttsRequest) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.err(SimOpPins)
+}
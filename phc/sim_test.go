@@ -0,0 +1,147 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/facebook/time/phc/unix" // a temporary shim for "golang.org/x/sys/unix" until v0.27.0 is cut
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNow struct {
+	t time.Time
+}
+
+func (f *fakeNow) Now() time.Time { return f.t }
+
+func TestSimDeviceDrift(t *testing.T) {
+	now := &fakeNow{t: time.Unix(1667818190, 0)}
+	sim := NewSimDevice("ptp0", SimConfig{Start: time.Unix(1667818153, 0), DriftPPB: 1000, Now: now.Now})
+
+	now.t = now.t.Add(time.Second)
+	got, err := sim.Time()
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1667818154, 1000), got)
+
+	// adjusting frequency keeps the time continuous
+	require.NoError(t, sim.AdjFreq(-1000))
+	now.t = now.t.Add(time.Second)
+	got, err = sim.Time()
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1667818155, 1000), got)
+	freq, err := sim.FreqPPB()
+	require.NoError(t, err)
+	require.Equal(t, -1000.0, freq)
+
+	require.NoError(t, sim.Step(-time.Microsecond))
+	got, err = sim.Time()
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1667818155, 0), got)
+
+	require.Error(t, sim.AdjFreq(2*DefaultMaxClockFreqPPB))
+}
+
+func TestSimDeviceSysoff(t *testing.T) {
+	now := &fakeNow{t: time.Unix(1667818190, 0)}
+	sim := NewSimDevice("ptp0", SimConfig{Start: time.Unix(1667818153, 0), ReadDelay: 100, Now: now.Now})
+
+	m, err := sim.OffsetFromSystem()
+	require.NoError(t, err)
+	require.Equal(t, 37*time.Second, m.Offset)
+
+	extended, err := sim.ReadSysoffExtended()
+	require.NoError(t, err)
+	best := extended.BestSample()
+	require.Equal(t, 37*time.Second, best.Offset)
+	require.Equal(t, time.Duration(100), best.Delay)
+}
+
+func TestSimDeviceJitter(t *testing.T) {
+	now := &fakeNow{t: time.Unix(1667818190, 0)}
+	sim := NewSimDevice("ptp0", SimConfig{Jitter: 50, Seed: 1, Now: now.Now})
+	for i := 0; i < 100; i++ {
+		got, err := sim.Time()
+		require.NoError(t, err)
+		require.LessOrEqual(t, got.Sub(now.t).Abs(), time.Duration(50))
+	}
+}
+
+func TestSimDeviceErrors(t *testing.T) {
+	sim := NewSimDevice("ptp0", SimConfig{})
+	sim.SetError(SimOpSysoff, fmt.Errorf("device wedged"))
+	_, err := sim.ReadSysoffPrecise()
+	require.ErrorContains(t, err, "ptp0: device wedged")
+	_, err = sim.ReadSysoffExtended()
+	require.Error(t, err)
+	_, err = sim.Time()
+	require.NoError(t, err)
+
+	sim.SetError(SimOpSysoff, nil)
+	_, err = sim.ReadSysoffPrecise()
+	require.NoError(t, err)
+}
+
+func TestSimDevicePPSSink(t *testing.T) {
+	sim := NewSimDevice("ptp1", SimConfig{})
+	sink, err := PPSSinkFromDevice(sim, 1)
+	require.NoError(t, err)
+	require.Equal(t, unix.PTP_PF_EXTTS, sim.PinFunc(1))
+
+	sim.QueueExttsEvent(1, time.Unix(1667818190, 42))
+	got, err := sink.getPPSEventTimestamp()
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1667818190, 42), got)
+
+	_, err = sink.getPPSEventTimestamp()
+	require.Error(t, err)
+}
+
+func TestSimDeviceSyncPair(t *testing.T) {
+	now := &fakeNow{t: time.Unix(1667818190, 0)}
+	src := NewSimDevice("ptp0", SimConfig{Now: now.Now})
+	dst := NewSimDevice("ptp1", SimConfig{Start: now.t.Add(time.Millisecond), DriftPPB: 10000, Now: now.Now})
+	pair, err := NewSyncPair("ptp0->ptp1", src, dst, time.Second, 20*time.Microsecond, 0)
+	require.NoError(t, err)
+	for i := 0; i < 30; i++ {
+		require.NoError(t, pair.Sync())
+		now.t = now.t.Add(time.Second)
+	}
+	st := pair.Stats()
+	require.Equal(t, "LOCKED", st.ServoState)
+	require.Equal(t, int64(1), st.Steps)
+	require.Less(t, st.Offset.Abs(), 100*time.Nanosecond)
+}
+
+func TestSimDeviceSyncPairPhase(t *testing.T) {
+	now := &fakeNow{t: time.Unix(1667818190, 0)}
+	src := NewSimDevice("ptp0", SimConfig{Now: now.Now})
+	dst := NewSimDevice("ptp1", SimConfig{Start: now.t.Add(time.Millisecond), DriftPPB: 100, AdjustPhase: true, Now: now.Now})
+	pair, err := NewSyncPair("ptp0->ptp1", src, dst, time.Second, 20*time.Microsecond, 0)
+	require.NoError(t, err)
+	require.NotNil(t, pair.phase)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, pair.Sync())
+		now.t = now.t.Add(time.Second)
+	}
+	require.NoError(t, pair.Sync())
+	// locked: phase is corrected every iteration, only drift within an interval is left
+	require.Equal(t, "LOCKED", pair.Stats().ServoState)
+	require.Less(t, pair.Stats().Offset.Abs(), 200*time.Nanosecond)
+}
@@ -0,0 +1,120 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultSnapshotRounds is a default number of interleaved read rounds in TakeSnapshot
+const DefaultSnapshotRounds = 5
+
+// TimeReader is a clock which can be read
+type TimeReader interface {
+	Time() (time.Time, error)
+}
+
+// Snapshot is a mutually consistent set of PHC offsets from the system clock
+// taken in a single interleaved sequence of reads: sys, phc0, sys, phc1, ..., sys
+type Snapshot struct {
+	// Devices are names of the PHCs, sorted
+	Devices []string
+	// Offsets are system time minus PHC time, in the order of Devices
+	Offsets []SysoffResult
+	// Skew is time between the first and the last system clock read of the sequence
+	Skew time.Duration
+}
+
+// TakeSnapshot samples all devices and the system clock in one tight sequence.
+// It does rounds sequences and returns the one with the smallest skew.
+func TakeSnapshot(devices map[string]TimeReader, rounds int) (*Snapshot, error) {
+	return takeSnapshot(devices, rounds, time.Now)
+}
+
+func takeSnapshot(devices map[string]TimeReader, rounds int, now func() time.Time) (*Snapshot, error) {
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("no devices to snapshot")
+	}
+	if rounds < 1 {
+		rounds = 1
+	}
+	names := make([]string, 0, len(devices))
+	for name := range devices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	readers := make([]TimeReader, len(names))
+	for i, name := range names {
+		readers[i] = devices[name]
+	}
+
+	var best *Snapshot
+	phcTimes := make([]time.Time, len(readers))
+	sysTimes := make([]time.Time, len(readers)+1)
+	for r := 0; r < rounds; r++ {
+		sysTimes[0] = now()
+		for i, reader := range readers {
+			t, err := reader.Time()
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", names[i], err)
+			}
+			phcTimes[i] = t
+			sysTimes[i+1] = now()
+		}
+		skew := sysTimes[len(readers)].Sub(sysTimes[0])
+		if best != nil && skew >= best.Skew {
+			continue
+		}
+		best = &Snapshot{Devices: names, Offsets: make([]SysoffResult, len(readers)), Skew: skew}
+		for i := range readers {
+			best.Offsets[i] = SysoffEstimateBasic(sysTimes[i], phcTimes[i], sysTimes[i+1])
+		}
+	}
+	return best, nil
+}
+
+// Offset returns PHC time of device j minus PHC time of device i
+func (s *Snapshot) Offset(i, j int) time.Duration {
+	return s.Offsets[i].Offset - s.Offsets[j].Offset
+}
+
+// Matrix returns offsets between all pairs of devices, where Matrix()[i][j] is Offset(i, j)
+func (s *Snapshot) Matrix() [][]time.Duration {
+	res := make([][]time.Duration, len(s.Offsets))
+	for i := range s.Offsets {
+		res[i] = make([]time.Duration, len(s.Offsets))
+		for j := range s.Offsets {
+			res[i][j] = s.Offset(i, j)
+		}
+	}
+	return res
+}
+
+// MaxSpread returns the largest absolute offset between any two devices
+func (s *Snapshot) MaxSpread() time.Duration {
+	if len(s.Offsets) == 0 {
+		return 0
+	}
+	lo, hi := s.Offsets[0].Offset, s.Offsets[0].Offset
+	for _, o := range s.Offsets[1:] {
+		lo = min(lo, o.Offset)
+		hi = max(hi, o.Offset)
+	}
+	return hi - lo
+}
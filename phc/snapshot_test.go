@@ -0,0 +1,64 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tickingNow advances by step on every read
+type tickingNow struct {
+	t    time.Time
+	step time.Duration
+}
+
+func (f *tickingNow) Now() time.Time {
+	f.t = f.t.Add(f.step)
+	return f.t
+}
+
+func TestTakeSnapshot(t *testing.T) {
+	now := &tickingNow{t: time.Unix(1667818190, 0), step: 100}
+	ptp0 := NewSimDevice("ptp0", SimConfig{Start: time.Unix(1667818153, 0), Now: now.Now})
+	ptp1 := NewSimDevice("ptp1", SimConfig{Start: time.Unix(1667818153, 500), Now: now.Now})
+	s, err := takeSnapshot(map[string]TimeReader{"ptp1": ptp1, "ptp0": ptp0}, 3, now.Now)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ptp0", "ptp1"}, s.Devices)
+	require.Equal(t, time.Duration(400), s.Skew)
+	// ptp1 was created one tick after ptp0, so it's 400ns rather than 500ns ahead
+	require.Equal(t, 37*time.Second+100, s.Offsets[0].Offset)
+	require.Equal(t, 37*time.Second-300, s.Offsets[1].Offset)
+	require.Equal(t, time.Duration(400), s.Offset(0, 1))
+	require.Equal(t, time.Duration(-400), s.Offset(1, 0))
+	require.Equal(t, [][]time.Duration{{0, 400}, {-400, 0}}, s.Matrix())
+	require.Equal(t, time.Duration(400), s.MaxSpread())
+}
+
+func TestTakeSnapshotError(t *testing.T) {
+	_, err := TakeSnapshot(map[string]TimeReader{}, 1)
+	require.Error(t, err)
+
+	dev := NewSimDevice("ptp0", SimConfig{})
+	readErr := errors.New("read failed")
+	dev.SetError(SimOpTime, readErr)
+	_, err = TakeSnapshot(map[string]TimeReader{"ptp0": dev}, 1)
+	require.ErrorIs(t, err, readErr)
+}
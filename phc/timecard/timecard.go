@@ -0,0 +1,301 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package timecard allows to manage OCP Time Card (ptp_ocp driver) via its sysfs controls:
+clock source selection, GNSS sync status, SMA connector routing and related devices.
+*/
+package timecard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sysfsPath is where ptp_ocp registers Time Cards
+var sysfsPath = "/sys/class/timecard"
+
+// NumSMA is the number of SMA connectors on the Time Card
+const NumSMA = 4
+
+// Direction of the SMA connector
+type Direction string
+
+// SMA connector directions
+const (
+	DirectionIn  Direction = "IN"
+	DirectionOut Direction = "OUT"
+)
+
+// SMA is a configuration of a single SMA connector
+type SMA struct {
+	Direction Direction
+	// Signals are the signals routed to (IN) or from (OUT) the connector
+	Signals []string
+}
+
+func (s SMA) String() string {
+	return fmt.Sprintf("%s: %s", s.Direction, strings.Join(s.Signals, " "))
+}
+
+// GNSSStatus is the GNSS receiver sync status
+type GNSSStatus struct {
+	Synced bool
+	// LostAt is when the sync was lost, as reported by the driver
+	LostAt string
+}
+
+// Card is a single Time Card
+type Card struct {
+	Name string
+	path string
+}
+
+// List returns names of all Time Cards on the host, like ocp0
+func List() ([]string, error) {
+	entries, err := os.ReadDir(sysfsPath)
+	if err != nil {
+		return nil, fmt.Errorf("listing time cards: %w", err)
+	}
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Open returns the Time Card with the given name, like ocp0
+func Open(name string) (*Card, error) {
+	path := filepath.Join(sysfsPath, name)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("time card %s: %w", name, err)
+	}
+	return &Card{Name: name, path: path}, nil
+}
+
+func (c *Card) read(attr string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(c.path, attr))
+	if err != nil {
+		return "", fmt.Errorf("%s: reading %s: %w", c.Name, attr, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (c *Card) readInt(attr string) (int, error) {
+	value, err := c.read(attr)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: parsing %s: %w", c.Name, attr, err)
+	}
+	return n, nil
+}
+
+func (c *Card) write(attr, value string) error {
+	// sysfs attributes always exist, no O_CREATE
+	f, err := os.OpenFile(filepath.Join(c.path, attr), os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return fmt.Errorf("%s: opening %s: %w", c.Name, attr, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(value); err != nil {
+		return fmt.Errorf("%s: setting %s to %q: %w", c.Name, attr, value, err)
+	}
+	return nil
+}
+
+// link returns the device node the sysfs symlink points to
+func (c *Card) link(attr string) (string, error) {
+	target, err := os.Readlink(filepath.Join(c.path, attr))
+	if err != nil {
+		return "", fmt.Errorf("%s: reading %s link: %w", c.Name, attr, err)
+	}
+	return filepath.Join("/dev", filepath.Base(target)), nil
+}
+
+// ClockSource returns currently selected clock source, like PPS or IRIG
+func (c *Card) ClockSource() (string, error) {
+	return c.read("clock_source")
+}
+
+// AvailableClockSources returns all clock sources supported by the card
+func (c *Card) AvailableClockSources() ([]string, error) {
+	value, err := c.read("available_clock_sources")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(value), nil
+}
+
+// SetClockSource selects the clock source
+func (c *Card) SetClockSource(source string) error {
+	available, err := c.AvailableClockSources()
+	if err != nil {
+		return err
+	}
+	for _, a := range available {
+		if a == source {
+			return c.write("clock_source", source)
+		}
+	}
+	return fmt.Errorf("%s: clock source %q is not one of %v", c.Name, source, available)
+}
+
+func parseGNSSStatus(value string) (GNSSStatus, error) {
+	if value == "SYNC" {
+		return GNSSStatus{Synced: true}, nil
+	}
+	if lostAt, found := strings.CutPrefix(value, "LOST"); found {
+		return GNSSStatus{LostAt: strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lostAt), "@"))}, nil
+	}
+	return GNSSStatus{}, fmt.Errorf("unknown GNSS sync status %q", value)
+}
+
+// GNSSSync returns GNSS receiver sync status
+func (c *Card) GNSSSync() (GNSSStatus, error) {
+	value, err := c.read("gnss_sync")
+	if err != nil {
+		return GNSSStatus{}, err
+	}
+	return parseGNSSStatus(value)
+}
+
+func parseSMA(value string) (SMA, error) {
+	direction, signals, found := strings.Cut(value, ":")
+	if !found {
+		return SMA{}, fmt.Errorf("malformed SMA configuration %q", value)
+	}
+	sma := SMA{Direction: Direction(strings.TrimSpace(direction)), Signals: strings.Fields(signals)}
+	if sma.Direction != DirectionIn && sma.Direction != DirectionOut {
+		return SMA{}, fmt.Errorf("unknown SMA direction in %q", value)
+	}
+	return sma, nil
+}
+
+func smaAttr(n int) (string, error) {
+	if n < 1 || n > NumSMA {
+		return "", fmt.Errorf("SMA connector %d is out of range [1, %d]", n, NumSMA)
+	}
+	return fmt.Sprintf("sma%d", n), nil
+}
+
+// SMA returns configuration of the SMA connector n, counting from 1
+func (c *Card) SMA(n int) (SMA, error) {
+	attr, err := smaAttr(n)
+	if err != nil {
+		return SMA{}, err
+	}
+	value, err := c.read(attr)
+	if err != nil {
+		return SMA{}, err
+	}
+	return parseSMA(value)
+}
+
+// SetSMA routes the signal to (IN) or from (OUT) the SMA connector n, counting from 1
+func (c *Card) SetSMA(n int, direction Direction, signal string) error {
+	attr, err := smaAttr(n)
+	if err != nil {
+		return err
+	}
+	var available []string
+	switch direction {
+	case DirectionIn:
+		available, err = c.AvailableSMAInputs()
+	case DirectionOut:
+		available, err = c.AvailableSMAOutputs()
+	default:
+		return fmt.Errorf("unknown SMA direction %q", direction)
+	}
+	if err != nil {
+		return err
+	}
+	for _, a := range available {
+		if a == signal {
+			return c.write(attr, SMA{Direction: direction, Signals: []string{signal}}.String())
+		}
+	}
+	return fmt.Errorf("%s: SMA %s signal %q is not one of %v", c.Name, direction, signal, available)
+}
+
+// AvailableSMAInputs returns signals which can be routed from SMA inputs
+func (c *Card) AvailableSMAInputs() ([]string, error) {
+	value, err := c.read("available_sma_inputs")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(value), nil
+}
+
+// AvailableSMAOutputs returns signals which can be routed to SMA outputs
+func (c *Card) AvailableSMAOutputs() ([]string, error) {
+	value, err := c.read("available_sma_outputs")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(value), nil
+}
+
+// ClockStatusOffset returns the offset (ns) reported by the card clock servo
+func (c *Card) ClockStatusOffset() (int, error) {
+	return c.readInt("clock_status_offset")
+}
+
+// ClockStatusDrift returns the drift (ps/s) reported by the card clock servo
+func (c *Card) ClockStatusDrift() (int, error) {
+	return c.readInt("clock_status_drift")
+}
+
+// UTCTAIOffset returns UTC-TAI offset used by the card
+func (c *Card) UTCTAIOffset() (int, error) {
+	return c.readInt("utc_tai_offset")
+}
+
+// SetUTCTAIOffset sets UTC-TAI offset used by the card
+func (c *Card) SetUTCTAIOffset(offset int) error {
+	return c.write("utc_tai_offset", strconv.Itoa(offset))
+}
+
+// Serial returns the card serial number
+func (c *Card) Serial() (string, error) {
+	return c.read("serialnum")
+}
+
+// PHCDevice returns the PHC device of the card, like /dev/ptp2
+func (c *Card) PHCDevice() (string, error) {
+	return c.link("ptp")
+}
+
+// PPSDevice returns the kernel PPS device of the card, like /dev/pps1
+func (c *Card) PPSDevice() (string, error) {
+	return c.link("pps")
+}
+
+// TTY returns the serial device of the card for the given port, like GNSS, MAC or NMEA
+func (c *Card) TTY(port string) (string, error) {
+	// newer kernels moved serial port links into tty directory
+	if _, err := os.Lstat(filepath.Join(c.path, "tty", port)); err == nil {
+		return c.link(filepath.Join("tty", port))
+	}
+	return c.link("tty" + port)
+}
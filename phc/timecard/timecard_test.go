@@ -0,0 +1,161 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timecard
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupSysfs(t *testing.T) *Card {
+	dir := t.TempDir()
+	sysfsPath = filepath.Join(dir, "timecard")
+	t.Cleanup(func() { sysfsPath = "/sys/class/timecard" })
+
+	card := filepath.Join(sysfsPath, "ocp0")
+	require.NoError(t, os.MkdirAll(filepath.Join(card, "tty"), 0755))
+	attrs := map[string]string{
+		"clock_source":            "PPS\n",
+		"available_clock_sources": "PPS TOD IRIG DCF\n",
+		"gnss_sync":               "SYNC\n",
+		"sma1":                    "IN: 10Mhz\n",
+		"sma2":                    "IN: PPS1\n",
+		"sma3":                    "OUT: PHC\n",
+		"sma4":                    "OUT: GNSS1\n",
+		"available_sma_inputs":    "10Mhz PPS1 PPS2 TS1 TS2 IRIG DCF None\n",
+		"available_sma_outputs":   "10Mhz PHC MAC GNSS1 GNSS2 IRIG DCF\n",
+		"clock_status_offset":     "-3\n",
+		"clock_status_drift":      "118\n",
+		"utc_tai_offset":          "37\n",
+		"serialnum":               "fc:c2:3d:11:22:33\n",
+	}
+	for attr, value := range attrs {
+		require.NoError(t, os.WriteFile(filepath.Join(card, attr), []byte(value), 0644))
+	}
+	require.NoError(t, os.Symlink("../../ptp/ptp2", filepath.Join(card, "ptp")))
+	require.NoError(t, os.Symlink("../../pps/pps1", filepath.Join(card, "pps")))
+	require.NoError(t, os.Symlink("../../../tty/ttyS5", filepath.Join(card, "tty", "GNSS")))
+	require.NoError(t, os.Symlink("../../tty/ttyS7", filepath.Join(card, "ttyNMEA")))
+
+	c, err := Open("ocp0")
+	require.NoError(t, err)
+	return c
+}
+
+func TestListOpen(t *testing.T) {
+	setupSysfs(t)
+	names, err := List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"ocp0"}, names)
+
+	_, err = Open("ocp1")
+	require.Error(t, err)
+}
+
+func TestClockSource(t *testing.T) {
+	c := setupSysfs(t)
+	source, err := c.ClockSource()
+	require.NoError(t, err)
+	require.Equal(t, "PPS", source)
+
+	require.NoError(t, c.SetClockSource("IRIG"))
+	source, err = c.ClockSource()
+	require.NoError(t, err)
+	require.Equal(t, "IRIG", source)
+
+	require.Error(t, c.SetClockSource("NTP"))
+}
+
+func TestParseGNSSStatus(t *testing.T) {
+	got, err := parseGNSSStatus("SYNC")
+	require.NoError(t, err)
+	require.Equal(t, GNSSStatus{Synced: true}, got)
+
+	got, err = parseGNSSStatus("LOST @ 2023-02-07T15:12:01")
+	require.NoError(t, err)
+	require.Equal(t, GNSSStatus{LostAt: "2023-02-07T15:12:01"}, got)
+
+	_, err = parseGNSSStatus("WAT")
+	require.Error(t, err)
+}
+
+func TestSMA(t *testing.T) {
+	c := setupSysfs(t)
+	sma, err := c.SMA(1)
+	require.NoError(t, err)
+	require.Equal(t, SMA{Direction: DirectionIn, Signals: []string{"10Mhz"}}, sma)
+
+	sma, err = c.SMA(3)
+	require.NoError(t, err)
+	require.Equal(t, SMA{Direction: DirectionOut, Signals: []string{"PHC"}}, sma)
+
+	require.NoError(t, c.SetSMA(4, DirectionOut, "MAC"))
+	sma, err = c.SMA(4)
+	require.NoError(t, err)
+	require.Equal(t, SMA{Direction: DirectionOut, Signals: []string{"MAC"}}, sma)
+
+	require.Error(t, c.SetSMA(4, DirectionOut, "PPS1"))
+	require.Error(t, c.SetSMA(5, DirectionIn, "PPS1"))
+	_, err = c.SMA(0)
+	require.Error(t, err)
+
+	_, err = parseSMA("10Mhz")
+	require.Error(t, err)
+	_, err = parseSMA("SIDEWAYS: 10Mhz")
+	require.Error(t, err)
+}
+
+func TestStatusAndDevices(t *testing.T) {
+	c := setupSysfs(t)
+	gnss, err := c.GNSSSync()
+	require.NoError(t, err)
+	require.True(t, gnss.Synced)
+
+	offset, err := c.ClockStatusOffset()
+	require.NoError(t, err)
+	require.Equal(t, -3, offset)
+	drift, err := c.ClockStatusDrift()
+	require.NoError(t, err)
+	require.Equal(t, 118, drift)
+
+	require.NoError(t, c.SetUTCTAIOffset(38))
+	tai, err := c.UTCTAIOffset()
+	require.NoError(t, err)
+	require.Equal(t, 38, tai)
+
+	serial, err := c.Serial()
+	require.NoError(t, err)
+	require.Equal(t, "fc:c2:3d:11:22:33", serial)
+
+	dev, err := c.PHCDevice()
+	require.NoError(t, err)
+	require.Equal(t, "/dev/ptp2", dev)
+	dev, err = c.PPSDevice()
+	require.NoError(t, err)
+	require.Equal(t, "/dev/pps1", dev)
+	dev, err = c.TTY("GNSS")
+	require.NoError(t, err)
+	require.Equal(t, "/dev/ttyS5", dev)
+	dev, err = c.TTY("NMEA")
+	require.NoError(t, err)
+	require.Equal(t, "/dev/ttyS7", dev)
+	_, err = c.TTY("MAC")
+	require.Error(t, err)
+}
@@ -76,6 +76,7 @@ const (
 	HWTSTAMP_TX_OFF          = 0x0 //nolint:revive
 	HWTSTAMP_TX_ON           = 0x1 //nolint:revive
 	HWTSTAMP_TX_ONESTEP_SYNC = 0x2 //nolint:revive
+	HWTSTAMP_TX_ONESTEP_P2P  = 0x3 //nolint:revive
 )
 
 // https://go-review.googlesource.com/c/sys/+/619335
@@ -226,6 +227,15 @@ func IoctlPtpSysOffsetPrecise(fd int) (*PtpSysOffsetPrecise, error) {
 	return &value, err
 }
 
+// IoctlPtpSysOffset returns a basic description of the clock offset
+// compared to the system clock. The samples parameter specifies the
+// desired number of measurements.
+func IoctlPtpSysOffset(fd int, samples uint) (*PtpSysOffset, error) {
+	value := PtpSysOffset{Samples: uint32(samples)}
+	err := ioctlPtr(fd, PTP_SYS_OFFSET2, unsafe.Pointer(&value))
+	return &value, err
+}
+
 // IoctlPtpSysOffsetExtended returns an extended description of the
 // clock offset compared to the system clock. The samples parameter
 // specifies the desired number of measurements.
@@ -270,53 +280,195 @@ const (
 	PTP_PF_PHYSYNC        //nolint:revive
 )
 
+// IoctlPtpEnablePPS enables or disables delivery of the PPS events
+// from the PTP device to the kernel PPS subsystem.
+func IoctlPtpEnablePPS(fd int, enable bool) error {
+	var arg uintptr
+	if enable {
+		arg = 1
+	}
+	return ioctl(fd, PTP_ENABLE_PPS2, arg)
+}
+
+// IoctlPPSFetch waits for the next event on the PPS device
+// (or returns the last one, depending on the timeout in data).
+func IoctlPPSFetch(fd int, data *PPSFData) error {
+	return ioctlPtr(fd, PPS_FETCH, unsafe.Pointer(data))
+}
+
+// IoctlPPSGetParams returns the current parameters of the PPS device.
+func IoctlPPSGetParams(fd int) (*PPSKParams, error) {
+	var value PPSKParams
+	err := ioctlPtr(fd, PPS_GETPARAMS, unsafe.Pointer(&value))
+	return &value, err
+}
+
+// IoctlPPSGetCap returns the capabilities bitmask of the PPS device.
+func IoctlPPSGetCap(fd int) (int, error) {
+	var value int32
+	err := ioctlPtr(fd, PPS_GETCAP, unsafe.Pointer(&value))
+	return int(value), err
+}
+
+// SoTimestamping is used in SO_TIMESTAMPING setsockopt to bind
+// timestamps to a specific PHC with SOF_TIMESTAMPING_BIND_PHC.
+type SoTimestamping struct {
+	Flags    int32
+	Bind_phc int32 //nolint:revive
+}
+
+// SetsockoptSoTimestamping sets SO_TIMESTAMPING socket option with
+// a struct so_timestamping argument.
+func SetsockoptSoTimestamping(fd, level, opt int, value *SoTimestamping) error {
+	_, _, e1 := Syscall6(SYS_SETSOCKOPT, uintptr(fd), uintptr(level), uintptr(opt), uintptr(unsafe.Pointer(value)), unsafe.Sizeof(*value), 0)
+	if e1 != 0 {
+		return errnoErr(e1)
+	}
+	return nil
+}
+
+// ScmTsPktinfo is a payload of SCM_TIMESTAMPING_PKTINFO control message
+type ScmTsPktinfo struct {
+	If_index   uint32    //nolint:revive
+	Pkt_length uint32    //nolint:revive
+	Reserved   [2]uint32 //nolint:revive
+}
+
+// Mmsghdr is used in recvmmsg to receive multiple messages at once
+type Mmsghdr struct {
+	Hdr Msghdr
+	Len uint32
+}
+
+// Recvmmsg receives up to len(msgs) messages with a single syscall
+func Recvmmsg(fd int, msgs []Mmsghdr, flags int) (int, error) {
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+	r0, _, e1 := Syscall6(SYS_RECVMMSG, uintptr(fd), uintptr(unsafe.Pointer(&msgs[0])), uintptr(len(msgs)), uintptr(flags), 0, 0)
+	if e1 != 0 {
+		return 0, errnoErr(e1)
+	}
+	return int(r0), nil
+}
+
+// from linux/pps.h
+const (
+	PPS_CAPTUREASSERT = 0x01  //nolint:revive
+	PPS_CAPTURECLEAR  = 0x02  //nolint:revive
+	PPS_CAPTUREBOTH   = 0x03  //nolint:revive
+	PPS_OFFSETASSERT  = 0x10  //nolint:revive
+	PPS_OFFSETCLEAR   = 0x20  //nolint:revive
+	PPS_CANWAIT       = 0x100 //nolint:revive
+	PPS_TIME_INVALID  = 0x01  //nolint:revive
+)
+
 // bridging to upstream
 
 type Cmsghdr = unix.Cmsghdr
 type Errno = unix.Errno
 type Msghdr = unix.Msghdr
+type PacketMreq = unix.PacketMreq
+type PPSFData = unix.PPSFData
+type PPSKInfo = unix.PPSKInfo
+type PPSKParams = unix.PPSKParams
+type PPSKTime = unix.PPSKTime
 type PollFd = unix.PollFd
 type RawSockaddrInet4 = unix.RawSockaddrInet4
+type SockExtendedErr = unix.SockExtendedErr
+type SockFilter = unix.SockFilter
+type SockFprog = unix.SockFprog
 type SockaddrInet4 = unix.SockaddrInet4
 type SockaddrInet6 = unix.SockaddrInet6
+type SockaddrLinklayer = unix.SockaddrLinklayer
 type Sockaddr = unix.Sockaddr
 type Timespec = unix.Timespec
 type Timex = unix.Timex
 type Utsname = unix.Utsname
 
+func Bind(fd int, sa Sockaddr) error              { return unix.Bind(fd, sa) }
 func ByteSliceToString(b []byte) string           { return unix.ByteSliceToString(b) }
 func ClockAdjtime(c int32, t *Timex) (int, error) { return unix.ClockAdjtime(c, t) }
 func ClockGettime(c int32, t *Timespec) error     { return unix.ClockGettime(c, t) }
 func Close(fd int) (err error)                    { return unix.Close(fd) }
 func ErrnoName(e syscall.Errno) string            { return unix.ErrnoName(e) }
+func GetsockoptInt(a, b, c int) (int, error)      { return unix.GetsockoptInt(a, b, c) }
 func Poll(f []PollFd, t int) (int, error)         { return unix.Poll(f, t) }
 func Recvmsg(a int, b, c []byte, d int) (int, int, int, Sockaddr, error) {
 	return unix.Recvmsg(a, b, c, d)
 }
-func SetsockoptInt(a, b, c, d int) error                   { return unix.SetsockoptInt(a, b, c, d) }
+func Sendto(fd int, p []byte, flags int, to Sockaddr) error { return unix.Sendto(fd, p, flags, to) }
+func SetsockoptInt(a, b, c, d int) error                    { return unix.SetsockoptInt(a, b, c, d) }
+func SetsockoptPacketMreq(fd, level, opt int, mreq *PacketMreq) error {
+	return unix.SetsockoptPacketMreq(fd, level, opt, mreq)
+}
+func SetsockoptSockFprog(fd, level, opt int, fprog *SockFprog) error {
+	return unix.SetsockoptSockFprog(fd, level, opt, fprog)
+}
 func Socket(domain, typ, proto int) (fd int, err error)    { return unix.Socket(domain, typ, proto) }
 func Syscall(a, b, c, d uintptr) (uintptr, uintptr, Errno) { return unix.Syscall(a, b, c, d) }
-func TimeToTimespec(t time.Time) (Timespec, error)         { return unix.TimeToTimespec(t) }
-func Uname(s *Utsname) error                               { return unix.Uname(s) }
+func Syscall6(a, b, c, d, e, f, g uintptr) (uintptr, uintptr, Errno) {
+	return unix.Syscall6(a, b, c, d, e, f, g)
+}
+func TimeToTimespec(t time.Time) (Timespec, error) { return unix.TimeToTimespec(t) }
+func Uname(s *Utsname) error                       { return unix.Uname(s) }
 
 const (
-	AF_INET                       = unix.AF_INET             //nolint:revive
-	EAGAIN                        = unix.EAGAIN              //nolint:revive
-	EINVAL                        = unix.EINVAL              //nolint:revive
-	ENOENT                        = unix.ENOENT              //nolint:revive
-	ENOTSUP                       = unix.ENOTSUP             //nolint:revive
-	ETHTOOL_GET_TS_INFO           = unix.ETHTOOL_GET_TS_INFO //nolint:revive
-	IFNAMSIZ                      = unix.IFNAMSIZ            //nolint:revive
-	MSG_ERRQUEUE                  = unix.MSG_ERRQUEUE        //nolint:revive
-	POLLERR                       = unix.POLLERR             //nolint:revive
+	AF_INET                       = unix.AF_INET                       //nolint:revive
+	AF_PACKET                     = unix.AF_PACKET                     //nolint:revive
+	CLOCK_REALTIME                = unix.CLOCK_REALTIME                //nolint:revive
+	CTRL_ATTR_FAMILY_ID           = unix.CTRL_ATTR_FAMILY_ID           //nolint:revive
+	CTRL_ATTR_FAMILY_NAME         = unix.CTRL_ATTR_FAMILY_NAME         //nolint:revive
+	CTRL_CMD_GETFAMILY            = unix.CTRL_CMD_GETFAMILY            //nolint:revive
+	EAGAIN                        = unix.EAGAIN                        //nolint:revive
+	EINVAL                        = unix.EINVAL                        //nolint:revive
+	ENOENT                        = unix.ENOENT                        //nolint:revive
+	ENOTSUP                       = unix.ENOTSUP                       //nolint:revive
+	ETHTOOL_A_BITSET_BITS         = unix.ETHTOOL_A_BITSET_BITS         //nolint:revive
+	ETHTOOL_A_BITSET_BITS_BIT     = unix.ETHTOOL_A_BITSET_BITS_BIT     //nolint:revive
+	ETHTOOL_A_BITSET_BIT_INDEX    = unix.ETHTOOL_A_BITSET_BIT_INDEX    //nolint:revive
+	ETHTOOL_A_BITSET_BIT_VALUE    = unix.ETHTOOL_A_BITSET_BIT_VALUE    //nolint:revive
+	ETHTOOL_A_BITSET_VALUE        = unix.ETHTOOL_A_BITSET_VALUE        //nolint:revive
+	ETHTOOL_A_HEADER_DEV_NAME     = unix.ETHTOOL_A_HEADER_DEV_NAME     //nolint:revive
+	ETHTOOL_A_HEADER_FLAGS        = unix.ETHTOOL_A_HEADER_FLAGS        //nolint:revive
+	ETHTOOL_A_TSINFO_HEADER       = unix.ETHTOOL_A_TSINFO_HEADER       //nolint:revive
+	ETHTOOL_A_TSINFO_PHC_INDEX    = unix.ETHTOOL_A_TSINFO_PHC_INDEX    //nolint:revive
+	ETHTOOL_A_TSINFO_RX_FILTERS   = unix.ETHTOOL_A_TSINFO_RX_FILTERS   //nolint:revive
+	ETHTOOL_A_TSINFO_TIMESTAMPING = unix.ETHTOOL_A_TSINFO_TIMESTAMPING //nolint:revive
+	ETHTOOL_A_TSINFO_TX_TYPES     = unix.ETHTOOL_A_TSINFO_TX_TYPES     //nolint:revive
+	ETHTOOL_FLAG_COMPACT_BITSETS  = unix.ETHTOOL_FLAG_COMPACT_BITSETS  //nolint:revive
+	ETHTOOL_GENL_NAME             = unix.ETHTOOL_GENL_NAME             //nolint:revive
+	ETHTOOL_GENL_VERSION          = unix.ETHTOOL_GENL_VERSION          //nolint:revive
+	ETHTOOL_MSG_TSINFO_GET        = unix.ETHTOOL_MSG_TSINFO_GET        //nolint:revive
+	ETH_P_1588                    = unix.ETH_P_1588                    //nolint:revive
+	ETIMEDOUT                     = unix.ETIMEDOUT                     //nolint:revive
+	ETHTOOL_GET_TS_INFO           = unix.ETHTOOL_GET_TS_INFO           //nolint:revive
+	GENL_ID_CTRL                  = unix.GENL_ID_CTRL                  //nolint:revive
+	IFNAMSIZ                      = unix.IFNAMSIZ                      //nolint:revive
+	IPV6_RECVERR                  = unix.IPV6_RECVERR                  //nolint:revive
+	IP_RECVERR                    = unix.IP_RECVERR                    //nolint:revive
+	MSG_DONTWAIT                  = unix.MSG_DONTWAIT                  //nolint:revive
+	MSG_ERRQUEUE                  = unix.MSG_ERRQUEUE                  //nolint:revive
+	NETLINK_GENERIC               = unix.NETLINK_GENERIC               //nolint:revive
+	PACKET_ADD_MEMBERSHIP         = unix.PACKET_ADD_MEMBERSHIP         //nolint:revive
+	PACKET_MR_MULTICAST           = unix.PACKET_MR_MULTICAST           //nolint:revive
+	POLLERR                       = unix.POLLERR                       //nolint:revive
 	POLLIN                        = unix.POLLIN
 	POLLPRI                       = unix.POLLPRI
+	PPS_FETCH                     = unix.PPS_FETCH     //nolint:revive
+	PPS_GETCAP                    = unix.PPS_GETCAP    //nolint:revive
+	PPS_GETPARAMS                 = unix.PPS_GETPARAMS //nolint:revive
 	SIOCETHTOOL                   = unix.SIOCETHTOOL   //nolint:revive
 	SIOCGHWTSTAMP                 = unix.SIOCGHWTSTAMP //nolint:revive
 	SIOCSHWTSTAMP                 = unix.SIOCSHWTSTAMP //nolint:revive
 	SizeofPtr                     = unix.SizeofPtr
 	SizeofSockaddrInet4           = unix.SizeofSockaddrInet4
+	SOCK_CLOEXEC                  = unix.SOCK_CLOEXEC                  //nolint:revive
 	SOCK_DGRAM                    = unix.SOCK_DGRAM                    //nolint:revive
+	SCM_TIMESTAMPING_PKTINFO      = unix.SCM_TIMESTAMPING_PKTINFO      //nolint:revive
+	SOF_TIMESTAMPING_BIND_PHC     = unix.SOF_TIMESTAMPING_BIND_PHC     //nolint:revive
+	SOF_TIMESTAMPING_OPT_ID       = unix.SOF_TIMESTAMPING_OPT_ID       //nolint:revive
+	SOF_TIMESTAMPING_OPT_PKTINFO  = unix.SOF_TIMESTAMPING_OPT_PKTINFO  //nolint:revive
 	SOF_TIMESTAMPING_OPT_TSONLY   = unix.SOF_TIMESTAMPING_OPT_TSONLY   //nolint:revive
 	SOF_TIMESTAMPING_RAW_HARDWARE = unix.SOF_TIMESTAMPING_RAW_HARDWARE //nolint:revive
 	SOF_TIMESTAMPING_RX_HARDWARE  = unix.SOF_TIMESTAMPING_RX_HARDWARE  //nolint:revive
@@ -324,13 +476,23 @@ const (
 	SOF_TIMESTAMPING_SOFTWARE     = unix.SOF_TIMESTAMPING_SOFTWARE     //nolint:revive
 	SOF_TIMESTAMPING_TX_HARDWARE  = unix.SOF_TIMESTAMPING_TX_HARDWARE  //nolint:revive
 	SOF_TIMESTAMPING_TX_SOFTWARE  = unix.SOF_TIMESTAMPING_TX_SOFTWARE  //nolint:revive
+	SOL_IP                        = unix.SOL_IP                        //nolint:revive
+	SOL_IPV6                      = unix.SOL_IPV6                      //nolint:revive
+	SOL_PACKET                    = unix.SOL_PACKET                    //nolint:revive
 	SOL_SOCKET                    = unix.SOL_SOCKET                    //nolint:revive
+	SO_ATTACH_FILTER              = unix.SO_ATTACH_FILTER              //nolint:revive
+	SO_BUSY_POLL                  = unix.SO_BUSY_POLL                  //nolint:revive
+	SO_BUSY_POLL_BUDGET           = unix.SO_BUSY_POLL_BUDGET           //nolint:revive
+	SO_EE_ORIGIN_TIMESTAMPING     = unix.SO_EE_ORIGIN_TIMESTAMPING     //nolint:revive
+	SO_PREFER_BUSY_POLL           = unix.SO_PREFER_BUSY_POLL           //nolint:revive
 	SO_SELECT_ERR_QUEUE           = unix.SO_SELECT_ERR_QUEUE           //nolint:revive
 	SO_TIMESTAMPING_NEW           = unix.SO_TIMESTAMPING_NEW           //nolint:revive
 	SO_TIMESTAMPING               = unix.SO_TIMESTAMPING               //nolint:revive
 	SYS_CLOCK_SETTIME             = unix.SYS_CLOCK_SETTIME             //nolint:revive
 	SYS_IOCTL                     = unix.SYS_IOCTL                     //nolint:revive
+	SYS_RECVMMSG                  = unix.SYS_RECVMMSG                  //nolint:revive
 	SYS_RECVMSG                   = unix.SYS_RECVMSG                   //nolint:revive
+	SYS_SETSOCKOPT                = unix.SYS_SETSOCKOPT                //nolint:revive
 	TIME_OK                       = unix.TIME_OK                       //nolint:revive
 )
 
@@ -348,6 +510,14 @@ func ioctlIfreqData(fd int, req uint, value *ifreqData) error {
 	return ioctlPtr(fd, req, unsafe.Pointer(value))
 }
 
+func ioctl(fd int, req uint, arg uintptr) (err error) {
+	_, _, e1 := Syscall(SYS_IOCTL, uintptr(fd), uintptr(req), arg)
+	if e1 != 0 {
+		err = errnoErr(e1)
+	}
+	return
+}
+
 func ioctlPtr(fd int, req uint, arg unsafe.Pointer) (err error) {
 	_, _, e1 := Syscall(SYS_IOCTL, uintptr(fd), uintptr(req), uintptr(arg))
 	if e1 != 0 {
@@ -0,0 +1,152 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/facebook/time/phc/unix" // a temporary shim for "golang.org/x/sys/unix" until v0.27.0 is cut
+)
+
+// ptpSysfsPath is where PHC devices are listed
+var ptpSysfsPath = "/sys/class/ptp"
+
+// phcName returns kernel name (like ptp0) of the PHC device, following symlinks like /dev/ptp_hyperv
+func phcName(device string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(device)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(resolved), nil
+}
+
+// PHCIndex returns index of the PHC device, i.e. 2 for /dev/ptp2
+func PHCIndex(device string) (int, error) {
+	name, err := phcName(device)
+	if err != nil {
+		return -1, err
+	}
+	index, err := strconv.Atoi(strings.TrimPrefix(name, "ptp"))
+	if err != nil || !strings.HasPrefix(name, "ptp") {
+		return -1, fmt.Errorf("%s: not a PHC device", device)
+	}
+	return index, nil
+}
+
+func readSysfsInt(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// MaxVclocks returns the maximum number of vclocks the PHC device supports
+func MaxVclocks(device string) (int, error) {
+	name, err := phcName(device)
+	if err != nil {
+		return 0, err
+	}
+	return readSysfsInt(filepath.Join(ptpSysfsPath, name, "max_vclocks"))
+}
+
+// NumVclocks returns the number of vclocks currently created on top of the PHC device
+func NumVclocks(device string) (int, error) {
+	name, err := phcName(device)
+	if err != nil {
+		return 0, err
+	}
+	return readSysfsInt(filepath.Join(ptpSysfsPath, name, "n_vclocks"))
+}
+
+// SetNumVclocks creates or destroys vclocks so that exactly n of them exist on top of the PHC device.
+// Setting n to 0 destroys all vclocks. Kernel refuses the change while vclocks are in use.
+func SetNumVclocks(device string, n int) error {
+	name, err := phcName(device)
+	if err != nil {
+		return err
+	}
+	maxVclocks, err := MaxVclocks(device)
+	if err != nil {
+		return fmt.Errorf("reading max_vclocks: %w", err)
+	}
+	if n < 0 || n > maxVclocks {
+		return fmt.Errorf("%s: number of vclocks %d is out of range [0, %d]", device, n, maxVclocks)
+	}
+	// n_vclocks is write-only for root, so no O_CREATE
+	f, err := os.OpenFile(filepath.Join(ptpSysfsPath, name, "n_vclocks"), os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(strconv.Itoa(n)); err != nil {
+		return fmt.Errorf("%s: setting n_vclocks to %d: %w", device, n, err)
+	}
+	return nil
+}
+
+// Vclocks returns device paths of all vclocks created on top of the PHC device
+func Vclocks(device string) ([]string, error) {
+	name, err := phcName(device)
+	if err != nil {
+		return nil, err
+	}
+	return vclocksByName(name)
+}
+
+func vclocksByName(name string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(ptpSysfsPath, name))
+	if err != nil {
+		return nil, err
+	}
+	indexes := []int{}
+	for _, e := range entries {
+		// vclocks are registered as child ptp devices of the physical clock
+		if !strings.HasPrefix(e.Name(), "ptp") {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimPrefix(e.Name(), "ptp"))
+		if err != nil {
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	vclocks := make([]string, 0, len(indexes))
+	for _, index := range indexes {
+		vclocks = append(vclocks, fmt.Sprintf("/dev/ptp%d", index))
+	}
+	return vclocks, nil
+}
+
+// BindTimestampsToPHC enables timestamps with given SOF_TIMESTAMPING_* flags on the socket,
+// binding them to the PHC (usually a vclock) with the given index using SOF_TIMESTAMPING_BIND_PHC
+func BindTimestampsToPHC(connFd int, flags int, phcIndex int) error {
+	value := unix.SoTimestamping{
+		Flags:    int32(flags | unix.SOF_TIMESTAMPING_BIND_PHC), //#nosec G115
+		Bind_phc: int32(phcIndex),                               //#nosec G115
+	}
+	if err := unix.SetsockoptSoTimestamping(connFd, unix.SOL_SOCKET, unix.SO_TIMESTAMPING_NEW, &value); err != nil {
+		return fmt.Errorf("binding timestamps to PHC %d: %w", phcIndex, err)
+	}
+	return nil
+}
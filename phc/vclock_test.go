@@ -0,0 +1,95 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupVclockSysfs(t *testing.T) string {
+	dir := t.TempDir()
+	ptpSysfsPath = filepath.Join(dir, "ptp")
+	t.Cleanup(func() { ptpSysfsPath = "/sys/class/ptp" })
+
+	phcDir := filepath.Join(ptpSysfsPath, "ptp0")
+	for _, sub := range []string{"ptp12", "ptp3", "power"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(phcDir, sub), 0755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(phcDir, "max_vclocks"), []byte("20\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(phcDir, "n_vclocks"), []byte("2\n"), 0644))
+	device := filepath.Join(dir, "ptp0")
+	require.NoError(t, os.WriteFile(device, nil, 0644))
+	return device
+}
+
+func TestPHCIndex(t *testing.T) {
+	dir := t.TempDir()
+	device := filepath.Join(dir, "ptp42")
+	require.NoError(t, os.WriteFile(device, nil, 0644))
+	link := filepath.Join(dir, "ptp_hyperv")
+	require.NoError(t, os.Symlink(device, link))
+
+	index, err := PHCIndex(device)
+	require.NoError(t, err)
+	require.Equal(t, 42, index)
+
+	index, err = PHCIndex(link)
+	require.NoError(t, err)
+	require.Equal(t, 42, index)
+
+	notPHC := filepath.Join(dir, "pps0")
+	require.NoError(t, os.WriteFile(notPHC, nil, 0644))
+	_, err = PHCIndex(notPHC)
+	require.Error(t, err)
+}
+
+func TestVclocks(t *testing.T) {
+	device := setupVclockSysfs(t)
+
+	maxVclocks, err := MaxVclocks(device)
+	require.NoError(t, err)
+	require.Equal(t, 20, maxVclocks)
+
+	n, err := NumVclocks(device)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	vclocks, err := Vclocks(device)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/dev/ptp3", "/dev/ptp12"}, vclocks)
+}
+
+func TestSetNumVclocks(t *testing.T) {
+	device := setupVclockSysfs(t)
+
+	require.NoError(t, SetNumVclocks(device, 4))
+	n, err := NumVclocks(device)
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+
+	require.NoError(t, SetNumVclocks(device, 0))
+	n, err = NumVclocks(device)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	require.Error(t, SetNumVclocks(device, 21))
+	require.Error(t, SetNumVclocks(device, -1))
+}
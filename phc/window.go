@@ -0,0 +1,244 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// madOutlierFactor is the default number of scaled MADs a sample may deviate from the median
+const madOutlierFactor = 3.0
+
+// madScale turns MAD into a consistent estimator of standard deviation for normally distributed data
+const madScale = 1.4826
+
+// OffsetWindow is a rolling window of PHC-vs-system offset measurements.
+// It is safe for concurrent use.
+type OffsetWindow struct {
+	sync.Mutex
+	size    int
+	samples []SysoffResult
+	next    int
+	filled  bool
+	// OutlierFactor is the number of scaled MADs beyond which a sample is considered an outlier
+	OutlierFactor float64
+}
+
+// WindowStats is a summary of offsets in the OffsetWindow
+type WindowStats struct {
+	Samples  int
+	Outliers int
+	Last     time.Duration
+	Mean     time.Duration
+	Median   time.Duration
+	MAD      time.Duration
+	Min      time.Duration
+	Max      time.Duration
+	StdDev   time.Duration
+	DriftPPB float64
+}
+
+// NewOffsetWindow returns OffsetWindow holding up to size samples
+func NewOffsetWindow(size int) *OffsetWindow {
+	if size < 1 {
+		size = 1
+	}
+	return &OffsetWindow{
+		size:          size,
+		samples:       make([]SysoffResult, 0, size),
+		OutlierFactor: madOutlierFactor,
+	}
+}
+
+// Add stores new measurement, evicting the oldest one if the window is full
+func (w *OffsetWindow) Add(s SysoffResult) {
+	w.Lock()
+	defer w.Unlock()
+	if !w.filled {
+		w.samples = append(w.samples, s)
+		if len(w.samples) == w.size {
+			w.filled = true
+		}
+		return
+	}
+	w.samples[w.next] = s
+	w.next = (w.next + 1) % w.size
+}
+
+// Len returns number of samples in the window
+func (w *OffsetWindow) Len() int {
+	w.Lock()
+	defer w.Unlock()
+	return len(w.samples)
+}
+
+// Full returns true if the window holds size samples
+func (w *OffsetWindow) Full() bool {
+	w.Lock()
+	defer w.Unlock()
+	return w.filled
+}
+
+// Reset drops all samples, for example after the clock was stepped
+func (w *OffsetWindow) Reset() {
+	w.Lock()
+	defer w.Unlock()
+	w.samples = w.samples[:0]
+	w.next = 0
+	w.filled = false
+}
+
+// Samples returns copy of all samples, oldest first
+func (w *OffsetWindow) Samples() []SysoffResult {
+	w.Lock()
+	defer w.Unlock()
+	return w.ordered()
+}
+
+func (w *OffsetWindow) ordered() []SysoffResult {
+	res := make([]SysoffResult, 0, len(w.samples))
+	res = append(res, w.samples[w.next:]...)
+	return append(res, w.samples[:w.next]...)
+}
+
+// Filtered returns samples (oldest first) which are within OutlierFactor scaled MADs from the median
+func (w *OffsetWindow) Filtered() []SysoffResult {
+	w.Lock()
+	defer w.Unlock()
+	filtered, _ := w.filter(w.ordered())
+	return filtered
+}
+
+func (w *OffsetWindow) filter(samples []SysoffResult) ([]SysoffResult, time.Duration) {
+	offsets := make([]float64, len(samples))
+	for i, s := range samples {
+		offsets[i] = float64(s.Offset)
+	}
+	med := median(offsets)
+	mad := medianAbsDeviation(offsets, med)
+	// with zero MAD any deviation would make sample an outlier, so keep everything equal to the median
+	limit := w.OutlierFactor * madScale * mad
+	res := make([]SysoffResult, 0, len(samples))
+	for _, s := range samples {
+		if math.Abs(float64(s.Offset)-med) <= limit {
+			res = append(res, s)
+		}
+	}
+	return res, time.Duration(mad)
+}
+
+// Stats returns summary of the window. Mean, StdDev and drift are calculated over filtered samples.
+func (w *OffsetWindow) Stats() WindowStats {
+	w.Lock()
+	defer w.Unlock()
+	samples := w.ordered()
+	if len(samples) == 0 {
+		return WindowStats{}
+	}
+	offsets := make([]float64, len(samples))
+	for i, s := range samples {
+		offsets[i] = float64(s.Offset)
+	}
+	filtered, mad := w.filter(samples)
+	stats := WindowStats{
+		Samples:  len(samples),
+		Outliers: len(samples) - len(filtered),
+		Last:     samples[len(samples)-1].Offset,
+		Median:   time.Duration(median(offsets)),
+		MAD:      mad,
+		Min:      samples[0].Offset,
+		Max:      samples[0].Offset,
+	}
+	for _, s := range samples {
+		stats.Min = min(stats.Min, s.Offset)
+		stats.Max = max(stats.Max, s.Offset)
+	}
+	if len(filtered) == 0 {
+		return stats
+	}
+	var sum float64
+	for _, s := range filtered {
+		sum += float64(s.Offset)
+	}
+	mean := sum / float64(len(filtered))
+	var sq float64
+	for _, s := range filtered {
+		sq += (float64(s.Offset) - mean) * (float64(s.Offset) - mean)
+	}
+	stats.Mean = time.Duration(mean)
+	stats.StdDev = time.Duration(math.Sqrt(sq / float64(len(filtered))))
+	stats.DriftPPB = driftPPB(filtered)
+	return stats
+}
+
+// Median returns median offset of the window
+func (w *OffsetWindow) Median() time.Duration {
+	return w.Stats().Median
+}
+
+// DriftPPB returns drift of the offset in PPB, estimated by least squares over filtered samples
+func (w *OffsetWindow) DriftPPB() float64 {
+	return w.Stats().DriftPPB
+}
+
+// driftPPB fits offset = a + b*systime and returns b in PPB
+func driftPPB(samples []SysoffResult) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	base := samples[0].SysTime
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := float64(s.SysTime.Sub(base))
+		y := float64(s.Offset)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	n := float64(len(samples))
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denom * 1e9
+}
+
+func median(data []float64) float64 {
+	l := len(data)
+	if l == 0 {
+		return math.NaN()
+	}
+	c := make([]float64, l)
+	copy(c, data)
+	sort.Float64s(c)
+	if l%2 == 0 {
+		return (c[l/2-1] + c[l/2]) / 2
+	}
+	return c[l/2]
+}
+
+func medianAbsDeviation(data []float64, med float64) float64 {
+	dev := make([]float64, len(data))
+	for i, v := range data {
+		dev[i] = math.Abs(v - med)
+	}
+	return median(dev)
+}
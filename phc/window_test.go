@@ -0,0 +1,73 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffsetWindowRolling(t *testing.T) {
+	w := NewOffsetWindow(3)
+	require.Equal(t, 0, w.Len())
+	require.Equal(t, WindowStats{}, w.Stats())
+	for i := 1; i <= 4; i++ {
+		w.Add(SysoffResult{Offset: time.Duration(i) * time.Microsecond})
+	}
+	require.True(t, w.Full())
+	require.Equal(t, 3, w.Len())
+	got := w.Samples()
+	require.Equal(t, []time.Duration{2 * time.Microsecond, 3 * time.Microsecond, 4 * time.Microsecond},
+		[]time.Duration{got[0].Offset, got[1].Offset, got[2].Offset})
+	require.Equal(t, 3*time.Microsecond, w.Median())
+
+	w.Reset()
+	require.Equal(t, 0, w.Len())
+	require.False(t, w.Full())
+}
+
+func TestOffsetWindowOutliers(t *testing.T) {
+	w := NewOffsetWindow(8)
+	for _, o := range []time.Duration{100, 102, 98, 101, 99, 100, 5000, 100} {
+		w.Add(SysoffResult{Offset: o})
+	}
+	stats := w.Stats()
+	require.Equal(t, 8, stats.Samples)
+	require.Equal(t, 1, stats.Outliers)
+	require.Equal(t, time.Duration(100), stats.Median)
+	require.Equal(t, time.Duration(1), stats.MAD)
+	require.Equal(t, time.Duration(98), stats.Min)
+	require.Equal(t, time.Duration(5000), stats.Max)
+	require.Equal(t, time.Duration(100), stats.Mean)
+	require.Equal(t, time.Duration(100), stats.Last)
+	require.Len(t, w.Filtered(), 7)
+}
+
+func TestOffsetWindowDrift(t *testing.T) {
+	w := NewOffsetWindow(10)
+	start := time.Unix(1667818190, 0)
+	for i := 0; i < 10; i++ {
+		// offset grows by 50ns every second
+		w.Add(SysoffResult{
+			SysTime: start.Add(time.Duration(i) * time.Second),
+			Offset:  time.Duration(i) * 50 * time.Nanosecond,
+		})
+	}
+	require.InDelta(t, 50.0, w.DriftPPB(), 0.001)
+}
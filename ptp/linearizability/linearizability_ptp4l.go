@@ -84,7 +84,7 @@ func reqUnicast(clockID ptp.ClockIdentity, port uint16, duration time.Duration,
 				PortNumber:    port,
 				ClockIdentity: clockID,
 			},
-			LogMessageInterval: 0x7f,
+			LogMessageInterval: ptp.LogIntervalUnspecified,
 		},
 		TargetPortIdentity: ptp.PortIdentity{
 			PortNumber:    0xffff,
@@ -117,7 +117,7 @@ func reqDelay(clockID ptp.ClockIdentity, port uint16) *ptp.SyncDelayReq {
 				PortNumber:    port,
 				ClockIdentity: clockID,
 			},
-			LogMessageInterval: 0x7f,
+			LogMessageInterval: ptp.LogIntervalUnspecified,
 		},
 	}
 }
@@ -0,0 +1,60 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"slices"
+)
+
+// Append-style marshaling lets hot paths reuse one buffer for all packets they send.
+// Packets which may carry TLVs need spare capacity of maxTLVPacketSize bytes to be marshaled without allocation.
+// Decoding into the same struct is allocation-free as well: UnmarshalBinary
+// reuses the TLVs slice of the packet instead of appending to it.
+
+// maxTLVPacketSize is the buffer size we use to marshal packets which may carry TLVs
+const maxTLVPacketSize = 508
+
+// appendTo grows b to fit up to size more bytes and marshals p after its current content
+func appendTo(b []byte, size int, p BinaryMarshalerTo) ([]byte, error) {
+	l := len(b)
+	b = slices.Grow(b, size)
+	n, err := p.MarshalBinaryTo(b[l : l+size])
+	if err != nil {
+		return b[:l], err
+	}
+	return b[:l+n], nil
+}
+
+// AppendTo appends binary representation of SyncDelayReq to b, without TrailingBytes
+func (p *SyncDelayReq) AppendTo(b []byte) ([]byte, error) {
+	return appendTo(b, maxTLVPacketSize, p)
+}
+
+// AppendTo appends binary representation of DelayResp to b, without TrailingBytes
+func (p *DelayResp) AppendTo(b []byte) ([]byte, error) {
+	return appendTo(b, 54, p)
+}
+
+// AppendTo appends binary representation of FollowUp to b, without TrailingBytes
+func (p *FollowUp) AppendTo(b []byte) ([]byte, error) {
+	return appendTo(b, 44, p)
+}
+
+// AppendTo appends binary representation of Announce to b, without TrailingBytes
+func (p *Announce) AppendTo(b []byte) ([]byte, error) {
+	return appendTo(b, maxTLVPacketSize, p)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func appendTestPackets() []interface {
+	Packet
+	AppendTo([]byte) ([]byte, error)
+} {
+	source := PortIdentity{PortNumber: 1, ClockIdentity: 36138748164966842}
+	ts := NewTimestamp(time.Unix(1700000000, 123456789))
+	announce := &Announce{
+		Header: Header{
+			SdoIDAndMsgType:    NewSdoIDAndMsgType(MessageAnnounce, 0),
+			Version:            Version,
+			MessageLength:      headerSize + announceBodySize,
+			SourcePortIdentity: source,
+		},
+		AnnounceBody: AnnounceBody{GrandmasterIdentity: source.ClockIdentity},
+	}
+	announce.SetPathTrace([]ClockIdentity{source.ClockIdentity})
+	return []interface {
+		Packet
+		AppendTo([]byte) ([]byte, error)
+	}{
+		&SyncDelayReq{
+			Header:           Header{SdoIDAndMsgType: NewSdoIDAndMsgType(MessageSync, 0), Version: Version, MessageLength: 44, SourcePortIdentity: source},
+			SyncDelayReqBody: SyncDelayReqBody{OriginTimestamp: ts},
+		},
+		&SyncDelayReq{
+			Header: Header{SdoIDAndMsgType: NewSdoIDAndMsgType(MessageDelayReq, 0), Version: Version, MessageLength: 50, SourcePortIdentity: source},
+			TLVs:   []TLV{&AlternateResponsePortTLV{TLVHead: TLVHead{TLVType: TLVAlternateResponsePort, LengthField: 2}, Offset: 1}},
+		},
+		&DelayResp{
+			Header:        Header{SdoIDAndMsgType: NewSdoIDAndMsgType(MessageDelayResp, 0), Version: Version, MessageLength: 54, SourcePortIdentity: source},
+			DelayRespBody: DelayRespBody{ReceiveTimestamp: ts, RequestingPortIdentity: source},
+		},
+		&FollowUp{
+			Header:       Header{SdoIDAndMsgType: NewSdoIDAndMsgType(MessageFollowUp, 0), Version: Version, MessageLength: 44, SourcePortIdentity: source},
+			FollowUpBody: FollowUpBody{PreciseOriginTimestamp: ts},
+		},
+		announce,
+	}
+}
+
+func TestAppendTo(t *testing.T) {
+	for _, p := range appendTestPackets() {
+		t.Run(p.MessageType().String(), func(t *testing.T) {
+			want, err := Bytes(p)
+			require.NoError(t, err)
+			want = want[:len(want)-TrailingBytes]
+
+			b, err := p.AppendTo(nil)
+			require.NoError(t, err)
+			require.Equal(t, want, b)
+
+			prefix := []byte{1, 2, 3}
+			b, err = p.AppendTo(prefix)
+			require.NoError(t, err)
+			require.Equal(t, append([]byte{1, 2, 3}, want...), b)
+
+			buf := make([]byte, 0, 1024)
+			allocs := testing.AllocsPerRun(100, func() {
+				buf, _ = p.AppendTo(buf[:0])
+			})
+			require.Zero(t, allocs)
+			require.Equal(t, want, buf)
+		})
+	}
+}
+
+func TestUnmarshalReusesTLVs(t *testing.T) {
+	raw := []uint8("\x0b\x12\x00\x4c\x00\x00\x04\x08\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x08\xc0\xeb\xff\xfe\x63\x7a\x4e\x00\x01\x00\x00\x05\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x25\x00\x80\xf8\xfe\xff\xff\x80\x08\xc0\xeb\xff\xfe\x63\x7a\x4e\x00\x00\xa0\x00\x08\x00\x18\x08\xc0\xeb\xff\xfe\x63\x7a\x4e\x01\xb6\xaf\xc4\xe5\x46\x12\x29\x04\xc0\x87\x32\xf0\x61\xee\xce\x00\x00")
+	p := &Announce{}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, p.UnmarshalBinary(raw))
+		require.Len(t, p.TLVs, 1)
+	}
+
+	sync, err := appendTestPackets()[0].AppendTo(nil)
+	require.NoError(t, err)
+	s := &SyncDelayReq{}
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = s.UnmarshalBinary(sync)
+	})
+	require.Zero(t, allocs)
+}
+
+func BenchmarkAppendSync(b *testing.B) {
+	p := appendTestPackets()[0]
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		buf, _ = p.AppendTo(buf[:0])
+	}
+}
+
+func BenchmarkAppendAnnounce(b *testing.B) {
+	p := appendTestPackets()[4]
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		buf, _ = p.AppendTo(buf[:0])
+	}
+}
@@ -0,0 +1,335 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+// 16.14 PTP integrated security mechanism
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// secParamIndicator flags, Table 131
+const (
+	SecParamDisclosedKey uint8 = 1 << 0 // disclosedKey field is present, used for delayed security processing
+	SecParamSequenceNo   uint8 = 1 << 1 // sequenceNo field is present
+	SecParamRES          uint8 = 1 << 2 // RES field is present
+)
+
+// authTLVFixedSize is the size of AUTHENTICATION TLV fields between the head and ICV
+const authTLVFixedSize = 6
+
+// errors returned by authentication helpers
+var (
+	ErrAuthNoTLV          = errors.New("no AUTHENTICATION TLV found")
+	ErrAuthUnknownSPP     = errors.New("unknown security parameter pointer")
+	ErrAuthUnknownKey     = errors.New("unknown key id")
+	ErrAuthICVMismatch    = errors.New("ICV mismatch")
+	ErrAuthDelayedSecProc = errors.New("delayed security processing is not supported")
+)
+
+// AuthenticationTLV Table 130 AUTHENTICATION TLV format.
+// Only immediate security processing is supported, so the optional disclosedKey, sequenceNo and RES fields are never present.
+type AuthenticationTLV struct {
+	TLVHead
+	SPP               uint8
+	SecParamIndicator uint8
+	KeyID             uint32
+	ICV               []byte
+}
+
+// MarshalBinaryTo marshals bytes to AuthenticationTLV
+func (t *AuthenticationTLV) MarshalBinaryTo(b []byte) (int, error) {
+	size := tlvHeadSize + authTLVFixedSize + len(t.ICV)
+	if len(b) < size {
+		return 0, fmt.Errorf("not enough buffer to write AuthenticationTLV")
+	}
+	tlvHeadMarshalBinaryTo(&t.TLVHead, b)
+	b[tlvHeadSize] = t.SPP
+	b[tlvHeadSize+1] = t.SecParamIndicator
+	binary.BigEndian.PutUint32(b[tlvHeadSize+2:], t.KeyID)
+	copy(b[tlvHeadSize+authTLVFixedSize:], t.ICV)
+	return size, nil
+}
+
+// UnmarshalBinary parses []byte and populates struct fields
+func (t *AuthenticationTLV) UnmarshalBinary(b []byte) error {
+	if err := unmarshalTLVHeader(&t.TLVHead, b); err != nil {
+		return err
+	}
+	if err := checkTLVLength(&t.TLVHead, len(b), authTLVFixedSize, false); err != nil {
+		return err
+	}
+	t.SPP = b[tlvHeadSize]
+	t.SecParamIndicator = b[tlvHeadSize+1]
+	if t.SecParamIndicator != 0 {
+		return ErrAuthDelayedSecProc
+	}
+	t.KeyID = binary.BigEndian.Uint32(b[tlvHeadSize+2:])
+	t.ICV = make([]byte, int(t.LengthField)-authTLVFixedSize)
+	copy(t.ICV, b[tlvHeadSize+authTLVFixedSize:])
+	return nil
+}
+
+// AuthAlgorithm is an integrity algorithm used to compute ICV
+type AuthAlgorithm uint8
+
+// Supported integrity algorithms
+const (
+	// AuthHMACSHA256_128 is HMAC-SHA256 truncated to 128 bits, as in the example of Annex P
+	AuthHMACSHA256_128 AuthAlgorithm = iota //nolint:revive
+	// AuthHMACSHA256 is HMAC-SHA256 with full 256 bit ICV
+	AuthHMACSHA256
+)
+
+// AuthAlgorithmToString is a map from AuthAlgorithm to string
+var AuthAlgorithmToString = map[AuthAlgorithm]string{
+	AuthHMACSHA256_128: "HMAC-SHA256-128",
+	AuthHMACSHA256:     "HMAC-SHA256",
+}
+
+func (a AuthAlgorithm) String() string {
+	return AuthAlgorithmToString[a]
+}
+
+// ICVLength returns length of ICV produced by the algorithm
+func (a AuthAlgorithm) ICVLength() int {
+	switch a {
+	case AuthHMACSHA256_128:
+		return 16
+	default:
+		return sha256.Size
+	}
+}
+
+func (a AuthAlgorithm) newHash(secret []byte) (hash.Hash, error) {
+	switch a {
+	case AuthHMACSHA256_128, AuthHMACSHA256:
+		return hmac.New(sha256.New, secret), nil
+	default:
+		return nil, fmt.Errorf("unsupported auth algorithm %d", a)
+	}
+}
+
+// AuthKey is a key of the security association
+type AuthKey struct {
+	ID        uint32
+	Algorithm AuthAlgorithm
+	Secret    []byte
+}
+
+// ComputeICV computes ICV over the PTP message, which must end right before ICV.
+// correctionField is excluded from ICV computation as transparent clocks modify it.
+func (k *AuthKey) ComputeICV(msg []byte) ([]byte, error) {
+	if len(msg) < headerSize {
+		return nil, fmt.Errorf("message is too short to compute ICV")
+	}
+	h, err := k.Algorithm.newHash(k.Secret)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(msg[:8])
+	h.Write(make([]byte, 8)) // zero correctionField
+	h.Write(msg[16:])
+	return h.Sum(nil)[:k.Algorithm.ICVLength()], nil
+}
+
+// SecurityAssociation is a set of keys shared by the parties, identified by the security parameter pointer
+type SecurityAssociation struct {
+	SPP uint8
+	// KeyID is the key messages are signed with
+	KeyID uint32
+	keys  map[uint32]*AuthKey
+}
+
+// NewSecurityAssociation creates SecurityAssociation signing messages with the key.
+// More keys can be added with AddKey to allow key rollover.
+func NewSecurityAssociation(spp uint8, key *AuthKey) *SecurityAssociation {
+	sa := &SecurityAssociation{SPP: spp, KeyID: key.ID, keys: map[uint32]*AuthKey{}}
+	sa.AddKey(key)
+	return sa
+}
+
+// AddKey adds key accepted during verification
+func (sa *SecurityAssociation) AddKey(key *AuthKey) {
+	sa.keys[key.ID] = key
+}
+
+// RemoveKey removes key, it's not possible to remove the key messages are signed with
+func (sa *SecurityAssociation) RemoveKey(id uint32) error {
+	if id == sa.KeyID {
+		return fmt.Errorf("key %d is in use", id)
+	}
+	delete(sa.keys, id)
+	return nil
+}
+
+// Key returns the key by id
+func (sa *SecurityAssociation) Key(id uint32) (*AuthKey, error) {
+	key, ok := sa.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %d", ErrAuthUnknownKey, id)
+	}
+	return key, nil
+}
+
+// Sign appends AUTHENTICATION TLV to the marshaled PTP message, updating messageLength.
+// Bytes after messageLength, like TrailingBytes, are kept after the TLV.
+func (sa *SecurityAssociation) Sign(b []byte) ([]byte, error) {
+	key, err := sa.Key(sa.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < headerSize {
+		return nil, fmt.Errorf("message is too short to sign")
+	}
+	msgLen := int(binary.BigEndian.Uint16(b[2:]))
+	if msgLen > len(b) {
+		return nil, fmt.Errorf("cannot sign message of length %d from %d bytes", msgLen, len(b))
+	}
+	icvLen := key.Algorithm.ICVLength()
+	tlv := &AuthenticationTLV{
+		TLVHead: TLVHead{
+			TLVType:     TLVAuthentication,
+			LengthField: uint16(authTLVFixedSize + icvLen), //#nosec G115
+		},
+		SPP:   sa.SPP,
+		KeyID: key.ID,
+		ICV:   make([]byte, icvLen),
+	}
+	tlvSize := tlvHeadSize + int(tlv.LengthField)
+	res := make([]byte, len(b)+tlvSize)
+	copy(res, b[:msgLen])
+	copy(res[msgLen+tlvSize:], b[msgLen:])
+	binary.BigEndian.PutUint16(res[2:], uint16(msgLen+tlvSize)) //#nosec G115
+	if _, err := tlv.MarshalBinaryTo(res[msgLen:]); err != nil {
+		return nil, err
+	}
+	icvPos := msgLen + tlvSize - icvLen
+	icv, err := key.ComputeICV(res[:icvPos])
+	if err != nil {
+		return nil, err
+	}
+	copy(res[icvPos:], icv)
+	return res, nil
+}
+
+// Verify checks ICV of AUTHENTICATION TLV, which must be the last TLV of the marshaled PTP message
+func (sa *SecurityAssociation) Verify(b []byte) error {
+	tlv, pos, err := findAuthenticationTLV(b)
+	if err != nil {
+		return err
+	}
+	return sa.verify(b, tlv, pos)
+}
+
+func (sa *SecurityAssociation) verify(b []byte, tlv *AuthenticationTLV, pos int) error {
+	if tlv.SPP != sa.SPP {
+		return fmt.Errorf("%w %d", ErrAuthUnknownSPP, tlv.SPP)
+	}
+	key, err := sa.Key(tlv.KeyID)
+	if err != nil {
+		return err
+	}
+	if len(tlv.ICV) != key.Algorithm.ICVLength() {
+		return fmt.Errorf("%w: got ICV of length %d, %s needs %d", ErrAuthICVMismatch, len(tlv.ICV), key.Algorithm, key.Algorithm.ICVLength())
+	}
+	icv, err := key.ComputeICV(b[:pos+tlvHeadSize+authTLVFixedSize])
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(icv, tlv.ICV) {
+		return ErrAuthICVMismatch
+	}
+	return nil
+}
+
+// SecurityAssociations is a set of security associations keyed by SPP
+type SecurityAssociations map[uint8]*SecurityAssociation
+
+// Add adds security association
+func (s SecurityAssociations) Add(sa *SecurityAssociation) {
+	s[sa.SPP] = sa
+}
+
+// Verify checks ICV of AUTHENTICATION TLV using the security association it points to
+func (s SecurityAssociations) Verify(b []byte) error {
+	tlv, pos, err := findAuthenticationTLV(b)
+	if err != nil {
+		return err
+	}
+	sa, ok := s[tlv.SPP]
+	if !ok {
+		return fmt.Errorf("%w %d", ErrAuthUnknownSPP, tlv.SPP)
+	}
+	return sa.verify(b, tlv, pos)
+}
+
+// tlvOffset returns where TLVs start in the message of given type
+func tlvOffset(t MessageType) (int, error) {
+	switch t {
+	case MessageSync, MessageDelayReq, MessageFollowUp, MessageSignaling:
+		return headerSize + 10, nil
+	case MessageDelayResp, MessagePDelayReq, MessagePDelayResp, MessagePDelayRespFollowUp:
+		return headerSize + 20, nil
+	case MessageAnnounce:
+		return headerSize + 30, nil
+	case MessageManagement:
+		return headerSize + 14, nil
+	default:
+		return 0, fmt.Errorf("unsupported message type %s", t)
+	}
+}
+
+// findAuthenticationTLV returns AUTHENTICATION TLV which ends the message and its position
+func findAuthenticationTLV(b []byte) (*AuthenticationTLV, int, error) {
+	if len(b) < headerSize {
+		return nil, 0, fmt.Errorf("message is too short")
+	}
+	msgLen := int(binary.BigEndian.Uint16(b[2:]))
+	if msgLen > len(b) {
+		return nil, 0, fmt.Errorf("cannot decode message of length %d from %d bytes", msgLen, len(b))
+	}
+	pos, err := tlvOffset(SdoIDAndMsgType(b[0]).MsgType())
+	if err != nil {
+		return nil, 0, err
+	}
+	for pos+tlvHeadSize <= msgLen {
+		tlvType := TLVType(binary.BigEndian.Uint16(b[pos:]))
+		length := int(binary.BigEndian.Uint16(b[pos+2:]))
+		end := pos + tlvHeadSize + length
+		if end > msgLen {
+			return nil, 0, fmt.Errorf("TLV %s of length %d doesn't fit into message", tlvType, length)
+		}
+		if tlvType == TLVAuthentication {
+			if end != msgLen {
+				return nil, 0, fmt.Errorf("AUTHENTICATION TLV must be the last TLV")
+			}
+			tlv := &AuthenticationTLV{}
+			if err := tlv.UnmarshalBinary(b[pos:end]); err != nil {
+				return nil, 0, err
+			}
+			return tlv, pos, nil
+		}
+		pos = end
+	}
+	return nil, 0, ErrAuthNoTLV
+}
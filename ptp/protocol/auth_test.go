@@ -0,0 +1,168 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testAnnounceBytes(t *testing.T) []byte {
+	announce := &Announce{
+		Header: Header{
+			SdoIDAndMsgType: NewSdoIDAndMsgType(MessageAnnounce, 0),
+			Version:         Version,
+			MessageLength:   64,
+			FlagField:       FlagUnicast | FlagPTPTimescale,
+			SequenceID:      42,
+			SourcePortIdentity: PortIdentity{
+				PortNumber:    1,
+				ClockIdentity: 36138748164966842,
+			},
+		},
+		AnnounceBody: AnnounceBody{
+			GrandmasterPriority1: 128,
+			GrandmasterClockQuality: ClockQuality{
+				ClockClass:              ClockClass6,
+				ClockAccuracy:           ClockAccuracyNanosecond100,
+				OffsetScaledLogVariance: 23008,
+			},
+			GrandmasterPriority2: 128,
+			GrandmasterIdentity:  36138748164966842,
+			TimeSource:           TimeSourceGNSS,
+		},
+	}
+	b, err := Bytes(announce)
+	require.NoError(t, err)
+	return b
+}
+
+func TestAuthenticationTLVMarshal(t *testing.T) {
+	tlv := &AuthenticationTLV{
+		TLVHead: TLVHead{TLVType: TLVAuthentication, LengthField: 10},
+		SPP:     3,
+		KeyID:   0xdeadbeef,
+		ICV:     []byte{1, 2, 3, 4},
+	}
+	b := make([]byte, 14)
+	n, err := tlv.MarshalBinaryTo(b)
+	require.NoError(t, err)
+	require.Equal(t, 14, n)
+	require.Equal(t, []byte{0x80, 0x09, 0x00, 0x0a, 0x03, 0x00, 0xde, 0xad, 0xbe, 0xef, 1, 2, 3, 4}, b)
+
+	got := &AuthenticationTLV{}
+	require.NoError(t, got.UnmarshalBinary(b))
+	require.Equal(t, tlv, got)
+
+	_, err = tlv.MarshalBinaryTo(make([]byte, 13))
+	require.Error(t, err)
+
+	b[5] = SecParamSequenceNo
+	require.ErrorIs(t, got.UnmarshalBinary(b), ErrAuthDelayedSecProc)
+	require.Error(t, got.UnmarshalBinary(b[:8]))
+}
+
+func TestSecurityAssociationSignVerify(t *testing.T) {
+	key := &AuthKey{ID: 1, Algorithm: AuthHMACSHA256_128, Secret: []byte("secret")}
+	sa := NewSecurityAssociation(2, key)
+
+	b := testAnnounceBytes(t)
+	signed, err := sa.Sign(b)
+	require.NoError(t, err)
+	require.Len(t, signed, len(b)+26)
+	require.Equal(t, uint16(64+26), binary.BigEndian.Uint16(signed[2:]))
+	// trailing bytes are kept
+	require.Equal(t, twoZeros, signed[len(signed)-2:])
+	require.NoError(t, sa.Verify(signed))
+
+	// the signed message is still a valid Announce carrying the TLV
+	announce := &Announce{}
+	require.NoError(t, FromBytes(signed, announce))
+	require.Len(t, announce.TLVs, 1)
+	tlv := announce.TLVs[0].(*AuthenticationTLV)
+	require.Equal(t, uint8(2), tlv.SPP)
+	require.Equal(t, uint32(1), tlv.KeyID)
+	require.Len(t, tlv.ICV, 16)
+
+	// transparent clocks may update correctionField
+	binary.BigEndian.PutUint64(signed[8:], 12345)
+	require.NoError(t, sa.Verify(signed))
+
+	// any other change is detected
+	signed[headerSize] = 1
+	require.ErrorIs(t, sa.Verify(signed), ErrAuthICVMismatch)
+
+	require.ErrorIs(t, sa.Verify(b), ErrAuthNoTLV)
+}
+
+func TestSecurityAssociationKeys(t *testing.T) {
+	oldKey := &AuthKey{ID: 1, Algorithm: AuthHMACSHA256, Secret: []byte("old")}
+	newKey := &AuthKey{ID: 2, Algorithm: AuthHMACSHA256, Secret: []byte("new")}
+	sender := NewSecurityAssociation(1, oldKey)
+	receiver := NewSecurityAssociation(1, newKey)
+
+	signed, err := sender.Sign(testAnnounceBytes(t))
+	require.NoError(t, err)
+	require.ErrorIs(t, receiver.Verify(signed), ErrAuthUnknownKey)
+
+	// key rollover
+	receiver.AddKey(oldKey)
+	require.NoError(t, receiver.Verify(signed))
+	require.Error(t, receiver.RemoveKey(2))
+	require.NoError(t, receiver.RemoveKey(1))
+	require.ErrorIs(t, receiver.Verify(signed), ErrAuthUnknownKey)
+
+	// same key id with different secret
+	receiver.AddKey(&AuthKey{ID: 1, Algorithm: AuthHMACSHA256, Secret: []byte("wrong")})
+	require.ErrorIs(t, receiver.Verify(signed), ErrAuthICVMismatch)
+
+	// ICV length doesn't match the key algorithm
+	receiver.AddKey(&AuthKey{ID: 1, Algorithm: AuthHMACSHA256_128, Secret: []byte("old")})
+	require.ErrorIs(t, receiver.Verify(signed), ErrAuthICVMismatch)
+}
+
+func TestSecurityAssociations(t *testing.T) {
+	sa1 := NewSecurityAssociation(1, &AuthKey{ID: 1, Secret: []byte("one")})
+	sa2 := NewSecurityAssociation(2, &AuthKey{ID: 1, Secret: []byte("two")})
+	sas := SecurityAssociations{}
+	sas.Add(sa1)
+
+	signed, err := sa2.Sign(testAnnounceBytes(t))
+	require.NoError(t, err)
+	require.ErrorIs(t, sas.Verify(signed), ErrAuthUnknownSPP)
+	require.ErrorIs(t, sa1.Verify(signed), ErrAuthUnknownSPP)
+
+	sas.Add(sa2)
+	require.NoError(t, sas.Verify(signed))
+}
+
+func TestFindAuthenticationTLVNotLast(t *testing.T) {
+	sa := NewSecurityAssociation(1, &AuthKey{ID: 1, Secret: []byte("one")})
+	signed, err := sa.Sign(testAnnounceBytes(t))
+	require.NoError(t, err)
+	// append PATH_TRACE TLV after AUTHENTICATION
+	msgLen := int(binary.BigEndian.Uint16(signed[2:]))
+	b := append([]byte{}, signed[:msgLen]...)
+	b = append(b, 0x00, 0x08, 0x00, 0x08, 0, 0, 0, 0, 0, 0, 0, 1)
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+	require.ErrorContains(t, sa.Verify(b), "must be the last TLV")
+
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)+10))
+	require.Error(t, sa.Verify(b))
+}
@@ -0,0 +1,114 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"math"
+	"time"
+)
+
+// correctionTooBig is the Correction value which means it's too big to be represented
+const correctionTooBig = Correction(math.MaxInt64)
+
+// Rounding defines how sub-nanosecond part of Correction is handled when converting it to time.Duration
+type Rounding int
+
+// Rounding modes
+const (
+	// RoundTowardZero drops the sub-nanosecond part
+	RoundTowardZero Rounding = iota
+	// RoundDown rounds toward negative infinity
+	RoundDown
+	// RoundUp rounds toward positive infinity
+	RoundUp
+	// RoundNearest rounds to the nearest nanosecond, half away from zero
+	RoundNearest
+)
+
+// NewCorrectionFromDuration returns Correction built from time.Duration.
+// Durations which can't be represented saturate to the too big value.
+func NewCorrectionFromDuration(d time.Duration) Correction {
+	if d > math.MaxInt64/twoPow16 || d < math.MinInt64/twoPow16 {
+		return correctionTooBig
+	}
+	return Correction(int64(d) * twoPow16)
+}
+
+// DurationRounded converts Correction to time.Duration, rounding sub-nanosecond part as requested.
+// Too big correction converts to 0.
+func (t Correction) DurationRounded(r Rounding) time.Duration {
+	if t.TooBig() {
+		return 0
+	}
+	v := int64(t)
+	ns := v / twoPow16
+	frac := v % twoPow16 // same sign as v
+	switch r {
+	case RoundDown:
+		if frac < 0 {
+			ns--
+		}
+	case RoundUp:
+		if frac > 0 {
+			ns++
+		}
+	case RoundNearest:
+		if frac >= twoPow16/2 {
+			ns++
+		} else if frac <= -twoPow16/2 {
+			ns--
+		}
+	}
+	return time.Duration(ns)
+}
+
+// SubNanoseconds returns the sub-nanosecond part of Correction in units of 2^-16 ns, with the sign of Correction
+func (t Correction) SubNanoseconds() int64 {
+	return int64(t) % twoPow16
+}
+
+// Add returns sum of two corrections. Too big value is sticky, and overflowing results saturate to it.
+func (t Correction) Add(u Correction) Correction {
+	if t.TooBig() || u.TooBig() {
+		return correctionTooBig
+	}
+	sum := t + u
+	// overflow happens when both operands have the same sign and the result has a different one
+	if (t >= 0) == (u >= 0) && (sum >= 0) != (t >= 0) {
+		return correctionTooBig
+	}
+	if sum == math.MinInt64 {
+		return correctionTooBig
+	}
+	return sum
+}
+
+// Sub returns difference of two corrections with the same saturation behavior as Add
+func (t Correction) Sub(u Correction) Correction {
+	if u.TooBig() {
+		return correctionTooBig
+	}
+	if u == math.MinInt64 {
+		return correctionTooBig
+	}
+	return t.Add(-u)
+}
+
+// AddDuration adds time.Duration to Correction with the same saturation behavior as Add
+func (t Correction) AddDuration(d time.Duration) Correction {
+	return t.Add(NewCorrectionFromDuration(d))
+}
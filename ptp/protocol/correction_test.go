@@ -0,0 +1,87 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCorrectionFromDuration(t *testing.T) {
+	require.Equal(t, Correction(65536000000), NewCorrectionFromDuration(time.Millisecond))
+	require.Equal(t, Correction(-65536), NewCorrectionFromDuration(-time.Nanosecond))
+	require.Equal(t, Correction(0), NewCorrectionFromDuration(0))
+	require.True(t, NewCorrectionFromDuration(40*time.Hour).TooBig())
+	require.True(t, NewCorrectionFromDuration(-40*time.Hour).TooBig())
+	require.Equal(t, 39*time.Hour, NewCorrectionFromDuration(39*time.Hour).Duration())
+}
+
+func TestCorrectionDurationRounded(t *testing.T) {
+	tests := []struct {
+		in                          Correction
+		towardZero, down, up, round time.Duration
+	}{
+		{in: NewCorrection(2.5), towardZero: 2, down: 2, up: 3, round: 3},
+		{in: NewCorrection(2.25), towardZero: 2, down: 2, up: 3, round: 2},
+		{in: NewCorrection(-2.5), towardZero: -2, down: -3, up: -2, round: -3},
+		{in: NewCorrection(-2.75), towardZero: -2, down: -3, up: -2, round: -3},
+		{in: NewCorrection(7), towardZero: 7, down: 7, up: 7, round: 7},
+		{in: correctionTooBig, towardZero: 0, down: 0, up: 0, round: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in.String(), func(t *testing.T) {
+			require.Equal(t, tt.towardZero, tt.in.DurationRounded(RoundTowardZero))
+			require.Equal(t, tt.towardZero, tt.in.Duration())
+			require.Equal(t, tt.down, tt.in.DurationRounded(RoundDown))
+			require.Equal(t, tt.up, tt.in.DurationRounded(RoundUp))
+			require.Equal(t, tt.round, tt.in.DurationRounded(RoundNearest))
+		})
+	}
+}
+
+func TestCorrectionSubNanoseconds(t *testing.T) {
+	require.Equal(t, int64(0x8000), NewCorrection(2.5).SubNanoseconds())
+	require.Equal(t, int64(-0x4000), NewCorrection(-1.25).SubNanoseconds())
+	require.Equal(t, int64(0), NewCorrection(3).SubNanoseconds())
+}
+
+func TestCorrectionArithmetic(t *testing.T) {
+	a := NewCorrection(2.5)
+	b := NewCorrection(0.75)
+	require.Equal(t, NewCorrection(3.25), a.Add(b))
+	require.Equal(t, NewCorrection(1.75), a.Sub(b))
+	require.Equal(t, NewCorrection(-1.75), b.Sub(a))
+	require.Equal(t, NewCorrection(1002.5), a.AddDuration(time.Microsecond))
+
+	almostTooBig := Correction(math.MaxInt64 - 1)
+	require.True(t, almostTooBig.Add(b).TooBig())
+	require.True(t, Correction(math.MinInt64+1).Sub(b).TooBig())
+	require.True(t, Correction(math.MinInt64+1).Add(-b).TooBig())
+	require.True(t, b.Sub(Correction(math.MinInt64)).TooBig())
+	require.Equal(t, Correction(math.MaxInt64-1), almostTooBig.Add(0))
+	require.Equal(t, Correction(0), almostTooBig.Sub(almostTooBig))
+
+	// too big is sticky
+	require.True(t, correctionTooBig.Add(-a).TooBig())
+	require.True(t, a.Sub(correctionTooBig).TooBig())
+	require.True(t, a.AddDuration(100*time.Hour).TooBig())
+
+	require.True(t, NewCorrection(-1e15).TooBig())
+}
@@ -0,0 +1,192 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// maxDumpHexBytes is how many bytes of a single field are shown in the hex column
+const maxDumpHexBytes = 8
+
+// dumpHexRow is how many bytes are shown in one row of raw data
+const dumpHexRow = 16
+
+var tlvInterface = reflect.TypeOf((*TLV)(nil)).Elem()
+
+// dumper keeps track of the offset of the field being printed.
+// Offset becomes unknown (-1) after a field which size can't be derived from its type,
+// the rest of such TLV is then printed as raw bytes next to the decoded values.
+type dumper struct {
+	w    strings.Builder
+	b    []byte
+	off  int
+	lost int
+}
+
+// Dump decodes PTP message and renders it as an annotated text block,
+// with offset, raw bytes and decoded value of every field, similar to what wireshark shows
+func Dump(b []byte) (string, error) {
+	p, err := DecodePacket(b)
+	if err != nil {
+		return "", err
+	}
+	return dumpPacket(p, b), nil
+}
+
+// DumpPacket renders PTP message like Dump does
+func DumpPacket(p Packet) (string, error) {
+	b, err := Bytes(p)
+	if err != nil {
+		return "", err
+	}
+	return dumpPacket(p, b[:len(b)-TrailingBytes]), nil
+}
+
+func dumpPacket(p Packet, b []byte) string {
+	d := &dumper{b: b}
+	fmt.Fprintf(&d.w, "%s, %d bytes\n", p.MessageType(), len(b))
+	if _, ok := p.(*V1Packet); ok {
+		// V1Packet doesn't follow the wire layout
+		d.off = -1
+	}
+	d.walk(reflect.ValueOf(p), "", len(b), false)
+	if d.off < 0 {
+		d.raw(d.lost, len(b), "")
+	} else if d.off < len(b) {
+		d.raw(d.off, len(b), "trailing bytes")
+	}
+	return d.w.String()
+}
+
+func (d *dumper) hex(off, size int) string {
+	if off < 0 || size <= 0 || off+size > len(d.b) {
+		return ""
+	}
+	b := d.b[off : off+size]
+	suffix := ""
+	if len(b) > maxDumpHexBytes {
+		b = b[:maxDumpHexBytes]
+		suffix = " .."
+	}
+	return fmt.Sprintf("% x%s", b, suffix)
+}
+
+func (d *dumper) line(off, size int, name string, value any) {
+	offset := "    "
+	if off >= 0 {
+		offset = fmt.Sprintf("%04x", off)
+	}
+	fmt.Fprintf(&d.w, "%s  %-26s %s: %v\n", offset, d.hex(off, size), name, value)
+}
+
+// raw prints bytes from off to end in rows
+func (d *dumper) raw(off, end int, name string) {
+	end = min(end, len(d.b))
+	for ; off >= 0 && off < end; off += dumpHexRow {
+		n := min(dumpHexRow, end-off)
+		fmt.Fprintf(&d.w, "%04x  % x\n", off, d.b[off:off+n])
+		if name != "" {
+			fmt.Fprintf(&d.w, "      ^ %s\n", name)
+			name = ""
+		}
+	}
+}
+
+func isDumpLeaf(v reflect.Value) bool {
+	if _, ok := v.Interface().(fmt.Stringer); ok {
+		return true
+	}
+	return v.Kind() != reflect.Struct
+}
+
+func dumpName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// walk prints v and its fields, within [d.off, end) part of the message
+func (d *dumper) walk(v reflect.Value, name string, end int, inTLV bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			d.line(-1, 0, name, "<nil>")
+			return
+		}
+		v = v.Elem()
+	}
+	if !inTLV && (v.Type().Implements(tlvInterface) || reflect.PointerTo(v.Type()).Implements(tlvInterface)) {
+		d.tlv(v, name)
+		return
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem() == tlvInterface {
+		for i := 0; i < v.Len(); i++ {
+			d.walk(v.Index(i), fmt.Sprintf("%s[%d]", name, i), end, inTLV)
+		}
+		return
+	}
+	if !isDumpLeaf(v) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			fieldName := dumpName(name, f.Name)
+			if f.Anonymous {
+				fieldName = name
+			}
+			d.walk(v.Field(i), fieldName, end, inTLV)
+		}
+		return
+	}
+	size := binary.Size(v.Interface())
+	if size < 0 || d.off < 0 || d.off+size > end {
+		if d.off >= 0 {
+			d.lost = d.off
+			d.off = -1
+		}
+		d.line(-1, 0, name, v.Interface())
+		return
+	}
+	d.line(d.off, size, name, v.Interface())
+	d.off += size
+}
+
+// tlv prints TLV header and fields, followed by whatever's left of its value
+func (d *dumper) tlv(v reflect.Value, name string) {
+	start := d.off
+	end := len(d.b)
+	if start >= 0 && start+tlvHeadSize <= len(d.b) {
+		end = min(len(d.b), start+tlvHeadSize+int(binary.BigEndian.Uint16(d.b[start+2:])))
+	}
+	d.walk(v, name, end, true)
+	if start < 0 {
+		return
+	}
+	switch {
+	case d.off < 0:
+		d.raw(d.lost, end, "")
+	case d.off < end:
+		d.raw(d.off, end, "padding")
+	}
+	d.off = end
+}
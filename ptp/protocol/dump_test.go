@@ -0,0 +1,101 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDump(t *testing.T) {
+	sync := &SyncDelayReq{
+		Header: Header{
+			SdoIDAndMsgType:    NewSdoIDAndMsgType(MessageSync, 1),
+			Version:            Version,
+			MessageLength:      44,
+			MinorSdoID:         2,
+			FlagField:          FlagUnicast | FlagTwoStep,
+			SourcePortIdentity: PortIdentity{PortNumber: 1, ClockIdentity: 0x4857ddfffe0e91da},
+			SequenceID:         116,
+			LogMessageInterval: -7,
+		},
+		SyncDelayReqBody: SyncDelayReqBody{
+			OriginTimestamp: NewTimestamp(time.Unix(1700000000, 5)),
+		},
+	}
+	want := `SYNC, 44 bytes
+0000  10                         SdoIDAndMsgType: SYNC, majorSdoId 1
+0001  12                         Version: 18
+0002  00 2c                      MessageLength: 44
+0004  00                         DomainNumber: 0
+0005  02                         MinorSdoID: 2
+0006  06 00                      FlagField: 1536
+0008  00 00 00 00 00 00 00 00    CorrectionField: Correction(0.000ns)
+0010  00 00 00 00                MessageTypeSpecific: 0
+0014  48 57 dd ff fe 0e 91 da .. SourcePortIdentity: 4857dd.fffe.0e91da-1
+001e  00 74                      SequenceID: 116
+0020  00                         ControlField: 0
+0021  f9                         LogMessageInterval: -7
+0022  00 00 65 53 f1 00 00 00 .. OriginTimestamp: Timestamp(2023-11-14 22:13:20.000000005 +0000 UTC)
+`
+	got, err := DumpPacket(sync)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	b, err := Bytes(sync)
+	require.NoError(t, err)
+	got, err = Dump(b)
+	require.NoError(t, err)
+	want = strings.Replace(want, "44 bytes", "46 bytes", 1)
+	require.Equal(t, want+"002c  00 00\n      ^ trailing bytes\n", got)
+
+	_, err = Dump(b[:10])
+	require.Error(t, err)
+}
+
+func TestDumpTLVs(t *testing.T) {
+	got, err := Dump(announcePathTraceRaw)
+	require.NoError(t, err)
+	require.Contains(t, got, "0040  00 08                      TLVs[0].TLVType: PATH_TRACE\n")
+	require.Contains(t, got, "0042  00 18                      TLVs[0].LengthField: 24\n")
+	require.Contains(t, got, "0044  08 c0 eb ff fe 63 7a 4e .. TLVs[0].PathSequence: [08c0eb.fffe.637a4e 01b6af.c4e5.461229 04c087.32f0.61eece]\n")
+}
+
+func TestDumpVariableFields(t *testing.T) {
+	m, err := NewManagement(RESPONSE, IDUserDescription, &UserDescriptionTLV{UserDescription: "rack1;gm"})
+	require.NoError(t, err)
+	got, err := DumpPacket(m)
+	require.NoError(t, err)
+	// PTPText size is not known from its type, so it's followed by raw bytes of the rest of the TLV
+	require.True(t, strings.HasSuffix(got, `0030  00 01                      TLV.TLVType: MANAGEMENT
+0032  00 0c                      TLV.LengthField: 12
+0034  00 02                      TLV.ManagementID: 2
+                                 TLV.UserDescription: rack1;gm
+0036  08 72 61 63 6b 31 3b 67 6d 00
+`), got)
+}
+
+func TestDumpV1(t *testing.T) {
+	got, err := Dump(v1SyncRaw())
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(got, "SYNC, 124 bytes\n"), got)
+	require.Contains(t, got, "SequenceID: ")
+	require.Contains(t, got, "\n0000  ")
+}
@@ -0,0 +1,159 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// Annex E Transport of PTP over IEEE 802.3 /Ethernet
+
+// EtherTypePTP is the EtherType of PTP messages sent directly over Ethernet
+const EtherTypePTP uint16 = 0x88F7
+
+// etherTypeVLAN is the EtherType of IEEE 802.1Q VLAN tag
+const etherTypeVLAN uint16 = 0x8100
+
+// EthernetHeaderSize is the size of untagged Ethernet header
+const EthernetHeaderSize = 14
+
+// vlanTagSize is the size of IEEE 802.1Q VLAN tag
+const vlanTagSize = 4
+
+var (
+	// MACPrimaryMulticast is the destination MAC address of all PTP messages except peer delay mechanism ones
+	MACPrimaryMulticast = net.HardwareAddr{0x01, 0x1b, 0x19, 0x00, 0x00, 0x00}
+	// MACPeerDelayMulticast is the destination MAC address of peer delay mechanism messages
+	MACPeerDelayMulticast = net.HardwareAddr{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e}
+)
+
+// EthernetHeader is an IEEE 802.3 header of a PTP frame
+type EthernetHeader struct {
+	Destination net.HardwareAddr
+	Source      net.HardwareAddr
+	// VLANTag is an optional IEEE 802.1Q tag control information, used only if HasVLAN is set
+	VLANTag uint16
+	HasVLAN bool
+}
+
+// Size returns size of the header on the wire
+func (h *EthernetHeader) Size() int {
+	if h.HasVLAN {
+		return EthernetHeaderSize + vlanTagSize
+	}
+	return EthernetHeaderSize
+}
+
+// MarshalBinaryTo marshals EthernetHeader into b
+func (h *EthernetHeader) MarshalBinaryTo(b []byte) (int, error) {
+	if len(h.Destination) != 6 || len(h.Source) != 6 {
+		return 0, fmt.Errorf("invalid MAC address length")
+	}
+	if len(b) < h.Size() {
+		return 0, fmt.Errorf("not enough buffer to write EthernetHeader")
+	}
+	copy(b, h.Destination)
+	copy(b[6:], h.Source)
+	pos := 12
+	if h.HasVLAN {
+		binary.BigEndian.PutUint16(b[pos:], etherTypeVLAN)
+		binary.BigEndian.PutUint16(b[pos+2:], h.VLANTag)
+		pos += vlanTagSize
+	}
+	binary.BigEndian.PutUint16(b[pos:], EtherTypePTP)
+	return pos + 2, nil
+}
+
+// UnmarshalBinary parses EthernetHeader from b, making sure frame carries PTP message
+func (h *EthernetHeader) UnmarshalBinary(b []byte) error {
+	if len(b) < EthernetHeaderSize {
+		return fmt.Errorf("not enough data to decode EthernetHeader")
+	}
+	h.Destination = net.HardwareAddr(bytes.Clone(b[:6]))
+	h.Source = net.HardwareAddr(bytes.Clone(b[6:12]))
+	h.VLANTag = 0
+	h.HasVLAN = false
+	etherType := binary.BigEndian.Uint16(b[12:])
+	if etherType == etherTypeVLAN {
+		if len(b) < EthernetHeaderSize+vlanTagSize {
+			return fmt.Errorf("not enough data to decode EthernetHeader")
+		}
+		h.HasVLAN = true
+		h.VLANTag = binary.BigEndian.Uint16(b[14:])
+		etherType = binary.BigEndian.Uint16(b[16:])
+	}
+	if etherType != EtherTypePTP {
+		return fmt.Errorf("unexpected EtherType %#04x", etherType)
+	}
+	return nil
+}
+
+// EthernetDestination returns the destination MAC address for the message type
+func EthernetDestination(msgType MessageType) net.HardwareAddr {
+	switch msgType {
+	case MessagePDelayReq, MessagePDelayResp, MessagePDelayRespFollowUp:
+		return MACPeerDelayMulticast
+	default:
+		return MACPrimaryMulticast
+	}
+}
+
+// EthernetBytes converts packet to Ethernet frame.
+// Unlike Bytes it doesn't add trailing bytes which are only needed for UDPv6.
+func EthernetBytes(h *EthernetHeader, p Packet) ([]byte, error) {
+	b := make([]byte, h.Size())
+	if _, err := h.MarshalBinaryTo(b); err != nil {
+		return nil, err
+	}
+	// interface smuggling
+	if pp, ok := p.(encoding.BinaryMarshaler); ok {
+		pb, err := pp.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return append(b, pb...), nil
+	}
+	buf := bytes.NewBuffer(b)
+	if err := binary.Write(buf, binary.BigEndian, p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeEthernetPacket decodes Ethernet frame carrying PTP message.
+// Frame padding after the PTP message is ignored.
+func DecodeEthernetPacket(b []byte) (*EthernetHeader, Packet, error) {
+	h := &EthernetHeader{}
+	if err := h.UnmarshalBinary(b); err != nil {
+		return nil, nil, err
+	}
+	payload := b[h.Size():]
+	if len(payload) >= headerSize {
+		if l := int(binary.BigEndian.Uint16(payload[2:])); l >= headerSize && l <= len(payload) {
+			payload = payload[:l]
+		}
+	}
+	p, err := DecodePacket(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	return h, p, nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEthernetHeader(t *testing.T) {
+	src := net.HardwareAddr{0x08, 0xc0, 0xeb, 0x63, 0x7a, 0x4e}
+	h := &EthernetHeader{Destination: MACPrimaryMulticast, Source: src}
+	b := make([]byte, 18)
+	n, err := h.MarshalBinaryTo(b)
+	require.NoError(t, err)
+	require.Equal(t, EthernetHeaderSize, n)
+	require.Equal(t, []byte("\x01\x1b\x19\x00\x00\x00\x08\xc0\xeb\x63\x7a\x4e\x88\xf7"), b[:n])
+
+	parsed := &EthernetHeader{}
+	require.NoError(t, parsed.UnmarshalBinary(b[:n]))
+	require.Equal(t, h, parsed)
+
+	h.HasVLAN = true
+	h.VLANTag = 0xa00c
+	n, err = h.MarshalBinaryTo(b)
+	require.NoError(t, err)
+	require.Equal(t, 18, n)
+	require.Equal(t, []byte("\x01\x1b\x19\x00\x00\x00\x08\xc0\xeb\x63\x7a\x4e\x81\x00\xa0\x0c\x88\xf7"), b[:n])
+	require.NoError(t, parsed.UnmarshalBinary(b[:n]))
+	require.Equal(t, h, parsed)
+
+	_, err = h.MarshalBinaryTo(b[:17])
+	require.Error(t, err)
+	_, err = (&EthernetHeader{Destination: MACPrimaryMulticast}).MarshalBinaryTo(b)
+	require.Error(t, err)
+
+	require.Error(t, parsed.UnmarshalBinary(b[:16]))
+	ipv4 := []byte("\x01\x1b\x19\x00\x00\x00\x08\xc0\xeb\x63\x7a\x4e\x08\x00")
+	require.ErrorContains(t, parsed.UnmarshalBinary(ipv4), "unexpected EtherType 0x0800")
+}
+
+func TestEthernetDestination(t *testing.T) {
+	require.Equal(t, MACPrimaryMulticast, EthernetDestination(MessageSync))
+	require.Equal(t, MACPrimaryMulticast, EthernetDestination(MessageAnnounce))
+	require.Equal(t, MACPeerDelayMulticast, EthernetDestination(MessagePDelayReq))
+	require.Equal(t, MACPeerDelayMulticast, EthernetDestination(MessagePDelayRespFollowUp))
+}
+
+func TestEthernetPacket(t *testing.T) {
+	src := net.HardwareAddr{0x08, 0xc0, 0xeb, 0x63, 0x7a, 0x4e}
+	sync := &SyncDelayReq{
+		Header: Header{
+			SdoIDAndMsgType: NewSdoIDAndMsgType(MessageSync, 0),
+			Version:         Version,
+			MessageLength:   44,
+			SequenceID:      42,
+			SourcePortIdentity: PortIdentity{
+				PortNumber:    1,
+				ClockIdentity: 630763432548989518,
+			},
+		},
+		SyncDelayReqBody: SyncDelayReqBody{
+			OriginTimestamp: Timestamp{Nanoseconds: 123},
+		},
+	}
+	announce := &Announce{
+		Header: Header{
+			SdoIDAndMsgType: NewSdoIDAndMsgType(MessageAnnounce, 0),
+			Version:         Version,
+			MessageLength:   headerSize + announceBodySize,
+		},
+		AnnounceBody: AnnounceBody{
+			GrandmasterIdentity: 630763432548989518,
+		},
+	}
+	announce.SetPathTrace([]ClockIdentity{630763432548989518})
+	pdelay := &PDelayReq{
+		Header: Header{
+			SdoIDAndMsgType: NewSdoIDAndMsgType(MessagePDelayReq, 0),
+			Version:         Version,
+			MessageLength:   54,
+		},
+	}
+
+	for _, p := range []Packet{sync, announce, pdelay} {
+		t.Run(p.MessageType().String(), func(t *testing.T) {
+			h := &EthernetHeader{Destination: EthernetDestination(p.MessageType()), Source: src}
+			b, err := EthernetBytes(h, p)
+			require.NoError(t, err)
+			plain, err := Bytes(p)
+			require.NoError(t, err)
+			require.Equal(t, plain[:len(plain)-TrailingBytes], b[EthernetHeaderSize:])
+
+			// frames shorter than 60 bytes are padded
+			for len(b) < 60 {
+				b = append(b, 0)
+			}
+			gotH, gotP, err := DecodeEthernetPacket(b)
+			require.NoError(t, err)
+			require.Equal(t, h, gotH)
+			require.Equal(t, p, gotP)
+		})
+	}
+
+	_, _, err := DecodeEthernetPacket([]byte("\x01\x1b\x19\x00\x00\x00\x08\xc0\xeb\x63\x7a\x4e\x88\xf7\x00"))
+	require.Error(t, err)
+}
@@ -0,0 +1,218 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// IEEE 802.1AS generalized PTP (gPTP) specifics.
+// All gPTP messages are sent over Ethernet to MACPeerDelayMulticast.
+
+// MajorSdoIDGPTP is the majorSdoId (transportSpecific) of all gPTP messages
+const MajorSdoIDGPTP uint8 = 1
+
+// OrgIDIEEE8021 is the IEEE 802.1 organizationId used by gPTP ORGANIZATION_EXTENSION TLVs
+var OrgIDIEEE8021 = [3]uint8{0x00, 0x80, 0xc2}
+
+// OrgSubTypeFollowUpInformation is the organizationSubType of Follow_Up information TLV
+var OrgSubTypeFollowUpInformation = [3]uint8{0x00, 0x00, 0x01}
+
+// followUpInformationLength is the lengthField of Follow_Up information TLV
+const followUpInformationLength = 28
+
+// gPTP messages of fixed length, Announce and Signaling carry TLVs and are not listed
+var gptpMessageLength = map[MessageType]uint16{
+	MessageSync:               headerSize + 10,
+	MessageFollowUp:           headerSize + 10 + tlvHeadSize + followUpInformationLength,
+	MessagePDelayReq:          headerSize + 20,
+	MessagePDelayResp:         headerSize + 20,
+	MessagePDelayRespFollowUp: headerSize + 20,
+}
+
+// ScaledNs is a signed 96-bit time interval in units of 2^-16 ns as per 802.1AS 6.4.3.3
+type ScaledNs struct {
+	NanosecondsMSB        int16
+	NanosecondsLSB        uint64
+	FractionalNanoseconds uint16
+}
+
+// FollowUpInformationTLV is an 802.1AS 11.4.4.3 Follow_Up information TLV
+type FollowUpInformationTLV struct {
+	TLVHead
+	OrganizationID             [3]uint8
+	OrganizationSubType        [3]uint8
+	CumulativeScaledRateOffset int32
+	GMTimeBaseIndicator        uint16
+	LastGMPhaseChange          ScaledNs
+	ScaledLastGMFreqChange     int32
+}
+
+// NewFollowUpInformationTLV returns Follow_Up information TLV with the header and organization fields populated
+func NewFollowUpInformationTLV() FollowUpInformationTLV {
+	return FollowUpInformationTLV{
+		TLVHead: TLVHead{
+			TLVType:     TLVOrganizationExtension,
+			LengthField: followUpInformationLength,
+		},
+		OrganizationID:      OrgIDIEEE8021,
+		OrganizationSubType: OrgSubTypeFollowUpInformation,
+	}
+}
+
+// RateRatio returns the ratio of the grandmaster frequency to the local clock frequency
+func (t *FollowUpInformationTLV) RateRatio() float64 {
+	return 1 + float64(t.CumulativeScaledRateOffset)/(1<<41)
+}
+
+// SetRateRatio sets cumulativeScaledRateOffset from the ratio of the grandmaster frequency to the local clock frequency
+func (t *FollowUpInformationTLV) SetRateRatio(r float64) {
+	t.CumulativeScaledRateOffset = int32((r - 1) * (1 << 41))
+}
+
+// MarshalBinaryTo marshals bytes to FollowUpInformationTLV
+func (t *FollowUpInformationTLV) MarshalBinaryTo(b []byte) (int, error) {
+	if len(b) < tlvHeadSize+followUpInformationLength {
+		return 0, fmt.Errorf("not enough buffer to write FollowUpInformationTLV")
+	}
+	tlvHeadMarshalBinaryTo(&t.TLVHead, b)
+	copy(b[4:], t.OrganizationID[:])
+	copy(b[7:], t.OrganizationSubType[:])
+	binary.BigEndian.PutUint32(b[10:], uint32(t.CumulativeScaledRateOffset))
+	binary.BigEndian.PutUint16(b[14:], t.GMTimeBaseIndicator)
+	binary.BigEndian.PutUint16(b[16:], uint16(t.LastGMPhaseChange.NanosecondsMSB))
+	binary.BigEndian.PutUint64(b[18:], t.LastGMPhaseChange.NanosecondsLSB)
+	binary.BigEndian.PutUint16(b[26:], t.LastGMPhaseChange.FractionalNanoseconds)
+	binary.BigEndian.PutUint32(b[28:], uint32(t.ScaledLastGMFreqChange))
+	return tlvHeadSize + followUpInformationLength, nil
+}
+
+// UnmarshalBinary parses []byte and populates struct fields
+func (t *FollowUpInformationTLV) UnmarshalBinary(b []byte) error {
+	if err := unmarshalTLVHeader(&t.TLVHead, b); err != nil {
+		return err
+	}
+	if t.TLVType != TLVOrganizationExtension {
+		return fmt.Errorf("expected TLV of type %s, got %s", TLVOrganizationExtension, t.TLVType)
+	}
+	if err := checkTLVLength(&t.TLVHead, len(b), followUpInformationLength, true); err != nil {
+		return err
+	}
+	copy(t.OrganizationID[:], b[4:])
+	copy(t.OrganizationSubType[:], b[7:])
+	if t.OrganizationID != OrgIDIEEE8021 || t.OrganizationSubType != OrgSubTypeFollowUpInformation {
+		return fmt.Errorf("unexpected organization %x subtype %x in Follow_Up information TLV", t.OrganizationID, t.OrganizationSubType)
+	}
+	t.CumulativeScaledRateOffset = int32(binary.BigEndian.Uint32(b[10:]))
+	t.GMTimeBaseIndicator = binary.BigEndian.Uint16(b[14:])
+	t.LastGMPhaseChange.NanosecondsMSB = int16(binary.BigEndian.Uint16(b[16:]))
+	t.LastGMPhaseChange.NanosecondsLSB = binary.BigEndian.Uint64(b[18:])
+	t.LastGMPhaseChange.FractionalNanoseconds = binary.BigEndian.Uint16(b[26:])
+	t.ScaledLastGMFreqChange = int32(binary.BigEndian.Uint32(b[28:]))
+	return nil
+}
+
+// GPTPFollowUp is a full 802.1AS Follow_Up packet, which always carries Follow_Up information TLV
+type GPTPFollowUp struct {
+	Header
+	FollowUpBody
+	FollowUpInformation FollowUpInformationTLV
+}
+
+// MarshalBinaryTo marshals bytes to GPTPFollowUp
+func (p *GPTPFollowUp) MarshalBinaryTo(b []byte) (int, error) {
+	if len(b) < headerSize+10 {
+		return 0, fmt.Errorf("not enough buffer to write GPTPFollowUp")
+	}
+	n := headerMarshalBinaryTo(&p.Header, b)
+	copy(b[n:], p.PreciseOriginTimestamp.Seconds[:]) //uint48
+	binary.BigEndian.PutUint32(b[n+6:], p.PreciseOriginTimestamp.Nanoseconds)
+	tlvLen, err := p.FollowUpInformation.MarshalBinaryTo(b[n+10:])
+	return n + 10 + tlvLen, err
+}
+
+// MarshalBinary converts packet to []bytes
+func (p *GPTPFollowUp) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, gptpMessageLength[MessageFollowUp])
+	n, err := p.MarshalBinaryTo(buf)
+	return buf[:n], err
+}
+
+// UnmarshalBinary unmarshals bytes to GPTPFollowUp
+func (p *GPTPFollowUp) UnmarshalBinary(b []byte) error {
+	if len(b) < headerSize+10 {
+		return fmt.Errorf("not enough data to decode GPTPFollowUp")
+	}
+	unmarshalHeader(&p.Header, b)
+	if err := checkPacketLength(&p.Header, len(b)); err != nil {
+		return err
+	}
+	copy(p.PreciseOriginTimestamp.Seconds[:], b[headerSize:]) //uint48
+	p.PreciseOriginTimestamp.Nanoseconds = binary.BigEndian.Uint32(b[headerSize+6:])
+	return p.FollowUpInformation.UnmarshalBinary(b[headerSize+10:])
+}
+
+// NewGPTPHeader returns a Header following 802.1AS 10.6.2 and 11.4.2 restrictions.
+// MessageLength is populated for messages of fixed length,
+// logMessageInterval is ignored for messages where it must be 0x7F.
+func NewGPTPHeader(msgType MessageType, source PortIdentity, sequence uint16, logInterval LogInterval) Header {
+	h := Header{
+		SdoIDAndMsgType:    NewSdoIDAndMsgType(msgType, MajorSdoIDGPTP),
+		Version:            Version,
+		MessageLength:      gptpMessageLength[msgType],
+		SourcePortIdentity: source,
+		SequenceID:         sequence,
+		LogMessageInterval: logInterval,
+	}
+	switch msgType {
+	case MessageSync, MessagePDelayResp:
+		h.FlagField = FlagTwoStep
+	}
+	switch msgType {
+	case MessagePDelayResp, MessagePDelayRespFollowUp, MessageSignaling:
+		h.LogMessageInterval = LogIntervalUnspecified
+	}
+	return h
+}
+
+// CheckGPTPHeader verifies Header follows 802.1AS restrictions
+func CheckGPTPHeader(h *Header) error {
+	if sdoID := h.SdoIDAndMsgType.MajorSdoID(); sdoID != MajorSdoIDGPTP {
+		return fmt.Errorf("unexpected majorSdoId %d for gPTP message", sdoID)
+	}
+	if h.Version&MajorVersionMask != MajorVersion {
+		return fmt.Errorf("unsupported PTP version %d", h.Version&MajorVersionMask)
+	}
+	if h.MinorSdoID != 0 {
+		return fmt.Errorf("unexpected minorSdoId %d for gPTP message", h.MinorSdoID)
+	}
+	if h.FlagField&FlagUnicast != 0 {
+		return fmt.Errorf("gPTP messages must not be unicast")
+	}
+	switch h.MessageType() {
+	case MessageSync, MessageFollowUp, MessageAnnounce, MessageSignaling:
+	case MessagePDelayReq, MessagePDelayResp, MessagePDelayRespFollowUp:
+		// peer delay mechanism is domain independent and always runs in domain 0
+		if h.DomainNumber != 0 {
+			return fmt.Errorf("unexpected domain %d for gPTP %s message", h.DomainNumber, h.MessageType())
+		}
+	default:
+		return fmt.Errorf("message type %s is not used by gPTP", h.MessageType())
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGPTPFollowUp(t *testing.T) {
+	raw := []byte("\x18\x12\x00\x4c\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x1b\x21\xff\xfe\xaa\xbb\xcc\x00\x01\x01\x02\x02\xfd" +
+		"\x00\x00\x00\x00\x00\x64\x00\x00\x02\x00" +
+		"\x00\x03\x00\x1c\x00\x80\xc2\x00\x00\x01\x00\x00\x04\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\xff\xff\xff\xff")
+	want := &GPTPFollowUp{
+		Header: Header{
+			SdoIDAndMsgType: NewSdoIDAndMsgType(MessageFollowUp, MajorSdoIDGPTP),
+			Version:         Version,
+			MessageLength:   76,
+			SourcePortIdentity: PortIdentity{
+				ClockIdentity: 0x001b21fffeaabbcc,
+				PortNumber:    1,
+			},
+			SequenceID:         258,
+			ControlField:       2,
+			LogMessageInterval: -3,
+		},
+		FollowUpBody: FollowUpBody{
+			PreciseOriginTimestamp: Timestamp{
+				Seconds:     [6]uint8{0, 0, 0, 0, 0, 100},
+				Nanoseconds: 512,
+			},
+		},
+		FollowUpInformation: FollowUpInformationTLV{
+			TLVHead: TLVHead{
+				TLVType:     TLVOrganizationExtension,
+				LengthField: 28,
+			},
+			OrganizationID:             OrgIDIEEE8021,
+			OrganizationSubType:        OrgSubTypeFollowUpInformation,
+			CumulativeScaledRateOffset: 1024,
+			GMTimeBaseIndicator:        1,
+			LastGMPhaseChange:          ScaledNs{FractionalNanoseconds: 1},
+			ScaledLastGMFreqChange:     -1,
+		},
+	}
+	packet := &GPTPFollowUp{}
+	require.NoError(t, FromBytes(raw, packet))
+	require.Equal(t, want, packet)
+	require.NoError(t, CheckGPTPHeader(&packet.Header))
+
+	b, err := Bytes(packet)
+	require.NoError(t, err)
+	require.Equal(t, raw, b[:len(b)-TrailingBytes])
+
+	pp, err := DecodePacket(b)
+	require.NoError(t, err)
+	require.Equal(t, want, pp)
+
+	// non-gPTP Follow_Up is not affected
+	raw[0] = byte(MessageFollowUp)
+	pp, err = DecodePacket(raw)
+	require.NoError(t, err)
+	require.IsType(t, &FollowUp{}, pp)
+
+	// wrong organization
+	raw[0] = 0x18
+	raw[49] = 0x00
+	require.Error(t, FromBytes(raw, packet))
+	require.Error(t, FromBytes(raw[:60], packet))
+}
+
+func TestFollowUpInformationRateRatio(t *testing.T) {
+	tlv := NewFollowUpInformationTLV()
+	require.Equal(t, 1.0, tlv.RateRatio())
+	tlv.SetRateRatio(1 + 1.0/(1<<20))
+	require.Equal(t, int32(1<<21), tlv.CumulativeScaledRateOffset)
+	require.Equal(t, 1+1.0/(1<<20), tlv.RateRatio())
+	tlv.SetRateRatio(1 - 1.0/(1<<30))
+	require.Equal(t, int32(-(1 << 11)), tlv.CumulativeScaledRateOffset)
+}
+
+func TestNewGPTPHeader(t *testing.T) {
+	source := PortIdentity{ClockIdentity: 0x001b21fffeaabbcc, PortNumber: 1}
+	h := NewGPTPHeader(MessageSync, source, 10, -3)
+	require.Equal(t, Header{
+		SdoIDAndMsgType:    NewSdoIDAndMsgType(MessageSync, MajorSdoIDGPTP),
+		Version:            Version,
+		MessageLength:      44,
+		FlagField:          FlagTwoStep,
+		SourcePortIdentity: source,
+		SequenceID:         10,
+		LogMessageInterval: -3,
+	}, h)
+	require.NoError(t, CheckGPTPHeader(&h))
+
+	h = NewGPTPHeader(MessagePDelayResp, source, 11, 0)
+	require.Equal(t, uint16(54), h.MessageLength)
+	require.Equal(t, FlagTwoStep, h.FlagField)
+	require.Equal(t, MgmtLogMessageInterval, h.LogMessageInterval)
+	require.NoError(t, CheckGPTPHeader(&h))
+
+	h = NewGPTPHeader(MessageFollowUp, source, 10, -3)
+	require.Equal(t, uint16(76), h.MessageLength)
+	require.Equal(t, uint16(0), h.FlagField)
+
+	h = NewGPTPHeader(MessagePDelayReq, source, 12, 0)
+	h.DomainNumber = 1
+	require.ErrorContains(t, CheckGPTPHeader(&h), "unexpected domain 1")
+
+	h = NewGPTPHeader(MessageDelayReq, source, 12, 0)
+	require.ErrorContains(t, CheckGPTPHeader(&h), "not used by gPTP")
+
+	h = NewGPTPHeader(MessageSync, source, 10, -3)
+	h.FlagField |= FlagUnicast
+	require.Error(t, CheckGPTPHeader(&h))
+	h.FlagField = 0
+	h.MinorSdoID = 1
+	require.Error(t, CheckGPTPHeader(&h))
+	h.MinorSdoID = 0
+	h.SdoIDAndMsgType = NewSdoIDAndMsgType(MessageSync, 0)
+	require.ErrorContains(t, CheckGPTPHeader(&h), "unexpected majorSdoId 0")
+}
@@ -0,0 +1,257 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSON representation of PTP messages follows the Go structs,
+// with clock identities rendered as hex strings, timestamps as RFC3339 and TLV types as their names.
+
+// MarshalText formats ClockIdentity same way String does
+func (c ClockIdentity) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText parses ClockIdentity from hex string, with or without the dots
+func (c *ClockIdentity) UnmarshalText(text []byte) error {
+	s := strings.ReplaceAll(string(text), ".", "")
+	if len(s) != 16 {
+		return fmt.Errorf("invalid clock identity %q", text)
+	}
+	v, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return fmt.Errorf("invalid clock identity %q: %w", text, err)
+	}
+	*c = ClockIdentity(v)
+	return nil
+}
+
+// MarshalText formats Timestamp as RFC3339 with nanoseconds in UTC
+func (t Timestamp) MarshalText() ([]byte, error) {
+	return []byte(time.Unix(int64(t.Seconds.Seconds()), int64(t.Nanoseconds)).UTC().Format(time.RFC3339Nano)), nil
+}
+
+// UnmarshalText parses Timestamp from RFC3339 with nanoseconds
+func (t *Timestamp) UnmarshalText(text []byte) error {
+	parsed, err := time.Parse(time.RFC3339Nano, string(text))
+	if err != nil {
+		return err
+	}
+	if parsed.Unix() < 0 {
+		return fmt.Errorf("timestamp %q is before the epoch", text)
+	}
+	*t = Timestamp{Seconds: NewPTPSeconds(time.Unix(parsed.Unix(), 0)), Nanoseconds: uint32(parsed.Nanosecond())}
+	return nil
+}
+
+// MarshalText formats TLVType as its name, falling back to the number for unknown types
+func (t TLVType) MarshalText() ([]byte, error) {
+	if s, ok := TLVTypeToString[t]; ok {
+		return []byte(s), nil
+	}
+	return []byte(strconv.Itoa(int(t))), nil
+}
+
+// UnmarshalText parses TLVType from its name or number
+func (t *TLVType) UnmarshalText(text []byte) error {
+	for k, v := range TLVTypeToString {
+		if v == string(text) {
+			*t = k
+			return nil
+		}
+	}
+	v, err := strconv.ParseUint(string(text), 0, 16)
+	if err != nil {
+		return fmt.Errorf("unknown TLV type %q", text)
+	}
+	*t = TLVType(v)
+	return nil
+}
+
+// constructors of TLVs which can be attached to messages, same set readTLVs decodes, used to decode them from JSON
+var tlvNew = map[TLVType]func() TLV{
+	TLVAcknowledgeCancelUnicastTransmission: func() TLV { return &AcknowledgeCancelUnicastTransmissionTLV{} },
+	TLVGrantUnicastTransmission:             func() TLV { return &GrantUnicastTransmissionTLV{} },
+	TLVRequestUnicastTransmission:           func() TLV { return &RequestUnicastTransmissionTLV{} },
+	TLVCancelUnicastTransmission:            func() TLV { return &CancelUnicastTransmissionTLV{} },
+	TLVPathTrace:                            func() TLV { return &PathTraceTLV{} },
+	TLVAlternateTimeOffsetIndicator:         func() TLV { return &AlternateTimeOffsetIndicatorTLV{} },
+	TLVAuthentication:                       func() TLV { return &AuthenticationTLV{} },
+	TLVAlternateResponsePort:                func() TLV { return &AlternateResponsePortTLV{} },
+	TLVOrganizationExtension:                func() TLV { return &OrganizationExtensionTLV{} },
+}
+
+// unmarshalTLVsJSON decodes list of TLVs, picking concrete type based on TLVType
+func unmarshalTLVsJSON(raw []json.RawMessage) ([]TLV, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	tlvs := make([]TLV, 0, len(raw))
+	for _, r := range raw {
+		head := struct {
+			TLVHead
+			OrganizationID      [3]uint8
+			OrganizationSubType [3]uint8
+		}{}
+		if err := json.Unmarshal(r, &head); err != nil {
+			return nil, err
+		}
+		newTLV, ok := tlvNew[head.TLVType]
+		if !ok {
+			return nil, fmt.Errorf("decoding TLV %s (%d) from JSON is not supported", head.TLVType, head.TLVType)
+		}
+		var tlv TLV
+		if head.TLVType == TLVOrganizationExtension {
+			tlv = newOrganizationTLV(Organization{ID: head.OrganizationID, SubType: head.OrganizationSubType})
+		} else {
+			tlv = newTLV()
+		}
+		if err := json.Unmarshal(r, tlv); err != nil {
+			return nil, err
+		}
+		tlvs = append(tlvs, tlv)
+	}
+	return tlvs, nil
+}
+
+// UnmarshalJSON decodes Announce from JSON
+func (p *Announce) UnmarshalJSON(b []byte) error {
+	type announce Announce
+	v := struct {
+		*announce
+		TLVs []json.RawMessage
+	}{announce: (*announce)(p)}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	var err error
+	p.TLVs, err = unmarshalTLVsJSON(v.TLVs)
+	return err
+}
+
+// UnmarshalJSON decodes SyncDelayReq from JSON
+func (p *SyncDelayReq) UnmarshalJSON(b []byte) error {
+	type syncDelayReq SyncDelayReq
+	v := struct {
+		*syncDelayReq
+		TLVs []json.RawMessage
+	}{syncDelayReq: (*syncDelayReq)(p)}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	var err error
+	p.TLVs, err = unmarshalTLVsJSON(v.TLVs)
+	return err
+}
+
+// UnmarshalJSON decodes Signaling from JSON
+func (p *Signaling) UnmarshalJSON(b []byte) error {
+	type signaling Signaling
+	v := struct {
+		*signaling
+		TLVs []json.RawMessage
+	}{signaling: (*signaling)(p)}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	var err error
+	p.TLVs, err = unmarshalTLVsJSON(v.TLVs)
+	return err
+}
+
+// UnmarshalJSON decodes Management from JSON, picking concrete TLV type based on ManagementID
+func (p *Management) UnmarshalJSON(b []byte) error {
+	v := struct {
+		ManagementMsgHead
+		TLV json.RawMessage
+	}{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	p.ManagementMsgHead = v.ManagementMsgHead
+	p.TLV = nil
+	if len(v.TLV) == 0 || string(v.TLV) == "null" {
+		return nil
+	}
+	head := ManagementTLVHead{}
+	if err := json.Unmarshal(v.TLV, &head); err != nil {
+		return err
+	}
+	newTLV, ok := mgmtTLVNew[head.ManagementID]
+	if !ok {
+		return fmt.Errorf("decoding management TLV %d from JSON is not supported", head.ManagementID)
+	}
+	tlv := newTLV()
+	if err := json.Unmarshal(v.TLV, tlv); err != nil {
+		return err
+	}
+	p.TLV = tlv
+	return nil
+}
+
+// DecodePacketJSON provides single entry point to decode JSON produced by json.Marshal of any packet
+func DecodePacketJSON(b []byte) (Packet, error) {
+	head := struct{ SdoIDAndMsgType SdoIDAndMsgType }{}
+	if err := json.Unmarshal(b, &head); err != nil {
+		return nil, err
+	}
+	var p Packet
+	switch msgType := head.SdoIDAndMsgType.MsgType(); msgType {
+	case MessageSync, MessageDelayReq:
+		p = &SyncDelayReq{}
+	case MessagePDelayReq:
+		p = &PDelayReq{}
+	case MessagePDelayResp:
+		p = &PDelayResp{}
+	case MessageFollowUp:
+		if head.SdoIDAndMsgType.MajorSdoID() == MajorSdoIDGPTP {
+			p = &GPTPFollowUp{}
+		} else {
+			p = &FollowUp{}
+		}
+	case MessageDelayResp:
+		p = &DelayResp{}
+	case MessagePDelayRespFollowUp:
+		p = &PDelayRespFollowUp{}
+	case MessageAnnounce:
+		p = &Announce{}
+	case MessageSignaling:
+		p = &Signaling{}
+	case MessageManagement:
+		tlv := struct{ TLVType TLVType }{}
+		if err := json.Unmarshal(b, &tlv); err != nil {
+			return nil, err
+		}
+		if tlv.TLVType == TLVManagementErrorStatus {
+			p = &ManagementMsgErrorStatus{}
+		} else {
+			p = &Management{}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %s", msgType)
+	}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
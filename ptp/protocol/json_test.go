@@ -0,0 +1,205 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClockIdentityText(t *testing.T) {
+	c := ClockIdentity(0x4857ddfffe0e91da)
+	b, err := c.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, "4857dd.fffe.0e91da", string(b))
+
+	var parsed ClockIdentity
+	require.NoError(t, parsed.UnmarshalText(b))
+	require.Equal(t, c, parsed)
+	require.NoError(t, parsed.UnmarshalText([]byte("4857ddfffe0e91da")))
+	require.Equal(t, c, parsed)
+
+	require.Error(t, parsed.UnmarshalText([]byte("4857dd.fffe.0e91")))
+	require.Error(t, parsed.UnmarshalText([]byte("4857dd.fffe.0e91zz")))
+}
+
+func TestTimestampText(t *testing.T) {
+	ts := NewTimestamp(time.Unix(1700000000, 123456789))
+	b, err := ts.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, "2023-11-14T22:13:20.123456789Z", string(b))
+
+	var parsed Timestamp
+	require.NoError(t, parsed.UnmarshalText(b))
+	require.Equal(t, ts, parsed)
+
+	b, err = Timestamp{}.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, "1970-01-01T00:00:00Z", string(b))
+	require.NoError(t, parsed.UnmarshalText(b))
+	require.True(t, parsed.Empty())
+
+	require.Error(t, parsed.UnmarshalText([]byte("yesterday")))
+	require.Error(t, parsed.UnmarshalText([]byte("1969-12-31T23:59:59Z")))
+}
+
+func TestTLVTypeText(t *testing.T) {
+	b, err := TLVPathTrace.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, "PATH_TRACE", string(b))
+	b, err = TLVType(0x7fff).MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, "32767", string(b))
+
+	var parsed TLVType
+	require.NoError(t, parsed.UnmarshalText([]byte("AUTHENTICATION")))
+	require.Equal(t, TLVAuthentication, parsed)
+	require.NoError(t, parsed.UnmarshalText([]byte("0x7fff")))
+	require.Equal(t, TLVType(0x7fff), parsed)
+	require.Error(t, parsed.UnmarshalText([]byte("NOPE")))
+}
+
+func TestAnnounceJSON(t *testing.T) {
+	announce := &Announce{
+		Header: Header{
+			SdoIDAndMsgType: NewSdoIDAndMsgType(MessageAnnounce, 0),
+			Version:         Version,
+			MessageLength:   headerSize + announceBodySize,
+			SourcePortIdentity: PortIdentity{
+				PortNumber:    1,
+				ClockIdentity: 0x4857ddfffe0e91da,
+			},
+		},
+		AnnounceBody: AnnounceBody{
+			OriginTimestamp:     NewTimestamp(time.Unix(1700000000, 5)),
+			GrandmasterIdentity: 0x4857ddfffe0e91da,
+		},
+	}
+	announce.SetPathTrace([]ClockIdentity{0x4857ddfffe0e91da})
+	b, err := json.Marshal(announce)
+	require.NoError(t, err)
+	s := string(b)
+	require.Contains(t, s, `"SourcePortIdentity":{"ClockIdentity":"4857dd.fffe.0e91da","PortNumber":1}`)
+	require.Contains(t, s, `"OriginTimestamp":"2023-11-14T22:13:20.000000005Z"`)
+	require.Contains(t, s, `"TLVs":[{"TLVType":"PATH_TRACE","LengthField":8,"PathSequence":["4857dd.fffe.0e91da"]}]`)
+
+	parsed := &Announce{}
+	require.NoError(t, json.Unmarshal(b, parsed))
+	require.Equal(t, announce, parsed)
+}
+
+func TestDecodePacketJSON(t *testing.T) {
+	source := PortIdentity{PortNumber: 1, ClockIdentity: 0x4857ddfffe0e91da}
+	ts := NewTimestamp(time.Unix(1700000000, 123456789))
+	defaultDS, err := NewManagement(RESPONSE, IDDefaultDataSet, &DefaultDataSetTLV{
+		NumberPorts:   1,
+		Priority1:     128,
+		ClockQuality:  ClockQuality{ClockClass: ClockClass6, ClockAccuracy: ClockAccuracyNanosecond100, OffsetScaledLogVariance: 0xffff},
+		Priority2:     128,
+		ClockIdentity: 0x4857ddfffe0e91da,
+	})
+	require.NoError(t, err)
+	userDescription, err := NewManagement(RESPONSE, IDUserDescription, &UserDescriptionTLV{UserDescription: "rack1;gm"})
+	require.NoError(t, err)
+	enablePort, err := NewManagement(COMMAND, IDEnablePort, &ManagementTLVHead{})
+	require.NoError(t, err)
+	gptpFollowUp := &GPTPFollowUp{
+		Header:              NewGPTPHeader(MessageFollowUp, source, 1, -3),
+		FollowUpBody:        FollowUpBody{PreciseOriginTimestamp: ts},
+		FollowUpInformation: NewFollowUpInformationTLV(),
+	}
+	gptpFollowUp.FollowUpInformation.SetRateRatio(1.000001)
+
+	packets := []Packet{
+		&SyncDelayReq{
+			Header:           Header{SdoIDAndMsgType: NewSdoIDAndMsgType(MessageSync, 0), Version: Version, MessageLength: 44, SourcePortIdentity: source, CorrectionField: NewCorrection(2.5)},
+			SyncDelayReqBody: SyncDelayReqBody{OriginTimestamp: ts},
+		},
+		&SyncDelayReq{
+			Header: Header{SdoIDAndMsgType: NewSdoIDAndMsgType(MessageDelayReq, 0), Version: Version, MessageLength: 50, SourcePortIdentity: source},
+			TLVs:   []TLV{&AlternateResponsePortTLV{TLVHead: TLVHead{TLVType: TLVAlternateResponsePort, LengthField: 2}, Offset: 1}},
+		},
+		&FollowUp{
+			Header:       Header{SdoIDAndMsgType: NewSdoIDAndMsgType(MessageFollowUp, 0), Version: Version, MessageLength: 44, SourcePortIdentity: source},
+			FollowUpBody: FollowUpBody{PreciseOriginTimestamp: ts},
+		},
+		&DelayResp{
+			Header:        Header{SdoIDAndMsgType: NewSdoIDAndMsgType(MessageDelayResp, 0), Version: Version, MessageLength: 54, SourcePortIdentity: source},
+			DelayRespBody: DelayRespBody{ReceiveTimestamp: ts, RequestingPortIdentity: source},
+		},
+		&PDelayReq{
+			Header: Header{SdoIDAndMsgType: NewSdoIDAndMsgType(MessagePDelayReq, 0), Version: Version, MessageLength: 54, SourcePortIdentity: source},
+		},
+		&PDelayResp{
+			Header:         Header{SdoIDAndMsgType: NewSdoIDAndMsgType(MessagePDelayResp, 0), Version: Version, MessageLength: 54, SourcePortIdentity: source},
+			PDelayRespBody: PDelayRespBody{RequestReceiptTimestamp: ts, RequestingPortIdentity: source},
+		},
+		&PDelayRespFollowUp{
+			Header:                 Header{SdoIDAndMsgType: NewSdoIDAndMsgType(MessagePDelayRespFollowUp, 0), Version: Version, MessageLength: 54, SourcePortIdentity: source},
+			PDelayRespFollowUpBody: PDelayRespFollowUpBody{ResponseOriginTimestamp: ts, RequestingPortIdentity: source},
+		},
+		gptpFollowUp,
+		&Signaling{
+			Header:             Header{SdoIDAndMsgType: NewSdoIDAndMsgType(MessageSignaling, 0), Version: Version, MessageLength: 56, SourcePortIdentity: source},
+			TargetPortIdentity: DefaultTargetPortIdentity,
+			TLVs: []TLV{
+				&GrantUnicastTransmissionTLV{
+					TLVHead:               TLVHead{TLVType: TLVGrantUnicastTransmission, LengthField: 8},
+					MsgTypeAndReserved:    NewUnicastMsgTypeAndFlags(MessageSync, 0),
+					LogInterMessagePeriod: -7,
+					DurationField:         300,
+					Renewal:               1,
+				},
+			},
+		},
+		defaultDS,
+		userDescription,
+		enablePort,
+		&ManagementMsgErrorStatus{
+			ManagementMsgHead: defaultDS.ManagementMsgHead,
+			ManagementErrorStatusTLV: ManagementErrorStatusTLV{
+				TLVHead:           TLVHead{TLVType: TLVManagementErrorStatus, LengthField: 8},
+				ManagementErrorID: ErrorNotSupported,
+				ManagementID:      IDDefaultDataSet,
+				DisplayData:       "nope",
+			},
+		},
+	}
+	for _, p := range packets {
+		t.Run(p.MessageType().String(), func(t *testing.T) {
+			b, err := json.Marshal(p)
+			require.NoError(t, err)
+			parsed, err := DecodePacketJSON(b)
+			require.NoError(t, err)
+			require.Equal(t, p, parsed)
+		})
+	}
+}
+
+func TestDecodePacketJSONErrors(t *testing.T) {
+	_, err := DecodePacketJSON([]byte(`{`))
+	require.Error(t, err)
+	_, err = DecodePacketJSON([]byte(`{"SdoIDAndMsgType":7}`))
+	require.Error(t, err)
+	_, err = DecodePacketJSON([]byte(`{"SdoIDAndMsgType":11,"TLVs":[{"TLVType":"MANAGEMENT_ERROR_STATUS"}]}`))
+	require.ErrorContains(t, err, "decoding TLV MANAGEMENT_ERROR_STATUS (2) from JSON is not supported")
+	_, err = DecodePacketJSON([]byte(`{"SdoIDAndMsgType":13,"TLV":{"TLVType":"MANAGEMENT","ManagementID":65000}}`))
+	require.ErrorContains(t, err, "decoding management TLV 65000 from JSON is not supported")
+}
@@ -82,6 +82,56 @@ type Management struct {
 	TLV ManagementTLV
 }
 
+// mgmtTLVHeader is implemented by all TLVs embedding ManagementTLVHead
+type mgmtTLVHeader interface {
+	mgmtTLVHead() *ManagementTLVHead
+}
+
+func (p *ManagementTLVHead) mgmtTLVHead() *ManagementTLVHead {
+	return p
+}
+
+// NewManagement prepares management packet with given action and TLV.
+// TLV type, management ID and all length fields are filled in, so TLV can be left zero apart from its data.
+// Use &ManagementTLVHead{} as TLV for GET and COMMAND requests without data.
+func NewManagement(action Action, id ManagementID, tlv ManagementTLV) (*Management, error) {
+	h, ok := tlv.(mgmtTLVHeader)
+	if !ok {
+		return nil, fmt.Errorf("management TLV %T doesn't embed ManagementTLVHead", tlv)
+	}
+	head := h.mgmtTLVHead()
+	head.TLVType = TLVManagement
+	head.ManagementID = id
+	var size int
+	if pp, ok := tlv.(encoding.BinaryMarshaler); ok {
+		b, err := pp.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		size = len(b)
+	} else {
+		size = binary.Size(tlv)
+	}
+	if size < binary.Size(ManagementTLVHead{}) {
+		return nil, fmt.Errorf("cannot determine size of management TLV %T", tlv)
+	}
+	head.LengthField = uint16(size - tlvHeadSize) //#nosec G115
+	return &Management{
+		ManagementMsgHead: ManagementMsgHead{
+			Header: Header{
+				SdoIDAndMsgType:    NewSdoIDAndMsgType(MessageManagement, 0),
+				Version:            Version,
+				MessageLength:      uint16(binary.Size(ManagementMsgHead{}) + size), //#nosec G115
+				SourcePortIdentity: identity,
+				LogMessageInterval: MgmtLogMessageInterval,
+			},
+			TargetPortIdentity: DefaultTargetPortIdentity,
+			ActionField:        action,
+		},
+		TLV: tlv,
+	}, nil
+}
+
 // UnmarshalBinary parses []byte and populates struct fields
 func (p *Management) UnmarshalBinary(rawBytes []byte) error {
 	var err error
@@ -192,11 +242,12 @@ func (p *ManagementMsgErrorStatus) UnmarshalBinary(rawBytes []byte) error {
 	toRead -= binary.Size(p.ManagementErrorStatusTLV.ManagementID)
 	toRead -= binary.Size(p.ManagementErrorStatusTLV.Reserved)
 
+	p.DisplayData = ""
 	if reader.Len() == 0 || toRead <= 0 {
 		// DisplayData is completely optional
 		return nil
 	}
-	data := make([]byte, reader.Len())
+	data := make([]byte, min(reader.Len(), toRead))
 	if _, err := io.ReadFull(reader, data); err != nil {
 		return err
 	}
@@ -283,6 +334,32 @@ func (t ManagementErrorID) Error() string {
 	return t.String()
 }
 
+// ManagementError is an error carried by MANAGEMENT_ERROR_STATUS TLV.
+// It wraps ManagementErrorID, so errors.Is(err, ErrorNotSupported) can be used to check for specific errors.
+type ManagementError struct {
+	ErrorID      ManagementErrorID
+	ManagementID ManagementID
+	DisplayData  PTPText
+}
+
+func (e *ManagementError) Error() string {
+	return fmt.Sprintf("got Management Error in response: %s", e.ErrorID)
+}
+
+// Unwrap returns ManagementErrorID
+func (e *ManagementError) Unwrap() error {
+	return e.ErrorID
+}
+
+// ManagementError returns error carried by the packet
+func (p *ManagementMsgErrorStatus) ManagementError() *ManagementError {
+	return &ManagementError{
+		ErrorID:      p.ManagementErrorID,
+		ManagementID: p.ManagementErrorStatusTLV.ManagementID,
+		DisplayData:  p.DisplayData,
+	}
+}
+
 func decodeMgmtPacket(data []byte) (Packet, error) {
 	packet := &Management{}
 	err := packet.UnmarshalBinary(data)
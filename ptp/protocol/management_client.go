@@ -59,7 +59,7 @@ func (c *MgmtClient) Communicate(packet *Management) (*Management, error) {
 	}
 	errorPacket, ok := res.(*ManagementMsgErrorStatus)
 	if ok {
-		return nil, fmt.Errorf("got Management Error in response: %w", errorPacket.ManagementErrorStatusTLV.ManagementErrorID)
+		return nil, errorPacket.ManagementError()
 	}
 	p, ok := res.(*Management)
 	if !ok {
@@ -123,3 +123,47 @@ func (c *MgmtClient) ClockAccuracy() (*ClockAccuracyTLV, error) {
 	}
 	return tlv, nil
 }
+
+// ClockDescription sends CLOCK_DESCRIPTION request and returns response
+func (c *MgmtClient) ClockDescription() (*ClockDescriptionTLV, error) {
+	resp, err := c.Get(IDClockDescription)
+	if err != nil {
+		return nil, err
+	}
+	tlv, ok := resp.(*ClockDescriptionTLV)
+	if !ok {
+		return nil, fmt.Errorf("got unexpected management TLV %T, wanted %T", resp, tlv)
+	}
+	return tlv, nil
+}
+
+// Get sends GET request for the management ID and returns the TLV from response
+func (c *MgmtClient) Get(id ManagementID) (ManagementTLV, error) {
+	return c.request(GET, id, &ManagementTLVHead{})
+}
+
+// Set sends SET request with the TLV and returns the TLV from response
+func (c *MgmtClient) Set(id ManagementID, tlv ManagementTLV) (ManagementTLV, error) {
+	return c.request(SET, id, tlv)
+}
+
+// Command sends COMMAND request without data, like ENABLE_PORT, and waits for acknowledgement
+func (c *MgmtClient) Command(id ManagementID) error {
+	_, err := c.request(COMMAND, id, &ManagementTLVHead{})
+	return err
+}
+
+func (c *MgmtClient) request(action Action, id ManagementID, tlv ManagementTLV) (ManagementTLV, error) {
+	req, err := NewManagement(action, id, tlv)
+	if err != nil {
+		return nil, err
+	}
+	p, err := c.Communicate(req)
+	if err != nil {
+		return nil, err
+	}
+	if p.TLV.MgmtID() != id {
+		return nil, fmt.Errorf("got response for management ID 0x%x, wanted 0x%x", p.TLV.MgmtID(), id)
+	}
+	return p.TLV, nil
+}
@@ -115,6 +115,11 @@ func TestMgmtClientCommunicateError(t *testing.T) {
 	_, client := prepareTestClient(t, packet)
 	_, err = client.Communicate(CurrentDataSetRequest())
 	require.EqualError(t, err, "got Management Error in response: NOT_SUPPORTED")
+	require.ErrorIs(t, err, ErrorNotSupported)
+	require.NotErrorIs(t, err, ErrorWrongLength)
+	var mgmtErr *ManagementError
+	require.ErrorAs(t, err, &mgmtErr)
+	require.Equal(t, IDCurrentDataSet, mgmtErr.ManagementID)
 }
 
 func TestMgmtClientCommunicateOK(t *testing.T) {
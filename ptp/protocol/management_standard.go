@@ -0,0 +1,969 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Management TLVs from 15.5.3 Management TLV data formats which are not covered elsewhere.
+// The same TLVs are used for GET responses and SET requests.
+
+// TimePropertiesDataSetTLV Spec Table 86 - TIME_PROPERTIES_DATA_SET management TLV data field
+type TimePropertiesDataSetTLV struct {
+	ManagementTLVHead
+
+	CurrentUTCOffset int16
+	Flags            uint8 // LI61, LI59, UTCV, PTP, TTRA, FTRA in bits 0..5
+	TimeSource       TimeSource
+}
+
+// PortDataSetTLV Spec Table 87 - PORT_DATA_SET management TLV data field
+type PortDataSetTLV struct {
+	ManagementTLVHead
+
+	PortIdentity            PortIdentity
+	PortState               PortState
+	LogMinDelayReqInterval  LogInterval
+	PeerMeanPathDelay       TimeInterval
+	LogAnnounceInterval     LogInterval
+	AnnounceReceiptTimeout  uint8
+	LogSyncInterval         LogInterval
+	DelayMechanism          uint8
+	LogMinPdelayReqInterval LogInterval
+	VersionNumber           uint8 // minorVersionPTP in upper nibble
+}
+
+// Priority1TLV Spec Table 88 - PRIORITY1 management TLV data field
+type Priority1TLV struct {
+	ManagementTLVHead
+
+	Priority1 uint8
+	Reserved  uint8
+}
+
+// Priority2TLV Spec Table 89 - PRIORITY2 management TLV data field
+type Priority2TLV struct {
+	ManagementTLVHead
+
+	Priority2 uint8
+	Reserved  uint8
+}
+
+// DomainTLV Spec Table 90 - DOMAIN management TLV data field
+type DomainTLV struct {
+	ManagementTLVHead
+
+	DomainNumber uint8
+	Reserved     uint8
+}
+
+// SlaveOnlyTLV Spec Table 91 - SLAVE_ONLY management TLV data field
+type SlaveOnlyTLV struct {
+	ManagementTLVHead
+
+	Flags    uint8 // SO in bit 0
+	Reserved uint8
+}
+
+// LogAnnounceIntervalTLV Spec Table 92 - LOG_ANNOUNCE_INTERVAL management TLV data field
+type LogAnnounceIntervalTLV struct {
+	ManagementTLVHead
+
+	LogAnnounceInterval LogInterval
+	Reserved            uint8
+}
+
+// AnnounceReceiptTimeoutTLV Spec Table 93 - ANNOUNCE_RECEIPT_TIMEOUT management TLV data field
+type AnnounceReceiptTimeoutTLV struct {
+	ManagementTLVHead
+
+	AnnounceReceiptTimeout uint8
+	Reserved               uint8
+}
+
+// LogSyncIntervalTLV Spec Table 94 - LOG_SYNC_INTERVAL management TLV data field
+type LogSyncIntervalTLV struct {
+	ManagementTLVHead
+
+	LogSyncInterval LogInterval
+	Reserved        uint8
+}
+
+// VersionNumberTLV Spec Table 95 - VERSION_NUMBER management TLV data field
+type VersionNumberTLV struct {
+	ManagementTLVHead
+
+	VersionNumber uint8 // minorVersionPTP in upper nibble
+	Reserved      uint8
+}
+
+// TimeTLV Spec Table 96 - TIME management TLV data field
+type TimeTLV struct {
+	ManagementTLVHead
+
+	CurrentTime Timestamp
+}
+
+// UTCPropertiesTLV Spec Table 97 - UTC_PROPERTIES management TLV data field
+type UTCPropertiesTLV struct {
+	ManagementTLVHead
+
+	CurrentUTCOffset int16
+	Flags            uint8 // LI61, LI59, UTCV in bits 0..2
+	Reserved         uint8
+}
+
+// TraceabilityPropertiesTLV Spec Table 98 - TRACEABILITY_PROPERTIES management TLV data field
+type TraceabilityPropertiesTLV struct {
+	ManagementTLVHead
+
+	Flags    uint8 // TTRA in bit 4, FTRA in bit 5
+	Reserved uint8
+}
+
+// TimescalePropertiesTLV Spec Table 99 - TIMESCALE_PROPERTIES management TLV data field
+type TimescalePropertiesTLV struct {
+	ManagementTLVHead
+
+	Flags      uint8 // PTP in bit 3
+	TimeSource TimeSource
+}
+
+// UnicastNegotiationEnableTLV Spec Table 100 - UNICAST_NEGOTIATION_ENABLE management TLV data field
+type UnicastNegotiationEnableTLV struct {
+	ManagementTLVHead
+
+	Flags    uint8 // EN in bit 0
+	Reserved uint8
+}
+
+// PathTraceEnableTLV Spec Table 102 - PATH_TRACE_ENABLE management TLV data field
+type PathTraceEnableTLV struct {
+	ManagementTLVHead
+
+	Flags    uint8 // EN in bit 0
+	Reserved uint8
+}
+
+// UnicastMasterMaxTableSizeTLV Spec Table 105 - UNICAST_MASTER_MAX_TABLE_SIZE management TLV data field
+type UnicastMasterMaxTableSizeTLV struct {
+	ManagementTLVHead
+
+	MaxTableSize uint16
+}
+
+// AcceptableMasterTableEnabledTLV Spec Table 107 - ACCEPTABLE_MASTER_TABLE_ENABLED management TLV data field
+type AcceptableMasterTableEnabledTLV struct {
+	ManagementTLVHead
+
+	Flags    uint8 // EN in bit 0
+	Reserved uint8
+}
+
+// AcceptableMasterMaxTableSizeTLV Spec Table 108 - ACCEPTABLE_MASTER_MAX_TABLE_SIZE management TLV data field
+type AcceptableMasterMaxTableSizeTLV struct {
+	ManagementTLVHead
+
+	MaxTableSize uint16
+}
+
+// AlternateMasterTLV Spec Table 109 - ALTERNATE_MASTER management TLV data field
+type AlternateMasterTLV struct {
+	ManagementTLVHead
+
+	Flags                             uint8 // S in bit 0
+	LogAlternateMulticastSyncInterval LogInterval
+	NumberOfAlternateMasters          uint8
+	Reserved                          uint8
+}
+
+// AlternateTimeOffsetEnableTLV Spec Table 110 - ALTERNATE_TIME_OFFSET_ENABLE management TLV data field
+type AlternateTimeOffsetEnableTLV struct {
+	ManagementTLVHead
+
+	KeyField uint8
+	Flags    uint8 // EN in bit 0
+}
+
+// AlternateTimeOffsetMaxKeyTLV Spec Table 112 - ALTERNATE_TIME_OFFSET_MAX_KEY management TLV data field
+type AlternateTimeOffsetMaxKeyTLV struct {
+	ManagementTLVHead
+
+	MaxKey   uint8
+	Reserved uint8
+}
+
+// AlternateTimeOffsetPropertiesTLV Spec Table 113 - ALTERNATE_TIME_OFFSET_PROPERTIES management TLV data field
+type AlternateTimeOffsetPropertiesTLV struct {
+	ManagementTLVHead
+
+	KeyField       uint8
+	CurrentOffset  int32
+	JumpSeconds    int32
+	TimeOfNextJump PTPSeconds
+	Reserved       uint8
+}
+
+// ExternalPortConfigurationEnabledTLV Spec Table 114 - EXTERNAL_PORT_CONFIGURATION_ENABLED management TLV data field
+type ExternalPortConfigurationEnabledTLV struct {
+	ManagementTLVHead
+
+	Flags    uint8 // EPC in bit 0
+	Reserved uint8
+}
+
+// MasterOnlyTLV Spec Table 115 - MASTER_ONLY management TLV data field
+type MasterOnlyTLV struct {
+	ManagementTLVHead
+
+	Flags    uint8 // MO in bit 0
+	Reserved uint8
+}
+
+// HoldoverUpgradeEnableTLV Spec Table 116 - HOLDOVER_UPGRADE_ENABLE management TLV data field
+type HoldoverUpgradeEnableTLV struct {
+	ManagementTLVHead
+
+	Flags    uint8 // EN in bit 0
+	Reserved uint8
+}
+
+// ExtPortConfigPortDataSetTLV Spec Table 117 - EXT_PORT_CONFIG_PORT_DATA_SET management TLV data field
+type ExtPortConfigPortDataSetTLV struct {
+	ManagementTLVHead
+
+	Flags        uint8 // AQ in bit 0
+	DesiredState PortState
+}
+
+// TransparentClockDefaultDataSetTLV Spec Table 118 - TRANSPARENT_CLOCK_DEFAULT_DATA_SET management TLV data field
+type TransparentClockDefaultDataSetTLV struct {
+	ManagementTLVHead
+
+	ClockIdentity  ClockIdentity
+	NumberPorts    uint16
+	DelayMechanism uint8
+	PrimaryDomain  uint8
+}
+
+// TransparentClockPortDataSetTLV Spec Table 119 - TRANSPARENT_CLOCK_PORT_DATA_SET management TLV data field
+type TransparentClockPortDataSetTLV struct {
+	ManagementTLVHead
+
+	PortIdentity            PortIdentity
+	Flags                   uint8 // FLT in bit 0
+	LogMinPdelayReqInterval LogInterval
+	PeerMeanPathDelay       TimeInterval
+}
+
+// PrimaryDomainTLV Spec Table 120 - PRIMARY_DOMAIN management TLV data field
+type PrimaryDomainTLV struct {
+	ManagementTLVHead
+
+	PrimaryDomain uint8
+	Reserved      uint8
+}
+
+// DelayMechanismTLV Spec Table 121 - DELAY_MECHANISM management TLV data field
+type DelayMechanismTLV struct {
+	ManagementTLVHead
+
+	DelayMechanism uint8
+	Reserved       uint8
+}
+
+// LogMinPdelayReqIntervalTLV Spec Table 122 - LOG_MIN_PDELAY_REQ_INTERVAL management TLV data field
+type LogMinPdelayReqIntervalTLV struct {
+	ManagementTLVHead
+
+	LogMinPdelayReqInterval LogInterval
+	Reserved                uint8
+}
+
+// InitializeTLV Spec Table 64 - INITIALIZE management TLV data field
+type InitializeTLV struct {
+	ManagementTLVHead
+
+	InitializationKey uint16
+}
+
+// ClockType is a bitmask of clock types, Spec Table 62 - clockType values
+type ClockType uint16
+
+// ClockType bits as per Table 62
+const (
+	ClockTypeOrdinary              ClockType = 0x8000
+	ClockTypeBoundary              ClockType = 0x4000
+	ClockTypePeerToPeerTransparent ClockType = 0x2000
+	ClockTypeEndToEndTransparent   ClockType = 0x1000
+	ClockTypeManagement            ClockType = 0x0800
+)
+
+// CLOCK_DESCRIPTION field limits as per Table 61
+const (
+	maxPhysicalLayerProtocolLength      = 32
+	maxProductDescriptionLength         = 64
+	maxRevisionDataLength               = 32
+	maxUserDescriptionLength            = 128
+	clockDescriptionManufacturerSize    = 4 // manufacturerIdentity and reserved
+	clockDescriptionProfileIdentitySize = 6
+)
+
+// ClockTypeToString is a map from ClockType bit to string
+var ClockTypeToString = map[ClockType]string{
+	ClockTypeOrdinary:              "ORDINARY",
+	ClockTypeBoundary:              "BOUNDARY",
+	ClockTypePeerToPeerTransparent: "P2P_TRANSPARENT",
+	ClockTypeEndToEndTransparent:   "E2E_TRANSPARENT",
+	ClockTypeManagement:            "MANAGEMENT",
+}
+
+func (c ClockType) String() string {
+	res := []string{}
+	for _, b := range []ClockType{ClockTypeOrdinary, ClockTypeBoundary, ClockTypePeerToPeerTransparent, ClockTypeEndToEndTransparent, ClockTypeManagement} {
+		if c&b != 0 {
+			res = append(res, ClockTypeToString[b])
+		}
+	}
+	if rest := c &^ 0xf800; rest != 0 || len(res) == 0 {
+		res = append(res, fmt.Sprintf("0x%04x", uint16(rest)))
+	}
+	return strings.Join(res, "|")
+}
+
+// ClockDescriptionTLV Spec Table 61 - CLOCK_DESCRIPTION management TLV data field
+type ClockDescriptionTLV struct {
+	ManagementTLVHead
+
+	ClockType             ClockType
+	PhysicalLayerProtocol PTPText
+	PhysicalAddress       []byte
+	ProtocolAddress       PortAddress
+	ManufacturerIdentity  [3]uint8
+	ProductDescription    PTPText
+	RevisionData          PTPText
+	UserDescription       PTPText
+	ProfileIdentity       [6]uint8
+}
+
+// PhysicalAddressString returns physicalAddress formatted like linuxptp does, as colon-separated hex bytes
+func (t *ClockDescriptionTLV) PhysicalAddressString() string {
+	return net.HardwareAddr(t.PhysicalAddress).String()
+}
+
+// MarshalBinary converts TLV to []bytes
+func (t *ClockDescriptionTLV) MarshalBinary() ([]byte, error) {
+	var bytes bytes.Buffer
+	if err := binary.Write(&bytes, binary.BigEndian, t.ManagementTLVHead); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&bytes, binary.BigEndian, t.ClockType); err != nil {
+		return nil, err
+	}
+	if err := writePTPText(&bytes, t.PhysicalLayerProtocol, maxPhysicalLayerProtocolLength); err != nil {
+		return nil, fmt.Errorf("writing physicalLayerProtocol: %w", err)
+	}
+	if len(t.PhysicalAddress) > 0xffff {
+		return nil, fmt.Errorf("physical address is too long: %d", len(t.PhysicalAddress))
+	}
+	if err := binary.Write(&bytes, binary.BigEndian, uint16(len(t.PhysicalAddress))); err != nil {
+		return nil, err
+	}
+	bytes.Write(t.PhysicalAddress)
+	if int(t.ProtocolAddress.AddressLength) != len(t.ProtocolAddress.AddressField) {
+		return nil, fmt.Errorf("protocol address length %d doesn't match address of %d bytes", t.ProtocolAddress.AddressLength, len(t.ProtocolAddress.AddressField))
+	}
+	ab, err := t.ProtocolAddress.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	bytes.Write(ab)
+	bytes.Write(t.ManufacturerIdentity[:])
+	bytes.WriteByte(0) // reserved
+	if err := writePTPText(&bytes, t.ProductDescription, maxProductDescriptionLength); err != nil {
+		return nil, fmt.Errorf("writing productDescription: %w", err)
+	}
+	if err := writePTPText(&bytes, t.RevisionData, maxRevisionDataLength); err != nil {
+		return nil, fmt.Errorf("writing revisionData: %w", err)
+	}
+	if err := writePTPText(&bytes, t.UserDescription, maxUserDescriptionLength); err != nil {
+		return nil, fmt.Errorf("writing userDescription: %w", err)
+	}
+	bytes.Write(t.ProfileIdentity[:])
+	if bytes.Len()%2 != 0 {
+		bytes.WriteByte(0)
+	}
+	return bytes.Bytes(), nil
+}
+
+// UnmarshalBinary parses []byte and populates struct fields
+func (t *ClockDescriptionTLV) UnmarshalBinary(b []byte) error {
+	n, err := unmarshalMgmtTLVHead(&t.ManagementTLVHead, b)
+	if err != nil {
+		return err
+	}
+	end := tlvHeadSize + int(t.LengthField)
+	if end < n+2 {
+		return fmt.Errorf("not enough data to decode CLOCK_DESCRIPTION")
+	}
+	t.ClockType = ClockType(binary.BigEndian.Uint16(b[n:]))
+	n += 2
+	read, err := readPTPText(&t.PhysicalLayerProtocol, b[n:end])
+	if err != nil {
+		return fmt.Errorf("reading physicalLayerProtocol: %w", err)
+	}
+	n += read
+	if end < n+2 {
+		return fmt.Errorf("not enough data to decode CLOCK_DESCRIPTION physicalAddressLength")
+	}
+	length := int(binary.BigEndian.Uint16(b[n:]))
+	n += 2
+	if end < n+length {
+		return fmt.Errorf("not enough data to decode CLOCK_DESCRIPTION physicalAddress of length %d", length)
+	}
+	t.PhysicalAddress = make([]byte, length)
+	copy(t.PhysicalAddress, b[n:])
+	n += length
+	addrs, err := unmarshalPortAddressTable(b[n:end], 1)
+	if err != nil {
+		return fmt.Errorf("reading protocolAddress: %w", err)
+	}
+	t.ProtocolAddress = addrs[0]
+	n += 4 + int(t.ProtocolAddress.AddressLength)
+	if end < n+clockDescriptionManufacturerSize {
+		return fmt.Errorf("not enough data to decode CLOCK_DESCRIPTION manufacturerIdentity")
+	}
+	copy(t.ManufacturerIdentity[:], b[n:])
+	n += clockDescriptionManufacturerSize
+	for _, f := range []struct {
+		name string
+		text *PTPText
+	}{
+		{name: "productDescription", text: &t.ProductDescription},
+		{name: "revisionData", text: &t.RevisionData},
+		{name: "userDescription", text: &t.UserDescription},
+	} {
+		read, err := readPTPText(f.text, b[n:end])
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", f.name, err)
+		}
+		n += read
+	}
+	if end < n+clockDescriptionProfileIdentitySize {
+		return fmt.Errorf("not enough data to decode CLOCK_DESCRIPTION profileIdentity")
+	}
+	copy(t.ProfileIdentity[:], b[n:])
+	return nil
+}
+
+// UserDescriptionTLV Spec Table 63 - USER_DESCRIPTION management TLV data field
+type UserDescriptionTLV struct {
+	ManagementTLVHead
+
+	UserDescription PTPText
+}
+
+// MarshalBinary converts TLV to []bytes
+func (t *UserDescriptionTLV) MarshalBinary() ([]byte, error) {
+	var bytes bytes.Buffer
+	if err := binary.Write(&bytes, binary.BigEndian, t.ManagementTLVHead); err != nil {
+		return nil, err
+	}
+	if len(t.UserDescription) > 128 {
+		return nil, fmt.Errorf("user description is too long")
+	}
+	bytes.WriteByte(uint8(len(t.UserDescription)))
+	bytes.WriteString(string(t.UserDescription))
+	if bytes.Len()%2 != 0 {
+		bytes.WriteByte(0)
+	}
+	return bytes.Bytes(), nil
+}
+
+// UnmarshalBinary parses []byte and populates struct fields
+func (t *UserDescriptionTLV) UnmarshalBinary(b []byte) error {
+	n, err := unmarshalMgmtTLVHead(&t.ManagementTLVHead, b)
+	if err != nil {
+		return err
+	}
+	if _, err := readPTPText(&t.UserDescription, b[n:]); err != nil {
+		return fmt.Errorf("reading USER_DESCRIPTION: %w", err)
+	}
+	return nil
+}
+
+// FaultRecord Spec Table 65 - FaultRecord
+type FaultRecord struct {
+	FaultTime        Timestamp
+	SeverityCode     uint8
+	FaultName        PTPText
+	FaultValue       PTPText
+	FaultDescription PTPText
+}
+
+// FaultLogTLV Spec Table 66 - FAULT_LOG management TLV data field
+type FaultLogTLV struct {
+	ManagementTLVHead
+
+	FaultRecords []FaultRecord
+}
+
+// MarshalBinary converts TLV to []bytes
+func (t *FaultLogTLV) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.BigEndian, t.ManagementTLVHead); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&buf, binary.BigEndian, uint16(len(t.FaultRecords))); err != nil { //#nosec G115
+		return nil, err
+	}
+	for _, r := range t.FaultRecords {
+		var rb bytes.Buffer
+		rb.Write([]byte{0, 0}) // length goes first
+		if err := binary.Write(&rb, binary.BigEndian, r.FaultTime); err != nil {
+			return nil, err
+		}
+		rb.WriteByte(r.SeverityCode)
+		rec := rb.Bytes()
+		for _, text := range []PTPText{r.FaultName, r.FaultValue, r.FaultDescription} {
+			if len(text) > 255 {
+				return nil, fmt.Errorf("text is too long")
+			}
+			rec = append(rec, uint8(len(text)))
+			rec = append(rec, text...)
+		}
+		binary.BigEndian.PutUint16(rec, uint16(len(rec)-2)) //#nosec G115
+		buf.Write(rec)
+	}
+	if buf.Len()%2 != 0 {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary parses []byte and populates struct fields
+func (t *FaultLogTLV) UnmarshalBinary(b []byte) error {
+	n, err := unmarshalMgmtTLVHead(&t.ManagementTLVHead, b)
+	if err != nil {
+		return err
+	}
+	if len(b) < n+2 {
+		return fmt.Errorf("not enough data to decode FAULT_LOG")
+	}
+	num := int(binary.BigEndian.Uint16(b[n:]))
+	n += 2
+	t.FaultRecords = make([]FaultRecord, 0, num)
+	for i := 0; i < num; i++ {
+		if len(b) < n+13 {
+			return fmt.Errorf("not enough data to decode fault record %d", i)
+		}
+		length := int(binary.BigEndian.Uint16(b[n:]))
+		end := n + 2 + length
+		if len(b) < end || length < 11 {
+			return fmt.Errorf("fault record %d of length %d doesn't fit into %d bytes", i, length, len(b)-n)
+		}
+		r := FaultRecord{}
+		if err := binary.Read(bytes.NewReader(b[n+2:n+12]), binary.BigEndian, &r.FaultTime); err != nil {
+			return err
+		}
+		r.SeverityCode = b[n+12]
+		pos := n + 13
+		for _, text := range []*PTPText{&r.FaultName, &r.FaultValue, &r.FaultDescription} {
+			read, err := readPTPText(text, b[pos:end])
+			if err != nil {
+				return fmt.Errorf("reading fault record %d: %w", i, err)
+			}
+			pos += read
+		}
+		t.FaultRecords = append(t.FaultRecords, r)
+		n = end
+	}
+	return nil
+}
+
+// PathTraceListTLV Spec Table 101 - PATH_TRACE_LIST management TLV data field
+type PathTraceListTLV struct {
+	ManagementTLVHead
+
+	PathSequence []ClockIdentity
+}
+
+// MarshalBinary converts TLV to []bytes
+func (t *PathTraceListTLV) MarshalBinary() ([]byte, error) {
+	var bytes bytes.Buffer
+	if err := binary.Write(&bytes, binary.BigEndian, t.ManagementTLVHead); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&bytes, binary.BigEndian, t.PathSequence); err != nil {
+		return nil, err
+	}
+	return bytes.Bytes(), nil
+}
+
+// UnmarshalBinary parses []byte and populates struct fields
+func (t *PathTraceListTLV) UnmarshalBinary(b []byte) error {
+	n, err := unmarshalMgmtTLVHead(&t.ManagementTLVHead, b)
+	if err != nil {
+		return err
+	}
+	end := tlvHeadSize + int(t.LengthField)
+	t.PathSequence = make([]ClockIdentity, 0, (end-n)/8)
+	for ; n+8 <= end; n += 8 {
+		t.PathSequence = append(t.PathSequence, ClockIdentity(binary.BigEndian.Uint64(b[n:])))
+	}
+	return nil
+}
+
+// GrandmasterClusterTableTLV Spec Table 103 - GRANDMASTER_CLUSTER_TABLE management TLV data field
+type GrandmasterClusterTableTLV struct {
+	ManagementTLVHead
+
+	LogQueryInterval LogInterval
+	PortAddresses    []PortAddress
+}
+
+// MarshalBinary converts TLV to []bytes
+func (t *GrandmasterClusterTableTLV) MarshalBinary() ([]byte, error) {
+	if len(t.PortAddresses) > 255 {
+		return nil, fmt.Errorf("too many port addresses: %d", len(t.PortAddresses))
+	}
+	head := []byte{byte(t.LogQueryInterval), uint8(len(t.PortAddresses))}
+	return marshalPortAddressTable(&t.ManagementTLVHead, head, t.PortAddresses)
+}
+
+// UnmarshalBinary parses []byte and populates struct fields
+func (t *GrandmasterClusterTableTLV) UnmarshalBinary(b []byte) error {
+	n, err := unmarshalMgmtTLVHead(&t.ManagementTLVHead, b)
+	if err != nil {
+		return err
+	}
+	if len(b) < n+2 {
+		return fmt.Errorf("not enough data to decode GRANDMASTER_CLUSTER_TABLE")
+	}
+	t.LogQueryInterval = LogInterval(b[n])
+	t.PortAddresses, err = unmarshalPortAddressTable(b[n+2:], int(b[n+1]))
+	return err
+}
+
+// UnicastMasterTableTLV Spec Table 104 - UNICAST_MASTER_TABLE management TLV data field
+type UnicastMasterTableTLV struct {
+	ManagementTLVHead
+
+	LogQueryInterval LogInterval
+	PortAddresses    []PortAddress
+}
+
+// MarshalBinary converts TLV to []bytes
+func (t *UnicastMasterTableTLV) MarshalBinary() ([]byte, error) {
+	if len(t.PortAddresses) > 0xffff {
+		return nil, fmt.Errorf("too many port addresses: %d", len(t.PortAddresses))
+	}
+	head := []byte{byte(t.LogQueryInterval), 0, 0}
+	binary.BigEndian.PutUint16(head[1:], uint16(len(t.PortAddresses)))
+	return marshalPortAddressTable(&t.ManagementTLVHead, head, t.PortAddresses)
+}
+
+// UnmarshalBinary parses []byte and populates struct fields
+func (t *UnicastMasterTableTLV) UnmarshalBinary(b []byte) error {
+	n, err := unmarshalMgmtTLVHead(&t.ManagementTLVHead, b)
+	if err != nil {
+		return err
+	}
+	if len(b) < n+3 {
+		return fmt.Errorf("not enough data to decode UNICAST_MASTER_TABLE")
+	}
+	t.LogQueryInterval = LogInterval(b[n])
+	t.PortAddresses, err = unmarshalPortAddressTable(b[n+3:], int(binary.BigEndian.Uint16(b[n+1:])))
+	return err
+}
+
+// AcceptableMaster Spec Table 106 - AcceptableMaster
+type AcceptableMaster struct {
+	AcceptablePortIdentity PortIdentity
+	AlternatePriority1     uint8
+}
+
+// AcceptableMasterTableTLV Spec Table 106 - ACCEPTABLE_MASTER_TABLE management TLV data field
+type AcceptableMasterTableTLV struct {
+	ManagementTLVHead
+
+	AcceptableMasters []AcceptableMaster
+}
+
+// MarshalBinary converts TLV to []bytes
+func (t *AcceptableMasterTableTLV) MarshalBinary() ([]byte, error) {
+	var bytes bytes.Buffer
+	if err := binary.Write(&bytes, binary.BigEndian, t.ManagementTLVHead); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&bytes, binary.BigEndian, int16(len(t.AcceptableMasters))); err != nil { //#nosec G115
+		return nil, err
+	}
+	if err := binary.Write(&bytes, binary.BigEndian, t.AcceptableMasters); err != nil {
+		return nil, err
+	}
+	if bytes.Len()%2 != 0 {
+		bytes.WriteByte(0)
+	}
+	return bytes.Bytes(), nil
+}
+
+// UnmarshalBinary parses []byte and populates struct fields
+func (t *AcceptableMasterTableTLV) UnmarshalBinary(b []byte) error {
+	n, err := unmarshalMgmtTLVHead(&t.ManagementTLVHead, b)
+	if err != nil {
+		return err
+	}
+	if len(b) < n+2 {
+		return fmt.Errorf("not enough data to decode ACCEPTABLE_MASTER_TABLE")
+	}
+	num := int(int16(binary.BigEndian.Uint16(b[n:]))) //#nosec G115
+	if num < 0 {
+		return fmt.Errorf("negative ACCEPTABLE_MASTER_TABLE size %d", num)
+	}
+	size := binary.Size(AcceptableMaster{})
+	if len(b) < n+2+num*size {
+		return fmt.Errorf("not enough data to decode %d acceptable masters", num)
+	}
+	t.AcceptableMasters = make([]AcceptableMaster, num)
+	return binary.Read(bytes.NewReader(b[n+2:n+2+num*size]), binary.BigEndian, t.AcceptableMasters)
+}
+
+// AlternateTimeOffsetNameTLV Spec Table 111 - ALTERNATE_TIME_OFFSET_NAME management TLV data field
+type AlternateTimeOffsetNameTLV struct {
+	ManagementTLVHead
+
+	KeyField    uint8
+	DisplayName PTPText
+}
+
+// MarshalBinary converts TLV to []bytes
+func (t *AlternateTimeOffsetNameTLV) MarshalBinary() ([]byte, error) {
+	var bytes bytes.Buffer
+	if err := binary.Write(&bytes, binary.BigEndian, t.ManagementTLVHead); err != nil {
+		return nil, err
+	}
+	bytes.WriteByte(t.KeyField)
+	if len(t.DisplayName) > 10 {
+		return nil, fmt.Errorf("display name is too long")
+	}
+	bytes.WriteByte(uint8(len(t.DisplayName)))
+	bytes.WriteString(string(t.DisplayName))
+	if bytes.Len()%2 != 0 {
+		bytes.WriteByte(0)
+	}
+	return bytes.Bytes(), nil
+}
+
+// UnmarshalBinary parses []byte and populates struct fields
+func (t *AlternateTimeOffsetNameTLV) UnmarshalBinary(b []byte) error {
+	n, err := unmarshalMgmtTLVHead(&t.ManagementTLVHead, b)
+	if err != nil {
+		return err
+	}
+	if len(b) < n+2 {
+		return fmt.Errorf("not enough data to decode ALTERNATE_TIME_OFFSET_NAME")
+	}
+	t.KeyField = b[n]
+	if _, err := readPTPText(&t.DisplayName, b[n+1:]); err != nil {
+		return fmt.Errorf("reading ALTERNATE_TIME_OFFSET_NAME: %w", err)
+	}
+	return nil
+}
+
+// unmarshalMgmtTLVHead reads management TLV head and returns the number of bytes read
+func unmarshalMgmtTLVHead(h *ManagementTLVHead, b []byte) (int, error) {
+	if err := unmarshalTLVHeader(&h.TLVHead, b); err != nil {
+		return 0, err
+	}
+	if len(b) < tlvHeadSize+2 {
+		return 0, fmt.Errorf("not enough data to decode management TLV head")
+	}
+	if tlvHeadSize+int(h.LengthField) > len(b) {
+		return 0, fmt.Errorf("cannot decode management TLV of length %d from %d bytes", tlvHeadSize+int(h.LengthField), len(b))
+	}
+	h.ManagementID = ManagementID(binary.BigEndian.Uint16(b[tlvHeadSize:]))
+	return tlvHeadSize + 2, nil
+}
+
+// writePTPText writes PTPText (without padding) no longer than maxLen
+func writePTPText(bytes *bytes.Buffer, text PTPText, maxLen int) error {
+	if len(text) > maxLen {
+		return fmt.Errorf("text of %d bytes is longer than %d", len(text), maxLen)
+	}
+	bytes.WriteByte(uint8(len(text)))
+	bytes.WriteString(string(text))
+	return nil
+}
+
+// readPTPText reads PTPText (without padding) and returns the number of bytes read
+func readPTPText(p *PTPText, b []byte) (int, error) {
+	if len(b) < 1 {
+		return 0, fmt.Errorf("not enough data to decode PTPText")
+	}
+	length := int(b[0])
+	if len(b) < 1+length {
+		return 0, fmt.Errorf("text field is too short, need %d got %d", 1+length, len(b))
+	}
+	*p = PTPText(b[1 : 1+length])
+	return 1 + length, nil
+}
+
+func marshalPortAddressTable(h *ManagementTLVHead, head []byte, addrs []PortAddress) ([]byte, error) {
+	var bytes bytes.Buffer
+	if err := binary.Write(&bytes, binary.BigEndian, h); err != nil {
+		return nil, err
+	}
+	bytes.Write(head)
+	for _, a := range addrs {
+		ab, err := a.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		bytes.Write(ab)
+	}
+	if bytes.Len()%2 != 0 {
+		bytes.WriteByte(0)
+	}
+	return bytes.Bytes(), nil
+}
+
+func unmarshalPortAddressTable(b []byte, num int) ([]PortAddress, error) {
+	res := make([]PortAddress, 0, num)
+	n := 0
+	for i := 0; i < num; i++ {
+		if len(b) < n+4 {
+			return nil, fmt.Errorf("not enough data to decode port address %d", i)
+		}
+		length := int(binary.BigEndian.Uint16(b[n+2:]))
+		if len(b) < n+4+length {
+			return nil, fmt.Errorf("not enough data to decode port address %d of length %d", i, length)
+		}
+		a := PortAddress{
+			NetworkProtocol: TransportType(binary.BigEndian.Uint16(b[n:])),
+			AddressLength:   uint16(length), //#nosec G115
+			AddressField:    make([]byte, length),
+		}
+		copy(a.AddressField, b[n+4:])
+		res = append(res, a)
+		n += 4 + length
+	}
+	return res, nil
+}
+
+// fixedMgmtTLVDecoder returns decoder for TLVs which can be read with binary.Read
+func fixedMgmtTLVDecoder(newTLV func() ManagementTLV) MgmtTLVDecoderFunc {
+	return func(data []byte) (ManagementTLV, error) {
+		tlv := newTLV()
+		if err := binary.Read(bytes.NewReader(data), binary.BigEndian, tlv); err != nil {
+			return nil, err
+		}
+		return tlv, nil
+	}
+}
+
+// mgmtTLVUnmarshaler is a management TLV which can decode itself
+type mgmtTLVUnmarshaler interface {
+	ManagementTLV
+	UnmarshalBinary([]byte) error
+}
+
+// unmarshalerMgmtTLVDecoder returns decoder for TLVs implementing UnmarshalBinary
+func unmarshalerMgmtTLVDecoder(newTLV func() mgmtTLVUnmarshaler) MgmtTLVDecoderFunc {
+	return func(data []byte) (ManagementTLV, error) {
+		tlv := newTLV()
+		if err := tlv.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+		return tlv, nil
+	}
+}
+
+func init() {
+	// the ones which carry no data
+	for _, id := range []ManagementID{
+		IDNullPTPManagement,
+		IDSaveInNonVolatileStorage,
+		IDResetNonVolatileStorage,
+		IDFaultLogReset,
+		IDEnablePort,
+		IDDisablePort,
+	} {
+		mgmtTLVDecoder[id] = fixedMgmtTLVDecoder(func() ManagementTLV { return &ManagementTLVHead{} })
+		mgmtTLVNew[id] = func() ManagementTLV { return &ManagementTLVHead{} }
+	}
+
+	fixed := map[ManagementID]func() ManagementTLV{
+		IDInitialize:                       func() ManagementTLV { return &InitializeTLV{} },
+		IDTimePropertiesDataSet:            func() ManagementTLV { return &TimePropertiesDataSetTLV{} },
+		IDPortDataSet:                      func() ManagementTLV { return &PortDataSetTLV{} },
+		IDPriority1:                        func() ManagementTLV { return &Priority1TLV{} },
+		IDPriority2:                        func() ManagementTLV { return &Priority2TLV{} },
+		IDDomain:                           func() ManagementTLV { return &DomainTLV{} },
+		IDSlaveOnly:                        func() ManagementTLV { return &SlaveOnlyTLV{} },
+		IDLogAnnounceInterval:              func() ManagementTLV { return &LogAnnounceIntervalTLV{} },
+		IDAnnounceReceiptTimeout:           func() ManagementTLV { return &AnnounceReceiptTimeoutTLV{} },
+		IDLogSyncInterval:                  func() ManagementTLV { return &LogSyncIntervalTLV{} },
+		IDVersionNumber:                    func() ManagementTLV { return &VersionNumberTLV{} },
+		IDTime:                             func() ManagementTLV { return &TimeTLV{} },
+		IDUTCProperties:                    func() ManagementTLV { return &UTCPropertiesTLV{} },
+		IDTraceabilityProperties:           func() ManagementTLV { return &TraceabilityPropertiesTLV{} },
+		IDTimescaleProperties:              func() ManagementTLV { return &TimescalePropertiesTLV{} },
+		IDUnicastNegotiationEnable:         func() ManagementTLV { return &UnicastNegotiationEnableTLV{} },
+		IDPathTraceEnable:                  func() ManagementTLV { return &PathTraceEnableTLV{} },
+		IDUnicastMasterMaxTableSize:        func() ManagementTLV { return &UnicastMasterMaxTableSizeTLV{} },
+		IDAcceptableMasterTableEnabled:     func() ManagementTLV { return &AcceptableMasterTableEnabledTLV{} },
+		IDAcceptableMasterMaxTableSize:     func() ManagementTLV { return &AcceptableMasterMaxTableSizeTLV{} },
+		IDAlternateMaster:                  func() ManagementTLV { return &AlternateMasterTLV{} },
+		IDAlternateTimeOffsetEnable:        func() ManagementTLV { return &AlternateTimeOffsetEnableTLV{} },
+		IDAlternateTimeOffsetMaxKey:        func() ManagementTLV { return &AlternateTimeOffsetMaxKeyTLV{} },
+		IDAlternateTimeOffsetProperties:    func() ManagementTLV { return &AlternateTimeOffsetPropertiesTLV{} },
+		IDExternalPortConfigurationEnabled: func() ManagementTLV { return &ExternalPortConfigurationEnabledTLV{} },
+		IDMasterOnly:                       func() ManagementTLV { return &MasterOnlyTLV{} },
+		IDHoldoverUpgradeEnable:            func() ManagementTLV { return &HoldoverUpgradeEnableTLV{} },
+		IDExtPortConfigPortDataSet:         func() ManagementTLV { return &ExtPortConfigPortDataSetTLV{} },
+		IDTransparentClockDefaultDataSet:   func() ManagementTLV { return &TransparentClockDefaultDataSetTLV{} },
+		IDTransparentClockPortDataSet:      func() ManagementTLV { return &TransparentClockPortDataSetTLV{} },
+		IDPrimaryDomain:                    func() ManagementTLV { return &PrimaryDomainTLV{} },
+		IDDelayMechanism:                   func() ManagementTLV { return &DelayMechanismTLV{} },
+		IDLogMinPdelayReqInterval:          func() ManagementTLV { return &LogMinPdelayReqIntervalTLV{} },
+	}
+	for id, f := range fixed {
+		mgmtTLVDecoder[id] = fixedMgmtTLVDecoder(f)
+		mgmtTLVNew[id] = f
+	}
+
+	variable := map[ManagementID]func() mgmtTLVUnmarshaler{
+		IDClockDescription:        func() mgmtTLVUnmarshaler { return &ClockDescriptionTLV{} },
+		IDUserDescription:         func() mgmtTLVUnmarshaler { return &UserDescriptionTLV{} },
+		IDFaultLog:                func() mgmtTLVUnmarshaler { return &FaultLogTLV{} },
+		IDPathTraceList:           func() mgmtTLVUnmarshaler { return &PathTraceListTLV{} },
+		IDGrandmasterClusterTable: func() mgmtTLVUnmarshaler { return &GrandmasterClusterTableTLV{} },
+		IDUnicastMasterTable:      func() mgmtTLVUnmarshaler { return &UnicastMasterTableTLV{} },
+		IDAcceptableMasterTable:   func() mgmtTLVUnmarshaler { return &AcceptableMasterTableTLV{} },
+		IDAlternateTimeOffsetName: func() mgmtTLVUnmarshaler { return &AlternateTimeOffsetNameTLV{} },
+	}
+	for id, f := range variable {
+		mgmtTLVDecoder[id] = unmarshalerMgmtTLVDecoder(f)
+		mgmtTLVNew[id] = func() ManagementTLV { return f() }
+	}
+}
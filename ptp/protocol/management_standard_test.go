@@ -0,0 +1,275 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mgmtRoundTrip(t *testing.T, action Action, id ManagementID, tlv ManagementTLV) *Management {
+	req, err := NewManagement(action, id, tlv)
+	require.NoError(t, err)
+	b, err := req.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, int(req.MessageLength), len(b))
+	require.Zero(t, len(b)%2, "packet length must be even")
+
+	got := &Management{}
+	require.NoError(t, got.UnmarshalBinary(b))
+	require.Equal(t, action, got.Action())
+	require.Equal(t, id, got.TLV.MgmtID())
+	return got
+}
+
+func TestManagementStandardTLVsRoundTrip(t *testing.T) {
+	tlvs := map[ManagementID]ManagementTLV{
+		IDNullPTPManagement:     &ManagementTLVHead{},
+		IDEnablePort:            &ManagementTLVHead{},
+		IDInitialize:            &InitializeTLV{InitializationKey: 1},
+		IDTimePropertiesDataSet: &TimePropertiesDataSetTLV{CurrentUTCOffset: 37, Flags: 0x8, TimeSource: TimeSourceGNSS},
+		IDPortDataSet: &PortDataSetTLV{
+			PortIdentity:            PortIdentity{ClockIdentity: 0x4857ddfffe0e91da, PortNumber: 1},
+			PortState:               PortStateSlave,
+			LogMinDelayReqInterval:  -4,
+			PeerMeanPathDelay:       NewTimeInterval(123.5),
+			LogAnnounceInterval:     1,
+			AnnounceReceiptTimeout:  3,
+			LogSyncInterval:         -4,
+			DelayMechanism:          1,
+			LogMinPdelayReqInterval: 0,
+			VersionNumber:           Version,
+		},
+		IDPriority1:                        &Priority1TLV{Priority1: 128},
+		IDPriority2:                        &Priority2TLV{Priority2: 127},
+		IDDomain:                           &DomainTLV{DomainNumber: 24},
+		IDSlaveOnly:                        &SlaveOnlyTLV{Flags: 1},
+		IDLogAnnounceInterval:              &LogAnnounceIntervalTLV{LogAnnounceInterval: -1},
+		IDAnnounceReceiptTimeout:           &AnnounceReceiptTimeoutTLV{AnnounceReceiptTimeout: 3},
+		IDLogSyncInterval:                  &LogSyncIntervalTLV{LogSyncInterval: -7},
+		IDVersionNumber:                    &VersionNumberTLV{VersionNumber: 0x12},
+		IDTime:                             &TimeTLV{CurrentTime: Timestamp{Seconds: PTPSeconds{0, 0, 0x65, 0x53, 0xf1, 0x00}, Nanoseconds: 42}},
+		IDUTCProperties:                    &UTCPropertiesTLV{CurrentUTCOffset: 37, Flags: 0x4},
+		IDTraceabilityProperties:           &TraceabilityPropertiesTLV{Flags: 0x30},
+		IDTimescaleProperties:              &TimescalePropertiesTLV{Flags: 0x8, TimeSource: TimeSourceGNSS},
+		IDUnicastNegotiationEnable:         &UnicastNegotiationEnableTLV{Flags: 1},
+		IDPathTraceEnable:                  &PathTraceEnableTLV{Flags: 1},
+		IDUnicastMasterMaxTableSize:        &UnicastMasterMaxTableSizeTLV{MaxTableSize: 16},
+		IDAcceptableMasterTableEnabled:     &AcceptableMasterTableEnabledTLV{Flags: 1},
+		IDAcceptableMasterMaxTableSize:     &AcceptableMasterMaxTableSizeTLV{MaxTableSize: 8},
+		IDAlternateMaster:                  &AlternateMasterTLV{Flags: 1, LogAlternateMulticastSyncInterval: -3, NumberOfAlternateMasters: 2},
+		IDAlternateTimeOffsetEnable:        &AlternateTimeOffsetEnableTLV{KeyField: 1, Flags: 1},
+		IDAlternateTimeOffsetMaxKey:        &AlternateTimeOffsetMaxKeyTLV{MaxKey: 4},
+		IDAlternateTimeOffsetProperties:    &AlternateTimeOffsetPropertiesTLV{KeyField: 1, CurrentOffset: -3600, JumpSeconds: 3600, TimeOfNextJump: PTPSeconds{0, 0, 0x65, 0x53, 0xf1, 0x00}},
+		IDExternalPortConfigurationEnabled: &ExternalPortConfigurationEnabledTLV{Flags: 1},
+		IDMasterOnly:                       &MasterOnlyTLV{Flags: 1},
+		IDHoldoverUpgradeEnable:            &HoldoverUpgradeEnableTLV{Flags: 1},
+		IDExtPortConfigPortDataSet:         &ExtPortConfigPortDataSetTLV{Flags: 1, DesiredState: PortStateMaster},
+		IDTransparentClockDefaultDataSet:   &TransparentClockDefaultDataSetTLV{ClockIdentity: 42, NumberPorts: 2, DelayMechanism: 2, PrimaryDomain: 0},
+		IDTransparentClockPortDataSet:      &TransparentClockPortDataSetTLV{PortIdentity: PortIdentity{ClockIdentity: 42, PortNumber: 2}, Flags: 1, PeerMeanPathDelay: NewTimeInterval(500)},
+		IDPrimaryDomain:                    &PrimaryDomainTLV{PrimaryDomain: 1},
+		IDDelayMechanism:                   &DelayMechanismTLV{DelayMechanism: 2},
+		IDLogMinPdelayReqInterval:          &LogMinPdelayReqIntervalTLV{LogMinPdelayReqInterval: 1},
+		IDUserDescription:                  &UserDescriptionTLV{UserDescription: "ptp;ptp4u"},
+		IDClockDescription: &ClockDescriptionTLV{
+			ClockType:             ClockTypeOrdinary,
+			PhysicalLayerProtocol: "IEEE 802.3",
+			PhysicalAddress:       []byte{0x48, 0x57, 0xdd, 0x0e, 0x91, 0xda},
+			ProtocolAddress:       PortAddress{NetworkProtocol: TransportTypeUDPIPV6, AddressLength: 16, AddressField: net.ParseIP("2001:db8::1")},
+			ManufacturerIdentity:  [3]uint8{1, 2, 3},
+			ProductDescription:    "fb;time;1",
+			RevisionData:          "1;2;3",
+			ProfileIdentity:       [6]uint8{0x00, 0x1b, 0x19, 0x00, 0x02, 0x00},
+		},
+		IDFaultLog: &FaultLogTLV{FaultRecords: []FaultRecord{
+			{SeverityCode: 3, FaultName: "sync", FaultValue: "lost", FaultDescription: "no sync received"},
+			{SeverityCode: 4, FaultName: "port"},
+		}},
+		IDPathTraceList:           &PathTraceListTLV{PathSequence: []ClockIdentity{1, 2, 3}},
+		IDGrandmasterClusterTable: &GrandmasterClusterTableTLV{LogQueryInterval: 2, PortAddresses: []PortAddress{{NetworkProtocol: TransportTypeUDPIPV4, AddressLength: 4, AddressField: net.ParseIP("192.168.0.1").To4()}}},
+		IDUnicastMasterTable: &UnicastMasterTableTLV{LogQueryInterval: 1, PortAddresses: []PortAddress{
+			{NetworkProtocol: TransportTypeUDPIPV6, AddressLength: 16, AddressField: net.ParseIP("2001:db8::1")},
+			{NetworkProtocol: TransportTypeUDPIPV4, AddressLength: 4, AddressField: net.ParseIP("192.168.0.1").To4()},
+		}},
+		IDAcceptableMasterTable:   &AcceptableMasterTableTLV{AcceptableMasters: []AcceptableMaster{{AcceptablePortIdentity: PortIdentity{ClockIdentity: 42, PortNumber: 1}, AlternatePriority1: 128}}},
+		IDAlternateTimeOffsetName: &AlternateTimeOffsetNameTLV{KeyField: 1, DisplayName: "CET"},
+
+		IDGrandmasterSettingsNP:      &GrandmasterSettingsNPTLV{ClockQuality: ClockQuality{ClockClass: ClockClass6, ClockAccuracy: ClockAccuracyNanosecond100, OffsetScaledLogVariance: 0xffff}, UTCOffset: 37, TimeFlags: 0x38, TimeSource: TimeSourceGNSS},
+		IDPortDataSetNP:              &PortDataSetNPTLV{NeighborPropDelayThresh: 800, AsCapable: 1},
+		IDSubscribeEventsNP:          &SubscribeEventsNPTLV{Duration: 180, Bitmask: [64]uint8{0x3}},
+		IDSynchronizationUncertainNP: &SynchronizationUncertainNPTLV{Val: 0xff},
+		IDPortHWClockNP:              &PortHWClockNPTLV{PortIdentity: PortIdentity{ClockIdentity: 42, PortNumber: 1}, PHCIndex: 2},
+		IDPowerProfileSettingsNP:     &PowerProfileSettingsNPTLV{Version: 2011, GrandmasterID: 255, GrandmasterTimeInaccuracy: 50, NetworkTimeInaccuracy: 200, TotalTimeInaccuracy: 1000},
+		IDCMLDSInfoNP:                &CMLDSInfoNPTLV{MeanLinkDelay: NewTimeInterval(100), ScaledNeighborRateRatio: 1, AsCapable: 1},
+	}
+	for id, tlv := range tlvs {
+		t.Run(fmt.Sprintf("0x%04x", uint16(id)), func(t *testing.T) {
+			got := mgmtRoundTrip(t, SET, id, tlv)
+			require.Equal(t, tlv, got.TLV)
+		})
+	}
+}
+
+func TestNewManagementGet(t *testing.T) {
+	req, err := NewManagement(GET, IDPriority1, &ManagementTLVHead{})
+	require.NoError(t, err)
+	require.Equal(t, uint16(54), req.MessageLength)
+	tlv := req.TLV.(*ManagementTLVHead)
+	require.Equal(t, TLVManagement, tlv.TLVType)
+	require.Equal(t, uint16(2), tlv.LengthField)
+	require.Equal(t, IDPriority1, tlv.MgmtID())
+
+	_, err = NewManagement(GET, IDPriority1, &fakeMgmtTLV{})
+	require.Error(t, err)
+}
+
+type fakeMgmtTLV struct{}
+
+func (f *fakeMgmtTLV) Type() TLVType        { return TLVManagement }
+func (f *fakeMgmtTLV) MgmtID() ManagementID { return IDPriority1 }
+
+func TestParsePriority1(t *testing.T) {
+	// PRIORITY1 response as sent by ptp4l
+	raw := []byte{
+		0x0d, 0x12, 0x00, 0x38, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x48, 0x57, 0xdd, 0xff, 0xfe, 0x0e, 0x91, 0xda, 0x00, 0x00, 0x00, 0x01,
+		0x04, 0x7f, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc4, 0xbf, 0x00, 0x00, 0x02, 0x00,
+		0x00, 0x01, 0x00, 0x04, 0x20, 0x05, 0x80, 0x00,
+	}
+	packet := new(Management)
+	require.NoError(t, FromBytes(raw, packet))
+	require.Equal(t, RESPONSE, packet.Action())
+	require.Equal(t, &Priority1TLV{
+		ManagementTLVHead: ManagementTLVHead{
+			TLVHead:      TLVHead{TLVType: TLVManagement, LengthField: 4},
+			ManagementID: IDPriority1,
+		},
+		Priority1: 128,
+	}, packet.TLV)
+}
+
+func TestClockDescriptionTLV(t *testing.T) {
+	// CLOCK_DESCRIPTION TLV as sent by ptp4l
+	raw := []byte{
+		0x00, 0x01, 0x00, 0x30, 0x00, 0x01, 0x80, 0x00, 0x0a, 0x49, 0x45, 0x45, 0x45, 0x20, 0x38, 0x30,
+		0x32, 0x2e, 0x33, 0x00, 0x06, 0x48, 0x57, 0xdd, 0x0e, 0x91, 0xda, 0x00, 0x01, 0x00, 0x04, 0xc0,
+		0xa8, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x02, 0x3b, 0x3b, 0x02, 0x3b, 0x3b, 0x00, 0x00, 0x1b,
+		0x19, 0x00, 0x01, 0x00,
+	}
+	want := &ClockDescriptionTLV{
+		ManagementTLVHead: ManagementTLVHead{
+			TLVHead:      TLVHead{TLVType: TLVManagement, LengthField: 48},
+			ManagementID: IDClockDescription,
+		},
+		ClockType:             ClockTypeOrdinary,
+		PhysicalLayerProtocol: "IEEE 802.3",
+		PhysicalAddress:       []byte{0x48, 0x57, 0xdd, 0x0e, 0x91, 0xda},
+		ProtocolAddress:       PortAddress{NetworkProtocol: TransportTypeUDPIPV4, AddressLength: 4, AddressField: []byte{192, 168, 0, 1}},
+		ProductDescription:    ";;",
+		RevisionData:          ";;",
+		ProfileIdentity:       [6]uint8{0x00, 0x1b, 0x19, 0x00, 0x01, 0x00},
+	}
+	tlv := &ClockDescriptionTLV{}
+	require.NoError(t, tlv.UnmarshalBinary(raw))
+	require.Equal(t, want, tlv)
+	require.Equal(t, "48:57:dd:0e:91:da", tlv.PhysicalAddressString())
+	require.Equal(t, "UDP_IPV4 192.168.0.1", tlv.ProtocolAddress.String())
+
+	b, err := tlv.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, raw, b)
+
+	for i := 0; i < len(raw); i++ {
+		require.Error(t, (&ClockDescriptionTLV{}).UnmarshalBinary(raw[:i]), "length %d", i)
+	}
+
+	tlv.ProductDescription = PTPText(make([]byte, 65))
+	_, err = tlv.MarshalBinary()
+	require.ErrorContains(t, err, "writing productDescription")
+	tlv.ProductDescription = ""
+	tlv.ProtocolAddress.AddressLength = 16
+	_, err = tlv.MarshalBinary()
+	require.Error(t, err)
+}
+
+func TestClockTypeString(t *testing.T) {
+	require.Equal(t, "ORDINARY", ClockTypeOrdinary.String())
+	require.Equal(t, "BOUNDARY|MANAGEMENT", (ClockTypeBoundary | ClockTypeManagement).String())
+	require.Equal(t, "0x0000", ClockType(0).String())
+	require.Equal(t, "ORDINARY|0x0001", ClockType(0x8001).String())
+}
+
+func TestPortAddressString(t *testing.T) {
+	require.Equal(t, "UDP_IPV6 2001:db8::1", PortAddress{NetworkProtocol: TransportTypeUDPIPV6, AddressLength: 16, AddressField: net.ParseIP("2001:db8::1")}.String())
+	require.Equal(t, "IEEE_802_3 48:57:dd:0e:91:da", PortAddress{NetworkProtocol: TransportTypeIEEE8023, AddressLength: 6, AddressField: []byte{0x48, 0x57, 0xdd, 0x0e, 0x91, 0xda}}.String())
+}
+
+func TestMgmtClientClockDescription(t *testing.T) {
+	resp, err := NewManagement(RESPONSE, IDClockDescription, &ClockDescriptionTLV{ClockType: ClockTypeBoundary, PhysicalLayerProtocol: "IEEE 802.3"})
+	require.NoError(t, err)
+	_, client := prepareTestClient(t, resp)
+	tlv, err := client.ClockDescription()
+	require.NoError(t, err)
+	require.Equal(t, ClockTypeBoundary, tlv.ClockType)
+	require.Equal(t, PTPText("IEEE 802.3"), tlv.PhysicalLayerProtocol)
+}
+
+func TestFaultLogTLVShort(t *testing.T) {
+	tlv := &FaultLogTLV{FaultRecords: []FaultRecord{{FaultName: "sync"}}}
+	req, err := NewManagement(RESPONSE, IDFaultLog, tlv)
+	require.NoError(t, err)
+	b, err := req.TLV.(*FaultLogTLV).MarshalBinary()
+	require.NoError(t, err)
+	for i := 0; i < len(b); i++ {
+		require.Error(t, (&FaultLogTLV{}).UnmarshalBinary(b[:i]), "length %d", i)
+	}
+}
+
+func TestSubscribeEventsNP(t *testing.T) {
+	tlv := &SubscribeEventsNPTLV{}
+	tlv.SetEvent(NotifyPortState)
+	tlv.SetEvent(NotifyCMLDS)
+	require.True(t, tlv.HasEvent(NotifyPortState))
+	require.False(t, tlv.HasEvent(NotifyTimeSync))
+	require.True(t, tlv.HasEvent(NotifyCMLDS))
+	require.Equal(t, uint8(0x9), tlv.Bitmask[0])
+}
+
+func TestMgmtClientGetSet(t *testing.T) {
+	resp, err := NewManagement(RESPONSE, IDPriority1, &Priority1TLV{Priority1: 100})
+	require.NoError(t, err)
+	conn, client := prepareTestClient(t, resp)
+	tlv, err := client.Set(IDPriority1, &Priority1TLV{Priority1: 100})
+	require.NoError(t, err)
+	require.Equal(t, uint8(100), tlv.(*Priority1TLV).Priority1)
+	sent := &Management{}
+	require.NoError(t, sent.UnmarshalBinary(conn.inputs[0]))
+	require.Equal(t, SET, sent.Action())
+	require.Equal(t, uint8(100), sent.TLV.(*Priority1TLV).Priority1)
+
+	_, client = prepareTestClient(t, resp)
+	_, err = client.Get(IDPriority2)
+	require.ErrorContains(t, err, "got response for management ID 0x2005, wanted 0x2006")
+
+	ack, err := NewManagement(ACKNOWLEDGE, IDEnablePort, &ManagementTLVHead{})
+	require.NoError(t, err)
+	_, client = prepareTestClient(t, ack)
+	require.NoError(t, client.Command(IDEnablePort))
+}
@@ -24,6 +24,21 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func TestManagementError(t *testing.T) {
+	p := &ManagementMsgErrorStatus{
+		ManagementErrorStatusTLV: ManagementErrorStatusTLV{
+			ManagementErrorID: ErrorNotSetable,
+			ManagementID:      IDPriority1,
+			DisplayData:       "read only",
+		},
+	}
+	err := p.ManagementError()
+	require.EqualError(t, err, "got Management Error in response: NOT_SETABLE")
+	require.ErrorIs(t, err, ErrorNotSetable)
+	require.NotErrorIs(t, err, ErrorNotSupported)
+	require.Equal(t, &ManagementError{ErrorID: ErrorNotSetable, ManagementID: IDPriority1, DisplayData: "read only"}, err)
+}
+
 func TestManagementErrorIDString(t *testing.T) {
 	require.Equal(t, "RESPONSE_TOO_BIG", ErrorResponseTooBig.String())
 	require.Equal(t, "NO_SUCH_ID", ErrorNoSuchID.String())
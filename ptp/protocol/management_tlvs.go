@@ -27,7 +27,7 @@ import (
 // ManagementID is type for Management IDs
 type ManagementID uint16
 
-// Management IDs we support, from Table 59 managementId values
+// Management IDs, from Table 59 managementId values
 const (
 	IDNullPTPManagement        ManagementID = 0x0000
 	IDClockDescription         ManagementID = 0x0001
@@ -38,13 +38,52 @@ const (
 	IDFaultLog                 ManagementID = 0x0006
 	IDFaultLogReset            ManagementID = 0x0007
 
-	IDDefaultDataSet        ManagementID = 0x2000
-	IDCurrentDataSet        ManagementID = 0x2001
-	IDParentDataSet         ManagementID = 0x2002
-	IDTimePropertiesDataSet ManagementID = 0x2003
-	IDPortDataSet           ManagementID = 0x2004
-	IDClockAccuracy         ManagementID = 0x2010
-	// rest of Management IDs that we don't implement yet
+	IDDefaultDataSet                ManagementID = 0x2000
+	IDCurrentDataSet                ManagementID = 0x2001
+	IDParentDataSet                 ManagementID = 0x2002
+	IDTimePropertiesDataSet         ManagementID = 0x2003
+	IDPortDataSet                   ManagementID = 0x2004
+	IDPriority1                     ManagementID = 0x2005
+	IDPriority2                     ManagementID = 0x2006
+	IDDomain                        ManagementID = 0x2007
+	IDSlaveOnly                     ManagementID = 0x2008
+	IDLogAnnounceInterval           ManagementID = 0x2009
+	IDAnnounceReceiptTimeout        ManagementID = 0x200A
+	IDLogSyncInterval               ManagementID = 0x200B
+	IDVersionNumber                 ManagementID = 0x200C
+	IDEnablePort                    ManagementID = 0x200D
+	IDDisablePort                   ManagementID = 0x200E
+	IDTime                          ManagementID = 0x200F
+	IDClockAccuracy                 ManagementID = 0x2010
+	IDUTCProperties                 ManagementID = 0x2011
+	IDTraceabilityProperties        ManagementID = 0x2012
+	IDTimescaleProperties           ManagementID = 0x2013
+	IDUnicastNegotiationEnable      ManagementID = 0x2014
+	IDPathTraceList                 ManagementID = 0x2015
+	IDPathTraceEnable               ManagementID = 0x2016
+	IDGrandmasterClusterTable       ManagementID = 0x2017
+	IDUnicastMasterTable            ManagementID = 0x2018
+	IDUnicastMasterMaxTableSize     ManagementID = 0x2019
+	IDAcceptableMasterTable         ManagementID = 0x201A
+	IDAcceptableMasterTableEnabled  ManagementID = 0x201B
+	IDAcceptableMasterMaxTableSize  ManagementID = 0x201C
+	IDAlternateMaster               ManagementID = 0x201D
+	IDAlternateTimeOffsetEnable     ManagementID = 0x201E
+	IDAlternateTimeOffsetName       ManagementID = 0x201F
+	IDAlternateTimeOffsetMaxKey     ManagementID = 0x2020
+	IDAlternateTimeOffsetProperties ManagementID = 0x2021
+
+	IDExternalPortConfigurationEnabled ManagementID = 0x3000
+	IDMasterOnly                       ManagementID = 0x3001
+	IDHoldoverUpgradeEnable            ManagementID = 0x3002
+	IDExtPortConfigPortDataSet         ManagementID = 0x3003
+
+	IDTransparentClockDefaultDataSet ManagementID = 0x4000
+	IDTransparentClockPortDataSet    ManagementID = 0x4001
+	IDPrimaryDomain                  ManagementID = 0x4002
+
+	IDDelayMechanism          ManagementID = 0x6000
+	IDLogMinPdelayReqInterval ManagementID = 0x6001
 )
 
 // ManagementTLV abstracts away any ManagementTLV
@@ -183,6 +222,19 @@ var mgmtTLVDecoder = map[ManagementID]MgmtTLVDecoderFunc{
 	},
 }
 
+// constructors of management TLVs we can decode from JSON
+var mgmtTLVNew = map[ManagementID]func() ManagementTLV{
+	IDDefaultDataSet:       func() ManagementTLV { return &DefaultDataSetTLV{} },
+	IDCurrentDataSet:       func() ManagementTLV { return &CurrentDataSetTLV{} },
+	IDParentDataSet:        func() ManagementTLV { return &ParentDataSetTLV{} },
+	IDPortStatsNP:          func() ManagementTLV { return &PortStatsNPTLV{} },
+	IDTimeStatusNP:         func() ManagementTLV { return &TimeStatusNPTLV{} },
+	IDPortServiceStatsNP:   func() ManagementTLV { return &PortServiceStatsNPTLV{} },
+	IDPortPropertiesNP:     func() ManagementTLV { return &PortPropertiesNPTLV{} },
+	IDUnicastMasterTableNP: func() ManagementTLV { return &UnicastMasterTableNPTLV{} },
+	IDClockAccuracy:        func() ManagementTLV { return &ClockAccuracyTLV{} },
+}
+
 // RegisterMgmtTLVDecoder registers function we'll use to decode particular custom management TLV.
 // IEEE1588-2019 specifies that range C000 – DFFF should be used for implementation-specific identifiers,
 // and E000 – FFFE is to be assigned by alternate PTP Profile.
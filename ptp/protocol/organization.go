@@ -0,0 +1,144 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"encoding"
+	"fmt"
+	"sync"
+)
+
+// organizationHeadSize is the size of organizationId and organizationSubType fields
+const organizationHeadSize = 6
+
+// Organization identifies ORGANIZATION_EXTENSION TLV by its organizationId and organizationSubType
+type Organization struct {
+	ID      [3]uint8
+	SubType [3]uint8
+}
+
+func (o Organization) String() string {
+	return fmt.Sprintf("%x/%x", o.ID, o.SubType)
+}
+
+// OrganizationTLV is a TLV codec which can be registered with RegisterOrganizationTLV
+type OrganizationTLV interface {
+	TLV
+	BinaryMarshalerTo
+	encoding.BinaryUnmarshaler
+}
+
+// OrganizationExtensionTLV is a Table 51 ORGANIZATION_EXTENSION TLV.
+// It's used for organizations with no registered codec.
+type OrganizationExtensionTLV struct {
+	TLVHead
+	OrganizationID      [3]uint8
+	OrganizationSubType [3]uint8
+	DataField           []byte
+}
+
+// Organization returns organization of the TLV
+func (t *OrganizationExtensionTLV) Organization() Organization {
+	return Organization{ID: t.OrganizationID, SubType: t.OrganizationSubType}
+}
+
+// MarshalBinaryTo marshals bytes to OrganizationExtensionTLV
+func (t *OrganizationExtensionTLV) MarshalBinaryTo(b []byte) (int, error) {
+	size := tlvHeadSize + organizationHeadSize + len(t.DataField)
+	if len(b) < size {
+		return 0, fmt.Errorf("not enough buffer to write OrganizationExtensionTLV")
+	}
+	tlvHeadMarshalBinaryTo(&t.TLVHead, b)
+	copy(b[4:], t.OrganizationID[:])
+	copy(b[7:], t.OrganizationSubType[:])
+	copy(b[10:], t.DataField)
+	return size, nil
+}
+
+// UnmarshalBinary parses []byte and populates struct fields
+func (t *OrganizationExtensionTLV) UnmarshalBinary(b []byte) error {
+	if err := unmarshalTLVHeader(&t.TLVHead, b); err != nil {
+		return err
+	}
+	if err := checkTLVLength(&t.TLVHead, len(b), organizationHeadSize, false); err != nil {
+		return err
+	}
+	copy(t.OrganizationID[:], b[4:])
+	copy(t.OrganizationSubType[:], b[7:])
+	// copy, as b is usually a reused read buffer
+	t.DataField = append(t.DataField[:0], b[tlvHeadSize+organizationHeadSize:tlvHeadSize+int(t.LengthField)]...)
+	return nil
+}
+
+// organizationTLVs holds registered ORGANIZATION_EXTENSION TLV constructors
+var organizationTLVs = struct {
+	sync.RWMutex
+	m map[Organization]func() OrganizationTLV
+}{m: map[Organization]func() OrganizationTLV{}}
+
+// RegisterOrganizationTLV registers constructor of ORGANIZATION_EXTENSION TLV codec,
+// so TLVs of the organization are decoded into it instead of OrganizationExtensionTLV.
+// It's safe to call concurrently with decoding.
+func RegisterOrganizationTLV(org Organization, newTLV func() OrganizationTLV) error {
+	organizationTLVs.Lock()
+	defer organizationTLVs.Unlock()
+	if _, ok := organizationTLVs.m[org]; ok {
+		return fmt.Errorf("TLV of organization %s is already registered", org)
+	}
+	organizationTLVs.m[org] = newTLV
+	return nil
+}
+
+// UnregisterOrganizationTLV removes codec registered with RegisterOrganizationTLV
+func UnregisterOrganizationTLV(org Organization) {
+	organizationTLVs.Lock()
+	defer organizationTLVs.Unlock()
+	delete(organizationTLVs.m, org)
+}
+
+// newOrganizationTLV returns registered TLV for the organization, falling back to OrganizationExtensionTLV
+func newOrganizationTLV(org Organization) OrganizationTLV {
+	organizationTLVs.RLock()
+	newTLV, ok := organizationTLVs.m[org]
+	organizationTLVs.RUnlock()
+	if ok {
+		return newTLV()
+	}
+	return &OrganizationExtensionTLV{}
+}
+
+// unmarshalOrganizationTLV decodes ORGANIZATION_EXTENSION TLV using registered codec
+func unmarshalOrganizationTLV(b []byte) (OrganizationTLV, error) {
+	if len(b) < tlvHeadSize+organizationHeadSize {
+		return nil, fmt.Errorf("not enough data to decode OrganizationExtensionTLV")
+	}
+	org := Organization{}
+	copy(org.ID[:], b[4:])
+	copy(org.SubType[:], b[7:])
+	tlv := newOrganizationTLV(org)
+	if err := tlv.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return tlv, nil
+}
+
+func init() {
+	_ = RegisterOrganizationTLV(
+		Organization{ID: OrgIDIEEE8021, SubType: OrgSubTypeFollowUpInformation},
+		func() OrganizationTLV { return &FollowUpInformationTLV{} },
+	)
+}
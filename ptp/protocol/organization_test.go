@@ -0,0 +1,161 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testOrganization = Organization{ID: [3]uint8{0x4c, 0x0b, 0xbe}, SubType: [3]uint8{0, 0, 1}}
+
+// testErrorBoundTLV is an example of organization specific TLV
+type testErrorBoundTLV struct {
+	TLVHead
+	OrganizationID      [3]uint8
+	OrganizationSubType [3]uint8
+	ErrorBound          uint32
+}
+
+func (t *testErrorBoundTLV) MarshalBinaryTo(b []byte) (int, error) {
+	if len(b) < tlvHeadSize+10 {
+		return 0, fmt.Errorf("not enough buffer to write testErrorBoundTLV")
+	}
+	tlvHeadMarshalBinaryTo(&t.TLVHead, b)
+	copy(b[4:], t.OrganizationID[:])
+	copy(b[7:], t.OrganizationSubType[:])
+	binary.BigEndian.PutUint32(b[10:], t.ErrorBound)
+	return tlvHeadSize + 10, nil
+}
+
+func (t *testErrorBoundTLV) UnmarshalBinary(b []byte) error {
+	if err := unmarshalTLVHeader(&t.TLVHead, b); err != nil {
+		return err
+	}
+	if err := checkTLVLength(&t.TLVHead, len(b), 10, true); err != nil {
+		return err
+	}
+	copy(t.OrganizationID[:], b[4:])
+	copy(t.OrganizationSubType[:], b[7:])
+	t.ErrorBound = binary.BigEndian.Uint32(b[10:])
+	return nil
+}
+
+func testOrganizationSignaling(tlv TLV) *Signaling {
+	return &Signaling{
+		Header: Header{
+			SdoIDAndMsgType:    NewSdoIDAndMsgType(MessageSignaling, 0),
+			Version:            Version,
+			MessageLength:      44 + 14,
+			SourcePortIdentity: PortIdentity{ClockIdentity: 1, PortNumber: 1},
+			LogMessageInterval: 0x7f,
+		},
+		TargetPortIdentity: PortIdentity{ClockIdentity: 0xffffffffffffffff, PortNumber: 0xffff},
+		TLVs:               []TLV{tlv},
+	}
+}
+
+func TestOrganizationExtensionTLV(t *testing.T) {
+	p := testOrganizationSignaling(&OrganizationExtensionTLV{
+		TLVHead:             TLVHead{TLVType: TLVOrganizationExtension, LengthField: 10},
+		OrganizationID:      testOrganization.ID,
+		OrganizationSubType: testOrganization.SubType,
+		DataField:           []byte{0, 0, 0, 42},
+	})
+	b, err := Bytes(p)
+	require.NoError(t, err)
+
+	got, err := ParsePacket(b)
+	require.NoError(t, err)
+	require.Equal(t, p, got)
+	require.Equal(t, testOrganization, got.(*Signaling).TLVs[0].(*OrganizationExtensionTLV).Organization())
+	require.Equal(t, "4c0bbe/000001", testOrganization.String())
+
+	// data is not aliased to the read buffer
+	b[57] = 0
+	require.Equal(t, []byte{0, 0, 0, 42}, got.(*Signaling).TLVs[0].(*OrganizationExtensionTLV).DataField)
+}
+
+func TestRegisterOrganizationTLV(t *testing.T) {
+	require.NoError(t, RegisterOrganizationTLV(testOrganization, func() OrganizationTLV { return &testErrorBoundTLV{} }))
+	defer UnregisterOrganizationTLV(testOrganization)
+	require.Error(t, RegisterOrganizationTLV(testOrganization, func() OrganizationTLV { return &testErrorBoundTLV{} }))
+
+	tlv := &testErrorBoundTLV{
+		TLVHead:             TLVHead{TLVType: TLVOrganizationExtension, LengthField: 10},
+		OrganizationID:      testOrganization.ID,
+		OrganizationSubType: testOrganization.SubType,
+		ErrorBound:          42,
+	}
+	p := testOrganizationSignaling(tlv)
+	b, err := Bytes(p)
+	require.NoError(t, err)
+
+	got := &Signaling{}
+	require.NoError(t, FromBytes(b, got))
+	require.Equal(t, p, got)
+
+	// JSON picks registered codec as well
+	j, err := json.Marshal(p)
+	require.NoError(t, err)
+	parsed, err := DecodePacketJSON(j)
+	require.NoError(t, err)
+	require.Equal(t, p, parsed)
+
+	// codec errors are propagated
+	b[47] = 8
+	require.Error(t, FromBytes(b, got))
+}
+
+func TestUnregisterOrganizationTLV(t *testing.T) {
+	require.NoError(t, RegisterOrganizationTLV(testOrganization, func() OrganizationTLV { return &testErrorBoundTLV{} }))
+	UnregisterOrganizationTLV(testOrganization)
+
+	b, err := Bytes(testOrganizationSignaling(&testErrorBoundTLV{
+		TLVHead:             TLVHead{TLVType: TLVOrganizationExtension, LengthField: 10},
+		OrganizationID:      testOrganization.ID,
+		OrganizationSubType: testOrganization.SubType,
+	}))
+	require.NoError(t, err)
+	got := &Signaling{}
+	require.NoError(t, FromBytes(b, got))
+	require.IsType(t, &OrganizationExtensionTLV{}, got.TLVs[0])
+}
+
+func TestFollowUpInformationTLVRegistered(t *testing.T) {
+	fi := NewFollowUpInformationTLV()
+	fi.SetRateRatio(1.5)
+	b := make([]byte, 64)
+	n, err := fi.MarshalBinaryTo(b)
+	require.NoError(t, err)
+	tlvs, err := readTLVs(nil, n, b)
+	require.NoError(t, err)
+	require.Equal(t, []TLV{&fi}, tlvs)
+}
+
+func TestOrganizationExtensionTLVErrors(t *testing.T) {
+	tlv := &OrganizationExtensionTLV{}
+	require.Error(t, tlv.UnmarshalBinary([]byte{0, 3, 0, 4, 0, 0, 0, 0}))
+	_, err := tlv.MarshalBinaryTo(make([]byte, 9))
+	require.Error(t, err)
+	_, err = unmarshalOrganizationTLV([]byte{0, 3, 0, 6, 0, 0, 0})
+	require.Error(t, err)
+}
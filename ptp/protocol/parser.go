@@ -0,0 +1,201 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Errors returned by ParsePacket, wrapped into ParseError
+var (
+	ErrShortPacket            = errors.New("packet is too short")
+	ErrUnsupportedVersion     = errors.New("unsupported PTP version")
+	ErrUnsupportedMessageType = errors.New("unsupported message type")
+	ErrBadMessageLength       = errors.New("messageLength doesn't match packet size")
+	ErrShortTLV               = errors.New("TLV is too short")
+	ErrBadTLVLength           = errors.New("TLV lengthField exceeds the message")
+	ErrOddTLVLength           = errors.New("TLV lengthField is odd")
+	ErrMalformed              = errors.New("malformed packet")
+)
+
+// ParseError describes why and where ParsePacket failed
+type ParseError struct {
+	// Offset of the failing field from the start of the packet
+	Offset int
+	Err    error
+}
+
+// Error implements error interface
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parsing PTP packet at offset %d: %v", e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error, one of Err* variables of this package
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func parseError(offset int, err error, format string, args ...any) error {
+	if format != "" {
+		err = fmt.Errorf("%w: "+format, append([]any{err}, args...)...)
+	}
+	return &ParseError{Offset: offset, Err: err}
+}
+
+// TLVScanner iterates over TLVs in a byte slice without allocating and with every access bounds-checked.
+// Scanning stops at the first malformed TLV, or when less than a TLV header is left.
+type TLVScanner struct {
+	b      []byte
+	pos    int
+	base   int
+	offset int
+	typ    TLVType
+	value  []byte
+	err    error
+}
+
+// NewTLVScanner returns TLVScanner over b. Base is added to offsets reported in errors.
+func NewTLVScanner(b []byte, base int) *TLVScanner {
+	return &TLVScanner{b: b, base: base}
+}
+
+// Next advances to the next TLV, returning false when there are none left or on error
+func (s *TLVScanner) Next() bool {
+	if s.err != nil || len(s.b)-s.pos < tlvHeadSize {
+		return false
+	}
+	s.offset = s.pos
+	s.typ = TLVType(binary.BigEndian.Uint16(s.b[s.pos:]))
+	length := int(binary.BigEndian.Uint16(s.b[s.pos+2:]))
+	if length%2 != 0 {
+		s.err = parseError(s.base+s.pos+2, ErrOddTLVLength, "TLV %s (%d) has length %d", s.typ, s.typ, length)
+		return false
+	}
+	end := s.pos + tlvHeadSize + length
+	if end > len(s.b) {
+		s.err = parseError(s.base+s.pos+2, ErrBadTLVLength, "TLV %s (%d) of length %d with %d bytes left", s.typ, s.typ, length, len(s.b)-s.pos-tlvHeadSize)
+		return false
+	}
+	s.value = s.b[s.pos+tlvHeadSize : end]
+	s.pos = end
+	return true
+}
+
+// Type returns type of the current TLV
+func (s *TLVScanner) Type() TLVType {
+	return s.typ
+}
+
+// Value returns the current TLV value, not including the TLV header. It's valid until the underlying slice is modified.
+func (s *TLVScanner) Value() []byte {
+	return s.value
+}
+
+// Bytes returns the current TLV including the TLV header
+func (s *TLVScanner) Bytes() []byte {
+	return s.b[s.offset:s.pos]
+}
+
+// Offset returns offset of the current TLV
+func (s *TLVScanner) Offset() int {
+	return s.base + s.offset
+}
+
+// Err returns the error which stopped scanning, if any
+func (s *TLVScanner) Err() error {
+	return s.err
+}
+
+// ParseHeader validates and decodes common PTP header of the packet.
+// It returns the header and the part of b covered by messageLength.
+func ParseHeader(b []byte) (*Header, []byte, error) {
+	if len(b) < headerSize {
+		return nil, nil, parseError(len(b), ErrShortPacket, "need %d bytes for header, got %d", headerSize, len(b))
+	}
+	h := &Header{}
+	unmarshalHeader(h, b)
+	if h.Version&MajorVersionMask != MajorVersion {
+		return nil, nil, parseError(1, ErrUnsupportedVersion, "%d", h.Version&MajorVersionMask)
+	}
+	minLen, err := tlvOffset(h.MessageType())
+	if err != nil {
+		return nil, nil, parseError(0, ErrUnsupportedMessageType, "%s", h.MessageType())
+	}
+	msgLen := int(h.MessageLength)
+	if msgLen > len(b) {
+		return nil, nil, parseError(2, ErrBadMessageLength, "messageLength %d with %d bytes received", msgLen, len(b))
+	}
+	if msgLen < minLen {
+		return nil, nil, parseError(2, ErrBadMessageLength, "messageLength %d is less than %d required for %s", msgLen, minLen, h.MessageType())
+	}
+	return h, b[:msgLen], nil
+}
+
+// ParsePacket is a hardened DecodePacket meant for untrusted input.
+// It validates header and every TLV boundary before decoding and returns *ParseError wrapping one of Err* variables of this package.
+// TLVs of types we can't decode, vendor-specific ones included, are skipped using their lengthField.
+func ParsePacket(b []byte) (Packet, error) {
+	if IsV1(b) {
+		v1, err := DecodeV1Packet(b)
+		if err != nil {
+			return nil, parseError(0, ErrMalformed, "%v", err)
+		}
+		return v1, nil
+	}
+	h, msg, err := ParseHeader(b)
+	if err != nil {
+		return nil, err
+	}
+	if h.MessageType() != MessageManagement {
+		if msg, err = skipUnknownTLVs(msg, h.MessageType()); err != nil {
+			return nil, err
+		}
+	}
+	p, err := DecodePacket(msg)
+	if err != nil {
+		return nil, parseError(0, ErrMalformed, "%v", err)
+	}
+	return p, nil
+}
+
+// skipUnknownTLVs validates TLVs of the message and returns it without TLVs we can't decode.
+// The message is returned as is if there is nothing to skip, otherwise the copy with adjusted messageLength is returned.
+func skipUnknownTLVs(msg []byte, msgType MessageType) ([]byte, error) {
+	start, _ := tlvOffset(msgType)
+	var filtered []byte
+	s := NewTLVScanner(msg[start:], start)
+	for s.Next() {
+		_, known := tlvNew[s.Type()]
+		if known && filtered != nil {
+			filtered = append(filtered, s.Bytes()...)
+		}
+		if !known && filtered == nil {
+			filtered = make([]byte, s.Offset(), len(msg))
+			copy(filtered, msg)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if filtered == nil {
+		return msg, nil
+	}
+	binary.BigEndian.PutUint16(filtered[2:], uint16(len(filtered))) //#nosec G115
+	return filtered, nil
+}
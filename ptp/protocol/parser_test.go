@@ -0,0 +1,209 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Announce with PATH_TRACE TLV of 3 entries
+var announcePathTraceRaw = []byte("\x0b\x12\x00\x5c\x00\x00\x04\x08\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x08\xc0\xeb\xff\xfe\x63\x7a\x4e\x00\x01\x00\x00\x05\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x25\x00\x80\xf8\xfe\xff\xff\x80\x08\xc0\xeb\xff\xfe\x63\x7a\x4e\x00\x00\xa0\x00\x08\x00\x18\x08\xc0\xeb\xff\xfe\x63\x7a\x4e\x01\xb6\xaf\xc4\xe5\x46\x12\x29\x04\xc0\x87\x32\xf0\x61\xee\xce\x00\x00")
+
+func TestParsePacket(t *testing.T) {
+	p, err := ParsePacket(announcePathTraceRaw)
+	require.NoError(t, err)
+	want, err := DecodePacket(announcePathTraceRaw)
+	require.NoError(t, err)
+	require.Equal(t, want, p)
+}
+
+func TestParsePacketSkipsUnknownTLVs(t *testing.T) {
+	want, err := DecodePacket(announcePathTraceRaw)
+	require.NoError(t, err)
+
+	// experimental TLV in front of PATH_TRACE
+	b := append([]byte{}, announcePathTraceRaw[:64]...)
+	b = append(b, 0x20, 0x04, 0x00, 0x04, 0xde, 0xad, 0xbe, 0xef)
+	b = append(b, announcePathTraceRaw[64:]...)
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+	p, err := ParsePacket(b)
+	require.NoError(t, err)
+	require.Equal(t, want, p)
+	// input is not modified
+	require.Equal(t, uint16(len(b)), binary.BigEndian.Uint16(b[2:]))
+}
+
+func TestParsePacketVendorTLV(t *testing.T) {
+	sync := &SyncDelayReq{
+		Header: Header{
+			SdoIDAndMsgType: NewSdoIDAndMsgType(MessageSync, 0),
+			Version:         Version,
+			SequenceID:      42,
+		},
+	}
+	b, err := sync.MarshalBinary()
+	require.NoError(t, err)
+	// vendor-specific TLV type, followed by ORGANIZATION_EXTENSION of unregistered organization
+	b = append(b, 0x7f, 0x00, 0x00, 0x02, 0x01, 0x02)
+	b = append(b, 0x00, 0x03, 0x00, 0x08, 0x00, 0x0f, 0x53, 0x00, 0x00, 0x01, 0xca, 0xfe)
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+
+	p, err := ParsePacket(b)
+	require.NoError(t, err)
+	got, ok := p.(*SyncDelayReq)
+	require.True(t, ok)
+	require.Equal(t, uint16(42), got.SequenceID)
+	require.Equal(t, uint16(headerSize+10+12), got.MessageLength)
+	require.Len(t, got.TLVs, 1)
+	org, ok := got.TLVs[0].(*OrganizationExtensionTLV)
+	require.True(t, ok)
+	require.Equal(t, TLVOrganizationExtension, org.Type())
+}
+
+func TestParsePacketErrors(t *testing.T) {
+	mutate := func(f func(b []byte) []byte) []byte {
+		b := append([]byte{}, announcePathTraceRaw...)
+		return f(b)
+	}
+	tests := []struct {
+		name   string
+		in     []byte
+		err    error
+		offset int
+	}{
+		{
+			name:   "empty",
+			in:     nil,
+			err:    ErrShortPacket,
+			offset: 0,
+		},
+		{
+			name:   "short header",
+			in:     announcePathTraceRaw[:20],
+			err:    ErrShortPacket,
+			offset: 20,
+		},
+		{
+			name:   "version 3",
+			in:     mutate(func(b []byte) []byte { b[1] = 0x03; return b }),
+			err:    ErrUnsupportedVersion,
+			offset: 1,
+		},
+		{
+			name:   "unknown message type",
+			in:     mutate(func(b []byte) []byte { b[0] = 0x07; return b }),
+			err:    ErrUnsupportedMessageType,
+			offset: 0,
+		},
+		{
+			name:   "truncated",
+			in:     announcePathTraceRaw[:70],
+			err:    ErrBadMessageLength,
+			offset: 2,
+		},
+		{
+			name:   "messageLength shorter than body",
+			in:     mutate(func(b []byte) []byte { b[3] = 40; return b }),
+			err:    ErrBadMessageLength,
+			offset: 2,
+		},
+		{
+			name:   "TLV past messageLength",
+			in:     mutate(func(b []byte) []byte { b[67] = 0x1a; return b }),
+			err:    ErrBadTLVLength,
+			offset: 66,
+		},
+		{
+			name:   "odd TLV length",
+			in:     mutate(func(b []byte) []byte { b[67] = 0x17; return b }),
+			err:    ErrOddTLVLength,
+			offset: 66,
+		},
+		{
+			name:   "TLV decoder error",
+			in:     mutate(func(b []byte) []byte { b[65] = byte(TLVGrantUnicastTransmission); return b }),
+			err:    ErrMalformed,
+			offset: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePacket(tt.in)
+			require.Nil(t, p)
+			require.ErrorIs(t, err, tt.err)
+			var perr *ParseError
+			require.True(t, errors.As(err, &perr))
+			require.Equal(t, tt.offset, perr.Offset)
+		})
+	}
+}
+
+func TestTLVScanner(t *testing.T) {
+	b := []byte{0x00, 0x08, 0x00, 0x08, 1, 2, 3, 4, 5, 6, 7, 8, 0x80, 0x09, 0x00, 0x00, 0xff}
+	s := NewTLVScanner(b, 64)
+	require.True(t, s.Next())
+	require.Equal(t, TLVPathTrace, s.Type())
+	require.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8}, s.Value())
+	require.Equal(t, b[:12], s.Bytes())
+	require.Equal(t, 64, s.Offset())
+	require.True(t, s.Next())
+	require.Equal(t, TLVAuthentication, s.Type())
+	require.Empty(t, s.Value())
+	require.Equal(t, 76, s.Offset())
+	// trailing byte is not enough for another TLV
+	require.False(t, s.Next())
+	require.NoError(t, s.Err())
+
+	s = NewTLVScanner([]byte{0x00, 0x08, 0x00, 0x10, 1, 2}, 0)
+	require.False(t, s.Next())
+	require.ErrorIs(t, s.Err(), ErrBadTLVLength)
+	require.False(t, s.Next())
+}
+
+func FuzzParsePacket(f *testing.F) {
+	f.Add(announcePathTraceRaw)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		p, err := ParsePacket(b)
+		if err != nil {
+			require.Nil(t, p)
+			var perr *ParseError
+			require.True(t, errors.As(err, &perr), "unexpected error type %T: %v", err, err)
+			require.GreaterOrEqual(t, perr.Offset, 0)
+			require.LessOrEqual(t, perr.Offset, len(b))
+			return
+		}
+		require.NotNil(t, p)
+	})
+}
+
+func FuzzTLVScanner(f *testing.F) {
+	f.Add(announcePathTraceRaw[64:])
+	f.Fuzz(func(t *testing.T, b []byte) {
+		s := NewTLVScanner(b, 0)
+		end := 0
+		for s.Next() {
+			require.Equal(t, end, s.Offset())
+			require.Len(t, s.Bytes(), tlvHeadSize+len(s.Value()))
+			end = s.Offset() + len(s.Bytes())
+			require.LessOrEqual(t, end, len(b))
+		}
+	})
+}
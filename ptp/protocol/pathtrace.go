@@ -0,0 +1,107 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"slices"
+)
+
+// announceBodySize is the size of Announce message body without TLVs
+const announceBodySize = 30
+
+// MaxPathTraceLength is the maximum number of ClockIdentity entries in PATH_TRACE TLV we generate,
+// so Announce carrying it still fits into the buffer used by Announce.MarshalBinary
+const MaxPathTraceLength = (maxTLVPacketSize - headerSize - announceBodySize - tlvHeadSize) / 8
+
+// NewPathTraceTLV returns PATH_TRACE TLV with given path sequence
+func NewPathTraceTLV(path []ClockIdentity) *PathTraceTLV {
+	return &PathTraceTLV{
+		TLVHead: TLVHead{
+			TLVType:     TLVPathTrace,
+			LengthField: uint16(8 * len(path)),
+		},
+		PathSequence: path,
+	}
+}
+
+// Contains returns true if ClockIdentity is present in the path sequence
+func (t *PathTraceTLV) Contains(identity ClockIdentity) bool {
+	return slices.Contains(t.PathSequence, identity)
+}
+
+// AppendPathTrace returns the path list to be sent by the clock with given identity
+// in its own Announce messages, as described in 16.2.3.
+// Second return value is false if the resulting path is too long and PATH_TRACE TLV must not be sent.
+func AppendPathTrace(path []ClockIdentity, self ClockIdentity) ([]ClockIdentity, bool) {
+	if len(path)+1 > MaxPathTraceLength {
+		return nil, false
+	}
+	res := make([]ClockIdentity, 0, len(path)+1)
+	res = append(res, path...)
+	return append(res, self), true
+}
+
+// PathTraceTLV returns PATH_TRACE TLV attached to Announce, or nil if there is none
+func (p *Announce) PathTraceTLV() *PathTraceTLV {
+	for _, tlv := range p.TLVs {
+		if pt, ok := tlv.(*PathTraceTLV); ok {
+			return pt
+		}
+	}
+	return nil
+}
+
+// PathTrace returns the path sequence from Announce PATH_TRACE TLV, or nil if there is none
+func (p *Announce) PathTrace() []ClockIdentity {
+	pt := p.PathTraceTLV()
+	if pt == nil {
+		return nil
+	}
+	return pt.PathSequence
+}
+
+// PathTraceLoop returns true if Announce went through the clock with given identity already.
+// Such Announce messages must be discarded, as described in 16.2.3.
+func (p *Announce) PathTraceLoop(self ClockIdentity) bool {
+	pt := p.PathTraceTLV()
+	if pt == nil {
+		return false
+	}
+	return pt.Contains(self)
+}
+
+// SetPathTrace replaces Announce PATH_TRACE TLV with the one carrying given path,
+// adding it if it's missing and updating MessageLength.
+// Empty path removes PATH_TRACE TLV.
+func (p *Announce) SetPathTrace(path []ClockIdentity) {
+	length := int(p.MessageLength)
+	tlvs := make([]TLV, 0, len(p.TLVs)+1)
+	for _, tlv := range p.TLVs {
+		if pt, ok := tlv.(*PathTraceTLV); ok {
+			length -= tlvHeadSize + int(pt.LengthField)
+			continue
+		}
+		tlvs = append(tlvs, tlv)
+	}
+	if len(path) > 0 {
+		pt := NewPathTraceTLV(path)
+		tlvs = append(tlvs, pt)
+		length += tlvHeadSize + int(pt.LengthField)
+	}
+	p.TLVs = tlvs
+	p.MessageLength = uint16(length)
+}
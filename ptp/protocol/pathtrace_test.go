@@ -0,0 +1,97 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathTraceTLVUnmarshalNoTrailingBytes(t *testing.T) {
+	raw := []byte{0x00, 0x08, 0x00, 0x10, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2}
+	tlv := &PathTraceTLV{}
+	require.NoError(t, tlv.UnmarshalBinary(raw))
+	require.Equal(t, NewPathTraceTLV([]ClockIdentity{1, 2}), tlv)
+
+	b := make([]byte, len(raw))
+	n, err := tlv.MarshalBinaryTo(b)
+	require.NoError(t, err)
+	require.Equal(t, raw, b[:n])
+
+	_, err = tlv.MarshalBinaryTo(b[:len(raw)-1])
+	require.Error(t, err)
+}
+
+func TestAppendPathTrace(t *testing.T) {
+	path := []ClockIdentity{1, 2}
+	got, ok := AppendPathTrace(path, 3)
+	require.True(t, ok)
+	require.Equal(t, []ClockIdentity{1, 2, 3}, got)
+	require.Equal(t, []ClockIdentity{1, 2}, path)
+
+	got, ok = AppendPathTrace(nil, 3)
+	require.True(t, ok)
+	require.Equal(t, []ClockIdentity{3}, got)
+
+	_, ok = AppendPathTrace(make([]ClockIdentity, MaxPathTraceLength), 3)
+	require.False(t, ok)
+}
+
+func TestAnnouncePathTrace(t *testing.T) {
+	announce := &Announce{
+		Header: Header{
+			SdoIDAndMsgType: NewSdoIDAndMsgType(MessageAnnounce, 0),
+			Version:         Version,
+			MessageLength:   headerSize + announceBodySize,
+		},
+		AnnounceBody: AnnounceBody{
+			GrandmasterIdentity: 1,
+		},
+	}
+	require.Nil(t, announce.PathTraceTLV())
+	require.Nil(t, announce.PathTrace())
+	require.False(t, announce.PathTraceLoop(1))
+
+	announce.SetPathTrace([]ClockIdentity{1, 2})
+	require.Equal(t, []ClockIdentity{1, 2}, announce.PathTrace())
+	require.Equal(t, uint16(headerSize+announceBodySize+tlvHeadSize+16), announce.MessageLength)
+	require.True(t, announce.PathTraceLoop(2))
+	require.False(t, announce.PathTraceLoop(3))
+
+	path, ok := AppendPathTrace(announce.PathTrace(), 3)
+	require.True(t, ok)
+	announce.SetPathTrace(path)
+	require.Len(t, announce.TLVs, 1)
+	require.Equal(t, uint16(headerSize+announceBodySize+tlvHeadSize+24), announce.MessageLength)
+
+	b, err := Bytes(announce)
+	require.NoError(t, err)
+	parsed := &Announce{}
+	require.NoError(t, FromBytes(b, parsed))
+	require.Equal(t, announce, parsed)
+	require.Equal(t, []ClockIdentity{1, 2, 3}, parsed.PathTrace())
+
+	longest := make([]ClockIdentity, MaxPathTraceLength)
+	announce.SetPathTrace(longest)
+	_, err = announce.MarshalBinary()
+	require.NoError(t, err)
+
+	announce.SetPathTrace(nil)
+	require.Nil(t, announce.PathTraceTLV())
+	require.Equal(t, uint16(headerSize+announceBodySize), announce.MessageLength)
+}
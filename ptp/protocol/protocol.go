@@ -104,6 +104,17 @@ func (p *Header) MessageType() MessageType {
 	return p.SdoIDAndMsgType.MsgType()
 }
 
+// SdoID returns SdoID combined from majorSdoId and minorSdoId
+func (p *Header) SdoID() SdoID {
+	return NewSdoID(p.SdoIDAndMsgType.MajorSdoID(), p.MinorSdoID)
+}
+
+// SetSdoID populates majorSdoId and minorSdoId fields, keeping the message type
+func (p *Header) SetSdoID(sdoID SdoID) {
+	p.SdoIDAndMsgType = NewSdoIDAndMsgType(p.MessageType(), sdoID.Major())
+	p.MinorSdoID = sdoID.Minor()
+}
+
 // SetSequence populates sequence field
 func (p *Header) SetSequence(sequence uint16) {
 	p.SequenceID = sequence
@@ -227,7 +238,7 @@ func (p *Announce) UnmarshalBinary(b []byte) error {
 	pos := n + 30
 	// unmarshal TLVs if present
 	var err error
-	p.TLVs, err = readTLVs(p.TLVs, int(p.MessageLength)-pos, b[pos:])
+	p.TLVs, err = readTLVs(p.TLVs[:0], int(p.MessageLength)-pos, b[pos:])
 	if err != nil {
 		return err
 	}
@@ -287,7 +298,7 @@ func (p *SyncDelayReq) UnmarshalBinary(b []byte) error {
 
 	pos := headerSize + 10
 	var err error
-	p.TLVs, err = readTLVs(p.TLVs, int(p.MessageLength)-pos, b[pos:])
+	p.TLVs, err = readTLVs(p.TLVs[:0], int(p.MessageLength)-pos, b[pos:])
 	return err
 }
 
@@ -470,7 +481,11 @@ func FromBytes(rawBytes []byte, p Packet) error {
 // DecodePacket provides single entry point to try and decode any []bytes to PTPv2 packet.
 // It can be used for easy integration with anything that provides UDP packet payload as bytes.
 // Resulting Packet user can then either switch based on MessageType(), or just with type switch.
+// PTPv1 messages are decoded into V1Packet.
 func DecodePacket(b []byte) (Packet, error) {
+	if IsV1(b) {
+		return DecodeV1Packet(b)
+	}
 	r := bytes.NewReader(b)
 	head := &Header{}
 	if err := binary.Read(r, binary.BigEndian, head); err != nil {
@@ -486,7 +501,11 @@ func DecodePacket(b []byte) (Packet, error) {
 	case MessagePDelayResp:
 		p = &PDelayResp{}
 	case MessageFollowUp:
-		p = &FollowUp{}
+		if head.SdoIDAndMsgType.MajorSdoID() == MajorSdoIDGPTP {
+			p = &GPTPFollowUp{}
+		} else {
+			p = &FollowUp{}
+		}
 	case MessageDelayResp:
 		p = &DelayResp{}
 	case MessagePDelayRespFollowUp:
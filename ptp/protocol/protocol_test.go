@@ -122,6 +122,28 @@ func TestParseSync(t *testing.T) {
 	assert.Equal(t, &want, pp)
 }
 
+func TestHeaderSdoID(t *testing.T) {
+	packet := &SyncDelayReq{
+		Header: Header{
+			SdoIDAndMsgType: NewSdoIDAndMsgType(MessageDelayReq, 0),
+			Version:         Version,
+			MessageLength:   44,
+		},
+	}
+	require.Equal(t, SdoID(0), packet.SdoID())
+	packet.SetSdoID(0x234)
+	require.Equal(t, MessageDelayReq, packet.MessageType())
+	require.Equal(t, uint8(0x34), packet.MinorSdoID)
+	require.Equal(t, SdoID(0x234), packet.SdoID())
+
+	b, err := Bytes(packet)
+	require.NoError(t, err)
+	require.True(t, MatchSdoID(b, 0x234))
+	parsed := &SyncDelayReq{}
+	require.NoError(t, FromBytes(b, parsed))
+	require.Equal(t, SdoID(0x234), parsed.SdoID())
+}
+
 func TestParseFollowup(t *testing.T) {
 	raw := []uint8{
 		0x8, 0x2, 0x0, 0x2c, 0x0, 0x0, 0x4, 0x0, 0x0,
@@ -33,11 +33,18 @@ const PTP4lSock = "/var/run/ptp4l"
 
 // ptp4l-specific management TLV ids
 const (
-	IDTimeStatusNP         ManagementID = 0xC000
-	IDPortPropertiesNP     ManagementID = 0xC004
-	IDPortStatsNP          ManagementID = 0xC005
-	IDPortServiceStatsNP   ManagementID = 0xC007
-	IDUnicastMasterTableNP ManagementID = 0xC008
+	IDTimeStatusNP               ManagementID = 0xC000
+	IDGrandmasterSettingsNP      ManagementID = 0xC001
+	IDPortDataSetNP              ManagementID = 0xC002
+	IDSubscribeEventsNP          ManagementID = 0xC003
+	IDPortPropertiesNP           ManagementID = 0xC004
+	IDPortStatsNP                ManagementID = 0xC005
+	IDSynchronizationUncertainNP ManagementID = 0xC006
+	IDPortServiceStatsNP         ManagementID = 0xC007
+	IDUnicastMasterTableNP       ManagementID = 0xC008
+	IDPortHWClockNP              ManagementID = 0xC009
+	IDPowerProfileSettingsNP     ManagementID = 0xC00A
+	IDCMLDSInfoNP                ManagementID = 0xC00B
 )
 
 // UnicastMasterState is a enum describing the unicast master state in ptp4l unicast master table
@@ -129,6 +136,88 @@ type TimeStatusNPTLV struct {
 	GMIdentity                 ClockIdentity
 }
 
+// GrandmasterSettingsNPTLV is a ptp4l struct with settings used when ptp4l is a grandmaster, supports SET
+type GrandmasterSettingsNPTLV struct {
+	ManagementTLVHead
+
+	ClockQuality ClockQuality
+	UTCOffset    int16
+	TimeFlags    uint8
+	TimeSource   TimeSource
+}
+
+// PortDataSetNPTLV is a ptp4l struct with 802.1AS port settings, supports SET
+type PortDataSetNPTLV struct {
+	ManagementTLVHead
+
+	NeighborPropDelayThresh uint32
+	AsCapable               int32
+}
+
+// SubscribeEventsNPTLV is a ptp4l struct to subscribe the management connection to push notifications, supports SET
+type SubscribeEventsNPTLV struct {
+	ManagementTLVHead
+
+	Duration uint16 // in seconds
+	Bitmask  [64]uint8
+}
+
+// ptp4l notification events which can be set in SubscribeEventsNPTLV Bitmask
+const (
+	NotifyPortState = iota
+	NotifyTimeSync
+	NotifyParentDataSet
+	NotifyCMLDS
+)
+
+// SetEvent enables the notification event in the bitmask
+func (t *SubscribeEventsNPTLV) SetEvent(event int) {
+	t.Bitmask[event/8] |= 1 << (event % 8)
+}
+
+// HasEvent returns true if the notification event is enabled in the bitmask
+func (t *SubscribeEventsNPTLV) HasEvent(event int) bool {
+	return t.Bitmask[event/8]&(1<<(event%8)) != 0
+}
+
+// SynchronizationUncertainNPTLV is a ptp4l struct with synchronizationUncertain flag, supports SET
+type SynchronizationUncertainNPTLV struct {
+	ManagementTLVHead
+
+	Val      uint8 // 0 - false, 1 - true, 0xff - don't care
+	Reserved uint8
+}
+
+// PortHWClockNPTLV is a ptp4l struct describing the PHC used by the port
+type PortHWClockNPTLV struct {
+	ManagementTLVHead
+
+	PortIdentity PortIdentity
+	PHCIndex     int32
+	Flags        uint8
+	Reserved     uint8
+}
+
+// PowerProfileSettingsNPTLV is a ptp4l struct with IEEE C37.238 power profile settings, supports SET
+type PowerProfileSettingsNPTLV struct {
+	ManagementTLVHead
+
+	Version                   uint16
+	GrandmasterID             uint16
+	GrandmasterTimeInaccuracy uint32
+	NetworkTimeInaccuracy     uint32
+	TotalTimeInaccuracy       uint32
+}
+
+// CMLDSInfoNPTLV is a ptp4l struct with Common Mean Link Delay Service information
+type CMLDSInfoNPTLV struct {
+	ManagementTLVHead
+
+	MeanLinkDelay           TimeInterval
+	ScaledNeighborRateRatio int32
+	AsCapable               uint32
+}
+
 // PortPropertiesNPTLV is a ptp4l struct containing port properties
 type PortPropertiesNPTLV struct {
 	ManagementTLVHead
@@ -543,3 +632,20 @@ func (c *MgmtClient) UnicastMasterTableNP() (*UnicastMasterTableNPTLV, error) {
 	}
 	return tlv, nil
 }
+
+func init() {
+	// ptp4l-specific TLVs which can be read with binary.Read
+	fixed := map[ManagementID]func() ManagementTLV{
+		IDGrandmasterSettingsNP:      func() ManagementTLV { return &GrandmasterSettingsNPTLV{} },
+		IDPortDataSetNP:              func() ManagementTLV { return &PortDataSetNPTLV{} },
+		IDSubscribeEventsNP:          func() ManagementTLV { return &SubscribeEventsNPTLV{} },
+		IDSynchronizationUncertainNP: func() ManagementTLV { return &SynchronizationUncertainNPTLV{} },
+		IDPortHWClockNP:              func() ManagementTLV { return &PortHWClockNPTLV{} },
+		IDPowerProfileSettingsNP:     func() ManagementTLV { return &PowerProfileSettingsNPTLV{} },
+		IDCMLDSInfoNP:                func() ManagementTLV { return &CMLDSInfoNPTLV{} },
+	}
+	for id, f := range fixed {
+		mgmtTLVDecoder[id] = fixedMgmtTLVDecoder(f)
+		mgmtTLVNew[id] = f
+	}
+}
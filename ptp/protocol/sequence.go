@@ -0,0 +1,224 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"sync"
+)
+
+// SequenceEvent is a classification of received sequenceId
+type SequenceEvent uint8
+
+// Possible results of sequenceId tracking
+const (
+	// SequenceFirst is the first message from the source
+	SequenceFirst SequenceEvent = iota
+	// SequenceInOrder is the message following the previous one
+	SequenceInOrder
+	// SequenceGap means some messages before this one were lost
+	SequenceGap
+	// SequenceDuplicate is the message we've already seen
+	SequenceDuplicate
+	// SequenceReordered is the message which arrived after a later one
+	SequenceReordered
+	// SequenceReset means sequenceId jumped further than max gap and tracking started over
+	SequenceReset
+)
+
+var sequenceEventToString = map[SequenceEvent]string{
+	SequenceFirst:     "FIRST",
+	SequenceInOrder:   "IN_ORDER",
+	SequenceGap:       "GAP",
+	SequenceDuplicate: "DUPLICATE",
+	SequenceReordered: "REORDERED",
+	SequenceReset:     "RESET",
+}
+
+func (e SequenceEvent) String() string {
+	return sequenceEventToString[e]
+}
+
+// SequenceResult is a result of tracking a single message
+type SequenceResult struct {
+	Event SequenceEvent
+	// Lost is the change of lost messages counter.
+	// It's negative when reordered message which was counted as lost arrives.
+	Lost int
+}
+
+// SequenceStats are counters of SequenceTracker
+type SequenceStats struct {
+	Received   uint64
+	Lost       uint64
+	Duplicates uint64
+	Reordered  uint64
+	Resets     uint64
+}
+
+func (s *SequenceStats) add(o SequenceStats) {
+	s.Received += o.Received
+	s.Lost += o.Lost
+	s.Duplicates += o.Duplicates
+	s.Reordered += o.Reordered
+	s.Resets += o.Resets
+}
+
+// DefaultSequenceMaxGap is the default max gap of SequenceTracker
+const DefaultSequenceMaxGap = 1024
+
+// sequenceWindow is how many sequenceIds before the latest one we remember to detect duplicates
+const sequenceWindow = 64
+
+type sequenceKey struct {
+	clockIdentity ClockIdentity
+	msgType       MessageType
+}
+
+type sequenceState struct {
+	latest uint16
+	// bit N is set if latest-N was received
+	seen uint64
+	// how many sequenceIds up to latest were either received or counted as lost, capped by sequenceWindow
+	known uint32
+	stats SequenceStats
+}
+
+// SequenceTracker detects lost, duplicate and reordered messages
+// per clockIdentity and message type, taking sequenceId wrap-around into account.
+// It's safe for concurrent use.
+type SequenceTracker struct {
+	mu      sync.Mutex
+	mask    uint16
+	maxGap  uint32
+	sources map[sequenceKey]*sequenceState
+}
+
+// NewSequenceTracker returns new SequenceTracker.
+// Mask selects lower bits of sequenceId which are incremented and wrap around, like one used by sptp client,
+// 0 means all 16 bits. Jumps of sequenceId by more than maxGap are treated as source restart
+// rather than message loss, 0 means DefaultSequenceMaxGap.
+func NewSequenceTracker(mask uint16, maxGap uint16) *SequenceTracker {
+	if mask == 0 {
+		mask = 0xffff
+	}
+	half := (uint32(mask) + 1) / 2
+	if maxGap == 0 {
+		maxGap = DefaultSequenceMaxGap
+	}
+	gap := min(uint32(maxGap), half-1)
+	return &SequenceTracker{
+		mask:    mask,
+		maxGap:  gap,
+		sources: map[sequenceKey]*sequenceState{},
+	}
+}
+
+// TrackHeader tracks received message by its header
+func (t *SequenceTracker) TrackHeader(h *Header) SequenceResult {
+	return t.Track(h.SourcePortIdentity.ClockIdentity, h.MessageType(), h.SequenceID)
+}
+
+// Track tracks received message
+func (t *SequenceTracker) Track(clockIdentity ClockIdentity, msgType MessageType, sequenceID uint16) SequenceResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := sequenceKey{clockIdentity: clockIdentity, msgType: msgType}
+	s, ok := t.sources[key]
+	if !ok {
+		s = &sequenceState{latest: sequenceID, seen: 1, known: 1}
+		s.stats.Received++
+		t.sources[key] = s
+		return SequenceResult{Event: SequenceFirst}
+	}
+	s.stats.Received++
+
+	half := (uint32(t.mask) + 1) / 2
+	ahead := uint32((sequenceID - s.latest) & t.mask)
+	behind := uint32((s.latest - sequenceID) & t.mask)
+	switch {
+	case ahead == 0:
+		s.stats.Duplicates++
+		return SequenceResult{Event: SequenceDuplicate}
+	case ahead < half && ahead <= t.maxGap:
+		lost := int(ahead - 1)
+		if ahead >= sequenceWindow {
+			s.seen = 1
+		} else {
+			s.seen = s.seen<<ahead | 1
+		}
+		s.latest = sequenceID
+		s.known = min(s.known+ahead, sequenceWindow)
+		s.stats.Lost += uint64(lost)
+		if lost == 0 {
+			return SequenceResult{Event: SequenceInOrder}
+		}
+		return SequenceResult{Event: SequenceGap, Lost: lost}
+	case ahead >= half && behind <= t.maxGap:
+		if behind >= s.known {
+			// too old to tell if it's a duplicate, or from before the reset
+			s.stats.Reordered++
+			return SequenceResult{Event: SequenceReordered}
+		}
+		bit := uint64(1) << behind
+		if s.seen&bit != 0 {
+			s.stats.Duplicates++
+			return SequenceResult{Event: SequenceDuplicate}
+		}
+		s.seen |= bit
+		s.stats.Reordered++
+		// it was counted as lost when we jumped over it
+		s.stats.Lost--
+		return SequenceResult{Event: SequenceReordered, Lost: -1}
+	}
+	s.latest = sequenceID
+	s.seen = 1
+	s.known = 1
+	s.stats.Resets++
+	return SequenceResult{Event: SequenceReset}
+}
+
+// Stats returns counters of the source
+func (t *SequenceTracker) Stats(clockIdentity ClockIdentity, msgType MessageType) SequenceStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s, ok := t.sources[sequenceKey{clockIdentity: clockIdentity, msgType: msgType}]; ok {
+		return s.stats
+	}
+	return SequenceStats{}
+}
+
+// Total returns counters summed over all sources
+func (t *SequenceTracker) Total() SequenceStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	total := SequenceStats{}
+	for _, s := range t.sources {
+		total.add(s.stats)
+	}
+	return total
+}
+
+// Forget stops tracking all message types of the clock
+func (t *SequenceTracker) Forget(clockIdentity ClockIdentity) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for k := range t.sources {
+		if k.clockIdentity == clockIdentity {
+			delete(t.sources, k)
+		}
+	}
+}
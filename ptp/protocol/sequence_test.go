@@ -0,0 +1,122 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequenceTracker(t *testing.T) {
+	tracker := NewSequenceTracker(0, 0)
+	track := func(seq uint16) SequenceResult {
+		return tracker.Track(1, MessageSync, seq)
+	}
+	require.Equal(t, SequenceResult{Event: SequenceFirst}, track(65530))
+	require.Equal(t, SequenceResult{Event: SequenceInOrder}, track(65531))
+	// 65532 and 65533 are lost
+	require.Equal(t, SequenceResult{Event: SequenceGap, Lost: 2}, track(65534))
+	require.Equal(t, SequenceResult{Event: SequenceDuplicate}, track(65534))
+	// 65533 arrives late
+	require.Equal(t, SequenceResult{Event: SequenceReordered, Lost: -1}, track(65533))
+	require.Equal(t, SequenceResult{Event: SequenceDuplicate}, track(65533))
+	// wrap around is not a gap
+	require.Equal(t, SequenceResult{Event: SequenceInOrder}, track(65535))
+	require.Equal(t, SequenceResult{Event: SequenceInOrder}, track(0))
+	require.Equal(t, SequenceResult{Event: SequenceGap, Lost: 1}, track(2))
+	require.Equal(t, SequenceResult{Event: SequenceReordered, Lost: -1}, track(1))
+	require.Equal(t, SequenceResult{Event: SequenceDuplicate}, track(65531))
+
+	require.Equal(t, SequenceStats{Received: 11, Lost: 1, Duplicates: 3, Reordered: 2}, tracker.Stats(1, MessageSync))
+	require.Equal(t, SequenceStats{}, tracker.Stats(1, MessageAnnounce))
+	require.Equal(t, SequenceStats{}, tracker.Stats(2, MessageSync))
+}
+
+func TestSequenceTrackerReset(t *testing.T) {
+	tracker := NewSequenceTracker(0, 100)
+	track := func(seq uint16) SequenceResult {
+		return tracker.Track(1, MessageSync, seq)
+	}
+	track(10)
+	require.Equal(t, SequenceResult{Event: SequenceGap, Lost: 99}, track(110))
+	// too far ahead, source restarted
+	require.Equal(t, SequenceResult{Event: SequenceReset}, track(5000))
+	// messages from before the reset don't affect lost counter
+	require.Equal(t, SequenceResult{Event: SequenceReordered}, track(4999))
+	// too far behind
+	require.Equal(t, SequenceResult{Event: SequenceReset}, track(20))
+	require.Equal(t, SequenceResult{Event: SequenceInOrder}, track(21))
+	require.Equal(t, SequenceStats{Received: 6, Lost: 99, Reordered: 1, Resets: 2}, tracker.Stats(1, MessageSync))
+}
+
+func TestSequenceTrackerWindow(t *testing.T) {
+	tracker := NewSequenceTracker(0, 0)
+	track := func(seq uint16) SequenceResult {
+		return tracker.Track(1, MessageSync, seq)
+	}
+	track(0)
+	require.Equal(t, SequenceResult{Event: SequenceGap, Lost: 199}, track(200))
+	require.Equal(t, SequenceResult{Event: SequenceReordered, Lost: -1}, track(137))
+	// beyond the window we can't tell duplicates
+	require.Equal(t, SequenceResult{Event: SequenceReordered}, track(100))
+	require.Equal(t, SequenceResult{Event: SequenceReordered}, track(0))
+	require.Equal(t, uint64(198), tracker.Stats(1, MessageSync).Lost)
+}
+
+func TestSequenceTrackerMask(t *testing.T) {
+	// sptp client with 1 bit mask uses 0x8000-0xffff range
+	tracker := NewSequenceTracker(0x7fff, 0)
+	track := func(seq uint16) SequenceResult {
+		return tracker.Track(1, MessageSync, seq)
+	}
+	track(0xfffe)
+	require.Equal(t, SequenceResult{Event: SequenceInOrder}, track(0xffff))
+	require.Equal(t, SequenceResult{Event: SequenceInOrder}, track(0x8000))
+	require.Equal(t, SequenceResult{Event: SequenceGap, Lost: 1}, track(0x8002))
+	require.Equal(t, SequenceResult{Event: SequenceReordered, Lost: -1}, track(0x8001))
+
+	// max gap can't exceed half of the range
+	tracker = NewSequenceTracker(0x000f, 1000)
+	require.Equal(t, uint32(7), tracker.maxGap)
+}
+
+func TestSequenceTrackerSources(t *testing.T) {
+	tracker := NewSequenceTracker(0, 0)
+	h := &Header{
+		SdoIDAndMsgType:    NewSdoIDAndMsgType(MessageAnnounce, 0),
+		SourcePortIdentity: PortIdentity{ClockIdentity: 1},
+		SequenceID:         42,
+	}
+	require.Equal(t, SequenceFirst, tracker.TrackHeader(h).Event)
+	h.SequenceID = 44
+	require.Equal(t, SequenceGap, tracker.TrackHeader(h).Event)
+	// different message type and clock are tracked separately
+	require.Equal(t, SequenceFirst, tracker.Track(1, MessageSync, 1).Event)
+	require.Equal(t, SequenceFirst, tracker.Track(2, MessageAnnounce, 7).Event)
+	require.Equal(t, SequenceGap, tracker.Track(2, MessageAnnounce, 9).Event)
+	require.Equal(t, SequenceStats{Received: 5, Lost: 2}, tracker.Total())
+
+	tracker.Forget(1)
+	require.Equal(t, SequenceStats{Received: 2, Lost: 1}, tracker.Total())
+	require.Equal(t, SequenceFirst, tracker.Track(1, MessageSync, 100).Event)
+}
+
+func TestSequenceEventString(t *testing.T) {
+	require.Equal(t, "REORDERED", SequenceReordered.String())
+	require.Equal(t, "FIRST", SequenceFirst.String())
+}
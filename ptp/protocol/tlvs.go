@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"time"
 )
 
 // TLV abstracts away any TLV
@@ -146,6 +147,13 @@ func readTLVs(tlvs []TLV, maxLength int, b []byte) ([]TLV, error) {
 			}
 			tlvs = append(tlvs, tlv)
 			pos += tlvHeadSize + int(tlv.LengthField)
+		case TLVAuthentication:
+			tlv := &AuthenticationTLV{}
+			if err := tlv.UnmarshalBinary(b[pos:]); err != nil {
+				return tlvs, err
+			}
+			tlvs = append(tlvs, tlv)
+			pos += tlvHeadSize + int(tlv.LengthField)
 		case TLVAlternateResponsePort:
 			tlv := &AlternateResponsePortTLV{}
 			if err := tlv.UnmarshalBinary(b[pos:]); err != nil {
@@ -153,6 +161,13 @@ func readTLVs(tlvs []TLV, maxLength int, b []byte) ([]TLV, error) {
 			}
 			tlvs = append(tlvs, tlv)
 			pos += tlvHeadSize + int(tlv.LengthField)
+		case TLVOrganizationExtension:
+			tlv, err := unmarshalOrganizationTLV(b[pos:])
+			if err != nil {
+				return tlvs, err
+			}
+			tlvs = append(tlvs, tlv)
+			pos += tlvHeadSize + int(binary.BigEndian.Uint16(b[pos+2:]))
 		default:
 			return tlvs, fmt.Errorf("reading TLV %s (%d) is not yet implemented", tlvType, tlvType)
 		}
@@ -297,6 +312,9 @@ type PathTraceTLV struct {
 
 // MarshalBinaryTo marshals bytes to PathTraceTLV
 func (t *PathTraceTLV) MarshalBinaryTo(b []byte) (int, error) {
+	if len(b) < tlvHeadSize+8*len(t.PathSequence) {
+		return 0, fmt.Errorf("not enough buffer to write PathTraceTLV")
+	}
 	tlvHeadMarshalBinaryTo(&t.TLVHead, b)
 	pos := tlvHeadSize
 	for _, ps := range t.PathSequence {
@@ -314,12 +332,10 @@ func (t *PathTraceTLV) UnmarshalBinary(b []byte) error {
 	if err := checkTLVLength(&t.TLVHead, len(b), 8, false); err != nil {
 		return err
 	}
-	t.PathSequence = []ClockIdentity{}
-	for i := 0; i*8 <= int(t.TLVHead.LengthField); i++ {
+	n := int(t.TLVHead.LengthField) / 8
+	t.PathSequence = make([]ClockIdentity, 0, n)
+	for i := 0; i < n; i++ {
 		pos := tlvHeadSize + i*8
-		if pos+8 >= len(b) {
-			break
-		}
 		identity := ClockIdentity(binary.BigEndian.Uint64(b[pos:]))
 		t.PathSequence = append(t.PathSequence, identity)
 	}
@@ -336,22 +352,80 @@ type AlternateTimeOffsetIndicatorTLV struct {
 	DisplayName    PTPText
 }
 
+// alternateTimeOffsetIndicatorSize is the size of fixed part of ALTERNATE_TIME_OFFSET_INDICATOR TLV data,
+// followed by displayName
+const alternateTimeOffsetIndicatorSize = 15
+
+// maxDisplayNameLength is the maximum length of alternate timescale displayName as per 16.3.3.7
+const maxDisplayNameLength = 10
+
+// NewAlternateTimeOffsetIndicatorTLV returns ALTERNATE_TIME_OFFSET_INDICATOR TLV with LengthField populated.
+// Offset is the offset of the alternate timescale from PTP time, jump is the size of the next discontinuity
+// happening at PTP time next. Zero jump means no discontinuity is planned.
+func NewAlternateTimeOffsetIndicatorTLV(key uint8, name string, offset, jump time.Duration, next time.Time) (*AlternateTimeOffsetIndicatorTLV, error) {
+	if len(name) > maxDisplayNameLength {
+		return nil, fmt.Errorf("display name %q is longer than %d", name, maxDisplayNameLength)
+	}
+	t := &AlternateTimeOffsetIndicatorTLV{
+		TLVHead: TLVHead{
+			TLVType:     TLVAlternateTimeOffsetIndicator,
+			LengthField: uint16(alternateTimeOffsetIndicatorSize + 1 + len(name) + len(name)%2), //#nosec G115
+		},
+		KeyField:      key,
+		CurrentOffset: int32(offset / time.Second),
+		JumpSeconds:   int32(jump / time.Second),
+		DisplayName:   PTPText(name),
+	}
+	if jump != 0 {
+		t.TimeOfNextJump = NewPTPSeconds(next)
+	}
+	return t, nil
+}
+
+// Offset returns current offset of the alternate timescale from PTP time
+func (t *AlternateTimeOffsetIndicatorTLV) Offset() time.Duration {
+	return time.Duration(t.CurrentOffset) * time.Second
+}
+
+// JumpPending reports whether discontinuity of the alternate timescale is announced
+func (t *AlternateTimeOffsetIndicatorTLV) JumpPending() bool {
+	return t.JumpSeconds != 0 && !t.TimeOfNextJump.Empty()
+}
+
+// OffsetAt returns offset of the alternate timescale from PTP time at the given PTP time,
+// taking announced discontinuity into account
+func (t *AlternateTimeOffsetIndicatorTLV) OffsetAt(ptpTime time.Time) time.Duration {
+	if t.JumpPending() && !ptpTime.Before(t.TimeOfNextJump.Time()) {
+		return time.Duration(int64(t.CurrentOffset)+int64(t.JumpSeconds)) * time.Second
+	}
+	return t.Offset()
+}
+
+// AlternateTime converts PTP time to the alternate timescale
+func (t *AlternateTimeOffsetIndicatorTLV) AlternateTime(ptpTime time.Time) time.Time {
+	return ptpTime.Add(t.OffsetAt(ptpTime))
+}
+
 // MarshalBinaryTo marshals bytes to AlternateTimeOffsetIndicatorTLV
 func (t *AlternateTimeOffsetIndicatorTLV) MarshalBinaryTo(b []byte) (int, error) {
+	if len(t.DisplayName) > maxDisplayNameLength {
+		return 0, fmt.Errorf("writing AlternateTimeOffsetIndicatorTLV DisplayName: display name is too long")
+	}
+	dd, err := t.DisplayName.MarshalBinary()
+	if err != nil {
+		return 0, fmt.Errorf("writing AlternateTimeOffsetIndicatorTLV DisplayName: %w", err)
+	}
+	size := tlvHeadSize + alternateTimeOffsetIndicatorSize + len(dd)
+	if len(b) < size {
+		return 0, fmt.Errorf("not enough buffer to write AlternateTimeOffsetIndicatorTLV")
+	}
 	tlvHeadMarshalBinaryTo(&t.TLVHead, b)
 	b[tlvHeadSize] = t.KeyField
 	binary.BigEndian.PutUint32(b[tlvHeadSize+1:], uint32(t.CurrentOffset))
 	binary.BigEndian.PutUint32(b[tlvHeadSize+5:], uint32(t.JumpSeconds))
 	copy(b[tlvHeadSize+9:], t.TimeOfNextJump[:]) //uint48
-	size := tlvHeadSize + 15
-	if t.DisplayName != "" {
-		dd, err := t.DisplayName.MarshalBinary()
-		if err != nil {
-			return 0, fmt.Errorf("writing AlternateTimeOffsetIndicatorTLV DisplayName: %w", err)
-		}
-		copy(b[tlvHeadSize+15:], dd)
-		size += len(dd)
-	}
+	// displayName is mandatory, empty one is just a zero length octet
+	copy(b[tlvHeadSize+alternateTimeOffsetIndicatorSize:], dd)
 	return size, nil
 }
 
@@ -360,14 +434,14 @@ func (t *AlternateTimeOffsetIndicatorTLV) UnmarshalBinary(b []byte) error {
 	if err := unmarshalTLVHeader(&t.TLVHead, b); err != nil {
 		return err
 	}
-	if err := checkTLVLength(&t.TLVHead, len(b), 20, false); err != nil {
+	if err := checkTLVLength(&t.TLVHead, len(b), alternateTimeOffsetIndicatorSize+1, false); err != nil {
 		return err
 	}
 	t.KeyField = b[tlvHeadSize]
 	t.CurrentOffset = int32(binary.BigEndian.Uint32(b[tlvHeadSize+1:]))
 	t.JumpSeconds = int32(binary.BigEndian.Uint32(b[tlvHeadSize+5:]))
 	copy(t.TimeOfNextJump[:], b[tlvHeadSize+9:]) // uint48
-	if err := t.DisplayName.UnmarshalBinary(b[tlvHeadSize+15:]); err != nil {
+	if _, err := readPTPText(&t.DisplayName, b[tlvHeadSize+alternateTimeOffsetIndicatorSize:tlvHeadSize+int(t.LengthField)]); err != nil {
 		return fmt.Errorf("reading AlternateTimeOffsetIndicatorTLV DisplayName: %w", err)
 	}
 	return nil
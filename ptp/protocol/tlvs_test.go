@@ -149,6 +149,60 @@ func TestParseAnnounceWithAlternateTimeOffsetIndicator(t *testing.T) {
 	require.Equal(t, &want, pp)
 }
 
+func TestAlternateTimeOffsetIndicatorTLV(t *testing.T) {
+	next := time.Unix(1656946102, 0)
+	tlv, err := NewAlternateTimeOffsetIndicatorTLV(1, "UTC", 37*time.Second, time.Second, next)
+	require.NoError(t, err)
+	require.Equal(t, uint16(20), tlv.LengthField)
+	b := make([]byte, 24)
+	n, err := tlv.MarshalBinaryTo(b)
+	require.NoError(t, err)
+	require.Equal(t, 24, n)
+	require.Equal(t, []byte{0x00, 0x09, 0x00, 0x14, 0x01, 0x00, 0x00, 0x00, 0x25, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x62, 0xc2, 0xfd, 0xb6, 0x03, 'U', 'T', 'C', 0x00}, b)
+
+	got := &AlternateTimeOffsetIndicatorTLV{DisplayName: "stale"}
+	require.NoError(t, got.UnmarshalBinary(b))
+	require.Equal(t, tlv, got)
+
+	require.True(t, tlv.JumpPending())
+	require.Equal(t, 37*time.Second, tlv.Offset())
+	require.Equal(t, 37*time.Second, tlv.OffsetAt(next.Add(-time.Second)))
+	require.Equal(t, 38*time.Second, tlv.OffsetAt(next))
+	require.Equal(t, next.Add(38*time.Second), tlv.AlternateTime(next))
+
+	// no jump planned, empty display name still takes a length octet
+	tlv, err = NewAlternateTimeOffsetIndicatorTLV(2, "", -time.Hour, 0, next)
+	require.NoError(t, err)
+	require.Equal(t, uint16(16), tlv.LengthField)
+	require.False(t, tlv.JumpPending())
+	require.True(t, tlv.TimeOfNextJump.Empty())
+	require.Equal(t, -time.Hour, tlv.OffsetAt(next.Add(time.Hour)))
+	n, err = tlv.MarshalBinaryTo(b)
+	require.NoError(t, err)
+	require.Equal(t, 20, n)
+	require.NoError(t, got.UnmarshalBinary(b[:n]))
+	require.Equal(t, tlv, got)
+}
+
+func TestAlternateTimeOffsetIndicatorTLVErrors(t *testing.T) {
+	_, err := NewAlternateTimeOffsetIndicatorTLV(1, "TOO LONG NAME", 0, 0, time.Time{})
+	require.Error(t, err)
+
+	tlv := &AlternateTimeOffsetIndicatorTLV{DisplayName: "TOO LONG NAME"}
+	_, err = tlv.MarshalBinaryTo(make([]byte, 64))
+	require.Error(t, err)
+
+	tlv, err = NewAlternateTimeOffsetIndicatorTLV(1, "UTC", 0, 0, time.Time{})
+	require.NoError(t, err)
+	_, err = tlv.MarshalBinaryTo(make([]byte, 20))
+	require.Error(t, err)
+
+	// too short
+	require.Error(t, tlv.UnmarshalBinary([]byte{0x00, 0x09, 0x00, 0x0e, 0x01, 0x00, 0x00, 0x00, 0x25, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x62, 0xc2, 0xfd}))
+	// display name past lengthField
+	require.Error(t, tlv.UnmarshalBinary([]byte{0x00, 0x09, 0x00, 0x10, 0x01, 0x00, 0x00, 0x00, 0x25, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x62, 0xc2, 0xfd, 0xb6, 0x03, 'U', 'T', 'C', 0x00}))
+}
+
 func TestParseSyncDelayReqWithAlternateResponsePort(t *testing.T) {
 	raw := []byte{1, 18, 0, 50, 0, 0, 36, 0, 0, 0, 0, 0, 6, 32, 0, 2, 0, 0, 0, 0, 184, 206, 246, 255, 254, 68, 148, 144, 0, 1, 149, 17, 0, 127, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 32, 7, 0, 2, 16, 146, 0, 0}
 	packet := new(SyncDelayReq)
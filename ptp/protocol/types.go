@@ -19,6 +19,7 @@ package protocol
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
 	"net"
@@ -76,6 +77,52 @@ func NewSdoIDAndMsgType(msgType MessageType, sdoID uint8) SdoIDAndMsgType {
 	return SdoIDAndMsgType(sdoID<<4 | uint8(msgType))
 }
 
+func (m SdoIDAndMsgType) String() string {
+	return fmt.Sprintf("%s, majorSdoId %d", m.MsgType(), m.MajorSdoID())
+}
+
+// MajorSdoID extracts majorSdoId (formerly transportSpecific) from SdoIDAndMsgType
+func (m SdoIDAndMsgType) MajorSdoID() uint8 {
+	return uint8(m) >> 4 // first 4 bits
+}
+
+// SdoID is a 12 bit sdoId as per 7.1.4, with majorSdoId being the 4 most significant bits and minorSdoId the rest.
+// Instances with different sdoId are isolated from each other and must ignore each other's messages.
+type SdoID uint16
+
+// NewSdoID builds new SdoID from majorSdoId and minorSdoId
+func NewSdoID(major, minor uint8) SdoID {
+	return SdoID(uint16(major&0xf)<<8 | uint16(minor))
+}
+
+// Major returns majorSdoId part of SdoID
+func (s SdoID) Major() uint8 {
+	return uint8(s>>8) & 0xf
+}
+
+// Minor returns minorSdoId part of SdoID
+func (s SdoID) Minor() uint8 {
+	return uint8(s)
+}
+
+func (s SdoID) String() string {
+	return fmt.Sprintf("0x%03x", uint16(s))
+}
+
+// ProbeSdoID reads first 6 bytes of data and returns SdoID of the message
+func ProbeSdoID(data []byte) (SdoID, error) {
+	if len(data) < 6 {
+		return 0, fmt.Errorf("not enough data to probe SdoID")
+	}
+	return NewSdoID(SdoIDAndMsgType(data[0]).MajorSdoID(), data[5]), nil
+}
+
+// MatchSdoID tells if raw message in data belongs to the PTP instance with given SdoID
+func MatchSdoID(data []byte, sdoID SdoID) bool {
+	s, err := ProbeSdoID(data)
+	return err == nil && s == sdoID
+}
+
 // ProbeMsgType reads first 8 bits of data and tries to decode it to SdoIDAndMsgType, then return MessageType
 func ProbeMsgType(data []byte) (msg MessageType, err error) {
 	if len(data) < 1 {
@@ -99,6 +146,7 @@ const (
 	TLVPathTrace                            TLVType = 0x0008
 	TLVAlternateTimeOffsetIndicator         TLVType = 0x0009
 	TLVAlternateResponsePort                TLVType = 0x2007
+	TLVAuthentication                       TLVType = 0x8009
 	// Remaining 52 tlvType TLVs not implemented
 )
 
@@ -114,6 +162,7 @@ var TLVTypeToString = map[TLVType]string{
 	TLVPathTrace:                            "PATH_TRACE",
 	TLVAlternateTimeOffsetIndicator:         "ALTERNATE_TIME_OFFSET_INDICATOR",
 	TLVAlternateResponsePort:                "ALTERNATE_RESPONSE_PORT",
+	TLVAuthentication:                       "AUTHENTICATION",
 }
 
 func (t TLVType) String() string {
@@ -168,10 +217,7 @@ func (t Correction) Nanoseconds() float64 {
 // Duration converts PTP CorrectionField to time.Duration, ignoring
 // case where correction is too big, and dropping fractions of nanoseconds
 func (t Correction) Duration() time.Duration {
-	if !t.TooBig() {
-		return time.Duration(t.Nanoseconds())
-	}
-	return 0
+	return t.DurationRounded(RoundTowardZero)
 }
 
 func (t Correction) String() string {
@@ -189,8 +235,8 @@ func (t Correction) TooBig() bool {
 // NewCorrection returns Correction built from Nanoseconds
 func NewCorrection(ns float64) Correction {
 	t := ns * twoPow16
-	if t > 0x7fffffffffffffff {
-		return Correction(0x7fffffffffffffff)
+	if t > 0x7fffffffffffffff || t < -0x7fffffffffffffff {
+		return correctionTooBig
 	}
 	return Correction(ns * twoPow16)
 }
@@ -308,7 +354,8 @@ func (s PTPSeconds) String() string {
 	return fmt.Sprintf("PTPSeconds(%s)", s.Time())
 }
 
-// NewPTPSeconds creates a new instance of PTPSeconds
+// NewPTPSeconds creates a new instance of PTPSeconds.
+// Times outside of PTPSeconds range are silently truncated, use NewPTPSecondsChecked to catch them.
 func NewPTPSeconds(t time.Time) PTPSeconds {
 	if t.IsZero() {
 		return PTPSeconds{}
@@ -358,7 +405,8 @@ func (t Timestamp) String() string {
 	return fmt.Sprintf("Timestamp(%s)", t.Time())
 }
 
-// NewTimestamp allows to create Timestamp from time.Time
+// NewTimestamp allows to create Timestamp from time.Time.
+// Times outside of Timestamp range are silently truncated, use NewTimestampChecked to catch them.
 func NewTimestamp(t time.Time) Timestamp {
 	if t.IsZero() {
 		return Timestamp{}
@@ -376,6 +424,68 @@ func NewTimestamp(t time.Time) Timestamp {
 	return ts
 }
 
+// MaxPTPSeconds is the largest number of seconds which fits into 48 bits of PTPSeconds
+const MaxPTPSeconds = 1<<48 - 1
+
+// Errors returned by range-checked Timestamp conversions
+var (
+	ErrTimestampOutOfRange  = errors.New("time is out of PTP timestamp range")
+	ErrTimestampNanoseconds = errors.New("timestamp nanoseconds are not less than 10^9")
+	ErrTimestampUnixNano    = errors.New("timestamp can't be represented as int64 nanoseconds")
+)
+
+// NewPTPSecondsChecked is NewPTPSeconds which returns an error instead of truncating
+// times before the epoch or after MaxPTPSeconds
+func NewPTPSecondsChecked(t time.Time) (PTPSeconds, error) {
+	if t.IsZero() {
+		return PTPSeconds{}, nil
+	}
+	if err := checkTimestampRange(t); err != nil {
+		return PTPSeconds{}, err
+	}
+	return NewPTPSeconds(t), nil
+}
+
+// NewTimestampChecked is NewTimestamp which returns an error instead of truncating
+// times before the epoch or after MaxPTPSeconds
+func NewTimestampChecked(t time.Time) (Timestamp, error) {
+	if t.IsZero() {
+		return Timestamp{}, nil
+	}
+	if err := checkTimestampRange(t); err != nil {
+		return Timestamp{}, err
+	}
+	return NewTimestamp(t), nil
+}
+
+func checkTimestampRange(t time.Time) error {
+	if sec := t.Unix(); sec < 0 || sec > MaxPTPSeconds {
+		return fmt.Errorf("%w: %s", ErrTimestampOutOfRange, t)
+	}
+	return nil
+}
+
+// Validate checks that Timestamp is well-formed, with nanoseconds less than 10^9
+func (t Timestamp) Validate() error {
+	if t.Nanoseconds >= 1e9 {
+		return fmt.Errorf("%w: %d", ErrTimestampNanoseconds, t.Nanoseconds)
+	}
+	return nil
+}
+
+// UnixNano returns Timestamp as nanoseconds since the epoch.
+// Unlike Time().UnixNano() it returns an error for far-future timestamps (after year 2262) instead of overflowing.
+func (t Timestamp) UnixNano() (int64, error) {
+	if err := t.Validate(); err != nil {
+		return 0, err
+	}
+	sec := t.Seconds.Seconds()
+	if sec > (math.MaxInt64-uint64(t.Nanoseconds))/1e9 {
+		return 0, fmt.Errorf("%w: %d seconds", ErrTimestampUnixNano, sec)
+	}
+	return int64(sec)*1e9 + int64(t.Nanoseconds), nil //#nosec G115
+}
+
 // ClockClass represents a PTP clock class
 type ClockClass uint8
 
@@ -549,24 +659,65 @@ func (t TimeSource) String() string {
 // In layman's terms, it's specified as a power of two in seconds.
 type LogInterval int8
 
-// Duration returns LogInterval as time.Duration
+// LogIntervalUnspecified is logMessageInterval of messages where it's not applicable, as per Table 42
+const LogIntervalUnspecified LogInterval = 0x7f
+
+// Limits of LogInterval which can be represented as time.Duration
+const (
+	MinLogInterval LogInterval = -30 // 2^-30 seconds is less than a nanosecond
+	MaxLogInterval LogInterval = 33  // 2^34 seconds don't fit into time.Duration
+)
+
+// ErrUnspecifiedLogInterval is returned when converting LogIntervalUnspecified to time.Duration
+var ErrUnspecifiedLogInterval = errors.New("logInterval is unspecified (0x7F)")
+
+// Unspecified reports whether LogInterval is LogIntervalUnspecified
+func (i LogInterval) Unspecified() bool {
+	return i == LogIntervalUnspecified
+}
+
+// Clamp limits LogInterval to [lo, hi] range
+func (i LogInterval) Clamp(lo, hi LogInterval) LogInterval {
+	if i < lo {
+		return lo
+	}
+	if i > hi {
+		return hi
+	}
+	return i
+}
+
+// ToDuration returns LogInterval as time.Duration.
+// It fails for LogIntervalUnspecified and values outside of [MinLogInterval, MaxLogInterval].
+func (i LogInterval) ToDuration() (time.Duration, error) {
+	if i.Unspecified() {
+		return 0, ErrUnspecifiedLogInterval
+	}
+	if i < MinLogInterval || i > MaxLogInterval {
+		return 0, fmt.Errorf("logInterval %d is outside of [%d, %d] range", i, MinLogInterval, MaxLogInterval)
+	}
+	if i < 0 {
+		return time.Second >> -i, nil
+	}
+	return time.Second << i, nil
+}
+
+// Duration returns LogInterval as time.Duration, clamped to [MinLogInterval, MaxLogInterval].
+// LogIntervalUnspecified is clamped as well, use ToDuration to tell it apart.
 func (i LogInterval) Duration() time.Duration {
-	secs := math.Pow(2, float64(i))
-	return time.Duration(secs * float64(time.Second))
+	d, _ := i.Clamp(MinLogInterval, MaxLogInterval).ToDuration()
+	return d
 }
 
 // NewLogInterval returns new LogInterval from time.Duration.
-// The values of these logarithmic attributes shall be selected from integers in the range -128 to 127 subject to
-// further limits established in the applicable PTP Profile.
+// Durations which are not a power of two seconds are rounded towards 1 second.
+// Result is always within [MinLogInterval, MaxLogInterval], further limits are set by PTP Profile.
 func NewLogInterval(d time.Duration) (LogInterval, error) {
-	li := int(math.Log2(d.Seconds()))
-	if li > 127 {
-		return 0, fmt.Errorf("logInterval %d is too big", li)
-	}
-	if li < -128 {
-		return 0, fmt.Errorf("logInterval %d is too small", li)
+	if d <= 0 {
+		return 0, fmt.Errorf("logInterval of non-positive duration %v", d)
 	}
-	return LogInterval(li), nil
+	li := int(math.Log2(d.Seconds()))
+	return LogInterval(li).Clamp(MinLogInterval, MaxLogInterval), nil
 }
 
 /*
@@ -715,6 +866,15 @@ func (p *PortAddress) UnmarshalBinary(b []byte) error {
 	return nil
 }
 
+// String formats PortAddress like linuxptp does: IP for UDP, colon-separated hex bytes otherwise
+func (p PortAddress) String() string {
+	addr := net.HardwareAddr(p.AddressField).String()
+	if ip, err := p.IP(); err == nil {
+		addr = ip.String()
+	}
+	return fmt.Sprintf("%s %s", p.NetworkProtocol, addr)
+}
+
 // IP converts PortAddress to IP
 func (p *PortAddress) IP() (net.IP, error) {
 	if p.NetworkProtocol != TransportTypeUDPIPV4 && p.NetworkProtocol != TransportTypeUDPIPV6 {
@@ -67,6 +67,29 @@ func TestProbeMsgType(t *testing.T) {
 	}
 }
 
+func TestSdoID(t *testing.T) {
+	s := NewSdoID(0x1, 0x23)
+	require.Equal(t, SdoID(0x123), s)
+	require.Equal(t, uint8(1), s.Major())
+	require.Equal(t, uint8(0x23), s.Minor())
+	require.Equal(t, "0x123", s.String())
+	// majorSdoId is only 4 bits
+	require.Equal(t, SdoID(0xf00), NewSdoID(0xff, 0))
+	require.Equal(t, uint8(0x1), NewSdoIDAndMsgType(MessageAnnounce, 1).MajorSdoID())
+}
+
+func TestProbeSdoID(t *testing.T) {
+	_, err := ProbeSdoID([]byte{0x1b, 0x02, 0x00, 0x40, 0x00})
+	require.Error(t, err)
+	got, err := ProbeSdoID([]byte{0x1b, 0x02, 0x00, 0x40, 0x00, 0x23})
+	require.NoError(t, err)
+	require.Equal(t, SdoID(0x123), got)
+
+	require.True(t, MatchSdoID([]byte{0x1b, 0x02, 0x00, 0x40, 0x00, 0x23}, 0x123))
+	require.False(t, MatchSdoID([]byte{0x0b, 0x02, 0x00, 0x40, 0x00, 0x23}, 0x123))
+	require.False(t, MatchSdoID([]byte{0x0b}, 0))
+}
+
 func TestMessageTypeString(t *testing.T) {
 	require.Equal(t, "SYNC", MessageSync.String())
 	require.Equal(t, "DELAY_REQ", MessageDelayReq.String())
@@ -249,6 +272,66 @@ func TestTimestamp(t *testing.T) {
 	}
 }
 
+func TestNewTimestampChecked(t *testing.T) {
+	maxTime := time.Unix(MaxPTPSeconds, 999999999)
+	tests := []struct {
+		name    string
+		in      time.Time
+		want    Timestamp
+		wantErr bool
+	}{
+		{name: "zero", in: time.Time{}, want: Timestamp{}},
+		{name: "epoch", in: time.Unix(0, 1), want: Timestamp{Nanoseconds: 1}},
+		{name: "now", in: time.Unix(1700000000, 5), want: Timestamp{Seconds: PTPSeconds{0, 0, 0x65, 0x53, 0xf1, 0x00}, Nanoseconds: 5}},
+		{name: "max", in: maxTime, want: Timestamp{Seconds: PTPSeconds{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, Nanoseconds: 999999999}},
+		{name: "before epoch", in: time.Unix(-1, 0), wantErr: true},
+		{name: "after max", in: maxTime.Add(time.Nanosecond), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewTimestampChecked(tt.in)
+			seconds, serr := NewPTPSecondsChecked(tt.in)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrTimestampOutOfRange)
+				require.ErrorIs(t, serr, ErrTimestampOutOfRange)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, serr)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.want.Seconds, seconds)
+			require.Equal(t, tt.in, got.Time())
+		})
+	}
+	// unchecked conversion silently wraps around
+	require.Equal(t, Timestamp{}, NewTimestamp(maxTime.Add(time.Nanosecond)))
+}
+
+func TestTimestampUnixNano(t *testing.T) {
+	ns, err := Timestamp{Seconds: PTPSeconds{0, 0, 0x65, 0x53, 0xf1, 0x00}, Nanoseconds: 5}.UnixNano()
+	require.NoError(t, err)
+	require.Equal(t, int64(1700000000000000005), ns)
+
+	// last second which fits into int64 nanoseconds, in year 2262
+	last := NewTimestamp(time.Unix(0, math.MaxInt64))
+	ns, err = last.UnixNano()
+	require.NoError(t, err)
+	require.Equal(t, int64(math.MaxInt64), ns)
+	last.Nanoseconds++
+	_, err = last.UnixNano()
+	require.ErrorIs(t, err, ErrTimestampUnixNano)
+
+	far := Timestamp{Seconds: PTPSeconds{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}}
+	_, err = far.UnixNano()
+	require.ErrorIs(t, err, ErrTimestampUnixNano)
+	// time.Time itself handles far future just fine
+	require.Equal(t, int64(MaxPTPSeconds), far.Time().Unix())
+
+	_, err = Timestamp{Nanoseconds: 1e9}.UnixNano()
+	require.ErrorIs(t, err, ErrTimestampNanoseconds)
+	require.NoError(t, Timestamp{Nanoseconds: 1e9 - 1}.Validate())
+}
+
 func TestCorrectionFromDuration(t *testing.T) {
 	tests := []struct {
 		in         time.Duration
@@ -346,6 +429,57 @@ func TestLogInterval(t *testing.T) {
 	}
 }
 
+func TestLogIntervalToDuration(t *testing.T) {
+	d, err := LogInterval(-3).ToDuration()
+	require.NoError(t, err)
+	require.Equal(t, 125*time.Millisecond, d)
+	d, err = MaxLogInterval.ToDuration()
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(1<<33)*time.Second, d)
+	d, err = MinLogInterval.ToDuration()
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), d)
+
+	_, err = LogIntervalUnspecified.ToDuration()
+	require.ErrorIs(t, err, ErrUnspecifiedLogInterval)
+	require.True(t, LogIntervalUnspecified.Unspecified())
+	_, err = LogInterval(34).ToDuration()
+	require.EqualError(t, err, "logInterval 34 is outside of [-30, 33] range")
+	_, err = LogInterval(-31).ToDuration()
+	require.Error(t, err)
+
+	// Duration saturates instead of overflowing
+	require.Equal(t, MaxLogInterval.Duration(), LogIntervalUnspecified.Duration())
+	require.Equal(t, time.Duration(0), LogInterval(-128).Duration())
+}
+
+func TestLogIntervalClamp(t *testing.T) {
+	require.Equal(t, LogInterval(-2), LogInterval(-7).Clamp(-2, 4))
+	require.Equal(t, LogInterval(4), LogIntervalUnspecified.Clamp(-2, 4))
+	require.Equal(t, LogInterval(1), LogInterval(1).Clamp(-2, 4))
+}
+
+func TestNewLogIntervalRounding(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want LogInterval
+	}{
+		{in: 3 * time.Second, want: 1},
+		{in: 300 * time.Millisecond, want: -1},
+		{in: time.Nanosecond, want: -29},
+		{in: time.Duration(math.MaxInt64), want: MaxLogInterval},
+	}
+	for _, tt := range tests {
+		got, err := NewLogInterval(tt.in)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, got, tt.in)
+	}
+	_, err := NewLogInterval(0)
+	require.Error(t, err)
+	_, err = NewLogInterval(-time.Second)
+	require.Error(t, err)
+}
+
 func TestClockIdentity(t *testing.T) {
 	macStr := "0c:42:a1:6d:7c:a6"
 	mac, err := net.ParseMAC(macStr)
@@ -81,7 +81,7 @@ func (p *Signaling) UnmarshalBinary(b []byte) error {
 
 	pos := headerSize + 10
 	var err error
-	p.TLVs, err = readTLVs(p.TLVs, int(p.MessageLength)-pos, b[pos:])
+	p.TLVs, err = readTLVs(p.TLVs[:0], int(p.MessageLength)-pos, b[pos:])
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,171 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// IEEE 1588-2002 (PTPv1) support. It's limited to what's needed to identify legacy devices:
+// header and timestamps, plus grandmaster info of Sync and Delay_Req.
+
+// VersionV1 is versionPTP of PTPv1 messages
+const VersionV1 uint8 = 1
+
+// v1HeaderSize is the size of PTPv1 header
+const v1HeaderSize = 40
+
+// V1Control is PTPv1 control field, which determines the message type
+type V1Control uint8
+
+// As per IEEE 1588-2002 Table 37 control field values
+const (
+	V1ControlSync       V1Control = 0
+	V1ControlDelayReq   V1Control = 1
+	V1ControlFollowUp   V1Control = 2
+	V1ControlDelayResp  V1Control = 3
+	V1ControlManagement V1Control = 4
+)
+
+// v1MessageType maps PTPv1 control values to corresponding PTPv2 message types
+var v1MessageType = map[V1Control]MessageType{
+	V1ControlSync:       MessageSync,
+	V1ControlDelayReq:   MessageDelayReq,
+	V1ControlFollowUp:   MessageFollowUp,
+	V1ControlDelayResp:  MessageDelayResp,
+	V1ControlManagement: MessageManagement,
+}
+
+// v1MessageSize is the minimal size of PTPv1 messages
+var v1MessageSize = map[V1Control]int{
+	V1ControlSync:       124,
+	V1ControlDelayReq:   124,
+	V1ControlFollowUp:   52,
+	V1ControlDelayResp:  60,
+	V1ControlManagement: v1HeaderSize,
+}
+
+func (c V1Control) String() string {
+	return v1MessageType[c].String()
+}
+
+// V1Header is IEEE 1588-2002 Table 18 common message header
+type V1Header struct {
+	VersionPTP                    uint16
+	VersionNetwork                uint16
+	Subdomain                     [16]byte
+	MessageClass                  uint8 // messageType field, 1 for event and 2 for general messages
+	SourceCommunicationTechnology uint8
+	SourceUUID                    [6]byte
+	SourcePortID                  uint16
+	SequenceID                    uint16
+	Control                       V1Control
+	Flags                         uint16
+}
+
+// SubdomainName returns subdomain with trailing zeros trimmed, like "_DFLT"
+func (h *V1Header) SubdomainName() string {
+	n := 0
+	for n < len(h.Subdomain) && h.Subdomain[n] != 0 {
+		n++
+	}
+	return string(h.Subdomain[:n])
+}
+
+// V1Packet is a minimally decoded PTPv1 message
+type V1Packet struct {
+	V1Header
+	// Timestamp is originTimestamp of Sync and Delay_Req, preciseOriginTimestamp of Follow_Up
+	// and delayReceiptTimestamp of Delay_Resp
+	Timestamp time.Time
+	// AssociatedSequenceID is set for Follow_Up
+	AssociatedSequenceID uint16
+	// Following fields are set for Sync and Delay_Req
+	CurrentUTCOffset           int16
+	GrandmasterUUID            [6]byte
+	GrandmasterClockStratum    uint8
+	GrandmasterClockIdentifier [4]byte
+	LocalStepsRemoved          uint16
+}
+
+// MessageType returns PTPv2 message type equivalent to PTPv1 control field
+func (p *V1Packet) MessageType() MessageType {
+	return v1MessageType[p.Control]
+}
+
+// SetSequence populates sequence field
+func (p *V1Packet) SetSequence(sequence uint16) {
+	p.SequenceID = sequence
+}
+
+// IsV1 reports whether b looks like PTPv1 message.
+// PTPv2 versionPTP nibble occupies the same place as lower bits of PTPv1 versionPTP.
+func IsV1(b []byte) bool {
+	return len(b) >= 2 && b[1]&MajorVersionMask == VersionV1
+}
+
+// v1Time converts PTPv1 seconds and nanoseconds to time
+func v1Time(b []byte) time.Time {
+	return time.Unix(int64(binary.BigEndian.Uint32(b)), int64(int32(binary.BigEndian.Uint32(b[4:]))))
+}
+
+// DecodeV1Packet decodes PTPv1 message
+func DecodeV1Packet(b []byte) (*V1Packet, error) {
+	if len(b) < v1HeaderSize {
+		return nil, fmt.Errorf("not enough data to decode PTPv1 header")
+	}
+	p := &V1Packet{}
+	p.VersionPTP = binary.BigEndian.Uint16(b[0:])
+	p.VersionNetwork = binary.BigEndian.Uint16(b[2:])
+	copy(p.Subdomain[:], b[4:20])
+	p.MessageClass = b[20]
+	p.SourceCommunicationTechnology = b[21]
+	copy(p.SourceUUID[:], b[22:28])
+	p.SourcePortID = binary.BigEndian.Uint16(b[28:])
+	p.SequenceID = binary.BigEndian.Uint16(b[30:])
+	p.Control = V1Control(b[32])
+	p.Flags = binary.BigEndian.Uint16(b[34:])
+
+	if p.VersionPTP != uint16(VersionV1) {
+		return nil, fmt.Errorf("not a PTPv1 message, versionPTP %d", p.VersionPTP)
+	}
+	size, ok := v1MessageSize[p.Control]
+	if !ok {
+		return nil, fmt.Errorf("unsupported PTPv1 control %d", p.Control)
+	}
+	if len(b) < size {
+		return nil, fmt.Errorf("not enough data to decode PTPv1 %s", p.Control)
+	}
+
+	switch p.Control {
+	case V1ControlSync, V1ControlDelayReq:
+		p.Timestamp = v1Time(b[40:])
+		p.CurrentUTCOffset = int16(binary.BigEndian.Uint16(b[50:]))
+		copy(p.GrandmasterUUID[:], b[54:60])
+		p.GrandmasterClockStratum = b[67]
+		copy(p.GrandmasterClockIdentifier[:], b[68:72])
+		p.LocalStepsRemoved = binary.BigEndian.Uint16(b[90:])
+	case V1ControlFollowUp:
+		p.AssociatedSequenceID = binary.BigEndian.Uint16(b[42:])
+		p.Timestamp = v1Time(b[44:])
+	case V1ControlDelayResp:
+		p.Timestamp = v1Time(b[40:])
+	}
+	return p, nil
+}
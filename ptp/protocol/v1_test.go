@@ -0,0 +1,135 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protocol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// v1Header returns PTPv1 header with given control field
+func v1Header(control V1Control, messageClass uint8) []byte {
+	return []byte{
+		0x00, 0x01, 0x00, 0x01, // versionPTP, versionNetwork
+		'_', 'D', 'F', 'L', 'T', 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // subdomain
+		messageClass, 0x01, // messageType, sourceCommunicationTechnology
+		0x00, 0x1b, 0x19, 0x00, 0x00, 0x01, // sourceUuid
+		0x00, 0x01, // sourcePortId
+		0x00, 0x2a, // sequenceId
+		byte(control), 0x00, // control, reserved
+		0x00, 0x08, // flags
+		0x00, 0x00, 0x00, 0x00, // reserved
+	}
+}
+
+func v1SyncRaw() []byte {
+	b := make([]byte, 124)
+	copy(b, v1Header(V1ControlSync, 1))
+	copy(b[40:], []byte{0x65, 0x53, 0xf1, 0x00, 0x00, 0x00, 0x03, 0xe8}) // originTimestamp
+	copy(b[50:], []byte{0x00, 0x25})                                     // currentUTCOffset
+	copy(b[54:], []byte{0x00, 0x1b, 0x19, 0x00, 0x00, 0x02})             // grandmasterClockUuid
+	b[67] = 1                                                            // grandmasterClockStratum
+	copy(b[68:], "GPS\x00")                                              // grandmasterClockIdentifier
+	copy(b[90:], []byte{0x00, 0x02})                                     // localStepsRemoved
+	return b
+}
+
+func TestIsV1(t *testing.T) {
+	require.True(t, IsV1(v1SyncRaw()))
+	require.False(t, IsV1(announcePathTraceRaw))
+	require.False(t, IsV1([]byte{0}))
+}
+
+func TestDecodeV1Sync(t *testing.T) {
+	want := &V1Packet{
+		V1Header: V1Header{
+			VersionPTP:                    1,
+			VersionNetwork:                1,
+			Subdomain:                     [16]byte{'_', 'D', 'F', 'L', 'T'},
+			MessageClass:                  1,
+			SourceCommunicationTechnology: 1,
+			SourceUUID:                    [6]byte{0x00, 0x1b, 0x19, 0x00, 0x00, 0x01},
+			SourcePortID:                  1,
+			SequenceID:                    42,
+			Control:                       V1ControlSync,
+			Flags:                         8,
+		},
+		Timestamp:                  time.Unix(1700000000, 1000),
+		CurrentUTCOffset:           37,
+		GrandmasterUUID:            [6]byte{0x00, 0x1b, 0x19, 0x00, 0x00, 0x02},
+		GrandmasterClockStratum:    1,
+		GrandmasterClockIdentifier: [4]byte{'G', 'P', 'S', 0},
+		LocalStepsRemoved:          2,
+	}
+	for name, decode := range map[string]func([]byte) (Packet, error){
+		"DecodeV1Packet": func(b []byte) (Packet, error) { return DecodeV1Packet(b) },
+		"DecodePacket":   DecodePacket,
+		"ParsePacket":    ParsePacket,
+	} {
+		t.Run(name, func(t *testing.T) {
+			p, err := decode(v1SyncRaw())
+			require.NoError(t, err)
+			require.Equal(t, want, p)
+			require.Equal(t, MessageSync, p.MessageType())
+		})
+	}
+	require.Equal(t, "_DFLT", want.SubdomainName())
+	require.Equal(t, "SYNC", V1ControlSync.String())
+	want.SetSequence(7)
+	require.Equal(t, uint16(7), want.SequenceID)
+}
+
+func TestDecodeV1FollowUpDelayResp(t *testing.T) {
+	b := append(v1Header(V1ControlFollowUp, 2), 0x00, 0x00, 0x00, 0x29, 0x65, 0x53, 0xf1, 0x00, 0x00, 0x00, 0x00, 0x01)
+	p, err := DecodeV1Packet(b)
+	require.NoError(t, err)
+	require.Equal(t, MessageFollowUp, p.MessageType())
+	require.Equal(t, uint16(41), p.AssociatedSequenceID)
+	require.Equal(t, time.Unix(1700000000, 1), p.Timestamp)
+
+	b = append(v1Header(V1ControlDelayResp, 2), 0x65, 0x53, 0xf1, 0x00, 0x00, 0x00, 0x00, 0x02)
+	b = append(b, make([]byte, 12)...)
+	p, err = DecodeV1Packet(b)
+	require.NoError(t, err)
+	require.Equal(t, MessageDelayResp, p.MessageType())
+	require.Equal(t, time.Unix(1700000000, 2), p.Timestamp)
+
+	p, err = DecodeV1Packet(v1Header(V1ControlManagement, 2))
+	require.NoError(t, err)
+	require.Equal(t, MessageManagement, p.MessageType())
+}
+
+func TestDecodeV1PacketErrors(t *testing.T) {
+	_, err := DecodeV1Packet(v1SyncRaw()[:39])
+	require.ErrorContains(t, err, "not enough data to decode PTPv1 header")
+
+	_, err = DecodeV1Packet(v1SyncRaw()[:100])
+	require.ErrorContains(t, err, "not enough data to decode PTPv1 SYNC")
+
+	_, err = DecodeV1Packet(v1Header(5, 2))
+	require.ErrorContains(t, err, "unsupported PTPv1 control 5")
+
+	b := v1SyncRaw()
+	b[0] = 0x0b
+	_, err = DecodeV1Packet(b)
+	require.ErrorContains(t, err, "not a PTPv1 message")
+
+	_, err = ParsePacket(v1SyncRaw()[:100])
+	require.ErrorIs(t, err, ErrMalformed)
+}
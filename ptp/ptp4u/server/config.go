@@ -42,6 +42,7 @@ var dcMux = sync.Mutex{}
 
 // StaticConfig is a set of static options which require a server restart
 type StaticConfig struct {
+	BusyPoll        timestamp.BusyPoll
 	ConfigFile      string
 	DebugAddr       string
 	DomainNumber    uint
@@ -51,9 +52,11 @@ type StaticConfig struct {
 	IP              net.IP
 	LogLevel        string
 	MonitoringPort  int
+	OneStep         bool
 	PidFile         string
 	QueueSize       int
 	RecvWorkers     int
+	SdoID           uint
 	SendWorkers     int
 	TimestampType   timestamp.Timestamp
 	UndrainFileName string
@@ -124,6 +127,15 @@ func (dc *DynamicConfig) Write(path string) error {
 	return os.WriteFile(path, d, 0644)
 }
 
+// enableTimestamps enables timestamps on the event socket.
+// One-step Sync carries the timestamp NIC puts into it on the fly, so NIC must support it
+func (c *StaticConfig) enableTimestamps(connFd int) error {
+	if c.OneStep {
+		return timestamp.EnableHWTimestampsTXType(connFd, c.Interface, timestamp.TXTypeOneStepSync)
+	}
+	return timestamp.EnableTimestamps(c.TimestampType, connFd, c.Interface)
+}
+
 // IfaceHasIP checks if selected IP is on interface
 func (c *Config) IfaceHasIP() (bool, error) {
 	ips, err := ifaceIPs(c.Interface)
@@ -22,6 +22,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/facebook/time/timestamp"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sys/unix"
 )
@@ -202,3 +203,18 @@ func TestPidFile(t *testing.T) {
 	require.NoError(t, err)
 	require.NoFileExists(t, c.PidFile)
 }
+
+func TestEnableTimestamps(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0})
+	require.NoError(t, err)
+	defer conn.Close()
+	connFd, err := timestamp.ConnFd(conn)
+	require.NoError(t, err)
+
+	c := &StaticConfig{TimestampType: timestamp.SW, Interface: "lo"}
+	require.NoError(t, c.enableTimestamps(connFd))
+
+	// loopback doesn't support one-step, and we don't fall back to two-step or software timestamps
+	c = &StaticConfig{TimestampType: timestamp.HW, Interface: "lo", OneStep: true}
+	require.ErrorContains(t, c.enableTimestamps(connFd), "tx type onestep_sync is not supported")
+}
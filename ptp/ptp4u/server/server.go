@@ -182,10 +182,16 @@ func (s *Server) startEventListener() {
 	}
 
 	// Enable RX timestamps. Delay requests need to be timestamped by ptp4u on receipt
-	if err := timestamp.EnableTimestamps(s.Config.TimestampType, s.eFd, s.Config.Interface); err != nil {
+	if err := s.Config.enableTimestamps(s.eFd); err != nil {
 		log.Fatal(err)
 	}
 
+	if s.Config.BusyPoll.Timeout > 0 {
+		if err := timestamp.EnableBusyPoll(s.eFd, s.Config.BusyPoll); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	err = unix.SetNonblock(s.eFd, false)
 	if err != nil {
 		log.Fatalf("Failed to set socket to blocking: %s", err)
@@ -255,7 +261,6 @@ func (s *Server) handleEventMessages(eventConn *net.UDPConn) {
 	buf := make([]byte, timestamp.PayloadSizeBytes)
 	oob := make([]byte, timestamp.ControlSizeBytes)
 	dReq := &ptp.SyncDelayReq{}
-	zerotlv := []ptp.TLV{}
 	// Initialize the new random. We will re-seed it every time in findWorker
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	var msgType ptp.MessageType
@@ -281,6 +286,11 @@ func (s *Server) handleEventMessages(eventConn *net.UDPConn) {
 			continue
 		}
 
+		if !ptp.MatchSdoID(buf[:bbuf], ptp.SdoID(s.Config.SdoID)) {
+			log.Debugf("Ignoring %s from another PTP instance", msgType)
+			continue
+		}
+
 		s.Stats.IncRX(msgType)
 
 		// Don't respond on event (delay) requests while being drained
@@ -290,7 +300,6 @@ func (s *Server) handleEventMessages(eventConn *net.UDPConn) {
 
 		switch msgType {
 		case ptp.MessageDelayReq:
-			dReq.TLVs = zerotlv
 			if err := ptp.FromBytes(buf[:bbuf], dReq); err != nil {
 				log.Errorf("Failed to read the ptp SyncDelayReq: %v", err)
 				continue
@@ -352,7 +361,6 @@ func (s *Server) handleEventMessages(eventConn *net.UDPConn) {
 func (s *Server) handleGeneralMessages(generalConn *net.UDPConn) {
 	buf := make([]byte, timestamp.PayloadSizeBytes)
 	signaling := &ptp.Signaling{}
-	zerotlv := []ptp.TLV{}
 	// Initialize the new random. We will re-seed it every time in findWorker
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 
@@ -376,9 +384,13 @@ func (s *Server) handleGeneralMessages(generalConn *net.UDPConn) {
 			continue
 		}
 
+		if !ptp.MatchSdoID(buf[:bbuf], ptp.SdoID(s.Config.SdoID)) {
+			log.Debugf("Ignoring %s from another PTP instance", msgType)
+			continue
+		}
+
 		switch msgType {
 		case ptp.MessageSignaling:
-			signaling.TLVs = zerotlv
 			if err := ptp.FromBytes(buf[:bbuf], signaling); err != nil {
 				log.Error(err)
 				continue
@@ -392,7 +404,11 @@ func (s *Server) handleGeneralMessages(generalConn *net.UDPConn) {
 					log.Debugf("Got %s grant request", signalingType)
 					durationt = time.Duration(v.DurationField) * time.Second
 					expire = time.Now().Add(durationt)
-					intervalt = v.LogInterMessagePeriod.Duration()
+					intervalt, err = v.LogInterMessagePeriod.ToDuration()
+					if err != nil {
+						log.Errorf("Got %s grant request with invalid interval: %v", signalingType, err)
+						continue
+					}
 
 					switch signalingType {
 					case ptp.MessageAnnounce, ptp.MessageSync, ptp.MessageDelayResp:
@@ -209,15 +209,21 @@ func (sc *SubscriptionClient) initSync() {
 				PortNumber:    1,
 				ClockIdentity: sc.serverConfig.clockIdentity,
 			},
-			LogMessageInterval: 0x7f,
+			LogMessageInterval: ptp.LogIntervalUnspecified,
 			ControlField:       0,
 		},
 	}
+	sc.syncP.SetSdoID(ptp.SdoID(sc.serverConfig.SdoID))
 }
 
 // UpdateSync updates ptp Sync packet
 func (sc *SubscriptionClient) UpdateSync() {
 	sc.syncP.SequenceID = sc.sequenceID
+	if sc.serverConfig.OneStep {
+		// NIC overwrites the origin timestamp on the fly only if two-step flag is not set
+		sc.syncP.FlagField = ptp.FlagUnicast
+		sc.syncP.OriginTimestamp = ptp.NewTimestamp(time.Now().Add(sc.serverConfig.UTCOffset))
+	}
 }
 
 // UpdateSyncDelayReq updates ptp SyncDelayReq packet
@@ -251,6 +257,7 @@ func (sc *SubscriptionClient) initFollowup() {
 			PreciseOriginTimestamp: ptp.NewTimestamp(time.Now()),
 		},
 	}
+	sc.followupP.SetSdoID(ptp.SdoID(sc.serverConfig.SdoID))
 }
 
 // UpdateFollowup updates ptp Follow Up packet
@@ -297,6 +304,7 @@ func (sc *SubscriptionClient) initAnnounce() {
 			TimeSource:           ptp.TimeSourceGNSS,
 		},
 	}
+	sc.announceP.SetSdoID(ptp.SdoID(sc.serverConfig.SdoID))
 }
 
 // UpdateAnnounce updates ptp Announce packet
@@ -341,12 +349,13 @@ func (sc *SubscriptionClient) initDelayResp() {
 				PortNumber:    1,
 				ClockIdentity: sc.serverConfig.clockIdentity,
 			},
-			LogMessageInterval: 0x7f,
+			LogMessageInterval: ptp.LogIntervalUnspecified,
 			ControlField:       3,
 			CorrectionField:    0,
 		},
 		DelayRespBody: ptp.DelayRespBody{},
 	}
+	sc.delayRespP.SetSdoID(ptp.SdoID(sc.serverConfig.SdoID))
 }
 
 // UpdateDelayResp updates ptp Delay Response packet
@@ -149,6 +149,18 @@ func TestSubscriptionflags(t *testing.T) {
 	require.Equal(t, ptp.FlagUnicast|ptp.FlagPTPTimescale, sc.Announce().Header.FlagField)
 }
 
+func TestSubscriptionflagsOneStep(t *testing.T) {
+	w := &sendWorker{}
+	c := &Config{clockIdentity: ptp.ClockIdentity(1234), StaticConfig: StaticConfig{OneStep: true}, DynamicConfig: DynamicConfig{UTCOffset: 37 * time.Second}}
+	sa := timestamp.IPToSockaddr(net.ParseIP("127.0.0.1"), 123)
+	sc := NewSubscriptionClient(w.queue, w.signalingQueue, sa, sa, ptp.MessageSync, c, time.Second, time.Time{})
+
+	start := time.Now()
+	sc.UpdateSync()
+	require.Equal(t, ptp.FlagUnicast, sc.Sync().Header.FlagField)
+	require.False(t, sc.Sync().OriginTimestamp.Time().Before(start.Add(37*time.Second).Truncate(time.Nanosecond)))
+}
+
 func TestSyncPacket(t *testing.T) {
 	sequenceID := uint16(42)
 	domainNumber := uint8(13)
@@ -172,6 +184,31 @@ func TestSyncPacket(t *testing.T) {
 	require.Equal(t, domainNumber, sc.Sync().Header.DomainNumber)
 }
 
+func TestPacketsSdoID(t *testing.T) {
+	w := &sendWorker{}
+	c := &Config{
+		clockIdentity: ptp.ClockIdentity(1234),
+		StaticConfig: StaticConfig{
+			SdoID: 0x123,
+		},
+	}
+	sa := timestamp.IPToSockaddr(net.ParseIP("127.0.0.1"), 123)
+	sc := NewSubscriptionClient(w.queue, w.signalingQueue, sa, sa, ptp.MessageAnnounce, c, time.Second, time.Time{})
+
+	sc.initSync()
+	sc.initFollowup()
+	sc.initAnnounce()
+	sc.initDelayResp()
+	require.Equal(t, ptp.SdoID(0x123), sc.Sync().SdoID())
+	require.Equal(t, ptp.MessageSync, sc.Sync().MessageType())
+	require.Equal(t, ptp.SdoID(0x123), sc.Followup().SdoID())
+	require.Equal(t, ptp.MessageFollowUp, sc.Followup().MessageType())
+	require.Equal(t, ptp.SdoID(0x123), sc.Announce().SdoID())
+	require.Equal(t, ptp.MessageAnnounce, sc.Announce().MessageType())
+	require.Equal(t, ptp.SdoID(0x123), sc.DelayResp().SdoID())
+	require.Equal(t, ptp.MessageDelayResp, sc.DelayResp().MessageType())
+}
+
 func TestSyncDelayReqPacket(t *testing.T) {
 	sequenceID := uint16(42)
 	domainNumber := uint8(13)
@@ -98,8 +98,9 @@ func (s *sendWorker) listen() (eventFD, generalFD int, err error) {
 		return -1, -1, fmt.Errorf("setting DSCP on event socket: %w", err)
 	}
 
-	// Syncs sent from event port, so need to turn on timestamping here
-	if err := timestamp.EnableTimestamps(s.config.TimestampType, eventFD, s.config.Interface); err != nil {
+	// Syncs sent from event port, so need to turn on timestamping here.
+	// No fallback to software timestamps: serving system time as PTP time is never accurate enough
+	if err := s.config.enableTimestamps(eventFD); err != nil {
 		return -1, -1, err
 	}
 
@@ -167,6 +168,10 @@ func (s *sendWorker) Start() {
 					continue
 				}
 				s.stats.IncTX(c.subscriptionType)
+				if s.config.OneStep {
+					// TX timestamp is already in the sync, no need for followup
+					break
+				}
 
 				txTS, attempts, err = timestamp.ReadTXtimestampBuf(eFd, oob, toob)
 				s.stats.SetMaxTXTSAttempts(s.id, int64(attempts))
@@ -222,10 +222,14 @@ func (c *Client) setup(ctx context.Context, eg *errgroup.Group) error {
 		return err
 	}
 
-	// we need to enable HW or SW timestamps on event port
-	if err := timestamp.EnableTimestamps(c.cfg.Timestamping, connFd, c.cfg.Iface); err != nil {
+	// we need to enable HW or SW timestamps on event port, SW ones are used if HW are not available
+	ts, err := timestamp.NegotiateTimestamps(c.cfg.Timestamping, connFd, c.cfg.Iface)
+	if err != nil {
 		return err
 	}
+	if ts != c.cfg.Timestamping {
+		log.Warningf("%s timestamps are not available on %s, falling back to %s", c.cfg.Timestamping, c.cfg.Iface, ts)
+	}
 
 	// set it to blocking mode, otherwise recvmsg will just return with nothing most of the time
 	if err := unix.SetNonblock(connFd, false); err != nil {
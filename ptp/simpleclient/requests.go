@@ -37,7 +37,7 @@ func reqUnicast(clockID ptp.ClockIdentity, duration time.Duration, what ptp.Mess
 				PortNumber:    1,
 				ClockIdentity: clockID,
 			},
-			LogMessageInterval: 0x7f,
+			LogMessageInterval: ptp.LogIntervalUnspecified,
 		},
 		TargetPortIdentity: ptp.PortIdentity{
 			PortNumber:    0xffff,
@@ -71,7 +71,7 @@ func reqAckCancelUnicast(clockID ptp.ClockIdentity, what ptp.MessageType) *ptp.S
 				PortNumber:    1,
 				ClockIdentity: clockID,
 			},
-			LogMessageInterval: 0x7f,
+			LogMessageInterval: ptp.LogIntervalUnspecified,
 		},
 		TargetPortIdentity: ptp.PortIdentity{
 			PortNumber:    0xffff,
@@ -102,7 +102,7 @@ func reqDelay(clockID ptp.ClockIdentity) *ptp.SyncDelayReq {
 				PortNumber:    1,
 				ClockIdentity: clockID,
 			},
-			LogMessageInterval: 0x7f,
+			LogMessageInterval: ptp.LogIntervalUnspecified,
 		},
 	}
 }
@@ -36,6 +36,16 @@ const (
 	BBetterTopo ComparisonResult = -2
 )
 
+// ABetter returns true if A is better, either by Announce Response or by topology
+func (r ComparisonResult) ABetter() bool {
+	return r == ABetter || r == ABetterTopo
+}
+
+// BBetter returns true if B is better, either by Announce Response or by topology
+func (r ComparisonResult) BBetter() bool {
+	return r == BBetter || r == BBetterTopo
+}
+
 // Dscmp2 finds better Announce based on network topology
 func Dscmp2(a, b *ptp.Announce) ComparisonResult {
 	if a.AnnounceBody.StepsRemoved+1 < b.AnnounceBody.StepsRemoved {
@@ -58,28 +68,36 @@ func Dscmp2(a, b *ptp.Announce) ComparisonResult {
 
 // Base comparison on all attributes
 func dscmp(a *ptp.Announce, b *ptp.Announce) ComparisonResult {
-	if a.AnnounceBody.GrandmasterClockQuality.ClockClass < b.AnnounceBody.GrandmasterClockQuality.ClockClass {
+	return compareQuality(
+		a.AnnounceBody.GrandmasterClockQuality, b.AnnounceBody.GrandmasterClockQuality,
+		a.AnnounceBody.GrandmasterPriority2, b.AnnounceBody.GrandmasterPriority2,
+	)
+}
+
+// compareQuality compares clock quality attributes followed by priority2
+func compareQuality(qa, qb ptp.ClockQuality, priority2A, priority2B uint8) ComparisonResult {
+	if qa.ClockClass < qb.ClockClass {
 		return ABetter
 	}
-	if a.AnnounceBody.GrandmasterClockQuality.ClockClass > b.AnnounceBody.GrandmasterClockQuality.ClockClass {
+	if qa.ClockClass > qb.ClockClass {
 		return BBetter
 	}
-	if a.AnnounceBody.GrandmasterClockQuality.ClockAccuracy < b.AnnounceBody.GrandmasterClockQuality.ClockAccuracy {
+	if qa.ClockAccuracy < qb.ClockAccuracy {
 		return ABetter
 	}
-	if a.AnnounceBody.GrandmasterClockQuality.ClockAccuracy > b.AnnounceBody.GrandmasterClockQuality.ClockAccuracy {
+	if qa.ClockAccuracy > qb.ClockAccuracy {
 		return BBetter
 	}
-	if a.AnnounceBody.GrandmasterClockQuality.OffsetScaledLogVariance < b.AnnounceBody.GrandmasterClockQuality.OffsetScaledLogVariance {
+	if qa.OffsetScaledLogVariance < qb.OffsetScaledLogVariance {
 		return ABetter
 	}
-	if a.AnnounceBody.GrandmasterClockQuality.OffsetScaledLogVariance > b.AnnounceBody.GrandmasterClockQuality.OffsetScaledLogVariance {
+	if qa.OffsetScaledLogVariance > qb.OffsetScaledLogVariance {
 		return BBetter
 	}
-	if a.AnnounceBody.GrandmasterPriority2 < b.AnnounceBody.GrandmasterPriority2 {
+	if priority2A < priority2B {
 		return ABetter
 	}
-	if a.AnnounceBody.GrandmasterPriority2 > b.AnnounceBody.GrandmasterPriority2 {
+	if priority2A > priority2B {
 		return BBetter
 	}
 
@@ -114,3 +114,14 @@ func TestTelcoDscmp(t *testing.T) {
 	require.Equal(t, TelcoDscmp(&lp1, &lp2, 1, 2), ABetter)
 	require.Equal(t, TelcoDscmp(&lp1, &lp2, 2, 1), BBetter)
 }
+
+func TestComparisonResult(t *testing.T) {
+	require.True(t, ABetterTopo.ABetter())
+	require.True(t, ABetter.ABetter())
+	require.False(t, Unknown.ABetter())
+	require.False(t, Unknown.BBetter())
+	require.True(t, BBetter.BBetter())
+	require.True(t, BBetterTopo.BBetter())
+	require.False(t, BBetter.ABetter())
+	require.False(t, ABetterTopo.BBetter())
+}
@@ -0,0 +1,142 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bmc
+
+import (
+	"slices"
+
+	ptp "github.com/facebook/time/ptp/protocol"
+)
+
+// Dataset holds the attributes used by the IEEE 1588-2019 dataset comparison algorithm, as listed in 9.3.4 Table 30
+type Dataset struct {
+	GrandmasterPriority1    uint8
+	GrandmasterIdentity     ptp.ClockIdentity
+	GrandmasterClockQuality ptp.ClockQuality
+	GrandmasterPriority2    uint8
+	StepsRemoved            uint16
+	// SenderIdentity is the sourcePortIdentity of the Announce message
+	SenderIdentity ptp.PortIdentity
+	// ReceiverIdentity is the portIdentity of the port which received the Announce message
+	ReceiverIdentity ptp.PortIdentity
+}
+
+// DatasetFromAnnounce builds Dataset from Announce message received by the port with given identity
+func DatasetFromAnnounce(a *ptp.Announce, receiver ptp.PortIdentity) Dataset {
+	return Dataset{
+		GrandmasterPriority1:    a.GrandmasterPriority1,
+		GrandmasterIdentity:     a.GrandmasterIdentity,
+		GrandmasterClockQuality: a.GrandmasterClockQuality,
+		GrandmasterPriority2:    a.GrandmasterPriority2,
+		StepsRemoved:            a.StepsRemoved,
+		SenderIdentity:          a.SourcePortIdentity,
+		ReceiverIdentity:        receiver,
+	}
+}
+
+// Compare compares two datasets as described in 9.3.4 Figure 34 and Figure 35.
+// Unknown is returned for the error cases of Figure 35: message sent and received by the same port, or a duplicate.
+func Compare(a, b *Dataset) ComparisonResult {
+	if a.GrandmasterIdentity == b.GrandmasterIdentity {
+		return compareTopology(a, b)
+	}
+	if a.GrandmasterPriority1 < b.GrandmasterPriority1 {
+		return ABetter
+	}
+	if a.GrandmasterPriority1 > b.GrandmasterPriority1 {
+		return BBetter
+	}
+	if cr := compareQuality(a.GrandmasterClockQuality, b.GrandmasterClockQuality, a.GrandmasterPriority2, b.GrandmasterPriority2); cr != Unknown {
+		return cr
+	}
+	if a.GrandmasterIdentity < b.GrandmasterIdentity {
+		return ABetter
+	}
+	return BBetter
+}
+
+// compareTopology compares datasets describing the same grandmaster, Figure 35
+func compareTopology(a, b *Dataset) ComparisonResult {
+	stepsA, stepsB := int(a.StepsRemoved), int(b.StepsRemoved)
+	switch {
+	case stepsA > stepsB+1:
+		return BBetter
+	case stepsA+1 < stepsB:
+		return ABetter
+	case stepsA > stepsB:
+		switch a.ReceiverIdentity.Compare(a.SenderIdentity) {
+		case -1:
+			return BBetter
+		case 1:
+			return BBetterTopo
+		default:
+			return Unknown
+		}
+	case stepsA < stepsB:
+		switch b.ReceiverIdentity.Compare(b.SenderIdentity) {
+		case -1:
+			return ABetter
+		case 1:
+			return ABetterTopo
+		default:
+			return Unknown
+		}
+	}
+	switch a.SenderIdentity.Compare(b.SenderIdentity) {
+	case -1:
+		return ABetterTopo
+	case 1:
+		return BBetterTopo
+	}
+	switch {
+	case a.ReceiverIdentity.PortNumber < b.ReceiverIdentity.PortNumber:
+		return ABetterTopo
+	case a.ReceiverIdentity.PortNumber > b.ReceiverIdentity.PortNumber:
+		return BBetterTopo
+	}
+	return Unknown
+}
+
+// Sort sorts datasets from the best to the worst. Datasets which can't be ordered keep their relative order.
+func Sort(datasets []Dataset) {
+	slices.SortStableFunc(datasets, func(a, b Dataset) int {
+		return compareOrder(&a, &b)
+	})
+}
+
+// compareOrder is Compare usable for sorting, best first
+func compareOrder(a, b *Dataset) int {
+	r := Compare(a, b)
+	switch {
+	case r.ABetter():
+		return -1
+	case r.BBetter():
+		return 1
+	}
+	return 0
+}
+
+// Best returns index of the best dataset, or -1 if there are none
+func Best(datasets []Dataset) int {
+	best := -1
+	for i := range datasets {
+		if best == -1 || Compare(&datasets[i], &datasets[best]).ABetter() {
+			best = i
+		}
+	}
+	return best
+}
@@ -0,0 +1,218 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bmc
+
+import (
+	"testing"
+
+	ptp "github.com/facebook/time/ptp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func gm(id ptp.ClockIdentity) Dataset {
+	return Dataset{
+		GrandmasterPriority1: 128,
+		GrandmasterIdentity:  id,
+		GrandmasterClockQuality: ptp.ClockQuality{
+			ClockClass:              ptp.ClockClass6,
+			ClockAccuracy:           ptp.ClockAccuracyNanosecond100,
+			OffsetScaledLogVariance: 0x4e5d,
+		},
+		GrandmasterPriority2: 128,
+		SenderIdentity:       ptp.PortIdentity{ClockIdentity: id, PortNumber: 1},
+		ReceiverIdentity:     ptp.PortIdentity{ClockIdentity: 0xaa, PortNumber: 1},
+	}
+}
+
+func TestCompareGrandmasters(t *testing.T) {
+	tests := []struct {
+		name string
+		b    func(d *Dataset)
+		want ComparisonResult
+	}{
+		{name: "priority1", b: func(d *Dataset) { d.GrandmasterPriority1 = 127 }, want: BBetter},
+		{name: "clock class", b: func(d *Dataset) { d.GrandmasterClockQuality.ClockClass = ptp.ClockClass7 }, want: ABetter},
+		{name: "clock accuracy", b: func(d *Dataset) { d.GrandmasterClockQuality.ClockAccuracy = ptp.ClockAccuracyNanosecond25 }, want: BBetter},
+		{name: "variance", b: func(d *Dataset) { d.GrandmasterClockQuality.OffsetScaledLogVariance = 0xffff }, want: ABetter},
+		{name: "priority2", b: func(d *Dataset) { d.GrandmasterPriority2 = 129 }, want: ABetter},
+		// identity 2 is worse than 1, unless attributes which go first say otherwise
+		{name: "identity", b: func(_ *Dataset) {}, want: ABetter},
+		{name: "priority1 wins over clock class", b: func(d *Dataset) {
+			d.GrandmasterPriority1 = 1
+			d.GrandmasterClockQuality.ClockClass = ptp.ClockClass52
+		}, want: BBetter},
+		{name: "steps removed is ignored for different grandmasters", b: func(d *Dataset) { d.StepsRemoved = 0; d.GrandmasterPriority2 = 127 }, want: BBetter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := gm(1)
+			a.StepsRemoved = 5
+			b := gm(2)
+			tt.b(&b)
+			require.Equal(t, tt.want, Compare(&a, &b))
+			require.Equal(t, -tt.want, Compare(&b, &a))
+		})
+	}
+}
+
+func TestCompareTopology(t *testing.T) {
+	port := func(id ptp.ClockIdentity, n uint16) ptp.PortIdentity {
+		return ptp.PortIdentity{ClockIdentity: id, PortNumber: n}
+	}
+	tests := []struct {
+		name string
+		a, b func(d *Dataset)
+		want ComparisonResult
+	}{
+		{
+			name: "A is 2 steps further",
+			a:    func(d *Dataset) { d.StepsRemoved = 3 },
+			b:    func(d *Dataset) { d.StepsRemoved = 1 },
+			want: BBetter,
+		},
+		{
+			name: "B is 2 steps further",
+			a:    func(d *Dataset) { d.StepsRemoved = 1 },
+			b:    func(d *Dataset) { d.StepsRemoved = 3 },
+			want: ABetter,
+		},
+		{
+			name: "A is 1 step further, receiver < sender",
+			a: func(d *Dataset) {
+				d.StepsRemoved = 2
+				d.SenderIdentity = port(0xff, 1)
+				d.ReceiverIdentity = port(0x10, 1)
+			},
+			b:    func(d *Dataset) { d.StepsRemoved = 1 },
+			want: BBetter,
+		},
+		{
+			name: "A is 1 step further, receiver > sender",
+			a: func(d *Dataset) {
+				d.StepsRemoved = 2
+				d.SenderIdentity = port(0x10, 1)
+				d.ReceiverIdentity = port(0xff, 1)
+			},
+			b:    func(d *Dataset) { d.StepsRemoved = 1 },
+			want: BBetterTopo,
+		},
+		{
+			name: "A is 1 step further, sent by receiver",
+			a: func(d *Dataset) {
+				d.StepsRemoved = 2
+				d.SenderIdentity = port(0x10, 1)
+				d.ReceiverIdentity = port(0x10, 1)
+			},
+			b:    func(d *Dataset) { d.StepsRemoved = 1 },
+			want: Unknown,
+		},
+		{
+			name: "B is 1 step further, receiver < sender",
+			a:    func(d *Dataset) { d.StepsRemoved = 1 },
+			b: func(d *Dataset) {
+				d.StepsRemoved = 2
+				d.SenderIdentity = port(0xff, 1)
+				d.ReceiverIdentity = port(0x10, 1)
+			},
+			want: ABetter,
+		},
+		{
+			name: "B is 1 step further, receiver > sender",
+			a:    func(d *Dataset) { d.StepsRemoved = 1 },
+			b: func(d *Dataset) {
+				d.StepsRemoved = 2
+				d.SenderIdentity = port(0x10, 1)
+				d.ReceiverIdentity = port(0xff, 1)
+			},
+			want: ABetterTopo,
+		},
+		{
+			name: "same steps, sender identity",
+			a:    func(d *Dataset) { d.SenderIdentity = port(0x20, 1) },
+			b:    func(d *Dataset) { d.SenderIdentity = port(0x10, 1) },
+			want: BBetterTopo,
+		},
+		{
+			name: "same steps, sender port number",
+			a:    func(d *Dataset) { d.SenderIdentity = port(0x10, 1) },
+			b:    func(d *Dataset) { d.SenderIdentity = port(0x10, 2) },
+			want: ABetterTopo,
+		},
+		{
+			name: "same sender, receiver port number",
+			a:    func(d *Dataset) { d.ReceiverIdentity = port(0xaa, 2) },
+			b:    func(d *Dataset) { d.ReceiverIdentity = port(0xaa, 1) },
+			want: BBetterTopo,
+		},
+		{
+			name: "duplicate",
+			a:    func(_ *Dataset) {},
+			b:    func(_ *Dataset) {},
+			want: Unknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := gm(1)
+			b := gm(1)
+			tt.a(&a)
+			tt.b(&b)
+			require.Equal(t, tt.want, Compare(&a, &b))
+		})
+	}
+}
+
+func TestDatasetFromAnnounce(t *testing.T) {
+	a := &ptp.Announce{
+		Header: ptp.Header{SourcePortIdentity: ptp.PortIdentity{ClockIdentity: 2, PortNumber: 3}},
+		AnnounceBody: ptp.AnnounceBody{
+			GrandmasterPriority1:    1,
+			GrandmasterClockQuality: ptp.ClockQuality{ClockClass: ptp.ClockClass7, ClockAccuracy: ptp.ClockAccuracyMicrosecond1, OffsetScaledLogVariance: 42},
+			GrandmasterPriority2:    2,
+			GrandmasterIdentity:     1,
+			StepsRemoved:            4,
+		},
+	}
+	receiver := ptp.PortIdentity{ClockIdentity: 5, PortNumber: 1}
+	want := Dataset{
+		GrandmasterPriority1:    1,
+		GrandmasterIdentity:     1,
+		GrandmasterClockQuality: ptp.ClockQuality{ClockClass: ptp.ClockClass7, ClockAccuracy: ptp.ClockAccuracyMicrosecond1, OffsetScaledLogVariance: 42},
+		GrandmasterPriority2:    2,
+		StepsRemoved:            4,
+		SenderIdentity:          ptp.PortIdentity{ClockIdentity: 2, PortNumber: 3},
+		ReceiverIdentity:        receiver,
+	}
+	require.Equal(t, want, DatasetFromAnnounce(a, receiver))
+}
+
+func TestSortAndBest(t *testing.T) {
+	require.Equal(t, -1, Best(nil))
+
+	worse := gm(3)
+	worse.GrandmasterClockQuality.ClockClass = ptp.ClockClass52
+	preferred := gm(4)
+	preferred.GrandmasterPriority1 = 1
+	closer := gm(1)
+	further := gm(1)
+	further.StepsRemoved = 2
+	datasets := []Dataset{worse, further, gm(2), preferred, closer}
+
+	require.Equal(t, 3, Best(datasets))
+	Sort(datasets)
+	require.Equal(t, []Dataset{preferred, closer, further, gm(2), worse}, datasets)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bmc
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	ptp "github.com/facebook/time/ptp/protocol"
+)
+
+const (
+	// ForeignMasterThreshold is the number of Announce messages within ForeignMasterTimeWindow to qualify a foreign master, 9.3.2.4.6
+	ForeignMasterThreshold = 2
+	// ForeignMasterTimeWindow is the qualification window in announce intervals, 9.3.2.4.6
+	ForeignMasterTimeWindow = 4
+	// maxStepsRemoved is the stepsRemoved from which Announce messages are not qualified, 9.3.2.5 d)
+	maxStepsRemoved = 255
+)
+
+// Reasons for Announce to be rejected by ForeignMasterDataset.Add
+var (
+	ErrOwnAnnounce   = errors.New("announce is sent by this clock")
+	ErrStepsRemoved  = errors.New("announce stepsRemoved is 255 or greater")
+	ErrStaleAnnounce = errors.New("announce is not newer than the last one from the same port")
+)
+
+// ForeignMaster is a foreignMasterDS record for a single sender port, 9.3.2.4.5
+type ForeignMaster struct {
+	Dataset
+	// MostRecent is the latest Announce message received from this foreign master
+	MostRecent ptp.Announce
+	// Received is the time MostRecent was received at
+	Received time.Time
+	// Messages is the number of Announce messages received within the time window
+	Messages int
+
+	timestamps []time.Time
+}
+
+// ForeignMasterDataset collects Announce messages received by a port and qualifies foreign masters as described in 9.3.2.5.
+// It's not safe for concurrent use.
+type ForeignMasterDataset struct {
+	receiver  ptp.PortIdentity
+	window    time.Duration
+	threshold int
+	masters   map[ptp.PortIdentity]*ForeignMaster
+}
+
+// NewForeignMasterDataset returns ForeignMasterDataset for the port with given identity and announce interval
+func NewForeignMasterDataset(receiver ptp.PortIdentity, announceInterval time.Duration) *ForeignMasterDataset {
+	return &ForeignMasterDataset{
+		receiver:  receiver,
+		window:    ForeignMasterTimeWindow * announceInterval,
+		threshold: ForeignMasterThreshold,
+		masters:   map[ptp.PortIdentity]*ForeignMaster{},
+	}
+}
+
+// Add records Announce message received at given time. It returns an error explaining why the message was ignored.
+func (f *ForeignMasterDataset) Add(a *ptp.Announce, now time.Time) error {
+	if a.SourcePortIdentity.ClockIdentity == f.receiver.ClockIdentity {
+		return ErrOwnAnnounce
+	}
+	if a.StepsRemoved >= maxStepsRemoved {
+		return ErrStepsRemoved
+	}
+	m, ok := f.masters[a.SourcePortIdentity]
+	if !ok {
+		m = &ForeignMaster{}
+		f.masters[a.SourcePortIdentity] = m
+	} else {
+		f.expire(m, now)
+		// sequenceId is only checked within the window, so restarted senders are accepted again
+		if len(m.timestamps) > 0 && int16(a.SequenceID-m.MostRecent.SequenceID) <= 0 { //#nosec G115
+			return ErrStaleAnnounce
+		}
+	}
+	m.Dataset = DatasetFromAnnounce(a, f.receiver)
+	m.MostRecent = *a
+	m.MostRecent.TLVs = slices.Clone(a.TLVs)
+	m.Received = now
+	m.timestamps = append(m.timestamps, now)
+	m.Messages = len(m.timestamps)
+	return nil
+}
+
+// expire drops timestamps which are outside of the time window
+func (f *ForeignMasterDataset) expire(m *ForeignMaster, now time.Time) {
+	i := 0
+	for i < len(m.timestamps) && now.Sub(m.timestamps[i]) > f.window {
+		i++
+	}
+	m.timestamps = m.timestamps[i:]
+	m.Messages = len(m.timestamps)
+}
+
+// Expire removes foreign masters which didn't send any Announce messages within the time window
+func (f *ForeignMasterDataset) Expire(now time.Time) {
+	for id, m := range f.masters {
+		f.expire(m, now)
+		if len(m.timestamps) == 0 {
+			delete(f.masters, id)
+		}
+	}
+}
+
+// Len returns the number of known foreign masters, qualified or not
+func (f *ForeignMasterDataset) Len() int {
+	return len(f.masters)
+}
+
+// Qualified returns foreign masters qualified at given time, sorted from the best to the worst
+func (f *ForeignMasterDataset) Qualified(now time.Time) []ForeignMaster {
+	f.Expire(now)
+	res := []ForeignMaster{}
+	for _, m := range f.masters {
+		if m.Messages >= f.threshold {
+			res = append(res, *m)
+		}
+	}
+	// map iteration order is random, make sure the order doesn't depend on it
+	slices.SortFunc(res, func(a, b ForeignMaster) int {
+		return a.SenderIdentity.Compare(b.SenderIdentity)
+	})
+	slices.SortStableFunc(res, func(a, b ForeignMaster) int {
+		return compareOrder(&a.Dataset, &b.Dataset)
+	})
+	return res
+}
+
+// Best returns the best qualified foreign master, Erbest in 9.3.2.2 terms
+func (f *ForeignMasterDataset) Best(now time.Time) (ForeignMaster, bool) {
+	q := f.Qualified(now)
+	if len(q) == 0 {
+		return ForeignMaster{}, false
+	}
+	return q[0], true
+}
@@ -0,0 +1,129 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bmc
+
+import (
+	"testing"
+	"time"
+
+	ptp "github.com/facebook/time/ptp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+var receiver = ptp.PortIdentity{ClockIdentity: 0xaa, PortNumber: 1}
+
+func announce(id ptp.ClockIdentity, seq uint16, priority1 uint8) *ptp.Announce {
+	d := gm(id)
+	return &ptp.Announce{
+		Header: ptp.Header{
+			SdoIDAndMsgType:    ptp.NewSdoIDAndMsgType(ptp.MessageAnnounce, 0),
+			Version:            ptp.Version,
+			SourcePortIdentity: d.SenderIdentity,
+			SequenceID:         seq,
+		},
+		AnnounceBody: ptp.AnnounceBody{
+			GrandmasterPriority1:    priority1,
+			GrandmasterClockQuality: d.GrandmasterClockQuality,
+			GrandmasterPriority2:    d.GrandmasterPriority2,
+			GrandmasterIdentity:     id,
+		},
+	}
+}
+
+func TestForeignMasterDatasetQualification(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	f := NewForeignMasterDataset(receiver, time.Second)
+
+	require.NoError(t, f.Add(announce(1, 10, 128), now))
+	require.Equal(t, 1, f.Len())
+	// a single Announce is not enough
+	_, ok := f.Best(now)
+	require.False(t, ok)
+	require.Empty(t, f.Qualified(now))
+
+	require.ErrorIs(t, f.Add(announce(1, 10, 128), now.Add(time.Second)), ErrStaleAnnounce)
+	require.ErrorIs(t, f.Add(announce(1, 9, 128), now.Add(time.Second)), ErrStaleAnnounce)
+	_, ok = f.Best(now.Add(time.Second))
+	require.False(t, ok)
+
+	require.NoError(t, f.Add(announce(1, 11, 128), now.Add(time.Second)))
+	best, ok := f.Best(now.Add(time.Second))
+	require.True(t, ok)
+	require.Equal(t, ptp.ClockIdentity(1), best.GrandmasterIdentity)
+	require.Equal(t, uint16(11), best.MostRecent.SequenceID)
+	require.Equal(t, now.Add(time.Second), best.Received)
+	require.Equal(t, 2, best.Messages)
+	require.Equal(t, receiver, best.ReceiverIdentity)
+
+	// first Announce falls out of the window
+	_, ok = f.Best(now.Add(5 * time.Second))
+	require.False(t, ok)
+	require.Equal(t, 1, f.Len())
+	// and then the second one
+	f.Expire(now.Add(6 * time.Second))
+	require.Equal(t, 0, f.Len())
+}
+
+func TestForeignMasterDatasetRejects(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	f := NewForeignMasterDataset(receiver, time.Second)
+
+	require.ErrorIs(t, f.Add(announce(0xaa, 1, 128), now), ErrOwnAnnounce)
+	a := announce(1, 1, 128)
+	a.StepsRemoved = 255
+	require.ErrorIs(t, f.Add(a, now), ErrStepsRemoved)
+	require.Equal(t, 0, f.Len())
+}
+
+func TestForeignMasterDatasetSequenceWrap(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	f := NewForeignMasterDataset(receiver, time.Second)
+
+	require.NoError(t, f.Add(announce(1, 0xffff, 128), now))
+	require.NoError(t, f.Add(announce(1, 0, 128), now.Add(time.Second)))
+	// sender restarted after being silent for the whole window
+	require.NoError(t, f.Add(announce(1, 5, 128), now.Add(10*time.Second)))
+	require.NoError(t, f.Add(announce(1, 1, 128), now.Add(20*time.Second)))
+	require.Equal(t, 1, f.Len())
+}
+
+func TestForeignMasterDatasetBest(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	f := NewForeignMasterDataset(receiver, time.Second)
+
+	for i := range uint16(2) {
+		ts := now.Add(time.Duration(i) * time.Second)
+		require.NoError(t, f.Add(announce(1, i, 128), ts))
+		require.NoError(t, f.Add(announce(2, i, 127), ts))
+		require.NoError(t, f.Add(announce(3, i, 128), ts))
+	}
+	// not qualified, despite being the best
+	require.NoError(t, f.Add(announce(4, 0, 1), now))
+	require.Equal(t, 4, f.Len())
+
+	q := f.Qualified(now.Add(time.Second))
+	require.Len(t, q, 3)
+	got := []ptp.ClockIdentity{}
+	for _, m := range q {
+		got = append(got, m.GrandmasterIdentity)
+	}
+	require.Equal(t, []ptp.ClockIdentity{2, 1, 3}, got)
+
+	best, ok := f.Best(now.Add(time.Second))
+	require.True(t, ok)
+	require.Equal(t, ptp.ClockIdentity(2), best.GrandmasterIdentity)
+}
@@ -44,7 +44,7 @@ func ReqDelay(clockID ptp.ClockIdentity, portID uint16) *ptp.SyncDelayReq {
 				PortNumber:    portID,
 				ClockIdentity: clockID,
 			},
-			LogMessageInterval: 0x7f,
+			LogMessageInterval: ptp.LogIntervalUnspecified,
 		},
 		TLVs: []ptp.TLV{&ptp.AlternateResponsePortTLV{
 			TLVHead: ptp.TLVHead{TLVType: ptp.TLVAlternateResponsePort, LengthField: uint16(binary.Size(ptp.AlternateResponsePortTLV{}) - binary.Size(ptp.TLVHead{}))}, //#nosec G115
@@ -66,7 +66,7 @@ func ReqAnnounce(clockID ptp.ClockIdentity, portID uint16, ts time.Time) *ptp.An
 				PortNumber:    portID,
 				ClockIdentity: clockID,
 			},
-			LogMessageInterval: 0x7f,
+			LogMessageInterval: ptp.LogIntervalUnspecified,
 		},
 		AnnounceBody: ptp.AnnounceBody{
 			OriginTimestamp: ptp.NewTimestamp(ts),
@@ -104,6 +104,9 @@ type Client struct {
 	// where we store timestamps
 	m *measurements
 
+	// sequenceIds of received syncs, to count lost ones
+	sequences *ptp.SequenceTracker
+
 	// where we store our metrics
 	stats StatsServer
 }
@@ -173,6 +176,7 @@ func NewClient(target netip.Addr, targetPort int, clockID ptp.ClockIdentity, eve
 		inChan:          make(chan bool, 100),
 		server:          target,
 		m:               newMeasurements(&cfg.Measurement),
+		sequences:       ptp.NewSequenceTracker(sequenceIDMask, 0),
 		stats:           stats,
 	}
 	return c, nil
@@ -209,6 +213,9 @@ func (c *Client) handleSync(b *ptp.SyncDelayReq, ts time.Time) {
 		ts,
 		t4,
 		cf)
+	if res := c.sequences.TrackHeader(&b.Header); res.Lost != 0 {
+		c.stats.AddRXSyncLost(res.Lost)
+	}
 	// T2 and CF1
 	c.m.addT2andCF1(b.SequenceID, ts, cf)
 	// sync carries T4 as well
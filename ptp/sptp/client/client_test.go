@@ -222,6 +222,30 @@ func TestClientIncrementSequence(t *testing.T) {
 	require.Equal(t, uint16(0xC000), c.eventSequence)
 }
 
+func TestClientSyncLost(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cid := ptp.ClockIdentity(0xc42a1fffe6d7ca6)
+
+	eventConn := NewMockUDPConnWithTS(ctrl)
+	cfg := Config{
+		SequenceIDMaskBits:  2,
+		SequenceIDMaskValue: 3,
+	}
+	statsServer := NewMockStatsServer(ctrl)
+	c, err := NewClient(netip.MustParseAddr("127.0.0.1"), ptp.PortEvent, cid, eventConn, &cfg, statsServer)
+	require.NoError(t, err)
+
+	c.handleSync(syncPkt(0xFFFE), time.Now())
+	// wraps around within the mask, nothing lost
+	c.handleSync(syncPkt(0xFFFF), time.Now())
+	c.handleSync(syncPkt(0xC000), time.Now())
+	// duplicate is not a loss either
+	c.handleSync(syncPkt(0xC000), time.Now())
+	statsServer.EXPECT().AddRXSyncLost(2)
+	c.handleSync(syncPkt(0xC003), time.Now())
+}
+
 func TestReqAnnounce(t *testing.T) {
 	now := time.Now()
 	a := ReqAnnounce(ptp.ClockIdentity(0xc42a1fffe6d7ca6), 1, now)
@@ -96,7 +96,8 @@ func NewUDPConnTS(address net.IP, port int, ts timestamp.Timestamp, iface string
 		return nil, fmt.Errorf("setting DSCP on event socket: %w", err)
 	}
 
-	// we need to enable HW or SW timestamps on event port
+	// we need to enable HW or SW timestamps on event port.
+	// No fallback to software timestamps, as with HW ones we steer PHC based on them
 	if err := timestamp.EnableTimestamps(ts, udpConn.connFd, iface); err != nil {
 		return nil, fmt.Errorf("failed to enable timestamps on port %d: %w", port, err)
 	}
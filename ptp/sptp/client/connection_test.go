@@ -0,0 +1,38 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"net"
+	"testing"
+
+	"github.com/facebook/time/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUDPConnTS(t *testing.T) {
+	conn, err := NewUDPConnTS(net.ParseIP("127.0.0.1"), 0, timestamp.SW, "lo", 0)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	// loopback has no hardware timestamps, and we don't fall back to software ones
+	_, err = NewUDPConnTS(net.ParseIP("127.0.0.1"), 0, timestamp.HW, "lo", 0)
+	require.Error(t, err)
+
+	_, err = NewUDPConnTS(net.ParseIP("127.0.0.1"), 0, timestamp.Timestamp(42), "lo", 0)
+	require.Error(t, err)
+}
@@ -36,6 +36,7 @@ type StatsServer interface {
 	SetServoState(state int)
 	IncFiltered()
 	IncRXSync()
+	AddRXSyncLost(lost int)
 	IncRXAnnounce()
 	IncRXDelayReq()
 	IncTXDelayReq()
@@ -64,6 +65,7 @@ type clientStats struct {
 	tickDuration int64
 	filtered     int64
 	rxSync       int64
+	rxSyncLost   int64
 	rxAnnounce   int64
 	rxDelayReq   int64
 	txDelayReq   int64
@@ -122,6 +124,11 @@ func (s *Stats) IncRXSync() {
 	atomic.AddInt64(&s.rxSync, 1)
 }
 
+// AddRXSyncLost atomically adds lost to the rxSyncLost, it's negative when late sync arrives
+func (s *Stats) AddRXSyncLost(lost int) {
+	atomic.AddInt64(&s.rxSyncLost, int64(lost))
+}
+
 // IncRXAnnounce atomically adds 1 to the rxAnnounce
 func (s *Stats) IncRXAnnounce() {
 	atomic.AddInt64(&s.rxAnnounce, 1)
@@ -154,6 +161,7 @@ func (s *Stats) GetCounters() map[string]int64 {
 		"ptp.sptp.tick_duration_ns":         s.tickDuration,
 		"ptp.sptp.filtered":                 s.filtered,
 		"ptp.sptp.portstats.rx.sync":        s.rxSync,
+		"ptp.sptp.portstats.rx.sync_lost":   s.rxSyncLost,
 		"ptp.sptp.portstats.rx.announce":    s.rxAnnounce,
 		"ptp.sptp.portstats.rx.delay_req":   s.rxDelayReq,
 		"ptp.sptp.portstats.tx.delay_req":   s.txDelayReq,
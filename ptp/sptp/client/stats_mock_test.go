@@ -51,6 +51,18 @@ func (m *MockStatsServer) EXPECT() *MockStatsServerMockRecorder {
 	return m.recorder
 }
 
+// AddRXSyncLost mocks base method.
+func (m *MockStatsServer) AddRXSyncLost(lost int) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddRXSyncLost", lost)
+}
+
+// AddRXSyncLost indicates an expected call of AddRXSyncLost.
+func (mr *MockStatsServerMockRecorder) AddRXSyncLost(lost interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRXSyncLost", reflect.TypeOf((*MockStatsServer)(nil).AddRXSyncLost), lost)
+}
+
 // CollectSysStats mocks base method.
 func (m *MockStatsServer) CollectSysStats() {
 	m.ctrl.T.Helper()
@@ -141,12 +141,14 @@ func TestInc(t *testing.T) {
 	s.IncTXDelayReq()
 	s.IncUnsupported()
 	s.IncFiltered()
+	s.AddRXSyncLost(3)
 	require.Equal(t, int64(43), s.rxAnnounce)
 	require.Equal(t, int64(44), s.rxSync)
 	require.Equal(t, int64(45), s.rxDelayReq)
 	require.Equal(t, int64(46), s.txDelayReq)
 	require.Equal(t, int64(47), s.unsupported)
 	require.Equal(t, int64(48), s.filtered)
+	require.Equal(t, int64(3), s.rxSyncLost)
 }
 
 func TestSysStats(t *testing.T) {
@@ -169,6 +171,7 @@ func TestGetCounters(t *testing.T) {
 	require.Contains(t, m, "ptp.sptp.tick_duration_ns")
 	require.Contains(t, m, "ptp.sptp.filtered")
 	require.Contains(t, m, "ptp.sptp.portstats.rx.sync")
+	require.Contains(t, m, "ptp.sptp.portstats.rx.sync_lost")
 	require.Contains(t, m, "ptp.sptp.portstats.rx.announce")
 	require.Contains(t, m, "ptp.sptp.portstats.rx.delay_req")
 	require.Contains(t, m, "ptp.sptp.portstats.tx.delay_req")
@@ -0,0 +1,315 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package unicast implements grantee side of IEEE 1588-2019 unicast negotiation (16.1).
+
+It is a pure state machine: it doesn't send or receive packets and doesn't run timers on its own.
+Callers feed it received TLVs and current time, and send TLVs it returns.
+*/
+package unicast
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	ptp "github.com/facebook/time/ptp/protocol"
+)
+
+// Errors returned when handling received TLVs
+var (
+	ErrWrongMessageType = errors.New("TLV is for a different message type")
+	ErrUnexpectedGrant  = errors.New("got grant without pending request")
+	ErrGrantAfterCancel = errors.New("got grant after cancellation was sent")
+)
+
+// State is a state of unicast negotiation
+type State uint8
+
+const (
+	// StateIdle means there is no grant and no request is pending
+	StateIdle State = iota
+	// StateRequested means request was sent and we are waiting for grant
+	StateRequested
+	// StateGranted means grantor agreed to send us messages
+	StateGranted
+	// StateDenied means grantor refused the request
+	StateDenied
+	// StateCancelling means cancellation was sent and we are waiting for acknowledgement
+	StateCancelling
+)
+
+var stateToString = map[State]string{
+	StateIdle:       "IDLE",
+	StateRequested:  "REQUESTED",
+	StateGranted:    "GRANTED",
+	StateDenied:     "DENIED",
+	StateCancelling: "CANCELLING",
+}
+
+func (s State) String() string {
+	return stateToString[s]
+}
+
+// Config is a negotiation config
+type Config struct {
+	// Interval is a requested logInterMessagePeriod
+	Interval ptp.LogInterval
+	// Duration is a requested grant duration, it's sent in whole seconds
+	Duration time.Duration
+	// RenewBefore is how long before grant expiration we send renewal request
+	RenewBefore time.Duration
+	// Timeout is how long we wait for grant or cancel acknowledgement before retransmitting
+	Timeout time.Duration
+	// RetryInterval is how long we wait before requesting again after denial or cancellation by grantor
+	RetryInterval time.Duration
+}
+
+// Negotiation tracks unicast transmission grant of a single message type
+type Negotiation struct {
+	msgType ptp.MessageType
+	config  Config
+
+	state    State
+	wanted   bool
+	renewing bool
+	deadline time.Time
+
+	granted  ptp.LogInterval
+	expires  time.Time
+	renewal  bool
+	requests int
+}
+
+// NewNegotiation returns new Negotiation for the message type
+func NewNegotiation(msgType ptp.MessageType, config Config) *Negotiation {
+	return &Negotiation{msgType: msgType, config: config}
+}
+
+// MessageType returns negotiated message type
+func (n *Negotiation) MessageType() ptp.MessageType {
+	return n.msgType
+}
+
+// State returns current state
+func (n *Negotiation) State() State {
+	return n.state
+}
+
+// Interval returns granted message interval
+func (n *Negotiation) Interval() ptp.LogInterval {
+	return n.granted
+}
+
+// Expires returns when current grant expires
+func (n *Negotiation) Expires() time.Time {
+	return n.expires
+}
+
+// RenewalInvited reports whether grantor set R flag, promising to maintain the grant on renewal
+func (n *Negotiation) RenewalInvited() bool {
+	return n.renewal
+}
+
+// Requests returns how many requests were sent
+func (n *Negotiation) Requests() int {
+	return n.requests
+}
+
+// Active reports whether grantor is expected to send us messages at the moment
+func (n *Negotiation) Active(now time.Time) bool {
+	return n.state == StateGranted && now.Before(n.expires)
+}
+
+// Deadline returns when Tick has to be called next. Zero time means nothing is scheduled.
+func (n *Negotiation) Deadline() time.Time {
+	return n.deadline
+}
+
+// Start requests the grant and returns TLV to send
+func (n *Negotiation) Start(now time.Time) ptp.TLV {
+	n.wanted = true
+	if n.state == StateGranted || n.state == StateRequested {
+		return nil
+	}
+	return n.request(now)
+}
+
+// Cancel cancels the grant and returns TLV to send, if anything needs to be cancelled
+func (n *Negotiation) Cancel(now time.Time) ptp.TLV {
+	n.wanted = false
+	n.renewing = false
+	switch n.state {
+	case StateRequested, StateGranted, StateCancelling:
+		// requested grant may still be on its way, so it has to be cancelled as well
+		n.state = StateCancelling
+		n.deadline = now.Add(n.config.Timeout)
+		return n.cancelTLV()
+	}
+	n.state = StateIdle
+	n.deadline = time.Time{}
+	return nil
+}
+
+// Tick handles timeouts and returns TLV to send if it's time to (re)transmit something
+func (n *Negotiation) Tick(now time.Time) ptp.TLV {
+	if n.deadline.IsZero() || now.Before(n.deadline) {
+		return nil
+	}
+	switch n.state {
+	case StateIdle, StateDenied:
+		if n.wanted {
+			return n.request(now)
+		}
+	case StateRequested:
+		return n.request(now)
+	case StateGranted:
+		if !now.Before(n.expires) {
+			n.state = StateIdle
+			return n.request(now)
+		}
+		if n.wanted {
+			n.renewing = true
+			n.requests++
+			n.deadline = minTime(now.Add(n.config.Timeout), n.expires)
+			return n.requestTLV()
+		}
+	case StateCancelling:
+		if !n.expires.IsZero() && !now.Before(n.expires) {
+			// grant is over anyway, no need to wait for acknowledgement
+			n.state = StateIdle
+			n.deadline = time.Time{}
+			return nil
+		}
+		n.deadline = now.Add(n.config.Timeout)
+		return n.cancelTLV()
+	}
+	n.deadline = time.Time{}
+	return nil
+}
+
+// HandleGrant handles received GRANT_UNICAST_TRANSMISSION TLV
+func (n *Negotiation) HandleGrant(now time.Time, t *ptp.GrantUnicastTransmissionTLV) error {
+	if t.MsgTypeAndReserved.MsgType() != n.msgType {
+		return fmt.Errorf("%w: %s", ErrWrongMessageType, t.MsgTypeAndReserved.MsgType())
+	}
+	switch n.state {
+	case StateCancelling:
+		// cancellation and grant crossed each other, our cancel will take care of it
+		return ErrGrantAfterCancel
+	case StateRequested:
+	case StateGranted:
+		if !n.renewing {
+			return ErrUnexpectedGrant
+		}
+	default:
+		return ErrUnexpectedGrant
+	}
+	n.renewing = false
+	if t.DurationField == 0 {
+		// denied. Existing grant, if any, is still valid till it expires
+		if n.state != StateGranted {
+			n.state = StateDenied
+			n.expires = time.Time{}
+		}
+		n.deadline = now.Add(n.config.RetryInterval)
+		if n.state == StateGranted {
+			n.deadline = minTime(n.deadline, n.expires)
+		}
+		return nil
+	}
+	n.state = StateGranted
+	n.granted = t.LogInterMessagePeriod
+	n.renewal = t.Renewal&0x1 == 1
+	n.expires = now.Add(time.Duration(t.DurationField) * time.Second)
+	n.deadline = n.expires.Add(-n.config.RenewBefore)
+	return nil
+}
+
+// HandleCancel handles received CANCEL_UNICAST_TRANSMISSION TLV and returns acknowledgement to send
+func (n *Negotiation) HandleCancel(now time.Time, t *ptp.CancelUnicastTransmissionTLV) (ptp.TLV, error) {
+	if t.MsgTypeAndFlags.MsgType() != n.msgType {
+		return nil, fmt.Errorf("%w: %s", ErrWrongMessageType, t.MsgTypeAndFlags.MsgType())
+	}
+	// Acknowledgement is always sent, even if we don't know about the grant (16.1.4.4)
+	ack := &ptp.AcknowledgeCancelUnicastTransmissionTLV{
+		TLVHead: ptp.TLVHead{
+			TLVType:     ptp.TLVAcknowledgeCancelUnicastTransmission,
+			LengthField: 2,
+		},
+		MsgTypeAndFlags: ptp.NewUnicastMsgTypeAndFlags(n.msgType, 0),
+	}
+	n.renewing = false
+	n.expires = time.Time{}
+	n.state = StateIdle
+	n.deadline = time.Time{}
+	if n.wanted {
+		n.deadline = now.Add(n.config.RetryInterval)
+	}
+	return ack, nil
+}
+
+// HandleAcknowledgeCancel handles received ACKNOWLEDGE_CANCEL_UNICAST_TRANSMISSION TLV
+func (n *Negotiation) HandleAcknowledgeCancel(_ time.Time, t *ptp.AcknowledgeCancelUnicastTransmissionTLV) error {
+	if t.MsgTypeAndFlags.MsgType() != n.msgType {
+		return fmt.Errorf("%w: %s", ErrWrongMessageType, t.MsgTypeAndFlags.MsgType())
+	}
+	if n.state != StateCancelling {
+		return nil
+	}
+	n.state = StateIdle
+	n.expires = time.Time{}
+	n.deadline = time.Time{}
+	return nil
+}
+
+func (n *Negotiation) request(now time.Time) ptp.TLV {
+	n.state = StateRequested
+	n.renewing = false
+	n.requests++
+	n.deadline = now.Add(n.config.Timeout)
+	return n.requestTLV()
+}
+
+func (n *Negotiation) requestTLV() *ptp.RequestUnicastTransmissionTLV {
+	return &ptp.RequestUnicastTransmissionTLV{
+		TLVHead: ptp.TLVHead{
+			TLVType:     ptp.TLVRequestUnicastTransmission,
+			LengthField: 6,
+		},
+		MsgTypeAndReserved:    ptp.NewUnicastMsgTypeAndFlags(n.msgType, 0),
+		LogInterMessagePeriod: n.config.Interval,
+		DurationField:         uint32(n.config.Duration.Seconds()),
+	}
+}
+
+func (n *Negotiation) cancelTLV() *ptp.CancelUnicastTransmissionTLV {
+	return &ptp.CancelUnicastTransmissionTLV{
+		TLVHead: ptp.TLVHead{
+			TLVType:     ptp.TLVCancelUnicastTransmission,
+			LengthField: 2,
+		},
+		MsgTypeAndFlags: ptp.NewUnicastMsgTypeAndFlags(n.msgType, 0),
+	}
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,222 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unicast
+
+import (
+	"testing"
+	"time"
+
+	ptp "github.com/facebook/time/ptp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+var testConfig = Config{
+	Interval:      0,
+	Duration:      60 * time.Second,
+	RenewBefore:   10 * time.Second,
+	Timeout:       time.Second,
+	RetryInterval: 5 * time.Second,
+}
+
+var start = time.Unix(1700000000, 0)
+
+func grant(msgType ptp.MessageType, interval ptp.LogInterval, duration uint32) *ptp.GrantUnicastTransmissionTLV {
+	return &ptp.GrantUnicastTransmissionTLV{
+		TLVHead:               ptp.TLVHead{TLVType: ptp.TLVGrantUnicastTransmission, LengthField: 8},
+		MsgTypeAndReserved:    ptp.NewUnicastMsgTypeAndFlags(msgType, 0),
+		LogInterMessagePeriod: interval,
+		DurationField:         duration,
+		Renewal:               1,
+	}
+}
+
+func cancel(msgType ptp.MessageType) *ptp.CancelUnicastTransmissionTLV {
+	return &ptp.CancelUnicastTransmissionTLV{
+		TLVHead:         ptp.TLVHead{TLVType: ptp.TLVCancelUnicastTransmission, LengthField: 2},
+		MsgTypeAndFlags: ptp.NewUnicastMsgTypeAndFlags(msgType, 0),
+	}
+}
+
+func ackCancel(msgType ptp.MessageType) *ptp.AcknowledgeCancelUnicastTransmissionTLV {
+	return &ptp.AcknowledgeCancelUnicastTransmissionTLV{
+		TLVHead:         ptp.TLVHead{TLVType: ptp.TLVAcknowledgeCancelUnicastTransmission, LengthField: 2},
+		MsgTypeAndFlags: ptp.NewUnicastMsgTypeAndFlags(msgType, 0),
+	}
+}
+
+func TestNegotiationGrantAndRenewal(t *testing.T) {
+	n := NewNegotiation(ptp.MessageSync, testConfig)
+	require.Equal(t, StateIdle, n.State())
+	require.Nil(t, n.Tick(start))
+
+	want := &ptp.RequestUnicastTransmissionTLV{
+		TLVHead:            ptp.TLVHead{TLVType: ptp.TLVRequestUnicastTransmission, LengthField: 6},
+		MsgTypeAndReserved: ptp.NewUnicastMsgTypeAndFlags(ptp.MessageSync, 0),
+		DurationField:      60,
+	}
+	require.Equal(t, want, n.Start(start))
+	require.Equal(t, StateRequested, n.State())
+	require.Equal(t, start.Add(time.Second), n.Deadline())
+	// already requested
+	require.Nil(t, n.Start(start))
+
+	// no answer, retransmit
+	require.Nil(t, n.Tick(start.Add(500*time.Millisecond)))
+	require.Equal(t, want, n.Tick(start.Add(time.Second)))
+	require.Equal(t, 2, n.Requests())
+
+	now := start.Add(1500 * time.Millisecond)
+	require.NoError(t, n.HandleGrant(now, grant(ptp.MessageSync, -3, 60)))
+	require.Equal(t, StateGranted, n.State())
+	require.Equal(t, ptp.LogInterval(-3), n.Interval())
+	require.True(t, n.RenewalInvited())
+	require.True(t, n.Active(now))
+	require.Equal(t, now.Add(60*time.Second), n.Expires())
+	require.Equal(t, now.Add(50*time.Second), n.Deadline())
+
+	// duplicate grant
+	require.ErrorIs(t, n.HandleGrant(now, grant(ptp.MessageSync, -3, 60)), ErrUnexpectedGrant)
+
+	// renewal keeps the grant active
+	require.Nil(t, n.Tick(now.Add(49*time.Second)))
+	now = now.Add(50 * time.Second)
+	require.Equal(t, want, n.Tick(now))
+	require.Equal(t, StateGranted, n.State())
+	require.True(t, n.Active(now))
+	require.NoError(t, n.HandleGrant(now, grant(ptp.MessageSync, -3, 60)))
+	require.Equal(t, now.Add(60*time.Second), n.Expires())
+}
+
+func TestNegotiationExpiry(t *testing.T) {
+	n := NewNegotiation(ptp.MessageAnnounce, testConfig)
+	n.Start(start)
+	require.NoError(t, n.HandleGrant(start, grant(ptp.MessageAnnounce, 0, 20)))
+	// renewal attempts are never answered
+	now := start.Add(10 * time.Second)
+	for i := 0; i < 10; i++ {
+		require.NotNil(t, n.Tick(now))
+		now = now.Add(time.Second)
+	}
+	require.Equal(t, start.Add(20*time.Second), now)
+	require.False(t, n.Active(now))
+	require.NotNil(t, n.Tick(now))
+	require.Equal(t, StateRequested, n.State())
+}
+
+func TestNegotiationDenied(t *testing.T) {
+	n := NewNegotiation(ptp.MessageDelayResp, testConfig)
+	n.Start(start)
+	require.NoError(t, n.HandleGrant(start, grant(ptp.MessageDelayResp, 0, 0)))
+	require.Equal(t, StateDenied, n.State())
+	require.False(t, n.Active(start))
+	require.Equal(t, start.Add(5*time.Second), n.Deadline())
+	require.Nil(t, n.Tick(start.Add(4*time.Second)))
+	require.NotNil(t, n.Tick(start.Add(5*time.Second)))
+	require.Equal(t, StateRequested, n.State())
+
+	// denied renewal keeps existing grant until it expires
+	require.NoError(t, n.HandleGrant(start, grant(ptp.MessageDelayResp, 0, 12)))
+	require.NotNil(t, n.Tick(start.Add(2*time.Second)))
+	require.NoError(t, n.HandleGrant(start.Add(2*time.Second), grant(ptp.MessageDelayResp, 0, 0)))
+	require.Equal(t, StateGranted, n.State())
+	require.Equal(t, start.Add(7*time.Second), n.Deadline())
+}
+
+func TestNegotiationWrongMessageType(t *testing.T) {
+	n := NewNegotiation(ptp.MessageSync, testConfig)
+	n.Start(start)
+	require.ErrorIs(t, n.HandleGrant(start, grant(ptp.MessageAnnounce, 0, 60)), ErrWrongMessageType)
+	_, err := n.HandleCancel(start, cancel(ptp.MessageAnnounce))
+	require.ErrorIs(t, err, ErrWrongMessageType)
+	require.ErrorIs(t, n.HandleAcknowledgeCancel(start, ackCancel(ptp.MessageAnnounce)), ErrWrongMessageType)
+	require.Equal(t, StateRequested, n.State())
+}
+
+func TestNegotiationUnsolicitedGrant(t *testing.T) {
+	n := NewNegotiation(ptp.MessageSync, testConfig)
+	require.ErrorIs(t, n.HandleGrant(start, grant(ptp.MessageSync, 0, 60)), ErrUnexpectedGrant)
+	require.Equal(t, StateIdle, n.State())
+}
+
+func TestNegotiationCancel(t *testing.T) {
+	n := NewNegotiation(ptp.MessageSync, testConfig)
+	require.Nil(t, n.Cancel(start))
+
+	n.Start(start)
+	require.NoError(t, n.HandleGrant(start, grant(ptp.MessageSync, 0, 60)))
+	require.Equal(t, cancel(ptp.MessageSync), n.Cancel(start))
+	require.Equal(t, StateCancelling, n.State())
+
+	// no acknowledgement, retransmit
+	require.Equal(t, cancel(ptp.MessageSync), n.Tick(start.Add(time.Second)))
+	require.NoError(t, n.HandleAcknowledgeCancel(start, ackCancel(ptp.MessageSync)))
+	require.Equal(t, StateIdle, n.State())
+	require.True(t, n.Deadline().IsZero())
+	require.Nil(t, n.Tick(start.Add(time.Hour)))
+}
+
+func TestNegotiationCancelGivesUpOnExpiry(t *testing.T) {
+	n := NewNegotiation(ptp.MessageSync, testConfig)
+	n.Start(start)
+	require.NoError(t, n.HandleGrant(start, grant(ptp.MessageSync, 0, 2)))
+	n.Cancel(start)
+	require.NotNil(t, n.Tick(start.Add(time.Second)))
+	require.Nil(t, n.Tick(start.Add(2*time.Second)))
+	require.Equal(t, StateIdle, n.State())
+}
+
+func TestNegotiationCancelRaces(t *testing.T) {
+	// we cancel while request is in flight, and grant arrives afterwards
+	n := NewNegotiation(ptp.MessageSync, testConfig)
+	n.Start(start)
+	require.NotNil(t, n.Cancel(start))
+	require.ErrorIs(t, n.HandleGrant(start, grant(ptp.MessageSync, 0, 60)), ErrGrantAfterCancel)
+	require.Equal(t, StateCancelling, n.State())
+	require.False(t, n.Active(start))
+
+	// both sides cancel at the same time
+	require.NotNil(t, n.Start(start.Add(time.Second)))
+	require.NoError(t, n.HandleGrant(start, grant(ptp.MessageSync, 0, 60)))
+	require.NotNil(t, n.Cancel(start))
+	ack, err := n.HandleCancel(start, cancel(ptp.MessageSync))
+	require.NoError(t, err)
+	require.Equal(t, ackCancel(ptp.MessageSync), ack)
+	require.Equal(t, StateIdle, n.State())
+	// late acknowledgement of our cancel is fine
+	require.NoError(t, n.HandleAcknowledgeCancel(start, ackCancel(ptp.MessageSync)))
+	require.Equal(t, StateIdle, n.State())
+}
+
+func TestNegotiationCancelledByGrantor(t *testing.T) {
+	n := NewNegotiation(ptp.MessageAnnounce, testConfig)
+	n.Start(start)
+	require.NoError(t, n.HandleGrant(start, grant(ptp.MessageAnnounce, 0, 60)))
+	ack, err := n.HandleCancel(start, cancel(ptp.MessageAnnounce))
+	require.NoError(t, err)
+	require.Equal(t, ackCancel(ptp.MessageAnnounce), ack)
+	require.Equal(t, StateIdle, n.State())
+	require.False(t, n.Active(start))
+	// we still want the grant, so we ask again later
+	require.Equal(t, start.Add(5*time.Second), n.Deadline())
+	require.NotNil(t, n.Tick(start.Add(5*time.Second)))
+	require.Equal(t, StateRequested, n.State())
+}
+
+func TestStateString(t *testing.T) {
+	require.Equal(t, "CANCELLING", StateCancelling.String())
+	require.Equal(t, "IDLE", StateIdle.String())
+}
@@ -0,0 +1,165 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unicast
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	ptp "github.com/facebook/time/ptp/protocol"
+)
+
+// Session tracks negotiations of several message types with a single grantor
+type Session struct {
+	negotiations []*Negotiation
+}
+
+// NewSession returns new Session negotiating all message types with the same config
+func NewSession(config Config, msgTypes ...ptp.MessageType) *Session {
+	s := &Session{}
+	for _, t := range msgTypes {
+		s.negotiations = append(s.negotiations, NewNegotiation(t, config))
+	}
+	return s
+}
+
+// Negotiation returns negotiation of the message type, nil if it's not negotiated in this session
+func (s *Session) Negotiation(msgType ptp.MessageType) *Negotiation {
+	for _, n := range s.negotiations {
+		if n.msgType == msgType {
+			return n
+		}
+	}
+	return nil
+}
+
+// Start requests grants for all message types
+func (s *Session) Start(now time.Time) []ptp.TLV {
+	return s.each(func(n *Negotiation) ptp.TLV { return n.Start(now) })
+}
+
+// Cancel cancels grants for all message types
+func (s *Session) Cancel(now time.Time) []ptp.TLV {
+	return s.each(func(n *Negotiation) ptp.TLV { return n.Cancel(now) })
+}
+
+// Tick handles timeouts of all negotiations
+func (s *Session) Tick(now time.Time) []ptp.TLV {
+	return s.each(func(n *Negotiation) ptp.TLV { return n.Tick(now) })
+}
+
+// Deadline returns the earliest deadline of all negotiations
+func (s *Session) Deadline() time.Time {
+	var d time.Time
+	for _, n := range s.negotiations {
+		if nd := n.Deadline(); !nd.IsZero() && (d.IsZero() || nd.Before(d)) {
+			d = nd
+		}
+	}
+	return d
+}
+
+// Handle handles unicast negotiation TLVs of received Signaling message and returns TLVs to send back.
+// Other TLVs are ignored.
+func (s *Session) Handle(now time.Time, p *ptp.Signaling) ([]ptp.TLV, error) {
+	var res []ptp.TLV
+	var errs []error
+	for _, tlv := range p.TLVs {
+		switch v := tlv.(type) {
+		case *ptp.GrantUnicastTransmissionTLV:
+			if n := s.Negotiation(v.MsgTypeAndReserved.MsgType()); n != nil {
+				errs = append(errs, n.HandleGrant(now, v))
+			} else {
+				errs = append(errs, ErrUnexpectedGrant)
+			}
+		case *ptp.CancelUnicastTransmissionTLV:
+			n := s.Negotiation(v.MsgTypeAndFlags.MsgType())
+			if n == nil {
+				n = NewNegotiation(v.MsgTypeAndFlags.MsgType(), Config{})
+			}
+			ack, err := n.HandleCancel(now, v)
+			errs = append(errs, err)
+			if ack != nil {
+				res = append(res, ack)
+			}
+		case *ptp.AcknowledgeCancelUnicastTransmissionTLV:
+			if n := s.Negotiation(v.MsgTypeAndFlags.MsgType()); n != nil {
+				errs = append(errs, n.HandleAcknowledgeCancel(now, v))
+			}
+		}
+	}
+	return res, errors.Join(errs...)
+}
+
+func (s *Session) each(f func(n *Negotiation) ptp.TLV) []ptp.TLV {
+	var res []ptp.TLV
+	for _, n := range s.negotiations {
+		if tlv := f(n); tlv != nil {
+			res = append(res, tlv)
+		}
+	}
+	return res
+}
+
+// tlvLength returns the number of bytes TLV takes on the wire
+func tlvLength(tlv ptp.TLV) int {
+	if m, ok := tlv.(ptp.BinaryMarshalerTo); ok {
+		// Signaling is marshaled into 508 bytes buffer, so no TLV can be larger
+		buf := make([]byte, 508)
+		n, err := m.MarshalBinaryTo(buf)
+		if err == nil {
+			return n
+		}
+	}
+	return binary.Size(tlv)
+}
+
+// NewSignaling wraps TLVs into Signaling message addressed to all ports
+func NewSignaling(source ptp.PortIdentity, sequenceID uint16, tlvs []ptp.TLV) *ptp.Signaling {
+	l := 44 // header and target port identity
+	for _, tlv := range tlvs {
+		switch v := tlv.(type) {
+		case *ptp.RequestUnicastTransmissionTLV:
+			l += 4 + int(v.LengthField)
+		case *ptp.GrantUnicastTransmissionTLV:
+			l += 4 + int(v.LengthField)
+		case *ptp.CancelUnicastTransmissionTLV:
+			l += 4 + int(v.LengthField)
+		case *ptp.AcknowledgeCancelUnicastTransmissionTLV:
+			l += 4 + int(v.LengthField)
+		default:
+			l += tlvLength(tlv)
+		}
+	}
+	return &ptp.Signaling{
+		Header: ptp.Header{
+			SdoIDAndMsgType:    ptp.NewSdoIDAndMsgType(ptp.MessageSignaling, 0),
+			Version:            ptp.Version,
+			SequenceID:         sequenceID,
+			MessageLength:      uint16(l), //#nosec G115
+			FlagField:          ptp.FlagUnicast,
+			SourcePortIdentity: source,
+			LogMessageInterval: ptp.LogIntervalUnspecified,
+		},
+		TargetPortIdentity: ptp.PortIdentity{
+			PortNumber:    0xffff,
+			ClockIdentity: 0xffffffffffffffff,
+		},
+		TLVs: tlvs,
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unicast
+
+import (
+	"testing"
+	"time"
+
+	ptp "github.com/facebook/time/ptp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession(t *testing.T) {
+	s := NewSession(testConfig, ptp.MessageAnnounce, ptp.MessageSync, ptp.MessageDelayResp)
+	require.Nil(t, s.Negotiation(ptp.MessageManagement))
+	require.True(t, s.Deadline().IsZero())
+	require.Len(t, s.Start(start), 3)
+	require.Equal(t, start.Add(time.Second), s.Deadline())
+
+	source := ptp.PortIdentity{ClockIdentity: 1, PortNumber: 1}
+	res, err := s.Handle(start, NewSignaling(source, 1, []ptp.TLV{
+		grant(ptp.MessageAnnounce, 1, 60),
+		grant(ptp.MessageSync, -3, 30),
+		cancel(ptp.MessagePDelayReq),
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []ptp.TLV{ackCancel(ptp.MessagePDelayReq)}, res)
+	require.True(t, s.Negotiation(ptp.MessageAnnounce).Active(start))
+	require.True(t, s.Negotiation(ptp.MessageSync).Active(start))
+	require.Equal(t, StateRequested, s.Negotiation(ptp.MessageDelayResp).State())
+
+	// delay resp is retransmitted, sync renewal comes earlier than announce one
+	require.Len(t, s.Tick(start.Add(time.Second)), 1)
+	require.Equal(t, start.Add(2*time.Second), s.Deadline())
+	_, err = s.Handle(start, NewSignaling(source, 2, []ptp.TLV{grant(ptp.MessageDelayResp, 0, 60)}))
+	require.NoError(t, err)
+	require.Equal(t, start.Add(20*time.Second), s.Deadline())
+
+	_, err = s.Handle(start, NewSignaling(source, 3, []ptp.TLV{grant(ptp.MessageManagement, 0, 60)}))
+	require.ErrorIs(t, err, ErrUnexpectedGrant)
+
+	tlvs := s.Cancel(start)
+	require.Len(t, tlvs, 3)
+	_, err = s.Handle(start, NewSignaling(source, 4, []ptp.TLV{
+		ackCancel(ptp.MessageAnnounce),
+		ackCancel(ptp.MessageSync),
+		ackCancel(ptp.MessageDelayResp),
+	}))
+	require.NoError(t, err)
+	require.True(t, s.Deadline().IsZero())
+}
+
+func TestNewSignaling(t *testing.T) {
+	source := ptp.PortIdentity{ClockIdentity: 1, PortNumber: 1}
+	n := NewNegotiation(ptp.MessageSync, testConfig)
+	p := NewSignaling(source, 42, []ptp.TLV{n.Start(start), n.Cancel(start)})
+	b, err := ptp.Bytes(p)
+	require.NoError(t, err)
+	require.Len(t, b, 44+10+6+ptp.TrailingBytes)
+	require.Equal(t, uint16(60), p.MessageLength)
+
+	got := &ptp.Signaling{}
+	require.NoError(t, ptp.FromBytes(b, got))
+	require.Equal(t, p, got)
+}
+
+func TestNewSignalingMixedTLVs(t *testing.T) {
+	source := ptp.PortIdentity{ClockIdentity: 1, PortNumber: 1}
+	n := NewNegotiation(ptp.MessageSync, testConfig)
+	alt := &ptp.AlternateResponsePortTLV{
+		TLVHead: ptp.TLVHead{TLVType: ptp.TLVAlternateResponsePort, LengthField: 2},
+		Offset:  1,
+	}
+	p := NewSignaling(source, 42, []ptp.TLV{n.Start(start), alt})
+	b, err := ptp.Bytes(p)
+	require.NoError(t, err)
+	require.Len(t, b, 44+10+6+ptp.TrailingBytes)
+	require.Equal(t, uint16(60), p.MessageLength)
+
+	got := &ptp.Signaling{}
+	require.NoError(t, ptp.FromBytes(b, got))
+	require.Equal(t, p, got)
+}
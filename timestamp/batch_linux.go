@@ -0,0 +1,155 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timestamp
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/facebook/time/phc/unix" // a temporary shim for "golang.org/x/sys/unix" until v0.27.0 is cut
+)
+
+// TXTimestamp is a TX timestamp matched to the sent packet by SOF_TIMESTAMPING_OPT_ID
+type TXTimestamp struct {
+	// ID is a sequential number of the packet sent since EnableOptID, starting from 0
+	ID   uint32
+	Time time.Time
+}
+
+// EnableOptID adds SOF_TIMESTAMPING_OPT_ID to timestamping flags already enabled on the socket,
+// so every TX timestamp carries the ID of the packet it belongs to
+func EnableOptID(connFd int) error {
+	return addTimestampingFlags(connFd, unix.SOF_TIMESTAMPING_OPT_ID)
+}
+
+// TXBatch holds buffers to read multiple TX timestamps with a single recvmmsg call.
+// It can be reused, but not concurrently.
+type TXBatch struct {
+	msgs []unix.Mmsghdr
+	oobs [][]byte
+	res  []TXTimestamp
+}
+
+// NewTXBatch allocates TXBatch able to read up to size TX timestamps at once
+func NewTXBatch(size int) *TXBatch {
+	if size < 1 {
+		size = 1
+	}
+	b := &TXBatch{
+		msgs: make([]unix.Mmsghdr, size),
+		oobs: make([][]byte, size),
+		res:  make([]TXTimestamp, 0, size),
+	}
+	for i := range b.msgs {
+		b.oobs[i] = make([]byte, ControlSizeBytes)
+		b.msgs[i].Hdr.Control = &b.oobs[i][0]
+	}
+	return b
+}
+
+func (b *TXBatch) recv(connFd int) (int, error) {
+	for i := range b.msgs {
+		b.msgs[i].Hdr.SetControllen(len(b.oobs[i]))
+		b.msgs[i].Len = 0
+	}
+	for {
+		n, err := unix.Recvmmsg(connFd, b.msgs, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT)
+		if !errors.Is(err, syscall.EINTR) {
+			return n, err
+		}
+	}
+}
+
+// Read drains all pending TX timestamps (up to the batch size) from the socket error queue.
+// If the queue is empty it waits up to TimeoutTXTS for the first timestamp to arrive.
+// Returned slice is only valid until the next Read.
+func (b *TXBatch) Read(connFd int) ([]TXTimestamp, error) {
+	deadline := time.Now().Add(TimeoutTXTS)
+	n, err := b.recv(connFd)
+	for errors.Is(err, unix.EAGAIN) {
+		if waitForHWTS(connFd, deadline) != nil {
+			return nil, fmt.Errorf("no TX timestamp found after %d ms", TimeoutTXTS.Milliseconds())
+		}
+		n, err = b.recv(connFd)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TX timestamps: %w", err)
+	}
+	b.res = b.res[:0]
+	for i := 0; i < n; i++ {
+		ts, err := socketControlMessageTXTimestamp(b.oobs[i][:b.msgs[i].Hdr.Controllen])
+		if err != nil {
+			return b.res, err
+		}
+		b.res = append(b.res, ts)
+	}
+	return b.res, nil
+}
+
+// ReadTXtimestamps returns all pending TX timestamps from the socket error queue, up to size
+func ReadTXtimestamps(connFd int, size int) ([]TXTimestamp, error) {
+	return NewTXBatch(size).Read(connFd)
+}
+
+// socketControlMessageTXTimestamp parses timestamp and packet ID from the error queue control messages
+func socketControlMessageTXTimestamp(b []byte) (TXTimestamp, error) {
+	var res TXTimestamp
+	var tsErr error = errNoTimestamp
+	idFound := false
+	mlen := 0
+	for i := 0; i+socketControlMessageHeaderOffset <= len(b); i += mlen {
+		h := (*unix.Cmsghdr)(unsafe.Pointer(&b[i]))
+		mlen = int(h.Len)
+		if mlen == 0 {
+			break
+		}
+		if mlen < socketControlMessageHeaderOffset || i+mlen > len(b) {
+			return res, fmt.Errorf("malformed socket control message of length %d at offset %d, %d bytes total", mlen, i, len(b))
+		}
+		data := b[i+socketControlMessageHeaderOffset : i+mlen]
+		switch {
+		case h.Level == unix.SOL_SOCKET && (int(h.Type) == unix.SO_TIMESTAMPING_NEW || int(h.Type) == unix.SO_TIMESTAMPING):
+			res.Time, tsErr = scmDataToTime(data)
+		case (h.Level == unix.SOL_IP && h.Type == unix.IP_RECVERR) || (h.Level == unix.SOL_IPV6 && h.Type == unix.IPV6_RECVERR):
+			// short payload can't carry extended error, skip it
+			if len(data) < int(unsafe.Sizeof(unix.SockExtendedErr{})) {
+				break
+			}
+			ee := (*unix.SockExtendedErr)(unsafe.Pointer(&data[0]))
+			if ee.Origin == unix.SO_EE_ORIGIN_TIMESTAMPING {
+				res.ID = ee.Data
+				idFound = true
+			}
+		}
+		// cmsg are aligned
+		mlen = cmsgAlign(mlen)
+	}
+	if tsErr != nil {
+		return res, tsErr
+	}
+	if !idFound {
+		return res, fmt.Errorf("failed to find timestamp ID in socket control message")
+	}
+	return res, nil
+}
+
+func cmsgAlign(l int) int {
+	return (l + unix.SizeofPtr - 1) & ^(unix.SizeofPtr - 1)
+}
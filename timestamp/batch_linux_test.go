@@ -0,0 +1,107 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timestamp
+
+import (
+	"net"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestReadTXtimestamps(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	connFd, err := ConnFd(conn)
+	require.NoError(t, err)
+	require.NoError(t, EnableSWTimestamps(connFd))
+	require.NoError(t, EnableOptID(connFd))
+
+	batch := NewTXBatch(8)
+	_, err = batch.Read(connFd)
+	require.ErrorContains(t, err, "no TX timestamp found")
+
+	addr := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 12345}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err = conn.WriteTo([]byte{}, addr)
+		require.NoError(t, err)
+	}
+	// wait for all timestamps to be queued
+	time.Sleep(10 * time.Millisecond)
+
+	got, err := batch.Read(connFd)
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	for i, ts := range got {
+		require.Equal(t, uint32(i), ts.ID)
+		require.False(t, ts.Time.Before(start))
+	}
+
+	_, err = conn.WriteTo([]byte{}, addr)
+	require.NoError(t, err)
+	got, err = ReadTXtimestamps(connFd, 8)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, uint32(3), got[0].ID)
+}
+
+func TestSocketControlMessageTXTimestampEmpty(t *testing.T) {
+	_, err := socketControlMessageTXTimestamp([]byte{})
+	require.ErrorIs(t, err, errNoTimestamp)
+}
+
+func TestSocketControlMessageTXTimestamp(t *testing.T) {
+	ts := make([]byte, 48)
+	*(*int64)(unsafe.Pointer(&ts[32])) = 1612028735
+	*(*int64)(unsafe.Pointer(&ts[40])) = 717200436
+	ee := make([]byte, unsafe.Sizeof(unix.SockExtendedErr{}))
+	eePtr := (*unix.SockExtendedErr)(unsafe.Pointer(&ee[0]))
+	eePtr.Origin = unix.SO_EE_ORIGIN_TIMESTAMPING
+	eePtr.Data = 42
+
+	// short IP_RECVERR payload must be skipped without breaking alignment of the following messages
+	oob := appendCmsg(nil, unix.SOL_IP, unix.IP_RECVERR, []byte{1, 2, 3})
+	oob = appendCmsg(oob, unix.SOL_SOCKET, unix.SO_TIMESTAMPING_NEW, ts)
+	oob = appendCmsg(oob, unix.SOL_IP, unix.IP_RECVERR, ee)
+	got, err := socketControlMessageTXTimestamp(oob)
+	require.NoError(t, err)
+	require.Equal(t, TXTimestamp{ID: 42, Time: time.Unix(1612028735, 717200436)}, got)
+}
+
+func TestSocketControlMessageTXTimestampMalformed(t *testing.T) {
+	oob := appendCmsg(nil, unix.SOL_IP, unix.IP_RECVERR, make([]byte, unsafe.Sizeof(unix.SockExtendedErr{})))
+
+	// length pointing past the end of the buffer
+	_, err := socketControlMessageTXTimestamp(oob[:len(oob)-4])
+	require.ErrorContains(t, err, "malformed socket control message")
+
+	// length shorter than the header
+	short := append([]byte{}, oob...)
+	(*unix.Cmsghdr)(unsafe.Pointer(&short[0])).SetLen(4)
+	_, err = socketControlMessageTXTimestamp(short)
+	require.ErrorContains(t, err, "malformed socket control message")
+
+	// trailing bytes too short for a header
+	_, err = socketControlMessageTXTimestamp(oob[:4])
+	require.ErrorIs(t, err, errNoTimestamp)
+}
@@ -0,0 +1,192 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timestamp
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultTXCorrelatorTTL is how long we wait for TX timestamp before considering it leaked
+const DefaultTXCorrelatorTTL = time.Second
+
+// TXPending is a sent packet waiting for its TX timestamp
+type TXPending struct {
+	ID   uint32
+	Sent time.Time
+	// Data is an arbitrary value attached by the caller, like a sequence id of the packet
+	Data any
+}
+
+// TXMatch is a sent packet matched to its TX timestamp
+type TXMatch struct {
+	TXPending
+	Time time.Time
+}
+
+// TXCorrelatorStats are counters of TXCorrelator
+type TXCorrelatorStats struct {
+	// Pending is the number of packets waiting for TX timestamp
+	Pending int
+	// Matched is the number of packets matched to TX timestamps
+	Matched uint64
+	// Leaked is the number of packets which never got TX timestamp within TTL
+	Leaked uint64
+	// Misses is the number of TX timestamps we had no pending packet for,
+	// either late ones arriving after expiry or ones for packets never tracked
+	Misses uint64
+	// Overwritten is the number of pending packets replaced by a new one with the same ID
+	Overwritten uint64
+}
+
+// TXCorrelator matches sent packets to their TX timestamps by SOF_TIMESTAMPING_OPT_ID.
+// Sent must be called for every packet sent on the socket after EnableOptID, in order,
+// as the kernel assigns IDs sequentially.
+type TXCorrelator struct {
+	sync.Mutex
+	ttl     time.Duration
+	nextID  uint32
+	pending map[uint32]TXPending
+	stats   TXCorrelatorStats
+	now     func() time.Time
+
+	// OnLeak is called for every packet which expired without TX timestamp, with lock held
+	OnLeak func(TXPending)
+}
+
+// NewTXCorrelator creates TXCorrelator which considers packets leaked after ttl
+func NewTXCorrelator(ttl time.Duration) *TXCorrelator {
+	if ttl <= 0 {
+		ttl = DefaultTXCorrelatorTTL
+	}
+	return &TXCorrelator{
+		ttl:     ttl,
+		pending: map[uint32]TXPending{},
+		now:     time.Now,
+	}
+}
+
+// Sent registers the next packet sent on the socket and returns the ID its TX timestamp will carry
+func (c *TXCorrelator) Sent(data any) uint32 {
+	c.Lock()
+	defer c.Unlock()
+	id := c.nextID
+	c.nextID++
+	c.add(id, data)
+	return id
+}
+
+// Add registers a sent packet with explicit ID, for sockets shared with other senders.
+// Following Sent calls continue from the next ID.
+func (c *TXCorrelator) Add(id uint32, data any) {
+	c.Lock()
+	defer c.Unlock()
+	c.nextID = id + 1
+	c.add(id, data)
+}
+
+func (c *TXCorrelator) add(id uint32, data any) {
+	if _, ok := c.pending[id]; ok {
+		c.stats.Overwritten++
+	}
+	c.pending[id] = TXPending{ID: id, Sent: c.now(), Data: data}
+}
+
+// Match looks up the packet the TX timestamp belongs to and stops tracking it
+func (c *TXCorrelator) Match(ts TXTimestamp) (TXMatch, bool) {
+	c.Lock()
+	defer c.Unlock()
+	return c.match(ts)
+}
+
+func (c *TXCorrelator) match(ts TXTimestamp) (TXMatch, bool) {
+	p, ok := c.pending[ts.ID]
+	if !ok {
+		c.stats.Misses++
+		return TXMatch{}, false
+	}
+	delete(c.pending, ts.ID)
+	c.stats.Matched++
+	return TXMatch{TXPending: p, Time: ts.Time}, true
+}
+
+// MatchAll matches a batch of TX timestamps, like the ones returned by TXBatch.Read.
+// Timestamps without a pending packet are counted as misses and skipped.
+func (c *TXCorrelator) MatchAll(tss []TXTimestamp) []TXMatch {
+	c.Lock()
+	defer c.Unlock()
+	res := make([]TXMatch, 0, len(tss))
+	for _, ts := range tss {
+		if m, ok := c.match(ts); ok {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// Expire stops tracking packets waiting for TX timestamp longer than TTL and returns them
+func (c *TXCorrelator) Expire() []TXPending {
+	c.Lock()
+	defer c.Unlock()
+	now := c.now()
+	var leaked []TXPending
+	for id, p := range c.pending {
+		if now.Sub(p.Sent) < c.ttl {
+			continue
+		}
+		delete(c.pending, id)
+		c.stats.Leaked++
+		leaked = append(leaked, p)
+		if c.OnLeak != nil {
+			c.OnLeak(p)
+		}
+	}
+	sortPending(leaked)
+	return leaked
+}
+
+// Pending returns packets still waiting for TX timestamp
+func (c *TXCorrelator) Pending() []TXPending {
+	c.Lock()
+	defer c.Unlock()
+	res := make([]TXPending, 0, len(c.pending))
+	for _, p := range c.pending {
+		res = append(res, p)
+	}
+	sortPending(res)
+	return res
+}
+
+// sortPending orders packets by the time they were sent
+func sortPending(ps []TXPending) {
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].Sent.Equal(ps[j].Sent) {
+			return ps[i].ID < ps[j].ID
+		}
+		return ps[i].Sent.Before(ps[j].Sent)
+	})
+}
+
+// Stats returns current counters
+func (c *TXCorrelator) Stats() TXCorrelatorStats {
+	c.Lock()
+	defer c.Unlock()
+	s := c.stats
+	s.Pending = len(c.pending)
+	return s
+}
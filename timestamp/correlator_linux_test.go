@@ -0,0 +1,108 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timestamp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTXCorrelator(t *testing.T) {
+	c := NewTXCorrelator(time.Second)
+	now := time.Unix(1700000000, 0)
+	c.now = func() time.Time { return now }
+
+	require.Equal(t, uint32(0), c.Sent("a"))
+	require.Equal(t, uint32(1), c.Sent("b"))
+	now = now.Add(500 * time.Millisecond)
+	require.Equal(t, uint32(2), c.Sent("c"))
+
+	ts := now.Add(time.Microsecond)
+	m, ok := c.Match(TXTimestamp{ID: 1, Time: ts})
+	require.True(t, ok)
+	require.Equal(t, "b", m.Data)
+	require.Equal(t, ts, m.Time)
+
+	_, ok = c.Match(TXTimestamp{ID: 1, Time: ts})
+	require.False(t, ok)
+
+	var leaked []TXPending
+	c.OnLeak = func(p TXPending) { leaked = append(leaked, p) }
+	now = now.Add(600 * time.Millisecond)
+	expired := c.Expire()
+	require.Len(t, expired, 1)
+	require.Equal(t, "a", expired[0].Data)
+	require.Equal(t, expired, leaked)
+
+	pending := c.Pending()
+	require.Len(t, pending, 1)
+	require.Equal(t, uint32(2), pending[0].ID)
+
+	// timestamp arriving after expiry is a miss
+	_, ok = c.Match(TXTimestamp{ID: 0, Time: ts})
+	require.False(t, ok)
+
+	require.Equal(t, TXCorrelatorStats{Pending: 1, Matched: 1, Leaked: 1, Misses: 2}, c.Stats())
+}
+
+func TestTXCorrelatorAdd(t *testing.T) {
+	c := NewTXCorrelator(0)
+	require.Equal(t, DefaultTXCorrelatorTTL, c.ttl)
+
+	c.Add(10, nil)
+	c.Add(10, nil)
+	require.Equal(t, uint32(11), c.Sent(nil))
+
+	matches := c.MatchAll([]TXTimestamp{{ID: 10}, {ID: 11}, {ID: 12}})
+	require.Len(t, matches, 2)
+	require.Equal(t, uint32(10), matches[0].ID)
+	require.Equal(t, uint32(11), matches[1].ID)
+	require.Equal(t, TXCorrelatorStats{Matched: 2, Misses: 1, Overwritten: 1}, c.Stats())
+}
+
+func TestTXCorrelatorSocket(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	connFd, err := ConnFd(conn)
+	require.NoError(t, err)
+	require.NoError(t, EnableSWTimestamps(connFd))
+	require.NoError(t, EnableOptID(connFd))
+
+	c := NewTXCorrelator(time.Second)
+	addr := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 12345}
+	for i := 0; i < 3; i++ {
+		_, err = conn.WriteTo([]byte{}, addr)
+		require.NoError(t, err)
+		c.Sent(i)
+	}
+	// wait for all timestamps to be queued
+	time.Sleep(10 * time.Millisecond)
+
+	tss, err := ReadTXtimestamps(connFd, 8)
+	require.NoError(t, err)
+	matches := c.MatchAll(tss)
+	require.Len(t, matches, 3)
+	for i, m := range matches {
+		require.Equal(t, i, m.Data)
+	}
+	require.Zero(t, c.Stats().Pending)
+}
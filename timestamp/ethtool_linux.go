@@ -0,0 +1,231 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timestamp
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+
+	"github.com/facebook/time/phc/unix" // a temporary shim for "golang.org/x/sys/unix" until v0.27.0 is cut
+)
+
+// TSInfo describes timestamping capabilities of the interface
+type TSInfo struct {
+	// Timestamping is a bitmask of supported SOF_TIMESTAMPING_* flags
+	Timestamping uint32
+	// TxTypes is a bitmask of supported 1<<HWTSTAMP_TX_* types
+	TxTypes uint32
+	// RxFilters is a bitmask of supported 1<<HWTSTAMP_FILTER_* filters
+	RxFilters uint32
+	// PHCIndex is the index of PHC of the interface, -1 if there is none
+	PHCIndex int
+}
+
+// SupportsTxType returns true if HWTSTAMP_TX_* type is supported
+func (i *TSInfo) SupportsTxType(txType int) bool {
+	return i.TxTypes&(1<<txType) != 0
+}
+
+// SupportsRxFilter returns true if HWTSTAMP_FILTER_* filter is supported
+func (i *TSInfo) SupportsRxFilter(filter int) bool {
+	return i.RxFilters&(1<<filter) != 0
+}
+
+// SupportsHW returns true if the interface can timestamp both TX and RX in hardware
+func (i *TSInfo) SupportsHW() bool {
+	hwFlags := uint32(unix.SOF_TIMESTAMPING_TX_HARDWARE | unix.SOF_TIMESTAMPING_RX_HARDWARE | unix.SOF_TIMESTAMPING_RAW_HARDWARE)
+	return i.Timestamping&hwFlags == hwFlags && i.SupportsTxType(unix.HWTSTAMP_TX_ON)
+}
+
+// GetTSInfo returns timestamping capabilities of the interface.
+// It uses ethtool netlink API and falls back to SIOCETHTOOL ioctl on kernels without it.
+func GetTSInfo(iface string) (*TSInfo, error) {
+	info, err := TSInfoNetlink(iface)
+	if err == nil {
+		return info, nil
+	}
+	fd, serr := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if serr != nil {
+		return nil, fmt.Errorf("failed to get ts info via netlink (%w), failed to create socket: %w", err, serr)
+	}
+	defer unix.Close(fd)
+	info, ierr := TSInfoIoctl(fd, iface)
+	if ierr != nil {
+		return nil, fmt.Errorf("failed to get ts info via netlink (%w) and ioctl: %w", err, ierr)
+	}
+	return info, nil
+}
+
+// TSInfoIoctl returns timestamping capabilities of the interface using SIOCETHTOOL ioctl
+func TSInfoIoctl(fd int, iface string) (*TSInfo, error) {
+	hw, err := unix.IoctlGetEthtoolTsInfo(fd, iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run ioctl SIOCETHTOOL: %w", err)
+	}
+	return &TSInfo{
+		Timestamping: hw.So_timestamping,
+		TxTypes:      hw.Tx_types,
+		RxFilters:    hw.Rx_filters,
+		PHCIndex:     int(hw.Phc_index),
+	}, nil
+}
+
+// TSInfoNetlink returns timestamping capabilities of the interface using ETHTOOL_MSG_TSINFO_GET
+func TSInfoNetlink(iface string) (*TSInfo, error) {
+	conn, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial generic netlink: %w", err)
+	}
+	defer conn.Close()
+
+	family, err := genlFamilyID(conn, unix.ETHTOOL_GENL_NAME)
+	if err != nil {
+		return nil, err
+	}
+
+	ae := netlink.NewAttributeEncoder()
+	ae.Nested(unix.ETHTOOL_A_TSINFO_HEADER, func(nae *netlink.AttributeEncoder) error {
+		nae.String(unix.ETHTOOL_A_HEADER_DEV_NAME, iface)
+		nae.Uint32(unix.ETHTOOL_A_HEADER_FLAGS, unix.ETHTOOL_FLAG_COMPACT_BITSETS)
+		return nil
+	})
+	reply, err := genlExecute(conn, family, unix.ETHTOOL_MSG_TSINFO_GET, unix.ETHTOOL_GENL_VERSION, ae)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ts info for %s: %w", iface, err)
+	}
+	return parseTSInfo(reply)
+}
+
+// genlExecute sends generic netlink command and returns attributes of the single reply
+func genlExecute(conn *netlink.Conn, family uint16, cmd, version uint8, ae *netlink.AttributeEncoder) ([]byte, error) {
+	attrs, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+	// struct genlmsghdr
+	data := append([]byte{cmd, version, 0, 0}, attrs...)
+	msgs, err := conn.Execute(netlink.Message{
+		Header: netlink.Header{Type: netlink.HeaderType(family), Flags: netlink.Request},
+		Data:   data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) != 1 {
+		return nil, fmt.Errorf("expected 1 netlink reply, got %d", len(msgs))
+	}
+	if len(msgs[0].Data) < 4 {
+		return nil, fmt.Errorf("short generic netlink reply")
+	}
+	return msgs[0].Data[4:], nil
+}
+
+// genlFamilyID resolves generic netlink family name into its ID
+func genlFamilyID(conn *netlink.Conn, name string) (uint16, error) {
+	ae := netlink.NewAttributeEncoder()
+	ae.String(unix.CTRL_ATTR_FAMILY_NAME, name)
+	reply, err := genlExecute(conn, unix.GENL_ID_CTRL, unix.CTRL_CMD_GETFAMILY, 1, ae)
+	if errors.Is(err, syscall.ENOENT) {
+		return 0, fmt.Errorf("generic netlink family %q is not supported by the kernel: %w", name, err)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve generic netlink family %q: %w", name, err)
+	}
+	ad, err := netlink.NewAttributeDecoder(reply)
+	if err != nil {
+		return 0, err
+	}
+	for ad.Next() {
+		if ad.Type() == unix.CTRL_ATTR_FAMILY_ID {
+			return ad.Uint16(), nil
+		}
+	}
+	if err := ad.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no family id for generic netlink family %q", name)
+}
+
+// parseTSInfo parses attributes of ETHTOOL_MSG_TSINFO_GET_REPLY
+func parseTSInfo(b []byte) (*TSInfo, error) {
+	ad, err := netlink.NewAttributeDecoder(b)
+	if err != nil {
+		return nil, err
+	}
+	info := &TSInfo{PHCIndex: -1}
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.ETHTOOL_A_TSINFO_TIMESTAMPING:
+			ad.Nested(bitsetDecoder(&info.Timestamping))
+		case unix.ETHTOOL_A_TSINFO_TX_TYPES:
+			ad.Nested(bitsetDecoder(&info.TxTypes))
+		case unix.ETHTOOL_A_TSINFO_RX_FILTERS:
+			ad.Nested(bitsetDecoder(&info.RxFilters))
+		case unix.ETHTOOL_A_TSINFO_PHC_INDEX:
+			info.PHCIndex = int(int32(ad.Uint32())) //#nosec G115
+		}
+	}
+	if err := ad.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse ts info: %w", err)
+	}
+	return info, nil
+}
+
+// bitsetDecoder decodes ethtool bitset, either compact or verbose, into the first 32 bits
+func bitsetDecoder(v *uint32) func(*netlink.AttributeDecoder) error {
+	return func(ad *netlink.AttributeDecoder) error {
+		for ad.Next() {
+			switch ad.Type() {
+			case unix.ETHTOOL_A_BITSET_VALUE:
+				b := ad.Bytes()
+				if len(b) >= 4 {
+					*v = nlenc.Uint32(b[:4])
+				}
+			case unix.ETHTOOL_A_BITSET_BITS:
+				ad.Nested(func(bits *netlink.AttributeDecoder) error {
+					for bits.Next() {
+						if bits.Type() != unix.ETHTOOL_A_BITSET_BITS_BIT {
+							continue
+						}
+						bits.Nested(func(bit *netlink.AttributeDecoder) error {
+							var index uint32
+							set := false
+							for bit.Next() {
+								switch bit.Type() {
+								case unix.ETHTOOL_A_BITSET_BIT_INDEX:
+									index = bit.Uint32()
+								case unix.ETHTOOL_A_BITSET_BIT_VALUE:
+									set = true
+								}
+							}
+							if set && index < 32 {
+								*v |= 1 << index
+							}
+							return nil
+						})
+					}
+					return nil
+				})
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timestamp
+
+import (
+	"testing"
+
+	"github.com/mdlayher/netlink"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseTSInfoCompact(t *testing.T) {
+	ae := netlink.NewAttributeEncoder()
+	ae.Nested(unix.ETHTOOL_A_TSINFO_TIMESTAMPING, func(nae *netlink.AttributeEncoder) error {
+		nae.Flag(unix.ETHTOOL_A_BITSET_NOMASK, true)
+		nae.Uint32(unix.ETHTOOL_A_BITSET_SIZE, 17)
+		nae.Uint32(unix.ETHTOOL_A_BITSET_VALUE, 0x45)
+		return nil
+	})
+	ae.Nested(unix.ETHTOOL_A_TSINFO_TX_TYPES, func(nae *netlink.AttributeEncoder) error {
+		nae.Flag(unix.ETHTOOL_A_BITSET_NOMASK, true)
+		nae.Uint32(unix.ETHTOOL_A_BITSET_VALUE, 0x7)
+		return nil
+	})
+	ae.Nested(unix.ETHTOOL_A_TSINFO_RX_FILTERS, func(nae *netlink.AttributeEncoder) error {
+		nae.Flag(unix.ETHTOOL_A_BITSET_NOMASK, true)
+		nae.Uint32(unix.ETHTOOL_A_BITSET_VALUE, 1<<unix.HWTSTAMP_FILTER_ALL)
+		return nil
+	})
+	ae.Uint32(unix.ETHTOOL_A_TSINFO_PHC_INDEX, 2)
+	b, err := ae.Encode()
+	require.NoError(t, err)
+
+	info, err := parseTSInfo(b)
+	require.NoError(t, err)
+	require.Equal(t, &TSInfo{Timestamping: 0x45, TxTypes: 0x7, RxFilters: 0x2, PHCIndex: 2}, info)
+	require.True(t, info.SupportsHW())
+	require.True(t, info.SupportsTxType(unix.HWTSTAMP_TX_ONESTEP_SYNC))
+	require.True(t, info.SupportsRxFilter(unix.HWTSTAMP_FILTER_ALL))
+	require.False(t, info.SupportsRxFilter(unix.HWTSTAMP_FILTER_PTP_V2_L4_EVENT))
+}
+
+func TestParseTSInfoVerbose(t *testing.T) {
+	ae := netlink.NewAttributeEncoder()
+	ae.Nested(unix.ETHTOOL_A_TSINFO_TIMESTAMPING, func(nae *netlink.AttributeEncoder) error {
+		nae.Nested(unix.ETHTOOL_A_BITSET_BITS, func(bits *netlink.AttributeEncoder) error {
+			for _, index := range []uint32{1, 3, 4} {
+				bits.Nested(unix.ETHTOOL_A_BITSET_BITS_BIT, func(bit *netlink.AttributeEncoder) error {
+					bit.Uint32(unix.ETHTOOL_A_BITSET_BIT_INDEX, index)
+					bit.String(unix.ETHTOOL_A_BITSET_BIT_NAME, "whatever")
+					bit.Flag(unix.ETHTOOL_A_BITSET_BIT_VALUE, true)
+					return nil
+				})
+			}
+			return nil
+		})
+		return nil
+	})
+	b, err := ae.Encode()
+	require.NoError(t, err)
+
+	info, err := parseTSInfo(b)
+	require.NoError(t, err)
+	require.Equal(t, &TSInfo{Timestamping: 0x1a, PHCIndex: -1}, info)
+	require.False(t, info.SupportsHW())
+}
+
+func TestGetTSInfoLoopback(t *testing.T) {
+	info, err := GetTSInfo("lo")
+	require.NoError(t, err)
+	require.Equal(t, -1, info.PHCIndex)
+	require.False(t, info.SupportsHW())
+	require.NotZero(t, info.Timestamping&unix.SOF_TIMESTAMPING_SOFTWARE)
+
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	require.NoError(t, err)
+	defer unix.Close(fd)
+	ioctlInfo, err := TSInfoIoctl(fd, "lo")
+	require.NoError(t, err)
+	require.Equal(t, ioctlInfo, info)
+}
+
+func TestTSInfoNetlinkNoInterface(t *testing.T) {
+	_, err := TSInfoNetlink("nosuchiface0")
+	require.Error(t, err)
+}
@@ -0,0 +1,169 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timestamp
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/net/bpf"
+
+	"github.com/facebook/time/hostendian"
+	"github.com/facebook/time/phc/unix" // a temporary shim for "golang.org/x/sys/unix" until v0.27.0 is cut
+)
+
+// PTP over IEEE 802.3 multicast addresses
+var (
+	// PTPMulticast is used for all messages except peer delay ones
+	PTPMulticast = net.HardwareAddr{0x01, 0x1b, 0x19, 0x00, 0x00, 0x00}
+	// PTPPeerMulticast is used for peer delay messages
+	PTPPeerMulticast = net.HardwareAddr{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e}
+)
+
+// rxFiltersL2 are RX filters suitable for PTP over Ethernet, in the order of preference
+var rxFiltersL2 = []int32{unix.HWTSTAMP_FILTER_PTP_V2_L2_EVENT, unix.HWTSTAMP_FILTER_PTP_V2_EVENT, unix.HWTSTAMP_FILTER_ALL}
+
+// ptpFilter accepts only frames with PTP ethertype
+var ptpFilter = []bpf.Instruction{
+	bpf.LoadExtension{Num: bpf.ExtProto},
+	bpf.JumpIf{Cond: bpf.JumpEqual, Val: unix.ETH_P_1588, SkipFalse: 1},
+	bpf.RetConstant{Val: 0xffff},
+	bpf.RetConstant{Val: 0},
+}
+
+// htons converts ethertype to network byte order expected by AF_PACKET
+func htons(v uint16) uint16 {
+	if hostendian.IsBigEndian {
+		return v
+	}
+	return v<<8 | v>>8
+}
+
+// PacketConn is an AF_PACKET socket sending and receiving PTP messages over Ethernet.
+// Kernel adds and strips Ethernet header, so payload is a PTP message.
+type PacketConn struct {
+	fd    int
+	iface *net.Interface
+}
+
+// NewPacketConn opens AF_PACKET socket bound to the interface which receives only PTP frames
+func NewPacketConn(iface *net.Interface) (*PacketConn, error) {
+	// protocol 0 means no frames are received until bind
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AF_PACKET socket: %w", err)
+	}
+	c := &PacketConn{fd: fd, iface: iface}
+	if err := c.attachFilter(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	sa := &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_1588), Ifindex: iface.Index}
+	if err := unix.Bind(fd, sa); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("failed to bind AF_PACKET socket to %s: %w", iface.Name, err)
+	}
+	return c, nil
+}
+
+func (c *PacketConn) attachFilter() error {
+	raw, err := bpf.Assemble(ptpFilter)
+	if err != nil {
+		return fmt.Errorf("failed to assemble PTP BPF filter: %w", err)
+	}
+	filter := make([]unix.SockFilter, len(raw))
+	for i, ins := range raw {
+		filter[i] = unix.SockFilter{Code: ins.Op, Jt: ins.Jt, Jf: ins.Jf, K: ins.K}
+	}
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]} //#nosec G115
+	if err := unix.SetsockoptSockFprog(c.fd, unix.SOL_SOCKET, unix.SO_ATTACH_FILTER, &prog); err != nil {
+		return fmt.Errorf("failed to attach PTP BPF filter: %w", err)
+	}
+	return nil
+}
+
+// Fd returns file descriptor of the socket
+func (c *PacketConn) Fd() int {
+	return c.fd
+}
+
+// Close closes the socket
+func (c *PacketConn) Close() error {
+	return unix.Close(c.fd)
+}
+
+// JoinMulticast subscribes the socket to frames sent to the multicast address, like PTPMulticast
+func (c *PacketConn) JoinMulticast(addr net.HardwareAddr) error {
+	mreq := unix.PacketMreq{
+		Ifindex: int32(c.iface.Index), //#nosec G115
+		Type:    unix.PACKET_MR_MULTICAST,
+		Alen:    uint16(len(addr)), //#nosec G115
+	}
+	copy(mreq.Address[:], addr)
+	if err := unix.SetsockoptPacketMreq(c.fd, unix.SOL_PACKET, unix.PACKET_ADD_MEMBERSHIP, &mreq); err != nil {
+		return fmt.Errorf("failed to join %s on %s: %w", addr, c.iface.Name, err)
+	}
+	return nil
+}
+
+// EnableTimestamps enables timestamps of given type on the socket.
+// With RXFilterAuto hardware RX filter is picked to match PTP over Ethernet.
+func (c *PacketConn) EnableTimestamps(ts Timestamp) error {
+	switch ts {
+	case HW:
+		if err := enableHWTimestamps(c.fd, c.iface.Name, TXTypeOn, HWRXFilter.rxFilters(rxFiltersL2)); err != nil {
+			return fmt.Errorf("cannot enable hardware timestamps: %w", err)
+		}
+		return nil
+	default:
+		return EnableTimestamps(ts, c.fd, c.iface.Name)
+	}
+}
+
+// WriteTo sends PTP message to the hardware address
+func (c *PacketConn) WriteTo(b []byte, addr net.HardwareAddr) error {
+	sa := &unix.SockaddrLinklayer{
+		Protocol: htons(unix.ETH_P_1588),
+		Ifindex:  c.iface.Index,
+		Halen:    uint8(len(addr)), //#nosec G115
+	}
+	copy(sa.Addr[:], addr)
+	return unix.Sendto(c.fd, b, 0, sa)
+}
+
+// WriteToWithTS sends PTP message to the hardware address and returns its TX timestamp
+func (c *PacketConn) WriteToWithTS(b []byte, addr net.HardwareAddr) (time.Time, error) {
+	if err := c.WriteTo(b, addr); err != nil {
+		return time.Time{}, err
+	}
+	ts, _, err := ReadTXtimestamp(c.fd)
+	return ts, err
+}
+
+// ReadPacketWithRXTimestamp reads PTP message into buf and returns its length, source address and RX timestamp
+func (c *PacketConn) ReadPacketWithRXTimestamp(buf, oob []byte) (int, net.HardwareAddr, time.Time, error) {
+	n, sa, ts, err := ReadPacketWithRXTimestampBuf(c.fd, buf, oob)
+	if err != nil {
+		return 0, nil, time.Time{}, err
+	}
+	var src net.HardwareAddr
+	if ll, ok := sa.(*unix.SockaddrLinklayer); ok {
+		src = net.HardwareAddr(append([]byte{}, ll.Addr[:ll.Halen]...))
+	}
+	return n, src, ts, nil
+}
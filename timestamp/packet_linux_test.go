@@ -0,0 +1,72 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timestamp
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestHtons(t *testing.T) {
+	b := []byte{0, 0}
+	v := htons(unix.ETH_P_1588)
+	*(*uint16)(unsafe.Pointer(&b[0])) = v
+	require.Equal(t, []byte{0x88, 0xf7}, b)
+}
+
+func TestPacketConnLoopback(t *testing.T) {
+	lo, err := net.InterfaceByName("lo")
+	require.NoError(t, err)
+	conn, err := NewPacketConn(lo)
+	if errors.Is(err, unix.EPERM) {
+		t.Skip("AF_PACKET sockets require CAP_NET_RAW")
+	}
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.JoinMulticast(PTPMulticast))
+	// no hardware timestamps on loopback
+	require.Error(t, conn.EnableTimestamps(HW))
+	require.NoError(t, conn.EnableTimestamps(SW))
+
+	// non-PTP frames are filtered out
+	other, err := unix.Socket(unix.AF_PACKET, unix.SOCK_DGRAM, 0)
+	require.NoError(t, err)
+	defer unix.Close(other)
+	sa := &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_IP), Ifindex: lo.Index, Halen: 6}
+	copy(sa.Addr[:], PTPMulticast)
+	require.NoError(t, unix.Sendto(other, []byte{0x45, 0, 0, 20}, 0, sa))
+
+	msg := []byte{0x12, 0x02, 0x00, 0x2c}
+	start := time.Now()
+	txts, err := conn.WriteToWithTS(msg, PTPMulticast)
+	require.NoError(t, err)
+	require.False(t, txts.Before(start))
+
+	buf := make([]byte, PayloadSizeBytes)
+	oob := make([]byte, ControlSizeBytes)
+	n, _, rxts, err := conn.ReadPacketWithRXTimestamp(buf, oob)
+	require.NoError(t, err)
+	require.Equal(t, msg, buf[:n])
+	require.False(t, rxts.Before(start))
+}
@@ -0,0 +1,87 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timestamp
+
+import (
+	"fmt"
+	"net"
+	"time"
+	"unsafe"
+
+	"github.com/facebook/time/phc/unix" // a temporary shim for "golang.org/x/sys/unix" until v0.27.0 is cut
+)
+
+// PktInfo is the information about the received packet reported with its hardware timestamp
+type PktInfo struct {
+	// IfIndex is the index of the interface which timestamped the packet
+	IfIndex int
+	// Length is the length of the packet at the L2 level
+	Length int
+}
+
+// Interface returns the interface which timestamped the packet
+func (p *PktInfo) Interface() (*net.Interface, error) {
+	return net.InterfaceByIndex(p.IfIndex)
+}
+
+// EnablePktInfo adds SOF_TIMESTAMPING_OPT_PKTINFO to timestamping flags already enabled on the socket,
+// so hardware RX timestamps carry the index of the interface they were taken on
+func EnablePktInfo(connFd int) error {
+	return addTimestampingFlags(connFd, unix.SOF_TIMESTAMPING_OPT_PKTINFO)
+}
+
+// ReadPacketWithRXTimestampPktInfo is like ReadPacketWithRXTimestampBuf, but also returns PktInfo.
+// PktInfo is nil if the kernel didn't provide it, which is the case for software timestamps.
+func ReadPacketWithRXTimestampPktInfo(connFd int, buf, oob []byte) (int, unix.Sockaddr, time.Time, *PktInfo, error) {
+	bbuf, boob, _, saddr, err := unix.Recvmsg(connFd, buf, oob, 0)
+	if err != nil {
+		return 0, nil, time.Time{}, nil, fmt.Errorf("failed to read timestamp: %w", err)
+	}
+
+	timestamp, err := socketControlMessageTimestamp(oob[:boob])
+	return bbuf, saddr, timestamp, socketControlMessagePktInfo(oob[:boob]), err
+}
+
+// CheckInterface returns an error if the timestamp was taken on another interface than expected
+func CheckInterface(info *PktInfo, iface *net.Interface) error {
+	if info == nil {
+		return fmt.Errorf("no packet info for the timestamp")
+	}
+	if info.IfIndex != iface.Index {
+		return fmt.Errorf("timestamp taken on interface index %d, expected %s (%d)", info.IfIndex, iface.Name, iface.Index)
+	}
+	return nil
+}
+
+// socketControlMessagePktInfo finds SCM_TIMESTAMPING_PKTINFO in control messages
+func socketControlMessagePktInfo(b []byte) *PktInfo {
+	mlen := 0
+	for i := 0; i < len(b); i += mlen {
+		h := (*unix.Cmsghdr)(unsafe.Pointer(&b[i]))
+		mlen = int(h.Len)
+		if mlen == 0 {
+			break
+		}
+		data := b[i+socketControlMessageHeaderOffset : i+mlen]
+		if h.Level == unix.SOL_SOCKET && h.Type == unix.SCM_TIMESTAMPING_PKTINFO && len(data) >= int(unsafe.Sizeof(unix.ScmTsPktinfo{})) {
+			info := (*unix.ScmTsPktinfo)(unsafe.Pointer(&data[0]))
+			return &PktInfo{IfIndex: int(info.If_index), Length: int(info.Pkt_length)}
+		}
+		mlen = cmsgAlign(mlen)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timestamp
+
+import (
+	"net"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// appendCmsg appends control message with given level, type and data
+func appendCmsg(b []byte, level, typ int32, data []byte) []byte {
+	msg := make([]byte, unix.CmsgSpace(len(data)))
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&msg[0]))
+	h.Level = level
+	h.Type = typ
+	h.SetLen(unix.CmsgLen(len(data)))
+	copy(msg[unix.CmsgLen(0):], data)
+	return append(b, msg...)
+}
+
+func TestSocketControlMessagePktInfo(t *testing.T) {
+	ts := make([]byte, 48)
+	*(*int64)(unsafe.Pointer(&ts[32])) = 1612028735
+	*(*int64)(unsafe.Pointer(&ts[40])) = 717200436
+	// struct scm_ts_pktinfo
+	infob := make([]byte, 16)
+	*(*uint32)(unsafe.Pointer(&infob[0])) = 3
+	*(*uint32)(unsafe.Pointer(&infob[4])) = 86
+
+	oob := appendCmsg(nil, unix.SOL_SOCKET, unix.SO_TIMESTAMPING_NEW, ts)
+	require.Nil(t, socketControlMessagePktInfo(oob))
+
+	oob = appendCmsg(oob, unix.SOL_SOCKET, unix.SCM_TIMESTAMPING_PKTINFO, infob)
+	got := socketControlMessagePktInfo(oob)
+	require.Equal(t, &PktInfo{IfIndex: 3, Length: 86}, got)
+
+	hwts, err := socketControlMessageTimestamp(oob)
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1612028735, 717200436), hwts)
+}
+
+func TestCheckInterface(t *testing.T) {
+	iface := &net.Interface{Index: 3, Name: "eth0"}
+	require.NoError(t, CheckInterface(&PktInfo{IfIndex: 3}, iface))
+	require.ErrorContains(t, CheckInterface(&PktInfo{IfIndex: 4}, iface), "expected eth0 (3)")
+	require.Error(t, CheckInterface(nil, iface))
+}
+
+func TestEnablePktInfo(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	connFd, err := ConnFd(conn)
+	require.NoError(t, err)
+	require.NoError(t, EnableSWTimestampsRx(connFd))
+	require.NoError(t, EnablePktInfo(connFd))
+
+	flags, err := unix.GetsockoptInt(connFd, unix.SOL_SOCKET, unix.SO_TIMESTAMPING)
+	require.NoError(t, err)
+	require.Equal(t, unix.SOF_TIMESTAMPING_RX_SOFTWARE|unix.SOF_TIMESTAMPING_SOFTWARE|unix.SOF_TIMESTAMPING_OPT_PKTINFO, flags)
+
+	// software timestamps come without packet info
+	addr := conn.LocalAddr().(*net.UDPAddr)
+	_, err = conn.WriteTo([]byte{1, 2, 3}, addr)
+	require.NoError(t, err)
+	buf := make([]byte, PayloadSizeBytes)
+	oob := make([]byte, ControlSizeBytes)
+	n, _, ts, info, err := ReadPacketWithRXTimestampPktInfo(connFd, buf, oob)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.NotEqual(t, time.Time{}, ts)
+	require.Nil(t, info)
+}
@@ -21,10 +21,8 @@ package timestamp
 import (
 	"fmt"
 	"net"
-	"net/netip"
+	"slices"
 	"time"
-
-	"github.com/facebook/time/phc/unix" // a temporary shim for "golang.org/x/sys/unix" until v0.27.0 is cut
 )
 
 const (
@@ -112,114 +110,226 @@ func (t *Timestamp) Type() string {
 	return "timestamp"
 }
 
-// AttemptsTXTS is configured amount of attempts to read TX timestamp
-var AttemptsTXTS = defaultTXTS
+// RXFilter is a hardware RX timestamping filter
+type RXFilter int
 
-// TimeoutTXTS is configured timeout to read TX timestamp
-var TimeoutTXTS = time.Millisecond
+const (
+	// RXFilterAuto picks the most specific PTP filter supported by the NIC, falling back to all packets
+	RXFilterAuto RXFilter = iota
+	// RXFilterAll timestamps all received packets
+	RXFilterAll
+	// RXFilterPTPV2Event timestamps PTPv2 event messages over any transport
+	RXFilterPTPV2Event
+	// RXFilterPTPV2L4Event timestamps PTPv2 event messages over UDP
+	RXFilterPTPV2L4Event
+	// RXFilterPTPV2L2Event timestamps PTPv2 event messages over Ethernet
+	RXFilterPTPV2L2Event
+)
 
-// ConnFd returns file descriptor of a connection
-func ConnFd(conn *net.UDPConn) (int, error) {
-	sc, err := conn.SyscallConn()
-	if err != nil {
-		return -1, err
+// rxFilterToString is a map from RXFilter to string
+var rxFilterToString = map[RXFilter]string{
+	RXFilterAuto:         "auto",
+	RXFilterAll:          "all",
+	RXFilterPTPV2Event:   "ptpv2_event",
+	RXFilterPTPV2L4Event: "ptpv2_l4_event",
+	RXFilterPTPV2L2Event: "ptpv2_l2_event",
+}
+
+// String RX filter to string
+func (f RXFilter) String() string {
+	v, ok := rxFilterToString[f]
+	if ok {
+		return v
 	}
-	var intfd int
-	err = sc.Control(func(fd uintptr) {
-		intfd = int(fd)
-	})
-	if err != nil {
-		return -1, err
+	return Unsupported
+}
+
+// MarshalText RX filter to byte slice
+func (f RXFilter) MarshalText() ([]byte, error) {
+	_, ok := rxFilterToString[f]
+	if ok {
+		return []byte(f.String()), nil
 	}
-	return intfd, nil
+	return []byte(Unsupported), fmt.Errorf("unknown rx filter %d", f)
 }
 
-// ReadPacketWithRXTimestamp returns byte packet and HW RX timestamp
-func ReadPacketWithRXTimestamp(connFd int) ([]byte, unix.Sockaddr, time.Time, error) {
-	// Accessing hw timestamp
-	buf := make([]byte, PayloadSizeBytes)
-	oob := make([]byte, ControlSizeBytes)
+// UnmarshalText RX filter from byte slice
+func (f *RXFilter) UnmarshalText(value []byte) error {
+	return f.Set(string(value))
+}
 
-	bbuf, sa, t, err := ReadPacketWithRXTimestampBuf(connFd, buf, oob)
-	return buf[:bbuf], sa, t, err
+// Set RX filter from string
+func (f *RXFilter) Set(value string) error {
+	for k, v := range rxFilterToString {
+		if v == value {
+			*f = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown rx filter %q", value)
 }
 
-// ReadPacketWithRXTimestampBuf writes byte packet into provide buffer buf, and returns number of bytes copied to the buffer, client ip and HW RX timestamp.
-// oob buffer can be reaused after ReadPacketWithRXTimestampBuf call.
-func ReadPacketWithRXTimestampBuf(connFd int, buf, oob []byte) (int, unix.Sockaddr, time.Time, error) {
-	bbuf, boob, _, saddr, err := unix.Recvmsg(connFd, buf, oob, 0)
-	if err != nil {
-		return 0, nil, time.Time{}, fmt.Errorf("failed to read timestamp: %w", err)
+// Type is required by the cobra.Value interface
+func (f *RXFilter) Type() string {
+	return "rxfilter"
+}
+
+// RXFilterNames returns names of all supported RX filters, ordered by value
+func RXFilterNames() []string {
+	filters := make([]RXFilter, 0, len(rxFilterToString))
+	for f := range rxFilterToString {
+		filters = append(filters, f)
+	}
+	slices.Sort(filters)
+	names := make([]string, 0, len(filters))
+	for _, f := range filters {
+		names = append(names, f.String())
 	}
+	return names
+}
+
+// HWRXFilter is the RX filter requested when enabling hardware timestamps.
+// Filtering all packets hurts performance on some NICs, while others only support it.
+var HWRXFilter = RXFilterAuto
+
+// TXType is a hardware TX timestamping mode
+type TXType int
+
+const (
+	// TXTypeOn timestamps all sent event messages, timestamps are read back from the socket (two-step)
+	TXTypeOn TXType = iota
+	// TXTypeOneStepSync makes the NIC insert TX timestamp into Sync messages on the fly (one-step),
+	// TX timestamps of Sync messages are not reported back
+	TXTypeOneStepSync
+	// TXTypeOneStepP2P is TXTypeOneStepSync which additionally handles Pdelay_Resp messages
+	TXTypeOneStepP2P
+)
+
+// txTypeToString is a map from TXType to string
+var txTypeToString = map[TXType]string{
+	TXTypeOn:          "on",
+	TXTypeOneStepSync: "onestep_sync",
+	TXTypeOneStepP2P:  "onestep_p2p",
+}
 
-	timestamp, err := socketControlMessageTimestamp(oob[:boob])
-	return bbuf, saddr, timestamp, err
+// String TX type to string
+func (t TXType) String() string {
+	v, ok := txTypeToString[t]
+	if ok {
+		return v
+	}
+	return Unsupported
 }
 
-// IPToSockaddr converts IP + port into a socket address
-// Somewhat copy from https://github.com/golang/go/blob/16cd770e0668a410a511680b2ac1412e554bd27b/src/net/ipsock_posix.go#L145
-func IPToSockaddr(ip net.IP, port int) unix.Sockaddr {
-	if ip.To4() != nil {
-		sa := &unix.SockaddrInet4{Port: port}
-		copy(sa.Addr[:], ip.To4())
-		return sa
+// MarshalText TX type to byte slice
+func (t TXType) MarshalText() ([]byte, error) {
+	_, ok := txTypeToString[t]
+	if ok {
+		return []byte(t.String()), nil
 	}
-	sa := &unix.SockaddrInet6{Port: port}
-	copy(sa.Addr[:], ip.To16())
-	return sa
+	return []byte(Unsupported), fmt.Errorf("unknown tx type %d", t)
 }
 
-// AddrToSockaddr converts netip.Addr + port into a socket address
-func AddrToSockaddr(ip netip.Addr, port int) unix.Sockaddr {
-	if ip.Is4() {
-		return &unix.SockaddrInet4{Port: port, Addr: ip.As4()}
+// UnmarshalText TX type from byte slice
+func (t *TXType) UnmarshalText(value []byte) error {
+	return t.Set(string(value))
+}
+
+// Set TX type from string
+func (t *TXType) Set(value string) error {
+	for k, v := range txTypeToString {
+		if v == value {
+			*t = k
+			return nil
+		}
 	}
-	return &unix.SockaddrInet6{Port: port, Addr: ip.As16()}
+	return fmt.Errorf("unknown tx type %q", value)
 }
 
-// SockaddrToIP converts socket address to an IP
-// Somewhat copy from https://github.com/golang/go/blob/658b5e66ecbc41a49e6fb5aa63c5d9c804cf305f/src/net/udpsock_posix.go#L15
-func SockaddrToIP(sa unix.Sockaddr) net.IP {
-	switch sa := sa.(type) {
-	case *unix.SockaddrInet4:
-		return sa.Addr[0:]
-	case *unix.SockaddrInet6:
-		return sa.Addr[0:]
+// Type is required by the cobra.Value interface
+func (t *TXType) Type() string {
+	return "txtype"
+}
+
+// OneStep returns true if Sync messages are timestamped by the NIC on the fly
+func (t TXType) OneStep() bool {
+	return t == TXTypeOneStepSync || t == TXTypeOneStepP2P
+}
+
+// BusyPoll is a socket busy polling configuration
+type BusyPoll struct {
+	// Timeout is how long to busy poll the device queue on blocking reads, 0 disables busy polling
+	Timeout time.Duration
+	// Budget is the maximum number of packets processed per busy poll, 0 keeps the kernel default
+	Budget int
+	// Prefer suppresses device interrupts while busy polling is active
+	Prefer bool
+}
+
+// Validate checks budget and preference are only set together with timeout
+func (b BusyPoll) Validate() error {
+	if b.Timeout < 0 || b.Budget < 0 {
+		return fmt.Errorf("busy poll timeout and budget must not be negative")
+	}
+	if b.Timeout == 0 && (b.Budget > 0 || b.Prefer) {
+		return fmt.Errorf("busy poll budget and preference require busy poll timeout")
 	}
 	return nil
 }
 
-// SockaddrToAddr converts socket address to a netip.Addr
-// Somewhat copy from https://github.com/golang/go/blob/658b5e66ecbc41a49e6fb5aa63c5d9c804cf305f/src/net/udpsock_posix.go#L15
-func SockaddrToAddr(sa unix.Sockaddr) netip.Addr {
-	switch sa := sa.(type) {
-	case *unix.SockaddrInet4:
-		return netip.AddrFrom4(sa.Addr)
-	case *unix.SockaddrInet6:
-		return netip.AddrFrom16(sa.Addr)
+// AttemptsTXTS is configured amount of attempts to read TX timestamp
+var AttemptsTXTS = defaultTXTS
+
+// TimeoutTXTS is configured timeout to read TX timestamp
+var TimeoutTXTS = time.Millisecond
+
+// ConnFd returns file descriptor of a connection
+func ConnFd(conn *net.UDPConn) (int, error) {
+	sc, err := conn.SyscallConn()
+	if err != nil {
+		return -1, err
+	}
+	var intfd int
+	err = sc.Control(func(fd uintptr) {
+		intfd = int(fd)
+	})
+	if err != nil {
+		return -1, err
 	}
-	return netip.Addr{}
+	return intfd, nil
 }
 
-// SockaddrToPort converts socket address to an IP
-// Somewhat copy from https://github.com/golang/go/blob/658b5e66ecbc41a49e6fb5aa63c5d9c804cf305f/src/net/udpsock_posix.go#L15
-func SockaddrToPort(sa unix.Sockaddr) int {
-	switch sa := sa.(type) {
-	case *unix.SockaddrInet4:
-		return sa.Port
-	case *unix.SockaddrInet6:
-		return sa.Port
+// softwareFallback maps hardware timestamp types to their software alternatives
+var softwareFallback = map[Timestamp]Timestamp{
+	HW:   SW,
+	HWRX: SWRX,
+}
+
+// NegotiateTimestamps enables timestamps of the requested type on the socket.
+// If hardware timestamps can't be enabled it falls back to software ones.
+// It returns the type of timestamps which is active, callers must branch on it rather than on the requested one.
+// Software timestamps come from the system clock, so the fallback is only suitable where PHC isn't required.
+func NegotiateTimestamps(ts Timestamp, connFd int, iface string) (Timestamp, error) {
+	err := EnableTimestamps(ts, connFd, iface)
+	if err == nil {
+		return ts, nil
+	}
+	fallback, ok := softwareFallback[ts]
+	if !ok {
+		return ts, err
+	}
+	if ferr := EnableTimestamps(fallback, connFd, iface); ferr != nil {
+		return ts, fmt.Errorf("%w; fallback to %s failed: %w", err, fallback, ferr)
 	}
-	return 0
+	return fallback, nil
 }
 
-// NewSockaddrWithPort creates a new socket address with the same IP and new port
-func NewSockaddrWithPort(sa unix.Sockaddr, port int) unix.Sockaddr {
-	switch sa := sa.(type) {
-	case *unix.SockaddrInet4:
-		return &unix.SockaddrInet4{Addr: sa.Addr, Port: port}
-	case *unix.SockaddrInet6:
-		return &unix.SockaddrInet6{Addr: sa.Addr, Port: port}
+// NegotiateTimestamping tries to enable hardware TX and RX timestamps on the connection,
+// falling back to software ones. It returns the type of timestamps which is active.
+func NegotiateTimestamping(conn *net.UDPConn, iface string) (Timestamp, error) {
+	connFd, err := ConnFd(conn)
+	if err != nil {
+		return HW, err
 	}
-	return nil
+	return NegotiateTimestamps(HW, connFd, iface)
 }
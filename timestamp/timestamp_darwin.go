@@ -67,6 +67,16 @@ func EnableSWTimestampsRx(connFd int) error {
 	return unix.SetsockoptInt(connFd, unix.SOL_SOCKET, timestamping, 1)
 }
 
+// ReadTXtimestamp is not supported, TX timestamps are only reported on Linux
+func ReadTXtimestamp(_ int) (time.Time, int, error) {
+	return time.Time{}, 0, fmt.Errorf("TX timestamps are not supported")
+}
+
+// EnableBusyPoll is not supported
+func EnableBusyPoll(_ int, _ BusyPoll) error {
+	return fmt.Errorf("busy polling is not supported")
+}
+
 // EnableTimestamps enables timestamps on the socket based on requested type
 func EnableTimestamps(ts Timestamp, connFd int, _ string) error {
 	switch ts {
@@ -24,6 +24,7 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/facebook/time/phc"
 	"github.com/facebook/time/phc/unix" // a temporary shim for "golang.org/x/sys/unix" until v0.27.0 is cut
 )
 
@@ -84,7 +85,43 @@ func byteToTime(data []byte) (time.Time, error) {
 	return time.Unix(sec, nsec), nil
 }
 
+// rxFiltersL4 are RX filters suitable for PTP over UDP, in the order of preference
+var rxFiltersL4 = []int32{unix.HWTSTAMP_FILTER_PTP_V2_L4_EVENT, unix.HWTSTAMP_FILTER_ALL}
+
+// rxFilters returns hwtstamp_config rx_filter values to try, in the order of preference
+func (f RXFilter) rxFilters(auto []int32) []int32 {
+	switch f {
+	case RXFilterAll:
+		return []int32{unix.HWTSTAMP_FILTER_ALL}
+	case RXFilterPTPV2Event:
+		return []int32{unix.HWTSTAMP_FILTER_PTP_V2_EVENT}
+	case RXFilterPTPV2L4Event:
+		return []int32{unix.HWTSTAMP_FILTER_PTP_V2_L4_EVENT}
+	case RXFilterPTPV2L2Event:
+		return []int32{unix.HWTSTAMP_FILTER_PTP_V2_L2_EVENT}
+	default:
+		return auto
+	}
+}
+
+// hwTxType returns hwtstamp_config tx_type value
+func (t TXType) hwTxType() int32 {
+	switch t {
+	case TXTypeOneStepSync:
+		return unix.HWTSTAMP_TX_ONESTEP_SYNC
+	case TXTypeOneStepP2P:
+		return unix.HWTSTAMP_TX_ONESTEP_P2P
+	default:
+		return unix.HWTSTAMP_TX_ON
+	}
+}
+
 func ioctlHWTimestampCaps(fd int, ifname string) (int32, int32, error) {
+	return ioctlHWTimestampCapsFilters(fd, ifname, TXTypeOn, HWRXFilter.rxFilters(rxFiltersL4))
+}
+
+// ioctlHWTimestampCapsFilters returns the first supported RX filter from preferred list and the TX type if it's supported
+func ioctlHWTimestampCapsFilters(fd int, ifname string, tx TXType, preferred []int32) (int32, int32, error) {
 	var rxFilter, txFilter int32
 
 	hw, err := unix.IoctlGetEthtoolTsInfo(fd, ifname)
@@ -92,23 +129,27 @@ func ioctlHWTimestampCaps(fd int, ifname string) (int32, int32, error) {
 		return 0, 0, fmt.Errorf("failed to run ioctl SIOCETHTOOL to see what is supported: (%w)", err)
 	}
 
-	if hw.Tx_types&(1<<unix.HWTSTAMP_TX_ON) > 0 {
-		txFilter = unix.HWTSTAMP_TX_ON
+	if txType := tx.hwTxType(); hw.Tx_types&(1<<txType) > 0 {
+		txFilter = txType
 	}
 
-	if hw.Rx_filters&(1<<unix.HWTSTAMP_FILTER_PTP_V2_L4_EVENT) > 0 {
-		rxFilter = unix.HWTSTAMP_FILTER_PTP_V2_L4_EVENT
-	} else if hw.Rx_filters&(1<<unix.HWTSTAMP_FILTER_ALL) > 0 {
-		rxFilter = unix.HWTSTAMP_FILTER_ALL
+	for _, filter := range preferred {
+		if hw.Rx_filters&(1<<filter) > 0 {
+			rxFilter = filter
+			break
+		}
 	}
 
-	if txFilter == 0 || rxFilter == 0 {
-		return rxFilter, txFilter, fmt.Errorf("hardware timestamping is not supported for the interface %s", ifname)
+	if txFilter == 0 {
+		return rxFilter, txFilter, fmt.Errorf("hardware timestamping with tx type %s is not supported for the interface %s", tx, ifname)
+	}
+	if rxFilter == 0 {
+		return rxFilter, txFilter, fmt.Errorf("hardware timestamping with rx filter %s is not supported for the interface %s", HWRXFilter, ifname)
 	}
 	return rxFilter, txFilter, nil
 }
 
-func ioctlTimestamp(fd int, ifname string, filter int32) error {
+func ioctlTimestamp(fd int, ifname string, filter int32, txType int32) error {
 	hw, err := unix.IoctlGetHwTstamp(fd, ifname)
 	if errors.Is(err, unix.ENOTSUP) {
 		// for the loopback interface
@@ -118,11 +159,11 @@ func ioctlTimestamp(fd int, ifname string, filter int32) error {
 	}
 
 	// now check if it matches what we want
-	if hw.Tx_type == unix.HWTSTAMP_TX_ON && hw.Rx_filter == filter {
+	if hw.Tx_type == txType && hw.Rx_filter == filter {
 		return nil
 	}
 	// set to desired values
-	hw.Tx_type = unix.HWTSTAMP_TX_ON
+	hw.Tx_type = txType
 	hw.Rx_filter = filter
 	if err := unix.IoctlSetHwTstamp(fd, ifname, hw); err != nil {
 		return fmt.Errorf("failed to run ioctl SIOCSHWTSTAMP to set timestamps enabled: %w", err)
@@ -130,6 +171,47 @@ func ioctlTimestamp(fd int, ifname string, filter int32) error {
 	return nil
 }
 
+// addTimestampingFlags adds flags to timestamping flags already enabled on the socket
+func addTimestampingFlags(connFd int, flags int) error {
+	// SO_TIMESTAMPING returns flags regardless of which option was used to set them
+	current, err := unix.GetsockoptInt(connFd, unix.SOL_SOCKET, unix.SO_TIMESTAMPING)
+	if err != nil {
+		return fmt.Errorf("failed to get timestamping flags: %w", err)
+	}
+	return unix.SetsockoptInt(connFd, unix.SOL_SOCKET, timestamping, current|flags)
+}
+
+// BindPHC binds hardware timestamps already enabled on the socket to the PHC with given index,
+// usually a vclock of the interface. The socket must be bound to the interface with SO_BINDTODEVICE.
+func BindPHC(connFd int, phcIndex int) error {
+	flags, err := unix.GetsockoptInt(connFd, unix.SOL_SOCKET, unix.SO_TIMESTAMPING)
+	if err != nil {
+		return fmt.Errorf("failed to get timestamping flags: %w", err)
+	}
+	return phc.BindTimestampsToPHC(connFd, flags, phcIndex)
+}
+
+// EnableBusyPoll enables busy polling on the socket to reduce RX latency.
+// Increasing the budget requires CAP_NET_ADMIN.
+func EnableBusyPoll(connFd int, cfg BusyPoll) error {
+	if err := unix.SetsockoptInt(connFd, unix.SOL_SOCKET, unix.SO_BUSY_POLL, int(cfg.Timeout.Microseconds())); err != nil {
+		return fmt.Errorf("failed to set SO_BUSY_POLL: %w", err)
+	}
+	if cfg.Budget > 0 {
+		if err := unix.SetsockoptInt(connFd, unix.SOL_SOCKET, unix.SO_BUSY_POLL_BUDGET, cfg.Budget); err != nil {
+			return fmt.Errorf("failed to set SO_BUSY_POLL_BUDGET: %w", err)
+		}
+	}
+	prefer := 0
+	if cfg.Prefer {
+		prefer = 1
+	}
+	if err := unix.SetsockoptInt(connFd, unix.SOL_SOCKET, unix.SO_PREFER_BUSY_POLL, prefer); err != nil {
+		return fmt.Errorf("failed to set SO_PREFER_BUSY_POLL: %w", err)
+	}
+	return nil
+}
+
 // EnableSWTimestampsRx enables SW RX timestamps on the socket
 func EnableSWTimestampsRx(connFd int) error {
 	flags := unix.SOF_TIMESTAMPING_RX_SOFTWARE |
@@ -154,11 +236,22 @@ func EnableSWTimestamps(connFd int) error {
 
 // EnableHWTimestamps enables HW timestamps (TX and RX) on the socket
 func EnableHWTimestamps(connFd int, iface string) error {
-	rxFilter, _, err := ioctlHWTimestampCaps(connFd, iface)
+	return enableHWTimestamps(connFd, iface, TXTypeOn, HWRXFilter.rxFilters(rxFiltersL4))
+}
+
+// EnableHWTimestampsTXType enables HW timestamps (TX and RX) on the socket with the NIC set to the TX type, like one-step Sync.
+// It fails if the NIC doesn't support the TX type.
+// The TX type is set for the whole NIC, so all sockets using it must request the same one
+func EnableHWTimestampsTXType(connFd int, iface string, tx TXType) error {
+	return enableHWTimestamps(connFd, iface, tx, HWRXFilter.rxFilters(rxFiltersL4))
+}
+
+func enableHWTimestamps(connFd int, iface string, tx TXType, preferred []int32) error {
+	rxFilter, txType, err := ioctlHWTimestampCapsFilters(connFd, iface, tx, preferred)
 	if err != nil {
 		return err
 	}
-	if err := ioctlTimestamp(connFd, iface, rxFilter); err != nil {
+	if err := ioctlTimestamp(connFd, iface, rxFilter, txType); err != nil {
 		return err
 	}
 
@@ -177,11 +270,11 @@ func EnableHWTimestamps(connFd int, iface string) error {
 
 // EnableHWTimestampsRx enables HW RX timestamps on the socket
 func EnableHWTimestampsRx(connFd int, iface string) error {
-	rxFilter, _, err := ioctlHWTimestampCaps(connFd, iface)
+	rxFilter, txType, err := ioctlHWTimestampCaps(connFd, iface)
 	if err != nil {
 		return err
 	}
-	if err := ioctlTimestamp(connFd, iface, rxFilter); err != nil {
+	if err := ioctlTimestamp(connFd, iface, rxFilter, txType); err != nil {
 		return err
 	}
 
@@ -195,14 +288,26 @@ func EnableHWTimestampsRx(connFd int, iface string) error {
 	return unix.SetsockoptInt(connFd, unix.SOL_SOCKET, unix.SO_SELECT_ERR_QUEUE, 1)
 }
 
-func waitForHWTS(connFd int) error {
-	// Wait until TX timestamp is ready
+// waitForHWTS waits until the error queue of the socket is readable or the deadline passes
+func waitForHWTS(connFd int, deadline time.Time) error {
 	fds := []unix.PollFd{{Fd: int32(connFd), Events: unix.POLLERR, Revents: 0}}
 	for {
-		_, err := unix.Poll(fds, int(TimeoutTXTS.Milliseconds()))
-		if !errors.Is(err, syscall.EINTR) {
+		left := time.Until(deadline)
+		if left <= 0 {
+			return unix.ETIMEDOUT
+		}
+		// round up so we never spin with zero timeout
+		n, err := unix.Poll(fds, int((left+time.Millisecond-1)/time.Millisecond))
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil {
 			return err
 		}
+		if n == 0 {
+			return unix.ETIMEDOUT
+		}
+		return nil
 	}
 }
 
@@ -222,6 +327,7 @@ func recvoob(connFd int, oob []byte) (oobn int, err error) {
 }
 
 // ReadTXtimestampBuf returns HW TX timestamp, needs to be provided 2 buffers which all can be re-used after ReadTXtimestampBuf finishes.
+// Each of AttemptsTXTS attempts waits up to TimeoutTXTS for the socket error queue to become readable.
 func ReadTXtimestampBuf(connFd int, oob, toob []byte) (time.Time, int, error) {
 	// Accessing hw timestamp
 	var boob int
@@ -236,8 +342,8 @@ func ReadTXtimestampBuf(connFd int, oob, toob []byte) (time.Time, int, error) {
 	attempts := 0
 	for ; attempts < AttemptsTXTS; attempts++ {
 		if !txfound {
-			// Wait for the poll event, ignore the error
-			_ = waitForHWTS(connFd)
+			// Wait for the poll event, ignore the error: reading the queue tells if there is a TX TS
+			_ = waitForHWTS(connFd, time.Now().Add(TimeoutTXTS))
 		}
 
 		tboob, err := recvoob(connFd, toob)
@@ -248,7 +354,10 @@ func ReadTXtimestampBuf(connFd int, oob, toob []byte) (time.Time, int, error) {
 				break
 			}
 			// Keep looking for a valid TX TS
-			continue
+			if errors.Is(err, unix.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return time.Time{}, attempts, fmt.Errorf("failed to read TX timestamp: %w", err)
 		}
 		// We found a valid TX TS. Still check more if there is a newer one
 		txfound = true
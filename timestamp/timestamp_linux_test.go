@@ -17,6 +17,7 @@ limitations under the License.
 package timestamp
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"runtime"
@@ -90,6 +91,68 @@ func Test_ReadTXtimestamp(t *testing.T) {
 	require.Nil(t, err)
 }
 
+func TestWaitForHWTS(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	connFd, err := ConnFd(conn)
+	require.NoError(t, err)
+	require.NoError(t, EnableSWTimestamps(connFd))
+
+	require.ErrorIs(t, waitForHWTS(connFd, time.Now().Add(-time.Second)), unix.ETIMEDOUT)
+
+	start := time.Now()
+	require.ErrorIs(t, waitForHWTS(connFd, start.Add(20*time.Millisecond)), unix.ETIMEDOUT)
+	require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	_, err = conn.WriteTo([]byte{}, &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 12345})
+	require.NoError(t, err)
+	start = time.Now()
+	require.NoError(t, waitForHWTS(connFd, start.Add(time.Second)))
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestReadTXtimestampWakesUp(t *testing.T) {
+	attempts, timeout := AttemptsTXTS, TimeoutTXTS
+	defer func() { AttemptsTXTS, TimeoutTXTS = attempts, timeout }()
+	AttemptsTXTS = 2
+	TimeoutTXTS = time.Second
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	connFd, err := ConnFd(conn)
+	require.NoError(t, err)
+	require.NoError(t, EnableSWTimestamps(connFd))
+
+	// TX timestamp arriving while we wait is picked up without waiting for the timeout
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = conn.WriteTo([]byte{}, &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 12345})
+	}()
+	start := time.Now()
+	txts, n, err := ReadTXtimestamp(connFd)
+	require.NoError(t, err)
+	require.NotEqual(t, time.Time{}, txts)
+	require.Equal(t, 1, n)
+	require.Less(t, time.Since(start), TimeoutTXTS)
+}
+
+func TestReadTXtimestampError(t *testing.T) {
+	attempts, timeout := AttemptsTXTS, TimeoutTXTS
+	defer func() { AttemptsTXTS, TimeoutTXTS = attempts, timeout }()
+	AttemptsTXTS = 10
+	TimeoutTXTS = time.Millisecond
+
+	// errors other than empty queue are not retried
+	_, n, err := ReadTXtimestamp(-1)
+	require.ErrorIs(t, err, unix.EBADF)
+	require.ErrorContains(t, err, "failed to read TX timestamp")
+	require.Equal(t, 0, n)
+}
+
 func Test_scmDataToTime(t *testing.T) {
 	hwData := []byte{
 		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
@@ -357,3 +420,81 @@ func TestReadHWTimestampCaps(t *testing.T) {
 	require.Equal(t, int32(0), txType)
 	require.Equal(t, int32(0), rxFilters)
 }
+
+func TestEnableHWTimestampsTXType(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0})
+	require.NoError(t, err)
+	defer conn.Close()
+	connFd, err := ConnFd(conn)
+	require.NoError(t, err)
+
+	// no fallback to other TX types
+	err = EnableHWTimestampsTXType(connFd, "lo", TXTypeOneStepSync)
+	require.ErrorContains(t, err, "tx type onestep_sync is not supported")
+}
+
+func TestBindPHC(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	connFd, err := ConnFd(conn)
+	require.NoError(t, err)
+	require.NoError(t, EnableSWTimestamps(connFd))
+
+	// socket is not bound to any device
+	err = BindPHC(connFd, 0)
+	require.ErrorContains(t, err, "binding timestamps to PHC 0")
+}
+
+func TestNegotiateTimestampsRx(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	connFd, err := ConnFd(conn)
+	require.NoError(t, err)
+	ts, err := NegotiateTimestamps(HWRX, connFd, "lo")
+	require.NoError(t, err)
+	require.Equal(t, SWRX, ts)
+}
+
+func TestRXFilters(t *testing.T) {
+	require.Equal(t, rxFiltersL4, RXFilterAuto.rxFilters(rxFiltersL4))
+	require.Equal(t, []int32{unix.HWTSTAMP_FILTER_ALL}, RXFilterAll.rxFilters(rxFiltersL4))
+	require.Equal(t, []int32{unix.HWTSTAMP_FILTER_PTP_V2_EVENT}, RXFilterPTPV2Event.rxFilters(rxFiltersL4))
+	require.Equal(t, []int32{unix.HWTSTAMP_FILTER_PTP_V2_L4_EVENT}, RXFilterPTPV2L4Event.rxFilters(rxFiltersL2))
+	require.Equal(t, []int32{unix.HWTSTAMP_FILTER_PTP_V2_L2_EVENT}, RXFilterPTPV2L2Event.rxFilters(rxFiltersL4))
+}
+
+func TestTXTypes(t *testing.T) {
+	require.Equal(t, int32(unix.HWTSTAMP_TX_ON), TXTypeOn.hwTxType())
+	require.Equal(t, int32(unix.HWTSTAMP_TX_ONESTEP_SYNC), TXTypeOneStepSync.hwTxType())
+	require.Equal(t, int32(3), TXTypeOneStepP2P.hwTxType()) // HWTSTAMP_TX_ONESTEP_P2P
+}
+
+func TestEnableBusyPoll(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	connFd, err := ConnFd(conn)
+	require.NoError(t, err)
+	err = EnableBusyPoll(connFd, BusyPoll{Timeout: 50 * time.Microsecond, Budget: 16, Prefer: true})
+	if errors.Is(err, unix.EPERM) {
+		t.Skip("busy polling requires CAP_NET_ADMIN")
+	}
+	require.NoError(t, err)
+
+	v, err := unix.GetsockoptInt(connFd, unix.SOL_SOCKET, unix.SO_BUSY_POLL)
+	require.NoError(t, err)
+	require.Equal(t, 50, v)
+	v, err = unix.GetsockoptInt(connFd, unix.SOL_SOCKET, unix.SO_PREFER_BUSY_POLL)
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+
+	require.NoError(t, EnableBusyPoll(connFd, BusyPoll{}))
+	v, err = unix.GetsockoptInt(connFd, unix.SOL_SOCKET, unix.SO_BUSY_POLL)
+	require.NoError(t, err)
+	require.Equal(t, 0, v)
+}
@@ -0,0 +1,36 @@
+//go:build !linux && !darwin && !freebsd
+
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timestamp
+
+import "fmt"
+
+// EnableBusyPoll is not supported
+func EnableBusyPoll(_ int, _ BusyPoll) error {
+	return fmt.Errorf("busy polling is not supported")
+}
+
+// EnableTimestamps accepts only software timestamps, which are taken in userspace with UserspaceConn
+func EnableTimestamps(ts Timestamp, _ int, _ string) error {
+	switch ts {
+	case SW, SWRX:
+		return nil
+	default:
+		return fmt.Errorf("Unrecognized timestamp type: %s", ts)
+	}
+}
@@ -1,3 +1,5 @@
+//go:build linux || darwin || freebsd
+
 /*
 Copyright (c) Facebook, Inc. and its affiliates.
 
@@ -21,6 +23,7 @@ import (
 	"net"
 	"net/netip"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sys/unix"
@@ -172,3 +175,72 @@ func TestNewSockaddrWithPort(t *testing.T) {
 	oldSA.Addr[0] = 42
 	require.NotEqual(t, oldSA.Addr, newSA4.Addr)
 }
+
+func TestNegotiateTimestamping(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 0})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// no hardware timestamps on loopback
+	ts, err := NegotiateTimestamping(conn, "lo")
+	require.NoError(t, err)
+	require.Equal(t, SW, ts)
+
+	connFd, err := ConnFd(conn)
+	require.NoError(t, err)
+	ts, err = NegotiateTimestamps(SW, connFd, "lo")
+	require.NoError(t, err)
+	require.Equal(t, SW, ts)
+
+	_, err = NegotiateTimestamps(Timestamp(42), connFd, "lo")
+	require.Error(t, err)
+}
+
+func TestBusyPollValidate(t *testing.T) {
+	require.NoError(t, BusyPoll{}.Validate())
+	require.NoError(t, BusyPoll{Timeout: 50 * time.Microsecond}.Validate())
+	require.NoError(t, BusyPoll{Timeout: 50 * time.Microsecond, Budget: 16, Prefer: true}.Validate())
+	require.Error(t, BusyPoll{Timeout: -time.Microsecond}.Validate())
+	require.Error(t, BusyPoll{Timeout: 50 * time.Microsecond, Budget: -1}.Validate())
+	require.Error(t, BusyPoll{Budget: 16}.Validate())
+	require.Error(t, BusyPoll{Prefer: true}.Validate())
+}
+
+func TestRXFilterText(t *testing.T) {
+	var f RXFilter
+	require.NoError(t, f.UnmarshalText([]byte("ptpv2_l4_event")))
+	require.Equal(t, RXFilterPTPV2L4Event, f)
+	require.Equal(t, "ptpv2_l4_event", f.String())
+	require.Equal(t, "rxfilter", f.Type())
+	b, err := RXFilterAll.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, []byte("all"), b)
+
+	require.Error(t, f.Set("some"))
+	require.Equal(t, RXFilterPTPV2L4Event, f)
+	_, err = RXFilter(42).MarshalText()
+	require.Error(t, err)
+	require.Equal(t, Unsupported, RXFilter(42).String())
+}
+
+func TestRXFilterNames(t *testing.T) {
+	require.Equal(t, []string{"auto", "all", "ptpv2_event", "ptpv2_l4_event", "ptpv2_l2_event"}, RXFilterNames())
+}
+
+func TestTXTypeText(t *testing.T) {
+	var tt TXType
+	require.NoError(t, tt.UnmarshalText([]byte("onestep_sync")))
+	require.Equal(t, TXTypeOneStepSync, tt)
+	require.True(t, tt.OneStep())
+	require.False(t, TXTypeOn.OneStep())
+	require.Equal(t, "txtype", tt.Type())
+	b, err := TXTypeOneStepP2P.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, []byte("onestep_p2p"), b)
+
+	require.Error(t, tt.Set("off"))
+	require.Equal(t, TXTypeOneStepSync, tt)
+	_, err = TXType(42).MarshalText()
+	require.Error(t, err)
+	require.Equal(t, Unsupported, TXType(42).String())
+}
@@ -0,0 +1,57 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timestamp
+
+import (
+	"net"
+	"time"
+)
+
+// UserspaceConn is a portable fallback for platforms without kernel timestamping.
+// Timestamps are system clock reads around send and receive calls, so they include
+// scheduling and syscall latency and are much less precise than kernel ones.
+type UserspaceConn struct {
+	*net.UDPConn
+	now func() time.Time
+}
+
+// NewUserspaceConn wraps the connection to timestamp packets in userspace
+func NewUserspaceConn(conn *net.UDPConn) *UserspaceConn {
+	return &UserspaceConn{UDPConn: conn, now: time.Now}
+}
+
+// WriteToWithTS sends the packet and returns the TX timestamp,
+// which is the middle of the send call
+func (c *UserspaceConn) WriteToWithTS(b []byte, addr net.Addr) (int, time.Time, error) {
+	before := c.now()
+	n, err := c.WriteTo(b, addr)
+	if err != nil {
+		return n, time.Time{}, err
+	}
+	after := c.now()
+	return n, before.Add(after.Sub(before) / 2), nil
+}
+
+// ReadPacketWithRXTimestamp reads the packet and returns the RX timestamp,
+// which is taken as soon as the receive call returns
+func (c *UserspaceConn) ReadPacketWithRXTimestamp(buf []byte) (int, net.Addr, time.Time, error) {
+	n, addr, err := c.ReadFrom(buf)
+	if err != nil {
+		return n, addr, time.Time{}, err
+	}
+	return n, addr, c.now(), nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright (c) Facebook, Inc. and its affiliates.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timestamp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserspaceConn(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("::1"), Port: 0})
+	require.NoError(t, err)
+	defer conn.Close()
+	uc := NewUserspaceConn(conn)
+
+	var calls int
+	start := time.Unix(1700000000, 0)
+	uc.now = func() time.Time {
+		calls++
+		return start.Add(time.Duration(calls) * time.Microsecond)
+	}
+
+	n, txts, err := uc.WriteToWithTS([]byte{1, 2, 3}, conn.LocalAddr())
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, start.Add(1500*time.Nanosecond), txts)
+
+	buf := make([]byte, PayloadSizeBytes)
+	n, addr, rxts, err := uc.ReadPacketWithRXTimestamp(buf)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, buf[:n])
+	require.Equal(t, conn.LocalAddr().String(), addr.String())
+	require.Equal(t, start.Add(3*time.Microsecond), rxts)
+}
+
+func TestUserspaceConnClosed(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("::1"), Port: 0})
+	require.NoError(t, err)
+	uc := NewUserspaceConn(conn)
+	require.NoError(t, conn.Close())
+
+	_, txts, err := uc.WriteToWithTS([]byte{1}, conn.LocalAddr())
+	require.Error(t, err)
+	require.True(t, txts.IsZero())
+
+	_, _, rxts, err := uc.ReadPacketWithRXTimestamp(make([]byte, PayloadSizeBytes))
+	require.Error(t, err)
+	require.True(t, rxts.IsZero())
+}